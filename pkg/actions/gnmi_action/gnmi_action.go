@@ -23,12 +23,14 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/gnmi/proto/gnmi_ext"
 
 	"github.com/openconfig/gnmic/pkg/actions"
 	"github.com/openconfig/gnmic/pkg/api"
 	"github.com/openconfig/gnmic/pkg/api/target"
 	"github.com/openconfig/gnmic/pkg/api/types"
 	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/extensions"
 	"github.com/openconfig/gnmic/pkg/formatters"
 	"github.com/openconfig/gnmic/pkg/gtemplate"
 )
@@ -90,11 +92,14 @@ type gnmiAction struct {
 	// Response format,
 	// possible values: `json`, `event`, `prototext`, `protojson`
 	Format string `mapstructure:"format,omitempty"`
+	// registered extensions values, indexed by extension name
+	Extensions map[string]any `mapstructure:"extensions,omitempty"`
 
 	target *template.Template
 	prefix *template.Template
 	paths  []*template.Template
 	values []*template.Template
+	exts   []*gnmi_ext.Extension
 
 	logger *log.Logger
 
@@ -122,6 +127,12 @@ func (g *gnmiAction) Init(cfg map[string]interface{}, opts ...actions.Option) er
 	if err != nil {
 		return err
 	}
+	if len(g.Extensions) > 0 {
+		g.exts, err = extensions.GetRegistry().EncodeValues(g.Extensions)
+		if err != nil {
+			return err
+		}
+	}
 	g.logger.Printf("action name %q of type %q initialized: %v", g.Name, actionType, g)
 	return nil
 }
@@ -316,7 +327,9 @@ func (g *gnmiAction) createGetRequest(in *actions.Context) (*gnmi.GetRequest, er
 		}
 		gnmiOpts = append(gnmiOpts, api.Path(b.String()))
 	}
-
+	for _, ext := range g.exts {
+		gnmiOpts = append(gnmiOpts, api.Extension(ext))
+	}
 	return api.NewGetRequest(gnmiOpts...)
 }
 
@@ -364,6 +377,9 @@ func (g *gnmiAction) createSetRequest(in *actions.Context) (*gnmi.SetRequest, er
 				))
 		}
 	}
+	for _, ext := range g.exts {
+		gnmiOpts = append(gnmiOpts, api.Extension(ext))
+	}
 	return api.NewSetRequest(gnmiOpts...)
 }
 
@@ -392,6 +408,9 @@ func (g *gnmiAction) createSubscribeRequest(in *actions.Context) (*gnmi.Subscrib
 		gnmiOpts = append(gnmiOpts, api.Subscription(
 			api.Path(b.String())))
 	}
+	for _, ext := range g.exts {
+		gnmiOpts = append(gnmiOpts, api.Extension(ext))
+	}
 	return api.NewSubscribeRequest(gnmiOpts...)
 }
 
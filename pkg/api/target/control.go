@@ -0,0 +1,203 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package target
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+)
+
+// subscriptionControl is the runtime state of a subscription
+// changed by PauseSubscription, ResumeSubscription, Resubscribe
+// and UpdateSubscription.
+type subscriptionControl struct {
+	paused bool
+	// closed when the subscription is resumed.
+	resume chan struct{}
+	// set when the subscription stream is canceled to be recreated.
+	restart bool
+	// context of the last subscription stream, done once the stream ended.
+	stream context.Context
+	// request the subscription stream is recreated with,
+	// set by UpdateSubscription.
+	request *gnmi.SubscribeRequest
+}
+
+// PauseSubscription cancels the stream of subscription name, the subscription
+// is not recreated until ResumeSubscription is called.
+// It can be called before the subscription is started.
+func (t *Target) PauseSubscription(name string) error {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if _, ok := t.Subscriptions[name]; !ok {
+		return fmt.Errorf("unknown subscription name %q", name)
+	}
+	c := t.control(name)
+	if c.paused {
+		return nil
+	}
+	c.paused = true
+	c.resume = make(chan struct{})
+	if cfn, ok := t.subscribeCancelFn[name]; ok {
+		cfn()
+	}
+	return nil
+}
+
+// ResumeSubscription recreates the stream of the paused subscription name.
+func (t *Target) ResumeSubscription(name string) error {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if _, ok := t.Subscriptions[name]; !ok {
+		return fmt.Errorf("unknown subscription name %q", name)
+	}
+	c := t.control(name)
+	if !c.paused {
+		return nil
+	}
+	c.paused = false
+	close(c.resume)
+	return nil
+}
+
+// Resubscribe cancels the stream of subscription name and immediately
+// recreates it, without waiting for the retry timer.
+func (t *Target) Resubscribe(name string) error {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if _, ok := t.Subscriptions[name]; !ok {
+		return fmt.Errorf("unknown subscription name %q", name)
+	}
+	c := t.control(name)
+	if c.paused {
+		return fmt.Errorf("subscription %q is paused", name)
+	}
+	if !c.streamActive() {
+		c.restart = false
+		return fmt.Errorf("subscription %q is not running", name)
+	}
+	c.restart = true
+	t.subscribeCancelFn[name]()
+	return nil
+}
+
+// UpdateSubscription replaces the configuration of the existing subscription sc.Name
+// and recreates its stream using req.
+// If the subscription is paused or waiting to retry, req is used when it restarts.
+func (t *Target) UpdateSubscription(sc *types.SubscriptionConfig, req *gnmi.SubscribeRequest) error {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if _, ok := t.Subscriptions[sc.Name]; !ok {
+		return fmt.Errorf("unknown subscription name %q", sc.Name)
+	}
+	t.Subscriptions[sc.Name] = sc
+	c := t.control(sc.Name)
+	c.request = req
+	if c.paused {
+		return nil
+	}
+	if c.streamActive() {
+		c.restart = true
+		t.subscribeCancelFn[sc.Name]()
+	}
+	return nil
+}
+
+// IsPaused returns true if subscription name is paused.
+func (t *Target) IsPaused(name string) bool {
+	t.m.Lock()
+	defer t.m.Unlock()
+	c, ok := t.controls[name]
+	return ok && c.paused
+}
+
+// PausedSubscriptions returns the sorted names of the paused subscriptions.
+func (t *Target) PausedSubscriptions() []string {
+	t.m.Lock()
+	defer t.m.Unlock()
+	names := make([]string, 0, len(t.controls))
+	for n, c := range t.controls {
+		if c.paused {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// control returns the control state of subscription name,
+// it assumes t.m is acquired.
+func (t *Target) control(name string) *subscriptionControl {
+	c, ok := t.controls[name]
+	if !ok {
+		c = new(subscriptionControl)
+		t.controls[name] = c
+	}
+	return c
+}
+
+// streamActive returns true if the subscription stream is running,
+// rather than waiting to be retried or done.
+func (c *subscriptionControl) streamActive() bool {
+	return c.stream != nil && c.stream.Err() == nil
+}
+
+// waitResumed blocks while subscription name is paused.
+// It returns false if ctx is done first.
+func (t *Target) waitResumed(ctx context.Context, name string) bool {
+	t.m.Lock()
+	c, ok := t.controls[name]
+	if !ok || !c.paused {
+		t.m.Unlock()
+		return true
+	}
+	ch := c.resume
+	t.m.Unlock()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-ch:
+		return true
+	}
+}
+
+// updatedRequest returns the request set by UpdateSubscription
+// since the last call, nil otherwise.
+func (t *Target) updatedRequest(name string) *gnmi.SubscribeRequest {
+	t.m.Lock()
+	defer t.m.Unlock()
+	c, ok := t.controls[name]
+	if !ok {
+		return nil
+	}
+	req := c.request
+	c.request = nil
+	return req
+}
+
+// interrupted returns true if the stream of subscription name
+// was canceled by PauseSubscription or Resubscribe.
+func (t *Target) interrupted(name string) bool {
+	t.m.Lock()
+	defer t.m.Unlock()
+	c, ok := t.controls[name]
+	if !ok {
+		return false
+	}
+	if c.restart {
+		c.restart = false
+		return true
+	}
+	return c.paused
+}
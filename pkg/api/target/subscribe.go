@@ -39,6 +39,14 @@ SUBSC:
 		}
 	}
 SUBSC_NODELAY:
+	// a paused subscription waits to be resumed
+	if !t.waitResumed(ctx, subscriptionName) {
+		return
+	}
+	// the subscription was updated while running or waiting
+	if r := t.updatedRequest(subscriptionName); r != nil {
+		req = r
+	}
 	select {
 	case <-ctx.Done():
 		return
@@ -59,24 +67,38 @@ SUBSC_NODELAY:
 	if cfn, ok := t.subscribeCancelFn[subscriptionName]; ok {
 		cfn()
 	}
+	if c, ok := t.controls[subscriptionName]; ok && c.paused {
+		// paused while the subscribe client was being created
+		t.m.Unlock()
+		cancel()
+		goto SUBSC_NODELAY
+	}
 	t.SubscribeClients[subscriptionName] = subscribeClient
 	t.subscribeCancelFn[subscriptionName] = cancel
+	t.control(subscriptionName).stream = nctx
 	subConfig := t.Subscriptions[subscriptionName]
 	t.m.Unlock()
 
 	err = subscribeClient.Send(req)
 	if err != nil {
+		cancel()
+		if t.interrupted(subscriptionName) {
+			goto SUBSC_NODELAY
+		}
 		t.errors <- &TargetError{
 			SubscriptionName: subscriptionName,
 			Err:              fmt.Errorf("target '%s' send error, retry in %d. err=%v", t.Config.Name, t.Config.RetryTimer, err),
 		}
-		cancel()
 		goto SUBSC
 	}
 
 	switch req.GetSubscribe().GetMode() {
 	case gnmi.SubscriptionList_STREAM:
 		err = t.handleStreamSubscriptionRcv(nctx, subscribeClient, subscriptionName, subConfig)
+		if t.interrupted(subscriptionName) {
+			cancel()
+			goto SUBSC_NODELAY
+		}
 		if err != nil {
 			t.errors <- &TargetError{
 				SubscriptionName: subscriptionName,
@@ -112,6 +134,10 @@ SUBSC_NODELAY:
 	case gnmi.SubscriptionList_POLL:
 		go t.listenPolls(nctx)
 		err = t.handlePollSubscriptionRcv(nctx, subscribeClient, subscriptionName, subConfig)
+		if t.interrupted(subscriptionName) {
+			cancel()
+			goto SUBSC_NODELAY
+		}
 		if err != nil {
 			t.errors <- &TargetError{
 				SubscriptionName: subscriptionName,
@@ -398,3 +424,18 @@ func (t *Target) handlePollSubscriptionRcv(ctx context.Context, stream gnmi.GNMI
 		}
 	}
 }
+
+// SubscribeStream creates a subscribe client and sends req,
+// unlike Subscribe it does not retry and leaves the responses handling to the caller.
+// The subscribe client is closed when ctx is canceled.
+func (t *Target) SubscribeStream(ctx context.Context, req *gnmi.SubscribeRequest) (gnmi.GNMI_SubscribeClient, error) {
+	subscribeClient, err := t.Client.Subscribe(t.appendRequestMetadata(ctx), t.callOpts()...)
+	if err != nil {
+		return nil, err
+	}
+	err = subscribeClient.Send(req)
+	if err != nil {
+		return nil, err
+	}
+	return subscribeClient, nil
+}
@@ -49,7 +49,8 @@ type Target struct {
 	Client             gnmi.GNMIClient                      `json:"-"`
 	SubscribeClients   map[string]gnmi.GNMI_SubscribeClient `json:"-"` // subscription name to subscribeClient
 	subscribeCancelFn  map[string]context.CancelFunc
-	pollChan           chan string // subscription name to be polled
+	controls           map[string]*subscriptionControl // subscription name to its runtime control state
+	pollChan           chan string                     // subscription name to be polled
 	subscribeResponses chan *SubscribeResponse
 	errors             chan *TargetError
 	stopped            bool
@@ -66,6 +67,7 @@ func NewTarget(c *types.TargetConfig) *Target {
 		m:                  new(sync.Mutex),
 		SubscribeClients:   make(map[string]gnmi.GNMI_SubscribeClient),
 		subscribeCancelFn:  make(map[string]context.CancelFunc),
+		controls:           make(map[string]*subscriptionControl),
 		pollChan:           make(chan string),
 		subscribeResponses: make(chan *SubscribeResponse, c.BufferSize),
 		errors:             make(chan *TargetError, c.BufferSize),
@@ -39,6 +39,8 @@ type SubscriptionConfig struct {
 	StreamSubscriptions []*SubscriptionConfig `mapstructure:"stream-subscriptions,omitempty" json:"stream-subscriptions,omitempty"`
 	Outputs             []string              `mapstructure:"outputs,omitempty" json:"outputs,omitempty"`
 	Depth               uint32                `mapstructure:"depth,omitempty" json:"depth,omitempty"`
+	// registered extensions values, indexed by extension name
+	Extensions map[string]any `mapstructure:"extensions,omitempty" json:"extensions,omitempty"`
 }
 
 type HistoryConfig struct {
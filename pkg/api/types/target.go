@@ -152,6 +152,9 @@ type TargetConfig struct {
 	CipherSuites     []string          `mapstructure:"cipher-suites,omitempty" yaml:"cipher-suites,omitempty" json:"cipher-suites,omitempty"`
 	TCPKeepalive     time.Duration     `mapstructure:"tcp-keepalive,omitempty" yaml:"tcp-keepalive,omitempty" json:"tcp-keepalive,omitempty"`
 	GRPCKeepalive    *clientKeepalive  `mapstructure:"grpc-keepalive,omitempty" yaml:"grpc-keepalive,omitempty" json:"grpc-keepalive,omitempty"`
+	// runtime state, set when the target is assigned to a cluster instance.
+	// It is not part of the target configuration.
+	PausedSubscriptions []string `mapstructure:"-" yaml:"-" json:"-"`
 
 	tlsConfig *tls.Config
 }
@@ -242,6 +245,7 @@ func (tc *TargetConfig) DeepCopy() *TargetConfig {
 	ntc.ProtoDirs = append(ntc.ProtoDirs, tc.ProtoDirs...)
 	ntc.Tags = append(ntc.Tags, tc.Tags...)
 	ntc.CipherSuites = append(ntc.CipherSuites, tc.CipherSuites...)
+	ntc.PausedSubscriptions = append(ntc.PausedSubscriptions, tc.PausedSubscriptions...)
 
 	for k, v := range tc.EventTags {
 		tc.EventTags[k] = v
@@ -28,6 +28,8 @@ import (
 	"github.com/openconfig/gnmic/pkg/api/types"
 	"github.com/openconfig/gnmic/pkg/api/utils"
 	"github.com/openconfig/gnmic/pkg/config"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/inputs"
 	"github.com/openconfig/gnmic/pkg/lockers"
 )
 
@@ -55,6 +57,9 @@ func (a *App) newAPIServer() (*http.Server, error) {
 		a.reg.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
 		a.reg.MustRegister(subscribeResponseReceivedCounter)
 		a.reg.MustRegister(subscribeResponseFailedCounter)
+		a.reg.MustRegister(formatters.Metrics()...)
+		a.reg.MustRegister(inputs.Metrics()...)
+		a.reg.MustRegister(completenessMetrics()...)
 		a.registerTargetMetrics()
 		go a.startClusterMetrics()
 	}
@@ -126,6 +131,7 @@ func (a *App) handleConfigTargetsPost(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
 		return
 	}
+	tc.PausedSubscriptions = r.URL.Query()[pausedSubscriptionParam]
 	a.AddTargetConfig(tc)
 }
 
@@ -221,6 +227,16 @@ func (a *App) handleConfig(w http.ResponseWriter, r *http.Request) {
 		Loader:        a.Config.Loader,
 		Actions:       a.Config.Actions,
 		TunnelServer:  a.Config.TunnelServer,
+
+		ProcessorsTrace: a.Config.ProcessorsTrace,
+		ProcessorsGuard: a.Config.ProcessorsGuard,
+		Facts:           a.Config.Facts,
+		Catalog:         a.Config.Catalog,
+		Completeness:    a.Config.Completeness,
+		Compliance:      a.Config.Compliance,
+
+		SubscriptionRollout:  a.Config.SubscriptionRollout,
+		RegisteredExtensions: a.Config.RegisteredExtensions,
 	}
 	for n, t := range a.Config.Targets {
 		tc := t.DeepCopy()
@@ -230,6 +246,122 @@ func (a *App) handleConfig(w http.ResponseWriter, r *http.Request) {
 	a.handlerCommonGet(w, nc)
 }
 
+func (a *App) handleTracesGet(w http.ResponseWriter, r *http.Request) {
+	t := formatters.GetTracer()
+	if t == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{"processors tracing is not enabled"}})
+		return
+	}
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		q := r.URL.Query()
+		a.handlerCommonGet(w, t.Traces(q.Get("target"), q.Get("subscription"), q.Get("output")))
+		return
+	}
+	if tr, ok := t.Get(id); ok {
+		a.handlerCommonGet(w, tr)
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+	_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{"trace not found"}})
+}
+
+func (a *App) handleTracesDelete(w http.ResponseWriter, r *http.Request) {
+	t := formatters.GetTracer()
+	if t == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{"processors tracing is not enabled"}})
+		return
+	}
+	t.Reset()
+}
+
+type guardResponse struct {
+	Circuits    []*formatters.CircuitState     `json:"circuits"`
+	Quarantined []*formatters.QuarantinedEvent `json:"quarantined"`
+}
+
+func (a *App) handleGuardGet(w http.ResponseWriter, r *http.Request) {
+	g := formatters.GetGuard()
+	processor := r.URL.Query().Get("processor")
+	res := &guardResponse{
+		Circuits:    make([]*formatters.CircuitState, 0),
+		Quarantined: g.Quarantined(processor),
+	}
+	for _, c := range g.Circuits() {
+		if processor != "" && c.Processor != processor {
+			continue
+		}
+		res.Circuits = append(res.Circuits, c)
+	}
+	a.handlerCommonGet(w, res)
+}
+
+func (a *App) handleGuardDelete(w http.ResponseWriter, r *http.Request) {
+	formatters.GetGuard().Reset(r.URL.Query().Get("processor"))
+}
+
+func (a *App) handleCatalogGet(w http.ResponseWriter, r *http.Request) {
+	c := formatters.GetCatalog()
+	if c == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{"telemetry catalog is not enabled"}})
+		return
+	}
+	q := r.URL.Query()
+	entries := c.Entries(q.Get("output"), q.Get("search"))
+	switch q.Get("format") {
+	case "", "json":
+		a.handlerCommonGet(w, entries)
+	case "json-schema":
+		a.handlerCommonGet(w, formatters.CatalogJSONSchema(entries))
+	case "markdown":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		_, _ = io.WriteString(w, formatters.CatalogMarkdown(entries))
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("unknown catalog format %q", q.Get("format"))}})
+	}
+}
+
+func (a *App) handleCatalogDelete(w http.ResponseWriter, r *http.Request) {
+	c := formatters.GetCatalog()
+	if c == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{"telemetry catalog is not enabled"}})
+		return
+	}
+	c.Reset()
+}
+
+func (a *App) handleEstimatePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	defer r.Body.Close()
+	req := new(EstimateRequest)
+	if len(body) > 0 {
+		err = json.Unmarshal(body, req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+			return
+		}
+	}
+	est, err := a.Estimate(r.Context(), req)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	a.handlerCommonGet(w, est)
+}
+
 func (a *App) handleTargetsGet(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -653,3 +785,42 @@ func getServiceScheme(service *lockers.Service) string {
 	}
 	return scheme
 }
+
+func (a *App) handleFactsGet(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if id == "" {
+		a.handlerCommonGet(w, a.facts.List())
+		return
+	}
+	if tf, ok := a.facts.GetTarget(id); ok {
+		a.handlerCommonGet(w, tf)
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+	_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{"no facts found"}})
+}
+
+func (a *App) handleFactsRefresh(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if a.factsGatherer == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{"facts gathering is not configured"}})
+		return
+	}
+	a.operLock.RLock()
+	t, ok := a.Targets[id]
+	a.operLock.RUnlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{"target not found"}})
+		return
+	}
+	if t.Client == nil {
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{"target is not connected"}})
+		return
+	}
+	a.gatherFacts(r.Context(), t)
+	tf, _ := a.facts.GetTarget(id)
+	a.handlerCommonGet(w, tf)
+}
@@ -0,0 +1,51 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/config"
+	"github.com/openconfig/gnmic/pkg/extensions"
+)
+
+func TestHandleConfig(t *testing.T) {
+	a := New()
+	a.Config.APIServer = &config.APIServer{}
+	a.Config.SubscriptionRollout = &config.SubscriptionRollout{
+		CanarySize: 2,
+		BakePeriod: time.Minute,
+		OnFailure:  "rollback",
+	}
+	a.Config.RegisteredExtensions = map[string]*extensions.Config{
+		"ext1": {ID: 1001, Message: "ext.Ext1", ProtoFiles: []string{"ext1.proto"}},
+	}
+	a.routes()
+
+	w := httptest.NewRecorder()
+	a.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/config", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	rsp := new(config.Config)
+	if err := json.NewDecoder(w.Body).Decode(rsp); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rsp.SubscriptionRollout, a.Config.SubscriptionRollout) {
+		t.Errorf("expected subscription-rollout %+v, got %+v", a.Config.SubscriptionRollout, rsp.SubscriptionRollout)
+	}
+	if !reflect.DeepEqual(rsp.RegisteredExtensions, a.Config.RegisteredExtensions) {
+		t.Errorf("expected registered-extensions %+v, got %+v", a.Config.RegisteredExtensions, rsp.RegisteredExtensions)
+	}
+}
@@ -42,6 +42,8 @@ import (
 	"github.com/openconfig/gnmic/pkg/api/types"
 	"github.com/openconfig/gnmic/pkg/cache"
 	"github.com/openconfig/gnmic/pkg/config"
+	"github.com/openconfig/gnmic/pkg/extensions"
+	"github.com/openconfig/gnmic/pkg/facts"
 	"github.com/openconfig/gnmic/pkg/formatters"
 	"github.com/openconfig/gnmic/pkg/formatters/plugin_manager"
 	"github.com/openconfig/gnmic/pkg/inputs"
@@ -102,6 +104,8 @@ type App struct {
 	// gNMI cache, used if a gnmi-server is configured
 	// with subscribe or proxy commands.
 	c cache.Cache
+	// event processors applied to the messages before they are cached
+	cacheEvps []formatters.EventProcessor
 	// tunnel server
 	// gRPC server where the tunnel service will be registered
 	grpcTunnelSrv *grpc.Server
@@ -111,6 +115,23 @@ type App struct {
 	tunTargetCfn  map[tunnel.Target]context.CancelFunc
 	// processors plugin manager
 	pm *plugin_manager.PluginManager
+	// targets facts gatherer and store
+	factsGatherer *facts.Gatherer
+	facts         *facts.Store
+	// active subscribe responses captures
+	captures *captureTaps
+	// paused subscriptions per target
+	pausedSubs *pausedSubscriptions
+	// staged subscription changes
+	rollouts *subscriptionRollouts
+	// telemetry completeness per target subscription
+	completeness *completenessTrackers
+	// compliance rules engine and last report
+	compliance *complianceRunner
+	// per target results of a one-shot command
+	targetResults *targetResults
+	// `--select` selectors applied to the stream subscriptions targets
+	targetSelectors []*targetSelector
 }
 
 func New() *App {
@@ -149,6 +170,11 @@ func New() *App {
 		ttm:          new(sync.RWMutex),
 		tunTargets:   make(map[tunnel.Target]struct{}),
 		tunTargetCfn: make(map[tunnel.Target]context.CancelFunc),
+		captures:     newCaptureTaps(),
+		pausedSubs:   newPausedSubscriptions(),
+		rollouts:     newSubscriptionRollouts(),
+		completeness: newCompletenessTrackers(),
+		facts:        facts.NewStore(),
 	}
 	a.router.StrictSlash(true)
 	a.router.Use(headersMiddleware, a.loggingMiddleware)
@@ -210,6 +236,8 @@ func (a *App) InitGlobalFlags() {
 	a.RootCmd.PersistentFlags().BoolVarP(&a.Config.GlobalFlags.UseTunnelServer, "use-tunnel-server", "", false, "use tunnel server to dial targets")
 	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.AuthScheme, "auth-scheme", "", "", "authentication scheme to use for the target's username/password")
 	a.RootCmd.PersistentFlags().BoolVarP(&a.Config.GlobalFlags.CalculateLatency, "calculate-latency", "", false, "calculate the delta between each message timestamp and the receive timestamp. JSON format only")
+	a.RootCmd.PersistentFlags().StringArrayVarP(&a.Config.GlobalFlags.Select, "select", "", nil, "select the targets to run one-shot RPCs against from the configured loader or targets, as comma separated `key=value` tags or a name regex")
+	a.RootCmd.PersistentFlags().IntVarP(&a.Config.GlobalFlags.Concurrency, "concurrency", "", 0, "maximum number of targets one-shot RPCs are run against concurrently, 0 means no limit")
 	a.RootCmd.PersistentFlags().StringToStringP("metadata", "H", a.Config.GlobalFlags.Metadata, "add metadata to gRPC requests (`key=value`)")
 	a.RootCmd.PersistentFlags().StringVarP(&a.Config.GlobalFlags.PluginProcessorsPath, "processors-plugins-path", "P", "", "filesystem path where gNMIc will look for even_plugin processors to initialize")
 	a.RootCmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
@@ -235,9 +263,32 @@ func (a *App) PreRunE(cmd *cobra.Command, args []string) error {
 	}
 	a.Logger.Printf("using config file %q", a.Config.FileConfig.ConfigFileUsed())
 	a.logConfigKVs()
+	err = a.loadRegisteredExtensions()
+	if err != nil {
+		return err
+	}
 	return a.validateGlobals()
 }
 
+// loadRegisteredExtensions loads the registered extensions declared in the config file
+// and makes them available to the requests builders and the formatters.
+func (a *App) loadRegisteredExtensions() error {
+	cfgs, err := a.Config.GetRegisteredExtensions()
+	if err != nil {
+		return err
+	}
+	if len(cfgs) == 0 {
+		return nil
+	}
+	r, err := extensions.NewRegistry(cfgs)
+	if err != nil {
+		return err
+	}
+	extensions.SetRegistry(r)
+	a.Logger.Printf("loaded %d registered extension(s)", len(cfgs))
+	return nil
+}
+
 func (a *App) validateGlobals() error {
 	if a.Config.Insecure {
 		if a.Config.SkipVerify {
@@ -370,6 +421,11 @@ func (a *App) loadTargets(e fsnotify.Event) {
 	defer a.sem.Release(1)
 	switch e.Op {
 	case fsnotify.Write, fsnotify.Create:
+		// subscription changes are only applied
+		// if staged rollouts are configured.
+		if a.Config.SubscriptionRollout != nil {
+			a.rolloutChangedSubscriptions()
+		}
 		newTargets, err := a.Config.GetTargets()
 		if err != nil && !errors.Is(err, config.ErrNoTargetsFound) {
 			a.Logger.Printf("failed getting targets from new config: %v", err)
@@ -495,7 +551,11 @@ func (a *App) LoadProtoFiles() (desc.Descriptor, error) {
 
 // GetTargets reads the targets configuration from flags or config file.
 // If enabled it will load targets from a configured tunnel server.
+// If `--select` is set, the targets are resolved through the configured loader.
 func (a *App) GetTargets() (map[string]*types.TargetConfig, error) {
+	if len(a.Config.Select) > 0 {
+		return a.getSelectedTargets(a.ctx)
+	}
 	targetsConfig, err := a.Config.GetTargets()
 	if errors.Is(err, config.ErrNoTargetsFound) {
 		if a.Config.UseTunnelServer {
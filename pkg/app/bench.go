@@ -0,0 +1,477 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/openconfig/gnmic/pkg/api/target"
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/config"
+)
+
+// maximum number of error messages kept per step.
+const benchMaxErrorMessages = 10
+
+// BenchReport is the result of a bench run against a target.
+type BenchReport struct {
+	Target string             `json:"target"`
+	Start  time.Time          `json:"start"`
+	End    time.Time          `json:"end"`
+	Steps  []*BenchStepReport `json:"steps"`
+	// set if a step breached one of the thresholds,
+	// the remaining steps are not run.
+	Stopped    bool   `json:"stopped,omitempty"`
+	StopReason string `json:"stop-reason,omitempty"`
+}
+
+// BenchStepReport holds the measurements of a bench step.
+type BenchStepReport struct {
+	Name           string   `json:"name"`
+	Subscriptions  int      `json:"subscriptions"`
+	SampleInterval string   `json:"sample-interval"`
+	Paths          []string `json:"paths"`
+	Duration       string   `json:"duration"`
+	// number of subscriptions that received a sync response.
+	Synced   int           `json:"synced"`
+	SyncTime *BenchLatency `json:"sync-time,omitempty"`
+	// number of series of the synced subscriptions, counted from their initial sync.
+	Series int `json:"series"`
+	// updates per second expected from the series at the sample interval.
+	RequestedRate float64 `json:"requested-rate"`
+	// updates per second received after the sync responses.
+	AchievedRate float64 `json:"achieved-rate"`
+	UpdateRatio  float64 `json:"update-ratio"`
+	// delay between the notifications timestamp and their reception.
+	TimestampLag *BenchLatency `json:"timestamp-lag,omitempty"`
+	// sample intervals evaluated and the ones with missing samples.
+	Intervals        uint64        `json:"intervals"`
+	DroppedIntervals uint64        `json:"dropped-intervals"`
+	RPC              string        `json:"rpc"`
+	RPCLatency       *BenchLatency `json:"rpc-latency,omitempty"`
+	Errors           int           `json:"errors"`
+	ErrorMessages    []string      `json:"error-messages,omitempty"`
+	// thresholds breached by the step.
+	Breaches []string `json:"breaches,omitempty"`
+}
+
+// BenchLatency summarizes a set of durations, in milliseconds.
+type BenchLatency struct {
+	Count int     `json:"count"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+func (a *App) BenchPreRunE(cmd *cobra.Command, _ []string) error {
+	a.Config.SetLocalFlagsFromFile(cmd)
+	a.createCollectorDialOpts()
+	return nil
+}
+
+func (a *App) BenchRunE(cmd *cobra.Command, _ []string) error {
+	defer a.InitBenchFlags(cmd)
+
+	bp, err := a.Config.GetBenchProfile()
+	if err != nil {
+		return err
+	}
+	targetsConfig, err := a.GetTargets()
+	if err != nil {
+		return err
+	}
+	if len(targetsConfig) != 1 {
+		return fmt.Errorf("bench runs against a single target, got %d, use --address or --select", len(targetsConfig))
+	}
+	var rep *BenchReport
+	for _, tc := range targetsConfig {
+		rep, err = a.Bench(a.ctx, tc, bp)
+	}
+	if err != nil {
+		return err
+	}
+	if a.Config.Format == "json" {
+		b, err := json.MarshalIndent(rep, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	} else {
+		printBenchReport(rep)
+	}
+	if rep.Stopped {
+		return fmt.Errorf("benchmark stopped: %s", rep.StopReason)
+	}
+	return nil
+}
+
+func (a *App) InitBenchFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+
+	cmd.Flags().StringVarP(&a.Config.LocalFlags.BenchProfile, "profile", "", "", "bench profile file, overrides the other flags")
+	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.BenchPath, "path", "", []string{}, "subscription paths")
+	cmd.Flags().IntVarP(&a.Config.LocalFlags.BenchSubscriptions, "subscriptions", "", 1, "number of concurrent subscriptions")
+	cmd.Flags().DurationVarP(&a.Config.LocalFlags.BenchSampleInterval, "sample-interval", "", 10*time.Second, "subscriptions sample interval")
+	cmd.Flags().DurationVarP(&a.Config.LocalFlags.BenchDuration, "duration", "", time.Minute, "benchmark duration")
+	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.BenchGetPath, "get-path", "", []string{}, "Get request paths, Capabilities requests are sent if not set")
+	cmd.Flags().DurationVarP(&a.Config.LocalFlags.BenchGetInterval, "get-interval", "", time.Second, "interval between two unary RPCs")
+
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
+	})
+}
+
+// Bench runs the profile steps in order against the target tc.
+// It stops at the first step breaching one of the profile thresholds
+// or when ctx is done, the report then includes the interrupted step.
+func (a *App) Bench(ctx context.Context, tc *types.TargetConfig, bp *config.BenchProfile) (*BenchReport, error) {
+	getReq, err := a.Config.CreateBenchGetRequest(bp, tc)
+	if err != nil {
+		return nil, err
+	}
+	reqs := make([]*gnmi.SubscribeRequest, 0, len(bp.Steps))
+	for _, step := range bp.Steps {
+		req, err := a.Config.CreateBenchSubscribeRequest(bp, step, tc)
+		if err != nil {
+			return nil, fmt.Errorf("step %q: %w", step.Name, err)
+		}
+		reqs = append(reqs, req)
+	}
+	t := target.NewTarget(tc)
+	err = a.CreateGNMIClient(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	defer t.Close()
+
+	rep := &BenchReport{
+		Target: tc.Name,
+		Start:  time.Now(),
+		Steps:  make([]*BenchStepReport, 0, len(bp.Steps)),
+	}
+	for i, step := range bp.Steps {
+		a.Logger.Printf("bench %q: running step %q: %d subscription(s), sample-interval=%s, duration=%s",
+			tc.Name, step.Name, step.Subscriptions, step.SampleInterval, step.Duration)
+		sr := a.benchStep(ctx, t, step, reqs[i], getReq, bp)
+		rep.Steps = append(rep.Steps, sr)
+		if ctx.Err() != nil {
+			rep.Stopped = true
+			rep.StopReason = fmt.Sprintf("step %q: interrupted", sr.Name)
+			break
+		}
+		if len(sr.Breaches) > 0 {
+			rep.Stopped = true
+			rep.StopReason = fmt.Sprintf("step %q: %s", sr.Name, strings.Join(sr.Breaches, ", "))
+			break
+		}
+	}
+	rep.End = time.Now()
+	return rep, nil
+}
+
+// benchStepRun collects the measurements of a running step.
+type benchStepRun struct {
+	m      sync.Mutex
+	rep    *BenchStepReport
+	cancel context.CancelFunc
+	// -1 if the number of errors is not limited.
+	maxErrors int
+
+	syncTimes []time.Duration
+	lags      []time.Duration
+	rpcs      []time.Duration
+}
+
+func (r *benchStepRun) addError(err error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.rep.Errors++
+	if len(r.rep.ErrorMessages) < benchMaxErrorMessages {
+		r.rep.ErrorMessages = append(r.rep.ErrorMessages, err.Error())
+	}
+	if r.maxErrors >= 0 && r.rep.Errors > r.maxErrors {
+		r.cancel()
+	}
+}
+
+func (r *benchStepRun) addLag(d time.Duration) {
+	r.m.Lock()
+	r.lags = append(r.lags, d)
+	r.m.Unlock()
+}
+
+func (r *benchStepRun) addRPC(d time.Duration) {
+	r.m.Lock()
+	r.rpcs = append(r.rpcs, d)
+	r.m.Unlock()
+}
+
+func (a *App) benchStep(ctx context.Context, t *target.Target, step *config.BenchStep, req *gnmi.SubscribeRequest, getReq *gnmi.GetRequest, bp *config.BenchProfile) *BenchStepReport {
+	sctx, cancel := context.WithTimeout(ctx, step.Duration)
+	defer cancel()
+	r := &benchStepRun{
+		rep: &BenchStepReport{
+			Name:           step.Name,
+			Subscriptions:  step.Subscriptions,
+			SampleInterval: step.SampleInterval.String(),
+			Paths:          step.Paths,
+			RPC:            "capabilities",
+		},
+		cancel:    cancel,
+		maxErrors: -1,
+	}
+	if getReq != nil {
+		r.rep.RPC = "get"
+	}
+	if bp.Thresholds != nil && bp.Thresholds.MaxErrors != nil {
+		r.maxErrors = *bp.Thresholds.MaxErrors
+	}
+	start := time.Now()
+	wg := new(sync.WaitGroup)
+	wg.Add(step.Subscriptions + 1)
+	for i := range step.Subscriptions {
+		go func() {
+			defer wg.Done()
+			a.benchSubscription(sctx, t, fmt.Sprintf("%s-%d", step.Name, i+1), req, step.SampleInterval, r)
+		}()
+	}
+	go func() {
+		defer wg.Done()
+		benchRPCs(sctx, t, getReq, bp.GetInterval, r)
+	}()
+	wg.Wait()
+
+	sr := r.rep
+	sr.Duration = time.Since(start).Round(time.Millisecond).String()
+	if sr.RequestedRate > 0 {
+		sr.UpdateRatio = sr.AchievedRate / sr.RequestedRate
+	}
+	sr.SyncTime = benchLatency(r.syncTimes)
+	sr.TimestampLag = benchLatency(r.lags)
+	sr.RPCLatency = benchLatency(r.rpcs)
+	sr.Breaches = benchBreaches(sr, bp.Thresholds)
+	return sr
+}
+
+// benchSubscription runs a subscription until ctx is done
+// and adds its measurements to the step.
+func (a *App) benchSubscription(ctx context.Context, t *target.Target, name string, req *gnmi.SubscribeRequest, interval time.Duration, r *benchStepRun) {
+	start := time.Now()
+	stream, err := t.SubscribeStream(ctx, req)
+	if err != nil {
+		if ctx.Err() == nil {
+			r.addError(fmt.Errorf("subscription %s: %w", name, err))
+		}
+		return
+	}
+	var tracker *completenessTracker
+	var synced time.Time
+	// the series are learned over the first sample interval following the first
+	// notification, targets may send the sync response before their first sample.
+	var learnEnd time.Time
+	series := make(map[string]struct{})
+	var updates int
+	for {
+		rsp, err := stream.Recv()
+		now := time.Now()
+		if err != nil {
+			if ctx.Err() == nil {
+				if errors.Is(err, io.EOF) {
+					err = errors.New("closed by the target")
+				}
+				r.addError(fmt.Errorf("subscription %s: %w", name, err))
+			}
+			break
+		}
+		switch rsp := rsp.GetResponse().(type) {
+		case *gnmi.SubscribeResponse_Update:
+			n := rsp.Update
+			if tracker == nil {
+				// the intervals are aligned on the first notification.
+				tracker = newCompletenessTracker(t.Config.Name, name, interval, &config.CompletenessPolicy{}, now)
+			}
+			tracker.observe(n, now)
+			if learnEnd.IsZero() {
+				learnEnd = now.Add(interval)
+			}
+			if now.Before(learnEnd) {
+				for _, u := range n.GetUpdate() {
+					series[completenessSeriesKey(n.GetPrefix(), u.GetPath())] = struct{}{}
+				}
+			}
+			if synced.IsZero() || now.Before(learnEnd) {
+				continue
+			}
+			updates += len(n.GetUpdate())
+			if n.GetTimestamp() > 0 {
+				r.addLag(now.Sub(time.Unix(0, n.GetTimestamp())))
+			}
+		case *gnmi.SubscribeResponse_SyncResponse:
+			if synced.IsZero() {
+				synced = now
+			}
+		}
+	}
+	end := time.Now()
+
+	r.m.Lock()
+	defer r.m.Unlock()
+	if synced.IsZero() {
+		r.rep.Errors++
+		if len(r.rep.ErrorMessages) < benchMaxErrorMessages {
+			r.rep.ErrorMessages = append(r.rep.ErrorMessages, fmt.Sprintf("subscription %s: no sync response received", name))
+		}
+	} else {
+		r.rep.Synced++
+		r.syncTimes = append(r.syncTimes, synced.Sub(start))
+		r.rep.Series += len(series)
+		r.rep.RequestedRate += float64(len(series)) / interval.Seconds()
+		// the achieved rate is measured once synced and the series learned.
+		measureStart := synced
+		if learnEnd.After(measureStart) {
+			measureStart = learnEnd
+		}
+		if d := end.Sub(measureStart); d > 0 && !learnEnd.IsZero() {
+			r.rep.AchievedRate += float64(updates) / d.Seconds()
+		}
+	}
+	if tracker != nil {
+		s := tracker.report(end)
+		r.rep.Intervals += s.Intervals
+		r.rep.DroppedIntervals += s.MissingIntervals
+	}
+}
+
+// benchRPCs sends a unary RPC every interval until ctx is done,
+// a Get RPC if getReq is set, a Capabilities RPC otherwise.
+func benchRPCs(ctx context.Context, t *target.Target, getReq *gnmi.GetRequest, interval time.Duration, r *benchStepRun) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		start := time.Now()
+		rctx, cancel := context.WithTimeout(ctx, t.Config.Timeout)
+		var err error
+		if getReq != nil {
+			_, err = t.Get(rctx, getReq)
+		} else {
+			_, err = t.Capabilities(rctx)
+		}
+		cancel()
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil:
+			r.addError(fmt.Errorf("%s RPC: %w", r.rep.RPC, err))
+		default:
+			r.addRPC(time.Since(start))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// benchBreaches returns the thresholds breached by the step.
+func benchBreaches(sr *BenchStepReport, th *config.BenchThresholds) []string {
+	if th == nil {
+		return nil
+	}
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	var res []string
+	if th.MaxErrors != nil && sr.Errors > *th.MaxErrors {
+		res = append(res, fmt.Sprintf("%d error(s) exceed the max of %d", sr.Errors, *th.MaxErrors))
+	}
+	if th.MaxSyncTime > 0 {
+		switch {
+		case sr.Synced < sr.Subscriptions:
+			res = append(res, fmt.Sprintf("%d subscription(s) out of %d did not sync", sr.Subscriptions-sr.Synced, sr.Subscriptions))
+		case sr.SyncTime != nil && sr.SyncTime.Max > ms(th.MaxSyncTime):
+			res = append(res, fmt.Sprintf("sync time %.0fms exceeds the max of %s", sr.SyncTime.Max, th.MaxSyncTime))
+		}
+	}
+	// the ratio is not checked if no series were learned.
+	if th.MinUpdateRatio > 0 && sr.RequestedRate > 0 && sr.UpdateRatio < th.MinUpdateRatio {
+		res = append(res, fmt.Sprintf("update ratio %.2f is below the min of %.2f", sr.UpdateRatio, th.MinUpdateRatio))
+	}
+	if th.MaxTimestampLag > 0 && sr.TimestampLag != nil && sr.TimestampLag.P99 > ms(th.MaxTimestampLag) {
+		res = append(res, fmt.Sprintf("timestamp lag p99 %.0fms exceeds the max of %s", sr.TimestampLag.P99, th.MaxTimestampLag))
+	}
+	if th.MaxDroppedIntervals != nil && sr.DroppedIntervals > uint64(*th.MaxDroppedIntervals) {
+		res = append(res, fmt.Sprintf("%d dropped interval(s) exceed the max of %d", sr.DroppedIntervals, *th.MaxDroppedIntervals))
+	}
+	if th.MaxRPCLatency > 0 && sr.RPCLatency != nil && sr.RPCLatency.P99 > ms(th.MaxRPCLatency) {
+		res = append(res, fmt.Sprintf("%s RPC latency p99 %.0fms exceeds the max of %s", sr.RPC, sr.RPCLatency.P99, th.MaxRPCLatency))
+	}
+	return res
+}
+
+// benchLatency returns the percentiles of ds, nil if ds is empty.
+func benchLatency(ds []time.Duration) *BenchLatency {
+	if len(ds) == 0 {
+		return nil
+	}
+	ds = slices.Clone(ds)
+	slices.Sort(ds)
+	pct := func(p float64) float64 {
+		i := int(p*float64(len(ds))+0.5) - 1
+		i = max(0, min(i, len(ds)-1))
+		return float64(ds[i]) / float64(time.Millisecond)
+	}
+	return &BenchLatency{
+		Count: len(ds),
+		P50:   pct(0.50),
+		P90:   pct(0.90),
+		P99:   pct(0.99),
+		Max:   float64(ds[len(ds)-1]) / float64(time.Millisecond),
+	}
+}
+
+func printBenchReport(rep *BenchReport) {
+	lat := func(l *BenchLatency) string {
+		if l == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f/%.1f/%.1f", l.P50, l.P99, l.Max)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "target: %s, duration: %s\n\n", rep.Target, rep.End.Sub(rep.Start).Round(time.Millisecond))
+	fmt.Fprintln(w, "STEP\tSUBS\tINTERVAL\tSYNCED\tSYNC MS (P50/P99/MAX)\tSERIES\tREQUESTED UPD/S\tACHIEVED UPD/S\tRATIO\tLAG MS (P50/P99/MAX)\tDROPPED INTERVALS\tRPC MS (P50/P99/MAX)\tERRORS")
+	for _, sr := range rep.Steps {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%s\t%d\t%.1f\t%.1f\t%.2f\t%s\t%d/%d\t%s\t%d\n",
+			sr.Name, sr.Subscriptions, sr.SampleInterval, sr.Synced, lat(sr.SyncTime), sr.Series,
+			sr.RequestedRate, sr.AchievedRate, sr.UpdateRatio, lat(sr.TimestampLag),
+			sr.DroppedIntervals, sr.Intervals, lat(sr.RPCLatency), sr.Errors)
+	}
+	for _, sr := range rep.Steps {
+		for _, e := range sr.ErrorMessages {
+			fmt.Fprintf(w, "\nstep %s: error: %s", sr.Name, e)
+		}
+	}
+	if rep.Stopped {
+		fmt.Fprintf(w, "\nstopped: %s\n", rep.StopReason)
+	}
+	w.Flush()
+}
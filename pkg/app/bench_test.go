@@ -0,0 +1,266 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/api/server"
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/config"
+)
+
+// benchServer is a gNMI server sending the counters of 3 interfaces
+// at the requested sample interval.
+type benchServer struct {
+	// only send the initial sync.
+	stalled bool
+	// send the sync response before the first sample.
+	syncFirst bool
+	// fail the Get RPCs.
+	getErr error
+}
+
+func (s *benchServer) subscribe(req *gnmi.SubscribeRequest, stream gnmi.GNMI_SubscribeServer) error {
+	send := func() error {
+		return stream.Send(&gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{
+			Update: completenessNotification(time.Now(), "e1", "e2", "e3"),
+		}})
+	}
+	if !s.syncFirst {
+		err := send()
+		if err != nil {
+			return err
+		}
+	}
+	err := stream.Send(&gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_SyncResponse{SyncResponse: true}})
+	if err != nil {
+		return err
+	}
+	if s.stalled {
+		<-stream.Context().Done()
+		return nil
+	}
+	ticker := time.NewTicker(time.Duration(req.GetSubscribe().GetSubscription()[0].GetSampleInterval()))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+			err = send()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *benchServer) get(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetResponse, error) {
+	if s.getErr != nil {
+		return nil, s.getErr
+	}
+	return &gnmi.GetResponse{Notification: []*gnmi.Notification{completenessNotification(time.Now(), "e1")}}, nil
+}
+
+// startBenchServer starts bs and returns the config of a target pointing to it.
+func startBenchServer(t *testing.T, bs *benchServer) *types.TargetConfig {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	s, err := server.New(server.Config{Address: addr},
+		server.WithLogger(log.New(io.Discard, "", 0)),
+		server.WithSubscribeHandler(bs.subscribe),
+		server.WithGetHandler(bs.get),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start(context.Background())
+	insecure := true
+	return &types.TargetConfig{
+		Name:     "bench1",
+		Address:  addr,
+		Insecure: &insecure,
+		Timeout:  5 * time.Second,
+	}
+}
+
+func TestBench(t *testing.T) {
+	tc := startBenchServer(t, &benchServer{})
+	bp := &config.BenchProfile{
+		Paths: []string{"/interfaces"},
+		Ramp: &config.BenchRamp{
+			Duration:        1500 * time.Millisecond,
+			Subscriptions:   []int{1, 3},
+			SampleIntervals: []time.Duration{100 * time.Millisecond},
+		},
+		GetPaths:    []string{"/interfaces"},
+		GetInterval: 100 * time.Millisecond,
+	}
+	if err := bp.SetDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	a := New()
+	a.Config.Encoding = "json"
+	rep, err := a.Bench(context.Background(), tc, bp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Stopped || len(rep.Steps) != 2 {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+	for i, subs := range []int{1, 3} {
+		sr := rep.Steps[i]
+		if sr.Name != fmt.Sprintf("paths-1/100ms/%d", subs) {
+			t.Errorf("unexpected step name %q", sr.Name)
+		}
+		if sr.Errors != 0 {
+			t.Errorf("step %s: unexpected errors: %v", sr.Name, sr.ErrorMessages)
+		}
+		if sr.Synced != subs || sr.Series != 3*subs || sr.SyncTime == nil {
+			t.Errorf("step %s: expected %d synced subscriptions with 3 series each, got %d with %d series",
+				sr.Name, subs, sr.Synced, sr.Series)
+		}
+		if sr.RequestedRate != float64(30*subs) {
+			t.Errorf("step %s: expected a requested rate of %d, got %f", sr.Name, 30*subs, sr.RequestedRate)
+		}
+		if sr.UpdateRatio < 0.7 || sr.UpdateRatio > 1.3 {
+			t.Errorf("step %s: unexpected update ratio %f", sr.Name, sr.UpdateRatio)
+		}
+		if sr.TimestampLag == nil || sr.TimestampLag.Count == 0 {
+			t.Errorf("step %s: missing timestamp lag", sr.Name)
+		}
+		if sr.Intervals == 0 || sr.DroppedIntervals > sr.Intervals/2 {
+			t.Errorf("step %s: unexpected intervals %d/%d", sr.Name, sr.DroppedIntervals, sr.Intervals)
+		}
+		if sr.RPC != "get" || sr.RPCLatency == nil || sr.RPCLatency.Count == 0 {
+			t.Errorf("step %s: missing RPC latency", sr.Name)
+		}
+	}
+}
+
+func TestBenchSyncBeforeSample(t *testing.T) {
+	tc := startBenchServer(t, &benchServer{syncFirst: true})
+	bp := &config.BenchProfile{
+		Steps: []*config.BenchStep{
+			{SampleInterval: 100 * time.Millisecond, Duration: time.Second},
+		},
+		Paths:      []string{"/interfaces"},
+		Thresholds: &config.BenchThresholds{MinUpdateRatio: 0.7},
+	}
+	if err := bp.SetDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	a := New()
+	a.Config.Encoding = "json"
+	rep, err := a.Bench(context.Background(), tc, bp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr := rep.Steps[0]
+	if sr.Series != 3 || sr.RequestedRate != 30 {
+		t.Errorf("expected 3 series learned after the sync response, got %d", sr.Series)
+	}
+	if rep.Stopped || len(sr.Breaches) != 0 {
+		t.Errorf("unexpected breaches: %v", sr.Breaches)
+	}
+}
+
+func TestBenchThresholds(t *testing.T) {
+	t.Run("min_update_ratio", func(t *testing.T) {
+		tc := startBenchServer(t, &benchServer{stalled: true})
+		bp := &config.BenchProfile{
+			Steps: []*config.BenchStep{
+				{Subscriptions: 2, SampleInterval: 100 * time.Millisecond, Duration: time.Second},
+				{Subscriptions: 4, SampleInterval: 100 * time.Millisecond, Duration: time.Second},
+			},
+			Paths:      []string{"/interfaces"},
+			Thresholds: &config.BenchThresholds{MinUpdateRatio: 0.9},
+		}
+		if err := bp.SetDefaults(); err != nil {
+			t.Fatal(err)
+		}
+		a := New()
+		a.Config.Encoding = "json"
+		rep, err := a.Bench(context.Background(), tc, bp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rep.Stopped || len(rep.Steps) != 1 {
+			t.Fatalf("expected the bench to stop after the first step, got %+v", rep)
+		}
+		sr := rep.Steps[0]
+		if len(sr.Breaches) != 1 || !strings.Contains(sr.Breaches[0], "update ratio") {
+			t.Errorf("unexpected breaches: %v", sr.Breaches)
+		}
+		if sr.DroppedIntervals == 0 {
+			t.Errorf("expected dropped intervals")
+		}
+		if !strings.Contains(rep.StopReason, "step-1") {
+			t.Errorf("unexpected stop reason %q", rep.StopReason)
+		}
+	})
+	t.Run("max_errors", func(t *testing.T) {
+		tc := startBenchServer(t, &benchServer{getErr: errors.New("busy")})
+		maxErrors := 2
+		bp := &config.BenchProfile{
+			Steps: []*config.BenchStep{
+				{SampleInterval: 100 * time.Millisecond, Duration: 10 * time.Second},
+			},
+			Paths:       []string{"/interfaces"},
+			GetPaths:    []string{"/interfaces"},
+			GetInterval: 10 * time.Millisecond,
+			Thresholds:  &config.BenchThresholds{MaxErrors: &maxErrors},
+		}
+		if err := bp.SetDefaults(); err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		a := New()
+		a.Config.Encoding = "json"
+		rep, err := a.Bench(context.Background(), tc, bp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Errorf("expected the step to be interrupted")
+		}
+		if !rep.Stopped || rep.Steps[0].Errors < 3 || len(rep.Steps[0].Breaches) == 0 {
+			t.Fatalf("expected the bench to stop after 3 errors, got %+v", rep.Steps[0])
+		}
+	})
+}
+
+func TestBenchLatency(t *testing.T) {
+	ds := make([]time.Duration, 0, 100)
+	for i := 100; i > 0; i-- {
+		ds = append(ds, time.Duration(i)*time.Millisecond)
+	}
+	l := benchLatency(ds)
+	if l.Count != 100 || l.P50 != 50 || l.P90 != 90 || l.P99 != 99 || l.Max != 100 {
+		t.Errorf("unexpected latency: %+v", l)
+	}
+	if benchLatency(nil) != nil {
+		t.Errorf("expected nil latency")
+	}
+}
@@ -0,0 +1,394 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/path"
+)
+
+const (
+	defaultCaptureDuration = 10 * time.Second
+	maxCaptureDuration     = 10 * time.Minute
+	captureBufferSize      = 1000
+
+	captureFormatProtoJSON = "protojson"
+	captureFormatProto     = "proto"
+)
+
+// CaptureRequest selects the subscribe responses
+// captured from a target and the capture limits.
+type CaptureRequest struct {
+	// subscriptions to capture, all subscriptions if empty.
+	Subscriptions []string `json:"subscriptions,omitempty"`
+	// paths to capture, all paths if empty.
+	Paths []string `json:"paths,omitempty"`
+	// capture duration, defaults to 10s.
+	Duration string `json:"duration,omitempty"`
+	// maximum number of captured responses.
+	Count int `json:"count,omitempty"`
+	// protojson or proto, defaults to protojson.
+	Format string `json:"format,omitempty"`
+}
+
+// capturedResponse is a subscribe response received from a target.
+type capturedResponse struct {
+	Received     time.Time
+	Subscription string
+	Response     *gnmi.SubscribeResponse
+}
+
+// captureTap receives a copy of the subscribe responses
+// of a target matching its filters.
+type captureTap struct {
+	target        string
+	subscriptions map[string]struct{}
+	paths         []*gnmi.Path
+	ch            chan *capturedResponse
+
+	m       sync.Mutex
+	dropped int
+}
+
+// captureTaps is the set of active captures.
+type captureTaps struct {
+	m    sync.RWMutex
+	taps map[*captureTap]struct{}
+}
+
+func newCaptureTaps() *captureTaps {
+	return &captureTaps{taps: make(map[*captureTap]struct{})}
+}
+
+func (c *captureTaps) add(t *captureTap) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.taps[t] = struct{}{}
+}
+
+func (c *captureTaps) delete(t *captureTap) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	delete(c.taps, t)
+}
+
+// publish hands a copy of the response to the matching taps.
+// It never blocks, the responses a tap is not ready to receive are dropped.
+func (c *captureTaps) publish(target, sub string, rsp *gnmi.SubscribeResponse) {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	if len(c.taps) == 0 {
+		return
+	}
+	now := time.Now()
+	var cp *gnmi.SubscribeResponse
+	for t := range c.taps {
+		if !t.match(target, sub, rsp) {
+			continue
+		}
+		if cp == nil {
+			cp = proto.Clone(rsp).(*gnmi.SubscribeResponse)
+		}
+		select {
+		case t.ch <- &capturedResponse{Received: now, Subscription: sub, Response: cp}:
+		default:
+			t.m.Lock()
+			t.dropped++
+			t.m.Unlock()
+		}
+	}
+}
+
+func (t *captureTap) match(target, sub string, rsp *gnmi.SubscribeResponse) bool {
+	if target != t.target {
+		return false
+	}
+	if len(t.subscriptions) > 0 {
+		if _, ok := t.subscriptions[sub]; !ok {
+			return false
+		}
+	}
+	if len(t.paths) == 0 {
+		return true
+	}
+	n := rsp.GetUpdate()
+	if n == nil {
+		// sync responses are kept as stream markers
+		return true
+	}
+	for _, u := range n.GetUpdate() {
+		if t.matchPath(n.GetPrefix(), u.GetPath()) {
+			return true
+		}
+	}
+	for _, d := range n.GetDelete() {
+		if t.matchPath(n.GetPrefix(), d) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath reports whether the prefix and path elements start with
+// the elements of one of the tap paths. A "*" name or key value matches any.
+func (t *captureTap) matchPath(prefix, p *gnmi.Path) bool {
+	elems := make([]*gnmi.PathElem, 0, len(prefix.GetElem())+len(p.GetElem()))
+	elems = append(elems, prefix.GetElem()...)
+	elems = append(elems, p.GetElem()...)
+OUTER:
+	for _, fp := range t.paths {
+		if len(fp.GetElem()) > len(elems) {
+			continue
+		}
+		for i, fe := range fp.GetElem() {
+			if fe.GetName() != "*" && fe.GetName() != elems[i].GetName() {
+				continue OUTER
+			}
+			for k, v := range fe.GetKey() {
+				if v != "*" && elems[i].GetKey()[k] != v {
+					continue OUTER
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func newCaptureTap(target string, req *CaptureRequest) (*captureTap, error) {
+	t := &captureTap{
+		target: target,
+		paths:  make([]*gnmi.Path, 0, len(req.Paths)),
+		ch:     make(chan *capturedResponse, captureBufferSize),
+	}
+	if len(req.Subscriptions) > 0 {
+		t.subscriptions = make(map[string]struct{}, len(req.Subscriptions))
+		for _, s := range req.Subscriptions {
+			t.subscriptions[s] = struct{}{}
+		}
+	}
+	for _, p := range req.Paths {
+		gp, err := path.ParsePath(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %q: %v", p, err)
+		}
+		t.paths = append(t.paths, gp)
+	}
+	return t, nil
+}
+
+func (req *CaptureRequest) validate() (time.Duration, error) {
+	switch req.Format {
+	case "":
+		req.Format = captureFormatProtoJSON
+	case captureFormatProtoJSON, captureFormatProto:
+	default:
+		return 0, fmt.Errorf("unknown capture format %q", req.Format)
+	}
+	if req.Count < 0 {
+		return 0, errors.New("capture count must be positive")
+	}
+	if req.Duration == "" {
+		return defaultCaptureDuration, nil
+	}
+	d, err := time.ParseDuration(req.Duration)
+	if err != nil {
+		return 0, fmt.Errorf("invalid capture duration: %v", err)
+	}
+	if d <= 0 || d > maxCaptureDuration {
+		return 0, fmt.Errorf("capture duration must be between 0 and %s", maxCaptureDuration)
+	}
+	return d, nil
+}
+
+// captureWriter writes the captured responses in one of the capture formats.
+type captureWriter interface {
+	write(*capturedResponse) error
+}
+
+// protoJSONCaptureWriter writes one JSON object per line.
+type protoJSONCaptureWriter struct {
+	w io.Writer
+}
+
+type protoJSONCaptureRecord struct {
+	Received     time.Time       `json:"received"`
+	Subscription string          `json:"subscription-name,omitempty"`
+	Response     json.RawMessage `json:"response"`
+}
+
+func (c *protoJSONCaptureWriter) write(r *capturedResponse) error {
+	b, err := protojson.Marshal(r.Response)
+	if err != nil {
+		return err
+	}
+	b, err = json.Marshal(&protoJSONCaptureRecord{
+		Received:     r.Received,
+		Subscription: r.Subscription,
+		Response:     b,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.w.Write(append(b, '\n'))
+	return err
+}
+
+// protoCaptureWriter writes length delimited records, each record is
+// the wire encoding of the message:
+//
+//	message CaptureRecord {
+//	  int64 received = 1; // receive timestamp in nanoseconds
+//	  string subscription_name = 2;
+//	  gnmi.SubscribeResponse response = 3;
+//	}
+type protoCaptureWriter struct {
+	w io.Writer
+}
+
+func (c *protoCaptureWriter) write(r *capturedResponse) error {
+	rb, err := proto.Marshal(r.Response)
+	if err != nil {
+		return err
+	}
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(r.Received.UnixNano()))
+	if r.Subscription != "" {
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		b = protowire.AppendString(b, r.Subscription)
+	}
+	b = protowire.AppendTag(b, 3, protowire.BytesType)
+	b = protowire.AppendBytes(b, rb)
+	_, err = c.w.Write(protowire.AppendBytes(nil, b))
+	return err
+}
+
+func (a *App) handleTargetsCapture(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	defer r.Body.Close()
+	req := new(CaptureRequest)
+	if len(body) > 0 {
+		err = json.Unmarshal(body, req)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+			return
+		}
+	}
+	d, err := req.validate()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	a.operLock.RLock()
+	_, ok := a.Targets[id]
+	a.operLock.RUnlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("target %q not found", id)}})
+		return
+	}
+	tap, err := newCaptureTap(id, req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	// the capture outlives the API server write timeout
+	rc := http.NewResponseController(w)
+	if a.Config.APIServer != nil {
+		_ = rc.SetWriteDeadline(time.Now().Add(d + a.Config.APIServer.Timeout))
+	}
+
+	var cw captureWriter
+	filename := fmt.Sprintf("%s-%s", sanitizeFilename(id), time.Now().UTC().Format("20060102T150405Z"))
+	switch req.Format {
+	case captureFormatProto:
+		w.Header().Set("Content-Type", "application/octet-stream")
+		filename += ".pb"
+		cw = &protoCaptureWriter{w: w}
+	default:
+		w.Header().Set("Content-Type", "application/jsonl")
+		filename += ".jsonl"
+		cw = &protoJSONCaptureWriter{w: w}
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	a.captures.add(tap)
+	defer a.captures.delete(tap)
+	a.Logger.Printf("target %q: starting capture for %s, count=%d, subscriptions=%v, paths=%v",
+		id, d, req.Count, req.Subscriptions, req.Paths)
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	count := 0
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-a.ctx.Done():
+			return
+		case <-timer.C:
+			a.logCaptureDone(tap, count)
+			return
+		case cr := <-tap.ch:
+			err = cw.write(cr)
+			if err != nil {
+				a.Logger.Printf("target %q: capture failed: %v", id, err)
+				return
+			}
+			count++
+			if req.Count > 0 && count >= req.Count {
+				a.logCaptureDone(tap, count)
+				return
+			}
+			_ = rc.Flush()
+		}
+	}
+}
+
+func (a *App) logCaptureDone(tap *captureTap, count int) {
+	tap.m.Lock()
+	defer tap.m.Unlock()
+	a.Logger.Printf("target %q: capture done, captured=%d, dropped=%d", tap.target, count, tap.dropped)
+}
+
+func sanitizeFilename(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
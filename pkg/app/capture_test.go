@@ -0,0 +1,183 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+func updateResponse(prefix string, paths ...string) *gnmi.SubscribeResponse {
+	n := &gnmi.Notification{Timestamp: 1}
+	if prefix != "" {
+		n.Prefix = &gnmi.Path{Elem: []*gnmi.PathElem{{Name: prefix}}}
+	}
+	for _, p := range paths {
+		n.Update = append(n.Update, &gnmi.Update{
+			Path: &gnmi.Path{Elem: []*gnmi.PathElem{
+				{Name: "interface", Key: map[string]string{"name": p}},
+				{Name: "state"},
+			}},
+			Val: &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 1}},
+		})
+	}
+	return &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: n}}
+}
+
+func TestCaptureTapMatch(t *testing.T) {
+	syncRsp := &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_SyncResponse{SyncResponse: true}}
+	tests := map[string]struct {
+		req    *CaptureRequest
+		target string
+		sub    string
+		rsp    *gnmi.SubscribeResponse
+		match  bool
+	}{
+		"no_filter": {
+			req:    &CaptureRequest{},
+			target: "t1",
+			rsp:    updateResponse("", "e1"),
+			match:  true,
+		},
+		"other_target": {
+			req:    &CaptureRequest{},
+			target: "t2",
+			rsp:    updateResponse("", "e1"),
+		},
+		"subscription": {
+			req:    &CaptureRequest{Subscriptions: []string{"sub1"}},
+			target: "t1",
+			sub:    "sub2",
+			rsp:    updateResponse("", "e1"),
+		},
+		"path_key": {
+			req:    &CaptureRequest{Paths: []string{"/interface[name=e2]"}},
+			target: "t1",
+			rsp:    updateResponse("", "e1", "e2"),
+			match:  true,
+		},
+		"path_key_mismatch": {
+			req:    &CaptureRequest{Paths: []string{"/interface[name=e3]"}},
+			target: "t1",
+			rsp:    updateResponse("", "e1", "e2"),
+		},
+		"path_wildcard_with_prefix": {
+			req:    &CaptureRequest{Paths: []string{"/interfaces/*/state"}},
+			target: "t1",
+			rsp:    updateResponse("interfaces", "e1"),
+			match:  true,
+		},
+		"sync_response": {
+			req:    &CaptureRequest{Paths: []string{"/system"}},
+			target: "t1",
+			rsp:    syncRsp,
+			match:  true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tap, err := newCaptureTap("t1", tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tap.match(tt.target, tt.sub, tt.rsp); got != tt.match {
+				t.Errorf("expected match=%v, got %v", tt.match, got)
+			}
+		})
+	}
+}
+
+func TestCaptureTapsPublish(t *testing.T) {
+	c := newCaptureTaps()
+	tap, err := newCaptureTap("t1", &CaptureRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tap.ch = make(chan *capturedResponse, 1)
+	c.add(tap)
+	rsp := updateResponse("", "e1")
+	c.publish("t1", "sub1", rsp)
+	// the tap buffer is full, the response is dropped
+	c.publish("t1", "sub1", rsp)
+	cr := <-tap.ch
+	if cr.Subscription != "sub1" || !proto.Equal(cr.Response, rsp) {
+		t.Errorf("unexpected captured response: %+v", cr)
+	}
+	if cr.Response == rsp {
+		t.Errorf("expected the captured response to be a copy")
+	}
+	if tap.dropped != 1 {
+		t.Errorf("expected 1 dropped response, got %d", tap.dropped)
+	}
+	c.delete(tap)
+	c.publish("t1", "sub1", rsp)
+	if len(tap.ch) != 0 {
+		t.Errorf("expected no response after the tap is deleted")
+	}
+}
+
+func TestProtoCaptureWriter(t *testing.T) {
+	b := new(bytes.Buffer)
+	cw := &protoCaptureWriter{w: b}
+	ts := time.Unix(0, 42)
+	rsp := updateResponse("", "e1")
+	for i := 0; i < 2; i++ {
+		if err := cw.write(&capturedResponse{Received: ts, Subscription: "sub1", Response: rsp}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data := b.Bytes()
+	records := 0
+	for len(data) > 0 {
+		rec, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		data = data[n:]
+		records++
+		for len(rec) > 0 {
+			num, typ, n := protowire.ConsumeTag(rec)
+			rec = rec[n:]
+			switch {
+			case num == 1 && typ == protowire.VarintType:
+				v, n := protowire.ConsumeVarint(rec)
+				rec = rec[n:]
+				if int64(v) != ts.UnixNano() {
+					t.Errorf("unexpected timestamp %d", v)
+				}
+			case num == 2 && typ == protowire.BytesType:
+				v, n := protowire.ConsumeString(rec)
+				rec = rec[n:]
+				if v != "sub1" {
+					t.Errorf("unexpected subscription name %q", v)
+				}
+			case num == 3 && typ == protowire.BytesType:
+				v, n := protowire.ConsumeBytes(rec)
+				rec = rec[n:]
+				got := new(gnmi.SubscribeResponse)
+				if err := proto.Unmarshal(v, got); err != nil {
+					t.Fatal(err)
+				}
+				if !proto.Equal(got, rsp) {
+					t.Errorf("unexpected response %v", got)
+				}
+			default:
+				t.Fatalf("unexpected field %d", num)
+			}
+		}
+	}
+	if records != 2 {
+		t.Errorf("expected 2 records, got %d", records)
+	}
+}
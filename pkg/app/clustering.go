@@ -17,6 +17,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"sort"
 	"strconv"
@@ -153,6 +154,19 @@ START:
 	defer cancel()
 	go func() {
 		go a.watchMembers(ctx)
+		// the paused subscriptions are loaded before the targets are dispatched.
+		for {
+			err := a.loadPausedSubscriptions(ctx)
+			if err == nil {
+				break
+			}
+			a.Logger.Printf("failed to load the paused subscriptions: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryTimer):
+			}
+		}
 		a.Logger.Printf("leader waiting %s before dispatching targets", a.Config.Clustering.LeaderWaitTimer)
 		time.Sleep(a.Config.Clustering.LeaderWaitTimer)
 		a.Logger.Printf("leader done waiting, starting loader and dispatching targets")
@@ -623,7 +637,6 @@ func (a *App) deleteTarget(ctx context.Context, name string) error {
 }
 
 func (a *App) assignTarget(ctx context.Context, tc *types.TargetConfig, service *lockers.Service) error {
-	// encode target config
 	buffer := new(bytes.Buffer)
 	err := json.NewEncoder(buffer).Encode(tc)
 	if err != nil {
@@ -633,8 +646,17 @@ func (a *App) assignTarget(ctx context.Context, tc *types.TargetConfig, service
 	if err != nil {
 		return err
 	}
+	// the paused subscriptions are sent along with the target config
+	q := make(url.Values)
+	for _, sub := range a.pausedSubs.get(tc.Name) {
+		q.Add(pausedSubscriptionParam, sub)
+	}
 	scheme := a.getServiceScheme(service)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s://%s/api/v1/config/targets", scheme, service.Address), buffer)
+	u := fmt.Sprintf("%s://%s/api/v1/config/targets", scheme, service.Address)
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, buffer)
 	if err != nil {
 		return err
 	}
@@ -22,6 +22,7 @@ import (
 	"github.com/openconfig/gnmic/pkg/api/target"
 	"github.com/openconfig/gnmic/pkg/api/types"
 	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
 	"github.com/openconfig/gnmic/pkg/outputs"
 )
 
@@ -63,10 +64,18 @@ func (a *App) StartCollector(ctx context.Context) {
 			remainingOnceSubscriptions := numOnceSubscriptions
 			numSubscriptions := len(t.Subscriptions)
 			rspChan, errChan := t.ReadSubscriptions()
+			// set when a subscription fails, the target facts
+			// are refreshed when it starts receiving responses again.
+			factsStale := false
 			for {
 				select {
 				case rsp := <-rspChan:
+					if factsStale && a.factsGatherer != nil {
+						factsStale = false
+						go a.gatherFacts(ctx, t)
+					}
 					subscribeResponseReceivedCounter.WithLabelValues(t.Config.Name, rsp.SubscriptionConfig.Name).Add(1)
+					a.rollouts.observeResponse(t.Config.Name, rsp.SubscriptionName, rsp.Response)
 					if a.Config.Debug {
 						a.Logger.Printf("target %q: gNMI Subscribe Response: %+v", t.Config.Name, rsp)
 					}
@@ -75,6 +84,8 @@ func (a *App) StartCollector(ctx context.Context) {
 						a.Logger.Printf("target %q: failed to decode proto bytes: %v", t.Config.Name, err)
 						continue
 					}
+					a.captures.publish(t.Config.Name, rsp.SubscriptionName, rsp.Response)
+					a.completeness.observe(t.Config.Name, rsp.SubscriptionConfig, rsp.Response)
 					m := outputs.Meta{
 						"source":            t.Config.Name,
 						"format":            a.Config.Format,
@@ -86,6 +97,9 @@ func (a *App) StartCollector(ctx context.Context) {
 					for k, v := range t.Config.EventTags {
 						m[k] = v
 					}
+					for k, v := range a.factsTags(t.Config.Name) {
+						m[k] = v
+					}
 
 					// Allow overridden outputs per subscription
 					// If both target and subscription have a specified Output, the subscription's Output will be used
@@ -112,10 +126,12 @@ func (a *App) StartCollector(ctx context.Context) {
 						return
 					}
 				case tErr := <-errChan:
+					factsStale = true
 					if errors.Is(tErr.Err, io.EOF) {
 						a.Logger.Printf("target %q: subscription %s closed stream(EOF)", t.Config.Name, tErr.SubscriptionName)
 					} else {
 						subscribeResponseFailedCounter.WithLabelValues(t.Config.Name, tErr.SubscriptionName).Inc()
+						a.rollouts.observeError(t.Config.Name, tErr.SubscriptionName, tErr.Err)
 						a.Logger.Printf("target %q: subscription %s rcv error: %v", t.Config.Name, tErr.SubscriptionName, tErr.Err)
 					}
 					if remainingOnceSubscriptions > 0 {
@@ -155,16 +171,24 @@ func (a *App) Export(ctx context.Context, rsp *gnmi.SubscribeResponse, m outputs
 	}
 	go a.updateCache(ctx, rsp, m)
 	wg := new(sync.WaitGroup)
-	// target has no explicitly defined outputs
+	// target has no explicitly defined outputs,
+	// output group members receive the message through their group.
 	if len(outs) == 0 {
-		wg.Add(len(a.Outputs))
-		for _, o := range a.Outputs {
-			go func(o outputs.Output) {
+		a.operLock.RLock()
+		members := outputs.GroupMembers(a.Outputs)
+		all := make(map[string]outputs.Output, len(a.Outputs))
+		for name, o := range a.Outputs {
+			if _, ok := members[name]; !ok {
+				all[name] = o
+			}
+		}
+		a.operLock.RUnlock()
+		wg.Add(len(all))
+		for name, o := range all {
+			go func(name string, o outputs.Output) {
 				defer wg.Done()
-				defer a.operLock.RUnlock()
-				a.operLock.RLock()
 				o.Write(ctx, rsp, m)
-			}(o)
+			}(name, o)
 		}
 		wg.Wait()
 		return
@@ -174,10 +198,10 @@ func (a *App) Export(ctx context.Context, rsp *gnmi.SubscribeResponse, m outputs
 		a.operLock.RLock()
 		if o, ok := a.Outputs[name]; ok {
 			wg.Add(1)
-			go func(o outputs.Output) {
+			go func(name string, o outputs.Output) {
 				defer wg.Done()
 				o.Write(ctx, rsp, m)
-			}(o)
+			}(name, o)
 		}
 		a.operLock.RUnlock()
 	}
@@ -206,10 +230,38 @@ func (a *App) updateCache(ctx context.Context, rsp *gnmi.SubscribeResponse, m ou
 			a.Logger.Printf("updating target %q cache", target)
 		}
 		sub := m["subscription-name"]
+		if len(a.cacheEvps) > 0 {
+			a.updateCacheProcessed(ctx, sub, target, &gnmi.SubscribeResponse{Response: r}, m)
+			return
+		}
 		a.c.Write(ctx, sub, &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: r.Update}})
 	}
 }
 
+// updateCacheProcessed applies the gnmi-server event processors to the response
+// and writes the resulting events to the cache as gNMI notifications.
+func (a *App) updateCacheProcessed(ctx context.Context, sub, target string, rsp *gnmi.SubscribeResponse, m outputs.Meta) {
+	evs, err := formatters.ResponseToEventMsgs(sub, rsp, m, a.cacheEvps...)
+	if err != nil {
+		a.Logger.Printf("failed to convert response to events: %v", err)
+		return
+	}
+	for _, ev := range evs {
+		n, err := formatters.EventToNotification(ev, a.Config.GnmiServer.EventMapping)
+		if err != nil {
+			a.Logger.Printf("failed to convert event to notification: %v", err)
+			continue
+		}
+		if n == nil {
+			continue
+		}
+		if n.GetPrefix().GetTarget() == "" {
+			n.Prefix.Target = target
+		}
+		a.c.Write(ctx, sub, &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: n}})
+	}
+}
+
 func (a *App) subscriptionMode(name string) string {
 	if sub, ok := a.Config.Subscriptions[name]; ok {
 		return strings.ToUpper(sub.Mode)
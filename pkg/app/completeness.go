@@ -0,0 +1,657 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/openconfig/gnmic/pkg/api/path"
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/config"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	completenessStateLearning = "learning"
+	completenessStateTracking = "tracking"
+
+	// period at which the closed intervals are evaluated.
+	completenessEvalPeriod = time.Second
+	// maximum number of missing series returned by the API.
+	completenessMaxMissingSeries = 10
+)
+
+var completenessLabels = []string{"source", "subscription"}
+
+var completenessExpectedSamples = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "completeness",
+	Name:      "expected_samples_total",
+	Help:      "Total number of samples expected per target and subscription",
+}, completenessLabels)
+
+var completenessReceivedSamples = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "completeness",
+	Name:      "received_samples_total",
+	Help:      "Total number of expected samples received per target and subscription",
+}, completenessLabels)
+
+var completenessLateSamples = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "completeness",
+	Name:      "late_samples_total",
+	Help:      "Total number of samples received later than max-lag per target and subscription",
+}, completenessLabels)
+
+var completenessIntervals = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "completeness",
+	Name:      "intervals_total",
+	Help:      "Total number of evaluated intervals per target and subscription",
+}, completenessLabels)
+
+var completenessMissingIntervals = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "completeness",
+	Name:      "missing_intervals_total",
+	Help:      "Total number of intervals with missing samples per target and subscription",
+}, completenessLabels)
+
+var completenessRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "completeness",
+	Name:      "ratio",
+	Help:      "Ratio of received to expected samples during the last report interval",
+}, completenessLabels)
+
+var completenessLateRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "completeness",
+	Name:      "late_ratio",
+	Help:      "Ratio of late to received samples during the last report interval",
+}, completenessLabels)
+
+var completenessExpectedSeries = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "completeness",
+	Name:      "expected_series",
+	Help:      "Number of series expected per interval",
+}, completenessLabels)
+
+func completenessMetrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		completenessExpectedSamples,
+		completenessReceivedSamples,
+		completenessLateSamples,
+		completenessIntervals,
+		completenessMissingIntervals,
+		completenessRatio,
+		completenessLateRatio,
+		completenessExpectedSeries,
+	}
+}
+
+// CompletenessStats counts the samples expected and received from a target subscription.
+type CompletenessStats struct {
+	Intervals        uint64 `json:"intervals"`
+	MissingIntervals uint64 `json:"missing-intervals"`
+	ExpectedSamples  uint64 `json:"expected-samples"`
+	ReceivedSamples  uint64 `json:"received-samples"`
+	LateSamples      uint64 `json:"late-samples"`
+	// received to expected samples ratio.
+	Completeness float64 `json:"completeness"`
+	// late to received samples ratio.
+	LateRatio float64 `json:"late-ratio"`
+}
+
+func (s *CompletenessStats) add(o *CompletenessStats) {
+	s.Intervals += o.Intervals
+	s.MissingIntervals += o.MissingIntervals
+	s.ExpectedSamples += o.ExpectedSamples
+	s.ReceivedSamples += o.ReceivedSamples
+	s.LateSamples += o.LateSamples
+}
+
+func (s *CompletenessStats) setRatios() {
+	s.Completeness, s.LateRatio = 0, 0
+	if s.ExpectedSamples > 0 {
+		s.Completeness = float64(s.ReceivedSamples) / float64(s.ExpectedSamples)
+	}
+	if s.ReceivedSamples > 0 {
+		s.LateRatio = min(float64(s.LateSamples)/float64(s.ReceivedSamples), 1)
+	}
+}
+
+// CompletenessStatus is the completeness of a target subscription returned by the API.
+type CompletenessStatus struct {
+	Target         string `json:"target"`
+	Subscription   string `json:"subscription"`
+	State          string `json:"state"`
+	Interval       string `json:"interval"`
+	ExpectedSeries int    `json:"expected-series"`
+	// time the tracking started, after the learning intervals.
+	Since       time.Time `json:"since,omitzero"`
+	LastMissing time.Time `json:"last-missing,omitzero"`
+	// series missing from the last interval with missing samples.
+	MissingSeries []string `json:"missing-series,omitempty"`
+	// counters since the tracking started.
+	Total *CompletenessStats `json:"total"`
+	// counters of the last report interval.
+	LastReport *CompletenessStats `json:"last-report,omitempty"`
+}
+
+// completenessTracker tracks the samples received from a target subscription.
+//
+// The intervals are aligned on the reception of the first sample: interval n
+// spans interval/2 on each side of start+n*interval, a series is complete in
+// an interval if at least one of its samples was received during it.
+// A series not received for expireIntervals, while other series are, is
+// no longer expected.
+type completenessTracker struct {
+	m              sync.Mutex
+	target         string
+	subscription   string
+	interval       time.Duration
+	maxLag         time.Duration
+	expected       int
+	learnIntervals int
+	// 0 disables the series expiration.
+	expireIntervals int
+
+	start time.Time
+	since time.Time
+	// next interval to evaluate
+	next int64
+	// latest interval any sample was received in
+	latest int64
+	// last interval each known series was received in
+	series map[string]int64
+
+	total      CompletenessStats
+	window     CompletenessStats
+	lastReport *CompletenessStats
+
+	lastMissing   time.Time
+	missingSeries []string
+}
+
+func newCompletenessTracker(target, sub string, interval time.Duration, p *config.CompletenessPolicy, now time.Time) *completenessTracker {
+	t := &completenessTracker{
+		target:          target,
+		subscription:    sub,
+		interval:        interval,
+		maxLag:          p.MaxLag,
+		expected:        p.ExpectedSeries,
+		learnIntervals:  p.LearnIntervals,
+		expireIntervals: p.ExpireIntervals,
+		start:           now,
+		latest:          -1,
+		series:          make(map[string]int64),
+	}
+	if t.maxLag <= 0 {
+		t.maxLag = interval
+	}
+	if t.expected > 0 {
+		t.learnIntervals = 0
+		t.since = now
+	}
+	return t
+}
+
+func (t *completenessTracker) learning() bool {
+	return t.next < int64(t.learnIntervals)
+}
+
+// intervalOf returns the interval a sample received at ts belongs to.
+func (t *completenessTracker) intervalOf(ts time.Time) int64 {
+	return int64((ts.Sub(t.start) + t.interval/2) / t.interval)
+}
+
+func (t *completenessTracker) observe(n *gnmi.Notification, now time.Time) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	// the intervals before the current one are closed.
+	t.evaluate(now)
+	cur := t.intervalOf(now)
+	lagged := n.GetTimestamp() > 0 && now.Sub(time.Unix(0, n.GetTimestamp())) > t.maxLag
+	for _, u := range n.GetUpdate() {
+		key := completenessSeriesKey(n.GetPrefix(), u.GetPath())
+		last, ok := t.series[key]
+		if ok && last == cur {
+			continue
+		}
+		if !ok && !t.learning() && t.expected > 0 && len(t.series) >= t.expected {
+			// unexpected series, only the expected number of series is tracked.
+			continue
+		}
+		t.series[key] = cur
+		t.latest = cur
+		if lagged && !t.learning() {
+			t.window.LateSamples++
+			completenessLateSamples.WithLabelValues(t.target, t.subscription).Inc()
+		}
+	}
+}
+
+// evaluate accounts for the intervals closed at now.
+func (t *completenessTracker) evaluate(now time.Time) {
+	due := t.intervalOf(now) - 1
+	if t.next > due {
+		return
+	}
+	defer t.expire()
+	for t.next <= due {
+		if t.learning() {
+			t.next++
+			if !t.learning() {
+				t.since = now
+			}
+			continue
+		}
+		if t.latest < t.next {
+			// nothing received since the next interval, all the remaining ones are missing.
+			t.account(uint64(due-t.next+1), 0)
+			t.next = due + 1
+			break
+		}
+		received := 0
+		for _, last := range t.series {
+			if last == t.next {
+				received++
+			}
+		}
+		t.account(1, received)
+		t.next++
+	}
+}
+
+// account adds count intervals during which received series were received.
+func (t *completenessTracker) account(count uint64, received int) {
+	expected := t.expectedSeries()
+	if expected == 0 {
+		return
+	}
+	received = min(received, expected)
+	s := &CompletenessStats{
+		Intervals:       count,
+		ExpectedSamples: count * uint64(expected),
+		ReceivedSamples: count * uint64(received),
+	}
+	if received < expected {
+		s.MissingIntervals = count
+		t.lastMissing = t.start.Add(time.Duration(t.next+int64(count)-1) * t.interval)
+		t.missingSeries = t.missing()
+	}
+	t.window.add(s)
+	completenessIntervals.WithLabelValues(t.target, t.subscription).Add(float64(s.Intervals))
+	completenessMissingIntervals.WithLabelValues(t.target, t.subscription).Add(float64(s.MissingIntervals))
+	completenessExpectedSamples.WithLabelValues(t.target, t.subscription).Add(float64(s.ExpectedSamples))
+	completenessReceivedSamples.WithLabelValues(t.target, t.subscription).Add(float64(s.ReceivedSamples))
+}
+
+// expire removes the series missing from the last expireIntervals closed intervals.
+// The series received last are kept, a target sending nothing keeps all its series expected.
+func (t *completenessTracker) expire() {
+	if t.expireIntervals <= 0 || t.learning() {
+		return
+	}
+	for k, last := range t.series {
+		if last < t.latest && last < t.next-int64(t.expireIntervals) {
+			delete(t.series, k)
+		}
+	}
+}
+
+func (t *completenessTracker) expectedSeries() int {
+	if t.expected > 0 {
+		return t.expected
+	}
+	return len(t.series)
+}
+
+// missing returns the known series not received in the interval being evaluated.
+func (t *completenessTracker) missing() []string {
+	res := make([]string, 0)
+	for k, last := range t.series {
+		if last < t.next {
+			res = append(res, k)
+		}
+	}
+	sort.Strings(res)
+	if len(res) > completenessMaxMissingSeries {
+		res = res[:completenessMaxMissingSeries]
+	}
+	return res
+}
+
+// report closes the current report interval and returns its counters,
+// it returns nil while the series are being learned.
+func (t *completenessTracker) report(now time.Time) *CompletenessStats {
+	t.m.Lock()
+	defer t.m.Unlock()
+	t.evaluate(now)
+	if t.learning() {
+		return nil
+	}
+	s := t.window
+	s.setRatios()
+	t.total.add(&t.window)
+	t.window = CompletenessStats{}
+	t.lastReport = &s
+	return &s
+}
+
+func (t *completenessTracker) status() *CompletenessStatus {
+	t.m.Lock()
+	defer t.m.Unlock()
+	st := &CompletenessStatus{
+		Target:         t.target,
+		Subscription:   t.subscription,
+		State:          completenessStateTracking,
+		Interval:       t.interval.String(),
+		ExpectedSeries: t.expectedSeries(),
+		Since:          t.since,
+		LastMissing:    t.lastMissing,
+		MissingSeries:  t.missingSeries,
+		Total:          new(CompletenessStats),
+	}
+	if t.learning() {
+		st.State = completenessStateLearning
+	}
+	*st.Total = t.total
+	st.Total.add(&t.window)
+	st.Total.setRatios()
+	if t.lastReport != nil {
+		lr := *t.lastReport
+		st.LastReport = &lr
+	}
+	return st
+}
+
+func completenessSeriesKey(prefix, p *gnmi.Path) string {
+	return path.GnmiPathToXPath(&gnmi.Path{
+		Origin: p.GetOrigin(),
+		Elem:   path.PathElems(prefix, p),
+	}, false)
+}
+
+// completenessTrackers holds the trackers of the target subscriptions.
+type completenessTrackers struct {
+	m   sync.RWMutex
+	cfg *config.Completeness
+	// a nil tracker marks a subscription that is not tracked.
+	trackers map[string]map[string]*completenessTracker
+}
+
+func newCompletenessTrackers() *completenessTrackers {
+	return &completenessTrackers{trackers: make(map[string]map[string]*completenessTracker)}
+}
+
+func (c *completenessTrackers) setConfig(cfg *config.Completeness) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.cfg = cfg
+}
+
+// observe records the samples of a subscribe response received from target.
+func (c *completenessTrackers) observe(target string, sc *types.SubscriptionConfig, rsp *gnmi.SubscribeResponse) {
+	n := rsp.GetUpdate()
+	if n == nil || sc == nil {
+		return
+	}
+	c.m.RLock()
+	if c.cfg == nil {
+		c.m.RUnlock()
+		return
+	}
+	t, ok := c.trackers[target][sc.Name]
+	c.m.RUnlock()
+	if !ok {
+		t = c.newTracker(target, sc)
+	}
+	if t == nil {
+		return
+	}
+	t.observe(n, time.Now())
+}
+
+func (c *completenessTrackers) newTracker(target string, sc *types.SubscriptionConfig) *completenessTracker {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if t, ok := c.trackers[target][sc.Name]; ok {
+		return t
+	}
+	if c.trackers[target] == nil {
+		c.trackers[target] = make(map[string]*completenessTracker)
+	}
+	p := c.cfg.Policy(sc.Name)
+	interval := p.Interval
+	if interval <= 0 {
+		interval = subscriptionSampleInterval(sc)
+	}
+	if p.Disable || interval <= 0 {
+		c.trackers[target][sc.Name] = nil
+		return nil
+	}
+	t := newCompletenessTracker(target, sc.Name, interval, p, time.Now())
+	c.trackers[target][sc.Name] = t
+	return t
+}
+
+// subscriptionSampleInterval returns the sample interval of a stream subscription,
+// it returns 0 if the subscription is not a sample subscription or if its
+// stream subscriptions do not share the same sample interval.
+func subscriptionSampleInterval(sc *types.SubscriptionConfig) time.Duration {
+	if !strings.EqualFold(sc.Mode, "STREAM") {
+		return 0
+	}
+	if len(sc.StreamSubscriptions) == 0 {
+		if !strings.EqualFold(sc.StreamMode, "SAMPLE") || sc.SampleInterval == nil {
+			return 0
+		}
+		return *sc.SampleInterval
+	}
+	var interval time.Duration
+	for _, ss := range sc.StreamSubscriptions {
+		if !strings.EqualFold(ss.StreamMode, "SAMPLE") || ss.SampleInterval == nil {
+			return 0
+		}
+		if interval != 0 && *ss.SampleInterval != interval {
+			return 0
+		}
+		interval = *ss.SampleInterval
+	}
+	return interval
+}
+
+// list returns the trackers status, filtered by target and subscription if not empty.
+func (c *completenessTrackers) list(target, sub string) []*CompletenessStatus {
+	res := make([]*CompletenessStatus, 0)
+	for _, t := range c.all() {
+		if target != "" && t.target != target {
+			continue
+		}
+		if sub != "" && t.subscription != sub {
+			continue
+		}
+		res = append(res, t.status())
+	}
+	return res
+}
+
+// all returns the trackers sorted by target and subscription.
+func (c *completenessTrackers) all() []*completenessTracker {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	res := make([]*completenessTracker, 0, len(c.trackers))
+	for _, subs := range c.trackers {
+		for _, t := range subs {
+			if t != nil {
+				res = append(res, t)
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].target == res[j].target {
+			return res[i].subscription < res[j].subscription
+		}
+		return res[i].target < res[j].target
+	})
+	return res
+}
+
+// deleteTarget stops tracking the subscriptions of target.
+func (c *completenessTrackers) deleteTarget(target string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if _, ok := c.trackers[target]; !ok {
+		return
+	}
+	delete(c.trackers, target)
+	for _, v := range []*prometheus.MetricVec{
+		completenessExpectedSamples.MetricVec,
+		completenessReceivedSamples.MetricVec,
+		completenessLateSamples.MetricVec,
+		completenessIntervals.MetricVec,
+		completenessMissingIntervals.MetricVec,
+		completenessRatio.MetricVec,
+		completenessLateRatio.MetricVec,
+		completenessExpectedSeries.MetricVec,
+	} {
+		v.DeletePartialMatch(prometheus.Labels{"source": target})
+	}
+}
+
+// reset restarts the tracking of the matching target subscriptions,
+// the series are learned again.
+func (c *completenessTrackers) reset(target, sub string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	for tn, subs := range c.trackers {
+		if target != "" && tn != target {
+			continue
+		}
+		for sn := range subs {
+			if sub == "" || sn == sub {
+				delete(subs, sn)
+			}
+		}
+	}
+}
+
+// startCompleteness evaluates the tracked subscriptions and reports their
+// completeness as metrics and events until the app context is done.
+func (a *App) startCompleteness() {
+	cfg := a.Config.Completeness
+	if cfg == nil {
+		return
+	}
+	a.completeness.setConfig(cfg)
+	go func() {
+		evalTicker := time.NewTicker(completenessEvalPeriod)
+		defer evalTicker.Stop()
+		reportTicker := time.NewTicker(cfg.ReportInterval)
+		defer reportTicker.Stop()
+		for {
+			select {
+			case <-a.ctx.Done():
+				return
+			case <-evalTicker.C:
+				now := time.Now()
+				for _, t := range a.completeness.all() {
+					if a.subscriptionPaused(t.target, t.subscription) {
+						t.skip(now)
+						continue
+					}
+					t.m.Lock()
+					t.evaluate(now)
+					t.m.Unlock()
+				}
+			case <-reportTicker.C:
+				a.reportCompleteness(a.ctx, cfg, time.Now())
+			}
+		}
+	}()
+}
+
+// skip moves the tracker past the intervals closed at now without accounting for them.
+func (t *completenessTracker) skip(now time.Time) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if due := t.intervalOf(now) - 1; due >= t.next {
+		t.next = due + 1
+	}
+}
+
+func (a *App) subscriptionPaused(target, sub string) bool {
+	return slices.Contains(a.pausedSubs.get(target), sub)
+}
+
+func (a *App) reportCompleteness(ctx context.Context, cfg *config.Completeness, now time.Time) {
+	for _, t := range a.completeness.all() {
+		s := t.report(now)
+		if s == nil {
+			continue
+		}
+		completenessRatio.WithLabelValues(t.target, t.subscription).Set(s.Completeness)
+		completenessLateRatio.WithLabelValues(t.target, t.subscription).Set(s.LateRatio)
+		t.m.Lock()
+		expected := t.expectedSeries()
+		t.m.Unlock()
+		completenessExpectedSeries.WithLabelValues(t.target, t.subscription).Set(float64(expected))
+		if len(cfg.Outputs) == 0 || s.Intervals == 0 {
+			continue
+		}
+		ev := &formatters.EventMsg{
+			Name:      cfg.EventName,
+			Timestamp: now.UnixNano(),
+			Tags: map[string]string{
+				"source":            t.target,
+				"subscription-name": t.subscription,
+			},
+			Values: map[string]interface{}{
+				"intervals":         s.Intervals,
+				"missing_intervals": s.MissingIntervals,
+				"expected_samples":  s.ExpectedSamples,
+				"received_samples":  s.ReceivedSamples,
+				"late_samples":      s.LateSamples,
+				"completeness":      s.Completeness,
+				"late_ratio":        s.LateRatio,
+				"expected_series":   expected,
+			},
+		}
+		a.operLock.RLock()
+		for _, name := range cfg.Outputs {
+			if o, ok := a.Outputs[name]; ok {
+				o.WriteEvent(ctx, ev)
+			}
+		}
+		a.operLock.RUnlock()
+	}
+}
+
+func (a *App) handleCompletenessGet(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	a.handlerCommonGet(w, a.completeness.list(q.Get("target"), q.Get("subscription")))
+}
+
+func (a *App) handleCompletenessDelete(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	a.completeness.reset(q.Get("target"), q.Get("subscription"))
+}
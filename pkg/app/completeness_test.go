@@ -0,0 +1,256 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/config"
+)
+
+// completenessNotification returns a notification with one update per interface.
+func completenessNotification(ts time.Time, ifaces ...string) *gnmi.Notification {
+	n := &gnmi.Notification{
+		Timestamp: ts.UnixNano(),
+		Prefix:    &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interfaces"}}},
+	}
+	for _, i := range ifaces {
+		n.Update = append(n.Update, &gnmi.Update{Path: &gnmi.Path{Elem: []*gnmi.PathElem{
+			{Name: "interface", Key: map[string]string{"name": i}},
+			{Name: "state"},
+			{Name: "counters"},
+		}}})
+	}
+	return n
+}
+
+func TestCompletenessTrackerLearned(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	at := func(s int) time.Time { return t0.Add(time.Duration(s) * time.Second) }
+	tr := newCompletenessTracker("r1", "sub1", 10*time.Second, &config.CompletenessPolicy{LearnIntervals: 2}, t0)
+
+	// learning intervals
+	tr.observe(completenessNotification(at(0), "e1", "e2", "e3"), at(0))
+	tr.observe(completenessNotification(at(10), "e1", "e2", "e3"), at(10))
+	if st := tr.status(); st.State != completenessStateLearning {
+		t.Fatalf("expected state %q, got %q", completenessStateLearning, st.State)
+	}
+	if s := tr.report(at(12)); s != nil {
+		t.Fatalf("expected no report while learning, got %+v", s)
+	}
+	// complete interval
+	tr.observe(completenessNotification(at(20), "e1", "e2", "e3"), at(21))
+	// a duplicate sample is counted once
+	tr.observe(completenessNotification(at(22), "e1"), at(22))
+	// e3 is missing
+	tr.observe(completenessNotification(at(30), "e1", "e2"), at(30))
+	// nothing received in the next interval
+	s := tr.report(at(45))
+	want := &CompletenessStats{
+		Intervals:        3,
+		MissingIntervals: 2,
+		ExpectedSamples:  9,
+		ReceivedSamples:  5,
+		Completeness:     5.0 / 9.0,
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("unexpected report:\n got: %+v\nwant: %+v", s, want)
+	}
+	st := tr.status()
+	if st.State != completenessStateTracking || st.ExpectedSeries != 3 {
+		t.Errorf("unexpected status: %+v", st)
+	}
+	if len(st.MissingSeries) != 3 {
+		t.Errorf("expected 3 missing series, got %v", st.MissingSeries)
+	}
+	if !st.LastMissing.Equal(at(40)) {
+		t.Errorf("expected last missing interval at %s, got %s", at(40), st.LastMissing)
+	}
+	// the next report only counts the intervals since the previous one.
+	tr.observe(completenessNotification(at(50), "e1", "e2", "e3"), at(50))
+	s = tr.report(at(55))
+	if s.Intervals != 1 || s.MissingIntervals != 0 || s.Completeness != 1 {
+		t.Errorf("unexpected report: %+v", s)
+	}
+	if st := tr.status(); st.Total.Intervals != 4 || st.Total.ReceivedSamples != 8 {
+		t.Errorf("unexpected total: %+v", st.Total)
+	}
+}
+
+func TestCompletenessTrackerExpected(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	at := func(s int) time.Time { return t0.Add(time.Duration(s) * time.Second) }
+	tr := newCompletenessTracker("r1", "sub1", 10*time.Second,
+		&config.CompletenessPolicy{ExpectedSeries: 4, MaxLag: 5 * time.Second}, t0)
+
+	// the series beyond the expected ones are not tracked.
+	tr.observe(completenessNotification(at(0), "e1", "e2", "e3", "e4", "e5"), at(1))
+	// 2 late samples out of 3
+	tr.observe(completenessNotification(at(0), "e1", "e2"), at(10))
+	tr.observe(completenessNotification(at(10), "e3"), at(11))
+	s := tr.report(at(16))
+	want := &CompletenessStats{
+		Intervals:        2,
+		MissingIntervals: 1,
+		ExpectedSamples:  8,
+		ReceivedSamples:  7,
+		LateSamples:      2,
+		Completeness:     7.0 / 8.0,
+		LateRatio:        2.0 / 7.0,
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("unexpected report:\n got: %+v\nwant: %+v", s, want)
+	}
+	if st := tr.status(); !reflect.DeepEqual(st.MissingSeries, []string{"interfaces/interface[name=e4]/state/counters"}) {
+		t.Errorf("unexpected missing series: %v", st.MissingSeries)
+	}
+}
+
+func TestCompletenessTrackerExpire(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	at := func(s int) time.Time { return t0.Add(time.Duration(s) * time.Second) }
+	tr := newCompletenessTracker("r1", "sub1", 10*time.Second,
+		&config.CompletenessPolicy{LearnIntervals: 1, ExpireIntervals: 2}, t0)
+	tr.observe(completenessNotification(at(0), "e1", "e2"), at(0))
+	// e2 is removed
+	for i := 1; i <= 4; i++ {
+		tr.observe(completenessNotification(at(10*i), "e1"), at(10*i))
+	}
+	s := tr.report(at(45))
+	if s.Intervals != 4 || s.MissingIntervals != 2 {
+		t.Errorf("expected e2 to be missing for 2 intervals, got %+v", s)
+	}
+	if st := tr.status(); st.ExpectedSeries != 1 {
+		t.Errorf("expected e2 to expire, got %d expected series", st.ExpectedSeries)
+	}
+	// nothing received, the series do not expire.
+	s = tr.report(at(105))
+	if s.Intervals != 6 || s.MissingIntervals != 6 || tr.status().ExpectedSeries != 1 {
+		t.Errorf("unexpected report: %+v", s)
+	}
+}
+
+func TestCompletenessTrackerSkip(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	tr := newCompletenessTracker("r1", "sub1", 10*time.Second, &config.CompletenessPolicy{ExpectedSeries: 1}, t0)
+	tr.observe(completenessNotification(t0, "e1"), t0)
+	tr.report(t0.Add(6 * time.Second))
+	// paused subscription
+	tr.skip(t0.Add(time.Minute))
+	tr.observe(completenessNotification(t0.Add(time.Minute), "e1"), t0.Add(time.Minute))
+	tr.report(t0.Add(65 * time.Second))
+	if st := tr.status(); st.Total.Intervals != 2 || st.Total.MissingIntervals != 0 {
+		t.Errorf("expected the paused intervals to be skipped, got %+v", st.Total)
+	}
+}
+
+func TestSubscriptionSampleInterval(t *testing.T) {
+	d := func(d time.Duration) *time.Duration { return &d }
+	tests := map[string]struct {
+		sc   *types.SubscriptionConfig
+		want time.Duration
+	}{
+		"sample": {
+			sc:   &types.SubscriptionConfig{Mode: "stream", StreamMode: "sample", SampleInterval: d(10 * time.Second)},
+			want: 10 * time.Second,
+		},
+		"on_change": {
+			sc: &types.SubscriptionConfig{Mode: "stream", StreamMode: "on-change"},
+		},
+		"once": {
+			sc: &types.SubscriptionConfig{Mode: "once", SampleInterval: d(10 * time.Second)},
+		},
+		"stream_subscriptions": {
+			sc: &types.SubscriptionConfig{Mode: "stream", StreamSubscriptions: []*types.SubscriptionConfig{
+				{StreamMode: "sample", SampleInterval: d(time.Minute)},
+				{StreamMode: "sample", SampleInterval: d(time.Minute)},
+			}},
+			want: time.Minute,
+		},
+		"stream_subscriptions_different_intervals": {
+			sc: &types.SubscriptionConfig{Mode: "stream", StreamSubscriptions: []*types.SubscriptionConfig{
+				{StreamMode: "sample", SampleInterval: d(time.Minute)},
+				{StreamMode: "sample", SampleInterval: d(10 * time.Second)},
+			}},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := subscriptionSampleInterval(tt.sc); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCompletenessAPI(t *testing.T) {
+	a := New()
+	a.Config.APIServer = &config.APIServer{}
+	a.routes()
+	interval := 10 * time.Second
+	subs := []*types.SubscriptionConfig{
+		{Name: "sub1", Mode: "stream", StreamMode: "sample", SampleInterval: &interval},
+		{Name: "sub2", Mode: "stream", StreamMode: "on-change"},
+		{Name: "sub3", Mode: "stream", StreamMode: "sample", SampleInterval: &interval},
+	}
+	rsp := &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{
+		Update: completenessNotification(time.Now(), "e1"),
+	}}
+	// not configured
+	a.completeness.observe("r1", subs[0], rsp)
+	if l := a.completeness.list("", ""); len(l) != 0 {
+		t.Fatalf("expected no tracked subscriptions, got %d", len(l))
+	}
+	a.completeness.setConfig(&config.Completeness{
+		CompletenessPolicy: config.CompletenessPolicy{LearnIntervals: 3},
+		Subscriptions: map[string]*config.CompletenessPolicy{
+			"sub3": {Disable: true},
+		},
+	})
+	for _, target := range []string{"r1", "r2"} {
+		for _, sc := range subs {
+			a.completeness.observe(target, sc, rsp)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/completeness?target=r2", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code %d", rec.Code)
+	}
+	res := make([]*CompletenessStatus, 0)
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0].Target != "r2" || res[0].Subscription != "sub1" ||
+		res[0].State != completenessStateLearning || res[0].Interval != "10s" {
+		t.Errorf("unexpected response: %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	a.router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/completeness?target=r1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code %d", rec.Code)
+	}
+	if l := a.completeness.list("", ""); len(l) != 1 || l[0].Target != "r2" {
+		t.Errorf("expected only r2 to be tracked, got %+v", l)
+	}
+	a.completeness.deleteTarget("r2")
+	if l := a.completeness.list("", ""); len(l) != 0 {
+		t.Errorf("expected no tracked subscriptions, got %d", len(l))
+	}
+}
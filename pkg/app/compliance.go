@@ -0,0 +1,366 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/compliance"
+	"github.com/openconfig/gnmic/pkg/config"
+)
+
+// complianceRunner holds the compliance rules engine and the last report.
+type complianceRunner struct {
+	engine *compliance.Engine
+	// parsed target selectors per rule name.
+	sels map[string][]*targetSelector
+
+	m    *sync.Mutex
+	last *compliance.Report
+}
+
+func (c *complianceRunner) setLast(rep *compliance.Report) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.last = rep
+}
+
+func (c *complianceRunner) getLast() *compliance.Report {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.last
+}
+
+// initCompliance reads the compliance configuration and compiles the rules.
+func (a *App) initCompliance() error {
+	ccfg, err := a.Config.GetCompliance()
+	if err != nil {
+		return fmt.Errorf("failed reading compliance config: %v", err)
+	}
+	e, err := compliance.New(ccfg)
+	if err != nil {
+		return fmt.Errorf("failed initializing compliance rules: %v", err)
+	}
+	if e == nil {
+		a.compliance = nil
+		return nil
+	}
+	cr := &complianceRunner{
+		engine: e,
+		sels:   make(map[string][]*targetSelector, len(ccfg.Rules)),
+		m:      new(sync.Mutex),
+	}
+	for name, r := range ccfg.Rules {
+		cr.sels[name], err = parseTargetSelectors(r.Targets)
+		if err != nil {
+			return fmt.Errorf("compliance rule %q: %v", name, err)
+		}
+	}
+	a.compliance = cr
+	return nil
+}
+
+// runCompliance evaluates the rules against the targets tcs, at most `--concurrency`
+// targets at a time. If rules is not empty, only the rules it names are evaluated.
+func (a *App) runCompliance(ctx context.Context, tcs map[string]*types.TargetConfig, rules []string) *compliance.Report {
+	start := time.Now()
+	var sem chan struct{}
+	if a.Config.Concurrency > 0 {
+		sem = make(chan struct{}, a.Config.Concurrency)
+	}
+	m := new(sync.Mutex)
+	results := make([]*compliance.Result, 0, len(tcs))
+	wg := new(sync.WaitGroup)
+	wg.Add(len(tcs))
+	for _, tc := range tcs {
+		go func(tc *types.TargetConfig) {
+			defer wg.Done()
+			if sem != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case sem <- struct{}{}:
+				}
+				defer func() { <-sem }()
+			}
+			res := a.compliance.engine.Check(ctx, a.complianceTarget(tc),
+				func(name string, _ *compliance.Rule) bool {
+					if len(rules) > 0 && !slices.Contains(rules, name) {
+						return false
+					}
+					sels := a.compliance.sels[name]
+					if len(sels) == 0 {
+						return true
+					}
+					for _, sel := range sels {
+						if sel.match(tc) {
+							return true
+						}
+					}
+					return false
+				})
+			m.Lock()
+			results = append(results, res...)
+			m.Unlock()
+		}(tc)
+	}
+	wg.Wait()
+	return compliance.NewReport(start, results)
+}
+
+func (a *App) complianceTarget(tc *types.TargetConfig) *compliance.Target {
+	ct := &compliance.Target{
+		Name: tc.Name,
+		Get: func(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetResponse, error) {
+			return a.ClientGet(ctx, tc, req)
+		},
+	}
+	if tc.Encoding != nil {
+		ct.Encoding = *tc.Encoding
+	}
+	if a.c != nil {
+		ct.Cache = func(p *gnmi.Path) ([]*gnmi.Notification, error) {
+			rs, err := a.c.Read("*", tc.Name, p)
+			if err != nil {
+				return nil, err
+			}
+			ns := make([]*gnmi.Notification, 0)
+			for _, sns := range rs {
+				ns = append(ns, sns...)
+			}
+			return ns, nil
+		}
+	}
+	return ct
+}
+
+// startCompliance evaluates the rules periodically against the targets
+// handled by this instance if an interval is configured.
+func (a *App) startCompliance() {
+	if a.compliance == nil || a.compliance.engine.Config().Interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(a.compliance.engine.Config().Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-a.ctx.Done():
+				return
+			case <-ticker.C:
+				a.complianceRound(a.ctx, nil, nil)
+			}
+		}
+	}()
+}
+
+// complianceRound evaluates the rules against the running targets, or the ones
+// named in targets, then exports the results and stores the report.
+func (a *App) complianceRound(ctx context.Context, rules, targets []string) *compliance.Report {
+	a.operLock.RLock()
+	tcs := make(map[string]*types.TargetConfig, len(a.Targets))
+	for n, t := range a.Targets {
+		if len(targets) > 0 && !slices.Contains(targets, n) {
+			continue
+		}
+		tcs[n] = t.Config
+	}
+	a.operLock.RUnlock()
+
+	rep := a.runCompliance(ctx, tcs, rules)
+	a.compliance.setLast(rep)
+	a.Logger.Printf("compliance: %d result(s): %d passed, %d failed, %d error(s)",
+		rep.Summary.Total, rep.Summary.Passed, rep.Summary.Failed, rep.Summary.Errors)
+	cfg := a.compliance.engine.Config()
+	if len(cfg.Outputs) > 0 {
+		evs := rep.Events(cfg.EventName)
+		a.operLock.RLock()
+		for _, name := range cfg.Outputs {
+			if o, ok := a.Outputs[name]; ok {
+				for _, ev := range evs {
+					o.WriteEvent(ctx, ev)
+				}
+			}
+		}
+		a.operLock.RUnlock()
+	}
+	for _, rc := range cfg.Reports {
+		err := rep.WriteFile(rc.File, rc.Format)
+		if err != nil {
+			a.Logger.Printf("compliance: failed to write %s report %q: %v", rc.Format, rc.File, err)
+		}
+	}
+	return rep
+}
+
+func (a *App) handleComplianceGet(w http.ResponseWriter, r *http.Request) {
+	if a.compliance == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{"compliance rules not configured"}})
+		return
+	}
+	rep := a.compliance.getLast()
+	if rep == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{"no compliance report available"}})
+		return
+	}
+	a.writeComplianceReport(w, r, rep)
+}
+
+// handleCompliancePost evaluates the rules now, the `rule` and `target`
+// query parameters restrict the evaluated rules and targets.
+func (a *App) handleCompliancePost(w http.ResponseWriter, r *http.Request) {
+	if a.compliance == nil {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{"compliance rules not configured"}})
+		return
+	}
+	q := r.URL.Query()
+	rules := q["rule"]
+	for _, name := range rules {
+		if !slices.Contains(a.compliance.engine.Rules(), name) {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("unknown compliance rule %q", name)}})
+			return
+		}
+	}
+	rep := a.complianceRound(r.Context(), rules, q["target"])
+	a.writeComplianceReport(w, r, rep)
+}
+
+func (a *App) writeComplianceReport(w http.ResponseWriter, r *http.Request, rep *compliance.Report) {
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", compliance.FormatJSON:
+		a.handlerCommonGet(w, rep)
+		return
+	case compliance.FormatJUnit:
+		w.Header().Set("Content-Type", "application/xml")
+	case compliance.FormatHTML:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("unknown report format %q", format)}})
+		return
+	}
+	err := rep.Write(w, format)
+	if err != nil {
+		a.Logger.Printf("compliance: failed to write %s report: %v", format, err)
+	}
+}
+
+func (a *App) CompliancePreRunE(cmd *cobra.Command, _ []string) error {
+	a.Config.SetLocalFlagsFromFile(cmd)
+	a.Config.LocalFlags.ComplianceRule = config.SanitizeArrayFlagValue(a.Config.LocalFlags.ComplianceRule)
+	switch a.Config.LocalFlags.ComplianceReportFormat {
+	case "", "text", compliance.FormatJSON, compliance.FormatJUnit, compliance.FormatHTML:
+	default:
+		return fmt.Errorf("unknown report format %q", a.Config.LocalFlags.ComplianceReportFormat)
+	}
+	a.createCollectorDialOpts()
+	return nil
+}
+
+func (a *App) ComplianceRunE(cmd *cobra.Command, _ []string) error {
+	defer a.InitComplianceFlags(cmd)
+
+	err := a.initCompliance()
+	if err != nil {
+		return err
+	}
+	if a.compliance == nil {
+		return errors.New("no compliance rules configured")
+	}
+	for _, name := range a.Config.LocalFlags.ComplianceRule {
+		if !slices.Contains(a.compliance.engine.Rules(), name) {
+			return fmt.Errorf("unknown compliance rule %q", name)
+		}
+	}
+	targetsConfig, err := a.GetTargets()
+	if err != nil {
+		return fmt.Errorf("failed getting targets config: %v", err)
+	}
+	rep := a.runCompliance(a.ctx, targetsConfig, a.Config.LocalFlags.ComplianceRule)
+
+	var w io.Writer = os.Stdout
+	if a.Config.LocalFlags.ComplianceReportFile != "" {
+		f, err := os.Create(a.Config.LocalFlags.ComplianceReportFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	switch a.Config.LocalFlags.ComplianceReportFormat {
+	case "", "text":
+		printComplianceReport(w, rep)
+	default:
+		err = rep.Write(w, a.Config.LocalFlags.ComplianceReportFormat)
+		if err != nil {
+			return err
+		}
+	}
+	if !rep.Compliant() {
+		return fmt.Errorf("%d compliance check(s) failed, %d error(s)", rep.Summary.Failed, rep.Summary.Errors)
+	}
+	return nil
+}
+
+func (a *App) InitComplianceFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+
+	cmd.Flags().StringSliceVarP(&a.Config.LocalFlags.ComplianceRule, "rule", "", []string{}, "rules to evaluate, all rules if not set")
+	cmd.Flags().StringVarP(&a.Config.LocalFlags.ComplianceReportFormat, "report-format", "", "text", "report format, one of: text, json, junit, html")
+	cmd.Flags().StringVarP(&a.Config.LocalFlags.ComplianceReportFile, "report-file", "", "", "file the report is written to, defaults to stdout")
+
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
+	})
+}
+
+func printComplianceReport(w io.Writer, rep *compliance.Report) {
+	tabData := make([][]string, 0, len(rep.Results))
+	for _, res := range rep.Results {
+		tabData = append(tabData, []string{
+			res.Rule,
+			res.Severity,
+			res.Target,
+			strings.ToUpper(res.Status),
+			fmt.Sprintf("%d/%d", res.Failed, res.Checked),
+			res.Message,
+			strings.Join(res.Evidence, "\n"),
+		})
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Rule", "Severity", "Target", "Status", "Failed", "Message", "Evidence"})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoWrapText(false)
+	table.AppendBulk(tabData)
+	table.Render()
+	fmt.Fprintf(w, "%d/%d check(s) passed, %d failed, %d error(s)\n",
+		rep.Summary.Passed, rep.Summary.Total, rep.Summary.Failed, rep.Summary.Errors)
+}
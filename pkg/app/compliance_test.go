@@ -0,0 +1,140 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/api/path"
+	"github.com/openconfig/gnmic/pkg/api/server"
+	"github.com/openconfig/gnmic/pkg/api/target"
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/compliance"
+	"github.com/openconfig/gnmic/pkg/config"
+)
+
+// startComplianceServer starts a gNMI server answering the Get requests
+// with the MTU of 2 interfaces.
+func startComplianceServer(t *testing.T, name string) *types.TargetConfig {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	get := func(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetResponse, error) {
+		n := &gnmi.Notification{Timestamp: time.Now().UnixNano()}
+		for ifName, mtu := range map[string]uint64{"e1": 9212, "e2": 1500} {
+			p, _ := path.ParsePath("/interfaces/interface[name=" + ifName + "]/config/mtu")
+			n.Update = append(n.Update, &gnmi.Update{
+				Path: p,
+				Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: mtu}},
+			})
+		}
+		return &gnmi.GetResponse{Notification: []*gnmi.Notification{n}}, nil
+	}
+	s, err := server.New(server.Config{Address: addr},
+		server.WithLogger(log.New(io.Discard, "", 0)),
+		server.WithGetHandler(get),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start(context.Background())
+	insecure := true
+	return &types.TargetConfig{
+		Name:      name,
+		Address:   addr,
+		Insecure:  &insecure,
+		Timeout:   5 * time.Second,
+		EventTags: map[string]string{"role": "core"},
+	}
+}
+
+func TestCompliance(t *testing.T) {
+	a := New()
+	a.Config.Encoding = "json"
+	a.Config.FileConfig.Set("compliance", map[string]any{
+		"rules": map[string]any{
+			"core-mtu": map[string]any{
+				"targets": []string{"role=core"},
+				"paths":   []string{"/interfaces"},
+				"select":  "/interfaces/interface/config",
+				"assert":  "node.mtu >= 9000",
+			},
+			"edge-mtu": map[string]any{
+				"targets": []string{"role=edge"},
+				"paths":   []string{"/interfaces"},
+				"assert":  "true",
+			},
+			"cached": map[string]any{
+				"source": "cache",
+				"paths":  []string{"/interfaces"},
+				"assert": "true",
+			},
+		},
+	})
+	if err := a.initCompliance(); err != nil {
+		t.Fatal(err)
+	}
+	a.Config.APIServer = &config.APIServer{}
+	a.routes()
+	tc := startComplianceServer(t, "router1")
+	a.Targets[tc.Name] = target.NewTarget(tc)
+
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/compliance", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected no report, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	a.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/compliance?format=junit", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/xml" {
+		t.Fatalf("unexpected response %d: %s", rec.Code, rec.Body)
+	}
+	if !strings.Contains(rec.Body.String(), "/interfaces/interface[name=e2]/config") {
+		t.Errorf("missing evidence in report: %s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	a.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/compliance", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code %d", rec.Code)
+	}
+	rep := new(compliance.Report)
+	if err := json.Unmarshal(rec.Body.Bytes(), rep); err != nil {
+		t.Fatal(err)
+	}
+	// edge-mtu does not apply to router1 and the cache is not available.
+	if len(rep.Results) != 2 || rep.Summary.Failed != 1 || rep.Summary.Errors != 1 {
+		t.Fatalf("unexpected report: %s", rec.Body)
+	}
+	res := rep.Results[1]
+	if res.Rule != "core-mtu" || res.Target != "router1" || res.Status != compliance.StatusFail || res.Checked != 2 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+
+	rec = httptest.NewRecorder()
+	a.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/compliance?rule=unknown", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected a bad request, got %d", rec.Code)
+	}
+}
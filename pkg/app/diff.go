@@ -17,6 +17,7 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/openconfig/gnmi/proto/gnmi"
 	"github.com/openconfig/grpctunnel/tunnel"
@@ -43,7 +44,6 @@ func (a *App) InitDiffFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&a.Config.LocalFlags.DiffRef, "ref", "", "", "reference gNMI target to compare the other targets to")
 	cmd.MarkFlagRequired("ref")
 	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.DiffCompare, "compare", "", []string{}, "gNMI targets to compare to the reference")
-	cmd.MarkFlagRequired("compare")
 	cmd.Flags().StringVarP(&a.Config.LocalFlags.DiffPrefix, "prefix", "", "", "diff request prefix")
 	cmd.Flags().StringSliceVarP(&a.Config.LocalFlags.DiffModel, "model", "", []string{}, "diff request models")
 	cmd.Flags().StringVarP(&a.Config.LocalFlags.DiffType, "type", "t", "ALL", "data type requested from the target. one of: ALL, CONFIG, STATE, OPERATIONAL")
@@ -64,6 +64,9 @@ func (a *App) DiffPreRunE(cmd *cobra.Command, args []string) error {
 	a.Config.LocalFlags.DiffPath = config.SanitizeArrayFlagValue(a.Config.LocalFlags.DiffPath)
 	a.Config.LocalFlags.DiffModel = config.SanitizeArrayFlagValue(a.Config.LocalFlags.DiffModel)
 	a.Config.LocalFlags.DiffCompare = config.SanitizeArrayFlagValue(a.Config.LocalFlags.DiffCompare)
+	if len(a.Config.LocalFlags.DiffCompare) == 0 && len(a.Config.Select) == 0 {
+		return errors.New("one of the flags --compare or --select must be set")
+	}
 
 	a.createCollectorDialOpts()
 	return a.initTunnelServer(tunnel.ServerConfig{
@@ -80,7 +83,7 @@ func (a *App) DiffRunE(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	// setupCloseHandler(cancel)
-	refTarget, targetsConfig, err := a.Config.GetDiffTargets()
+	refTarget, targetsConfig, err := a.getDiffTargets()
 	if err != nil {
 		return fmt.Errorf("failed getting diff targets config: %v", err)
 	}
@@ -115,33 +118,82 @@ func (a *App) DiffRunE(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	numTargets := len(targetsConfig) + 1
-	a.errCh = make(chan error, numTargets*2)
-	a.wg.Add(numTargets)
+	a.errCh = make(chan error, (len(targetsConfig)+1)*2)
 
-	compares := make([]*types.TargetConfig, 0, len(targetsConfig))
-	for _, t := range targetsConfig {
-		compares = append(compares, t)
-	}
-	sort.Slice(compares, func(i, j int) bool {
-		return compares[i].Name < compares[j].Name
-	})
-
-	err = a.diff(ctx, cmd, refTarget, compares)
+	err = a.diff(ctx, cmd, refTarget, targetsConfig)
 	if err != nil {
 		a.logError(err)
 	}
 	return a.checkErrors()
 }
 
-func (a *App) diff(ctx context.Context, cmd *cobra.Command, ref *types.TargetConfig, compare []*types.TargetConfig) error {
+// getDiffTargets returns the reference and compare targets configs.
+// If `--select` is set, the selected targets are compared to the reference
+// on top of the ones set with `--compare`.
+func (a *App) getDiffTargets() (*types.TargetConfig, map[string]*types.TargetConfig, error) {
+	if len(a.Config.Select) == 0 {
+		return a.Config.GetDiffTargets()
+	}
+	sels, err := parseTargetSelectors(a.Config.Select)
+	if err != nil {
+		return nil, nil, err
+	}
+	tcs, err := a.getTargetsInventory(a.ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	lookup := func(name string) (*types.TargetConfig, error) {
+		if tc, ok := tcs[name]; ok {
+			return tc, nil
+		}
+		tc := &types.TargetConfig{Name: name, Address: name}
+		return tc, a.Config.SetTargetConfigDefaults(tc)
+	}
+	refTarget, err := lookup(a.Config.DiffRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	compareConfigs := matchTargets(tcs, sels)
+	for _, cmp := range a.Config.DiffCompare {
+		compareConfigs[cmp], err = lookup(cmp)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	delete(compareConfigs, refTarget.Name)
+	return refTarget, compareConfigs, nil
+}
+
+func (a *App) diff(ctx context.Context, cmd *cobra.Command, ref *types.TargetConfig, compare map[string]*types.TargetConfig) error {
 	if a.Config.DiffSub {
 		return a.subscribeBasedDiff(ctx, cmd, ref, compare)
 	}
 	return a.getBasedDiff(ctx, ref, compare)
 }
 
-func (a *App) subscribeBasedDiff(ctx context.Context, cmd *cobra.Command, ref *types.TargetConfig, compare []*types.TargetConfig) error {
+// diffResponses collects the responses of the compared targets.
+type diffResponses struct {
+	m    sync.Mutex
+	rsps []*targetDiffResponse
+}
+
+func (d *diffResponses) add(r *targetDiffResponse) {
+	d.m.Lock()
+	defer d.m.Unlock()
+	d.rsps = append(d.rsps, r)
+}
+
+// sorted returns the collected responses sorted by target name.
+func (d *diffResponses) sorted() []*targetDiffResponse {
+	d.m.Lock()
+	defer d.m.Unlock()
+	sort.Slice(d.rsps, func(i, j int) bool {
+		return d.rsps[i].t < d.rsps[j].t
+	})
+	return d.rsps
+}
+
+func (a *App) subscribeBasedDiff(ctx context.Context, cmd *cobra.Command, ref *types.TargetConfig, compare map[string]*types.TargetConfig) error {
 	subReq, err := a.Config.CreateDiffSubscribeRequest(cmd)
 	if err != nil {
 		if errors.Is(errors.Unwrap(err), config.ErrConfig) {
@@ -149,106 +201,32 @@ func (a *App) subscribeBasedDiff(ctx context.Context, cmd *cobra.Command, ref *t
 			os.Exit(1)
 		}
 	}
-	numCompares := len(compare)
-	refResponse := make([]proto.Message, 0)
-	rspChan := make(chan *targetDiffResponse, numCompares)
-	a.operLock.Lock()
-	refTarget, err := a.initTarget(ref)
-	a.operLock.Unlock()
-	if err != nil {
-		return err
-	}
-
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	go func() {
-		defer a.wg.Done()
-		err = refTarget.CreateGNMIClient(ctx, a.dialOpts...)
+	refResponse, err := a.subscribeOnceResponses(ctx, ref, subReq)
+	if err != nil {
+		return fmt.Errorf("target %q subscribe request failed: %v", ref.Name, err)
+	}
+	rsps := new(diffResponses)
+	a.runOnTargets(ctx, compare, func(ctx context.Context, tc *types.TargetConfig) {
+		responses, err := a.subscribeOnceResponses(ctx, tc, subReq)
 		if err != nil {
-			a.logError(err)
+			a.logTargetError(tc.Name, fmt.Errorf("target %q subscribe request failed: %v", tc.Name, err))
 			return
 		}
-		a.Logger.Printf("sending gNMI SubscribeRequest: subscribe='%+v', mode='%+v', encoding='%+v', to %s",
-			subReq.Request, subReq.GetSubscribe().GetMode(), subReq.GetSubscribe().GetEncoding(), ref)
-		rspChan, errChan := refTarget.SubscribeOnceChan(ctx, subReq)
-		for {
-			select {
-			case r := <-rspChan:
-				switch r.Response.(type) {
-				case *gnmi.SubscribeResponse_Update:
-					refResponse = append(refResponse, r)
-				case *gnmi.SubscribeResponse_SyncResponse:
-					return
-				}
-			case err := <-errChan:
-				if err != io.EOF {
-					a.logError(err)
-				}
-				return
-			}
-		}
-	}()
-
-	for _, tc := range compare {
-		a.operLock.Lock()
-		t, err := a.initTarget(tc)
-		a.operLock.Unlock()
-		if err != nil {
-			return err
-		}
-		go func(tName string) {
-			defer a.wg.Done()
-			err = t.CreateGNMIClient(ctx, a.dialOpts...)
-			if err != nil {
-				a.logError(err)
-				return
-			}
-			responses := make([]proto.Message, 0)
-			a.Logger.Printf("sending gNMI SubscribeRequest: subscribe='%+v', mode='%+v', encoding='%+v', to %s",
-				subReq.Request, subReq.GetSubscribe().GetMode(), subReq.GetSubscribe().GetEncoding(), tName)
-			subRspChan, errChan := t.SubscribeOnceChan(ctx, subReq)
-			for {
-				select {
-				case r := <-subRspChan:
-					switch r.Response.(type) {
-					case *gnmi.SubscribeResponse_Update:
-						responses = append(responses, r)
-					case *gnmi.SubscribeResponse_SyncResponse:
-						rspChan <- &targetDiffResponse{
-							t:  tName,
-							rs: responses,
-						}
-						return
-					}
-				case err := <-errChan:
-					if err == io.EOF {
-						rspChan <- &targetDiffResponse{
-							t:  tName,
-							rs: responses,
-						}
-						return
-					}
-					a.logError(err)
-					return
-				}
-			}
-		}(tc.Name)
-		continue
-	}
-	a.wg.Wait()
-	close(rspChan)
-
-	rsps := make([]*targetDiffResponse, 0, numCompares)
-	for r := range rspChan {
-		rsps = append(rsps, r)
-	}
-	if len(rsps) == 0 {
+		rsps.add(&targetDiffResponse{
+			t:  tc.Name,
+			rs: responses,
+		})
+	})
+	crs := rsps.sorted()
+	if len(crs) == 0 {
 		a.Logger.Printf("missing response(s)")
 		return fmt.Errorf("missing response(s)")
 	}
 
-	for _, cr := range rsps {
+	for _, cr := range crs {
 		fmt.Fprintf(os.Stderr, "%q vs %q\n", ref.Name, cr.t)
 		err = a.responsesDiff(refResponse, cr.rs)
 		if err != nil {
@@ -258,7 +236,42 @@ func (a *App) subscribeBasedDiff(ctx context.Context, cmd *cobra.Command, ref *t
 	return nil
 }
 
-func (a *App) getBasedDiff(ctx context.Context, ref *types.TargetConfig, compare []*types.TargetConfig) error {
+// subscribeOnceResponses sends subReq to the target and returns the
+// updates received until the sync response or the end of the stream.
+func (a *App) subscribeOnceResponses(ctx context.Context, tc *types.TargetConfig, subReq *gnmi.SubscribeRequest) ([]proto.Message, error) {
+	a.operLock.Lock()
+	t, err := a.initTarget(tc)
+	a.operLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	err = t.CreateGNMIClient(ctx, a.dialOpts...)
+	if err != nil {
+		return nil, err
+	}
+	a.Logger.Printf("sending gNMI SubscribeRequest: subscribe='%+v', mode='%+v', encoding='%+v', to %s",
+		subReq.Request, subReq.GetSubscribe().GetMode(), subReq.GetSubscribe().GetEncoding(), tc.Name)
+	responses := make([]proto.Message, 0)
+	subRspChan, errChan := t.SubscribeOnceChan(ctx, subReq)
+	for {
+		select {
+		case r := <-subRspChan:
+			switch r.Response.(type) {
+			case *gnmi.SubscribeResponse_Update:
+				responses = append(responses, r)
+			case *gnmi.SubscribeResponse_SyncResponse:
+				return responses, nil
+			}
+		case err := <-errChan:
+			if err == io.EOF {
+				return responses, nil
+			}
+			return nil, err
+		}
+	}
+}
+
+func (a *App) getBasedDiff(ctx context.Context, ref *types.TargetConfig, compare map[string]*types.TargetConfig) error {
 	getReq, err := a.Config.CreateDiffGetRequest()
 	if err != nil {
 		return err
@@ -266,50 +279,32 @@ func (a *App) getBasedDiff(ctx context.Context, ref *types.TargetConfig, compare
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	var refResponse proto.Message
-	numCompares := len(compare)
-
-	go func() {
-		defer a.wg.Done()
+	a.Logger.Printf("sending gNMI GetRequest: prefix='%v', path='%v', type='%v', encoding='%v', models='%+v', extension='%+v' to %s",
+		getReq.Prefix, getReq.Path, getReq.Type, getReq.Encoding, getReq.UseModels, getReq.Extension, ref.Name)
+	refResponse, err := a.ClientGet(ctx, ref, getReq)
+	if err != nil {
+		return fmt.Errorf("target %q get request failed: %v", ref.Name, err)
+	}
+	rsps := new(diffResponses)
+	a.runOnTargets(ctx, compare, func(ctx context.Context, tc *types.TargetConfig) {
 		a.Logger.Printf("sending gNMI GetRequest: prefix='%v', path='%v', type='%v', encoding='%v', models='%+v', extension='%+v' to %s",
-			getReq.Prefix, getReq.Path, getReq.Type, getReq.Encoding, getReq.UseModels, getReq.Extension, ref)
-		refResponse, err = a.ClientGet(ctx, ref, getReq)
+			getReq.Prefix, getReq.Path, getReq.Type, getReq.Encoding, getReq.UseModels, getReq.Extension, tc.Name)
+		response, err := a.ClientGet(ctx, tc, getReq)
 		if err != nil {
-			a.logError(fmt.Errorf("target %q get request failed: %v", ref, err))
+			a.logTargetError(tc.Name, fmt.Errorf("target %q get request failed: %v", tc.Name, err))
 			return
 		}
-	}()
-	rspChan := make(chan *targetDiffResponse, numCompares)
-	for _, tc := range compare {
-		go func(tc *types.TargetConfig) {
-			defer a.wg.Done()
-			a.Logger.Printf("sending gNMI GetRequest: prefix='%v', path='%v', type='%v', encoding='%v', models='%+v', extension='%+v' to %s",
-				getReq.Prefix, getReq.Path, getReq.Type, getReq.Encoding, getReq.UseModels, getReq.Extension, tc.Name)
-			response, err := a.ClientGet(ctx, tc, getReq)
-			if err != nil {
-				a.logError(fmt.Errorf("target %q get request failed: %v", tc.Name, err))
-				return
-			}
-			rspChan <- &targetDiffResponse{
-				t: tc.Name,
-				r: response,
-			}
-		}(tc)
-	}
-	a.wg.Wait()
-	close(rspChan)
-	rsps := make([]*targetDiffResponse, 0, numCompares)
-	for r := range rspChan {
-		rsps = append(rsps, r)
-	}
-	if len(rsps) == 0 {
+		rsps.add(&targetDiffResponse{
+			t: tc.Name,
+			r: response,
+		})
+	})
+	crs := rsps.sorted()
+	if len(crs) == 0 {
 		return fmt.Errorf("no responses received")
 	}
 
-	sort.Slice(rsps, func(i, j int) bool {
-		return rsps[i].t < rsps[j].t
-	})
-	for _, cr := range rsps {
+	for _, cr := range crs {
 		fmt.Fprintf(os.Stderr, "%q vs %q\n", ref.Name, cr.t)
 		err = a.responsesDiff([]proto.Message{refResponse}, []proto.Message{cr.r})
 		if err != nil {
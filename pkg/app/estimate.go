@@ -0,0 +1,640 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/path"
+	"github.com/openconfig/gnmic/pkg/api/target"
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	promcom "github.com/openconfig/gnmic/pkg/outputs/prometheus_output"
+)
+
+const (
+	defaultEstimateSampleSize = 3
+	defaultEstimateTopPaths   = 10
+	// interval assumed for subscriptions without
+	// a sample or heartbeat interval (on-change, target-defined).
+	defaultEstimateInterval = time.Minute
+)
+
+// EstimateRequest selects the targets, subscriptions and outputs
+// of a subscription impact estimation.
+type EstimateRequest struct {
+	// targets the estimation is extrapolated to, all targets if empty.
+	Targets []string `json:"targets,omitempty"`
+	// subscriptions to estimate, all subscriptions if empty.
+	Subscriptions []string `json:"subscriptions,omitempty"`
+	// outputs to estimate, all outputs if empty.
+	Outputs []string `json:"outputs,omitempty"`
+	// number of targets sampled.
+	SampleSize int `json:"sample-size,omitempty"`
+	// number of top contributing paths to report.
+	TopPaths int `json:"top-paths,omitempty"`
+}
+
+// Estimate is the result of a subscription impact estimation,
+// extrapolated from the sampled targets to all the selected targets.
+type Estimate struct {
+	NumTargets     int                     `json:"num-targets"`
+	SampledTargets []string                `json:"sampled-targets,omitempty"`
+	Subscriptions  []*SubscriptionEstimate `json:"subscriptions,omitempty"`
+	Outputs        []*OutputEstimate       `json:"outputs,omitempty"`
+	TopPaths       []*PathEstimate         `json:"top-paths,omitempty"`
+	Errors         []string                `json:"errors,omitempty"`
+}
+
+type SubscriptionEstimate struct {
+	Name     string `json:"name,omitempty"`
+	Interval string `json:"interval,omitempty"`
+	// the interval is assumed if the subscription does not
+	// set a sample or a heartbeat interval.
+	IntervalAssumed bool `json:"interval-assumed,omitempty"`
+	// number of distinct paths (without keys) per target.
+	Paths              int     `json:"paths"`
+	UpdatesPerInterval int64   `json:"updates-per-interval"`
+	EventsPerInterval  int64   `json:"events-per-interval"`
+	BytesPerSecond     float64 `json:"bytes-per-second"`
+}
+
+type OutputEstimate struct {
+	Name            string  `json:"name,omitempty"`
+	Type            string  `json:"type,omitempty"`
+	Series          int64   `json:"series"`
+	EventsPerSecond float64 `json:"events-per-second"`
+	BytesPerSecond  float64 `json:"bytes-per-second"`
+}
+
+type PathEstimate struct {
+	Path               string  `json:"path,omitempty"`
+	Subscription       string  `json:"subscription,omitempty"`
+	UpdatesPerInterval int64   `json:"updates-per-interval"`
+	BytesPerSecond     float64 `json:"bytes-per-second"`
+}
+
+// estimateSub is a subscription converted to ONCE mode.
+type estimateSub struct {
+	name     string
+	sc       *types.SubscriptionConfig
+	interval time.Duration
+	assumed  bool
+}
+
+// outputEstimator counts the series and bytes written by an output
+// using the output processors and metric builder.
+type outputEstimator struct {
+	name string
+	typ  string
+	// newEvps creates fresh instances of the output processors,
+	// so that the state kept by a processor for a sampled target
+	// does not change the results of the next one.
+	newEvps func() ([]formatters.EventProcessor, error)
+	series  map[string]struct{}
+	events  float64
+	bytes   float64
+	measure func(ev *formatters.EventMsg) ([]string, int)
+}
+
+type pathCounter struct {
+	path    string
+	sub     string
+	updates int64
+	bytes   float64
+}
+
+func (a *App) SubscribeRunEstimate(cmd *cobra.Command, args []string) error {
+	req := &EstimateRequest{
+		Subscriptions: a.Config.LocalFlags.SubscribeName,
+		Outputs:       a.Config.LocalFlags.SubscribeOutput,
+		SampleSize:    a.Config.LocalFlags.SubscribeEstimateSample,
+		TopPaths:      a.Config.LocalFlags.SubscribeEstimateTop,
+	}
+	if len(a.Config.LocalFlags.SubscribePath) > 0 {
+		// subscription created from flags
+		req.Subscriptions = nil
+	}
+	_, err := a.Config.GetTargets()
+	if err != nil {
+		return fmt.Errorf("failed reading targets config: %v", err)
+	}
+	est, err := a.Estimate(cmd.Context(), req)
+	if err != nil {
+		return err
+	}
+	if a.Config.Format == "json" {
+		b, err := json.MarshalIndent(est, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+	printEstimate(est)
+	return nil
+}
+
+// Estimate runs a ONCE subscription on a sample of the selected targets,
+// passes the responses through the outputs processors and metric builders
+// then extrapolates the results to all the selected targets.
+func (a *App) Estimate(ctx context.Context, req *EstimateRequest) (*Estimate, error) {
+	if req.SampleSize <= 0 {
+		req.SampleSize = defaultEstimateSampleSize
+	}
+	if req.TopPaths <= 0 {
+		req.TopPaths = defaultEstimateTopPaths
+	}
+	a.configLock.RLock()
+	tcs, err := selectTargets(a.Config.Targets, req.Targets)
+	if err != nil {
+		a.configLock.RUnlock()
+		return nil, err
+	}
+	subs, err := selectSubscriptions(a.Config.Subscriptions, req.Subscriptions)
+	if err != nil {
+		a.configLock.RUnlock()
+		return nil, err
+	}
+	oes, err := a.outputEstimators(tcs, req.Outputs)
+	a.configLock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(tcs))
+	for n := range tcs {
+		names = append(names, n)
+	}
+	est := &Estimate{NumTargets: len(names)}
+	names = sampleTargets(names, req.SampleSize)
+
+	subEstimates := make(map[string]*SubscriptionEstimate)
+	subPaths := make(map[string]map[string]struct{})
+	paths := make(map[string]*pathCounter)
+	numSampled := 0
+	for _, name := range names {
+		tc := tcs[name]
+		rsps, err := a.estimateTarget(ctx, tc, subs)
+		if err != nil {
+			est.Errors = append(est.Errors, fmt.Sprintf("target %q: %v", name, err))
+			continue
+		}
+		numSampled++
+		est.SampledTargets = append(est.SampledTargets, name)
+		for _, r := range rsps {
+			se, ok := subEstimates[r.sub.name]
+			if !ok {
+				se = &SubscriptionEstimate{
+					Name:            r.sub.name,
+					Interval:        r.sub.interval.String(),
+					IntervalAssumed: r.sub.assumed,
+				}
+				subEstimates[r.sub.name] = se
+				subPaths[r.sub.name] = make(map[string]struct{})
+			}
+			for _, rsp := range r.rsps {
+				n := rsp.GetUpdate()
+				if n == nil {
+					continue
+				}
+				size := float64(proto.Size(rsp))
+				se.BytesPerSecond += size / r.sub.interval.Seconds()
+				for _, upd := range n.GetUpdate() {
+					p := "/" + path.GnmiPathToXPath(&gnmi.Path{
+						Elem: path.PathElems(n.GetPrefix(), upd.GetPath()),
+					}, true)
+					se.UpdatesPerInterval++
+					subPaths[r.sub.name][p] = struct{}{}
+					pk := r.sub.name + "\x00" + p
+					pc, ok := paths[pk]
+					if !ok {
+						pc = &pathCounter{path: p, sub: r.sub.name}
+						paths[pk] = pc
+					}
+					pc.updates++
+					pc.bytes += float64(proto.Size(upd)) / r.sub.interval.Seconds()
+				}
+				meta := map[string]string{
+					"source":            name,
+					"subscription-name": r.sub.name,
+				}
+				evs, err := formatters.DryRunResponseToEventMsgs(r.sub.name, rsp, meta)
+				if err == nil {
+					se.EventsPerInterval += int64(len(evs))
+				}
+			}
+			for _, oe := range oes {
+				if len(r.sub.sc.Outputs) > 0 && !strInSlice(oe.name, r.sub.sc.Outputs) {
+					continue
+				}
+				err = oe.estimate(name, r)
+				if err != nil {
+					est.Errors = append(est.Errors, fmt.Sprintf("target %q: output %q: %v", name, oe.name, err))
+				}
+			}
+		}
+	}
+	if numSampled == 0 {
+		return est, errors.New("failed to estimate: no target could be sampled")
+	}
+	// extrapolate to all the selected targets
+	scale := float64(est.NumTargets) / float64(numSampled)
+	for name, se := range subEstimates {
+		se.Paths = len(subPaths[name])
+		se.UpdatesPerInterval = int64(float64(se.UpdatesPerInterval) * scale)
+		se.EventsPerInterval = int64(float64(se.EventsPerInterval) * scale)
+		se.BytesPerSecond *= scale
+		est.Subscriptions = append(est.Subscriptions, se)
+	}
+	sort.Slice(est.Subscriptions, func(i, j int) bool {
+		return est.Subscriptions[i].Name < est.Subscriptions[j].Name
+	})
+	for _, oe := range oes {
+		est.Outputs = append(est.Outputs, &OutputEstimate{
+			Name:            oe.name,
+			Type:            oe.typ,
+			Series:          int64(float64(len(oe.series)) * scale),
+			EventsPerSecond: oe.events * scale,
+			BytesPerSecond:  oe.bytes * scale,
+		})
+	}
+	pcs := make([]*pathCounter, 0, len(paths))
+	for _, pc := range paths {
+		pcs = append(pcs, pc)
+	}
+	sort.Slice(pcs, func(i, j int) bool {
+		if pcs[i].bytes == pcs[j].bytes {
+			return pcs[i].path < pcs[j].path
+		}
+		return pcs[i].bytes > pcs[j].bytes
+	})
+	for i, pc := range pcs {
+		if i >= req.TopPaths {
+			break
+		}
+		est.TopPaths = append(est.TopPaths, &PathEstimate{
+			Path:               pc.path,
+			Subscription:       pc.sub,
+			UpdatesPerInterval: int64(float64(pc.updates) * scale),
+			BytesPerSecond:     pc.bytes * scale,
+		})
+	}
+	return est, nil
+}
+
+// estimate passes the responses of a sampled target subscription
+// through fresh instances of the output processors, including the
+// events they hold until flushed, and counts the written series and bytes.
+// The processors run as a dry run: the processors with side effects
+// are left out and the events are not traced nor cataloged.
+func (oe *outputEstimator) estimate(name string, r *estimateResponses) (err error) {
+	evps, err := oe.newEvps()
+	if err != nil {
+		return err
+	}
+	// the processors are not guarded in a dry run.
+	defer func() {
+		if rc := recover(); rc != nil {
+			err = fmt.Errorf("event processor panic: %v", rc)
+		}
+	}()
+	meta := map[string]string{
+		"source":            name,
+		"subscription-name": r.sub.name,
+	}
+	for _, rsp := range r.rsps {
+		if rsp.GetUpdate() == nil {
+			continue
+		}
+		evs, err := formatters.DryRunResponseToEventMsgs(r.sub.name, rsp, meta, evps...)
+		if err != nil {
+			continue
+		}
+		oe.measureEvents(evs, r.sub.interval)
+	}
+	oe.measureEvents(formatters.FlushEventProcessors(evps, time.Now(), nil), r.sub.interval)
+	return nil
+}
+
+func (oe *outputEstimator) measureEvents(evs []*formatters.EventMsg, interval time.Duration) {
+	for _, ev := range evs {
+		keys, size := oe.measure(ev)
+		for _, k := range keys {
+			oe.series[k] = struct{}{}
+		}
+		oe.events += 1 / interval.Seconds()
+		oe.bytes += float64(size) / interval.Seconds()
+	}
+}
+
+// sampleTargets returns up to n target names picked at random, sorted.
+func sampleTargets(names []string, n int) []string {
+	if len(names) > n {
+		rand.Shuffle(len(names), func(i, j int) {
+			names[i], names[j] = names[j], names[i]
+		})
+		names = names[:n]
+	}
+	sort.Strings(names)
+	return names
+}
+
+type estimateResponses struct {
+	sub  *estimateSub
+	rsps []*gnmi.SubscribeResponse
+}
+
+// estimateTarget runs the ONCE version of the target subscriptions.
+// The target is not added to the app targets.
+func (a *App) estimateTarget(ctx context.Context, tc *types.TargetConfig, subs map[string]*types.SubscriptionConfig) ([]*estimateResponses, error) {
+	t := target.NewTarget(tc)
+	err := a.CreateGNMIClient(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	defer t.Close()
+
+	subNames := tc.Subscriptions
+	if len(subNames) == 0 {
+		for n := range subs {
+			subNames = append(subNames, n)
+		}
+	}
+	sort.Strings(subNames)
+	res := make([]*estimateResponses, 0, len(subNames))
+	for _, n := range subNames {
+		sc, ok := subs[n]
+		if !ok {
+			continue
+		}
+		for _, es := range onceSubscriptions(sc) {
+			req, err := a.Config.CreateSubscribeRequest(es.sc, tc)
+			if err != nil {
+				return nil, err
+			}
+			sctx, cancel := context.WithTimeout(ctx, tc.Timeout)
+			rsps, err := t.SubscribeOnce(sctx, req)
+			cancel()
+			if err != nil {
+				return nil, fmt.Errorf("subscription %q: %v", n, err)
+			}
+			res = append(res, &estimateResponses{sub: es, rsps: rsps})
+		}
+	}
+	return res, nil
+}
+
+// onceSubscriptions converts a subscription config into ONCE subscriptions,
+// one per stream subscription.
+func onceSubscriptions(sc *types.SubscriptionConfig) []*estimateSub {
+	if len(sc.StreamSubscriptions) == 0 {
+		return []*estimateSub{newEstimateSub(sc, sc)}
+	}
+	ess := make([]*estimateSub, 0, len(sc.StreamSubscriptions))
+	for _, ssc := range sc.StreamSubscriptions {
+		nsc := *ssc
+		if nsc.SampleInterval == nil {
+			nsc.SampleInterval = sc.SampleInterval
+		}
+		if nsc.HeartbeatInterval == nil {
+			nsc.HeartbeatInterval = sc.HeartbeatInterval
+		}
+		nsc.Prefix = sc.Prefix
+		nsc.Target = sc.Target
+		nsc.SetTarget = sc.SetTarget
+		nsc.Encoding = sc.Encoding
+		nsc.Models = sc.Models
+		nsc.Outputs = sc.Outputs
+		ess = append(ess, newEstimateSub(sc, &nsc))
+	}
+	return ess
+}
+
+func newEstimateSub(parent, sc *types.SubscriptionConfig) *estimateSub {
+	nsc := *sc
+	nsc.Name = parent.Name
+	nsc.Mode = "ONCE"
+	nsc.StreamMode = ""
+	nsc.StreamSubscriptions = nil
+	es := &estimateSub{name: parent.Name, sc: &nsc}
+	switch {
+	case sc.SampleInterval != nil && *sc.SampleInterval > 0:
+		es.interval = *sc.SampleInterval
+	case sc.HeartbeatInterval != nil && *sc.HeartbeatInterval > 0:
+		es.interval = *sc.HeartbeatInterval
+	default:
+		es.interval = defaultEstimateInterval
+		es.assumed = true
+	}
+	return es
+}
+
+func (a *App) outputEstimators(tcs map[string]*types.TargetConfig, names []string) ([]*outputEstimator, error) {
+	for _, n := range names {
+		if _, ok := a.Config.Outputs[n]; !ok {
+			return nil, fmt.Errorf("unknown output %q", n)
+		}
+	}
+	outNames := make([]string, 0, len(a.Config.Outputs))
+	for n := range a.Config.Outputs {
+		if len(names) > 0 && !strInSlice(n, names) {
+			continue
+		}
+		outNames = append(outNames, n)
+	}
+	sort.Strings(outNames)
+	oes := make([]*outputEstimator, 0, len(outNames))
+	for _, n := range outNames {
+		cfg := a.Config.Outputs[n]
+		oe := &outputEstimator{
+			name:   n,
+			series: make(map[string]struct{}),
+		}
+		oe.typ, _ = cfg["type"].(string)
+		var epNames []string
+		switch eps := cfg["event-processors"].(type) {
+		case []string:
+			epNames = eps
+		case []interface{}:
+			for _, ep := range eps {
+				epNames = append(epNames, fmt.Sprint(ep))
+			}
+		}
+		processors := a.Config.Processors
+		actions := a.Config.Actions
+		oe.newEvps = func() ([]formatters.EventProcessor, error) {
+			return formatters.MakeDryRunEventProcessors(a.Logger, epNames, processors, tcs, actions)
+		}
+		// check the processors config once before sampling the targets
+		if _, err := oe.newEvps(); err != nil {
+			return nil, fmt.Errorf("output %q: %v", n, err)
+		}
+		switch oe.typ {
+		case "prometheus", "prometheus_write":
+			mb, err := a.promMetricBuilder(n)
+			if err != nil {
+				return nil, err
+			}
+			oe.measure = promMeasure(mb)
+		case "influxdb":
+			oe.measure = influxMeasure
+		default:
+			oe.measure = eventMeasure
+		}
+		oes = append(oes, oe)
+	}
+	return oes, nil
+}
+
+func promMeasure(mb *promcom.MetricBuilder) func(ev *formatters.EventMsg) ([]string, int) {
+	return func(ev *formatters.EventMsg) ([]string, int) {
+		pms := mb.MetricsFromEvent(ev, time.Now())
+		keys := make([]string, 0, len(pms))
+		b := new(bytes.Buffer)
+		for _, pm := range pms {
+			keys = append(keys, strconv.FormatUint(pm.CalculateKey(), 10))
+			_ = writePromMetric(b, pm)
+		}
+		return keys, b.Len()
+	}
+}
+
+func influxMeasure(ev *formatters.EventMsg) ([]string, int) {
+	if len(ev.Values) == 0 {
+		return nil, 0
+	}
+	name := ev.Name
+	tags := make(map[string]string, len(ev.Tags))
+	for k, v := range ev.Tags {
+		tags[k] = v
+	}
+	if subscriptionName, ok := tags["subscription-name"]; ok {
+		name = subscriptionName
+		delete(tags, "subscription-name")
+	}
+	p := influxdb2.NewPoint(name, tags, ev.Values, time.Unix(0, ev.Timestamp))
+	return []string{seriesKey(name, tags)}, len(write.PointToLineProtocol(p, time.Nanosecond))
+}
+
+func eventMeasure(ev *formatters.EventMsg) ([]string, int) {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return nil, 0
+	}
+	keys := make([]string, 0, len(ev.Values))
+	sk := seriesKey(ev.Name, ev.Tags)
+	for k := range ev.Values {
+		keys = append(keys, sk+"\x00"+k)
+	}
+	return keys, len(b)
+}
+
+func seriesKey(name string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	sb := new(strings.Builder)
+	sb.WriteString(name)
+	for _, k := range keys {
+		sb.WriteString(",")
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(tags[k])
+	}
+	return sb.String()
+}
+
+func selectTargets(tcs map[string]*types.TargetConfig, names []string) (map[string]*types.TargetConfig, error) {
+	if len(names) == 0 {
+		return tcs, nil
+	}
+	res := make(map[string]*types.TargetConfig, len(names))
+	for _, n := range names {
+		tc, ok := tcs[n]
+		if !ok {
+			return nil, fmt.Errorf("unknown target %q", n)
+		}
+		res[n] = tc
+	}
+	return res, nil
+}
+
+func selectSubscriptions(subs map[string]*types.SubscriptionConfig, names []string) (map[string]*types.SubscriptionConfig, error) {
+	if len(subs) == 0 {
+		return nil, errors.New("no subscriptions configuration found")
+	}
+	if len(names) == 0 {
+		return subs, nil
+	}
+	res := make(map[string]*types.SubscriptionConfig, len(names))
+	for _, n := range names {
+		sc, ok := subs[n]
+		if !ok {
+			return nil, fmt.Errorf("unknown subscription %q", n)
+		}
+		res[n] = sc
+	}
+	return res, nil
+}
+
+func strInSlice(s string, ls []string) bool {
+	for _, ss := range ls {
+		if ss == s {
+			return true
+		}
+	}
+	return false
+}
+
+func printEstimate(est *Estimate) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "targets: %d, sampled: %s\n\n", est.NumTargets, strings.Join(est.SampledTargets, ", "))
+	fmt.Fprintln(w, "SUBSCRIPTION\tINTERVAL\tPATHS\tUPDATES/INTERVAL\tEVENTS/INTERVAL\tBYTES/S")
+	for _, se := range est.Subscriptions {
+		interval := se.Interval
+		if se.IntervalAssumed {
+			interval += " (assumed)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%.0f\n", se.Name, interval, se.Paths, se.UpdatesPerInterval, se.EventsPerInterval, se.BytesPerSecond)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "OUTPUT\tTYPE\tSERIES\tEVENTS/S\tBYTES/S")
+	for _, oe := range est.Outputs {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%.1f\t%.0f\n", oe.Name, oe.Type, oe.Series, oe.EventsPerSecond, oe.BytesPerSecond)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "PATH\tSUBSCRIPTION\tUPDATES/INTERVAL\tBYTES/S")
+	for _, pe := range est.TopPaths {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%.0f\n", pe.Path, pe.Subscription, pe.UpdatesPerInterval, pe.BytesPerSecond)
+	}
+	for _, e := range est.Errors {
+		fmt.Fprintf(w, "\nerror: %s", e)
+	}
+	w.Flush()
+}
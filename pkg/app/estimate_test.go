@@ -0,0 +1,159 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AlekSi/pointer"
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+)
+
+func TestOnceSubscriptions(t *testing.T) {
+	tests := map[string]struct {
+		in        *types.SubscriptionConfig
+		intervals []time.Duration
+		assumed   []bool
+	}{
+		"sample": {
+			in: &types.SubscriptionConfig{
+				Name:           "sub1",
+				Paths:          []string{"/interface"},
+				Mode:           "stream",
+				StreamMode:     "sample",
+				SampleInterval: pointer.ToDuration(10 * time.Second),
+			},
+			intervals: []time.Duration{10 * time.Second},
+			assumed:   []bool{false},
+		},
+		"on_change": {
+			in: &types.SubscriptionConfig{
+				Name:       "sub1",
+				Paths:      []string{"/interface"},
+				Mode:       "stream",
+				StreamMode: "on-change",
+			},
+			intervals: []time.Duration{defaultEstimateInterval},
+			assumed:   []bool{true},
+		},
+		"stream_subscriptions": {
+			in: &types.SubscriptionConfig{
+				Name:           "sub1",
+				SampleInterval: pointer.ToDuration(30 * time.Second),
+				StreamSubscriptions: []*types.SubscriptionConfig{
+					{
+						Paths:          []string{"/interface"},
+						StreamMode:     "sample",
+						SampleInterval: pointer.ToDuration(10 * time.Second),
+					},
+					{
+						Paths:      []string{"/system"},
+						StreamMode: "sample",
+					},
+				},
+			},
+			intervals: []time.Duration{10 * time.Second, 30 * time.Second},
+			assumed:   []bool{false, false},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ess := onceSubscriptions(tt.in)
+			if len(ess) != len(tt.intervals) {
+				t.Fatalf("expected %d subscriptions, got %d", len(tt.intervals), len(ess))
+			}
+			for i, es := range ess {
+				if es.name != tt.in.Name || es.sc.Name != tt.in.Name {
+					t.Errorf("unexpected subscription name %q", es.name)
+				}
+				if es.sc.Mode != "ONCE" || len(es.sc.StreamSubscriptions) != 0 {
+					t.Errorf("subscription %d is not a ONCE subscription: %+v", i, es.sc)
+				}
+				if es.interval != tt.intervals[i] {
+					t.Errorf("subscription %d: expected interval %s, got %s", i, tt.intervals[i], es.interval)
+				}
+				if es.assumed != tt.assumed[i] {
+					t.Errorf("subscription %d: expected assumed %v, got %v", i, tt.assumed[i], es.assumed)
+				}
+			}
+		})
+	}
+}
+
+func TestSampleTargets(t *testing.T) {
+	names := []string{"r1", "r2", "r3", "r4", "r5", "r6", "r7", "r8"}
+	seen := make(map[string]struct{})
+	for i := 0; i < 50; i++ {
+		sample := sampleTargets(append([]string(nil), names...), 3)
+		if len(sample) != 3 {
+			t.Fatalf("expected 3 sampled targets, got %v", sample)
+		}
+		for j, n := range sample {
+			if j > 0 && sample[j-1] >= n {
+				t.Fatalf("expected sorted, distinct targets, got %v", sample)
+			}
+			seen[n] = struct{}{}
+		}
+	}
+	// the first targets by name are not always the sampled ones
+	if len(seen) <= 3 {
+		t.Errorf("expected the samples to spread over the targets, got %v", seen)
+	}
+	if got := sampleTargets([]string{"r2", "r1"}, 3); len(got) != 2 || got[0] != "r1" {
+		t.Errorf("unexpected sample %v", got)
+	}
+}
+
+func TestEstimateSideEffects(t *testing.T) {
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	a := New()
+	a.Config.Outputs = map[string]map[string]interface{}{
+		"out1": {"type": "file", "event-processors": []string{"trigger1"}},
+	}
+	a.Config.Processors = map[string]map[string]interface{}{
+		"trigger1": {"event-trigger": map[string]interface{}{"actions": []string{"act1"}}},
+	}
+	a.Config.Actions = map[string]map[string]interface{}{
+		"act1": {"type": "http", "url": srv.URL},
+	}
+	oes, err := a.outputEstimators(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &estimateResponses{
+		sub: &estimateSub{name: "sub1", interval: time.Second},
+		rsps: []*gnmi.SubscribeResponse{{Response: &gnmi.SubscribeResponse_Update{Update: &gnmi.Notification{
+			Timestamp: 1,
+			Update: []*gnmi.Update{{
+				Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "a"}}},
+				Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 1}},
+			}},
+		}}}},
+	}
+	if err := oes[0].estimate("t1", r); err != nil {
+		t.Fatal(err)
+	}
+	if oes[0].events == 0 {
+		t.Errorf("expected the events to be counted")
+	}
+	if n := calls.Load(); n != 0 {
+		t.Errorf("expected the trigger action not to be called, got %d call(s)", n)
+	}
+}
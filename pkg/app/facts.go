@@ -0,0 +1,108 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/api/target"
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/facts"
+)
+
+// initFacts reads the facts configuration and creates the facts gatherer.
+func (a *App) initFacts() error {
+	fcfg, err := a.Config.GetFacts()
+	if err != nil {
+		return fmt.Errorf("failed reading facts config: %v", err)
+	}
+	a.factsGatherer, err = facts.New(fcfg)
+	if err != nil {
+		return fmt.Errorf("failed initializing facts gathering: %v", err)
+	}
+	facts.SetTemplateStore(a.facts)
+	return nil
+}
+
+// startFacts gathers the target facts in the background once its gNMI client
+// is created, then refreshes them periodically if a refresh interval is configured.
+// The subscriptions do not wait for the facts, the events get the facts tags
+// once they are gathered.
+func (a *App) startFacts(ctx context.Context, t *target.Target) {
+	if a.factsGatherer == nil {
+		return
+	}
+	go func() {
+		a.gatherFacts(ctx, t)
+		interval := a.factsGatherer.Config().RefreshInterval
+		if interval <= 0 {
+			return
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				a.gatherFacts(ctx, t)
+			}
+		}
+	}()
+}
+
+// gatherFacts gathers the facts of a target using its existing gNMI client.
+func (a *App) gatherFacts(ctx context.Context, t *target.Target) map[string]string {
+	fs, errs := a.factsGatherer.Gather(ctx,
+		func(ctx context.Context) (*gnmi.CapabilityResponse, error) {
+			return t.Capabilities(ctx)
+		},
+		t.Get,
+	)
+	a.storeFacts(t.Config.Name, fs, errs)
+	return fs
+}
+
+// collectFacts gathers the facts of a target, creating a gNMI client if needed.
+// It is used by the commands that do not start the collector.
+func (a *App) collectFacts(ctx context.Context, tc *types.TargetConfig) {
+	if a.factsGatherer == nil {
+		return
+	}
+	fs, errs := a.factsGatherer.Gather(ctx,
+		func(ctx context.Context) (*gnmi.CapabilityResponse, error) {
+			return a.ClientCapabilities(ctx, tc)
+		},
+		func(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetResponse, error) {
+			return a.ClientGet(ctx, tc, req)
+		},
+	)
+	a.storeFacts(tc.Name, fs, errs)
+}
+
+func (a *App) storeFacts(name string, fs map[string]string, errs []error) {
+	for _, err := range errs {
+		a.Logger.Printf("target %q: failed to gather facts: %v", name, err)
+	}
+	if a.facts.Set(name, fs, errs) {
+		a.Logger.Printf("target %q: facts updated: %v", name, fs)
+	}
+}
+
+// factsTags returns the target facts to be added as events tags.
+func (a *App) factsTags(name string) map[string]string {
+	if a.factsGatherer == nil {
+		return nil
+	}
+	return a.factsGatherer.Tags(a.facts.Get(name))
+}
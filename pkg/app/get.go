@@ -78,11 +78,7 @@ func (a *App) GetRun(cmd *cobra.Command, args []string) error {
 	// other formats
 	numTargets := len(a.Config.Targets)
 	a.errCh = make(chan error, numTargets*3)
-	a.wg.Add(numTargets)
-	for _, tc := range a.Config.Targets {
-		go a.GetRequest(ctx, tc)
-	}
-	a.wg.Wait()
+	a.runOnTargets(ctx, a.Config.Targets, a.GetRequest)
 	err = a.checkErrors()
 	if err != nil {
 		return err
@@ -91,15 +87,14 @@ func (a *App) GetRun(cmd *cobra.Command, args []string) error {
 }
 
 func (a *App) GetRequest(ctx context.Context, tc *types.TargetConfig) {
-	defer a.wg.Done()
 	req, err := a.Config.CreateGetRequest(tc)
 	if err != nil {
-		a.logError(fmt.Errorf("target %q building Get request failed: %v", tc.Name, err))
+		a.logTargetError(tc.Name, fmt.Errorf("target %q building Get request failed: %v", tc.Name, err))
 		return
 	}
 	response, err := a.getRequest(ctx, tc, req)
 	if err != nil {
-		a.logError(fmt.Errorf("target %q Get request failed: %v", tc.Name, err))
+		a.logTargetError(tc.Name, fmt.Errorf("target %q Get request failed: %v", tc.Name, err))
 		return
 	}
 	if response == nil {
@@ -107,7 +102,7 @@ func (a *App) GetRequest(ctx context.Context, tc *types.TargetConfig) {
 	}
 	err = a.PrintMsg(tc.Name, "Get Response:", response)
 	if err != nil {
-		a.logError(fmt.Errorf("target %q: %v", tc.Name, err))
+		a.logTargetError(tc.Name, fmt.Errorf("target %q: %v", tc.Name, err))
 	}
 }
 
@@ -116,7 +111,7 @@ func (a *App) getRequest(ctx context.Context, tc *types.TargetConfig, req *gnmi.
 	if len(a.Config.LocalFlags.GetModel) > 0 {
 		spModels, unspModels, err := a.filterModels(ctx, tc, a.Config.LocalFlags.GetModel)
 		if err != nil {
-			a.logError(fmt.Errorf("failed getting supported models from %q: %v", tc.Name, err))
+			a.logTargetError(tc.Name, fmt.Errorf("failed getting supported models from %q: %v", tc.Name, err))
 			return nil, err
 		}
 		if len(unspModels) > 0 {
@@ -129,7 +124,7 @@ func (a *App) getRequest(ctx context.Context, tc *types.TargetConfig, req *gnmi.
 	if a.Config.PrintRequest || a.Config.GetDryRun {
 		err := a.PrintMsg(tc.Name, "Get Request:", req)
 		if err != nil {
-			a.logError(fmt.Errorf("target %q Get Request printing failed: %v", tc.Name, err))
+			a.logTargetError(tc.Name, fmt.Errorf("target %q Get Request printing failed: %v", tc.Name, err))
 		}
 	}
 	if a.Config.GetDryRun {
@@ -199,6 +194,7 @@ func (a *App) InitGetFlags(cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(&a.Config.LocalFlags.GetValuesOnly, "values-only", "", false, "print GetResponse values only")
 	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.GetProcessor, "processor", "", []string{}, "list of processor names to run")
 	cmd.Flags().Uint32VarP(&a.Config.LocalFlags.GetDepth, "depth", "", 0, "depth extension value")
+	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.GetExtension, "extension", "", []string{}, "registered extension to add to the request, in the format name=value, the value is JSON or YAML")
 	cmd.Flags().BoolVarP(&a.Config.LocalFlags.GetDryRun, "dry-run", "", false, "prints the get request without initiating a gRPC connection")
 
 	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
@@ -242,29 +238,27 @@ func (a *App) intializeEventProcessors() ([]formatters.EventProcessor, error) {
 func (a *App) handleGetRequestEvent(ctx context.Context, evps []formatters.EventProcessor) error {
 	numTargets := len(a.Config.Targets)
 	a.errCh = make(chan error, numTargets*3)
-	a.wg.Add(numTargets)
 	rsps := make(chan *getResponseEvents, numTargets)
-	for _, tc := range a.Config.Targets {
-		go func(tc *types.TargetConfig) {
-			defer a.wg.Done()
-			req, err := a.Config.CreateGetRequest(tc)
-			if err != nil {
-				a.errCh <- err
-				return
-			}
-			resp, err := a.getRequest(ctx, tc, req)
-			if err != nil {
-				a.errCh <- err
-				return
-			}
-			evs, err := formatters.GetResponseToEventMsgs(resp, map[string]string{"source": tc.Name}, evps...)
-			if err != nil {
-				a.errCh <- err
-			}
-			rsps <- &getResponseEvents{name: tc.Name, rsp: evs}
-		}(tc)
-	}
-	a.wg.Wait()
+	a.runOnTargets(ctx, a.Config.Targets, func(ctx context.Context, tc *types.TargetConfig) {
+		req, err := a.Config.CreateGetRequest(tc)
+		if err != nil {
+			a.targetResults.fail(tc.Name, err)
+			a.errCh <- err
+			return
+		}
+		resp, err := a.getRequest(ctx, tc, req)
+		if err != nil {
+			a.targetResults.fail(tc.Name, err)
+			a.errCh <- err
+			return
+		}
+		evs, err := formatters.GetResponseToEventMsgs(resp, map[string]string{"source": tc.Name}, evps...)
+		if err != nil {
+			a.targetResults.fail(tc.Name, err)
+			a.errCh <- err
+		}
+		rsps <- &getResponseEvents{name: tc.Name, rsp: evs}
+	})
 	close(rsps)
 
 	responses := make(map[string][]*formatters.EventMsg)
@@ -62,21 +62,18 @@ func (a *App) GetSetRunE(cmd *cobra.Command, args []string) error {
 	}
 	numTargets := len(a.Config.Targets)
 	a.errCh = make(chan error, numTargets*3)
-	a.wg.Add(numTargets)
-	for _, tc := range a.Config.Targets {
-		go a.GetSetRequest(ctx, tc, req)
-	}
-	a.wg.Wait()
+	a.runOnTargets(ctx, a.Config.Targets, func(ctx context.Context, tc *types.TargetConfig) {
+		a.GetSetRequest(ctx, tc, req)
+	})
 	return a.checkErrors()
 }
 
 func (a *App) GetSetRequest(ctx context.Context, tc *types.TargetConfig, req *gnmi.GetRequest) {
-	defer a.wg.Done()
 	xreq := req
 	if len(a.Config.LocalFlags.GetSetModel) > 0 {
 		spModels, unspModels, err := a.filterModels(ctx, tc, a.Config.LocalFlags.GetSetModel)
 		if err != nil {
-			a.logError(fmt.Errorf("failed getting supported models from %q: %v", tc.Name, err))
+			a.logTargetError(tc.Name, fmt.Errorf("failed getting supported models from %q: %v", tc.Name, err))
 			return
 		}
 		if len(unspModels) > 0 {
@@ -89,54 +86,54 @@ func (a *App) GetSetRequest(ctx context.Context, tc *types.TargetConfig, req *gn
 	if a.Config.PrintRequest {
 		err := a.PrintMsg(tc.Name, "Get Request:", req)
 		if err != nil {
-			a.logError(fmt.Errorf("target %q Get Request printing failed: %v", tc.Name, err))
+			a.logTargetError(tc.Name, fmt.Errorf("target %q Get Request printing failed: %v", tc.Name, err))
 		}
 	}
 	a.Logger.Printf("sending gNMI GetRequest: prefix='%v', path='%v', type='%v', encoding='%v', models='%+v', extension='%+v' to %s",
 		xreq.Prefix, xreq.Path, xreq.Type, xreq.Encoding, xreq.UseModels, xreq.Extension, tc.Name)
 	response, err := a.ClientGet(ctx, tc, xreq)
 	if err != nil {
-		a.logError(fmt.Errorf("target %q get request failed: %v", tc.Name, err))
+		a.logTargetError(tc.Name, fmt.Errorf("target %q get request failed: %v", tc.Name, err))
 		return
 	}
 	err = a.PrintMsg(tc.Name, "Get Response:", response)
 	if err != nil {
-		a.logError(fmt.Errorf("target %q: %v", tc.Name, err))
+		a.logTargetError(tc.Name, fmt.Errorf("target %q: %v", tc.Name, err))
 	}
 	//
 	q, err := gojq.Parse(a.Config.LocalFlags.GetSetCondition)
 	if err != nil {
-		a.logError(err)
+		a.logTargetError(tc.Name, err)
 		return
 	}
 	code, err := gojq.Compile(q)
 	if err != nil {
-		a.logError(err)
+		a.logTargetError(tc.Name, err)
 		return
 	}
 	mo := formatters.MarshalOptions{Format: "json"}
 	b, err := mo.Marshal(response, map[string]string{"address": tc.Name})
 	if err != nil {
-		a.logError(fmt.Errorf("error marshaling message: %v", err))
+		a.logTargetError(tc.Name, fmt.Errorf("error marshaling message: %v", err))
 		return
 	}
 	var input interface{}
 	err = json.Unmarshal(b, &input)
 	if err != nil {
-		a.logError(fmt.Errorf("error unmarshaling message: %v", err))
+		a.logTargetError(tc.Name, fmt.Errorf("error unmarshaling message: %v", err))
 		return
 	}
 	iter := code.Run(input)
 	var ok bool
 	res, ok := iter.Next()
 	if !ok {
-		a.logError(fmt.Errorf("unexpected jq result type: %v", res))
+		a.logTargetError(tc.Name, fmt.Errorf("unexpected jq result type: %v", res))
 		// iterator not done, so the final result won't be a boolean
 		return
 	}
 	if err, ok = res.(error); ok {
 		if err != nil {
-			a.logError(fmt.Errorf("condition evaluation failed: %v", err))
+			a.logTargetError(tc.Name, fmt.Errorf("condition evaluation failed: %v", err))
 			return
 		}
 	}
@@ -146,7 +143,7 @@ func (a *App) GetSetRequest(ctx context.Context, tc *types.TargetConfig, req *gn
 		if res {
 			setReq, err := a.Config.CreateGASSetRequest(input)
 			if err != nil {
-				a.logError(err)
+				a.logTargetError(tc.Name, err)
 				return
 			}
 			if len(setReq.Delete) == 0 && len(setReq.Replace) == 0 && len(setReq.Update) == 0 {
@@ -157,7 +154,7 @@ func (a *App) GetSetRequest(ctx context.Context, tc *types.TargetConfig, req *gn
 		}
 		return
 	default:
-		a.logError(errors.New("unexpected condition return type"))
+		a.logTargetError(tc.Name, errors.New("unexpected condition return type"))
 		return
 	}
 }
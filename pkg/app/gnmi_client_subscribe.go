@@ -210,6 +210,7 @@ CRCLIENT:
 		}
 	}
 	a.Logger.Printf("target %q gNMI client created", t.Config.Name)
+	a.startFacts(gnmiCtx, t)
 
 	for _, sreq := range subRequests {
 		a.Logger.Printf("sending gNMI SubscribeRequest: subscribe='%+v', mode='%+v', encoding='%+v', to %s",
@@ -31,6 +31,7 @@ import (
 	"github.com/openconfig/gnmic/pkg/api/target"
 	"github.com/openconfig/gnmic/pkg/api/types"
 	"github.com/openconfig/gnmic/pkg/cache"
+	"github.com/openconfig/gnmic/pkg/formatters"
 )
 
 type streamClient struct {
@@ -53,6 +54,20 @@ func (a *App) startGnmiServer() error {
 		a.Logger.Printf("failed to initialize gNMI cache: %v", err)
 		return err
 	}
+	a.cacheEvps, err = formatters.MakeEventProcessors(
+		a.Logger,
+		a.Config.GnmiServer.EventProcessors,
+		a.Config.Processors,
+		a.Config.Targets,
+		a.Config.Actions,
+	)
+	if err != nil {
+		a.Logger.Printf("failed to initialize gNMI cache event processors: %v", err)
+		return err
+	}
+	if len(a.cacheEvps) > 0 {
+		a.cacheEvps = formatters.ForOutput("gnmi-server", a.cacheEvps)
+	}
 
 	s, err := server.New(server.Config{
 		Address:              a.Config.GnmiServer.Address,
@@ -53,6 +53,9 @@ START:
 	for targetOp := range ld.Start(ctx) {
 		// do deletes first, since target change equates to delete+add
 		for _, del := range targetOp.Del {
+			if len(a.targetSelectors) > 0 && !a.targetConfigExists(del) {
+				continue
+			}
 			// not clustered, delete local target
 			if !a.inCluster() {
 				err = a.DeleteTarget(ctx, del)
@@ -77,6 +80,9 @@ START:
 				a.Logger.Printf("failed parsing new target configuration %#v: %v", add, err)
 				continue
 			}
+			if !a.targetSelected(add) {
+				continue
+			}
 			// not clustered, add target and subscribe
 			if !a.inCluster() {
 				a.Config.Targets[add.Name] = add
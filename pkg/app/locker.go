@@ -19,3 +19,9 @@ func (a *App) targetLockKey(s string) string {
 	}
 	return fmt.Sprintf("gnmic/%s/targets/%s", a.Config.Clustering.ClusterName, s)
 }
+
+// pausedSubscriptionsKey is the locker key the leader persists
+// the paused subscriptions of target s under.
+func (a *App) pausedSubscriptionsKey(s string) string {
+	return fmt.Sprintf("gnmic/%s/paused-subscriptions/%s", a.Config.Clustering.ClusterName, s)
+}
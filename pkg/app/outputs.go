@@ -44,6 +44,7 @@ func (a *App) InitOutput(ctx context.Context, name string, tcs map[string]*types
 						outputs.WithName(a.Config.InstanceName),
 						outputs.WithClusterName(a.Config.ClusterName),
 						outputs.WithTargetsConfig(tcs),
+						outputs.WithOutputLookup(a.lookupOutput),
 					)
 					if err != nil {
 						a.Logger.Printf("failed to init output type %q: %v", outType, err)
@@ -58,6 +59,15 @@ func (a *App) InitOutput(ctx context.Context, name string, tcs map[string]*types
 	wg.Wait()
 }
 
+// lookupOutput returns the running output called name,
+// it is used by the output groups to reach their members.
+func (a *App) lookupOutput(name string) (outputs.Output, bool) {
+	a.operLock.RLock()
+	defer a.operLock.RUnlock()
+	o, ok := a.Outputs[name]
+	return o, ok
+}
+
 func (a *App) InitOutputs(ctx context.Context) {
 	for name := range a.Config.Outputs {
 		a.InitOutput(ctx, name, a.Config.Targets)
@@ -36,6 +36,13 @@ func (a *App) ProcessorRunE(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed reading event processors config: %v", err)
 	}
+	gcfg, err := a.Config.GetProcessorsGuard()
+	if err != nil {
+		return fmt.Errorf("failed reading processors guard config: %v", err)
+	}
+	if gcfg != nil {
+		formatters.SetGuard(formatters.NewGuard(gcfg))
+	}
 	tcs, err := a.Config.GetTargets()
 	if err != nil {
 		if !errors.Is(err, config.ErrNoTargetsFound) {
@@ -80,6 +87,14 @@ func (a *App) ProcessorRunE(cmd *cobra.Command, args []string) error {
 		}
 		evInput = append(evInput, evs)
 	}
+	if a.Config.LocalFlags.ProcessorTrace {
+		t, err := formatters.NewTracer(&formatters.TraceConfig{BufferSize: len(evInput)})
+		if err != nil {
+			return err
+		}
+		formatters.SetTracer(t)
+		defer formatters.SetTracer(nil)
+	}
 	rrevs := make([][]*formatters.EventMsg, 0, len(evInput))
 	for _, evs := range evInput {
 		revs := evs
@@ -89,6 +104,15 @@ func (a *App) ProcessorRunE(cmd *cobra.Command, args []string) error {
 		rrevs = append(rrevs, revs)
 	}
 
+	if t := formatters.GetTracer(); t != nil {
+		b, err := json.MarshalIndent(t.Traces("", "", ""), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
 	if len(a.Config.LocalFlags.ProcessorOutput) != 0 {
 		b, err := a.promFormat(rrevs, a.Config.LocalFlags.ProcessorOutput)
 		if err != nil {
@@ -121,9 +145,33 @@ func (a *App) InitProcessorFlags(cmd *cobra.Command) {
 	cmd.Flags().StringSliceVarP(&a.Config.LocalFlags.ProcessorName, "name", "", nil, "list of processors to apply to the input")
 	cmd.MarkFlagRequired("name")
 	cmd.Flags().StringVarP(&a.Config.LocalFlags.ProcessorOutput, "output", "", "", "output name")
+	cmd.Flags().BoolVarP(&a.Config.LocalFlags.ProcessorTrace, "trace", "", false, "print the events lineage through the processors instead of the resulting events")
 }
 
 func (a *App) promFormat(rrevs [][]*formatters.EventMsg, outName string) ([]byte, error) {
+	mb, err := a.promMetricBuilder(outName)
+	if err != nil {
+		return nil, err
+	}
+	b := new(bytes.Buffer)
+	now := time.Now()
+	for _, revs := range rrevs {
+		for _, ev := range revs {
+			pms := mb.MetricsFromEvent(ev, now)
+			for _, pm := range pms {
+				err = writePromMetric(b, pm)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// promMetricBuilder creates a prometheus metric builder
+// from the configuration of the output outName.
+func (a *App) promMetricBuilder(outName string) (*promcom.MetricBuilder, error) {
 	// read output config
 	outputPath := "outputs/" + outName
 	outputConfig := a.Config.FileConfig.GetStringMap(outputPath)
@@ -131,39 +179,32 @@ func (a *App) promFormat(rrevs [][]*formatters.EventMsg, outName string) ([]byte
 		return nil, fmt.Errorf("unknown output name: %s", outName)
 	}
 	outType := a.Config.FileConfig.GetString(outputPath + "/type")
-	if outType != "prometheus" && outType != "remote_write" {
-		return nil, fmt.Errorf("output %q must be of type 'prometheus' or 'remote_write'", outName)
+	switch outType {
+	case "prometheus", "prometheus_write", "remote_write":
+	default:
+		return nil, fmt.Errorf("output %q must be of type 'prometheus' or 'prometheus_write'", outName)
 	}
-	mb := &promcom.MetricBuilder{
+	return &promcom.MetricBuilder{
 		Prefix:                 a.Config.FileConfig.GetString(outputPath + "/metric-prefix"),
 		AppendSubscriptionName: a.Config.FileConfig.GetBool(outputPath + "/append-subscription-name"),
 		StringsAsLabels:        a.Config.FileConfig.GetBool(outputPath + "/strings-as-labels"),
 		OverrideTimestamps:     a.Config.FileConfig.GetBool(outputPath + "/override-timestamps"),
 		ExportTimestamps:       a.Config.FileConfig.GetBool(outputPath + "/export-timestamps"),
-	}
+	}, nil
+}
 
-	b := new(bytes.Buffer)
-	now := time.Now()
-	for _, revs := range rrevs {
-		for _, ev := range revs {
-			pms := mb.MetricsFromEvent(ev, now)
-			for _, pm := range pms {
-				m := &dto.Metric{}
-				err := pm.Write(m)
-				if err != nil {
-					return nil, err
-				}
-				_, err = expfmt.MetricFamilyToText(b, &dto.MetricFamily{
-					Name:   pointer.ToString(pm.Name),
-					Help:   pointer.ToString("gNMIc generated metric"),
-					Type:   dto.MetricType_UNTYPED.Enum(),
-					Metric: []*dto.Metric{m},
-				})
-				if err != nil {
-					return nil, err
-				}
-			}
-		}
+// writePromMetric writes the prometheus text format of pm to b.
+func writePromMetric(b *bytes.Buffer, pm *promcom.PromMetric) error {
+	m := &dto.Metric{}
+	err := pm.Write(m)
+	if err != nil {
+		return err
 	}
-	return b.Bytes(), nil
+	_, err = expfmt.MetricFamilyToText(b, &dto.MetricFamily{
+		Name:   pointer.ToString(pm.Name),
+		Help:   pointer.ToString("gNMIc generated metric"),
+		Type:   dto.MetricType_UNTYPED.Enum(),
+		Metric: []*dto.Metric{m},
+	})
+	return err
 }
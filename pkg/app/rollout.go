@@ -0,0 +1,629 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/api/target"
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/config"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	rolloutStateRunning     = "running"
+	rolloutStatePaused      = "paused"
+	rolloutStateSucceeded   = "succeeded"
+	rolloutStateRollingBack = "rolling-back"
+	rolloutStateRolledBack  = "rolled-back"
+	rolloutStateFailed      = "failed"
+
+	rolloutOpRollback = "rollback"
+	rolloutOpContinue = "continue"
+)
+
+var errRolloutInProgress = errors.New("a rollout of the subscription is already in progress")
+
+// SubscriptionRolloutStatus is the state of a subscription rollout returned by the API.
+type SubscriptionRolloutStatus struct {
+	ID           string `json:"id"`
+	Subscription string `json:"subscription"`
+	State        string `json:"state"`
+	// current stage: 0 is the canary stage, then one stage per wave.
+	Stage          int               `json:"stage"`
+	UpdatedTargets []string          `json:"updated-targets,omitempty"`
+	Failures       []*RolloutFailure `json:"failures,omitempty"`
+	Error          string            `json:"error,omitempty"`
+	StartedAt      time.Time         `json:"started-at"`
+	FinishedAt     *time.Time        `json:"finished-at,omitempty"`
+
+	Strategy *config.SubscriptionRollout `json:"strategy,omitempty"`
+	Previous *types.SubscriptionConfig   `json:"previous,omitempty"`
+	Config   *types.SubscriptionConfig   `json:"config,omitempty"`
+}
+
+// RolloutFailure is a target that failed the rollout thresholds.
+type RolloutFailure struct {
+	Target string `json:"target"`
+	Stage  int    `json:"stage"`
+	Reason string `json:"reason"`
+}
+
+// rolloutObservation is what a target did since the change was applied to it.
+type rolloutObservation struct {
+	start    time.Time
+	errors   int
+	lastErr  string
+	updates  int
+	syncTime time.Duration
+}
+
+type subscriptionRollout struct {
+	m        *sync.Mutex
+	status   *SubscriptionRolloutStatus
+	strategy *config.SubscriptionRollout
+	prev     *types.SubscriptionConfig
+	next     *types.SubscriptionConfig
+	// targets the change was applied to
+	updated map[string]struct{}
+	// observations of the targets of the current stage
+	obs map[string]*rolloutObservation
+	// receives the operations requested through the API
+	ops chan string
+}
+
+// subscriptionRollouts tracks the subscription rollouts of the instance.
+type subscriptionRollouts struct {
+	m        *sync.RWMutex
+	seq      int
+	rollouts map[string]*subscriptionRollout
+	// in progress rollouts by subscription name
+	active map[string]*subscriptionRollout
+}
+
+func newSubscriptionRollouts() *subscriptionRollouts {
+	return &subscriptionRollouts{
+		m:        new(sync.RWMutex),
+		rollouts: make(map[string]*subscriptionRollout),
+		active:   make(map[string]*subscriptionRollout),
+	}
+}
+
+// add registers a new rollout of next, it fails if a rollout
+// of the same subscription is in progress.
+func (rs *subscriptionRollouts) add(prev, next *types.SubscriptionConfig, strategy *config.SubscriptionRollout) (*subscriptionRollout, error) {
+	rs.m.Lock()
+	defer rs.m.Unlock()
+	if _, ok := rs.active[next.Name]; ok {
+		return nil, errRolloutInProgress
+	}
+	rs.seq++
+	r := &subscriptionRollout{
+		m: new(sync.Mutex),
+		status: &SubscriptionRolloutStatus{
+			ID:           fmt.Sprintf("%s-%d", next.Name, rs.seq),
+			Subscription: next.Name,
+			State:        rolloutStateRunning,
+			StartedAt:    time.Now(),
+			Strategy:     strategy,
+			Previous:     prev,
+			Config:       next,
+		},
+		strategy: strategy,
+		prev:     prev,
+		next:     next,
+		updated:  make(map[string]struct{}),
+		obs:      make(map[string]*rolloutObservation),
+		ops:      make(chan string),
+	}
+	rs.rollouts[r.status.ID] = r
+	rs.active[next.Name] = r
+	return r, nil
+}
+
+func (rs *subscriptionRollouts) done(r *subscriptionRollout) {
+	rs.m.Lock()
+	defer rs.m.Unlock()
+	if rs.active[r.next.Name] == r {
+		delete(rs.active, r.next.Name)
+	}
+}
+
+func (rs *subscriptionRollouts) get(id string) (*subscriptionRollout, bool) {
+	rs.m.RLock()
+	defer rs.m.RUnlock()
+	r, ok := rs.rollouts[id]
+	return r, ok
+}
+
+func (rs *subscriptionRollouts) list() []*SubscriptionRolloutStatus {
+	rs.m.RLock()
+	defer rs.m.RUnlock()
+	sts := make([]*SubscriptionRolloutStatus, 0, len(rs.rollouts))
+	for _, r := range rs.rollouts {
+		sts = append(sts, r.getStatus())
+	}
+	sort.Slice(sts, func(i, j int) bool {
+		return sts[i].StartedAt.Before(sts[j].StartedAt)
+	})
+	return sts
+}
+
+// observe calls fn with the observation of target for the in progress
+// rollout of subscription sub, if any.
+func (rs *subscriptionRollouts) observe(targetName, sub string, fn func(o *rolloutObservation)) {
+	if rs == nil {
+		return
+	}
+	rs.m.RLock()
+	r, ok := rs.active[sub]
+	rs.m.RUnlock()
+	if !ok {
+		return
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	if o, ok := r.obs[targetName]; ok {
+		fn(o)
+	}
+}
+
+// observeResponse records a subscribe response received from a target.
+func (rs *subscriptionRollouts) observeResponse(targetName, sub string, rsp *gnmi.SubscribeResponse) {
+	rs.observe(targetName, sub, func(o *rolloutObservation) {
+		switch rsp := rsp.GetResponse().(type) {
+		case *gnmi.SubscribeResponse_Update:
+			o.updates += len(rsp.Update.GetUpdate()) + len(rsp.Update.GetDelete())
+		case *gnmi.SubscribeResponse_SyncResponse:
+			if o.syncTime == 0 {
+				o.syncTime = time.Since(o.start)
+			}
+		}
+	})
+}
+
+// observeError records a subscription error returned by a target.
+func (rs *subscriptionRollouts) observeError(targetName, sub string, err error) {
+	rs.observe(targetName, sub, func(o *rolloutObservation) {
+		o.errors++
+		o.lastErr = err.Error()
+	})
+}
+
+func (r *subscriptionRollout) getStatus() *SubscriptionRolloutStatus {
+	r.m.Lock()
+	defer r.m.Unlock()
+	st := *r.status
+	st.UpdatedTargets = make([]string, 0, len(r.updated))
+	for n := range r.updated {
+		st.UpdatedTargets = append(st.UpdatedTargets, n)
+	}
+	sort.Strings(st.UpdatedTargets)
+	st.Failures = slices.Clone(r.status.Failures)
+	return &st
+}
+
+func (r *subscriptionRollout) setState(state string) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.status.State = state
+	switch state {
+	case rolloutStateSucceeded, rolloutStateRolledBack, rolloutStateFailed:
+		now := time.Now()
+		r.status.FinishedAt = &now
+	}
+}
+
+// evaluate checks the observations of the current stage targets against
+// the rollout thresholds, it returns the failed targets.
+func (r *subscriptionRollout) evaluate(stage int, bake time.Duration) []*RolloutFailure {
+	r.m.Lock()
+	defer r.m.Unlock()
+	names := make([]string, 0, len(r.obs))
+	for n := range r.obs {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	failures := make([]*RolloutFailure, 0)
+	for _, n := range names {
+		o := r.obs[n]
+		var reason string
+		switch {
+		case o.errors > r.strategy.MaxErrors:
+			reason = fmt.Sprintf("%d subscription error(s) exceed the max of %d, last error: %s", o.errors, r.strategy.MaxErrors, o.lastErr)
+		case r.strategy.MaxSyncTime > 0 && o.syncTime == 0:
+			reason = "no sync response received"
+		case r.strategy.MaxSyncTime > 0 && o.syncTime > r.strategy.MaxSyncTime:
+			reason = fmt.Sprintf("sync time %s exceeds the max of %s", o.syncTime, r.strategy.MaxSyncTime)
+		case o.updates < r.strategy.MinUpdates:
+			reason = fmt.Sprintf("%d update(s) received, below the min of %d", o.updates, r.strategy.MinUpdates)
+		case r.strategy.MaxUpdateRate > 0 && float64(o.updates)/bake.Seconds() > r.strategy.MaxUpdateRate:
+			reason = fmt.Sprintf("update rate %.2f/s exceeds the max of %.2f/s", float64(o.updates)/bake.Seconds(), r.strategy.MaxUpdateRate)
+		default:
+			continue
+		}
+		failures = append(failures, &RolloutFailure{Target: n, Stage: stage, Reason: reason})
+	}
+	r.status.Failures = append(r.status.Failures, failures...)
+	return failures
+}
+
+// StartSubscriptionRollout starts a staged rollout of the subscription sc,
+// replacing the existing subscription with the same name.
+func (a *App) StartSubscriptionRollout(sc *types.SubscriptionConfig, strategy *config.SubscriptionRollout) (*SubscriptionRolloutStatus, error) {
+	a.configLock.RLock()
+	prev, ok := a.Config.Subscriptions[sc.Name]
+	a.configLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("subscription %q does not exist", sc.Name)
+	}
+	if err := config.ValidateSubscriptionConfig(sc); err != nil {
+		return nil, err
+	}
+	if strategy == nil {
+		strategy = new(config.SubscriptionRollout)
+	}
+	if err := strategy.SetDefaults(); err != nil {
+		return nil, err
+	}
+	r, err := a.rollouts.add(prev, sc, strategy)
+	if err != nil {
+		return nil, err
+	}
+	a.Logger.Printf("starting rollout %q of subscription %q", r.status.ID, sc.Name)
+	go a.runSubscriptionRollout(a.ctx, r)
+	return r.getStatus(), nil
+}
+
+// rolloutChangedSubscriptions starts a rollout for each subscription
+// changed in the config file.
+func (a *App) rolloutChangedSubscriptions() {
+	subs, err := a.Config.ReadSubscriptionsFromFile()
+	if err != nil {
+		a.Logger.Printf("failed reading subscriptions from new config: %v", err)
+		return
+	}
+	for name, sc := range subs {
+		a.configLock.RLock()
+		cur, ok := a.Config.Subscriptions[name]
+		a.configLock.RUnlock()
+		if !ok {
+			continue
+		}
+		if err := config.ValidateSubscriptionConfig(sc); err != nil {
+			a.Logger.Printf("invalid subscription %q in new config: %v", name, err)
+			continue
+		}
+		curCopy := *cur
+		if err := config.ValidateSubscriptionConfig(&curCopy); err == nil && reflect.DeepEqual(&curCopy, sc) {
+			continue
+		}
+		_, err := a.StartSubscriptionRollout(sc, a.Config.SubscriptionRollout)
+		if err != nil {
+			a.Logger.Printf("failed to start rollout of subscription %q: %v", name, err)
+		}
+	}
+}
+
+func (a *App) runSubscriptionRollout(ctx context.Context, r *subscriptionRollout) {
+	defer a.rollouts.done(r)
+	name := r.next.Name
+	for stage := 0; ; stage++ {
+		targets, err := a.rolloutStageTargets(r, stage)
+		if err != nil {
+			a.Logger.Printf("rollout %q: %v", r.status.ID, err)
+			r.m.Lock()
+			r.status.Error = err.Error()
+			r.m.Unlock()
+			r.setState(rolloutStateFailed)
+			return
+		}
+		if len(targets) == 0 {
+			break
+		}
+		a.Logger.Printf("rollout %q: stage %d: applying subscription %q to %d target(s)", r.status.ID, stage, name, len(targets))
+		r.m.Lock()
+		r.status.Stage = stage
+		r.obs = make(map[string]*rolloutObservation, len(targets))
+		r.m.Unlock()
+		failures := make([]*RolloutFailure, 0)
+		for _, t := range targets {
+			r.m.Lock()
+			r.obs[t.Config.Name] = &rolloutObservation{start: time.Now()}
+			r.updated[t.Config.Name] = struct{}{}
+			r.m.Unlock()
+			err = a.applySubscriptionConfig(t, r.next)
+			if err != nil {
+				failures = append(failures, &RolloutFailure{Target: t.Config.Name, Stage: stage, Reason: err.Error()})
+			}
+		}
+		// bake
+		timer := time.NewTimer(r.strategy.BakePeriod)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case op := <-r.ops:
+			timer.Stop()
+			if op == rolloutOpRollback {
+				a.rollbackSubscription(r)
+				return
+			}
+		case <-timer.C:
+		}
+		r.m.Lock()
+		r.status.Failures = append(r.status.Failures, failures...)
+		r.m.Unlock()
+		failures = append(failures, r.evaluate(stage, r.strategy.BakePeriod)...)
+		if len(failures) == 0 {
+			continue
+		}
+		a.Logger.Printf("rollout %q: stage %d: %d target(s) failed", r.status.ID, stage, len(failures))
+		if r.strategy.OnFailure == config.RolloutOnFailureRollback {
+			a.rollbackSubscription(r)
+			return
+		}
+		r.setState(rolloutStatePaused)
+		select {
+		case <-ctx.Done():
+			return
+		case op := <-r.ops:
+			if op == rolloutOpRollback {
+				a.rollbackSubscription(r)
+				return
+			}
+			a.Logger.Printf("rollout %q: continuing after stage %d", r.status.ID, stage)
+		}
+	}
+	// all targets are updated, the new definition
+	// is used by the targets started from now on.
+	a.configLock.Lock()
+	a.Config.Subscriptions[name] = r.next
+	a.configLock.Unlock()
+	for _, t := range a.rolloutPendingTargets(r) {
+		r.m.Lock()
+		r.updated[t.Config.Name] = struct{}{}
+		r.m.Unlock()
+		if err := a.applySubscriptionConfig(t, r.next); err != nil {
+			a.Logger.Printf("rollout %q: failed to apply subscription %q to target %q: %v", r.status.ID, name, t.Config.Name, err)
+		}
+	}
+	a.Logger.Printf("rollout %q: subscription %q applied to all targets", r.status.ID, name)
+	r.setState(rolloutStateSucceeded)
+}
+
+// rolloutPendingTargets returns the running targets with the rolled out
+// subscription that were not updated yet, sorted by name.
+func (a *App) rolloutPendingTargets(r *subscriptionRollout) []*target.Target {
+	a.operLock.RLock()
+	defer a.operLock.RUnlock()
+	r.m.Lock()
+	defer r.m.Unlock()
+	pending := make([]*target.Target, 0)
+	for n, t := range a.Targets {
+		if _, ok := r.updated[n]; ok {
+			continue
+		}
+		if _, ok := t.Subscriptions[r.next.Name]; ok {
+			pending = append(pending, t)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].Config.Name < pending[j].Config.Name
+	})
+	return pending
+}
+
+// rolloutStageTargets returns the targets the change is applied to at stage:
+// the canary targets first, then waves of the remaining targets.
+func (a *App) rolloutStageTargets(r *subscriptionRollout, stage int) ([]*target.Target, error) {
+	pending := a.rolloutPendingTargets(r)
+	if stage > 0 {
+		if r.strategy.WaveSize > 0 && len(pending) > r.strategy.WaveSize {
+			return pending[:r.strategy.WaveSize], nil
+		}
+		return pending, nil
+	}
+	if len(r.strategy.CanaryTargets) == 0 {
+		if len(pending) > r.strategy.CanarySize {
+			return pending[:r.strategy.CanarySize], nil
+		}
+		return pending, nil
+	}
+	canaries := make([]*target.Target, 0, len(r.strategy.CanaryTargets))
+	for _, t := range pending {
+		if slices.Contains(r.strategy.CanaryTargets, t.Config.Name) {
+			canaries = append(canaries, t)
+		}
+	}
+	if len(canaries) == 0 && len(pending) > 0 {
+		return nil, fmt.Errorf("none of the canary targets %q is running subscription %q", r.strategy.CanaryTargets, r.next.Name)
+	}
+	return canaries, nil
+}
+
+// rollbackSubscription applies the previous subscription definition
+// to the targets updated by the rollout.
+func (a *App) rollbackSubscription(r *subscriptionRollout) {
+	r.setState(rolloutStateRollingBack)
+	a.Logger.Printf("rollout %q: rolling back subscription %q", r.status.ID, r.prev.Name)
+	r.m.Lock()
+	updated := make([]string, 0, len(r.updated))
+	for n := range r.updated {
+		updated = append(updated, n)
+	}
+	r.m.Unlock()
+	for _, n := range updated {
+		a.operLock.RLock()
+		t, ok := a.Targets[n]
+		a.operLock.RUnlock()
+		if !ok {
+			continue
+		}
+		if err := a.applySubscriptionConfig(t, r.prev); err != nil {
+			a.Logger.Printf("rollout %q: failed to roll back subscription %q on target %q: %v", r.status.ID, r.prev.Name, n, err)
+		}
+	}
+	r.setState(rolloutStateRolledBack)
+}
+
+// applySubscriptionConfig replaces the subscription sc.Name of target t with sc.
+func (a *App) applySubscriptionConfig(t *target.Target, sc *types.SubscriptionConfig) error {
+	req, err := a.Config.CreateSubscribeRequest(sc, t.Config)
+	if err != nil {
+		return err
+	}
+	err = t.UpdateSubscription(sc, req)
+	if err != nil {
+		return err
+	}
+	// the sample interval and the series may have changed, they are learned again.
+	a.completeness.reset(t.Config.Name, sc.Name)
+	return nil
+}
+
+// rolloutOperation passes op to the rollout r.
+func (a *App) rolloutOperation(r *subscriptionRollout, op string) error {
+	r.m.Lock()
+	id, state := r.status.ID, r.status.State
+	switch op {
+	case rolloutOpContinue:
+		if state != rolloutStatePaused {
+			r.m.Unlock()
+			return fmt.Errorf("rollout %q is not paused", id)
+		}
+		// set the state before the operation is received so that
+		// a status read after the request returns does not report
+		// the rollout as still paused.
+		r.status.State = rolloutStateRunning
+	case rolloutOpRollback:
+		if state != rolloutStateRunning && state != rolloutStatePaused {
+			r.m.Unlock()
+			return fmt.Errorf("rollout %q is %s", id, state)
+		}
+	}
+	r.m.Unlock()
+	select {
+	case r.ops <- op:
+		return nil
+	case <-time.After(time.Second):
+		r.setState(state)
+		return fmt.Errorf("rollout %q is not accepting operations", id)
+	}
+}
+
+// SubscriptionRolloutRequest is the body of a subscription rollout API request.
+type SubscriptionRolloutRequest struct {
+	// the new subscription definition
+	Config map[string]any `json:"config,omitempty"`
+	// overrides of the configured rollout strategy
+	Strategy map[string]any `json:"strategy,omitempty"`
+}
+
+func (a *App) handleConfigSubscriptionsRollout(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	a.configLock.RLock()
+	_, ok := a.Config.Subscriptions[id]
+	a.configLock.RUnlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("subscription %q not found", id)}})
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	defer r.Body.Close()
+	req := new(SubscriptionRolloutRequest)
+	err = json.Unmarshal(body, req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	sc, err := a.Config.DecodeSubscriptionConfig(id, req.Config)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	strategy := new(config.SubscriptionRollout)
+	if a.Config.SubscriptionRollout != nil {
+		*strategy = *a.Config.SubscriptionRollout
+	}
+	err = outputs.DecodeConfig(req.Strategy, strategy)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	st, err := a.StartSubscriptionRollout(sc, strategy)
+	if err != nil {
+		if errors.Is(err, errRolloutInProgress) {
+			w.WriteHeader(http.StatusConflict)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(st)
+}
+
+func (a *App) handleRolloutsGet(w http.ResponseWriter, r *http.Request) {
+	id, ok := mux.Vars(r)["id"]
+	if !ok {
+		a.handlerCommonGet(w, a.rollouts.list())
+		return
+	}
+	ro, ok := a.rollouts.get(id)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("rollout %q not found", id)}})
+		return
+	}
+	a.handlerCommonGet(w, ro.getStatus())
+}
+
+func (a *App) handleRolloutsOperation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, op := vars["id"], vars["op"]
+	ro, ok := a.rollouts.get(id)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("rollout %q not found", id)}})
+		return
+	}
+	err := a.rolloutOperation(ro, op)
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(ro.getStatus())
+}
@@ -0,0 +1,199 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/config"
+)
+
+func newRolloutTestApp(t *testing.T, targets ...string) *App {
+	a := New()
+	a.Config.APIServer = &config.APIServer{}
+	a.Config.Encoding = "json"
+	a.Config.Subscriptions = map[string]*types.SubscriptionConfig{
+		"sub1": {Name: "sub1", Paths: []string{"/interfaces"}},
+	}
+	for _, n := range targets {
+		a.Config.Targets[n] = &types.TargetConfig{Name: n}
+		if _, err := a.initTarget(a.Config.Targets[n]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a.routes()
+	return a
+}
+
+// feedRollout simulates the responses of the targets until ctx is done.
+func feedRollout(ctx context.Context, a *App, updates map[string]bool, errs map[string]bool) {
+	rsp := &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{
+		Update: &gnmi.Notification{Update: []*gnmi.Update{{Path: &gnmi.Path{}}}},
+	}}
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for n := range updates {
+				a.rollouts.observeResponse(n, "sub1", rsp)
+			}
+			for n := range errs {
+				a.rollouts.observeError(n, "sub1", errors.New("path not found"))
+			}
+		}
+	}
+}
+
+func waitRolloutState(t *testing.T, a *App, id, state string) *SubscriptionRolloutStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		r, ok := a.rollouts.get(id)
+		if !ok {
+			t.Fatalf("rollout %q not found", id)
+		}
+		if st := r.getStatus(); st.State == state {
+			return st
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	r, _ := a.rollouts.get(id)
+	t.Fatalf("rollout %q did not reach state %q: %+v", id, state, r.getStatus())
+	return nil
+}
+
+func TestSubscriptionRolloutSucceeds(t *testing.T) {
+	a := newRolloutTestApp(t, "t1", "t2", "t3")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go feedRollout(ctx, a, map[string]bool{"t1": true, "t2": true, "t3": true}, nil)
+
+	next := &types.SubscriptionConfig{Name: "sub1", Paths: []string{"/interfaces", "/system"}}
+	st, err := a.StartSubscriptionRollout(next, &config.SubscriptionRollout{
+		WaveSize:   1,
+		BakePeriod: 50 * time.Millisecond,
+		MinUpdates: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st = waitRolloutState(t, a, st.ID, rolloutStateSucceeded)
+	if st.Stage != 2 {
+		t.Errorf("expected the rollout to end at stage 2, got %d", st.Stage)
+	}
+	if !reflect.DeepEqual(st.UpdatedTargets, []string{"t1", "t2", "t3"}) {
+		t.Errorf("unexpected updated targets: %v", st.UpdatedTargets)
+	}
+	if a.Config.Subscriptions["sub1"] != next {
+		t.Errorf("expected the subscription config to be replaced")
+	}
+	for n, tg := range a.Targets {
+		if tg.Subscriptions["sub1"] != next {
+			t.Errorf("target %q was not updated", n)
+		}
+	}
+}
+
+func TestSubscriptionRolloutRollsBack(t *testing.T) {
+	a := newRolloutTestApp(t, "t1", "t2", "t3")
+	prev := a.Config.Subscriptions["sub1"]
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go feedRollout(ctx, a, map[string]bool{"t1": true, "t2": true, "t3": true}, map[string]bool{"t2": true})
+
+	next := &types.SubscriptionConfig{Name: "sub1", Paths: []string{"/bad/path"}}
+	st, err := a.StartSubscriptionRollout(next, &config.SubscriptionRollout{
+		CanaryTargets: []string{"t2"},
+		BakePeriod:    50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	st = waitRolloutState(t, a, st.ID, rolloutStateRolledBack)
+	if len(st.Failures) != 1 || st.Failures[0].Target != "t2" || !strings.Contains(st.Failures[0].Reason, "path not found") {
+		t.Errorf("unexpected failures: %+v", st.Failures)
+	}
+	if a.Config.Subscriptions["sub1"] != prev {
+		t.Errorf("expected the subscription config to be unchanged")
+	}
+	for n, tg := range a.Targets {
+		if tg.Subscriptions["sub1"] != prev {
+			t.Errorf("target %q subscription was not rolled back", n)
+		}
+	}
+}
+
+func TestSubscriptionRolloutAPI(t *testing.T) {
+	a := newRolloutTestApp(t, "t1", "t2")
+
+	post := func(url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		a.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, url, strings.NewReader(body)))
+		return w
+	}
+	// no updates are received: the canary stage fails and the rollout pauses
+	body := `{"config": {"paths": ["/system"], "sample-interval": "1s"}, "strategy": {"bake-period": "20ms", "min-updates": 1, "on-failure": "pause"}}`
+	w := post("/api/v1/config/subscriptions/sub1/rollout", body)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status %d: %s", w.Code, w.Body.String())
+	}
+	st := new(SubscriptionRolloutStatus)
+	if err := json.NewDecoder(w.Body).Decode(st); err != nil {
+		t.Fatal(err)
+	}
+	if st.Config.SampleInterval == nil || *st.Config.SampleInterval != time.Second {
+		t.Errorf("unexpected rollout config: %+v", st.Config)
+	}
+	if w := post("/api/v1/config/subscriptions/sub1/rollout", body); w.Code != http.StatusConflict {
+		t.Errorf("expected a conflict starting a second rollout, got %d", w.Code)
+	}
+	if w := post("/api/v1/config/subscriptions/sub2/rollout", body); w.Code != http.StatusNotFound {
+		t.Errorf("expected an unknown subscription rollout to fail, got %d", w.Code)
+	}
+	waitRolloutState(t, a, st.ID, rolloutStatePaused)
+	// continue to the next wave, which fails too
+	if w := post("/api/v1/rollouts/"+st.ID+"/continue", ""); w.Code != http.StatusAccepted {
+		t.Fatalf("unexpected continue status %d: %s", w.Code, w.Body.String())
+	}
+	waitRolloutState(t, a, st.ID, rolloutStatePaused)
+	if w := post("/api/v1/rollouts/"+st.ID+"/rollback", ""); w.Code != http.StatusAccepted {
+		t.Fatalf("unexpected rollback status %d: %s", w.Code, w.Body.String())
+	}
+	final := waitRolloutState(t, a, st.ID, rolloutStateRolledBack)
+	if !reflect.DeepEqual(final.UpdatedTargets, []string{"t1", "t2"}) {
+		t.Errorf("unexpected updated targets: %v", final.UpdatedTargets)
+	}
+	if w := post("/api/v1/rollouts/"+st.ID+"/continue", ""); w.Code != http.StatusConflict {
+		t.Errorf("expected continuing a finished rollout to fail, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	a.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/rollouts", nil))
+	sts := make([]*SubscriptionRolloutStatus, 0)
+	if err := json.NewDecoder(w.Body).Decode(&sts); err != nil {
+		t.Fatal(err)
+	}
+	if len(sts) != 1 || sts[0].State != rolloutStateRolledBack || len(sts[0].Failures) != 2 {
+		t.Errorf("unexpected rollouts: %+v", sts)
+	}
+}
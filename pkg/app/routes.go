@@ -20,7 +20,14 @@ func (a *App) routes() {
 	a.configRoutes(apiV1)
 	a.targetRoutes(apiV1)
 	a.healthRoutes(apiV1)
+	a.traceRoutes(apiV1)
+	a.catalogRoutes(apiV1)
+	a.guardRoutes(apiV1)
+	a.estimateRoutes(apiV1)
 	a.adminRoutes(apiV1)
+	a.rolloutRoutes(apiV1)
+	a.completenessRoutes(apiV1)
+	a.complianceRoutes(apiV1)
 }
 
 func (a *App) clusterRoutes(r *mux.Router) {
@@ -43,6 +50,7 @@ func (a *App) configRoutes(r *mux.Router) {
 	r.HandleFunc("/config/targets/{id}/subscriptions", a.handleConfigTargetsSubscriptions).Methods(http.MethodPatch)
 	// config/subscriptions
 	r.HandleFunc("/config/subscriptions", a.handleConfigSubscriptions).Methods(http.MethodGet)
+	r.HandleFunc("/config/subscriptions/{id}/rollout", a.handleConfigSubscriptionsRollout).Methods(http.MethodPost)
 	// config/outputs
 	r.HandleFunc("/config/outputs", a.handleConfigOutputs).Methods(http.MethodGet)
 	// config/inputs
@@ -63,12 +71,53 @@ func (a *App) targetRoutes(r *mux.Router) {
 	r.HandleFunc("/targets/{id}", a.handleTargetsGet).Methods(http.MethodGet)
 	r.HandleFunc("/targets/{id}", a.handleTargetsPost).Methods(http.MethodPost)
 	r.HandleFunc("/targets/{id}", a.handleTargetsDelete).Methods(http.MethodDelete)
+	r.HandleFunc("/targets/{id}/facts", a.handleFactsGet).Methods(http.MethodGet)
+	r.HandleFunc("/targets/{id}/facts", a.handleFactsRefresh).Methods(http.MethodPost)
+	r.HandleFunc("/targets/{id}/capture", a.handleTargetsCapture).Methods(http.MethodPost)
+	r.HandleFunc("/targets/{id}/{op:pause|resume|resubscribe|poll}", a.handleTargetsOperation).Methods(http.MethodPost)
+	r.HandleFunc("/facts", a.handleFactsGet).Methods(http.MethodGet)
 }
 
 func (a *App) healthRoutes(r *mux.Router) {
 	r.HandleFunc("/healthz", a.handleHealthzGet).Methods(http.MethodGet)
 }
 
+func (a *App) traceRoutes(r *mux.Router) {
+	r.HandleFunc("/traces", a.handleTracesGet).Methods(http.MethodGet)
+	r.HandleFunc("/traces/{id}", a.handleTracesGet).Methods(http.MethodGet)
+	r.HandleFunc("/traces", a.handleTracesDelete).Methods(http.MethodDelete)
+}
+
+func (a *App) guardRoutes(r *mux.Router) {
+	r.HandleFunc("/processors/guard", a.handleGuardGet).Methods(http.MethodGet)
+	r.HandleFunc("/processors/guard", a.handleGuardDelete).Methods(http.MethodDelete)
+}
+
+func (a *App) catalogRoutes(r *mux.Router) {
+	r.HandleFunc("/catalog", a.handleCatalogGet).Methods(http.MethodGet)
+	r.HandleFunc("/catalog", a.handleCatalogDelete).Methods(http.MethodDelete)
+}
+
+func (a *App) estimateRoutes(r *mux.Router) {
+	r.HandleFunc("/estimate", a.handleEstimatePost).Methods(http.MethodPost)
+}
+
+func (a *App) rolloutRoutes(r *mux.Router) {
+	r.HandleFunc("/rollouts", a.handleRolloutsGet).Methods(http.MethodGet)
+	r.HandleFunc("/rollouts/{id}", a.handleRolloutsGet).Methods(http.MethodGet)
+	r.HandleFunc("/rollouts/{id}/{op:rollback|continue}", a.handleRolloutsOperation).Methods(http.MethodPost)
+}
+
+func (a *App) completenessRoutes(r *mux.Router) {
+	r.HandleFunc("/completeness", a.handleCompletenessGet).Methods(http.MethodGet)
+	r.HandleFunc("/completeness", a.handleCompletenessDelete).Methods(http.MethodDelete)
+}
+
+func (a *App) complianceRoutes(r *mux.Router) {
+	r.HandleFunc("/compliance", a.handleComplianceGet).Methods(http.MethodGet)
+	r.HandleFunc("/compliance", a.handleCompliancePost).Methods(http.MethodPost)
+}
+
 func (a *App) adminRoutes(r *mux.Router) {
 	r.HandleFunc("/admin/shutdown", a.handleAdminShutdown).Methods(http.MethodPost)
 }
@@ -0,0 +1,333 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/loaders"
+)
+
+const (
+	targetResultOK      = "ok"
+	targetResultFailed  = "failed"
+	targetResultSkipped = "skipped"
+)
+
+// targetSelector is a parsed `--select` flag value.
+// A target matches the selector if it matches all its terms.
+type targetSelector struct {
+	// key=value terms, matched against the target event-tags
+	// or the target tags formatted as key=value.
+	tags map[string]string
+	// terms without a `=`, matched against the target name.
+	names []*regexp.Regexp
+}
+
+// parseTargetSelectors parses a list of selectors made of comma separated terms.
+// A term is either `key=value` or a regular expression matched against the target name.
+func parseTargetSelectors(exprs []string) ([]*targetSelector, error) {
+	sels := make([]*targetSelector, 0, len(exprs))
+	for _, expr := range exprs {
+		sel := &targetSelector{tags: make(map[string]string)}
+		for _, term := range strings.Split(expr, ",") {
+			term = strings.TrimSpace(term)
+			if term == "" {
+				continue
+			}
+			if k, v, ok := strings.Cut(term, "="); ok {
+				if k == "" {
+					return nil, fmt.Errorf("invalid selector %q: missing tag name in %q", expr, term)
+				}
+				sel.tags[k] = v
+				continue
+			}
+			re, err := regexp.Compile(term)
+			if err != nil {
+				return nil, fmt.Errorf("invalid selector %q: %v", expr, err)
+			}
+			sel.names = append(sel.names, re)
+		}
+		if len(sel.tags) == 0 && len(sel.names) == 0 {
+			return nil, fmt.Errorf("invalid selector %q: no terms", expr)
+		}
+		sels = append(sels, sel)
+	}
+	return sels, nil
+}
+
+func (s *targetSelector) match(tc *types.TargetConfig) bool {
+	for _, re := range s.names {
+		if !re.MatchString(tc.Name) {
+			return false
+		}
+	}
+	for k, v := range s.tags {
+		if ev, ok := tc.EventTags[k]; ok && ev == v {
+			continue
+		}
+		if slices.Contains(tc.Tags, k+"="+v) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// matchTargets returns the targets matching any of the selectors.
+func matchTargets(tcs map[string]*types.TargetConfig, sels []*targetSelector) map[string]*types.TargetConfig {
+	selected := make(map[string]*types.TargetConfig)
+	for n, tc := range tcs {
+		for _, sel := range sels {
+			if sel.match(tc) {
+				selected[n] = tc
+				break
+			}
+		}
+	}
+	return selected
+}
+
+// getSelectedTargets resolves the targets matching the `--select` flag.
+func (a *App) getSelectedTargets(ctx context.Context) (map[string]*types.TargetConfig, error) {
+	sels, err := parseTargetSelectors(a.Config.Select)
+	if err != nil {
+		return nil, err
+	}
+	tcs, err := a.getTargetsInventory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	selected := matchTargets(tcs, sels)
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no targets matching %q out of %d", a.Config.Select, len(tcs))
+	}
+	a.Logger.Printf("selected %d target(s) out of %d", len(selected), len(tcs))
+	a.configLock.Lock()
+	a.Config.Targets = selected
+	a.configLock.Unlock()
+	return selected, nil
+}
+
+// initStreamSelectors restricts the stream subscriptions to the targets
+// matching the `--select` flag. The configured targets are filtered once,
+// the targets discovered by the loader are filtered as they are added.
+func (a *App) initStreamSelectors() error {
+	sels, err := parseTargetSelectors(a.Config.Select)
+	if err != nil {
+		return err
+	}
+	a.targetSelectors = sels
+	a.configLock.Lock()
+	defer a.configLock.Unlock()
+	if len(a.Config.Targets) == 0 {
+		return nil
+	}
+	selected := matchTargets(a.Config.Targets, sels)
+	a.Logger.Printf("selected %d target(s) out of %d", len(selected), len(a.Config.Targets))
+	a.Config.Targets = selected
+	return nil
+}
+
+// targetSelected reports whether tc matches the stream subscriptions selectors, if any.
+func (a *App) targetSelected(tc *types.TargetConfig) bool {
+	if len(a.targetSelectors) == 0 {
+		return true
+	}
+	for _, sel := range a.targetSelectors {
+		if sel.match(tc) {
+			return true
+		}
+	}
+	return false
+}
+
+// getTargetsInventory returns all the known targets: the ones discovered
+// by the configured loader if any, the ones set with the `--address` flag
+// or under the `targets` section otherwise.
+func (a *App) getTargetsInventory(ctx context.Context) (map[string]*types.TargetConfig, error) {
+	err := a.Config.GetLoader()
+	if err != nil {
+		return nil, fmt.Errorf("failed reading loader config: %v", err)
+	}
+	if len(a.Config.Loader) > 0 && len(a.Config.Address) == 0 {
+		tcs, err := a.loadTargetsOnce(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed loading targets: %v", err)
+		}
+		return tcs, nil
+	}
+	tcs, err := a.Config.GetTargets()
+	if err != nil {
+		return nil, fmt.Errorf("failed reading targets config: %v", err)
+	}
+	return tcs, nil
+}
+
+// loadTargetsOnce runs the configured loader once and returns the discovered targets.
+func (a *App) loadTargetsOnce(ctx context.Context) (map[string]*types.TargetConfig, error) {
+	ldTypeS := a.Config.Loader["type"].(string)
+	a.Logger.Printf("running loader type %q once", ldTypeS)
+	var fnTargetsDefaults func(tc *types.TargetConfig) error
+	if expandEnv, ok := a.Config.Loader["expand-env"].(bool); ok && expandEnv {
+		fnTargetsDefaults = a.Config.SetTargetConfigDefaultsExpandEnv
+	} else {
+		fnTargetsDefaults = a.Config.SetTargetConfigDefaults
+	}
+	ld := loaders.Loaders[ldTypeS]()
+	err := ld.Init(ctx, a.Config.Loader, a.Logger,
+		loaders.WithActions(a.Config.Actions),
+		loaders.WithTargetsDefaults(fnTargetsDefaults),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return ld.RunOnce(ctx)
+}
+
+// targetResult is the outcome of a one-shot RPC run against a target.
+type targetResult struct {
+	status   string
+	duration time.Duration
+	err      error
+}
+
+// targetResults collects the per target outcome of a one-shot command.
+type targetResults struct {
+	m       *sync.Mutex
+	results map[string]*targetResult
+}
+
+func newTargetResults() *targetResults {
+	return &targetResults{
+		m:       new(sync.Mutex),
+		results: make(map[string]*targetResult),
+	}
+}
+
+func (r *targetResults) get(name string) *targetResult {
+	res, ok := r.results[name]
+	if !ok {
+		res = &targetResult{status: targetResultOK}
+		r.results[name] = res
+	}
+	return res
+}
+
+// fail records the first error returned by a target.
+func (r *targetResults) fail(name string, err error) {
+	if r == nil {
+		return
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	res := r.get(name)
+	if res.err == nil {
+		res.status = targetResultFailed
+		res.err = err
+	}
+}
+
+func (r *targetResults) done(name string, d time.Duration) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.get(name).duration = d
+}
+
+func (r *targetResults) skip(name string, err error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	res := r.get(name)
+	res.status = targetResultSkipped
+	res.err = err
+}
+
+func (r *targetResults) print(w io.Writer) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	names := make([]string, 0, len(r.results))
+	for n := range r.results {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	var failed int
+	tabData := make([][]string, 0, len(names))
+	for _, n := range names {
+		res := r.results[n]
+		errMsg := ""
+		if res.err != nil {
+			errMsg = res.err.Error()
+		}
+		if res.status != targetResultOK {
+			failed++
+		}
+		tabData = append(tabData, []string{n, res.status, res.duration.Round(time.Millisecond).String(), errMsg})
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Target", "Status", "Duration", "Error"})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoWrapText(false)
+	table.AppendBulk(tabData)
+	table.Render()
+	fmt.Fprintf(w, "%d/%d target(s) succeeded\n", len(names)-failed, len(names))
+}
+
+// logTargetError logs an error returned by a target and records it
+// in the per target results.
+func (a *App) logTargetError(name string, err error) {
+	a.targetResults.fail(name, err)
+	a.logError(err)
+}
+
+// runOnTargets runs fn against each target, at most `--concurrency`
+// targets at a time, and waits for all the runs to return.
+// If targets were selected with `--select`, a per target summary is
+// printed to stderr once all the runs are done.
+func (a *App) runOnTargets(ctx context.Context, tcs map[string]*types.TargetConfig, fn func(context.Context, *types.TargetConfig)) {
+	a.targetResults = newTargetResults()
+	var sem chan struct{}
+	if a.Config.Concurrency > 0 {
+		sem = make(chan struct{}, a.Config.Concurrency)
+	}
+	a.wg.Add(len(tcs))
+	for _, tc := range tcs {
+		go func(tc *types.TargetConfig) {
+			defer a.wg.Done()
+			if sem != nil {
+				select {
+				case <-ctx.Done():
+					a.targetResults.skip(tc.Name, ctx.Err())
+					return
+				case sem <- struct{}{}:
+				}
+				defer func() { <-sem }()
+			}
+			start := time.Now()
+			fn(ctx, tc)
+			a.targetResults.done(tc.Name, time.Since(start))
+		}(tc)
+	}
+	a.wg.Wait()
+	if len(a.Config.Select) > 0 {
+		a.targetResults.print(os.Stderr)
+	}
+}
@@ -0,0 +1,180 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+)
+
+var selectTestTargets = map[string]*types.TargetConfig{
+	"spine1": {Name: "spine1", EventTags: map[string]string{"role": "spine", "site": "par1"}},
+	"spine2": {Name: "spine2", EventTags: map[string]string{"role": "spine", "site": "ams1"}},
+	"leaf1":  {Name: "leaf1", EventTags: map[string]string{"role": "leaf", "site": "par1"}},
+	"leaf2":  {Name: "leaf2", Tags: []string{"role=leaf", "site=ams1"}},
+}
+
+func TestMatchTargets(t *testing.T) {
+	tests := []struct {
+		name   string
+		sels   []string
+		want   []string
+		hasErr bool
+	}{
+		{name: "single_tag", sels: []string{"role=spine"}, want: []string{"spine1", "spine2"}},
+		{name: "tags_and", sels: []string{"role=spine,site=par1"}, want: []string{"spine1"}},
+		{name: "tags_list", sels: []string{"site=ams1"}, want: []string{"leaf2", "spine2"}},
+		{name: "name_regex", sels: []string{"^leaf"}, want: []string{"leaf1", "leaf2"}},
+		{name: "regex_and_tag", sels: []string{"^leaf,site=par1"}, want: []string{"leaf1"}},
+		{name: "selectors_or", sels: []string{"role=spine,site=par1", "leaf2"}, want: []string{"leaf2", "spine1"}},
+		{name: "no_match", sels: []string{"role=border"}, want: []string{}},
+		{name: "empty_value", sels: []string{"role="}, want: []string{}},
+		{name: "invalid_regex", sels: []string{"leaf["}, hasErr: true},
+		{name: "missing_key", sels: []string{"=spine"}, hasErr: true},
+		{name: "no_terms", sels: []string{" , "}, hasErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sels, err := parseTargetSelectors(tt.sels)
+			if tt.hasErr {
+				if err == nil {
+					t.Fatalf("expected an error parsing %q", tt.sels)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0)
+			for n := range matchTargets(selectTestTargets, sels) {
+				got = append(got, n)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestGetSelectedTargetsFromLoader(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "targets.yaml")
+	err := os.WriteFile(f, []byte(`
+spine1:57400:
+  event-tags:
+    role: spine
+spine2:57400:
+  event-tags:
+    role: spine
+leaf1:57400:
+  event-tags:
+    role: leaf
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New()
+	a.Config.TargetsFile = f
+	a.Config.Select = []string{"role=spine"}
+	tcs, err := a.GetTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0, len(tcs))
+	for n := range tcs {
+		got = append(got, n)
+	}
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, []string{"spine1:57400", "spine2:57400"}) {
+		t.Errorf("unexpected selected targets: %v", got)
+	}
+	if len(a.Config.Targets) != 2 {
+		t.Errorf("expected the selected targets to be set in the config, got %d", len(a.Config.Targets))
+	}
+
+	a.Config.Select = []string{"role=border"}
+	if _, err = a.GetTargets(); err == nil {
+		t.Error("expected an error when no target matches")
+	}
+}
+
+func TestRunOnTargets(t *testing.T) {
+	a := New()
+	a.Config.Concurrency = 2
+	var running, maxRunning int32
+	a.runOnTargets(context.Background(), selectTestTargets, func(ctx context.Context, tc *types.TargetConfig) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		if tc.Name == "leaf1" {
+			a.targetResults.fail(tc.Name, errors.New("leaf1 failed"))
+			a.targetResults.fail(tc.Name, errors.New("leaf1 failed again"))
+		}
+	})
+	if maxRunning > 2 {
+		t.Errorf("expected at most 2 concurrent runs, got %d", maxRunning)
+	}
+	if len(a.targetResults.results) != len(selectTestTargets) {
+		t.Fatalf("expected %d results, got %d", len(selectTestTargets), len(a.targetResults.results))
+	}
+	for n, res := range a.targetResults.results {
+		switch n {
+		case "leaf1":
+			if res.status != targetResultFailed || res.err.Error() != "leaf1 failed" {
+				t.Errorf("unexpected %q result: %+v", n, res)
+			}
+		default:
+			if res.status != targetResultOK || res.err != nil {
+				t.Errorf("unexpected %q result: %+v", n, res)
+			}
+		}
+	}
+}
+
+func TestInitStreamSelectors(t *testing.T) {
+	a := New()
+	a.Config.Targets = make(map[string]*types.TargetConfig)
+	for n, tc := range selectTestTargets {
+		a.Config.Targets[n] = tc
+	}
+	a.Config.Select = []string{"site=par1"}
+	if err := a.initStreamSelectors(); err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0, len(a.Config.Targets))
+	for n := range a.Config.Targets {
+		got = append(got, n)
+	}
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, []string{"leaf1", "spine1"}) {
+		t.Errorf("unexpected selected targets: %v", got)
+	}
+	// targets added later by a loader
+	if a.targetSelected(selectTestTargets["leaf2"]) {
+		t.Error("expected leaf2 not to be selected")
+	}
+	if !a.targetSelected(&types.TargetConfig{Name: "leaf3", EventTags: map[string]string{"site": "par1"}}) {
+		t.Error("expected leaf3 to be selected")
+	}
+}
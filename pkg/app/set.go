@@ -59,21 +59,23 @@ func (a *App) SetRunE(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed reading set request files: %v", err)
 	}
+	err = a.initFacts()
+	if err != nil {
+		return err
+	}
 	numTargets := len(a.Config.Targets)
 	a.errCh = make(chan error, numTargets*2)
-	a.wg.Add(numTargets)
-	for _, tc := range a.Config.Targets {
-		go a.SetRequest(ctx, tc)
-	}
-	a.wg.Wait()
+	a.runOnTargets(ctx, a.Config.Targets, a.SetRequest)
 	return a.checkErrors()
 }
 
 func (a *App) SetRequest(ctx context.Context, tc *types.TargetConfig) {
-	defer a.wg.Done()
+	if len(a.Config.SetRequestFile) > 0 {
+		a.collectFacts(ctx, tc)
+	}
 	reqs, err := a.Config.CreateSetRequest(tc.Name)
 	if err != nil {
-		a.logError(fmt.Errorf("target %q: failed to create set request: %v", tc.Name, err))
+		a.logTargetError(tc.Name, fmt.Errorf("target %q: failed to create set request: %v", tc.Name, err))
 		return
 	}
 	for _, req := range reqs {
@@ -87,7 +89,7 @@ func (a *App) setRequest(ctx context.Context, tc *types.TargetConfig, req *gnmi.
 	if a.Config.PrintRequest || a.Config.SetDryRun {
 		err := a.PrintMsg(tc.Name, "Set Request:", req)
 		if err != nil {
-			a.logError(fmt.Errorf("target %q: %v", tc.Name, err))
+			a.logTargetError(tc.Name, fmt.Errorf("target %q: %v", tc.Name, err))
 		}
 	}
 	if a.Config.SetDryRun {
@@ -95,12 +97,12 @@ func (a *App) setRequest(ctx context.Context, tc *types.TargetConfig, req *gnmi.
 	}
 	response, err := a.ClientSet(ctx, tc, req)
 	if err != nil {
-		a.logError(fmt.Errorf("target %q set request failed: %v", tc.Name, err))
+		a.logTargetError(tc.Name, fmt.Errorf("target %q set request failed: %v", tc.Name, err))
 		return
 	}
 	err = a.PrintMsg(tc.Name, "Set Response:", response)
 	if err != nil {
-		a.logError(fmt.Errorf("target %q: %v", tc.Name, err))
+		a.logTargetError(tc.Name, fmt.Errorf("target %q: %v", tc.Name, err))
 	}
 }
 
@@ -145,6 +147,7 @@ func (a *App) InitSetFlags(cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(&a.Config.LocalFlags.SetCommitConfirm, "commit-confirm", "", false, "confirm the commit ID")
 	cmd.Flags().BoolVarP(&a.Config.LocalFlags.SetCommitCancel, "commit-cancel", "", false, "cancel the commit")
 	cmd.Flags().DurationVarP(&a.Config.LocalFlags.SetCommitRollbackDuration, "rollback-duration", "", 0, "set the commit rollback duration")
+	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.SetExtension, "extension", "", []string{}, "registered extension to add to the request, in the format name=value, the value is JSON or YAML")
 
 	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
 		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
@@ -81,6 +81,10 @@ func (a *App) SubscribeRunE(cmd *cobra.Command, args []string) error {
 	if len(subCfg) == 0 && numInputs == 0 {
 		return errors.New("no subscriptions or inputs configuration found")
 	}
+	// estimate subscriptions impact
+	if a.Config.LocalFlags.SubscribeEstimate {
+		return a.SubscribeRunEstimate(cmd, args)
+	}
 	// only once mode subscriptions requested
 	if allSubscriptionsModeOnce(subCfg) {
 		return a.SubscribeRunONCE(cmd, args)
@@ -110,6 +114,12 @@ func (a *App) SubscribeRunE(cmd *cobra.Command, args []string) error {
 	} else if err != nil {
 		return fmt.Errorf("failed reading targets config: %v", err)
 	}
+	if len(a.Config.Select) > 0 {
+		err = a.initStreamSelectors()
+		if err != nil {
+			return err
+		}
+	}
 
 	//
 	for {
@@ -124,6 +134,8 @@ func (a *App) SubscribeRunE(cmd *cobra.Command, args []string) error {
 
 	a.startAPIServer()
 	a.startGnmiServer()
+	a.startCompleteness()
+	a.startCompliance()
 	go a.startCluster()
 	a.startIO()
 
@@ -183,6 +195,10 @@ func (a *App) InitSubscribeFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&a.Config.LocalFlags.SubscribeHistoryStart, "history-start", "", "", "sets the start time in a historical range subscription, nanoseconds since Unix epoch or RFC3339 format")
 	cmd.Flags().StringVarP(&a.Config.LocalFlags.SubscribeHistoryEnd, "history-end", "", "", "sets the end time in a historical range subscription, nanoseconds since Unix epoch or RFC3339 format")
 	cmd.Flags().Uint32VarP(&a.Config.LocalFlags.SubscribeDepth, "depth", "", 0, "depth extension value")
+	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.SubscribeExtension, "extension", "", []string{}, "registered extension to add to the subscribe request, in the format name=value, the value is JSON or YAML")
+	cmd.Flags().BoolVarP(&a.Config.LocalFlags.SubscribeEstimate, "estimate", "", false, "estimate the subscriptions impact on the outputs using a ONCE subscription on a sample of the targets")
+	cmd.Flags().IntVarP(&a.Config.LocalFlags.SubscribeEstimateSample, "estimate-sample", "", defaultEstimateSampleSize, "number of targets sampled when estimating the subscriptions impact")
+	cmd.Flags().IntVarP(&a.Config.LocalFlags.SubscribeEstimateTop, "estimate-top", "", defaultEstimateTopPaths, "number of top contributing paths reported when estimating the subscriptions impact")
 	//
 	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
 		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", cmd.Name(), flag.Name), flag)
@@ -207,6 +223,47 @@ func (a *App) readConfigs() error {
 	if err != nil {
 		return fmt.Errorf("failed reading event processors config: %v", err)
 	}
+	tcfg, err := a.Config.GetProcessorsTrace()
+	if err != nil {
+		return fmt.Errorf("failed reading processors trace config: %v", err)
+	}
+	if tcfg != nil {
+		t, err := formatters.NewTracer(tcfg)
+		if err != nil {
+			return fmt.Errorf("failed initializing processors tracer: %v", err)
+		}
+		formatters.SetTracer(t)
+	}
+	gcfg, err := a.Config.GetProcessorsGuard()
+	if err != nil {
+		return fmt.Errorf("failed reading processors guard config: %v", err)
+	}
+	if gcfg != nil {
+		formatters.SetGuard(formatters.NewGuard(gcfg))
+	}
+	_, err = a.Config.GetSubscriptionRollout()
+	if err != nil {
+		return fmt.Errorf("failed reading subscription rollout config: %v", err)
+	}
+	_, err = a.Config.GetCompleteness()
+	if err != nil {
+		return fmt.Errorf("failed reading completeness config: %v", err)
+	}
+	ccfg, err := a.Config.GetCatalog()
+	if err != nil {
+		return fmt.Errorf("failed reading catalog config: %v", err)
+	}
+	if ccfg != nil {
+		formatters.SetCatalog(formatters.NewCatalog(ccfg))
+	}
+	err = a.initFacts()
+	if err != nil {
+		return err
+	}
+	err = a.initCompliance()
+	if err != nil {
+		return err
+	}
 	_, err = a.LoadProtoFiles()
 	if err != nil {
 		return fmt.Errorf("failed loading proto files: %v", err)
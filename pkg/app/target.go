@@ -39,6 +39,16 @@ func (a *App) initTarget(tc *types.TargetConfig) (*target.Target, error) {
 		if err != nil {
 			return nil, err
 		}
+		// the leader keeps track of the paused subscriptions of the
+		// targets it assigns, the other instances get them with the target config.
+		if a.inCluster() && !a.isLeader {
+			a.pausedSubs.replace(tc.Name, tc.PausedSubscriptions)
+		}
+		for _, subName := range a.pausedSubs.get(tc.Name) {
+			if _, ok := t.Subscriptions[subName]; ok {
+				_ = t.PauseSubscription(subName)
+			}
+		}
 		a.Targets[t.Config.Name] = t
 		return t, nil
 	}
@@ -72,6 +82,16 @@ func (a *App) DeleteTarget(ctx context.Context, name string) error {
 	if !a.targetConfigExists(name) {
 		return fmt.Errorf("target %q does not exist", name)
 	}
+	// the leader forgets the paused subscriptions of the targets deleted from the cluster.
+	if a.inCluster() && a.isLeader {
+		a.pausedSubs.replace(name, nil)
+		if a.locker != nil {
+			err := a.locker.Delete(ctx, a.pausedSubscriptionsKey(name))
+			if err != nil {
+				a.Logger.Printf("failed to delete the paused subscriptions of target %q: %v", name, err)
+			}
+		}
+	}
 	if !a.isLeader {
 		a.configLock.Lock()
 		delete(a.Config.Targets, name)
@@ -87,6 +107,8 @@ func (a *App) DeleteTarget(ctx context.Context, name string) error {
 	if a.c != nil {
 		a.c.DeleteTarget(name)
 	}
+	a.facts.Delete(name)
+	a.completeness.deleteTarget(name)
 	if t, ok := a.Targets[name]; ok {
 		delete(a.Targets, name)
 		t.Close()
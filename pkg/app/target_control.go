@@ -0,0 +1,391 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/lockers"
+)
+
+const (
+	targetOpPause       = "pause"
+	targetOpResume      = "resume"
+	targetOpResubscribe = "resubscribe"
+	targetOpPoll        = "poll"
+)
+
+// forwardedHeader is set on the target operations forwarded
+// from one cluster instance to another.
+const forwardedHeader = "X-Gnmic-Forwarded-By"
+
+// pausedSubscriptionParam is the query parameter the leader sets
+// the paused subscriptions of a target with, when it assigns it.
+const pausedSubscriptionParam = "paused-subscription"
+
+var errTargetNotRunning = errors.New("target is not running")
+
+// TargetOperationResponse is the result of a target runtime operation.
+type TargetOperationResponse struct {
+	Target              string   `json:"target"`
+	Operation           string   `json:"operation"`
+	Subscriptions       []string `json:"subscriptions,omitempty"`
+	PausedSubscriptions []string `json:"paused-subscriptions,omitempty"`
+}
+
+// pausedSubscriptions is the set of paused subscriptions per target name.
+// It is kept separately from the targets so that the paused state
+// survives the targets being deleted and re-created on config reloads.
+// In a cluster, the leader persists it in the locker.
+type pausedSubscriptions struct {
+	m    sync.RWMutex
+	subs map[string]map[string]struct{}
+}
+
+func newPausedSubscriptions() *pausedSubscriptions {
+	return &pausedSubscriptions{subs: make(map[string]map[string]struct{})}
+}
+
+// set pauses or unpauses the subscriptions subs of target name.
+func (p *pausedSubscriptions) set(name string, subs []string, paused bool) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if paused {
+		if _, ok := p.subs[name]; !ok {
+			p.subs[name] = make(map[string]struct{}, len(subs))
+		}
+		for _, s := range subs {
+			p.subs[name][s] = struct{}{}
+		}
+		return
+	}
+	for _, s := range subs {
+		delete(p.subs[name], s)
+	}
+	if len(p.subs[name]) == 0 {
+		delete(p.subs, name)
+	}
+}
+
+// replace sets the paused subscriptions of target name to subs.
+func (p *pausedSubscriptions) replace(name string, subs []string) {
+	p.m.Lock()
+	delete(p.subs, name)
+	p.m.Unlock()
+	p.set(name, subs, true)
+}
+
+// load replaces the paused subscriptions of all the targets with subs.
+func (p *pausedSubscriptions) load(subs map[string][]string) {
+	p.m.Lock()
+	p.subs = make(map[string]map[string]struct{}, len(subs))
+	p.m.Unlock()
+	for name, ss := range subs {
+		p.set(name, ss, true)
+	}
+}
+
+// get returns the sorted paused subscriptions of target name.
+func (p *pausedSubscriptions) get(name string) []string {
+	p.m.RLock()
+	defer p.m.RUnlock()
+	if len(p.subs[name]) == 0 {
+		return nil
+	}
+	subs := make([]string, 0, len(p.subs[name]))
+	for s := range p.subs[name] {
+		subs = append(subs, s)
+	}
+	sort.Strings(subs)
+	return subs
+}
+
+func (a *App) handleTargetsOperation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, op := vars["id"], vars["op"]
+	a.configLock.RLock()
+	tc, ok := a.Config.Targets[id]
+	a.configLock.RUnlock()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("target %q not found", id)}})
+		return
+	}
+	known := a.targetSubscriptionNames(tc)
+	subs := r.URL.Query()["subscription"]
+	for _, s := range subs {
+		if !slices.Contains(known, s) {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("unknown subscription %q for target %q", s, id)}})
+			return
+		}
+	}
+	explicit := len(subs) > 0
+	if !explicit {
+		subs = known
+	}
+	// in a cluster, the leader keeps track of the paused subscriptions
+	// and forwards the operation to the instance the target is assigned to.
+	// The other instances forward the operations they receive to the leader,
+	// unless it is the leader that forwarded them.
+	switch {
+	case a.inCluster() && a.isLeader:
+		if op == targetOpPause || op == targetOpResume {
+			a.pausedSubs.set(id, subs, op == targetOpPause)
+			err := a.storePausedSubscriptions(r.Context(), id)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+				return
+			}
+		}
+		instance, err := a.targetInstance(r.Context(), id)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+			return
+		}
+		if instance != "" && instance != a.Config.Clustering.InstanceName {
+			a.forwardTargetOperation(w, r, instance)
+			return
+		}
+	case a.inCluster() && r.Header.Get(forwardedHeader) == "":
+		leader, err := a.getLeaderName(r.Context())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+			return
+		}
+		if leader == "" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{"cluster has no leader"}})
+			return
+		}
+		a.forwardTargetOperation(w, r, leader)
+		return
+	}
+	rsp, err := a.targetOperation(id, op, subs, explicit)
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	a.handlerCommonGet(w, rsp)
+}
+
+// targetOperation applies operation op to the subscriptions subs of the local target name.
+// explicit is true if the subscriptions were selected by the user rather than defaulted to all.
+func (a *App) targetOperation(name, op string, subs []string, explicit bool) (*TargetOperationResponse, error) {
+	a.operLock.RLock()
+	t, running := a.Targets[name]
+	a.operLock.RUnlock()
+	rsp := &TargetOperationResponse{Target: name, Operation: op}
+	switch op {
+	case targetOpPause, targetOpResume:
+		a.pausedSubs.set(name, subs, op == targetOpPause)
+		rsp.Subscriptions = subs
+		if !running {
+			break
+		}
+		for _, s := range subs {
+			var err error
+			if op == targetOpPause {
+				err = t.PauseSubscription(s)
+			} else {
+				err = t.ResumeSubscription(s)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	case targetOpResubscribe:
+		if !running {
+			return nil, errTargetNotRunning
+		}
+		for _, s := range subs {
+			if t.IsPaused(s) && !explicit {
+				continue
+			}
+			if err := t.Resubscribe(s); err != nil {
+				return nil, err
+			}
+			rsp.Subscriptions = append(rsp.Subscriptions, s)
+		}
+	case targetOpPoll:
+		if !running {
+			return nil, errTargetNotRunning
+		}
+		for _, s := range subs {
+			if a.subscriptionMode(s) != "POLL" {
+				if explicit {
+					return nil, fmt.Errorf("subscription %q is not a POLL subscription", s)
+				}
+				continue
+			}
+			if t.IsPaused(s) {
+				if explicit {
+					return nil, fmt.Errorf("subscription %q is paused", s)
+				}
+				continue
+			}
+			if err := t.SubscribePoll(a.ctx, s); err != nil {
+				return nil, err
+			}
+			rsp.Subscriptions = append(rsp.Subscriptions, s)
+		}
+		if len(rsp.Subscriptions) == 0 {
+			return nil, fmt.Errorf("target %q has no active POLL subscriptions", name)
+		}
+	}
+	rsp.PausedSubscriptions = a.pausedSubs.get(name)
+	return rsp, nil
+}
+
+// storePausedSubscriptions persists the paused subscriptions of target name
+// in the locker, for a new leader to load them.
+func (a *App) storePausedSubscriptions(ctx context.Context, name string) error {
+	subs := a.pausedSubs.get(name)
+	if len(subs) == 0 {
+		return a.locker.Delete(ctx, a.pausedSubscriptionsKey(name))
+	}
+	b, err := json.Marshal(subs)
+	if err != nil {
+		return err
+	}
+	return a.locker.Put(ctx, a.pausedSubscriptionsKey(name), b)
+}
+
+// loadPausedSubscriptions replaces the paused subscriptions
+// with the ones persisted in the locker by the previous leaders.
+func (a *App) loadPausedSubscriptions(ctx context.Context) error {
+	prefix := a.pausedSubscriptionsKey("")
+	kvs, err := a.locker.GetPrefix(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	subs := make(map[string][]string, len(kvs))
+	for k, v := range kvs {
+		name := strings.TrimPrefix(k, prefix)
+		var ss []string
+		err = json.Unmarshal(v, &ss)
+		if err != nil {
+			a.Logger.Printf("failed to decode the paused subscriptions of target %q: %v", name, err)
+			continue
+		}
+		subs[name] = ss
+	}
+	a.pausedSubs.load(subs)
+	return nil
+}
+
+// targetSubscriptionNames returns the sorted names of the subscriptions of target tc.
+func (a *App) targetSubscriptionNames(tc *types.TargetConfig) []string {
+	a.configLock.RLock()
+	defer a.configLock.RUnlock()
+	names := make([]string, 0, len(a.Config.Subscriptions))
+	if len(tc.Subscriptions) > 0 {
+		for _, s := range tc.Subscriptions {
+			if _, ok := a.Config.Subscriptions[s]; ok {
+				names = append(names, s)
+			}
+		}
+	} else {
+		for s := range a.Config.Subscriptions {
+			names = append(names, s)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// targetInstance returns the name of the cluster instance holding the lock of target name,
+// or an empty string if the target is not locked.
+func (a *App) targetInstance(ctx context.Context, name string) (string, error) {
+	locks, err := a.getTargetToInstanceMapping(ctx)
+	if err != nil {
+		return "", err
+	}
+	return locks[name], nil
+}
+
+// forwardTargetOperation sends the target operation request r to the cluster instance
+// and copies its response to w.
+func (a *App) forwardTargetOperation(w http.ResponseWriter, r *http.Request, instance string) {
+	err := a.createAPIClient()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	s, err := a.instanceAPIService(r.Context(), instance)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	if s == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{fmt.Sprintf("unknown instance %q", instance)}})
+		return
+	}
+	url := fmt.Sprintf("%s://%s%s", a.getServiceScheme(s), s.Address, r.URL.RequestURI())
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, url, nil)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	req.Header.Set(forwardedHeader, a.Config.Clustering.InstanceName)
+	rsp, err := a.clusteringClient.Do(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		_ = json.NewEncoder(w).Encode(APIErrors{Errors: []string{err.Error()}})
+		return
+	}
+	defer rsp.Body.Close()
+	a.Logger.Printf("received response code=%d, for POST %s", rsp.StatusCode, url)
+	w.WriteHeader(rsp.StatusCode)
+	_, _ = io.Copy(w, rsp.Body)
+}
+
+// instanceAPIService returns the API service of the cluster instance,
+// or nil if it is not registered.
+// Only the leader watches the cluster members, the other instances
+// query the locker.
+func (a *App) instanceAPIService(ctx context.Context, instance string) (*lockers.Service, error) {
+	id := instance + "-api"
+	a.configLock.RLock()
+	s, ok := a.apiServices[id]
+	a.configLock.RUnlock()
+	if ok {
+		return s, nil
+	}
+	services, err := a.locker.GetServices(ctx, fmt.Sprintf("%s-%s", a.Config.Clustering.ClusterName, apiServiceName), nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range services {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return nil, nil
+}
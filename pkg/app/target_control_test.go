@@ -0,0 +1,237 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/config"
+	"github.com/openconfig/gnmic/pkg/lockers"
+)
+
+func TestPausedSubscriptions(t *testing.T) {
+	p := newPausedSubscriptions()
+	p.set("t1", []string{"sub2", "sub1"}, true)
+	p.set("t1", []string{"sub3"}, true)
+	p.set("t1", []string{"sub2"}, false)
+	if subs := p.get("t1"); !reflect.DeepEqual(subs, []string{"sub1", "sub3"}) {
+		t.Errorf("unexpected paused subscriptions: %v", subs)
+	}
+	p.replace("t1", []string{"sub4"})
+	if subs := p.get("t1"); !reflect.DeepEqual(subs, []string{"sub4"}) {
+		t.Errorf("unexpected paused subscriptions after replace: %v", subs)
+	}
+	p.set("t1", []string{"sub4"}, false)
+	if subs := p.get("t1"); subs != nil {
+		t.Errorf("expected no paused subscriptions, got %v", subs)
+	}
+}
+
+func TestHandleTargetsOperation(t *testing.T) {
+	a := New()
+	a.Config.APIServer = &config.APIServer{}
+	a.Config.Subscriptions = map[string]*types.SubscriptionConfig{
+		"sub1": {Name: "sub1", Mode: "stream"},
+		"sub2": {Name: "sub2", Mode: "poll"},
+	}
+	a.Config.Targets = map[string]*types.TargetConfig{
+		"t1": {Name: "t1"},
+		"t2": {Name: "t2"},
+	}
+	if _, err := a.initTarget(a.Config.Targets["t1"]); err != nil {
+		t.Fatal(err)
+	}
+	a.routes()
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+		paused []string
+	}{
+		{name: "pause_one", url: "/api/v1/targets/t1/pause?subscription=sub1", status: http.StatusOK, paused: []string{"sub1"}},
+		{name: "pause_all", url: "/api/v1/targets/t1/pause", status: http.StatusOK, paused: []string{"sub1", "sub2"}},
+		{name: "resume_one", url: "/api/v1/targets/t1/resume?subscription=sub2", status: http.StatusOK, paused: []string{"sub1"}},
+		{name: "resubscribe_paused", url: "/api/v1/targets/t1/resubscribe?subscription=sub1", status: http.StatusConflict},
+		{name: "poll_stream", url: "/api/v1/targets/t1/poll?subscription=sub1", status: http.StatusConflict},
+		{name: "poll_not_running", url: "/api/v1/targets/t2/poll", status: http.StatusConflict},
+		{name: "pause_not_running", url: "/api/v1/targets/t2/pause", status: http.StatusOK, paused: []string{"sub1", "sub2"}},
+		{name: "unknown_subscription", url: "/api/v1/targets/t1/pause?subscription=sub3", status: http.StatusBadRequest},
+		{name: "unknown_target", url: "/api/v1/targets/t3/pause", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			a.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.url, nil))
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			rsp := new(TargetOperationResponse)
+			if err := json.NewDecoder(w.Body).Decode(rsp); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(rsp.PausedSubscriptions, tt.paused) {
+				t.Errorf("expected paused subscriptions %v, got %v", tt.paused, rsp.PausedSubscriptions)
+			}
+		})
+	}
+	if !reflect.DeepEqual(a.Targets["t1"].PausedSubscriptions(), []string{"sub1"}) {
+		t.Errorf("unexpected target paused subscriptions: %v", a.Targets["t1"].PausedSubscriptions())
+	}
+	// the paused state is applied when the target is re-created
+	delete(a.Targets, "t2")
+	tg, err := a.initTarget(a.Config.Targets["t2"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tg.IsPaused("sub1") || !tg.IsPaused("sub2") {
+		t.Errorf("expected re-created target subscriptions to be paused: %v", tg.PausedSubscriptions())
+	}
+}
+
+func TestConfigTargetsPostPausedSubscriptions(t *testing.T) {
+	a := New()
+	a.Config.APIServer = &config.APIServer{}
+	a.routes()
+	b, err := json.Marshal(&types.TargetConfig{Name: "t1", Address: "10.0.0.1:57400", PausedSubscriptions: []string{"sub2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the paused subscriptions are not part of the target config
+	if strings.Contains(string(b), "sub2") {
+		t.Fatalf("unexpected paused subscriptions in the target config: %s", b)
+	}
+	w := httptest.NewRecorder()
+	a.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/config/targets?paused-subscription=sub1", bytes.NewReader(b)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	tc, ok := a.Config.Targets["t1"]
+	if !ok {
+		t.Fatal("target config not added")
+	}
+	if !reflect.DeepEqual(tc.PausedSubscriptions, []string{"sub1"}) {
+		t.Errorf("unexpected paused subscriptions: %v", tc.PausedSubscriptions)
+	}
+}
+
+// kvLocker is an in memory locker key value store,
+// the locking and registration methods are not implemented.
+type kvLocker struct {
+	lockers.Locker
+	m  sync.Mutex
+	kv map[string][]byte
+}
+
+func (l *kvLocker) List(context.Context, string) (map[string]string, error) {
+	return nil, nil
+}
+
+func (l *kvLocker) Put(_ context.Context, key string, val []byte) error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	l.kv[key] = val
+	return nil
+}
+
+func (l *kvLocker) GetPrefix(_ context.Context, prefix string) (map[string][]byte, error) {
+	l.m.Lock()
+	defer l.m.Unlock()
+	rs := make(map[string][]byte)
+	for k, v := range l.kv {
+		if strings.HasPrefix(k, prefix) {
+			rs[k] = v
+		}
+	}
+	return rs, nil
+}
+
+func (l *kvLocker) Delete(_ context.Context, key string) error {
+	l.m.Lock()
+	defer l.m.Unlock()
+	delete(l.kv, key)
+	return nil
+}
+
+func TestPersistedPausedSubscriptions(t *testing.T) {
+	kv := &kvLocker{kv: make(map[string][]byte)}
+	lockers.Register("kv-test", func() lockers.Locker { return kv })
+	newLeader := func() *App {
+		a := New()
+		a.Config.APIServer = &config.APIServer{}
+		a.Config.FileConfig.Set("clustering", map[string]any{
+			"cluster-name": "c1",
+			"locker":       map[string]any{"type": "kv-test"},
+		})
+		if err := a.Config.GetClustering(); err != nil {
+			t.Fatal(err)
+		}
+		a.locker = kv
+		a.isLeader = true
+		a.Config.Subscriptions = map[string]*types.SubscriptionConfig{
+			"sub1": {Name: "sub1", Mode: "stream"},
+			"sub2": {Name: "sub2", Mode: "stream"},
+		}
+		a.Config.Targets = map[string]*types.TargetConfig{
+			"t1": {Name: "t1"},
+			"t2": {Name: "t2"},
+		}
+		a.routes()
+		return a
+	}
+
+	a := newLeader()
+	for _, u := range []string{
+		"/api/v1/targets/t1/pause?subscription=sub1",
+		"/api/v1/targets/t2/pause",
+		"/api/v1/targets/t2/resume",
+	} {
+		w := httptest.NewRecorder()
+		a.router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, u, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d: %s", u, http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+	if _, ok := kv.kv["gnmic/c1/paused-subscriptions/t2"]; ok {
+		t.Errorf("expected the resumed target entry to be deleted, got %v", kv.kv)
+	}
+
+	// a new leader loads the paused subscriptions.
+	b := newLeader()
+	b.pausedSubs.set("t3", []string{"stale"}, true)
+	if err := b.loadPausedSubscriptions(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if subs := b.pausedSubs.get("t1"); !reflect.DeepEqual(subs, []string{"sub1"}) {
+		t.Errorf("unexpected loaded paused subscriptions: %v", subs)
+	}
+	if subs := b.pausedSubs.get("t3"); subs != nil {
+		t.Errorf("expected the in memory paused subscriptions to be replaced, got %v", subs)
+	}
+
+	// deleting the target deletes its entry.
+	if err := b.DeleteTarget(context.Background(), "t1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(kv.kv) != 0 || b.pausedSubs.get("t1") != nil {
+		t.Errorf("expected the paused subscriptions of the deleted target to be deleted, got %v", kv.kv)
+	}
+}
@@ -0,0 +1,441 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openconfig/goyang/pkg/yang"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/config"
+	gfile "github.com/openconfig/gnmic/pkg/file"
+	"github.com/openconfig/gnmic/pkg/yangdiff"
+)
+
+var (
+	// matches the paths found in processors configs and Set templates:
+	// a sequence of path elements with optional keys.
+	configPathRegex = regexp.MustCompile(`(?:^|[^\w\]\-.])(/[A-Za-z_*][\w\-]*(?::[A-Za-z_][\w\-]*)?(?:\[[^\]]*\])*(?:/[A-Za-z_*][\w\-]*(?::[A-Za-z_][\w\-]*)?(?:\[[^\]]*\])*)*)`)
+	// matches Go template actions
+	templateActionRegex = regexp.MustCompile(`\{\{.*?\}\}`)
+)
+
+// YangDiffReport is the output of the yang diff command.
+type YangDiffReport struct {
+	Diff   *yangdiff.Result  `json:"diff,omitempty"`
+	Broken []*yangdiff.Issue `json:"broken,omitempty"`
+}
+
+type configPath struct {
+	source string
+	path   string
+}
+
+func (a *App) InitYangDiffFlags(cmd *cobra.Command) {
+	cmd.ResetFlags()
+
+	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.YangDiffOld, "old", "", []string{}, "YANG files or directories of the old model set")
+	cmd.Flags().StringArrayVarP(&a.Config.LocalFlags.YangDiffNew, "new", "", []string{}, "YANG files or directories of the new model set")
+	cmd.Flags().StringVarP(&a.Config.LocalFlags.YangDiffRepo, "repo", "", "", "repository path holding the models versions, as sub directories or git references")
+	cmd.Flags().StringVarP(&a.Config.LocalFlags.YangDiffOldVersion, "old-version", "", "", "version of the old model set in the repository")
+	cmd.Flags().StringVarP(&a.Config.LocalFlags.YangDiffNewVersion, "new-version", "", "", "version of the new model set in the repository")
+	cmd.Flags().BoolVarP(&a.Config.LocalFlags.YangDiffCheck, "check", "", false, "check the subscriptions, processors and Set requests paths of the config file against the new model set")
+
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		a.Config.FileConfig.BindPFlag(fmt.Sprintf("%s-%s", "yang-diff", flag.Name), flag)
+	})
+}
+
+func (a *App) YangDiffPreRunE(cmd *cobra.Command, args []string) error {
+	a.Config.LocalFlags.YangDiffOld = config.SanitizeArrayFlagValue(a.Config.LocalFlags.YangDiffOld)
+	a.Config.LocalFlags.YangDiffNew = config.SanitizeArrayFlagValue(a.Config.LocalFlags.YangDiffNew)
+	a.Config.GlobalFlags.Dir = config.SanitizeArrayFlagValue(a.Config.GlobalFlags.Dir)
+	a.Config.GlobalFlags.Exclude = config.SanitizeArrayFlagValue(a.Config.GlobalFlags.Exclude)
+	if len(a.Config.LocalFlags.YangDiffOld) == 0 && a.Config.LocalFlags.YangDiffOldVersion == "" {
+		return errors.New("missing old model set, set --old or --old-version")
+	}
+	if len(a.Config.LocalFlags.YangDiffNew) == 0 && a.Config.LocalFlags.YangDiffNewVersion == "" {
+		return errors.New("missing new model set, set --new or --new-version")
+	}
+	if a.Config.LocalFlags.YangDiffRepo == "" &&
+		(a.Config.LocalFlags.YangDiffOldVersion != "" || a.Config.LocalFlags.YangDiffNewVersion != "") {
+		return errors.New("flags --old-version and --new-version require --repo")
+	}
+	var err error
+	a.Config.GlobalFlags.Dir, err = resolveGlobs(a.Config.GlobalFlags.Dir)
+	return err
+}
+
+func (a *App) YangDiffRunE(cmd *cobra.Command, args []string) error {
+	defer a.InitYangDiffFlags(cmd)
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	oldPaths, cleanup, err := a.yangDiffPaths(ctx, a.Config.LocalFlags.YangDiffOld, a.Config.LocalFlags.YangDiffOldVersion)
+	if err != nil {
+		return fmt.Errorf("old model set: %v", err)
+	}
+	defer cleanup()
+	newPaths, cleanup, err := a.yangDiffPaths(ctx, a.Config.LocalFlags.YangDiffNew, a.Config.LocalFlags.YangDiffNewVersion)
+	if err != nil {
+		return fmt.Errorf("new model set: %v", err)
+	}
+	defer cleanup()
+
+	oldSchema, err := a.loadYangSchema(oldPaths)
+	if err != nil {
+		return fmt.Errorf("old model set: %v", err)
+	}
+	newSchema, err := a.loadYangSchema(newPaths)
+	if err != nil {
+		return fmt.Errorf("new model set: %v", err)
+	}
+	a.Logger.Printf("loaded old model set: %d nodes, new model set: %d nodes", oldSchema.Len(), newSchema.Len())
+
+	rep := &YangDiffReport{Diff: yangdiff.Diff(oldSchema, newSchema)}
+	if a.Config.LocalFlags.YangDiffCheck {
+		cps, err := a.yangDiffConfigPaths(ctx)
+		if err != nil {
+			return err
+		}
+		rep.Broken = make([]*yangdiff.Issue, 0)
+		for _, cp := range cps {
+			issues, err := yangdiff.Check(oldSchema, newSchema, rep.Diff, cp.path)
+			if err != nil {
+				a.Logger.Printf("%s: skipping path %q: %v", cp.source, cp.path, err)
+				continue
+			}
+			for _, is := range issues {
+				is.Source = cp.source
+			}
+			rep.Broken = append(rep.Broken, issues...)
+		}
+	}
+	if a.Config.Format == "json" {
+		b, err := json.MarshalIndent(rep, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	} else {
+		printYangDiff(rep, a.Config.LocalFlags.YangDiffCheck)
+	}
+	if len(rep.Broken) > 0 {
+		return fmt.Errorf("%d configuration path(s) affected by the model changes", len(rep.Broken))
+	}
+	return nil
+}
+
+// yangDiffPaths returns the YANG files and directories of a model set.
+// If a version is set, the paths are relative to the version root: the repository
+// sub directory named after the version, or the version git reference extracted
+// to a temporary directory.
+func (a *App) yangDiffPaths(ctx context.Context, paths []string, version string) ([]string, func(), error) {
+	cleanup := func() {}
+	if version == "" {
+		return paths, cleanup, nil
+	}
+	root := filepath.Join(a.Config.LocalFlags.YangDiffRepo, version)
+	if fi, err := os.Stat(root); err != nil || !fi.IsDir() {
+		tmp, err := os.MkdirTemp("", "gnmic-yang-")
+		if err != nil {
+			return nil, cleanup, err
+		}
+		cleanup = func() { os.RemoveAll(tmp) }
+		a.Logger.Printf("extracting version %q from git repository %q", version, a.Config.LocalFlags.YangDiffRepo)
+		err = gitArchiveYang(ctx, a.Config.LocalFlags.YangDiffRepo, version, tmp)
+		if err != nil {
+			cleanup()
+			return nil, func() {}, err
+		}
+		root = tmp
+	}
+	if len(paths) == 0 {
+		return []string{root}, cleanup, nil
+	}
+	res := make([]string, 0, len(paths))
+	for _, p := range paths {
+		res = append(res, filepath.Join(root, p))
+	}
+	return res, cleanup, nil
+}
+
+// gitArchiveYang extracts the YANG files of a git reference to dst.
+func gitArchiveYang(ctx context.Context, repo, ref, dst string) error {
+	cmd := exec.CommandContext(ctx, "git", "-C", repo, "archive", "--format=tar", ref)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	err = extractYangFiles(tar.NewReader(out), dst)
+	if werr := cmd.Wait(); werr != nil {
+		return fmt.Errorf("git archive %q: %v: %s", ref, werr, strings.TrimSpace(stderr.String()))
+	}
+	return err
+}
+
+func extractYangFiles(tr *tar.Reader, dst string) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg || filepath.Ext(hdr.Name) != ".yang" {
+			continue
+		}
+		target := filepath.Join(dst, hdr.Name)
+		if rel, err := filepath.Rel(dst, target); err != nil || strings.HasPrefix(rel, "..") {
+			return fmt.Errorf("invalid file path %q", hdr.Name)
+		}
+		if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		f, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, tr)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// loadYangSchema reads and processes the YANG files found in paths.
+// The directories in paths and the global --dir flag are used to resolve imports.
+func (a *App) loadYangSchema(paths []string) (*yangdiff.Schema, error) {
+	paths, err := resolveGlobs(paths)
+	if err != nil {
+		return nil, err
+	}
+	ms := yang.NewModules()
+	for _, p := range append(append([]string{}, a.Config.GlobalFlags.Dir...), paths...) {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			continue
+		}
+		expanded, err := yang.PathsWithModules(p)
+		if err != nil {
+			return nil, err
+		}
+		ms.AddPath(expanded...)
+	}
+	files, err := findYangFiles(paths)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no YANG files found")
+	}
+	for _, f := range files {
+		if a.Config.Debug {
+			a.Logger.Printf("loading %s file", f)
+		}
+		if err := ms.Read(f); err != nil {
+			return nil, err
+		}
+	}
+	if errs := ms.Process(); len(errs) > 0 {
+		for _, e := range errs {
+			fmt.Fprintf(os.Stderr, "yang processing error: %v\n", e)
+		}
+		return nil, fmt.Errorf("yang processing failed with %d errors", len(errs))
+	}
+	excludes := make([]*regexp.Regexp, 0, len(a.Config.GlobalFlags.Exclude))
+	for _, e := range a.Config.GlobalFlags.Exclude {
+		re, err := regexp.Compile(e)
+		if err != nil {
+			return nil, err
+		}
+		excludes = append(excludes, re)
+	}
+	root := buildRootEntry()
+MODULES:
+	for _, m := range ms.Modules {
+		if _, ok := root.Dir[m.Name]; ok {
+			continue
+		}
+		for _, re := range excludes {
+			if re.MatchString(m.Name) {
+				continue MODULES
+			}
+		}
+		root.Dir[m.Name] = yang.ToEntry(m)
+	}
+	return yangdiff.NewSchema(root), nil
+}
+
+// yangDiffConfigPaths returns the paths referenced by the config file subscriptions,
+// processors and Set requests.
+func (a *App) yangDiffConfigPaths(ctx context.Context) ([]*configPath, error) {
+	cps := make([]*configPath, 0)
+	subs, err := a.Config.GetSubscriptions(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading subscriptions config: %v", err)
+	}
+	for _, name := range sortedKeys(subs) {
+		for _, p := range subscriptionPaths(subs[name]) {
+			cps = append(cps, &configPath{source: "subscription " + name, path: p})
+		}
+	}
+	eps, err := a.Config.GetEventProcessors()
+	if err != nil {
+		return nil, fmt.Errorf("failed reading processors config: %v", err)
+	}
+	for _, name := range sortedKeys(eps) {
+		for _, p := range extractConfigPaths(stringValues(eps[name])...) {
+			cps = append(cps, &configPath{source: "processor " + name, path: p})
+		}
+	}
+	for _, f := range a.Config.FileConfig.GetStringSlice("set-request-file") {
+		b, err := gfile.ReadFile(ctx, f)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading set request file %q: %v", f, err)
+		}
+		for _, p := range extractConfigPaths(string(b)) {
+			cps = append(cps, &configPath{source: "set-request-file " + f, path: p})
+		}
+	}
+	for _, k := range []string{"set-update-path", "set-replace-path", "set-union-replace-path", "set-delete"} {
+		for _, p := range extractConfigPaths(a.Config.FileConfig.GetStringSlice(k)...) {
+			cps = append(cps, &configPath{source: k, path: p})
+		}
+	}
+	return cps, nil
+}
+
+func subscriptionPaths(sc *types.SubscriptionConfig) []string {
+	paths := make([]string, 0, len(sc.Paths))
+	for _, p := range sc.Paths {
+		if sc.Prefix != "" {
+			p = strings.TrimSuffix(sc.Prefix, "/") + "/" + strings.TrimPrefix(p, "/")
+		}
+		paths = append(paths, p)
+	}
+	for _, ssc := range sc.StreamSubscriptions {
+		paths = append(paths, subscriptionPaths(ssc)...)
+	}
+	return paths
+}
+
+// extractConfigPaths returns the unique paths found in ss.
+// Go template actions are replaced with wildcards.
+func extractConfigPaths(ss ...string) []string {
+	seen := make(map[string]struct{})
+	paths := make([]string, 0)
+	for _, s := range ss {
+		s = templateActionRegex.ReplaceAllString(s, "*")
+		for _, m := range configPathRegex.FindAllStringSubmatch(s, -1) {
+			if _, ok := seen[m[1]]; ok {
+				continue
+			}
+			seen[m[1]] = struct{}{}
+			paths = append(paths, m[1])
+		}
+	}
+	return paths
+}
+
+// stringValues returns all the strings found in a decoded config.
+func stringValues(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		ss := make([]string, 0)
+		for _, i := range v {
+			ss = append(ss, stringValues(i)...)
+		}
+		return ss
+	case []string:
+		return v
+	case map[string]any:
+		ss := make([]string, 0)
+		for _, k := range sortedKeys(v) {
+			ss = append(ss, stringValues(v[k])...)
+		}
+		return ss
+	case map[string]map[string]any:
+		ss := make([]string, 0)
+		for _, k := range sortedKeys(v) {
+			ss = append(ss, stringValues(v[k])...)
+		}
+		return ss
+	}
+	return nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func printYangDiff(rep *YangDiffReport, check bool) {
+	d := rep.Diff
+	fmt.Printf("added (%d):\n", len(d.Added))
+	for _, n := range d.Added {
+		fmt.Printf("  + %s (%s)\n", n.Path, n.Type)
+	}
+	fmt.Printf("removed (%d):\n", len(d.Removed))
+	for _, n := range d.Removed {
+		fmt.Printf("  - %s (%s)\n", n.Path, n.Type)
+	}
+	fmt.Printf("moved (%d):\n", len(d.Moved))
+	for _, m := range d.Moved {
+		fmt.Printf("  ~ %s -> %s\n", m.From, m.To)
+	}
+	fmt.Printf("changed (%d):\n", len(d.Changed))
+	for _, c := range d.Changed {
+		fmt.Printf("  * %s: %s\n", c.Path, c)
+	}
+	if !check {
+		return
+	}
+	fmt.Printf("broken (%d):\n", len(rep.Broken))
+	for _, is := range rep.Broken {
+		if is.Node != "" {
+			fmt.Printf("  ! [%s] %s: %s: %s\n", is.Source, is.Path, is.Node, is.Reason)
+			continue
+		}
+		fmt.Printf("  ! [%s] %s: %s\n", is.Source, is.Path, is.Reason)
+	}
+}
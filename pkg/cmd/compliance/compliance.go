@@ -0,0 +1,27 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package compliance
+
+import (
+	"github.com/openconfig/gnmic/pkg/app"
+	"github.com/spf13/cobra"
+)
+
+// complianceCmd represents the compliance command
+func New(gApp *app.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "compliance",
+		Short:        "evaluate the compliance rules against the targets",
+		PreRunE:      gApp.CompliancePreRunE,
+		RunE:         gApp.ComplianceRunE,
+		SilenceUsage: true,
+	}
+	gApp.InitComplianceFlags(cmd)
+	return cmd
+}
@@ -19,7 +19,9 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/openconfig/gnmic/pkg/app"
+	"github.com/openconfig/gnmic/pkg/cmd/bench"
 	"github.com/openconfig/gnmic/pkg/cmd/capabilities"
+	"github.com/openconfig/gnmic/pkg/cmd/compliance"
 	"github.com/openconfig/gnmic/pkg/cmd/diff"
 	"github.com/openconfig/gnmic/pkg/cmd/generate"
 	"github.com/openconfig/gnmic/pkg/cmd/get"
@@ -31,6 +33,7 @@ import (
 	"github.com/openconfig/gnmic/pkg/cmd/set"
 	"github.com/openconfig/gnmic/pkg/cmd/subscribe"
 	"github.com/openconfig/gnmic/pkg/cmd/version"
+	"github.com/openconfig/gnmic/pkg/cmd/yang"
 )
 
 var encodings = [][2]string{
@@ -67,7 +70,9 @@ func newRootCmd() *cobra.Command {
 	gApp.RootCmd.AddCommand(newPromptCmd())
 
 	// Subcommands
+	gApp.RootCmd.AddCommand(bench.New(gApp))
 	gApp.RootCmd.AddCommand(capabilities.New(gApp))
+	gApp.RootCmd.AddCommand(compliance.New(gApp))
 	gApp.RootCmd.AddCommand(get.New(gApp))
 	gApp.RootCmd.AddCommand(getset.New(gApp))
 	gApp.RootCmd.AddCommand(listener.New(gApp))
@@ -79,6 +84,7 @@ func newRootCmd() *cobra.Command {
 	gApp.RootCmd.AddCommand(version.New(gApp))
 	gApp.RootCmd.AddCommand(proxy.New(gApp))
 	gApp.RootCmd.AddCommand(processor.New(gApp))
+	gApp.RootCmd.AddCommand(yang.New(gApp))
 	return gApp.RootCmd
 }
 
@@ -0,0 +1,42 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package yang
+
+import (
+	"github.com/openconfig/gnmic/pkg/app"
+	"github.com/spf13/cobra"
+)
+
+// New creates the yang command tree.
+func New(gApp *app.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "yang",
+		Short:        "YANG models utilities",
+		SilenceUsage: true,
+	}
+	cmd.AddCommand(newYangDiffCmd(gApp))
+	return cmd
+}
+
+// newYangDiffCmd creates a new yang diff command.
+func newYangDiffCmd(gApp *app.App) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "compare two YANG model sets and check the config paths against the new one",
+		Annotations: map[string]string{
+			"--old": "YANG",
+			"--new": "YANG",
+		},
+		PreRunE:      gApp.YangDiffPreRunE,
+		RunE:         gApp.YangDiffRunE,
+		SilenceUsage: true,
+	}
+	gApp.InitYangDiffFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,388 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package compliance evaluates declarative rules, written as CEL expressions,
+// against the JSON trees built from the targets Get responses or cached notifications.
+package compliance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/api"
+	"github.com/openconfig/gnmic/pkg/api/path"
+)
+
+const (
+	SourceGet   = "get"
+	SourceCache = "cache"
+
+	StatusPass  = "pass"
+	StatusFail  = "fail"
+	StatusError = "error"
+
+	FormatJSON  = "json"
+	FormatJUnit = "junit"
+	FormatHTML  = "html"
+
+	defaultTimeout   = 30 * time.Second
+	defaultEventName = "compliance"
+	// maximum number of evidence paths per result.
+	maxEvidence = 100
+)
+
+// Config is the compliance rules configuration.
+type Config struct {
+	// rule name to rule definition.
+	Rules map[string]*Rule `mapstructure:"rules,omitempty" json:"rules,omitempty"`
+	// the rules are evaluated periodically if set,
+	// otherwise they are only evaluated on demand.
+	Interval time.Duration `mapstructure:"interval,omitempty" json:"interval,omitempty"`
+	// timeout of the data retrieval of a rule.
+	Timeout time.Duration `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	// outputs the results are written to as events.
+	Outputs []string `mapstructure:"outputs,omitempty" json:"outputs,omitempty"`
+	// name of the results events.
+	EventName string `mapstructure:"event-name,omitempty" json:"event-name,omitempty"`
+	// report files written after each scheduled evaluation.
+	Reports []*ReportConfig `mapstructure:"reports,omitempty" json:"reports,omitempty"`
+}
+
+// Rule is a CEL assertion evaluated against the data of each selected target.
+type Rule struct {
+	Description string `mapstructure:"description,omitempty" json:"description,omitempty"`
+	Severity    string `mapstructure:"severity,omitempty" json:"severity,omitempty"`
+	// target selectors, using the `--select` flag syntax.
+	// The rule applies to the targets matching any of them, all targets if empty.
+	Targets []string `mapstructure:"targets,omitempty" json:"targets,omitempty"`
+	// `get` or `cache`.
+	Source string `mapstructure:"source,omitempty" json:"source,omitempty"`
+	// paths of the data the rule is evaluated against.
+	Paths []string `mapstructure:"paths,omitempty" json:"paths,omitempty"`
+	// Get request data type and encoding.
+	Type     string `mapstructure:"type,omitempty" json:"type,omitempty"`
+	Encoding string `mapstructure:"encoding,omitempty" json:"encoding,omitempty"`
+	// path of the nodes the assertion is evaluated against, each failing node is
+	// reported as evidence. If empty, the assertion is evaluated once against the whole tree.
+	Select string `mapstructure:"select,omitempty" json:"select,omitempty"`
+	// CEL expression returning a boolean.
+	Assert string `mapstructure:"assert,omitempty" json:"assert,omitempty"`
+}
+
+// ReportConfig is a report file and its format.
+type ReportConfig struct {
+	// json, junit or html.
+	Format string `mapstructure:"format,omitempty" json:"format,omitempty"`
+	File   string `mapstructure:"file,omitempty" json:"file,omitempty"`
+}
+
+// Result is the outcome of a rule evaluated against a target.
+type Result struct {
+	Rule        string    `json:"rule"`
+	Description string    `json:"description,omitempty"`
+	Severity    string    `json:"severity,omitempty"`
+	Target      string    `json:"target"`
+	Status      string    `json:"status"`
+	Message     string    `json:"message,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+	// number of nodes the assertion was evaluated against.
+	Checked int `json:"checked"`
+	// number of failing nodes.
+	Failed int `json:"failed"`
+	// paths of the failing nodes, or of the rule data if it does not select nodes.
+	Evidence []string `json:"evidence,omitempty"`
+}
+
+type GetFn func(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetResponse, error)
+
+// CacheFn returns the cached notifications of the target under the path p.
+type CacheFn func(p *gnmi.Path) ([]*gnmi.Notification, error)
+
+// Target is a target the rules are evaluated against.
+type Target struct {
+	Name string
+	// encoding of the Get requests of the rules that do not set one.
+	Encoding string
+	Get      GetFn
+	// nil if the cache is not available.
+	Cache CacheFn
+}
+
+// Engine evaluates the compiled rules.
+type Engine struct {
+	cfg   *Config
+	rules []*rule
+}
+
+type rule struct {
+	name   string
+	cfg    *Rule
+	opts   []api.GNMIOption
+	paths  []*gnmi.Path
+	sel    []*gnmi.PathElem
+	assert cel.Program
+}
+
+func New(cfg *Config) (*Engine, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.EventName == "" {
+		cfg.EventName = defaultEventName
+	}
+	for _, rc := range cfg.Reports {
+		switch rc.Format {
+		case FormatJSON, FormatJUnit, FormatHTML:
+		default:
+			return nil, fmt.Errorf("unknown compliance report format %q", rc.Format)
+		}
+		if rc.File == "" {
+			return nil, fmt.Errorf("compliance %s report: missing file", rc.Format)
+		}
+	}
+	env, err := cel.NewEnv(
+		cel.Variable("node", cel.DynType),
+		cel.Variable("data", cel.DynType),
+		cel.Variable("path", cel.StringType),
+		cel.Variable("target", cel.StringType),
+		// JSON numbers are doubles, allow comparing them with integer literals.
+		cel.CrossTypeNumericComparisons(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(cfg.Rules))
+	for n := range cfg.Rules {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	e := &Engine{cfg: cfg, rules: make([]*rule, 0, len(names))}
+	for _, name := range names {
+		r, err := newRule(env, name, cfg.Rules[name])
+		if err != nil {
+			return nil, fmt.Errorf("compliance rule %q: %v", name, err)
+		}
+		e.rules = append(e.rules, r)
+	}
+	return e, nil
+}
+
+func newRule(env *cel.Env, name string, rc *Rule) (*rule, error) {
+	if rc == nil {
+		return nil, errors.New("empty rule")
+	}
+	switch rc.Source {
+	case "":
+		rc.Source = SourceGet
+	case SourceGet, SourceCache:
+	default:
+		return nil, fmt.Errorf("unknown source %q", rc.Source)
+	}
+	if len(rc.Paths) == 0 {
+		return nil, errors.New("missing paths")
+	}
+	if strings.TrimSpace(rc.Assert) == "" {
+		return nil, errors.New("missing assert expression")
+	}
+	r := &rule{name: name, cfg: rc}
+	r.opts = make([]api.GNMIOption, 0, len(rc.Paths)+1)
+	for _, p := range rc.Paths {
+		gp, err := path.ParsePath(p)
+		if err != nil {
+			return nil, fmt.Errorf("path %q: %v", p, err)
+		}
+		r.paths = append(r.paths, gp)
+		r.opts = append(r.opts, api.Path(p))
+	}
+	if rc.Type != "" {
+		r.opts = append(r.opts, api.DataType(rc.Type))
+	}
+	_, err := r.getRequest("")
+	if err != nil {
+		return nil, err
+	}
+	if rc.Select != "" {
+		sp, err := path.ParsePath(rc.Select)
+		if err != nil {
+			return nil, fmt.Errorf("select %q: %v", rc.Select, err)
+		}
+		r.sel = sp.GetElem()
+	}
+	ast, iss := env.Compile(rc.Assert)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf("assert: %v", iss.Err())
+	}
+	if t := ast.OutputType(); t != cel.BoolType && t != cel.DynType {
+		return nil, fmt.Errorf("assert: the expression must return a bool, got %s", t)
+	}
+	r.assert, err = env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("assert: %v", err)
+	}
+	return r, nil
+}
+
+func (e *Engine) Config() *Config {
+	return e.cfg
+}
+
+// Rules returns the names of the rules, sorted.
+func (e *Engine) Rules() []string {
+	names := make([]string, 0, len(e.rules))
+	for _, r := range e.rules {
+		names = append(names, r.name)
+	}
+	return names
+}
+
+// Check evaluates the rules accepted by match against the target.
+// The data of each rule is retrieved using getFn or cacheFn depending on the rule source.
+func (e *Engine) Check(ctx context.Context, t *Target, match func(name string, r *Rule) bool) []*Result {
+	res := make([]*Result, 0, len(e.rules))
+	for _, r := range e.rules {
+		if match != nil && !match(r.name, r.cfg) {
+			continue
+		}
+		res = append(res, r.check(ctx, e.cfg.Timeout, t))
+	}
+	return res
+}
+
+func (r *rule) getRequest(encoding string) (*gnmi.GetRequest, error) {
+	if r.cfg.Encoding != "" {
+		encoding = r.cfg.Encoding
+	}
+	if encoding == "" {
+		return api.NewGetRequest(r.opts...)
+	}
+	return api.NewGetRequest(append(r.opts[:len(r.opts):len(r.opts)], api.Encoding(encoding))...)
+}
+
+func (r *rule) check(ctx context.Context, timeout time.Duration, t *Target) *Result {
+	res := &Result{
+		Rule:        r.name,
+		Description: r.cfg.Description,
+		Severity:    r.cfg.Severity,
+		Target:      t.Name,
+		Timestamp:   time.Now(),
+	}
+	ns, err := r.fetch(ctx, timeout, t)
+	if err != nil {
+		res.Status = StatusError
+		res.Message = err.Error()
+		return res
+	}
+	tr, err := buildTree(ns)
+	if err != nil {
+		res.Status = StatusError
+		res.Message = err.Error()
+		return res
+	}
+	// a rule evaluated against no data is not reported as compliant:
+	// the paths or the select path may be wrong or the cache not synced yet.
+	if len(tr.root) == 0 {
+		res.Status = StatusError
+		res.Message = "no data"
+		return res
+	}
+	nodes := []*node{{path: "/", value: tr.root}}
+	if r.sel != nil {
+		nodes = tr.selectNodes(r.sel)
+		if len(nodes) == 0 {
+			res.Status = StatusError
+			res.Message = fmt.Sprintf("no data matching select %q", r.cfg.Select)
+			return res
+		}
+	}
+	res.Status = StatusPass
+	for _, n := range nodes {
+		res.Checked++
+		ok, err := r.eval(tr.root, n, t.Name)
+		if err != nil {
+			res.Status = StatusError
+			if res.Message == "" {
+				res.Message = fmt.Sprintf("%s: %v", n.path, err)
+			}
+			continue
+		}
+		if ok {
+			continue
+		}
+		if res.Status == StatusPass {
+			res.Status = StatusFail
+		}
+		res.Failed++
+		if r.sel != nil && len(res.Evidence) < maxEvidence {
+			res.Evidence = append(res.Evidence, n.path)
+		}
+	}
+	if res.Status != StatusPass && r.sel == nil {
+		res.Evidence = r.cfg.Paths
+	}
+	if res.Failed > 0 && res.Message == "" {
+		res.Message = fmt.Sprintf("%d of %d node(s) failed the assertion", res.Failed, res.Checked)
+	}
+	return res
+}
+
+func (r *rule) fetch(ctx context.Context, timeout time.Duration, t *Target) ([]*gnmi.Notification, error) {
+	switch r.cfg.Source {
+	case SourceCache:
+		if t.Cache == nil {
+			return nil, errors.New("cache not available")
+		}
+		ns := make([]*gnmi.Notification, 0)
+		for _, p := range r.paths {
+			rns, err := t.Cache(p)
+			if err != nil {
+				return nil, fmt.Errorf("cache read: %v", err)
+			}
+			ns = append(ns, rns...)
+		}
+		return ns, nil
+	default:
+		req, err := r.getRequest(t.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		rsp, err := t.Get(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("get: %v", err)
+		}
+		return rsp.GetNotification(), nil
+	}
+}
+
+func (r *rule) eval(data any, n *node, target string) (bool, error) {
+	out, _, err := r.assert.Eval(map[string]any{
+		"node":   n.value,
+		"data":   data,
+		"path":   n.path,
+		"target": target,
+	})
+	if err != nil {
+		return false, err
+	}
+	b, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("assert returned %T instead of a bool", out.Value())
+	}
+	return b, nil
+}
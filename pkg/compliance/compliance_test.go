@@ -0,0 +1,358 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package compliance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/api/path"
+)
+
+func notification(xpath string, val *gnmi.TypedValue) *gnmi.Notification {
+	p, _ := path.ParsePath(xpath)
+	return &gnmi.Notification{
+		Timestamp: 1,
+		Update:    []*gnmi.Update{{Path: p, Val: val}},
+	}
+}
+
+func jsonVal(s string) *gnmi.TypedValue {
+	return &gnmi.TypedValue{Value: &gnmi.TypedValue_JsonIetfVal{JsonIetfVal: []byte(s)}}
+}
+
+func uintVal(u uint64) *gnmi.TypedValue {
+	return &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: u}}
+}
+
+func stringVal(s string) *gnmi.TypedValue {
+	return &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: s}}
+}
+
+var routerData = []*gnmi.Notification{
+	// BGP neighbors as a single JSON value.
+	notification("/network-instances/network-instance[name=default]/protocols/protocol[identifier=BGP][name=bgp]/bgp/neighbors", jsonVal(`{
+		"openconfig-network-instance:neighbor": [
+			{"neighbor-address": "10.0.0.1", "config": {"neighbor-address": "10.0.0.1", "auth-password": "secret"}},
+			{"neighbor-address": "10.0.0.2", "config": {"neighbor-address": "10.0.0.2"}}
+		]
+	}`)),
+	// interfaces MTU as leaves.
+	notification("/interfaces/interface[name=e1]/config/mtu", uintVal(9212)),
+	notification("/interfaces/interface[name=e2]/config/mtu", uintVal(1500)),
+	notification("/interfaces/interface[name=e2]/config/description", stringVal("core")),
+	notification("/system/ntp/servers", jsonVal(`{"server": [{"address": "10.1.1.1"}, {"address": "10.1.1.2"}]}`)),
+}
+
+func getFn(ns []*gnmi.Notification, err error) GetFn {
+	return func(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetResponse, error) {
+		if err != nil {
+			return nil, err
+		}
+		return &gnmi.GetResponse{Notification: ns}, nil
+	}
+}
+
+func TestBuildTree(t *testing.T) {
+	tr, err := buildTree(routerData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ifaces, ok := tr.root["interfaces"].(map[string]any)["interface"].([]any)
+	if !ok || len(ifaces) != 2 {
+		t.Fatalf("unexpected interfaces: %v", tr.root["interfaces"])
+	}
+	e2 := ifaces[1].(map[string]any)
+	want := map[string]any{
+		"name": "e2",
+		"config": map[string]any{
+			"mtu":         uint64(1500),
+			"description": "core",
+		},
+	}
+	if !reflect.DeepEqual(e2, want) {
+		t.Errorf("unexpected entry: %v", e2)
+	}
+	if !reflect.DeepEqual(tr.keys["/interfaces/interface"], []string{"name"}) {
+		t.Errorf("unexpected keys: %v", tr.keys)
+	}
+}
+
+func TestSelectNodes(t *testing.T) {
+	tr, err := buildTree(routerData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		sel  string
+		want []string
+	}{
+		{
+			sel:  "/interfaces/interface/config",
+			want: []string{"/interfaces/interface[name=e1]/config", "/interfaces/interface[name=e2]/config"},
+		},
+		{
+			sel:  "/interfaces/interface[name=e2]",
+			want: []string{"/interfaces/interface[name=e2]"},
+		},
+		{
+			sel: "/network-instances/network-instance/protocols/protocol[identifier=*]/bgp/neighbors/neighbor[neighbor-address=*]",
+			want: []string{
+				"/network-instances/network-instance[name=default]/protocols/protocol[identifier=BGP][name=bgp]/bgp/neighbors/neighbor[neighbor-address=10.0.0.1]",
+				"/network-instances/network-instance[name=default]/protocols/protocol[identifier=BGP][name=bgp]/bgp/neighbors/neighbor[neighbor-address=10.0.0.2]",
+			},
+		},
+		{
+			// key names unknown, the index is used.
+			sel:  "/system/ntp/servers/server",
+			want: []string{"/system/ntp/servers/server[#=0]", "/system/ntp/servers/server[#=1]"},
+		},
+		{
+			sel:  "/*/interface[name=e1]/config/mtu",
+			want: []string{"/interfaces/interface[name=e1]/config/mtu"},
+		},
+		{
+			sel:  "/interfaces/interface[name=e3]",
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sel, func(t *testing.T) {
+			p, err := path.ParsePath(tt.sel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0)
+			for _, n := range tr.selectNodes(p.GetElem()) {
+				got = append(got, n.path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	e, err := New(&Config{Rules: map[string]*Rule{
+		"bgp-auth": {
+			Severity: "critical",
+			Paths:    []string{"/network-instances/network-instance/protocols/protocol/bgp/neighbors"},
+			Select:   "/network-instances/network-instance/protocols/protocol/bgp/neighbors/neighbor[neighbor-address=*]",
+			Assert:   `"auth-password" in node.config`,
+		},
+		"core-mtu": {
+			Paths:  []string{"/interfaces"},
+			Select: "/interfaces/interface/config",
+			Assert: `node.mtu >= 9000`,
+		},
+		"ntp-servers": {
+			Paths:  []string{"/system/ntp/servers"},
+			Assert: `data.system.ntp.servers.server.map(s, s.address) == ["10.1.1.1", "10.1.1.2"]`,
+		},
+		"description": {
+			Paths:  []string{"/interfaces"},
+			Select: "/interfaces/interface/config",
+			Assert: `node.description == "core"`,
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := e.Check(context.Background(), &Target{Name: "router1", Get: getFn(routerData, nil)}, nil)
+	got := make(map[string]*Result)
+	for _, r := range res {
+		got[r.Rule] = r
+	}
+	bgp := got["bgp-auth"]
+	if bgp.Status != StatusFail || bgp.Checked != 2 || bgp.Failed != 1 || bgp.Severity != "critical" ||
+		!reflect.DeepEqual(bgp.Evidence, []string{"/network-instances/network-instance[name=default]/protocols/protocol[identifier=BGP][name=bgp]/bgp/neighbors/neighbor[neighbor-address=10.0.0.2]"}) {
+		t.Errorf("unexpected bgp-auth result: %+v", bgp)
+	}
+	mtu := got["core-mtu"]
+	if mtu.Status != StatusFail || !reflect.DeepEqual(mtu.Evidence, []string{"/interfaces/interface[name=e2]/config"}) {
+		t.Errorf("unexpected core-mtu result: %+v", mtu)
+	}
+	if ntp := got["ntp-servers"]; ntp.Status != StatusPass || ntp.Checked != 1 || len(ntp.Evidence) != 0 {
+		t.Errorf("unexpected ntp-servers result: %+v", ntp)
+	}
+	// e1 has no description.
+	if d := got["description"]; d.Status != StatusError || !strings.Contains(d.Message, "interface[name=e1]") {
+		t.Errorf("unexpected description result: %+v", d)
+	}
+
+	match := func(name string, r *Rule) bool { return name == "ntp-servers" }
+	res = e.Check(context.Background(), &Target{Name: "router1", Get: getFn(nil, errors.New("unavailable"))}, match)
+	if len(res) != 1 || res[0].Status != StatusError || !strings.Contains(res[0].Message, "unavailable") {
+		t.Errorf("unexpected results: %+v", res)
+	}
+	// the ntp servers list is missing.
+	res = e.Check(context.Background(), &Target{Name: "router1", Get: getFn(routerData[:1], nil)}, match)
+	if res[0].Status != StatusError {
+		t.Errorf("unexpected result: %+v", res[0])
+	}
+	// no data at all.
+	res = e.Check(context.Background(), &Target{Name: "router1", Get: getFn(nil, nil)}, match)
+	if res[0].Status != StatusError || res[0].Message != "no data" {
+		t.Errorf("unexpected result: %+v", res[0])
+	}
+	// the select path matches no node.
+	match = func(name string, r *Rule) bool { return name == "core-mtu" }
+	res = e.Check(context.Background(), &Target{Name: "router1", Get: getFn(routerData[:1], nil)}, match)
+	if res[0].Status != StatusError || res[0].Checked != 0 || !strings.Contains(res[0].Message, "no data matching select") {
+		t.Errorf("unexpected result: %+v", res[0])
+	}
+}
+
+func TestCheckGetRequest(t *testing.T) {
+	e, err := New(&Config{Rules: map[string]*Rule{
+		"r1": {Paths: []string{"/system"}, Type: "config", Assert: "true"},
+		"r2": {Paths: []string{"/system"}, Encoding: "json_ietf", Assert: "true"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqs := make([]*gnmi.GetRequest, 0, 2)
+	get := func(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetResponse, error) {
+		reqs = append(reqs, req)
+		return &gnmi.GetResponse{}, nil
+	}
+	e.Check(context.Background(), &Target{Name: "router1", Encoding: "proto", Get: get}, nil)
+	if len(reqs) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(reqs))
+	}
+	if reqs[0].GetEncoding() != gnmi.Encoding_PROTO || reqs[0].GetType() != gnmi.GetRequest_CONFIG {
+		t.Errorf("unexpected r1 request: %v", reqs[0])
+	}
+	if reqs[1].GetEncoding() != gnmi.Encoding_JSON_IETF || reqs[1].GetType() != gnmi.GetRequest_ALL {
+		t.Errorf("unexpected r2 request: %v", reqs[1])
+	}
+}
+
+func TestCheckCache(t *testing.T) {
+	e, err := New(&Config{Rules: map[string]*Rule{
+		"core-mtu": {
+			Source: SourceCache,
+			Paths:  []string{"/interfaces/interface/config/mtu"},
+			Select: "/interfaces/interface/config/mtu",
+			Assert: `node >= 9000 || target == "router2"`,
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cacheFn := func(p *gnmi.Path) ([]*gnmi.Notification, error) {
+		return routerData[1:3], nil
+	}
+	res := e.Check(context.Background(), &Target{Name: "router1", Cache: cacheFn}, nil)
+	if res[0].Status != StatusFail || res[0].Failed != 1 {
+		t.Errorf("unexpected result: %+v", res[0])
+	}
+	res = e.Check(context.Background(), &Target{Name: "router2", Cache: cacheFn}, nil)
+	if res[0].Status != StatusPass || res[0].Checked != 2 {
+		t.Errorf("unexpected result: %+v", res[0])
+	}
+	res = e.Check(context.Background(), &Target{Name: "router1"}, nil)
+	if res[0].Status != StatusError {
+		t.Errorf("unexpected result: %+v", res[0])
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := map[string]*Config{
+		"missing paths":  {Rules: map[string]*Rule{"r": {Assert: "true"}}},
+		"missing assert": {Rules: map[string]*Rule{"r": {Paths: []string{"/"}}}},
+		"syntax":         {Rules: map[string]*Rule{"r": {Paths: []string{"/"}, Assert: "node.mtu >"}}},
+		"not a bool":     {Rules: map[string]*Rule{"r": {Paths: []string{"/"}, Assert: `"x"`}}},
+		"source":         {Rules: map[string]*Rule{"r": {Paths: []string{"/"}, Assert: "true", Source: "file"}}},
+		"report format":  {Reports: []*ReportConfig{{Format: "pdf", File: "r.pdf"}}},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := New(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func testReport() *Report {
+	ts := time.Unix(100, 0)
+	return NewReport(ts, []*Result{
+		{Rule: "r2", Target: "t1", Status: StatusPass, Checked: 1, Timestamp: ts},
+		{Rule: "r1", Target: "t2", Status: StatusError, Message: "get: unavailable", Timestamp: ts},
+		{Rule: "r1", Target: "t1", Status: StatusFail, Severity: "major", Checked: 2, Failed: 1,
+			Message: "1 of 2 node(s) failed the assertion", Evidence: []string{"/a[k=<1>]"}, Timestamp: ts},
+	})
+}
+
+func TestReport(t *testing.T) {
+	r := testReport()
+	if r.Compliant() || *r.Summary != (Summary{Total: 3, Passed: 1, Failed: 1, Errors: 1}) {
+		t.Errorf("unexpected summary: %+v", r.Summary)
+	}
+	if r.Results[0].Rule != "r1" || r.Results[0].Target != "t1" || r.Results[2].Rule != "r2" {
+		t.Errorf("unexpected results order")
+	}
+
+	buf := new(bytes.Buffer)
+	if err := r.Write(buf, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var jr Report
+	if err := json.Unmarshal(buf.Bytes(), &jr); err != nil || len(jr.Results) != 3 {
+		t.Errorf("unexpected JSON report: %v: %s", err, buf)
+	}
+
+	buf.Reset()
+	if err := r.Write(buf, FormatJUnit); err != nil {
+		t.Fatal(err)
+	}
+	var ju junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &ju); err != nil {
+		t.Fatal(err)
+	}
+	if len(ju.Suites) != 2 || ju.Failures != 1 || ju.Errors != 1 {
+		t.Fatalf("unexpected JUnit report: %s", buf)
+	}
+	r1 := ju.Suites[0]
+	if r1.Name != "r1" || r1.Tests != 2 || r1.Cases[0].Failure == nil || r1.Cases[0].Failure.Text != "/a[k=<1>]" ||
+		r1.Cases[1].Error == nil || ju.Suites[1].Cases[0].Failure != nil {
+		t.Errorf("unexpected JUnit report: %s", buf)
+	}
+
+	buf.Reset()
+	if err := r.Write(buf, FormatHTML); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<li>/a[k=&lt;1&gt;]</li>") || !strings.Contains(buf.String(), `class="fail"`) {
+		t.Errorf("unexpected HTML report: %s", buf)
+	}
+}
+
+func TestReportEvents(t *testing.T) {
+	evs := testReport().Events("compliance")
+	if len(evs) != 3 {
+		t.Fatalf("unexpected events: %v", evs)
+	}
+	ev := evs[0]
+	if ev.Name != "compliance" || ev.Tags["source"] != "t1" || ev.Tags["rule"] != "r1" ||
+		ev.Tags["severity"] != "major" || ev.Values["failed"] != 1 || ev.Values["evidence"] != "/a[k=<1>]" {
+		t.Errorf("unexpected event: %v", ev)
+	}
+}
@@ -0,0 +1,265 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package compliance
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+// Report is the set of results of a compliance run.
+type Report struct {
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Summary *Summary  `json:"summary"`
+	Results []*Result `json:"results"`
+}
+
+type Summary struct {
+	Total  int `json:"total"`
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+	Errors int `json:"errors"`
+}
+
+// NewReport sorts the results by rule and target and summarizes them.
+func NewReport(start time.Time, results []*Result) *Report {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rule == results[j].Rule {
+			return results[i].Target < results[j].Target
+		}
+		return results[i].Rule < results[j].Rule
+	})
+	r := &Report{
+		Start:   start,
+		End:     time.Now(),
+		Summary: &Summary{Total: len(results)},
+		Results: results,
+	}
+	for _, res := range results {
+		switch res.Status {
+		case StatusPass:
+			r.Summary.Passed++
+		case StatusFail:
+			r.Summary.Failed++
+		default:
+			r.Summary.Errors++
+		}
+	}
+	return r
+}
+
+// Compliant returns true if all the results passed.
+func (r *Report) Compliant() bool {
+	return r.Summary.Passed == r.Summary.Total
+}
+
+// Write writes the report in the given format.
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		return r.WriteJSON(w)
+	case FormatJUnit:
+		return r.WriteJUnit(w)
+	case FormatHTML:
+		return r.WriteHTML(w)
+	default:
+		return fmt.Errorf("unknown compliance report format %q", format)
+	}
+}
+
+// WriteFile writes the report to a file, the file is replaced
+// once the report is fully written.
+func (r *Report) WriteFile(name, format string) error {
+	tmp := name + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = r.Write(f, format)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Errors   int               `xml:"errors,attr"`
+	Time     string            `xml:"time,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Errors    int              `xml:"errors,attr"`
+	Timestamp string           `xml:"timestamp,attr"`
+	Cases     []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as a JUnit XML document,
+// with a test suite per rule and a test case per target.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	ts := &junitTestSuites{
+		Name:     "compliance",
+		Tests:    r.Summary.Total,
+		Failures: r.Summary.Failed,
+		Errors:   r.Summary.Errors,
+		Time:     fmt.Sprintf("%.3f", r.End.Sub(r.Start).Seconds()),
+	}
+	var suite *junitTestSuite
+	for _, res := range r.Results {
+		if suite == nil || suite.Name != res.Rule {
+			suite = &junitTestSuite{
+				Name:      res.Rule,
+				Timestamp: res.Timestamp.UTC().Format(time.RFC3339),
+			}
+			ts.Suites = append(ts.Suites, suite)
+		}
+		suite.Tests++
+		tc := &junitTestCase{Name: res.Target, ClassName: res.Rule}
+		msg := &junitMessage{
+			Message: res.Message,
+			Type:    res.Severity,
+			Text:    strings.Join(res.Evidence, "\n"),
+		}
+		switch res.Status {
+		case StatusFail:
+			suite.Failures++
+			tc.Failure = msg
+		case StatusError:
+			suite.Errors++
+			tc.Error = msg
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(ts)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+var htmlReportTemplate = template.Must(template.New("compliance").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Compliance report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #eee; }
+.pass { color: #1a7f37; }
+.fail { color: #cf222e; font-weight: bold; }
+.error { color: #9a6700; font-weight: bold; }
+ul { margin: 0; padding-left: 1.2em; }
+</style>
+</head>
+<body>
+<h1>Compliance report</h1>
+<p>{{ .Start.Format "2006-01-02T15:04:05Z07:00" }} - {{ .End.Format "2006-01-02T15:04:05Z07:00" }}</p>
+<p>total: {{ .Summary.Total }}, passed: {{ .Summary.Passed }}, failed: {{ .Summary.Failed }}, errors: {{ .Summary.Errors }}</p>
+<table>
+<tr><th>Rule</th><th>Severity</th><th>Target</th><th>Status</th><th>Checked</th><th>Message</th><th>Evidence</th></tr>
+{{- range .Results }}
+<tr>
+<td>{{ .Rule }}{{ if .Description }}<br><small>{{ .Description }}</small>{{ end }}</td>
+<td>{{ .Severity }}</td>
+<td>{{ .Target }}</td>
+<td class="{{ .Status }}">{{ .Status }}</td>
+<td>{{ .Checked }}</td>
+<td>{{ .Message }}</td>
+<td>{{ if .Evidence }}<ul>{{ range .Evidence }}<li>{{ . }}</li>{{ end }}</ul>{{ end }}</td>
+</tr>
+{{- end }}
+</table>
+</body>
+</html>
+`))
+
+func (r *Report) WriteHTML(w io.Writer) error {
+	return htmlReportTemplate.Execute(w, r)
+}
+
+// Events converts the report results to events named name.
+func (r *Report) Events(name string) []*formatters.EventMsg {
+	evs := make([]*formatters.EventMsg, 0, len(r.Results))
+	for _, res := range r.Results {
+		ev := &formatters.EventMsg{
+			Name:      name,
+			Timestamp: res.Timestamp.UnixNano(),
+			Tags: map[string]string{
+				"source": res.Target,
+				"rule":   res.Rule,
+				"status": res.Status,
+			},
+			Values: map[string]interface{}{
+				"status":  res.Status,
+				"checked": res.Checked,
+				"failed":  res.Failed,
+			},
+		}
+		if res.Severity != "" {
+			ev.Tags["severity"] = res.Severity
+		}
+		if res.Message != "" {
+			ev.Values["message"] = res.Message
+		}
+		if len(res.Evidence) > 0 {
+			ev.Values["evidence"] = strings.Join(res.Evidence, ",")
+		}
+		evs = append(evs, ev)
+	}
+	return evs
+}
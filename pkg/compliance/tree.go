@@ -0,0 +1,338 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package compliance
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/api/path"
+)
+
+// tree is the JSON tree built from a set of notifications.
+// Lists are arrays of objects, the entries created from keyed path elements
+// hold their keys as string fields.
+type tree struct {
+	root map[string]any
+	// list schema path (without keys) to its key names,
+	// learned from the notifications paths.
+	keys map[string][]string
+}
+
+// node is a tree node selected by a rule.
+type node struct {
+	path  string
+	value any
+}
+
+func buildTree(ns []*gnmi.Notification) (*tree, error) {
+	t := &tree{
+		root: make(map[string]any),
+		keys: make(map[string][]string),
+	}
+	for _, n := range ns {
+		for _, upd := range n.GetUpdate() {
+			v, err := decodeValue(upd.GetVal())
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", updatePath(n.GetPrefix(), upd.GetPath()), err)
+			}
+			t.insert(path.PathElems(n.GetPrefix(), upd.GetPath()), v)
+		}
+	}
+	return t, nil
+}
+
+func (t *tree) insert(elems []*gnmi.PathElem, v any) {
+	if len(elems) == 0 {
+		if m, ok := v.(map[string]any); ok {
+			merge(t.root, m)
+		}
+		return
+	}
+	m := t.root
+	schema := ""
+	for i, pe := range elems {
+		name := stripPrefix(pe.GetName())
+		schema += "/" + name
+		last := i == len(elems)-1
+		if len(pe.GetKey()) == 0 {
+			if last {
+				m[name] = mergeValue(m[name], v)
+				return
+			}
+			child, ok := m[name].(map[string]any)
+			if !ok {
+				child = make(map[string]any)
+				m[name] = child
+			}
+			m = child
+			continue
+		}
+		if _, ok := t.keys[schema]; !ok {
+			t.keys[schema] = sortedKeys(pe.GetKey())
+		}
+		entry := listEntry(m, name, pe.GetKey())
+		if last {
+			if vm, ok := v.(map[string]any); ok {
+				merge(entry, vm)
+			}
+			return
+		}
+		m = entry
+	}
+}
+
+// listEntry returns the entry of the list name matching keys,
+// the entry is created if it does not exist.
+func listEntry(m map[string]any, name string, keys map[string]string) map[string]any {
+	l, _ := m[name].([]any)
+	for _, e := range l {
+		em, ok := e.(map[string]any)
+		if ok && matchKeys(em, keys) {
+			return em
+		}
+	}
+	em := make(map[string]any, len(keys))
+	for k, v := range keys {
+		em[k] = v
+	}
+	m[name] = append(l, em)
+	return em
+}
+
+// matchKeys returns true if the entry fields match the keys,
+// the "*" key value matches any field value.
+func matchKeys(e map[string]any, keys map[string]string) bool {
+	for k, v := range keys {
+		if v == "*" {
+			continue
+		}
+		ev, ok := e[k]
+		if !ok || scalarString(ev) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeValue merges v into the existing value old.
+func mergeValue(old, v any) any {
+	om, ok := old.(map[string]any)
+	if !ok {
+		return v
+	}
+	vm, ok := v.(map[string]any)
+	if !ok {
+		return v
+	}
+	merge(om, vm)
+	return om
+}
+
+func merge(dst, src map[string]any) {
+	for k, v := range src {
+		dst[k] = mergeValue(dst[k], v)
+	}
+}
+
+// selectNodes returns the nodes matching the path elements,
+// the names and key values can be set to "*".
+func (t *tree) selectNodes(elems []*gnmi.PathElem) []*node {
+	nodes := make([]*node, 0)
+	var walk func(v any, i int, schema string, p []*gnmi.PathElem)
+	walk = func(v any, i int, schema string, p []*gnmi.PathElem) {
+		if i == len(elems) {
+			nodes = append(nodes, &node{
+				path:  "/" + path.GnmiPathToXPath(&gnmi.Path{Elem: p}, false),
+				value: v,
+			})
+			return
+		}
+		m, ok := v.(map[string]any)
+		if !ok {
+			return
+		}
+		pe := elems[i]
+		names := []string{stripPrefix(pe.GetName())}
+		if names[0] == "*" {
+			names = make([]string, 0, len(m))
+			for n := range m {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+		}
+		for _, name := range names {
+			child, ok := m[name]
+			if !ok {
+				continue
+			}
+			cschema := schema + "/" + name
+			l, ok := child.([]any)
+			if !ok {
+				walk(child, i+1, cschema, append(p[:len(p):len(p)], &gnmi.PathElem{Name: name}))
+				continue
+			}
+			keyNames := listKeys(pe.GetKey(), t.keys[cschema])
+			for idx, e := range l {
+				em, ok := e.(map[string]any)
+				if !ok || !matchKeys(em, pe.GetKey()) {
+					continue
+				}
+				walk(em, i+1, cschema, append(p[:len(p):len(p)], entryElem(name, em, keyNames, idx)))
+			}
+		}
+	}
+	walk(t.root, 0, "", nil)
+	return nodes
+}
+
+// entryElem returns the path element of a list entry,
+// the entry index is used as key if its key names are unknown.
+func entryElem(name string, e map[string]any, keyNames []string, idx int) *gnmi.PathElem {
+	pe := &gnmi.PathElem{Name: name, Key: make(map[string]string, len(keyNames))}
+	for _, k := range keyNames {
+		if v, ok := e[k]; ok {
+			pe.Key[k] = scalarString(v)
+		}
+	}
+	if len(pe.Key) == 0 {
+		pe.Key["#"] = fmt.Sprint(idx)
+	}
+	return pe
+}
+
+// listKeys returns the key names of a list, merging the ones set in the
+// rule select path with the ones learned from the notifications paths.
+func listKeys(pattern map[string]string, learned []string) []string {
+	if len(pattern) == 0 {
+		return learned
+	}
+	ks := sortedKeys(pattern)
+	for _, k := range learned {
+		if _, ok := pattern[k]; !ok {
+			ks = append(ks, k)
+		}
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+func sortedKeys(m map[string]string) []string {
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+func scalarString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprint(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
+
+// stripPrefix removes the YANG module prefix from a node name.
+func stripPrefix(s string) string {
+	if i := strings.Index(s, ":"); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+func updatePath(pf, p *gnmi.Path) string {
+	return "/" + path.GnmiPathToXPath(&gnmi.Path{Elem: path.PathElems(pf, p)}, false)
+}
+
+// decodeValue converts a TypedValue to a value usable in a CEL expression,
+// JSON values are decoded with their member names stripped of the module prefixes.
+func decodeValue(tv *gnmi.TypedValue) (any, error) {
+	if tv == nil {
+		return nil, nil
+	}
+	var jsondata []byte
+	switch tv.Value.(type) {
+	case *gnmi.TypedValue_AsciiVal:
+		return tv.GetAsciiVal(), nil
+	case *gnmi.TypedValue_BoolVal:
+		return tv.GetBoolVal(), nil
+	case *gnmi.TypedValue_BytesVal:
+		return tv.GetBytesVal(), nil
+	case *gnmi.TypedValue_DecimalVal:
+		//lint:ignore SA1019 still need DecimalVal for backward compatibility
+		v := tv.GetDecimalVal()
+		return float64(v.Digits) / math.Pow10(int(v.Precision)), nil
+	case *gnmi.TypedValue_FloatVal:
+		//lint:ignore SA1019 still need GetFloatVal for backward compatibility
+		return float64(tv.GetFloatVal()), nil
+	case *gnmi.TypedValue_DoubleVal:
+		return tv.GetDoubleVal(), nil
+	case *gnmi.TypedValue_IntVal:
+		return tv.GetIntVal(), nil
+	case *gnmi.TypedValue_StringVal:
+		return tv.GetStringVal(), nil
+	case *gnmi.TypedValue_UintVal:
+		return tv.GetUintVal(), nil
+	case *gnmi.TypedValue_LeaflistVal:
+		elems := tv.GetLeaflistVal().GetElement()
+		vs := make([]any, 0, len(elems))
+		for _, e := range elems {
+			v, err := decodeValue(e)
+			if err != nil {
+				return nil, err
+			}
+			vs = append(vs, v)
+		}
+		return vs, nil
+	case *gnmi.TypedValue_JsonIetfVal:
+		jsondata = tv.GetJsonIetfVal()
+	case *gnmi.TypedValue_JsonVal:
+		jsondata = tv.GetJsonVal()
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", tv.Value)
+	}
+	var v any
+	err := json.Unmarshal(jsondata, &v)
+	if err != nil {
+		return nil, err
+	}
+	return stripPrefixes(v), nil
+}
+
+func stripPrefixes(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		r := make(map[string]any, len(v))
+		for k, cv := range v {
+			r[stripPrefix(k)] = stripPrefixes(cv)
+		}
+		return r
+	case []any:
+		for i, cv := range v {
+			v[i] = stripPrefixes(cv)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,246 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/api"
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	defaultBenchStepDuration   = time.Minute
+	defaultBenchSubscriptions  = 1
+	defaultBenchSampleInterval = 10 * time.Second
+	defaultBenchGetInterval    = time.Second
+)
+
+// BenchProfile defines the load applied to a target by the bench command:
+// a list of steps, each one running a number of concurrent sample subscriptions
+// while unary RPCs are sent to measure their latency under load.
+type BenchProfile struct {
+	// encoding of the subscriptions and Get requests, defaults to the target encoding.
+	Encoding string `mapstructure:"encoding,omitempty" json:"encoding,omitempty" yaml:"encoding,omitempty"`
+	// default paths of the steps that do not set any.
+	Paths []string `mapstructure:"paths,omitempty" json:"paths,omitempty" yaml:"paths,omitempty"`
+	// steps run in order.
+	Steps []*BenchStep `mapstructure:"steps,omitempty" json:"steps,omitempty" yaml:"steps,omitempty"`
+	// generates steps run after the explicit ones.
+	Ramp *BenchRamp `mapstructure:"ramp,omitempty" json:"ramp,omitempty" yaml:"ramp,omitempty"`
+	// paths of the Get requests sent during each step,
+	// if empty Capabilities requests are sent.
+	GetPaths []string `mapstructure:"get-paths,omitempty" json:"get-paths,omitempty" yaml:"get-paths,omitempty"`
+	// interval between two unary RPCs.
+	GetInterval time.Duration `mapstructure:"get-interval,omitempty" json:"get-interval,omitempty" yaml:"get-interval,omitempty"`
+	// the benchmark stops at the first step breaching one of the thresholds.
+	Thresholds *BenchThresholds `mapstructure:"thresholds,omitempty" json:"thresholds,omitempty" yaml:"thresholds,omitempty"`
+}
+
+// BenchStep is a set of identical subscriptions run concurrently for a duration.
+type BenchStep struct {
+	Name           string        `mapstructure:"name,omitempty" json:"name,omitempty" yaml:"name,omitempty"`
+	Duration       time.Duration `mapstructure:"duration,omitempty" json:"duration,omitempty" yaml:"duration,omitempty"`
+	Subscriptions  int           `mapstructure:"subscriptions,omitempty" json:"subscriptions,omitempty" yaml:"subscriptions,omitempty"`
+	SampleInterval time.Duration `mapstructure:"sample-interval,omitempty" json:"sample-interval,omitempty" yaml:"sample-interval,omitempty"`
+	Paths          []string      `mapstructure:"paths,omitempty" json:"paths,omitempty" yaml:"paths,omitempty"`
+}
+
+// BenchRamp generates a step for each combination of path set,
+// sample interval and number of subscriptions, in that order.
+type BenchRamp struct {
+	// duration of each generated step.
+	Duration        time.Duration   `mapstructure:"duration,omitempty" json:"duration,omitempty" yaml:"duration,omitempty"`
+	Subscriptions   []int           `mapstructure:"subscriptions,omitempty" json:"subscriptions,omitempty" yaml:"subscriptions,omitempty"`
+	SampleIntervals []time.Duration `mapstructure:"sample-intervals,omitempty" json:"sample-intervals,omitempty" yaml:"sample-intervals,omitempty"`
+	PathSets        []*BenchPathSet `mapstructure:"path-sets,omitempty" json:"path-sets,omitempty" yaml:"path-sets,omitempty"`
+}
+
+type BenchPathSet struct {
+	Name  string   `mapstructure:"name,omitempty" json:"name,omitempty" yaml:"name,omitempty"`
+	Paths []string `mapstructure:"paths,omitempty" json:"paths,omitempty" yaml:"paths,omitempty"`
+}
+
+// BenchThresholds are checked at the end of each step, the unset ones are ignored.
+type BenchThresholds struct {
+	// maximum time for a subscription to send its sync response.
+	MaxSyncTime time.Duration `mapstructure:"max-sync-time,omitempty" json:"max-sync-time,omitempty" yaml:"max-sync-time,omitempty"`
+	// minimum ratio of received to requested updates.
+	MinUpdateRatio float64 `mapstructure:"min-update-ratio,omitempty" json:"min-update-ratio,omitempty" yaml:"min-update-ratio,omitempty"`
+	// maximum 99th percentile of the delay between a sample timestamp and its reception.
+	MaxTimestampLag time.Duration `mapstructure:"max-timestamp-lag,omitempty" json:"max-timestamp-lag,omitempty" yaml:"max-timestamp-lag,omitempty"`
+	// maximum number of intervals with missing samples, all subscriptions included.
+	MaxDroppedIntervals *int `mapstructure:"max-dropped-intervals,omitempty" json:"max-dropped-intervals,omitempty" yaml:"max-dropped-intervals,omitempty"`
+	// maximum 99th percentile of the unary RPCs latency.
+	MaxRPCLatency time.Duration `mapstructure:"max-rpc-latency,omitempty" json:"max-rpc-latency,omitempty" yaml:"max-rpc-latency,omitempty"`
+	// maximum number of errors, the step is interrupted as soon as it is exceeded.
+	MaxErrors *int `mapstructure:"max-errors,omitempty" json:"max-errors,omitempty" yaml:"max-errors,omitempty"`
+}
+
+// GetBenchProfile reads the bench profile file if set,
+// otherwise it builds a single step profile from the bench flags.
+func (c *Config) GetBenchProfile() (*BenchProfile, error) {
+	bp := new(BenchProfile)
+	if c.LocalFlags.BenchProfile != "" {
+		b, err := readFile(c.LocalFlags.BenchProfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bench profile: %w", err)
+		}
+		m := make(map[string]any)
+		err = json.Unmarshal(b, &m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bench profile: %w", err)
+		}
+		err = outputs.DecodeConfig(m, bp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode bench profile: %w", err)
+		}
+	} else {
+		bp.Steps = []*BenchStep{{
+			Duration:       c.LocalFlags.BenchDuration,
+			Subscriptions:  c.LocalFlags.BenchSubscriptions,
+			SampleInterval: c.LocalFlags.BenchSampleInterval,
+			Paths:          c.LocalFlags.BenchPath,
+		}}
+		bp.GetPaths = c.LocalFlags.BenchGetPath
+		bp.GetInterval = c.LocalFlags.BenchGetInterval
+	}
+	err := bp.SetDefaults()
+	if err != nil {
+		return nil, fmt.Errorf("bench profile: %w", err)
+	}
+	if c.Debug {
+		c.logger.Printf("bench profile: %+v", bp)
+	}
+	return bp, nil
+}
+
+// SetDefaults expands the ramp into steps, validates them and sets their unset fields to their defaults.
+func (bp *BenchProfile) SetDefaults() error {
+	if bp.Ramp != nil {
+		bp.Steps = append(bp.Steps, bp.Ramp.steps()...)
+		bp.Ramp = nil
+	}
+	if len(bp.Steps) == 0 {
+		return errors.New("no steps defined")
+	}
+	for i, s := range bp.Steps {
+		if s.Name == "" {
+			s.Name = fmt.Sprintf("step-%d", i+1)
+		}
+		if len(s.Paths) == 0 {
+			s.Paths = bp.Paths
+		}
+		if len(s.Paths) == 0 {
+			return fmt.Errorf("step %q: no paths defined", s.Name)
+		}
+		if s.Duration <= 0 {
+			s.Duration = defaultBenchStepDuration
+		}
+		if s.Subscriptions <= 0 {
+			s.Subscriptions = defaultBenchSubscriptions
+		}
+		if s.SampleInterval <= 0 {
+			s.SampleInterval = defaultBenchSampleInterval
+		}
+	}
+	if bp.GetInterval <= 0 {
+		bp.GetInterval = defaultBenchGetInterval
+	}
+	if t := bp.Thresholds; t != nil {
+		if t.MinUpdateRatio < 0 {
+			return errors.New("min-update-ratio must be positive")
+		}
+		if t.MaxDroppedIntervals != nil && *t.MaxDroppedIntervals < 0 {
+			return errors.New("max-dropped-intervals must be positive")
+		}
+		if t.MaxErrors != nil && *t.MaxErrors < 0 {
+			return errors.New("max-errors must be positive")
+		}
+	}
+	return nil
+}
+
+func (r *BenchRamp) steps() []*BenchStep {
+	subs := r.Subscriptions
+	if len(subs) == 0 {
+		subs = []int{defaultBenchSubscriptions}
+	}
+	intervals := r.SampleIntervals
+	if len(intervals) == 0 {
+		intervals = []time.Duration{defaultBenchSampleInterval}
+	}
+	pathSets := r.PathSets
+	if len(pathSets) == 0 {
+		// the steps use the profile paths
+		pathSets = []*BenchPathSet{{}}
+	}
+	steps := make([]*BenchStep, 0, len(pathSets)*len(intervals)*len(subs))
+	for i, ps := range pathSets {
+		psName := ps.Name
+		if psName == "" {
+			psName = fmt.Sprintf("paths-%d", i+1)
+		}
+		for _, si := range intervals {
+			for _, n := range subs {
+				steps = append(steps, &BenchStep{
+					Name:           fmt.Sprintf("%s/%s/%d", psName, si, n),
+					Duration:       r.Duration,
+					Subscriptions:  n,
+					SampleInterval: si,
+					Paths:          ps.Paths,
+				})
+			}
+		}
+	}
+	return steps
+}
+
+// CreateBenchSubscribeRequest creates the STREAM/SAMPLE subscribe request of a bench step.
+func (c *Config) CreateBenchSubscribeRequest(bp *BenchProfile, step *BenchStep, tc *types.TargetConfig) (*gnmi.SubscribeRequest, error) {
+	sc := &types.SubscriptionConfig{
+		Name:           step.Name,
+		Paths:          step.Paths,
+		Mode:           "stream",
+		StreamMode:     "sample",
+		SampleInterval: &step.SampleInterval,
+	}
+	if bp.Encoding != "" {
+		sc.Encoding = &bp.Encoding
+	}
+	return c.CreateSubscribeRequest(sc, tc)
+}
+
+// CreateBenchGetRequest creates the Get request sent during the bench steps,
+// it returns nil if the profile does not define Get paths.
+func (c *Config) CreateBenchGetRequest(bp *BenchProfile, tc *types.TargetConfig) (*gnmi.GetRequest, error) {
+	if len(bp.GetPaths) == 0 {
+		return nil, nil
+	}
+	enc := c.Encoding
+	switch {
+	case bp.Encoding != "":
+		enc = bp.Encoding
+	case tc.Encoding != nil:
+		enc = *tc.Encoding
+	}
+	gnmiOpts := make([]api.GNMIOption, 0, 1+len(bp.GetPaths))
+	gnmiOpts = append(gnmiOpts, api.Encoding(enc))
+	for _, p := range bp.GetPaths {
+		gnmiOpts = append(gnmiOpts, api.Path(strings.TrimSpace(p)))
+	}
+	return api.NewGetRequest(gnmiOpts...)
+}
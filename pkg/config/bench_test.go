@@ -0,0 +1,97 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var benchProfileYAML = []byte(`
+paths:
+  - /interfaces/interface/state/counters
+steps:
+  - name: warmup
+    duration: 30s
+get-paths:
+  - /system/state
+thresholds:
+  max-sync-time: 10s
+  max-dropped-intervals: 0
+ramp:
+  duration: 2m
+  subscriptions: [1, 10]
+  sample-intervals: [10s, 1s]
+  path-sets:
+    - name: counters
+      paths:
+        - /interfaces/interface/state/counters
+`)
+
+func TestGetBenchProfile(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "profile.yaml")
+	if err := os.WriteFile(f, benchProfileYAML, 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := New()
+	c.LocalFlags.BenchProfile = f
+	bp, err := c.GetBenchProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(bp.Steps))
+	for _, s := range bp.Steps {
+		names = append(names, s.Name)
+	}
+	want := []string{
+		"warmup",
+		"counters/10s/1", "counters/10s/10",
+		"counters/1s/1", "counters/1s/10",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("unexpected steps: %v", names)
+	}
+	warmup := bp.Steps[0]
+	if warmup.Duration != 30*time.Second || warmup.Subscriptions != 1 ||
+		warmup.SampleInterval != defaultBenchSampleInterval || len(warmup.Paths) != 1 {
+		t.Errorf("unexpected step defaults: %+v", warmup)
+	}
+	last := bp.Steps[4]
+	if last.Duration != 2*time.Minute || last.Subscriptions != 10 || last.SampleInterval != time.Second {
+		t.Errorf("unexpected ramp step: %+v", last)
+	}
+	if bp.GetInterval != defaultBenchGetInterval {
+		t.Errorf("unexpected get-interval: %s", bp.GetInterval)
+	}
+	th := bp.Thresholds
+	if th == nil || th.MaxSyncTime != 10*time.Second || th.MaxDroppedIntervals == nil ||
+		*th.MaxDroppedIntervals != 0 || th.MaxErrors != nil {
+		t.Errorf("unexpected thresholds: %+v", th)
+	}
+}
+
+func TestGetBenchProfileFlags(t *testing.T) {
+	c := New()
+	c.LocalFlags.BenchPath = []string{"/interfaces"}
+	c.LocalFlags.BenchSubscriptions = 5
+	bp, err := c.GetBenchProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bp.Steps) != 1 || bp.Steps[0].Subscriptions != 5 || bp.Steps[0].Duration != defaultBenchStepDuration {
+		t.Errorf("unexpected profile: %+v", bp.Steps)
+	}
+	c.LocalFlags.BenchPath = nil
+	if _, err := c.GetBenchProfile(); err == nil {
+		t.Errorf("expected an error without paths")
+	}
+}
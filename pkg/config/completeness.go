@@ -0,0 +1,158 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	defaultCompletenessReportInterval  = time.Minute
+	defaultCompletenessLearnIntervals  = 3
+	defaultCompletenessExpireIntervals = 10
+	defaultCompletenessEventName       = "completeness"
+)
+
+// Completeness configures the tracking of the telemetry completeness:
+// the number of samples received from each target and subscription,
+// compared to the number of samples expected at the subscription interval.
+type Completeness struct {
+	// default policy, applied to all the sample subscriptions.
+	CompletenessPolicy `mapstructure:",squash" json:",inline" yaml:",inline"`
+	// interval at which the completeness events are written and the ratio metrics updated.
+	ReportInterval time.Duration `mapstructure:"report-interval,omitempty" json:"report-interval,omitempty" yaml:"report-interval,omitempty"`
+	// outputs the completeness events are written to.
+	Outputs []string `mapstructure:"outputs,omitempty" json:"outputs,omitempty" yaml:"outputs,omitempty"`
+	// name of the completeness events.
+	EventName string `mapstructure:"event-name,omitempty" json:"event-name,omitempty" yaml:"event-name,omitempty"`
+	// per subscription policies, the unset fields are inherited from the default policy.
+	Subscriptions map[string]*CompletenessPolicy `mapstructure:"subscriptions,omitempty" json:"subscriptions,omitempty" yaml:"subscriptions,omitempty"`
+}
+
+// CompletenessPolicy defines the samples expected from a subscription.
+type CompletenessPolicy struct {
+	// do not track the subscription.
+	Disable bool `mapstructure:"disable,omitempty" json:"disable,omitempty" yaml:"disable,omitempty"`
+	// interval at which each series is expected to be sampled,
+	// defaults to the subscription sample-interval.
+	Interval time.Duration `mapstructure:"interval,omitempty" json:"interval,omitempty" yaml:"interval,omitempty"`
+	// number of series expected per target,
+	// if not set, the series are learned during the first learn-intervals.
+	ExpectedSeries int `mapstructure:"expected-series,omitempty" json:"expected-series,omitempty" yaml:"expected-series,omitempty"`
+	// number of intervals the expected series are learned from.
+	LearnIntervals int `mapstructure:"learn-intervals,omitempty" json:"learn-intervals,omitempty" yaml:"learn-intervals,omitempty"`
+	// number of intervals after which a series no longer received
+	// stops being expected, while other series are still received.
+	ExpireIntervals int `mapstructure:"expire-intervals,omitempty" json:"expire-intervals,omitempty" yaml:"expire-intervals,omitempty"`
+	// maximum delay between a sample timestamp and its reception,
+	// the samples received later are counted as late. Defaults to the interval.
+	MaxLag time.Duration `mapstructure:"max-lag,omitempty" json:"max-lag,omitempty" yaml:"max-lag,omitempty"`
+}
+
+// GetCompleteness reads the telemetry completeness configuration.
+// It returns nil if completeness tracking is not configured.
+func (c *Config) GetCompleteness() (*Completeness, error) {
+	if !c.FileConfig.IsSet("completeness") {
+		return nil, nil
+	}
+	ccfg := new(Completeness)
+	err := outputs.DecodeConfig(convert(c.FileConfig.Get("completeness")), ccfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode completeness config: %w", err)
+	}
+	err = ccfg.SetDefaults()
+	if err != nil {
+		return nil, fmt.Errorf("completeness: %w", err)
+	}
+	c.Completeness = ccfg
+	if c.Debug {
+		c.logger.Printf("completeness: %+v", c.Completeness)
+	}
+	return c.Completeness, nil
+}
+
+// SetDefaults validates the completeness configuration and sets the unset fields to their defaults.
+func (c *Completeness) SetDefaults() error {
+	if c.ReportInterval <= 0 {
+		c.ReportInterval = defaultCompletenessReportInterval
+	}
+	if c.EventName == "" {
+		c.EventName = defaultCompletenessEventName
+	}
+	if c.LearnIntervals <= 0 {
+		c.LearnIntervals = defaultCompletenessLearnIntervals
+	}
+	if c.ExpireIntervals <= 0 {
+		c.ExpireIntervals = defaultCompletenessExpireIntervals
+	}
+	err := c.CompletenessPolicy.validate()
+	if err != nil {
+		return err
+	}
+	for n, p := range c.Subscriptions {
+		if p == nil {
+			continue
+		}
+		err = p.validate()
+		if err != nil {
+			return fmt.Errorf("subscription %q: %w", n, err)
+		}
+	}
+	return nil
+}
+
+func (p *CompletenessPolicy) validate() error {
+	if p.Interval < 0 {
+		return fmt.Errorf("negative interval %s", p.Interval)
+	}
+	if p.ExpectedSeries < 0 {
+		return errors.New("expected-series must be positive")
+	}
+	if p.LearnIntervals < 0 {
+		return errors.New("learn-intervals must be positive")
+	}
+	if p.ExpireIntervals < 0 {
+		return errors.New("expire-intervals must be positive")
+	}
+	if p.MaxLag < 0 {
+		return fmt.Errorf("negative max-lag %s", p.MaxLag)
+	}
+	return nil
+}
+
+// Policy returns the policy of the subscription called name,
+// the fields it does not set are inherited from the default policy.
+func (c *Completeness) Policy(name string) *CompletenessPolicy {
+	p := c.CompletenessPolicy
+	sp, ok := c.Subscriptions[name]
+	if !ok || sp == nil {
+		return &p
+	}
+	p.Disable = sp.Disable
+	if sp.Interval > 0 {
+		p.Interval = sp.Interval
+	}
+	if sp.ExpectedSeries > 0 {
+		p.ExpectedSeries = sp.ExpectedSeries
+	}
+	if sp.LearnIntervals > 0 {
+		p.LearnIntervals = sp.LearnIntervals
+	}
+	if sp.ExpireIntervals > 0 {
+		p.ExpireIntervals = sp.ExpireIntervals
+	}
+	if sp.MaxLag > 0 {
+		p.MaxLag = sp.MaxLag
+	}
+	return &p
+}
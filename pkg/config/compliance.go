@@ -0,0 +1,34 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/openconfig/gnmic/pkg/compliance"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+// GetCompliance reads the compliance rules configuration.
+// It returns nil if no compliance rules are configured.
+func (c *Config) GetCompliance() (*compliance.Config, error) {
+	if !c.FileConfig.IsSet("compliance") {
+		return nil, nil
+	}
+	ccfg := new(compliance.Config)
+	err := outputs.DecodeConfig(convert(c.FileConfig.Get("compliance")), ccfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode compliance config: %w", err)
+	}
+	c.Compliance = ccfg
+	if c.Debug {
+		c.logger.Printf("compliance: %+v", c.Compliance)
+	}
+	return c.Compliance, nil
+}
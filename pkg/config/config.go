@@ -35,7 +35,11 @@ import (
 	"github.com/openconfig/gnmic/pkg/api"
 	"github.com/openconfig/gnmic/pkg/api/types"
 	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/compliance"
+	"github.com/openconfig/gnmic/pkg/extensions"
+	"github.com/openconfig/gnmic/pkg/facts"
 	gfile "github.com/openconfig/gnmic/pkg/file"
+	"github.com/openconfig/gnmic/pkg/formatters"
 )
 
 const (
@@ -65,6 +69,22 @@ type Config struct {
 	Loader        map[string]interface{}               `mapstructure:"loader,omitempty" json:"loader,omitempty" yaml:"loader,omitempty"`
 	Actions       map[string]map[string]interface{}    `mapstructure:"actions,omitempty" json:"actions,omitempty" yaml:"actions,omitempty"`
 	TunnelServer  *tunnelServer                        `mapstructure:"tunnel-server,omitempty" json:"tunnel-server,omitempty" yaml:"tunnel-server,omitempty"`
+	// processors tracing
+	ProcessorsTrace *formatters.TraceConfig `mapstructure:"processors-trace,omitempty" json:"processors-trace,omitempty" yaml:"processors-trace,omitempty"`
+	// processors panic isolation, time budgets and circuit breakers
+	ProcessorsGuard *formatters.GuardConfig `mapstructure:"processors-guard,omitempty" json:"processors-guard,omitempty" yaml:"processors-guard,omitempty"`
+	// targets facts gathering
+	Facts *facts.Config `mapstructure:"facts,omitempty" json:"facts,omitempty" yaml:"facts,omitempty"`
+	// telemetry catalog
+	Catalog *formatters.CatalogConfig `mapstructure:"catalog,omitempty" json:"catalog,omitempty" yaml:"catalog,omitempty"`
+	// staged application of subscription changes
+	SubscriptionRollout *SubscriptionRollout `mapstructure:"subscription-rollout,omitempty" json:"subscription-rollout,omitempty" yaml:"subscription-rollout,omitempty"`
+	// registered gNMI extensions declarations
+	RegisteredExtensions map[string]*extensions.Config `mapstructure:"registered-extensions,omitempty" json:"registered-extensions,omitempty" yaml:"registered-extensions,omitempty"`
+	// telemetry completeness tracking
+	Completeness *Completeness `mapstructure:"completeness,omitempty" json:"completeness,omitempty" yaml:"completeness,omitempty"`
+	// config compliance rules
+	Compliance *compliance.Config `mapstructure:"compliance,omitempty" json:"compliance,omitempty" yaml:"compliance,omitempty"`
 	//
 	logger             *log.Logger
 	setRequestTemplate []*template.Template
@@ -119,6 +139,8 @@ type GlobalFlags struct {
 	UseTunnelServer  bool          `mapstructure:"use-tunnel-server,omitempty" json:"use-tunnel-server,omitempty" yaml:"use-tunnel-server,omitempty"`
 	AuthScheme       string        `mapstructure:"auth-scheme,omitempty" json:"auth-scheme,omitempty" yaml:"auth-scheme,omitempty"`
 	CalculateLatency bool          `mapstructure:"calculate-latency,omitempty" json:"calculate-latency,omitempty" yaml:"calculate-latency,omitempty"`
+	Select           []string      `mapstructure:"select,omitempty" json:"select,omitempty" yaml:"select,omitempty"`
+	Concurrency      int           `mapstructure:"concurrency,omitempty" json:"concurrency,omitempty" yaml:"concurrency,omitempty"`
 
 	Metadata             map[string]string `mapstructure:"metadata,omitempty" json:"metadata,omitempty" yaml:"metadata,omitempty"`
 	PluginProcessorsPath string            `mapstructure:"plugin-processors-path,omitempty" yaml:"plugin-processors-path,omitempty" json:"plugin-processors-path,omitempty"`
@@ -137,6 +159,7 @@ type LocalFlags struct {
 	GetProcessor  []string `mapstructure:"get-processor,omitempty" json:"get-processor,omitempty" yaml:"get-processor,omitempty"`
 	GetDepth      uint32   `mapstructure:"get-depth,omitempty" yaml:"get-depth,omitempty" json:"get-depth,omitempty"`
 	GetDryRun     bool     `mapstructure:"get-dry-run,omitempty" json:"get-dry-run,omitempty" yaml:"get-dry-run,omitempty"`
+	GetExtension  []string `mapstructure:"get-extension,omitempty" json:"get-extension,omitempty" yaml:"get-extension,omitempty"`
 	// Set
 	SetPrefix                 string        `mapstructure:"set-prefix,omitempty" json:"set-prefix,omitempty" yaml:"set-prefix,omitempty"`
 	SetDelete                 []string      `mapstructure:"set-delete,omitempty" json:"set-delete,omitempty" yaml:"set-delete,omitempty"`
@@ -168,6 +191,7 @@ type LocalFlags struct {
 	SetCommitRollbackDuration time.Duration `mapstructure:"set-commit-rollback-duration,omitempty" yaml:"set-commit-rollback-duration,omitempty" json:"set-commit-rollback-duration,omitempty"`
 	SetCommitCancel           bool          `mapstructure:"set-commit-cancel,omitempty" yaml:"set-commit-cancel,omitempty" json:"set-commit-cancel,omitempty"`
 	SetCommitConfirm          bool          `mapstructure:"set-commit-confirm,omitempty" yaml:"set-commit-confirm,omitempty" json:"set-commit-confirm,omitempty"`
+	SetExtension              []string      `mapstructure:"set-extension,omitempty" yaml:"set-extension,omitempty" json:"set-extension,omitempty"`
 	// Sub
 	SubscribePrefix            string        `mapstructure:"subscribe-prefix,omitempty" json:"subscribe-prefix,omitempty" yaml:"subscribe-prefix,omitempty"`
 	SubscribePath              []string      `mapstructure:"subscribe-path,omitempty" json:"subscribe-path,omitempty" yaml:"subscribe-path,omitempty"`
@@ -191,6 +215,10 @@ type LocalFlags struct {
 	SubscribeHistoryStart      string        `mapstructure:"subscribe-history-start,omitempty" json:"subscribe-history-start,omitempty" yaml:"subscribe-history-start,omitempty"`
 	SubscribeHistoryEnd        string        `mapstructure:"subscribe-history-end,omitempty" json:"subscribe-history-end,omitempty" yaml:"subscribe-history-end,omitempty"`
 	SubscribeDepth             uint32        `mapstructure:"subscribe-depth,omitempty" yaml:"subscribe-depth,omitempty" json:"subscribe-depth,omitempty"`
+	SubscribeEstimate          bool          `mapstructure:"subscribe-estimate,omitempty" json:"subscribe-estimate,omitempty" yaml:"subscribe-estimate,omitempty"`
+	SubscribeEstimateSample    int           `mapstructure:"subscribe-estimate-sample,omitempty" json:"subscribe-estimate-sample,omitempty" yaml:"subscribe-estimate-sample,omitempty"`
+	SubscribeEstimateTop       int           `mapstructure:"subscribe-estimate-top,omitempty" json:"subscribe-estimate-top,omitempty" yaml:"subscribe-estimate-top,omitempty"`
+	SubscribeExtension         []string      `mapstructure:"subscribe-extension,omitempty" json:"subscribe-extension,omitempty" yaml:"subscribe-extension,omitempty"`
 	// Path
 	PathPathType   string `mapstructure:"path-path-type,omitempty" json:"path-path-type,omitempty" yaml:"path-path-type,omitempty"`
 	PathWithDescr  bool   `mapstructure:"path-descr,omitempty" json:"path-descr,omitempty" yaml:"path-descr,omitempty"`
@@ -263,12 +291,32 @@ type LocalFlags struct {
 	DiffSetToNotifsResponse string   `mapstructure:"diff-set-to-notifs-response,omitempty" json:"diff-set-to-notifs-response,omitempty" yaml:"diff-set-to-notifs-response,omitempty"`
 	DiffSetToNotifsFull     bool     `mapstructure:"diff-set-to-notifs-full,omitempty" json:"diff-set-to-notifs-full,omitempty" yaml:"diff-set-to-notifs-full,omitempty"`
 	//
+	YangDiffOld        []string `mapstructure:"yang-diff-old,omitempty" json:"yang-diff-old,omitempty" yaml:"yang-diff-old,omitempty"`
+	YangDiffNew        []string `mapstructure:"yang-diff-new,omitempty" json:"yang-diff-new,omitempty" yaml:"yang-diff-new,omitempty"`
+	YangDiffRepo       string   `mapstructure:"yang-diff-repo,omitempty" json:"yang-diff-repo,omitempty" yaml:"yang-diff-repo,omitempty"`
+	YangDiffOldVersion string   `mapstructure:"yang-diff-old-version,omitempty" json:"yang-diff-old-version,omitempty" yaml:"yang-diff-old-version,omitempty"`
+	YangDiffNewVersion string   `mapstructure:"yang-diff-new-version,omitempty" json:"yang-diff-new-version,omitempty" yaml:"yang-diff-new-version,omitempty"`
+	YangDiffCheck      bool     `mapstructure:"yang-diff-check,omitempty" json:"yang-diff-check,omitempty" yaml:"yang-diff-check,omitempty"`
+	//
 	TunnelServerSubscribe bool `mapstructure:"tunnel-server-subscribe,omitempty" yaml:"tunnel-server-subscribe,omitempty" json:"tunnel-server-subscribe,omitempty"`
 	// Processor
 	ProcessorInput          string   `mapstructure:"processor-input,omitempty" yaml:"processor-input,omitempty" json:"processor-input,omitempty"`
 	ProcessorInputDelimiter string   `mapstructure:"processor-input-delimiter,omitempty" yaml:"processor-input-delimiter,omitempty" json:"processor-input-delimiter,omitempty"`
 	ProcessorName           []string `mapstructure:"processor-name,omitempty" yaml:"processor-name,omitempty" json:"processor-name,omitempty"`
 	ProcessorOutput         string   `mapstructure:"processor-output,omitempty" yaml:"processor-output,omitempty" json:"processor-output,omitempty"`
+	ProcessorTrace          bool     `mapstructure:"processor-trace,omitempty" yaml:"processor-trace,omitempty" json:"processor-trace,omitempty"`
+	// Bench
+	BenchProfile        string        `mapstructure:"bench-profile,omitempty" yaml:"bench-profile,omitempty" json:"bench-profile,omitempty"`
+	BenchPath           []string      `mapstructure:"bench-path,omitempty" yaml:"bench-path,omitempty" json:"bench-path,omitempty"`
+	BenchSubscriptions  int           `mapstructure:"bench-subscriptions,omitempty" yaml:"bench-subscriptions,omitempty" json:"bench-subscriptions,omitempty"`
+	BenchSampleInterval time.Duration `mapstructure:"bench-sample-interval,omitempty" yaml:"bench-sample-interval,omitempty" json:"bench-sample-interval,omitempty"`
+	BenchDuration       time.Duration `mapstructure:"bench-duration,omitempty" yaml:"bench-duration,omitempty" json:"bench-duration,omitempty"`
+	BenchGetPath        []string      `mapstructure:"bench-get-path,omitempty" yaml:"bench-get-path,omitempty" json:"bench-get-path,omitempty"`
+	BenchGetInterval    time.Duration `mapstructure:"bench-get-interval,omitempty" yaml:"bench-get-interval,omitempty" json:"bench-get-interval,omitempty"`
+	// Compliance
+	ComplianceRule         []string `mapstructure:"compliance-rule,omitempty" yaml:"compliance-rule,omitempty" json:"compliance-rule,omitempty"`
+	ComplianceReportFormat string   `mapstructure:"compliance-report-format,omitempty" yaml:"compliance-report-format,omitempty" json:"compliance-report-format,omitempty"`
+	ComplianceReportFile   string   `mapstructure:"compliance-report-file,omitempty" yaml:"compliance-report-file,omitempty" json:"compliance-report-file,omitempty"`
 }
 
 func New() *Config {
@@ -287,6 +335,14 @@ func New() *Config {
 		nil,
 		nil,
 		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
 		log.New(io.Discard, configLogPrefix, utils.DefaultLoggingFlags),
 		nil,
 		make(map[string]interface{}),
@@ -464,6 +520,13 @@ func (c *Config) CreateGetRequest(tc *types.TargetConfig) (*gnmi.GetRequest, err
 	if c.LocalFlags.GetDepth > 0 {
 		gnmiOpts = append(gnmiOpts, api.Extension_Depth(c.LocalFlags.GetDepth))
 	}
+	exts, err := registeredExtensions(c.LocalFlags.GetExtension)
+	if err != nil {
+		return nil, err
+	}
+	for _, ext := range exts {
+		gnmiOpts = append(gnmiOpts, api.Extension(ext))
+	}
 	return api.NewGetRequest(gnmiOpts...)
 }
 
@@ -602,6 +665,21 @@ func (c *Config) execValueTemplate(tplString string, input interface{}) (string,
 }
 
 func (c *Config) CreateSetRequest(targetName string) ([]*gnmi.SetRequest, error) {
+	reqs, err := c.createSetRequest(targetName)
+	if err != nil {
+		return nil, err
+	}
+	exts, err := registeredExtensions(c.LocalFlags.SetExtension)
+	if err != nil {
+		return nil, err
+	}
+	for _, req := range reqs {
+		req.Extension = append(req.Extension, exts...)
+	}
+	return reqs, nil
+}
+
+func (c *Config) createSetRequest(targetName string) ([]*gnmi.SetRequest, error) {
 	if len(c.SetRequestProtoFile) > 0 {
 		return c.CreateSetRequestFromProtoFile()
 	}
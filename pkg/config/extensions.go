@@ -0,0 +1,58 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/openconfig/gnmi/proto/gnmi_ext"
+
+	"github.com/openconfig/gnmic/pkg/extensions"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+// GetRegisteredExtensions reads the registered extensions declarations.
+func (c *Config) GetRegisteredExtensions() (map[string]*extensions.Config, error) {
+	if !c.FileConfig.IsSet("registered-extensions") {
+		return nil, nil
+	}
+	cfgs := make(map[string]*extensions.Config)
+	for name, ec := range c.FileConfig.GetStringMap("registered-extensions") {
+		ecfg := new(extensions.Config)
+		err := outputs.DecodeConfig(convert(ec), ecfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode registered extension %q config: %w", name, err)
+		}
+		for i := range ecfg.ProtoFiles {
+			ecfg.ProtoFiles[i] = os.ExpandEnv(ecfg.ProtoFiles[i])
+		}
+		for i := range ecfg.ProtoDirs {
+			ecfg.ProtoDirs[i] = os.ExpandEnv(ecfg.ProtoDirs[i])
+		}
+		cfgs[name] = ecfg
+	}
+	c.RegisteredExtensions = cfgs
+	if c.Debug {
+		c.logger.Printf("registered-extensions: %+v", c.RegisteredExtensions)
+	}
+	return c.RegisteredExtensions, nil
+}
+
+// registeredExtensions encodes the `name=value` registered extensions set with the --extension flag.
+func registeredExtensions(ss []string) ([]*gnmi_ext.Extension, error) {
+	if len(ss) == 0 {
+		return nil, nil
+	}
+	values, err := extensions.ParseValues(ss)
+	if err != nil {
+		return nil, err
+	}
+	return extensions.GetRegistry().EncodeValues(values)
+}
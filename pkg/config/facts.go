@@ -0,0 +1,34 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/openconfig/gnmic/pkg/facts"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+// GetFacts reads the targets facts gathering configuration.
+// It returns nil if facts gathering is not configured.
+func (c *Config) GetFacts() (*facts.Config, error) {
+	if !c.FileConfig.IsSet("facts") {
+		return nil, nil
+	}
+	fcfg := new(facts.Config)
+	err := outputs.DecodeConfig(convert(c.FileConfig.Get("facts")), fcfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode facts config: %w", err)
+	}
+	c.Facts = fcfg
+	if c.Debug {
+		c.logger.Printf("facts: %+v", c.Facts)
+	}
+	return c.Facts, nil
+}
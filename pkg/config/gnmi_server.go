@@ -16,6 +16,7 @@ import (
 
 	"github.com/openconfig/gnmic/pkg/api/types"
 	"github.com/openconfig/gnmic/pkg/cache"
+	"github.com/openconfig/gnmic/pkg/formatters"
 	"google.golang.org/grpc/keepalive"
 )
 
@@ -53,6 +54,9 @@ type gnmiServer struct {
 	ServiceRegistration *serviceRegistration `mapstructure:"service-registration,omitempty" json:"service-registration,omitempty"`
 	// cache config
 	Cache *cache.Config `mapstructure:"cache,omitempty" json:"cache,omitempty"`
+	// event processors applied before caching
+	EventProcessors []string                 `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	EventMapping    *formatters.EventMapping `mapstructure:"event-mapping,omitempty" json:"event-mapping,omitempty"`
 }
 
 type serviceRegistration struct {
@@ -177,6 +181,17 @@ func (c *Config) GetGNMIServer() error {
 		c.GnmiServer.Cache.FetchBatchSize = c.FileConfig.GetInt("gnmi-server/cache/fetch-batch-size")
 		c.GnmiServer.Cache.FetchWaitTime = c.FileConfig.GetDuration("gnmi-server/cache/fetch-wait-time")
 	}
+	c.GnmiServer.EventProcessors = c.FileConfig.GetStringSlice("gnmi-server/event-processors")
+	if c.FileConfig.IsSet("gnmi-server/event-mapping") {
+		c.GnmiServer.EventMapping = new(formatters.EventMapping)
+		err := formatters.DecodeConfig(convert(c.FileConfig.Get("gnmi-server/event-mapping")), c.GnmiServer.EventMapping)
+		if err != nil {
+			return fmt.Errorf("failed to decode gnmi-server event-mapping: %w", err)
+		}
+	}
+	if err := c.GnmiServer.EventMapping.Init(); err != nil {
+		return fmt.Errorf("gnmi-server event-mapping error: %w", err)
+	}
 	return nil
 }
 
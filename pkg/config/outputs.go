@@ -56,6 +56,10 @@ func (c *Config) GetOutputs() (map[string]map[string]any, error) {
 	for n := range c.Outputs {
 		expandMapEnv(c.Outputs[n], expandExcept("msg-template", "target-template"))
 	}
+	err := c.validateOutputGroups()
+	if err != nil {
+		return nil, err
+	}
 	namedOutputs := c.FileConfig.GetStringSlice("subscribe-output")
 	if len(namedOutputs) == 0 {
 		if c.Debug {
@@ -75,12 +79,69 @@ func (c *Config) GetOutputs() (map[string]map[string]any, error) {
 	if len(notFound) > 0 {
 		return nil, fmt.Errorf("named output(s) not found in config file: %v", notFound)
 	}
+	// the members of the selected output groups are selected as well
+	for name := range filteredOutputs {
+		for _, m := range outputGroupMembers(c.Outputs[name]) {
+			filteredOutputs[m] = c.Outputs[m]
+		}
+	}
 	if c.Debug {
 		c.logger.Printf("outputs: %+v", filteredOutputs)
 	}
 	return filteredOutputs, nil
 }
 
+// outputGroupMemberTypes are the output types that report their health,
+// they are the only ones allowed as output group members.
+var outputGroupMemberTypes = map[string]struct{}{
+	"kafka":    {},
+	"influxdb": {},
+}
+
+// validateOutputGroups checks that the output groups members
+// are existing outputs of a type reporting its health.
+func (c *Config) validateOutputGroups() error {
+	for name, cfg := range c.Outputs {
+		if cfg["type"] != "group" {
+			continue
+		}
+		members := outputGroupMembers(cfg)
+		if len(members) == 0 {
+			return fmt.Errorf("output group %q: missing members", name)
+		}
+		for _, m := range members {
+			mcfg, ok := c.Outputs[m]
+			if !ok {
+				return fmt.Errorf("output group %q: unknown member %q", name, m)
+			}
+			if mcfg["type"] == "group" {
+				return fmt.Errorf("output group %q: member %q is an output group", name, m)
+			}
+			if _, ok := outputGroupMemberTypes[fmt.Sprint(mcfg["type"])]; !ok {
+				return fmt.Errorf("output group %q: member %q has type %q, which does not report its health", name, m, mcfg["type"])
+			}
+		}
+	}
+	return nil
+}
+
+func outputGroupMembers(cfg map[string]any) []string {
+	if cfg["type"] != "group" {
+		return nil
+	}
+	switch ms := cfg["members"].(type) {
+	case []string:
+		return ms
+	case []any:
+		members := make([]string, 0, len(ms))
+		for _, m := range ms {
+			members = append(members, fmt.Sprint(m))
+		}
+		return members
+	}
+	return nil
+}
+
 func convert(i interface{}) interface{} {
 	switch x := i.(type) {
 	case map[interface{}]interface{}:
@@ -68,6 +68,37 @@ outputs:
 			},
 		},
 	},
+	"output_group": {
+		in: []byte(`
+outputs:
+  kafka1:
+    type: kafka
+  kafka2:
+    type: kafka
+  kafka-group:
+    type: group
+    policy: failover
+    members:
+      - kafka1
+      - kafka2
+`),
+		out: map[string]map[string]interface{}{
+			"kafka1": {
+				"type":   "kafka",
+				"format": "",
+			},
+			"kafka2": {
+				"type":   "kafka",
+				"format": "",
+			},
+			"kafka-group": {
+				"type":    "group",
+				"format":  "",
+				"policy":  "failover",
+				"members": []interface{}{"kafka1", "kafka2"},
+			},
+		},
+	},
 }
 
 func TestGetOutputs(t *testing.T) {
@@ -102,3 +133,54 @@ func TestGetOutputs(t *testing.T) {
 		})
 	}
 }
+
+func TestGetOutputsGroupErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown_member": `
+outputs:
+  kafka-group:
+    type: group
+    members:
+      - kafka1
+`,
+		"nested_group": `
+outputs:
+  kafka1:
+    type: kafka
+  group1:
+    type: group
+    members:
+      - kafka1
+  group2:
+    type: group
+    members:
+      - group1
+`,
+		"unsupported_member_type": `
+outputs:
+  kafka1:
+    type: kafka
+  file1:
+    type: file
+  group1:
+    type: group
+    members:
+      - kafka1
+      - file1
+`,
+	}
+	for name, in := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := New()
+			cfg.SetLogger()
+			cfg.FileConfig.SetConfigType("yaml")
+			err := cfg.FileConfig.ReadConfig(bytes.NewBufferString(in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := cfg.GetOutputs(); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -41,7 +41,7 @@ func (c *Config) GetEventProcessors() (map[string]map[string]interface{}, error)
 	for n := range c.Processors {
 		expandMapEnv(c.Processors[n], expandExcept(
 			"expression",
-			"condition",
+			"condition", "clear-condition",
 			"value-names", "values",
 			"tag-names", "tags",
 			"old", "new", // strings.replace
@@ -54,6 +54,64 @@ func (c *Config) GetEventProcessors() (map[string]map[string]interface{}, error)
 	return c.Processors, nil
 }
 
+// GetProcessorsTrace reads the processors tracing configuration.
+// It returns nil if tracing is not configured.
+func (c *Config) GetProcessorsTrace() (*formatters.TraceConfig, error) {
+	if !c.FileConfig.IsSet("processors-trace") {
+		return nil, nil
+	}
+	tcfg := new(formatters.TraceConfig)
+	err := formatters.DecodeConfig(convert(c.FileConfig.Get("processors-trace")), tcfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode processors-trace config: %w", err)
+	}
+	c.ProcessorsTrace = tcfg
+	if c.Debug {
+		c.logger.Printf("processors-trace: %+v", c.ProcessorsTrace)
+	}
+	return c.ProcessorsTrace, nil
+}
+
+// GetProcessorsGuard reads the processors guard configuration.
+// It returns nil if the guard is not configured.
+func (c *Config) GetProcessorsGuard() (*formatters.GuardConfig, error) {
+	if !c.FileConfig.IsSet("processors-guard") {
+		return nil, nil
+	}
+	gcfg := new(formatters.GuardConfig)
+	err := formatters.DecodeConfig(convert(c.FileConfig.Get("processors-guard")), gcfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode processors-guard config: %w", err)
+	}
+	err = gcfg.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid processors-guard config: %w", err)
+	}
+	c.ProcessorsGuard = gcfg
+	if c.Debug {
+		c.logger.Printf("processors-guard: %+v", c.ProcessorsGuard)
+	}
+	return c.ProcessorsGuard, nil
+}
+
+// GetCatalog reads the telemetry catalog configuration.
+// It returns nil if the catalog is not configured.
+func (c *Config) GetCatalog() (*formatters.CatalogConfig, error) {
+	if !c.FileConfig.IsSet("catalog") {
+		return nil, nil
+	}
+	ccfg := new(formatters.CatalogConfig)
+	err := formatters.DecodeConfig(convert(c.FileConfig.Get("catalog")), ccfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode catalog config: %w", err)
+	}
+	c.Catalog = ccfg
+	if c.Debug {
+		c.logger.Printf("catalog: %+v", c.Catalog)
+	}
+	return c.Catalog, nil
+}
+
 func (c *Config) validateProcessorConfig(pcfg map[string]interface{}) error {
 	for epType := range pcfg {
 		if !strInlist(epType, formatters.EventProcessorTypes) {
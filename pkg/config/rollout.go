@@ -0,0 +1,124 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	RolloutOnFailureRollback = "rollback"
+	RolloutOnFailurePause    = "pause"
+
+	defaultRolloutCanarySize = 1
+	defaultRolloutBakePeriod = time.Minute
+)
+
+// SubscriptionRollout configures the staged application of subscription changes:
+// a changed subscription is applied to a set of canary targets first, then to
+// the remaining targets in waves. Each stage is observed for a bake period
+// and checked against the thresholds before moving to the next one.
+type SubscriptionRollout struct {
+	// names of the targets the change is applied to first.
+	CanaryTargets []string `mapstructure:"canary-targets,omitempty" json:"canary-targets,omitempty" yaml:"canary-targets,omitempty"`
+	// number of canary targets, used if CanaryTargets is empty.
+	CanarySize int `mapstructure:"canary-size,omitempty" json:"canary-size,omitempty" yaml:"canary-size,omitempty"`
+	// number of targets per wave after the canary stage,
+	// 0 applies the change to all the remaining targets at once.
+	WaveSize int `mapstructure:"wave-size,omitempty" json:"wave-size,omitempty" yaml:"wave-size,omitempty"`
+	// duration each stage is observed for.
+	BakePeriod time.Duration `mapstructure:"bake-period,omitempty" json:"bake-period,omitempty" yaml:"bake-period,omitempty"`
+	// maximum number of subscription errors per target during the bake period.
+	MaxErrors int `mapstructure:"max-errors,omitempty" json:"max-errors,omitempty" yaml:"max-errors,omitempty"`
+	// maximum time for a target to send the sync response after the change.
+	MaxSyncTime time.Duration `mapstructure:"max-sync-time,omitempty" json:"max-sync-time,omitempty" yaml:"max-sync-time,omitempty"`
+	// minimum number of updates per target during the bake period.
+	MinUpdates int `mapstructure:"min-updates,omitempty" json:"min-updates,omitempty" yaml:"min-updates,omitempty"`
+	// maximum number of updates per second per target during the bake period.
+	MaxUpdateRate float64 `mapstructure:"max-update-rate,omitempty" json:"max-update-rate,omitempty" yaml:"max-update-rate,omitempty"`
+	// action taken when a stage fails the thresholds, `rollback` or `pause`.
+	OnFailure string `mapstructure:"on-failure,omitempty" json:"on-failure,omitempty" yaml:"on-failure,omitempty"`
+}
+
+// GetSubscriptionRollout reads the subscription rollout configuration.
+// It returns nil if subscription rollouts are not configured.
+func (c *Config) GetSubscriptionRollout() (*SubscriptionRollout, error) {
+	if !c.FileConfig.IsSet("subscription-rollout") {
+		return nil, nil
+	}
+	rcfg := new(SubscriptionRollout)
+	err := outputs.DecodeConfig(convert(c.FileConfig.Get("subscription-rollout")), rcfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode subscription-rollout config: %w", err)
+	}
+	err = rcfg.SetDefaults()
+	if err != nil {
+		return nil, fmt.Errorf("subscription-rollout: %w", err)
+	}
+	c.SubscriptionRollout = rcfg
+	if c.Debug {
+		c.logger.Printf("subscription-rollout: %+v", c.SubscriptionRollout)
+	}
+	return c.SubscriptionRollout, nil
+}
+
+// SetDefaults validates the rollout configuration and sets the unset fields to their defaults.
+func (r *SubscriptionRollout) SetDefaults() error {
+	if r.CanarySize <= 0 {
+		r.CanarySize = defaultRolloutCanarySize
+	}
+	if r.WaveSize < 0 {
+		r.WaveSize = 0
+	}
+	if r.BakePeriod <= 0 {
+		r.BakePeriod = defaultRolloutBakePeriod
+	}
+	switch r.OnFailure {
+	case "":
+		r.OnFailure = RolloutOnFailureRollback
+	case RolloutOnFailureRollback, RolloutOnFailurePause:
+	default:
+		return fmt.Errorf("unknown on-failure value %q, must be one of %q", r.OnFailure,
+			[]string{RolloutOnFailureRollback, RolloutOnFailurePause})
+	}
+	return nil
+}
+
+// ReadSubscriptionsFromFile decodes the subscriptions defined in the config file,
+// without changing the current subscriptions.
+func (c *Config) ReadSubscriptionsFromFile() (map[string]*types.SubscriptionConfig, error) {
+	subs := make(map[string]*types.SubscriptionConfig)
+	for sn, s := range c.FileConfig.GetStringMap("subscriptions") {
+		sub, err := c.DecodeSubscriptionConfig(sn, s)
+		if err != nil {
+			return nil, err
+		}
+		subs[sn] = sub
+	}
+	return subs, nil
+}
+
+// DecodeSubscriptionConfig decodes the subscription named name from s.
+func (c *Config) DecodeSubscriptionConfig(name string, s any) (*types.SubscriptionConfig, error) {
+	switch s := s.(type) {
+	case map[string]any:
+		return c.decodeSubscriptionConfig(name, s, nil)
+	default:
+		return nil, fmt.Errorf("%w: subscription %q: unexpected type %T", ErrConfig, name, s)
+	}
+}
+
+// ValidateSubscriptionConfig validates sc and sets its unset fields to their defaults.
+func ValidateSubscriptionConfig(sc *types.SubscriptionConfig) error {
+	return validateAndSetDefaults(sc)
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/openconfig/gnmi/proto/gnmi"
 
 	"github.com/openconfig/gnmic/pkg/api"
+	"github.com/openconfig/gnmic/pkg/facts"
 	gfile "github.com/openconfig/gnmic/pkg/file"
 	"github.com/openconfig/gnmic/pkg/gtemplate"
 )
@@ -129,6 +130,7 @@ func (c *Config) CreateSetRequestFromFile(targetName string) ([]*gnmi.SetRequest
 		err := srf.Execute(buf, templateInput{
 			TargetName: targetName,
 			Vars:       c.setRequestVars,
+			Facts:      facts.Lookup(targetName),
 		})
 		if err != nil {
 			return nil, err
@@ -277,6 +279,7 @@ func (c *Config) CreateSetRequestFromFile(targetName string) ([]*gnmi.SetRequest
 type templateInput struct {
 	TargetName string
 	Vars       map[string]interface{}
+	Facts      map[string]string
 }
 
 func (c *Config) CreateSetRequestFromProtoFile() ([]*gnmi.SetRequest, error) {
@@ -24,6 +24,7 @@ import (
 
 	"github.com/openconfig/gnmic/pkg/api"
 	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/extensions"
 )
 
 const (
@@ -113,6 +114,13 @@ func (c *Config) subscriptionConfigFromFlags(cmd *cobra.Command) (map[string]*ty
 		Mode:      c.LocalFlags.SubscribeMode,
 		Depth:     c.LocalFlags.SubscribeDepth,
 	}
+	if len(c.LocalFlags.SubscribeExtension) > 0 {
+		exts, err := extensions.ParseValues(c.LocalFlags.SubscribeExtension)
+		if err != nil {
+			return nil, err
+		}
+		sub.Extensions = exts
+	}
 	// if globalFlagIsSet(cmd, "encoding") {
 	// 	sub.Encoding = &c.Encoding
 	// }
@@ -204,6 +212,13 @@ func (c *Config) setSubscriptionFieldsFromFlags(sub *types.SubscriptionConfig, c
 	if flagIsSet(cmd, "depth") {
 		sub.Depth = c.LocalFlags.SubscribeDepth
 	}
+	if sub.Extensions == nil && flagIsSet(cmd, "extension") {
+		exts, err := extensions.ParseValues(c.LocalFlags.SubscribeExtension)
+		if err != nil {
+			return err
+		}
+		sub.Extensions = exts
+	}
 	if sub.History == nil && flagIsSet(cmd, "history-snapshot") {
 		snapshot, err := time.Parse(time.RFC3339Nano, c.LocalFlags.SubscribeHistorySnapshot)
 		if err != nil {
@@ -360,6 +375,16 @@ func (c *Config) subscriptionOpts(sc *types.SubscriptionConfig, tc *types.Target
 	if sc.Depth > 0 {
 		gnmiOpts = append(gnmiOpts, api.Extension_Depth(sc.Depth))
 	}
+	// registered extensions
+	if len(sc.Extensions) > 0 {
+		exts, err := extensions.GetRegistry().EncodeValues(sc.Extensions)
+		if err != nil {
+			return nil, fmt.Errorf("subscription %q: %v", sc.Name, err)
+		}
+		for _, ext := range exts {
+			gnmiOpts = append(gnmiOpts, api.Extension(ext))
+		}
+	}
 	return gnmiOpts, nil
 }
 
@@ -0,0 +1,263 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package extensions encodes and decodes gNMI registered extensions
+// using the protobuf message definitions declared in the configuration.
+package extensions
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/fullstorydev/grpcurl"
+	"github.com/jhump/protoreflect/desc"
+	flattener "github.com/karimra/go-map-flattener"
+	"github.com/openconfig/gnmi/proto/gnmi_ext"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"gopkg.in/yaml.v2"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+)
+
+// Config declares a registered gNMI extension.
+type Config struct {
+	// the extension ID, as set in the RegisteredExtension id field.
+	ID int32 `mapstructure:"id,omitempty" json:"id,omitempty" yaml:"id,omitempty"`
+	// fully qualified name of the protobuf message carried in the extension.
+	Message string `mapstructure:"message,omitempty" json:"message,omitempty" yaml:"message,omitempty"`
+	// proto files defining the message.
+	ProtoFiles []string `mapstructure:"proto-files,omitempty" json:"proto-files,omitempty" yaml:"proto-files,omitempty"`
+	// directories to look for the proto files and their imports in.
+	ProtoDirs []string `mapstructure:"proto-dirs,omitempty" json:"proto-dirs,omitempty" yaml:"proto-dirs,omitempty"`
+}
+
+// Decoded is a registered extension decoded into its message fields.
+type Decoded struct {
+	ID   int32          `json:"id"`
+	Name string         `json:"name"`
+	Msg  map[string]any `json:"msg,omitempty"`
+}
+
+type extension struct {
+	name string
+	id   gnmi_ext.ExtensionID
+	md   protoreflect.MessageDescriptor
+}
+
+// Registry holds the declared registered extensions, indexed by name and ID.
+type Registry struct {
+	byName map[string]*extension
+	byID   map[gnmi_ext.ExtensionID]*extension
+}
+
+// NewRegistry loads the messages of the extensions declared in cfgs.
+func NewRegistry(cfgs map[string]*Config) (*Registry, error) {
+	r := &Registry{
+		byName: make(map[string]*extension, len(cfgs)),
+		byID:   make(map[gnmi_ext.ExtensionID]*extension, len(cfgs)),
+	}
+	for name, cfg := range cfgs {
+		if cfg == nil {
+			return nil, fmt.Errorf("registered extension %q: missing config", name)
+		}
+		if cfg.ID <= 0 {
+			return nil, fmt.Errorf("registered extension %q: invalid id %d", name, cfg.ID)
+		}
+		if cfg.Message == "" {
+			return nil, fmt.Errorf("registered extension %q: missing message name", name)
+		}
+		if len(cfg.ProtoFiles) == 0 {
+			return nil, fmt.Errorf("registered extension %q: missing proto files", name)
+		}
+		id := gnmi_ext.ExtensionID(cfg.ID)
+		if other, ok := r.byID[id]; ok {
+			return nil, fmt.Errorf("registered extension %q: id %d already used by %q", name, cfg.ID, other.name)
+		}
+		ds, err := grpcurl.DescriptorSourceFromProtoFiles(cfg.ProtoDirs, cfg.ProtoFiles...)
+		if err != nil {
+			return nil, fmt.Errorf("registered extension %q: failed to load proto files: %v", name, err)
+		}
+		d, err := ds.FindSymbol(cfg.Message)
+		if err != nil {
+			return nil, fmt.Errorf("registered extension %q: %v", name, err)
+		}
+		md, ok := d.(*desc.MessageDescriptor)
+		if !ok {
+			return nil, fmt.Errorf("registered extension %q: %q is not a message", name, cfg.Message)
+		}
+		ext := &extension{name: name, id: id, md: md.UnwrapMessage()}
+		r.byName[name] = ext
+		r.byID[id] = ext
+	}
+	return r, nil
+}
+
+// Encode builds the registered extension name from v,
+// a value decoded from JSON or YAML matching the extension message.
+func (r *Registry) Encode(name string, v any) (*gnmi_ext.Extension, error) {
+	if r == nil {
+		return nil, fmt.Errorf("unknown registered extension %q: no registered extensions configured", name)
+	}
+	ext, ok := r.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown registered extension %q", name)
+	}
+	b, err := json.Marshal(utils.Convert(v))
+	if err != nil {
+		return nil, fmt.Errorf("registered extension %q: %v", name, err)
+	}
+	m := dynamicpb.NewMessage(ext.md)
+	err = protojson.Unmarshal(b, m)
+	if err != nil {
+		return nil, fmt.Errorf("registered extension %q: %v", name, err)
+	}
+	msg, err := proto.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("registered extension %q: %v", name, err)
+	}
+	return &gnmi_ext.Extension{
+		Ext: &gnmi_ext.Extension_RegisteredExt{
+			RegisteredExt: &gnmi_ext.RegisteredExtension{
+				Id:  ext.id,
+				Msg: msg,
+			},
+		},
+	}, nil
+}
+
+// EncodeValues builds the registered extensions from a map of extension
+// names to values, sorted by name.
+func (r *Registry) EncodeValues(values map[string]any) ([]*gnmi_ext.Extension, error) {
+	names := make([]string, 0, len(values))
+	for n := range values {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	exts := make([]*gnmi_ext.Extension, 0, len(names))
+	for _, n := range names {
+		ext, err := r.Encode(n, values[n])
+		if err != nil {
+			return nil, err
+		}
+		exts = append(exts, ext)
+	}
+	return exts, nil
+}
+
+// ParseValues parses a list of `name=value` strings, value being JSON or YAML,
+// into a map of extension names to values.
+func ParseValues(ss []string) (map[string]any, error) {
+	values := make(map[string]any, len(ss))
+	for _, s := range ss {
+		name, val, ok := strings.Cut(s, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid extension %q, expected format name=value", s)
+		}
+		var v any
+		err := yaml.Unmarshal([]byte(val), &v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid extension %q value: %v", name, err)
+		}
+		values[name] = utils.Convert(v)
+	}
+	return values, nil
+}
+
+// Decode decodes a registered extension.
+// It returns nil if the extension ID is not declared.
+func (r *Registry) Decode(rext *gnmi_ext.RegisteredExtension) (*Decoded, error) {
+	if r == nil || rext == nil {
+		return nil, nil
+	}
+	ext, ok := r.byID[rext.GetId()]
+	if !ok {
+		return nil, nil
+	}
+	m := dynamicpb.NewMessage(ext.md)
+	err := proto.Unmarshal(rext.GetMsg(), m)
+	if err != nil {
+		return nil, fmt.Errorf("registered extension %q: %v", ext.name, err)
+	}
+	b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("registered extension %q: %v", ext.name, err)
+	}
+	d := &Decoded{ID: int32(ext.id), Name: ext.name}
+	err = json.Unmarshal(b, &d.Msg)
+	if err != nil {
+		return nil, fmt.Errorf("registered extension %q: %v", ext.name, err)
+	}
+	return d, nil
+}
+
+// Format returns the extensions in a JSON friendly form: registered extensions
+// known to the registry are decoded, the others are returned as is.
+func (r *Registry) Format(exts []*gnmi_ext.Extension) []any {
+	if len(exts) == 0 {
+		return nil
+	}
+	res := make([]any, 0, len(exts))
+	for _, ext := range exts {
+		d, err := r.Decode(ext.GetRegisteredExt())
+		if err != nil || d == nil {
+			res = append(res, ext)
+			continue
+		}
+		res = append(res, map[string]*Decoded{"registered-ext": d})
+	}
+	return res
+}
+
+// Tags returns the fields of the decoded registered extensions as tags,
+// named after the extension name and the field path joined with `_`.
+// Extensions that fail to decode are skipped.
+func (r *Registry) Tags(exts []*gnmi_ext.Extension) map[string]string {
+	if r == nil || len(exts) == 0 {
+		return nil
+	}
+	var tags map[string]string
+	for _, ext := range exts {
+		d, err := r.Decode(ext.GetRegisteredExt())
+		if err != nil || d == nil {
+			continue
+		}
+		f := flattener.NewFlattener()
+		f.SetPrefix(d.Name)
+		f.SetSeparator("_")
+		fields, err := f.Flatten(d.Msg)
+		if err != nil {
+			continue
+		}
+		if tags == nil {
+			tags = make(map[string]string, len(fields))
+		}
+		for k, v := range fields {
+			tags[k] = fmt.Sprint(v)
+		}
+	}
+	return tags
+}
+
+var registry atomic.Pointer[Registry]
+
+// SetRegistry sets the Registry used to encode and decode registered extensions.
+func SetRegistry(r *Registry) {
+	registry.Store(r)
+}
+
+// GetRegistry returns the current Registry, nil if no extensions are declared.
+func GetRegistry() *Registry {
+	return registry.Load()
+}
@@ -0,0 +1,177 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package extensions
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/openconfig/gnmi/proto/gnmi_ext"
+)
+
+const testProto = `
+syntax = "proto3";
+
+package vendor.ext;
+
+message Session {
+  string session_id = 1;
+  uint32 priority = 2;
+  Owner owner = 3;
+}
+
+message Owner {
+  string user = 1;
+}
+`
+
+func newTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "session.proto"), []byte(testProto), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRegistry(map[string]*Config{
+		"session": {
+			ID:         1001,
+			Message:    "vendor.ext.Session",
+			ProtoFiles: []string{"session.proto"},
+			ProtoDirs:  []string{dir},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestEncodeDecode(t *testing.T) {
+	r := newTestRegistry(t)
+	values, err := ParseValues([]string{`session={"session_id": "abc", "owner": {"user": "admin"}}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exts, err := r.EncodeValues(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exts) != 1 || exts[0].GetRegisteredExt().GetId() != 1001 {
+		t.Fatalf("unexpected extensions: %v", exts)
+	}
+	d, err := r.Decode(exts[0].GetRegisteredExt())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Decoded{
+		ID:   1001,
+		Name: "session",
+		Msg: map[string]any{
+			"session_id": "abc",
+			"owner":      map[string]any{"user": "admin"},
+		},
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("expected %+v, got %+v", want, d)
+	}
+	tags := r.Tags(exts)
+	wantTags := map[string]string{"session_session_id": "abc", "session_owner_user": "admin"}
+	if !reflect.DeepEqual(tags, wantTags) {
+		t.Errorf("expected tags %v, got %v", wantTags, tags)
+	}
+}
+
+func TestEncodeYAMLValue(t *testing.T) {
+	r := newTestRegistry(t)
+	values, err := ParseValues([]string{"session=priority: 3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exts, err := r.EncodeValues(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := r.Decode(exts[0].GetRegisteredExt())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Msg["priority"] != float64(3) {
+		t.Errorf("unexpected decoded message: %v", d.Msg)
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	r := newTestRegistry(t)
+	if _, err := r.Encode("unknown", nil); err == nil {
+		t.Error("expected an error encoding an unknown extension")
+	}
+	if _, err := r.Encode("session", map[string]any{"unknown_field": 1}); err == nil {
+		t.Error("expected an error encoding an unknown field")
+	}
+	if _, err := ParseValues([]string{"session"}); err == nil {
+		t.Error("expected an error parsing a value without a name")
+	}
+	var nilRegistry *Registry
+	if _, err := nilRegistry.Encode("session", nil); err == nil {
+		t.Error("expected an error encoding without registry")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	r := newTestRegistry(t)
+	known, err := r.Encode("session", map[string]any{"session_id": "abc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown := &gnmi_ext.Extension{
+		Ext: &gnmi_ext.Extension_RegisteredExt{
+			RegisteredExt: &gnmi_ext.RegisteredExtension{Id: 2002, Msg: []byte{0x01}},
+		},
+	}
+	res := r.Format([]*gnmi_ext.Extension{known, unknown})
+	if len(res) != 2 {
+		t.Fatalf("unexpected result length: %d", len(res))
+	}
+	dm, ok := res[0].(map[string]*Decoded)
+	if !ok || dm["registered-ext"].Msg["session_id"] != "abc" {
+		t.Errorf("expected the first extension to be decoded, got %v", res[0])
+	}
+	if res[1] != unknown {
+		t.Errorf("expected the unknown extension to be returned as is, got %v", res[1])
+	}
+}
+
+func TestNewRegistryErrors(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "session.proto"), []byte(testProto), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]map[string]*Config{
+		"missing_id": {
+			"a": {Message: "vendor.ext.Session", ProtoFiles: []string{"session.proto"}, ProtoDirs: []string{dir}},
+		},
+		"unknown_message": {
+			"a": {ID: 1, Message: "vendor.ext.Unknown", ProtoFiles: []string{"session.proto"}, ProtoDirs: []string{dir}},
+		},
+		"duplicate_id": {
+			"a": {ID: 1, Message: "vendor.ext.Session", ProtoFiles: []string{"session.proto"}, ProtoDirs: []string{dir}},
+			"b": {ID: 1, Message: "vendor.ext.Owner", ProtoFiles: []string{"session.proto"}, ProtoDirs: []string{dir}},
+		},
+	}
+	for name, cfgs := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewRegistry(cfgs); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
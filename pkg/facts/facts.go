@@ -0,0 +1,296 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package facts gathers targets facts (hostname, software version, serial number,...)
+// using gNMI Capabilities and Get RPCs and extracts them using jq expressions.
+package facts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/itchyny/gojq"
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/api"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	// the default jq expression used to extract a fact from a Get response:
+	// the first value of the first event.
+	defaultGetQuery = "[.[].values[]?] | first"
+)
+
+// Config is the facts gathering configuration.
+type Config struct {
+	// list of vendor presets, each one adds a set of predefined
+	// Get requests and Capabilities facts.
+	Presets []string `mapstructure:"presets,omitempty" json:"presets,omitempty"`
+	// facts extracted from the Capabilities response,
+	// fact name to jq expression.
+	Capabilities map[string]string `mapstructure:"capabilities,omitempty" json:"capabilities,omitempty"`
+	// facts extracted from Get responses.
+	Gets []*GetConfig `mapstructure:"gets,omitempty" json:"gets,omitempty"`
+	// add the facts as tags to all the target's events.
+	AddTags bool `mapstructure:"add-tags,omitempty" json:"add-tags,omitempty"`
+	// list of facts added as tags, defaults to all facts.
+	Tags []string `mapstructure:"tags,omitempty" json:"tags,omitempty"`
+	// prefix added to the facts names when added as tags.
+	TagPrefix string `mapstructure:"tag-prefix,omitempty" json:"tag-prefix,omitempty"`
+	// facts are refreshed periodically if set.
+	RefreshInterval time.Duration `mapstructure:"refresh-interval,omitempty" json:"refresh-interval,omitempty"`
+	// timeout of the facts gathering RPCs.
+	Timeout time.Duration `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// GetConfig defines a Get request and the facts extracted from its response.
+type GetConfig struct {
+	Path     string `mapstructure:"path,omitempty" json:"path,omitempty"`
+	Type     string `mapstructure:"type,omitempty" json:"type,omitempty"`
+	Encoding string `mapstructure:"encoding,omitempty" json:"encoding,omitempty"`
+	// fact name to jq expression.
+	// The expression runs against the list of events built from the Get response.
+	// If empty, the first value of the first event is used.
+	Facts map[string]string `mapstructure:"facts,omitempty" json:"facts,omitempty"`
+}
+
+type CapabilitiesFn func(ctx context.Context) (*gnmi.CapabilityResponse, error)
+
+type GetFn func(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetResponse, error)
+
+// Gatherer runs the configured requests and extracts the facts.
+type Gatherer struct {
+	cfg  *Config
+	caps []*query
+	gets []*getQuery
+}
+
+type query struct {
+	fact string
+	code *gojq.Code
+}
+
+type getQuery struct {
+	path    string
+	req     *gnmi.GetRequest
+	queries []*query
+}
+
+func New(cfg *Config) (*Gatherer, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	g := &Gatherer{cfg: cfg}
+	caps := make(map[string]string)
+	gets := make([]*GetConfig, 0, len(cfg.Gets))
+	for _, name := range cfg.Presets {
+		p, ok := presets[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown facts preset %q", name)
+		}
+		for k, v := range p.capabilities {
+			caps[k] = v
+		}
+		gets = append(gets, p.gets...)
+	}
+	// user defined facts override the presets ones
+	for k, v := range cfg.Capabilities {
+		caps[k] = v
+	}
+	gets = append(gets, cfg.Gets...)
+
+	var err error
+	g.caps, err = compileQueries(caps, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, gc := range gets {
+		if gc.Path == "" {
+			return nil, errors.New("facts get: missing path")
+		}
+		opts := []api.GNMIOption{api.Path(gc.Path)}
+		if gc.Type != "" {
+			opts = append(opts, api.DataType(gc.Type))
+		}
+		if gc.Encoding != "" {
+			opts = append(opts, api.Encoding(gc.Encoding))
+		}
+		req, err := api.NewGetRequest(opts...)
+		if err != nil {
+			return nil, fmt.Errorf("facts get %q: %v", gc.Path, err)
+		}
+		qs, err := compileQueries(gc.Facts, defaultGetQuery)
+		if err != nil {
+			return nil, fmt.Errorf("facts get %q: %v", gc.Path, err)
+		}
+		g.gets = append(g.gets, &getQuery{path: gc.Path, req: req, queries: qs})
+	}
+	return g, nil
+}
+
+func (g *Gatherer) Config() *Config {
+	return g.cfg
+}
+
+// Gather runs the Capabilities and Get requests and extracts the facts.
+// It returns the facts it was able to extract along with the errors of the failed requests and queries.
+func (g *Gatherer) Gather(ctx context.Context, capFn CapabilitiesFn, getFn GetFn) (map[string]string, []error) {
+	facts := make(map[string]string)
+	errs := make([]error, 0)
+	if len(g.caps) > 0 {
+		cctx, cancel := context.WithTimeout(ctx, g.cfg.Timeout)
+		rsp, err := capFn(cctx)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("capabilities: %v", err))
+		} else {
+			errs = append(errs, runQueries(g.caps, capabilitiesInput(rsp), facts)...)
+		}
+	}
+	for _, gq := range g.gets {
+		gctx, cancel := context.WithTimeout(ctx, g.cfg.Timeout)
+		rsp, err := getFn(gctx, gq.req)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("get %q: %v", gq.path, err))
+			continue
+		}
+		in, err := getInput(rsp)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("get %q: %v", gq.path, err))
+			continue
+		}
+		errs = append(errs, runQueries(gq.queries, in, facts)...)
+	}
+	return facts, errs
+}
+
+// Tags returns the facts that should be added as events tags,
+// nil if tags are not enabled.
+func (g *Gatherer) Tags(facts map[string]string) map[string]string {
+	if !g.cfg.AddTags || len(facts) == 0 {
+		return nil
+	}
+	tags := make(map[string]string, len(facts))
+	if len(g.cfg.Tags) == 0 {
+		for k, v := range facts {
+			tags[g.cfg.TagPrefix+k] = v
+		}
+		return tags
+	}
+	for _, k := range g.cfg.Tags {
+		if v, ok := facts[k]; ok {
+			tags[g.cfg.TagPrefix+k] = v
+		}
+	}
+	return tags
+}
+
+func compileQueries(m map[string]string, defaultQuery string) ([]*query, error) {
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	qs := make([]*query, 0, len(m))
+	for _, name := range names {
+		expr := strings.TrimSpace(m[name])
+		if expr == "" {
+			expr = defaultQuery
+		}
+		if expr == "" {
+			return nil, fmt.Errorf("fact %q: missing jq expression", name)
+		}
+		q, err := gojq.Parse(expr)
+		if err != nil {
+			return nil, fmt.Errorf("fact %q: %v", name, err)
+		}
+		code, err := gojq.Compile(q)
+		if err != nil {
+			return nil, fmt.Errorf("fact %q: %v", name, err)
+		}
+		qs = append(qs, &query{fact: name, code: code})
+	}
+	return qs, nil
+}
+
+// runQueries runs the queries against the input and stores
+// the results in facts. Queries returning null or no result are ignored.
+func runQueries(qs []*query, in interface{}, facts map[string]string) []error {
+	var errs []error
+	for _, q := range qs {
+		iter := q.code.Run(in)
+		r, ok := iter.Next()
+		if !ok {
+			continue
+		}
+		switch r := r.(type) {
+		case error:
+			errs = append(errs, fmt.Errorf("fact %q: %v", q.fact, r))
+		case nil:
+		case string:
+			facts[q.fact] = r
+		default:
+			b, err := json.Marshal(r)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("fact %q: %v", q.fact, err))
+				continue
+			}
+			facts[q.fact] = string(b)
+		}
+	}
+	return errs
+}
+
+// capabilitiesInput builds the jq input from a capabilities response.
+func capabilitiesInput(rsp *gnmi.CapabilityResponse) interface{} {
+	models := make([]interface{}, 0, len(rsp.GetSupportedModels()))
+	for _, m := range rsp.GetSupportedModels() {
+		models = append(models, map[string]interface{}{
+			"name":         m.GetName(),
+			"organization": m.GetOrganization(),
+			"version":      m.GetVersion(),
+		})
+	}
+	encodings := make([]interface{}, 0, len(rsp.GetSupportedEncodings()))
+	for _, e := range rsp.GetSupportedEncodings() {
+		encodings = append(encodings, e.String())
+	}
+	return map[string]interface{}{
+		"gnmi-version":        rsp.GetGNMIVersion(),
+		"supported-models":    models,
+		"supported-encodings": encodings,
+	}
+}
+
+// getInput builds the jq input from a Get response:
+// the list of events built from its notifications.
+func getInput(rsp *gnmi.GetResponse) (interface{}, error) {
+	evs, err := formatters.GetResponseToEventMsgs(rsp, nil)
+	if err != nil {
+		return nil, err
+	}
+	// go through JSON to get jq compatible types
+	b, err := json.Marshal(evs)
+	if err != nil {
+		return nil, err
+	}
+	var in interface{}
+	err = json.Unmarshal(b, &in)
+	return in, err
+}
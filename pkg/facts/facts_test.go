@@ -0,0 +1,242 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package facts
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/api/path"
+)
+
+func getResponse(notifs ...*gnmi.Notification) *gnmi.GetResponse {
+	return &gnmi.GetResponse{Notification: notifs}
+}
+
+func leafNotification(xpath string, val *gnmi.TypedValue) *gnmi.Notification {
+	p, _ := path.ParsePath(xpath)
+	return &gnmi.Notification{
+		Timestamp: 1,
+		Update:    []*gnmi.Update{{Path: p, Val: val}},
+	}
+}
+
+func jsonNotification(xpath, val string) *gnmi.Notification {
+	return leafNotification(xpath, &gnmi.TypedValue{Value: &gnmi.TypedValue_JsonIetfVal{JsonIetfVal: []byte(val)}})
+}
+
+func stringVal(s string) *gnmi.TypedValue {
+	return &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: s}}
+}
+
+type fakeTarget struct {
+	caps *gnmi.CapabilityResponse
+	gets map[string]*gnmi.GetResponse
+}
+
+func (f *fakeTarget) capabilities(ctx context.Context) (*gnmi.CapabilityResponse, error) {
+	if f.caps == nil {
+		return nil, errors.New("unimplemented")
+	}
+	return f.caps, nil
+}
+
+func (f *fakeTarget) get(ctx context.Context, req *gnmi.GetRequest) (*gnmi.GetResponse, error) {
+	rsp, ok := f.gets[path.GnmiPathToXPath(req.GetPath()[0], false)]
+	if !ok {
+		return nil, errors.New("path not found")
+	}
+	return rsp, nil
+}
+
+func TestGather(t *testing.T) {
+	tests := map[string]struct {
+		cfg     *Config
+		target  *fakeTarget
+		facts   map[string]string
+		numErrs int
+	}{
+		"get_default_query": {
+			cfg: &Config{
+				Gets: []*GetConfig{
+					{
+						Path:  "/system/name/host-name",
+						Facts: map[string]string{"hostname": ""},
+					},
+				},
+			},
+			target: &fakeTarget{
+				gets: map[string]*gnmi.GetResponse{
+					"system/name/host-name": getResponse(leafNotification("/system/name/host-name", stringVal("srl1"))),
+				},
+			},
+			facts: map[string]string{"hostname": "srl1"},
+		},
+		"capabilities_and_jq": {
+			cfg: &Config{
+				Capabilities: map[string]string{
+					"gnmi-version": `."gnmi-version"`,
+					"models":       `[."supported-models"[].name] | length`,
+				},
+				Gets: []*GetConfig{
+					{
+						Path: "/system/information",
+						Facts: map[string]string{
+							"software-version": `.[0].values["/system/information/version"]`,
+							"missing":          `.[0].values["/system/information/missing"]`,
+						},
+					},
+				},
+			},
+			target: &fakeTarget{
+				caps: &gnmi.CapabilityResponse{
+					GNMIVersion:     "0.10.0",
+					SupportedModels: []*gnmi.ModelData{{Name: "m1"}, {Name: "m2"}},
+				},
+				gets: map[string]*gnmi.GetResponse{
+					"system/information": getResponse(jsonNotification("/system/information", `{"version":"v24.3.1","description":"SRLinux"}`)),
+				},
+			},
+			facts: map[string]string{
+				"gnmi-version":     "0.10.0",
+				"models":           "2",
+				"software-version": "v24.3.1",
+			},
+		},
+		"partial_failure": {
+			cfg: &Config{
+				Capabilities: map[string]string{"gnmi-version": `."gnmi-version"`},
+				Gets: []*GetConfig{
+					{
+						Path:  "/system/name/host-name",
+						Facts: map[string]string{"hostname": ""},
+					},
+					{
+						Path:  "/system/information/version",
+						Facts: map[string]string{"software-version": ""},
+					},
+				},
+			},
+			target: &fakeTarget{
+				gets: map[string]*gnmi.GetResponse{
+					"system/name/host-name": getResponse(leafNotification("/system/name/host-name", stringVal("srl1"))),
+				},
+			},
+			facts:   map[string]string{"hostname": "srl1"},
+			numErrs: 2,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			g, err := New(tt.cfg)
+			if err != nil {
+				t.Fatalf("failed to create gatherer: %v", err)
+			}
+			fs, errs := g.Gather(context.Background(), tt.target.capabilities, tt.target.get)
+			if !reflect.DeepEqual(fs, tt.facts) {
+				t.Errorf("expected facts %v, got %v", tt.facts, fs)
+			}
+			if len(errs) != tt.numErrs {
+				t.Errorf("expected %d errors, got %d: %v", tt.numErrs, len(errs), errs)
+			}
+		})
+	}
+}
+
+func TestPresets(t *testing.T) {
+	for name := range presets {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(&Config{Presets: []string{name}})
+			if err != nil {
+				t.Errorf("failed to create gatherer: %v", err)
+			}
+		})
+	}
+	_, err := New(&Config{Presets: []string{"unknown"}})
+	if err == nil {
+		t.Errorf("expected an unknown preset error")
+	}
+}
+
+func TestOpenconfigChassisQuery(t *testing.T) {
+	g, err := New(&Config{
+		Gets: []*GetConfig{openconfigPreset.gets[2]},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := &fakeTarget{
+		gets: map[string]*gnmi.GetResponse{
+			"components/component/state": getResponse(
+				jsonNotification("/components/component[name=Linecard1]/state", `{"type":"openconfig-platform-types:LINECARD","serial-no":"LC1","part-no":"LC-01"}`),
+				jsonNotification("/components/component[name=Chassis]/state", `{"type":"openconfig-platform-types:CHASSIS","serial-no":"SN123","part-no":"DCS-7280"}`),
+			),
+		},
+	}
+	fs, errs := g.Gather(context.Background(), target.capabilities, target.get)
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	expected := map[string]string{"serial-number": "SN123", "platform": "DCS-7280"}
+	if !reflect.DeepEqual(fs, expected) {
+		t.Errorf("expected facts %v, got %v", expected, fs)
+	}
+}
+
+func TestTags(t *testing.T) {
+	fs := map[string]string{"hostname": "r1", "software-version": "v1"}
+	g, _ := New(&Config{})
+	if tags := g.Tags(fs); tags != nil {
+		t.Errorf("expected no tags, got %v", tags)
+	}
+	g, _ = New(&Config{AddTags: true, TagPrefix: "fact_"})
+	expected := map[string]string{"fact_hostname": "r1", "fact_software-version": "v1"}
+	if tags := g.Tags(fs); !reflect.DeepEqual(tags, expected) {
+		t.Errorf("expected tags %v, got %v", expected, tags)
+	}
+	g, _ = New(&Config{AddTags: true, Tags: []string{"hostname", "serial-number"}})
+	expected = map[string]string{"hostname": "r1"}
+	if tags := g.Tags(fs); !reflect.DeepEqual(tags, expected) {
+		t.Errorf("expected tags %v, got %v", expected, tags)
+	}
+}
+
+func TestStore(t *testing.T) {
+	s := NewStore()
+	if !s.Set("t1", map[string]string{"hostname": "r1"}, nil) {
+		t.Errorf("expected first set to be a change")
+	}
+	if s.Set("t1", map[string]string{"hostname": "r1"}, nil) {
+		t.Errorf("expected identical facts not to be a change")
+	}
+	if !s.Set("t1", map[string]string{"hostname": "r1", "software-version": "v2"}, nil) {
+		t.Errorf("expected new facts to be a change")
+	}
+	fs := s.Get("t1")
+	fs["hostname"] = "modified"
+	if s.Get("t1")["hostname"] != "r1" {
+		t.Errorf("Get did not return a copy")
+	}
+	if Lookup("t1") != nil {
+		t.Errorf("expected no facts without a templates store")
+	}
+	SetTemplateStore(s)
+	defer SetTemplateStore(nil)
+	if Lookup("t1")["hostname"] != "r1" {
+		t.Errorf("expected the templates to read the facts from the store")
+	}
+	s.Delete("t1")
+	if s.Get("t1") != nil {
+		t.Errorf("expected facts to be deleted")
+	}
+}
@@ -0,0 +1,101 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package facts
+
+import "fmt"
+
+type preset struct {
+	capabilities map[string]string
+	gets         []*GetConfig
+}
+
+var capabilitiesFacts = map[string]string{
+	"gnmi-version": `."gnmi-version"`,
+}
+
+// selects the value of the leaf ending with `leaf` from the events
+// of the CHASSIS component.
+const ocChassisQuery = `[.[] | .values // {} | select([to_entries[] | select(.key | endswith("/state/type")) | .value | tostring | test("CHASSIS")] | any) | to_entries[] | select(.key | endswith("/state/%s")) | .value] | first`
+
+var openconfigPreset = &preset{
+	capabilities: capabilitiesFacts,
+	gets: []*GetConfig{
+		{
+			Path:  "/system/state/hostname",
+			Type:  "state",
+			Facts: map[string]string{"hostname": ""},
+		},
+		{
+			Path:  "/system/state/software-version",
+			Type:  "state",
+			Facts: map[string]string{"software-version": ""},
+		},
+		{
+			Path: "/components/component/state",
+			Type: "state",
+			Facts: map[string]string{
+				"serial-number": fmt.Sprintf(ocChassisQuery, "serial-no"),
+				"platform":      fmt.Sprintf(ocChassisQuery, "part-no"),
+			},
+		},
+	},
+}
+
+var presets = map[string]*preset{
+	"openconfig": openconfigPreset,
+	"nokia_srl": {
+		capabilities: capabilitiesFacts,
+		gets: []*GetConfig{
+			{
+				Path:  "/system/name/host-name",
+				Facts: map[string]string{"hostname": ""},
+			},
+			{
+				Path:  "/system/information/version",
+				Type:  "state",
+				Facts: map[string]string{"software-version": ""},
+			},
+			{
+				Path:  "/platform/chassis/type",
+				Type:  "state",
+				Facts: map[string]string{"platform": ""},
+			},
+			{
+				Path:  "/platform/chassis/serial-number",
+				Type:  "state",
+				Facts: map[string]string{"serial-number": ""},
+			},
+		},
+	},
+	"nokia_sros": {
+		capabilities: capabilitiesFacts,
+		gets: []*GetConfig{
+			{
+				Path:  "/state/system/oper-name",
+				Facts: map[string]string{"hostname": ""},
+			},
+			{
+				Path:  "/state/system/version/version-number",
+				Facts: map[string]string{"software-version": ""},
+			},
+			{
+				Path:  "/state/system/platform",
+				Facts: map[string]string{"platform": ""},
+			},
+			{
+				Path:  "/state/chassis[chassis-class=router][chassis-number=1]/hardware-data/serial-number",
+				Facts: map[string]string{"serial-number": ""},
+			},
+		},
+	},
+	// the following platforms expose the openconfig models
+	"arista_eos":    openconfigPreset,
+	"cisco_iosxr":   openconfigPreset,
+	"juniper_junos": openconfigPreset,
+}
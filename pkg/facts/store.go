@@ -0,0 +1,134 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package facts
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TargetFacts are the facts of a single target.
+type TargetFacts struct {
+	Target    string            `json:"target,omitempty"`
+	Facts     map[string]string `json:"facts,omitempty"`
+	Errors    []string          `json:"errors,omitempty"`
+	UpdatedAt time.Time         `json:"updated-at,omitempty"`
+	ChangedAt time.Time         `json:"changed-at,omitempty"`
+}
+
+// Store holds the facts of the targets.
+type Store struct {
+	m       sync.RWMutex
+	targets map[string]*TargetFacts
+}
+
+func NewStore() *Store {
+	return &Store{targets: make(map[string]*TargetFacts)}
+}
+
+// Set stores the facts of a target and reports
+// whether they differ from the previously stored ones.
+func (s *Store) Set(target string, facts map[string]string, errs []error) bool {
+	now := time.Now()
+	tf := &TargetFacts{
+		Target:    target,
+		Facts:     facts,
+		UpdatedAt: now,
+		ChangedAt: now,
+	}
+	for _, err := range errs {
+		tf.Errors = append(tf.Errors, err.Error())
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	prev, ok := s.targets[target]
+	changed := !ok || !equal(prev.Facts, facts)
+	if !changed {
+		tf.ChangedAt = prev.ChangedAt
+	}
+	s.targets[target] = tf
+	return changed
+}
+
+// Get returns a copy of the facts of a target.
+func (s *Store) Get(target string) map[string]string {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	tf, ok := s.targets[target]
+	if !ok {
+		return nil
+	}
+	facts := make(map[string]string, len(tf.Facts))
+	for k, v := range tf.Facts {
+		facts[k] = v
+	}
+	return facts
+}
+
+// GetTarget returns the facts of a target along with
+// their gathering errors and timestamps.
+func (s *Store) GetTarget(target string) (*TargetFacts, bool) {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	tf, ok := s.targets[target]
+	return tf, ok
+}
+
+// List returns the facts of all targets sorted by target name.
+func (s *Store) List() []*TargetFacts {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	tfs := make([]*TargetFacts, 0, len(s.targets))
+	for _, tf := range s.targets {
+		tfs = append(tfs, tf)
+	}
+	sort.Slice(tfs, func(i, j int) bool {
+		return tfs[i].Target < tfs[j].Target
+	})
+	return tfs
+}
+
+// Delete removes the facts of a target.
+func (s *Store) Delete(target string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	delete(s.targets, target)
+}
+
+// the store read by the templates functions `facts` and `fact`.
+var templateStore atomic.Pointer[Store]
+
+// SetTemplateStore sets the store the templates read the targets facts from.
+func SetTemplateStore(s *Store) {
+	templateStore.Store(s)
+}
+
+// Lookup returns a copy of the facts of a target from the templates store,
+// nil if no store is set.
+func Lookup(target string) map[string]string {
+	s := templateStore.Load()
+	if s == nil {
+		return nil
+	}
+	return s.Get(target)
+}
+
+func equal(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
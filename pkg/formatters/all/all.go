@@ -10,11 +10,14 @@ package all
 
 import (
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_add_tag"
+	_ "github.com/openconfig/gnmic/pkg/formatters/event_align"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_allow"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_combine"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_convert"
+	_ "github.com/openconfig/gnmic/pkg/formatters/event_correlate"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_data_convert"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_date_string"
+	_ "github.com/openconfig/gnmic/pkg/formatters/event_dedup"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_delete"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_drop"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_duration_convert"
@@ -25,10 +28,12 @@ import (
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_merge"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_override_ts"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_rate_limit"
+	_ "github.com/openconfig/gnmic/pkg/formatters/event_redact"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_starlark"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_strings"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_time_epoch"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_to_tag"
+	_ "github.com/openconfig/gnmic/pkg/formatters/event_topk"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_trigger"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_value_tag"
 	_ "github.com/openconfig/gnmic/pkg/formatters/event_value_tag_v2"
@@ -0,0 +1,613 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package formatters
+
+import (
+	"fmt"
+	"hash/maphash"
+	"math"
+	"math/bits"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+const (
+	defaultCatalogMaxEntries  = 1000
+	defaultCatalogMaxExamples = 3
+	defaultCatalogMaxPaths    = 20
+
+	// HyperLogLog precision of the tags cardinality estimation,
+	// 2^10 registers, ~3% standard error.
+	catalogHLLPrecision = 10
+	// number of independently locked catalog shards.
+	catalogShards = 16
+)
+
+// CatalogConfig sets the limits of the telemetry catalog.
+type CatalogConfig struct {
+	// maximum number of catalog entries (output and event name pairs)
+	MaxEntries int `mapstructure:"max-entries,omitempty" json:"max-entries,omitempty" yaml:"max-entries,omitempty"`
+	// maximum number of example values kept per value name and tag key
+	MaxExamples int `mapstructure:"max-examples,omitempty" json:"max-examples,omitempty" yaml:"max-examples,omitempty"`
+	// maximum number of source paths kept per entry
+	MaxPaths int `mapstructure:"max-paths,omitempty" json:"max-paths,omitempty" yaml:"max-paths,omitempty"`
+}
+
+// CatalogEntry describes the events called Name written to an output.
+type CatalogEntry struct {
+	Output        string                   `json:"output,omitempty"`
+	Name          string                   `json:"name"`
+	Values        map[string]*CatalogValue `json:"values,omitempty"`
+	Tags          map[string]*CatalogTag   `json:"tags,omitempty"`
+	Subscriptions []string                 `json:"subscriptions,omitempty"`
+	Paths         []string                 `json:"paths,omitempty"`
+	Count         uint64                   `json:"count"`
+	FirstSeen     time.Time                `json:"first-seen"`
+	LastSeen      time.Time                `json:"last-seen"`
+}
+
+// CatalogValue describes an event value: the JSON types
+// observed for it and a few example values.
+type CatalogValue struct {
+	Types    []string      `json:"types,omitempty"`
+	Examples []interface{} `json:"examples,omitempty"`
+}
+
+// CatalogTag describes an event tag: the estimated number
+// of distinct values and a few example values.
+type CatalogTag struct {
+	Cardinality uint64   `json:"cardinality"`
+	Examples    []string `json:"examples,omitempty"`
+}
+
+// Catalog records the schema of the events written to the outputs,
+// after the outputs processors are applied.
+// The entries are sharded by key so that concurrent outputs do not contend on a single lock.
+type Catalog struct {
+	cfg  *CatalogConfig
+	seed maphash.Seed
+
+	shards []*catalogShard
+	// number of entries, all shards included.
+	size    atomic.Int64
+	dropped atomic.Uint64
+}
+
+type catalogShard struct {
+	m       sync.Mutex
+	entries map[catalogKey]*catalogEntry
+}
+
+type catalogKey struct {
+	output string
+	name   string
+}
+
+type catalogEntry struct {
+	values        map[string]*catalogValue
+	tags          map[string]*catalogTag
+	subscriptions map[string]struct{}
+	paths         map[string]struct{}
+	count         uint64
+	firstSeen     time.Time
+	lastSeen      time.Time
+}
+
+type catalogValue struct {
+	types    map[string]struct{}
+	examples []interface{}
+}
+
+type catalogTag struct {
+	hll      *hyperLogLog
+	examples []string
+}
+
+var catalog atomic.Pointer[Catalog]
+
+// SetCatalog sets the Catalog fed by the processors chains.
+// A nil Catalog disables it.
+func SetCatalog(c *Catalog) {
+	catalog.Store(c)
+}
+
+// GetCatalog returns the current Catalog, nil if it is disabled.
+func GetCatalog() *Catalog {
+	return catalog.Load()
+}
+
+func NewCatalog(cfg *CatalogConfig) *Catalog {
+	if cfg == nil {
+		cfg = new(CatalogConfig)
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = defaultCatalogMaxEntries
+	}
+	if cfg.MaxExamples <= 0 {
+		cfg.MaxExamples = defaultCatalogMaxExamples
+	}
+	if cfg.MaxPaths <= 0 {
+		cfg.MaxPaths = defaultCatalogMaxPaths
+	}
+	c := &Catalog{
+		cfg:    cfg,
+		seed:   maphash.MakeSeed(),
+		shards: make([]*catalogShard, catalogShards),
+	}
+	for i := range c.shards {
+		c.shards[i] = &catalogShard{entries: make(map[catalogKey]*catalogEntry)}
+	}
+	return c
+}
+
+func (c *Catalog) Config() *CatalogConfig {
+	return c.cfg
+}
+
+// Dropped returns the number of events not recorded
+// because the catalog reached its maximum number of entries.
+func (c *Catalog) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+func (c *Catalog) Reset() {
+	for _, s := range c.shards {
+		s.m.Lock()
+		c.size.Add(-int64(len(s.entries)))
+		s.entries = make(map[catalogKey]*catalogEntry)
+		s.m.Unlock()
+	}
+	c.dropped.Store(0)
+}
+
+func (c *Catalog) shard(k catalogKey) *catalogShard {
+	var h maphash.Hash
+	h.SetSeed(c.seed)
+	h.WriteString(k.output)
+	h.WriteByte(0)
+	h.WriteString(k.name)
+	return c.shards[h.Sum64()%uint64(len(c.shards))]
+}
+
+// reserve accounts for a new entry, it returns false if the catalog is full.
+func (c *Catalog) reserve() bool {
+	for {
+		n := c.size.Load()
+		if n >= int64(c.cfg.MaxEntries) {
+			return false
+		}
+		if c.size.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// ObserveEvents records the events evs written to output, e.g by an input.
+// The subscriptions are read from the events "subscription-name" tag.
+func ObserveEvents(output string, evs []*EventMsg) {
+	c := GetCatalog()
+	if c == nil {
+		return
+	}
+	c.Observe(output, "", nil, evs)
+}
+
+// ObserveResponse records the events a subscribe response converts to,
+// for the outputs writing it in a format other than event.
+func ObserveResponse(output, name string, rsp *gnmi.SubscribeResponse, meta map[string]string) {
+	c := GetCatalog()
+	if c == nil || rsp.GetUpdate() == nil {
+		return
+	}
+	evs, err := responseEvents(name, rsp, meta)
+	if err != nil {
+		return
+	}
+	c.Observe(output, name, sourcePaths(evs), evs)
+}
+
+// Observe records the events evs resulting from a single message received
+// by subscription and written to output.
+// If subscription is empty, it is read from the events "subscription-name" tag.
+// paths maps the events as they were before the processors to their source paths.
+// The events created by the processors inherit all the message paths.
+func (c *Catalog) Observe(output, subscription string, paths map[*EventMsg][]string, evs []*EventMsg) {
+	if len(evs) == 0 {
+		return
+	}
+	now := time.Now()
+	// the consecutive events of a message usually share their name,
+	// the shard lock is held until an event belongs to another shard.
+	var s *catalogShard
+	defer func() {
+		if s != nil {
+			s.m.Unlock()
+		}
+	}()
+	for _, ev := range evs {
+		if ev == nil {
+			continue
+		}
+		k := catalogKey{output: output, name: ev.Name}
+		if ks := c.shard(k); ks != s {
+			if s != nil {
+				s.m.Unlock()
+			}
+			s = ks
+			s.m.Lock()
+		}
+		e, ok := s.entries[k]
+		if !ok {
+			if !c.reserve() {
+				c.dropped.Add(1)
+				continue
+			}
+			e = &catalogEntry{
+				values:        make(map[string]*catalogValue),
+				tags:          make(map[string]*catalogTag),
+				subscriptions: make(map[string]struct{}),
+				paths:         make(map[string]struct{}),
+				firstSeen:     now,
+			}
+			s.entries[k] = e
+		}
+		e.count++
+		e.lastSeen = now
+		sub := subscription
+		if sub == "" {
+			sub = ev.Tags["subscription-name"]
+		}
+		if sub != "" {
+			e.subscriptions[sub] = struct{}{}
+		}
+		for vn, v := range ev.Values {
+			cv, ok := e.values[vn]
+			if !ok {
+				cv = &catalogValue{types: make(map[string]struct{})}
+				e.values[vn] = cv
+			}
+			cv.types[jsonType(v)] = struct{}{}
+			if len(cv.examples) < c.cfg.MaxExamples && !exampleIn(v, cv.examples) {
+				cv.examples = append(cv.examples, v)
+			}
+		}
+		for tn, tv := range ev.Tags {
+			ct, ok := e.tags[tn]
+			if !ok {
+				ct = &catalogTag{hll: newHyperLogLog(catalogHLLPrecision)}
+				e.tags[tn] = ct
+			}
+			ct.hll.add(maphash.String(c.seed, tv))
+			if len(ct.examples) < c.cfg.MaxExamples && !strInList(tv, ct.examples) {
+				ct.examples = append(ct.examples, tv)
+			}
+		}
+		ps, ok := paths[ev]
+		if !ok {
+			for _, pps := range paths {
+				c.addPaths(e, pps)
+			}
+			continue
+		}
+		c.addPaths(e, ps)
+	}
+}
+
+func (c *Catalog) addPaths(e *catalogEntry, ps []string) {
+	for _, p := range ps {
+		if len(e.paths) >= c.cfg.MaxPaths {
+			return
+		}
+		e.paths[p] = struct{}{}
+	}
+}
+
+// Entries returns the catalog entries sorted by output and event name.
+// The entries can be filtered by output name and by a case insensitive search string
+// matched against the event names, value names, tag keys and source paths.
+// Empty filter values match all entries.
+func (c *Catalog) Entries(output, search string) []*CatalogEntry {
+	search = strings.ToLower(search)
+	res := make([]*CatalogEntry, 0, c.size.Load())
+	for _, s := range c.shards {
+		s.m.Lock()
+		for k, e := range s.entries {
+			if output != "" && k.output != output {
+				continue
+			}
+			if search != "" && !e.match(k.name, search) {
+				continue
+			}
+			res = append(res, e.export(k))
+		}
+		s.m.Unlock()
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Output == res[j].Output {
+			return res[i].Name < res[j].Name
+		}
+		return res[i].Output < res[j].Output
+	})
+	return res
+}
+
+func (e *catalogEntry) match(name, search string) bool {
+	if strings.Contains(strings.ToLower(name), search) {
+		return true
+	}
+	for vn := range e.values {
+		if strings.Contains(strings.ToLower(vn), search) {
+			return true
+		}
+	}
+	for tn := range e.tags {
+		if strings.Contains(strings.ToLower(tn), search) {
+			return true
+		}
+	}
+	for p := range e.paths {
+		if strings.Contains(strings.ToLower(p), search) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *catalogEntry) export(k catalogKey) *CatalogEntry {
+	ce := &CatalogEntry{
+		Output:        k.output,
+		Name:          k.name,
+		Values:        make(map[string]*CatalogValue, len(e.values)),
+		Tags:          make(map[string]*CatalogTag, len(e.tags)),
+		Subscriptions: sortedKeys(e.subscriptions),
+		Paths:         sortedKeys(e.paths),
+		Count:         e.count,
+		FirstSeen:     e.firstSeen,
+		LastSeen:      e.lastSeen,
+	}
+	for vn, v := range e.values {
+		ce.Values[vn] = &CatalogValue{
+			Types:    sortedKeys(v.types),
+			Examples: append([]interface{}(nil), v.examples...),
+		}
+	}
+	for tn, t := range e.tags {
+		ce.Tags[tn] = &CatalogTag{
+			Cardinality: t.hll.count(),
+			Examples:    append([]string(nil), t.examples...),
+		}
+	}
+	return ce
+}
+
+// CatalogJSONSchema returns a JSON Schema document describing the catalog entries.
+// Each entry is a definition under "$defs" named after its output and event name.
+func CatalogJSONSchema(entries []*CatalogEntry) map[string]interface{} {
+	defs := make(map[string]interface{}, len(entries))
+	refs := make([]interface{}, 0, len(entries))
+	for _, e := range entries {
+		id := e.Name
+		if e.Output != "" {
+			id = e.Output + "/" + e.Name
+		}
+		values := make(map[string]interface{}, len(e.Values))
+		for vn, v := range e.Values {
+			vs := map[string]interface{}{}
+			if len(v.Types) == 1 {
+				vs["type"] = v.Types[0]
+			} else if len(v.Types) > 1 {
+				vs["type"] = v.Types
+			}
+			if len(v.Examples) > 0 {
+				vs["examples"] = v.Examples
+			}
+			values[vn] = vs
+		}
+		tags := make(map[string]interface{}, len(e.Tags))
+		for tn, t := range e.Tags {
+			ts := map[string]interface{}{
+				"type":                "string",
+				"x-gnmic-cardinality": t.Cardinality,
+			}
+			if len(t.Examples) > 0 {
+				ts["examples"] = t.Examples
+			}
+			tags[tn] = ts
+		}
+		def := map[string]interface{}{
+			"title": e.Name,
+			"type":  "object",
+			"properties": map[string]interface{}{
+				"name":      map[string]interface{}{"const": e.Name},
+				"timestamp": map[string]interface{}{"type": "integer"},
+				"tags":      map[string]interface{}{"type": "object", "properties": tags},
+				"values":    map[string]interface{}{"type": "object", "properties": values},
+				"deletes":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			},
+			"required": []string{"name", "timestamp"},
+		}
+		if len(e.Subscriptions) > 0 {
+			def["x-gnmic-subscriptions"] = e.Subscriptions
+		}
+		if len(e.Paths) > 0 {
+			def["x-gnmic-paths"] = e.Paths
+		}
+		defs[id] = def
+		refs = append(refs, map[string]interface{}{"$ref": "#/$defs/" + jsonPointerEscape(id)})
+	}
+	return map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "gNMIc events",
+		"$defs":   defs,
+		"anyOf":   refs,
+	}
+}
+
+// CatalogMarkdown renders the catalog entries as a Markdown document,
+// one section per output and one subsection per event name.
+func CatalogMarkdown(entries []*CatalogEntry) string {
+	sb := new(strings.Builder)
+	sb.WriteString("# Telemetry catalog\n")
+	output := "\x00"
+	for _, e := range entries {
+		if e.Output != output {
+			output = e.Output
+			if output == "" {
+				sb.WriteString("\n## No output\n")
+			} else {
+				fmt.Fprintf(sb, "\n## Output `%s`\n", output)
+			}
+		}
+		fmt.Fprintf(sb, "\n### `%s`\n\n", e.Name)
+		fmt.Fprintf(sb, "- Count: %d\n", e.Count)
+		fmt.Fprintf(sb, "- First seen: %s\n", e.FirstSeen.UTC().Format(time.RFC3339))
+		fmt.Fprintf(sb, "- Last seen: %s\n", e.LastSeen.UTC().Format(time.RFC3339))
+		if len(e.Subscriptions) > 0 {
+			fmt.Fprintf(sb, "- Subscriptions: %s\n", mdCodeList(e.Subscriptions))
+		}
+		if len(e.Paths) > 0 {
+			fmt.Fprintf(sb, "- Paths: %s\n", mdCodeList(e.Paths))
+		}
+		if len(e.Values) > 0 {
+			sb.WriteString("\n| Value | Types | Examples |\n|---|---|---|\n")
+			for _, vn := range sortedKeys(e.Values) {
+				v := e.Values[vn]
+				exs := make([]string, 0, len(v.Examples))
+				for _, ex := range v.Examples {
+					exs = append(exs, fmt.Sprint(ex))
+				}
+				fmt.Fprintf(sb, "| `%s` | %s | %s |\n", mdEscape(vn), strings.Join(v.Types, ", "), mdCodeList(exs))
+			}
+		}
+		if len(e.Tags) > 0 {
+			sb.WriteString("\n| Tag | Cardinality | Examples |\n|---|---|---|\n")
+			for _, tn := range sortedKeys(e.Tags) {
+				t := e.Tags[tn]
+				fmt.Fprintf(sb, "| `%s` | %d | %s |\n", mdEscape(tn), t.Cardinality, mdCodeList(t.Examples))
+			}
+		}
+	}
+	return sb.String()
+}
+
+func mdCodeList(ls []string) string {
+	items := make([]string, 0, len(ls))
+	for _, s := range ls {
+		items = append(items, "`"+mdEscape(s)+"`")
+	}
+	return strings.Join(items, ", ")
+}
+
+func mdEscape(s string) string {
+	return strings.NewReplacer("|", "\\|", "`", "'", "\n", " ").Replace(s)
+}
+
+func jsonPointerEscape(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// jsonType returns the JSON Schema type of an event value.
+func jsonType(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return "string"
+	}
+}
+
+func exampleIn(v interface{}, exs []interface{}) bool {
+	s := fmt.Sprint(v)
+	for _, ex := range exs {
+		if fmt.Sprint(ex) == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	if len(m) == 0 {
+		return nil
+	}
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+// sourcePaths returns the value names and deleted paths of the events.
+func sourcePaths(evs []*EventMsg) map[*EventMsg][]string {
+	res := make(map[*EventMsg][]string, len(evs))
+	for _, ev := range evs {
+		ps := make([]string, 0, len(ev.Values)+len(ev.Deletes))
+		for vn := range ev.Values {
+			ps = append(ps, vn)
+		}
+		ps = append(ps, ev.Deletes...)
+		res[ev] = ps
+	}
+	return res
+}
+
+// hyperLogLog is a distinct values counter using a fixed amount of memory.
+type hyperLogLog struct {
+	p         uint8
+	registers []uint8
+}
+
+func newHyperLogLog(p uint8) *hyperLogLog {
+	return &hyperLogLog{p: p, registers: make([]uint8, 1<<p)}
+}
+
+func (h *hyperLogLog) add(x uint64) {
+	idx := x >> (64 - h.p)
+	rank := uint8(bits.LeadingZeros64(x<<h.p|1<<(h.p-1))) + 1
+	if rank > h.registers[idx] {
+		h.registers[idx] = rank
+	}
+}
+
+func (h *hyperLogLog) count() uint64 {
+	m := float64(len(h.registers))
+	sum := 0.0
+	zeros := 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	est := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if est <= 2.5*m && zeros > 0 {
+		// small range correction: linear counting
+		est = m * math.Log(m/float64(zeros))
+	}
+	return uint64(math.Round(est))
+}
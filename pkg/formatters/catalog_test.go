@@ -0,0 +1,220 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package formatters
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/maphash"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+)
+
+func interfaceResponse(name string, octets uint64) *gnmi.SubscribeResponse {
+	return &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: 42,
+				Prefix:    &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interface", Key: map[string]string{"name": name}}}},
+				Update: []*gnmi.Update{
+					{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "statistics"}, {Name: "in-octets"}}},
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: octets}},
+					},
+					{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "oper-state"}}},
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: "up"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestCatalogObserve(t *testing.T) {
+	c := NewCatalog(&CatalogConfig{MaxExamples: 2})
+	SetCatalog(c)
+	defer SetCatalog(nil)
+
+	out1 := ForOutput("out1", newTestChain(func(es ...*EventMsg) []*EventMsg {
+		for _, e := range es {
+			e.Name = "interfaces"
+		}
+		return es
+	}))
+	for i := 0; i < 3; i++ {
+		meta := map[string]string{"source": "router1"}
+		_, err := ResponseToEventMsgs("sub1", interfaceResponse(fmt.Sprintf("ethernet-1/%d", i), uint64(i)), meta, out1...)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// an output without processors.
+	_, err := ResponseToEventMsgs("sub1", interfaceResponse("ethernet-1/1", 1), nil, ForOutput("out2", nil)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := c.Entries("", "")
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	e := entries[0]
+	if e.Output != "out1" || e.Name != "interfaces" || e.Count != 6 {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	if !reflect.DeepEqual(e.Subscriptions, []string{"sub1"}) {
+		t.Errorf("unexpected subscriptions: %v", e.Subscriptions)
+	}
+	expectedPaths := []string{"/interface/oper-state", "/interface/statistics/in-octets"}
+	if !reflect.DeepEqual(e.Paths, expectedPaths) {
+		t.Errorf("unexpected paths: %v", e.Paths)
+	}
+	v := e.Values["/interface/statistics/in-octets"]
+	if v == nil || !reflect.DeepEqual(v.Types, []string{"integer"}) || len(v.Examples) != 2 {
+		t.Errorf("unexpected value: %+v", v)
+	}
+	tag := e.Tags["interface_name"]
+	if tag == nil || tag.Cardinality != 3 || len(tag.Examples) != 2 {
+		t.Errorf("unexpected tag: %+v", tag)
+	}
+	if e.FirstSeen.IsZero() || e.LastSeen.Before(e.FirstSeen) {
+		t.Errorf("unexpected first/last seen: %s, %s", e.FirstSeen, e.LastSeen)
+	}
+	if entries[1].Output != "out2" || entries[1].Name != "sub1" {
+		t.Errorf("unexpected entry: %+v", entries[1])
+	}
+}
+
+func TestCatalogObserveOutsideProcessors(t *testing.T) {
+	c := NewCatalog(nil)
+	SetCatalog(c)
+	defer SetCatalog(nil)
+
+	// a message written in a non event format.
+	ObserveResponse("out1", "sub1", interfaceResponse("ethernet-1/1", 1), nil)
+	// events written by an input.
+	ObserveEvents("out2", []*EventMsg{{Name: "bgp", Tags: map[string]string{"subscription-name": "sub2"}}})
+
+	entries := c.Entries("", "")
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if e := entries[0]; e.Output != "out1" || e.Name != "sub1" || len(e.Paths) != 2 || e.Count != 2 {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+	if e := entries[1]; e.Output != "out2" || !reflect.DeepEqual(e.Subscriptions, []string{"sub2"}) {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+	c.Reset()
+	if len(c.Entries("", "")) != 0 || c.size.Load() != 0 {
+		t.Errorf("expected an empty catalog after a reset")
+	}
+}
+
+func TestCatalogEntriesFilter(t *testing.T) {
+	c := NewCatalog(&CatalogConfig{MaxEntries: 2})
+	c.Observe("out1", "sub1", nil, []*EventMsg{
+		{Name: "cpu", Values: map[string]interface{}{"/system/cpu/total": 1.5}},
+		{Name: "memory", Tags: map[string]string{"Slot": "A"}, Values: map[string]interface{}{"/system/memory/used": 10}},
+	})
+	c.Observe("out2", "sub1", nil, []*EventMsg{{Name: "bgp"}})
+	if c.Dropped() != 1 {
+		t.Errorf("expected 1 dropped event, got %d", c.Dropped())
+	}
+	tests := map[string]struct {
+		output string
+		search string
+		names  []string
+	}{
+		"all":          {names: []string{"cpu", "memory"}},
+		"output":       {output: "out2"},
+		"name":         {search: "CPU", names: []string{"cpu"}},
+		"value":        {search: "memory/used", names: []string{"memory"}},
+		"tag":          {search: "slot", names: []string{"memory"}},
+		"no_match":     {search: "interface"},
+		"output_match": {output: "out1", search: "system", names: []string{"cpu", "memory"}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var names []string
+			for _, e := range c.Entries(tt.output, tt.search) {
+				names = append(names, e.Name)
+			}
+			if !reflect.DeepEqual(names, tt.names) {
+				t.Errorf("expected %v, got %v", tt.names, names)
+			}
+		})
+	}
+}
+
+func TestCatalogExport(t *testing.T) {
+	c := NewCatalog(nil)
+	c.Observe("out1", "sub1", nil, []*EventMsg{
+		{Name: "cpu", Tags: map[string]string{"source": "r1"}, Values: map[string]interface{}{"/system/cpu/total": 1.5}},
+		{Name: "cpu", Tags: map[string]string{"source": "r2"}, Values: map[string]interface{}{"/system/cpu/total": "n/a"}},
+	})
+	entries := c.Entries("", "")
+
+	b, err := json.Marshal(CatalogJSONSchema(entries))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Defs map[string]struct {
+			Properties struct {
+				Values struct {
+					Properties map[string]struct {
+						Type []string `json:"type"`
+					} `json:"properties"`
+				} `json:"values"`
+			} `json:"properties"`
+		} `json:"$defs"`
+		AnyOf []map[string]string `json:"anyOf"`
+	}
+	if err = json.Unmarshal(b, &schema); err != nil {
+		t.Fatal(err)
+	}
+	def, ok := schema.Defs["out1/cpu"]
+	if !ok {
+		t.Fatalf("missing definition: %s", b)
+	}
+	if types := def.Properties.Values.Properties["/system/cpu/total"].Type; !reflect.DeepEqual(types, []string{"number", "string"}) {
+		t.Errorf("unexpected value types: %v", types)
+	}
+	if len(schema.AnyOf) != 1 || schema.AnyOf[0]["$ref"] != "#/$defs/out1~1cpu" {
+		t.Errorf("unexpected refs: %v", schema.AnyOf)
+	}
+
+	md := CatalogMarkdown(entries)
+	for _, s := range []string{"## Output `out1`", "### `cpu`", "| `/system/cpu/total` | number, string |", "| `source` | 2 |"} {
+		if !strings.Contains(md, s) {
+			t.Errorf("expected %q in markdown:\n%s", s, md)
+		}
+	}
+}
+
+func TestHyperLogLog(t *testing.T) {
+	seed := maphash.MakeSeed()
+	for _, n := range []int{0, 10, 1000, 50000} {
+		h := newHyperLogLog(catalogHLLPrecision)
+		for i := 0; i < n; i++ {
+			// values are added twice, duplicates are not counted
+			h.add(maphash.String(seed, fmt.Sprint(i)))
+			h.add(maphash.String(seed, fmt.Sprint(i)))
+		}
+		got := float64(h.count())
+		if got < 0.9*float64(n) || got > 1.1*float64(n) {
+			t.Errorf("estimated %v distinct values, expected %d", got, n)
+		}
+	}
+}
@@ -16,6 +16,8 @@ import (
 
 	flattener "github.com/karimra/go-map-flattener"
 	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/extensions"
 )
 
 // EventMsg represents a gNMI update message,
@@ -39,29 +41,64 @@ func ResponseToEventMsgs(name string, rsp *gnmi.SubscribeResponse, meta map[stri
 	if rsp == nil {
 		return nil, nil
 	}
-	evs := make([]*EventMsg, 0, len(rsp.GetUpdate().GetUpdate())+len(rsp.GetUpdate().GetDelete()))
-	switch rsp := rsp.Response.(type) {
-	case *gnmi.SubscribeResponse_Update:
-		namePrefix, prefixTags := tagsFromGNMIPath(rsp.Update.GetPrefix())
-		// notification updates
-		uevs, err := updatesToEvent(name, namePrefix, rsp.Update.GetTimestamp(), rsp.Update.GetUpdate(), prefixTags, meta)
-		if err != nil {
-			return nil, err
-		}
-		evs = append(evs, uevs...)
-		// notification deletes
-		for _, del := range rsp.Update.GetDelete() {
-			e := deleteToEvent(name, namePrefix, rsp.Update.GetTimestamp(), del, prefixTags)
-			addMetaTags(e, meta)
-			if (e != nil && e != &EventMsg{}) {
-				evs = append(evs, e)
-			}
-		}
+	if rsp.GetUpdate() == nil {
+		return []*EventMsg{}, nil
+	}
+	evs, err := responseEvents(name, rsp, meta)
+	if err != nil {
+		return nil, err
+	}
+	// the events are traced by the processors chain.
+	c := GetCatalog()
+	var paths map[*EventMsg][]string
+	if c != nil {
+		paths = sourcePaths(evs)
+	}
+	for _, ep := range eps {
+		evs = ep.Apply(evs...)
+	}
+	if c != nil {
+		c.Observe(ChainOutput(eps), name, paths, evs)
+	}
+	return evs, nil
+}
+
+// DryRunResponseToEventMsgs is ResponseToEventMsgs for a dry run of the processors eps,
+// the events are not recorded by the tracer nor the catalog.
+func DryRunResponseToEventMsgs(name string, rsp *gnmi.SubscribeResponse, meta map[string]string, eps ...EventProcessor) ([]*EventMsg, error) {
+	if rsp.GetUpdate() == nil {
+		return nil, nil
+	}
+	evs, err := responseEvents(name, rsp, meta)
+	if err != nil {
+		return nil, err
+	}
+	for _, ep := range eps {
+		evs = ep.Apply(evs...)
+	}
+	return evs, nil
+}
 
-		for _, ep := range eps {
-			evs = ep.Apply(evs...)
+// responseEvents converts the notification of a subscribe response to events.
+func responseEvents(name string, rsp *gnmi.SubscribeResponse, meta map[string]string) ([]*EventMsg, error) {
+	n := rsp.GetUpdate()
+	evs := make([]*EventMsg, 0, len(n.GetUpdate())+len(n.GetDelete()))
+	namePrefix, prefixTags := tagsFromGNMIPath(n.GetPrefix())
+	// notification updates
+	uevs, err := updatesToEvent(name, namePrefix, n.GetTimestamp(), n.GetUpdate(), prefixTags, meta)
+	if err != nil {
+		return nil, err
+	}
+	evs = append(evs, uevs...)
+	// notification deletes
+	for _, del := range n.GetDelete() {
+		e := deleteToEvent(name, namePrefix, n.GetTimestamp(), del, prefixTags)
+		addMetaTags(e, meta)
+		if (e != nil && e != &EventMsg{}) {
+			evs = append(evs, e)
 		}
 	}
+	addExtensionsTags(evs, extensions.GetRegistry().Tags(rsp.GetExtension()))
 	return evs, nil
 }
 
@@ -78,6 +115,7 @@ func GetResponseToEventMsgs(rsp *gnmi.GetResponse, meta map[string]string, eps .
 		}
 		evs = append(evs, uevs...)
 	}
+	addExtensionsTags(evs, extensions.GetRegistry().Tags(rsp.GetExtension()))
 	for _, ep := range eps {
 		evs = ep.Apply(evs...)
 	}
@@ -395,6 +433,21 @@ func num64(n interface{}) interface{} {
 	return nil
 }
 
+// addExtensionsTags adds the decoded registered extensions fields to the events tags.
+func addExtensionsTags(evs []*EventMsg, tags map[string]string) {
+	if len(tags) == 0 {
+		return
+	}
+	for _, e := range evs {
+		if e.Tags == nil {
+			e.Tags = make(map[string]string, len(tags))
+		}
+		for k, v := range tags {
+			e.Tags[k] = v
+		}
+	}
+}
+
 func addMetaTags(e *EventMsg, meta map[string]string) {
 	for k, v := range meta {
 		if k == "format" {
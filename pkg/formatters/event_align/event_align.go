@@ -0,0 +1,475 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_align
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	processorType = "event-align"
+	loggingPrefix = "[" + processorType + "] "
+
+	policyRound = "round"
+	policyFloor = "floor"
+	policyCeil  = "ceil"
+
+	modeNone     = "none"
+	modeLast     = "last"
+	modeLinear   = "linear"
+	modePrevious = "previous"
+	modeValue    = "value"
+
+	defaultGapTag         = "gap"
+	defaultMaxGapInterval = 10
+	defaultExpiration     = time.Hour
+)
+
+// align snaps the events timestamps to interval boundaries,
+// optionally resamples the values and fills the gaps between samples.
+type align struct {
+	ValueNames []string      `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	Interval   time.Duration `mapstructure:"interval,omitempty" json:"interval,omitempty"`
+	Policy     string        `mapstructure:"policy,omitempty" json:"policy,omitempty"`
+	Resample   string        `mapstructure:"resample,omitempty" json:"resample,omitempty"`
+	Fill       string        `mapstructure:"fill,omitempty" json:"fill,omitempty"`
+	FillValue  interface{}   `mapstructure:"fill-value,omitempty" json:"fill-value,omitempty"`
+	MaxGap     time.Duration `mapstructure:"max-gap,omitempty" json:"max-gap,omitempty"`
+	GapEvents  bool          `mapstructure:"gap-events,omitempty" json:"gap-events,omitempty"`
+	GapTag     string        `mapstructure:"gap-tag,omitempty" json:"gap-tag,omitempty"`
+	Expiration time.Duration `mapstructure:"expiration,omitempty" json:"expiration,omitempty"`
+	Debug      bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	valueNames []*regexp.Regexp
+	interval   int64
+
+	m           sync.Mutex
+	series      map[string]*series
+	lastCleanup time.Time
+
+	now    func() time.Time
+	logger *log.Logger
+}
+
+// series is the state of a single value of an event:
+// the last sample, the last emitted boundary
+// and the last boundary reported missing by a flush.
+type series struct {
+	name      string
+	valueName string
+	tags      map[string]string
+
+	ts       int64
+	value    interface{}
+	boundary int64
+	reported int64
+	seen     time.Time
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &align{
+			now:    time.Now,
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (p *align) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.Interval <= 0 {
+		return fmt.Errorf("%s: missing interval", processorType)
+	}
+	p.interval = p.Interval.Nanoseconds()
+	p.valueNames = make([]*regexp.Regexp, 0, len(p.ValueNames))
+	for _, reg := range p.ValueNames {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		p.valueNames = append(p.valueNames, re)
+	}
+	switch p.Policy {
+	case "":
+		p.Policy = policyRound
+	case policyRound, policyFloor, policyCeil:
+	default:
+		return fmt.Errorf("%s: unknown policy %q", processorType, p.Policy)
+	}
+	switch p.Resample {
+	case "":
+		p.Resample = modeNone
+	case modeNone, modeLast, modeLinear:
+	default:
+		return fmt.Errorf("%s: unknown resample mode %q", processorType, p.Resample)
+	}
+	switch p.Fill {
+	case "":
+		p.Fill = modeNone
+	case modeNone, modePrevious, modeLinear:
+	case modeValue:
+		if p.FillValue == nil {
+			return fmt.Errorf("%s: fill mode %q requires a fill-value", processorType, p.Fill)
+		}
+	default:
+		return fmt.Errorf("%s: unknown fill mode %q", processorType, p.Fill)
+	}
+	if p.MaxGap <= 0 {
+		p.MaxGap = defaultMaxGapInterval * p.Interval
+	}
+	if p.MaxGap < p.Interval {
+		return fmt.Errorf("%s: max-gap %s must be greater than the interval %s", processorType, p.MaxGap, p.Interval)
+	}
+	if p.GapTag == "" {
+		p.GapTag = defaultGapTag
+	}
+	if p.Expiration <= 0 {
+		p.Expiration = defaultExpiration
+	}
+	p.series = make(map[string]*series)
+
+	if p.logger.Writer() != io.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *align) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	now := p.now()
+	expired := newMerger()
+	p.expire(now, expired)
+	result := make([]*formatters.EventMsg, 0, len(es))
+	result = append(result, expired.events()...)
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		if e.Timestamp <= 0 {
+			result = append(result, e)
+			continue
+		}
+		gen := newMerger()
+		matched := false
+		for k, v := range e.Values {
+			if !p.matchValueName(k) {
+				continue
+			}
+			matched = true
+			s := p.getSeries(e, k, now)
+			if p.Resample == modeNone {
+				if !p.align(s, e.Timestamp, v, gen) {
+					// the value would duplicate or precede
+					// an already aligned point.
+					delete(e.Values, k)
+				}
+				continue
+			}
+			p.resample(s, e.Timestamp, v, gen)
+			delete(e.Values, k)
+		}
+		result = append(result, gen.events()...)
+		switch {
+		case !matched:
+			result = append(result, e)
+		case len(e.Values) == 0 && len(e.Deletes) == 0:
+			// the event is passed through only if it has values
+			// other than the resampled or dropped ones.
+		case p.Resample == modeNone:
+			e.Timestamp = p.snap(e.Timestamp)
+			result = append(result, e)
+		default:
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Flush emits the gaps of the series not updated for max-gap
+// and the final gaps of the expired series.
+func (p *align) Flush(now time.Time) []*formatters.EventMsg {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	gen := newMerger()
+	p.expire(now, gen)
+	for _, s := range p.series {
+		p.dueGap(s, now.UnixNano(), gen)
+	}
+	return gen.events()
+}
+
+func (p *align) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (p *align) WithTargets(tcs map[string]*types.TargetConfig) {}
+
+func (p *align) WithActions(act map[string]map[string]interface{}) {}
+
+func (p *align) WithProcessors(procs map[string]map[string]any) {}
+
+// align snaps the sample timestamp and fills the missing
+// boundaries since the previous sample of the series.
+// It reports false if the sample snaps at or before
+// the last aligned boundary of the series.
+func (p *align) align(s *series, ts int64, v interface{}, gen *merger) bool {
+	b := p.snap(ts)
+	if b <= s.boundary {
+		// out of order or duplicate sample
+		return false
+	}
+	if s.boundary == 0 {
+		// first sample
+		s.boundary, s.ts, s.value = b, ts, v
+		return true
+	}
+	// the boundaries reported missing by a flush are not emitted again.
+	start := max(s.boundary, s.reported) + p.interval
+	gap := b - s.boundary
+	if gap > p.MaxGap.Nanoseconds() {
+		p.gap(s, start, b-p.interval, gen)
+	} else if p.Fill != modeNone {
+		for fb := start; fb < b; fb += p.interval {
+			gen.add(s, fb, p.fillValue(s.boundary, s.value, b, v, fb))
+		}
+	}
+	s.boundary, s.ts, s.value = b, ts, v
+	return true
+}
+
+// resample emits the series value at each boundary between
+// the previous sample and the current one.
+func (p *align) resample(s *series, ts int64, v interface{}, gen *merger) {
+	if s.ts != 0 && ts <= s.ts {
+		// out of order sample
+		return
+	}
+	if s.ts != 0 && ts-s.ts > p.MaxGap.Nanoseconds() {
+		p.gap(s, max(s.boundary, s.reported)+p.interval, ceil(ts, p.interval)-p.interval, gen)
+		s.ts = 0
+	}
+	if s.ts == 0 {
+		// first sample of the series
+		s.boundary = floor(ts, p.interval)
+		if s.boundary == ts {
+			gen.add(s, ts, v)
+		}
+		s.ts, s.value = ts, v
+		return
+	}
+	for b := s.boundary + p.interval; b <= ts; b += p.interval {
+		var bv interface{}
+		switch {
+		case b == ts:
+			bv = v
+		case p.Resample == modeLinear:
+			bv = interpolate(s.ts, s.value, ts, v, b)
+		default:
+			bv = s.value
+		}
+		gen.add(s, b, bv)
+		s.boundary = b
+	}
+	s.ts, s.value = ts, v
+}
+
+// gap emits a gap event for the missing boundaries from start to end.
+func (p *align) gap(s *series, start, end int64, gen *merger) {
+	if end < start {
+		return
+	}
+	p.logger.Printf("series %s %q: gap from %d to %d", s.name, s.valueName, start, end)
+	if !p.GapEvents {
+		return
+	}
+	tags := make(map[string]string, len(s.tags)+1)
+	for k, v := range s.tags {
+		tags[k] = v
+	}
+	tags[p.GapTag] = s.valueName
+	gen.gaps = append(gen.gaps, &formatters.EventMsg{
+		Name:      s.name,
+		Timestamp: start,
+		Tags:      tags,
+		Values: map[string]interface{}{
+			"start":   start,
+			"end":     end,
+			"missing": (end-start)/p.interval + 1,
+		},
+	})
+}
+
+func (p *align) fillValue(t0 int64, v0 interface{}, t1 int64, v1 interface{}, b int64) interface{} {
+	switch p.Fill {
+	case modeLinear:
+		return interpolate(t0, v0, t1, v1, b)
+	case modeValue:
+		return p.FillValue
+	}
+	return v0
+}
+
+func (p *align) snap(ts int64) int64 {
+	switch p.Policy {
+	case policyFloor:
+		return floor(ts, p.interval)
+	case policyCeil:
+		return ceil(ts, p.interval)
+	}
+	return floor(ts+p.interval/2, p.interval)
+}
+
+func (p *align) getSeries(e *formatters.EventMsg, valueName string, now time.Time) *series {
+	key := formatters.SeriesKey(e.Name, valueName, e.Tags)
+	s, ok := p.series[key]
+	if !ok {
+		tags := make(map[string]string, len(e.Tags))
+		for k, v := range e.Tags {
+			tags[k] = v
+		}
+		s = &series{name: e.Name, valueName: valueName, tags: tags}
+		p.series[key] = s
+	}
+	s.seen = now
+	return s
+}
+
+// dueGap reports the boundaries missing up to now
+// if the series was not updated for max-gap.
+func (p *align) dueGap(s *series, now int64, gen *merger) {
+	if s.ts == 0 || now-max(s.ts, s.reported) <= p.MaxGap.Nanoseconds() {
+		return
+	}
+	end := floor(now, p.interval)
+	p.gap(s, max(s.boundary, s.reported)+p.interval, end, gen)
+	s.reported = end
+}
+
+// expire deletes the series not updated for the expiration duration,
+// after reporting the boundaries missing since their last sample.
+func (p *align) expire(now time.Time, gen *merger) {
+	if now.Sub(p.lastCleanup) < p.Interval {
+		return
+	}
+	p.lastCleanup = now
+	for k, s := range p.series {
+		if now.Sub(s.seen) > p.Expiration {
+			if s.ts != 0 {
+				p.gap(s, max(s.boundary, s.reported)+p.interval, floor(now.UnixNano(), p.interval), gen)
+			}
+			delete(p.series, k)
+		}
+	}
+}
+
+func (p *align) matchValueName(k string) bool {
+	if len(p.valueNames) == 0 {
+		return true
+	}
+	for _, re := range p.valueNames {
+		if re.MatchString(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// merger groups the generated values of the series
+// sharing the same name, tags and timestamp in a single event.
+type merger struct {
+	byKey map[string]*formatters.EventMsg
+	order []*formatters.EventMsg
+	gaps  []*formatters.EventMsg
+}
+
+func newMerger() *merger {
+	return &merger{byKey: make(map[string]*formatters.EventMsg)}
+}
+
+func (m *merger) add(s *series, ts int64, v interface{}) {
+	key := formatters.SeriesKey(s.name, strconv.FormatInt(ts, 10), s.tags)
+	e, ok := m.byKey[key]
+	if !ok {
+		tags := make(map[string]string, len(s.tags))
+		for k, v := range s.tags {
+			tags[k] = v
+		}
+		e = &formatters.EventMsg{
+			Name:      s.name,
+			Timestamp: ts,
+			Tags:      tags,
+			Values:    make(map[string]interface{}),
+		}
+		m.byKey[key] = e
+		m.order = append(m.order, e)
+	}
+	e.Values[s.valueName] = v
+}
+
+func (m *merger) events() []*formatters.EventMsg {
+	es := append(m.gaps, m.order...)
+	sort.SliceStable(es, func(i, j int) bool {
+		return es[i].Timestamp < es[j].Timestamp
+	})
+	return es
+}
+
+func interpolate(t0 int64, v0 interface{}, t1 int64, v1 interface{}, t int64) interface{} {
+	f0, err := formatters.ToFloat(v0)
+	if err != nil {
+		return v0
+	}
+	f1, err := formatters.ToFloat(v1)
+	if err != nil || t1 == t0 {
+		return v0
+	}
+	return f0 + (f1-f0)*float64(t-t0)/float64(t1-t0)
+}
+
+func floor(ts, interval int64) int64 {
+	return ts - ts%interval
+}
+
+func ceil(ts, interval int64) int64 {
+	if ts%interval == 0 {
+		return ts
+	}
+	return floor(ts, interval) + interval
+}
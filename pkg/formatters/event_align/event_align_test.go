@@ -0,0 +1,333 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_align
+
+import (
+	"io"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+func ev(ts int64, tags map[string]string, values map[string]interface{}) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: ts,
+		Tags:      tags,
+		Values:    values,
+	}
+}
+
+var tags = map[string]string{"source": "r1"}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"round": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"interval": "10ns",
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					ev(12, tags, map[string]interface{}{"v": 1}),
+					ev(26, tags, map[string]interface{}{"v": 2}),
+				},
+				output: []*formatters.EventMsg{
+					ev(10, tags, map[string]interface{}{"v": 1}),
+					ev(30, tags, map[string]interface{}{"v": 2}),
+				},
+			},
+		},
+	},
+	"floor_fill_previous": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"interval":    "10ns",
+			"policy":      "floor",
+			"fill":        "previous",
+			"value-names": []string{"^v$"},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					ev(12, tags, map[string]interface{}{"v": 1, "other": "a"}),
+					ev(48, tags, map[string]interface{}{"v": 4}),
+				},
+				output: []*formatters.EventMsg{
+					ev(10, tags, map[string]interface{}{"v": 1, "other": "a"}),
+					ev(20, tags, map[string]interface{}{"v": 1}),
+					ev(30, tags, map[string]interface{}{"v": 1}),
+					ev(40, tags, map[string]interface{}{"v": 4}),
+				},
+			},
+		},
+	},
+	"fill_value": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"interval":   "10ns",
+			"fill":       "value",
+			"fill-value": "missing",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					ev(10, tags, map[string]interface{}{"v": 1}),
+					ev(30, tags, map[string]interface{}{"v": 3}),
+				},
+				output: []*formatters.EventMsg{
+					ev(10, tags, map[string]interface{}{"v": 1}),
+					ev(20, tags, map[string]interface{}{"v": "missing"}),
+					ev(30, tags, map[string]interface{}{"v": 3}),
+				},
+			},
+		},
+	},
+	"gap_event": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"interval":   "10ns",
+			"fill":       "previous",
+			"max-gap":    "20ns",
+			"gap-events": true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					ev(10, tags, map[string]interface{}{"v": 1}),
+					ev(60, tags, map[string]interface{}{"v": 6}),
+				},
+				output: []*formatters.EventMsg{
+					ev(10, tags, map[string]interface{}{"v": 1}),
+					ev(20, map[string]string{"source": "r1", "gap": "v"},
+						map[string]interface{}{"start": int64(20), "end": int64(50), "missing": int64(4)}),
+					ev(60, tags, map[string]interface{}{"v": 6}),
+				},
+			},
+		},
+	},
+	"resample_last": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"interval": "10ns",
+			"resample": "last",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					ev(5, tags, map[string]interface{}{"v": 1}),
+					ev(27, tags, map[string]interface{}{"v": 2}),
+					// out of order
+					ev(26, tags, map[string]interface{}{"v": 3}),
+					ev(30, tags, map[string]interface{}{"v": 4}),
+				},
+				output: []*formatters.EventMsg{
+					ev(10, tags, map[string]interface{}{"v": 1}),
+					ev(20, tags, map[string]interface{}{"v": 1}),
+					ev(30, tags, map[string]interface{}{"v": 4}),
+				},
+			},
+		},
+	},
+	"round_drop_duplicates": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"interval": "10ns",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					ev(12, tags, map[string]interface{}{"v": 1}),
+					// snaps to the same boundary as the previous sample
+					ev(14, tags, map[string]interface{}{"v": 2}),
+					ev(26, tags, map[string]interface{}{"v": 3}),
+					// older than the last aligned boundary
+					ev(18, tags, map[string]interface{}{"v": 4, "other": "a"}),
+				},
+				output: []*formatters.EventMsg{
+					ev(10, tags, map[string]interface{}{"v": 1}),
+					ev(30, tags, map[string]interface{}{"v": 3}),
+					ev(20, tags, map[string]interface{}{"other": "a"}),
+				},
+			},
+		},
+	},
+	"resample_linear": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"interval": "10ns",
+			"resample": "linear",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					ev(0, tags, map[string]interface{}{"v": 0}),
+				},
+				// events without a timestamp are not aligned
+				output: []*formatters.EventMsg{
+					ev(0, tags, map[string]interface{}{"v": 0}),
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					ev(10, tags, map[string]interface{}{"v": 100, "w": "up"}),
+					ev(35, tags, map[string]interface{}{"v": 350, "w": "down"}),
+				},
+				output: []*formatters.EventMsg{
+					ev(10, tags, map[string]interface{}{"v": 100, "w": "up"}),
+					ev(20, tags, map[string]interface{}{"v": 200.0, "w": "up"}),
+					ev(30, tags, map[string]interface{}{"v": 300.0, "w": "up"}),
+				},
+			},
+		},
+	},
+}
+
+func TestEventAlign(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor)
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if !reflect.DeepEqual(outs, item.output) {
+						t.Errorf("failed at %s item %d, expected %+v, got: %+v", name, i, item.output, outs)
+						for _, o := range outs {
+							t.Logf("got: %+v", o)
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventAlignInitErrors(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing_interval": {},
+		"unknown_policy": {
+			"interval": "10s",
+			"policy":   "nearest",
+		},
+		"unknown_resample": {
+			"interval": "10s",
+			"resample": "mean",
+		},
+		"fill_value_without_value": {
+			"interval": "10s",
+			"fill":     "value",
+		},
+		"max_gap_lower_than_interval": {
+			"interval": "10s",
+			"max-gap":  "5s",
+		},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := formatters.EventProcessors[processorType]()
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an init error")
+			}
+		})
+	}
+}
+
+func newTestProcessor(t *testing.T, now *time.Time, cfg map[string]interface{}) *align {
+	t.Helper()
+	p := &align{
+		now:    func() time.Time { return *now },
+		logger: log.New(io.Discard, "", 0),
+	}
+	if err := p.Init(cfg); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func gapEvent(start, end time.Time, missing int64) *formatters.EventMsg {
+	return ev(start.UnixNano(), map[string]string{"source": "r1", "gap": "v"}, map[string]interface{}{
+		"start":   start.UnixNano(),
+		"end":     end.UnixNano(),
+		"missing": missing,
+	})
+}
+
+func TestEventAlignFlush(t *testing.T) {
+	now := time.Unix(1000, 0)
+	p := newTestProcessor(t, &now, map[string]interface{}{
+		"interval":   "10s",
+		"max-gap":    "30s",
+		"gap-events": true,
+	})
+	p.Apply(ev(now.UnixNano(), tags, map[string]interface{}{"v": 1}))
+
+	if res := p.Flush(time.Unix(1020, 0)); len(res) != 0 {
+		t.Fatalf("expected no gap within max-gap, got %v", res)
+	}
+	res := p.Flush(time.Unix(1041, 0))
+	expected := []*formatters.EventMsg{gapEvent(time.Unix(1010, 0), time.Unix(1040, 0), 4)}
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatalf("expected the due gap %v, got %v", expected, res)
+	}
+	if res := p.Flush(time.Unix(1045, 0)); len(res) != 0 {
+		t.Fatalf("expected the gap to be reported once, got %v", res)
+	}
+	// the boundaries already reported are not reported again.
+	now = time.Unix(1050, 0)
+	res = p.Apply(ev(now.UnixNano(), tags, map[string]interface{}{"v": 2}))
+	expected = []*formatters.EventMsg{ev(now.UnixNano(), tags, map[string]interface{}{"v": 2})}
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatalf("expected %v, got %v", expected, res)
+	}
+}
+
+func TestEventAlignExpiration(t *testing.T) {
+	now := time.Unix(1000, 0)
+	p := newTestProcessor(t, &now, map[string]interface{}{
+		"interval":   "10s",
+		"gap-events": true,
+		"expiration": "1m",
+	})
+	p.Apply(ev(now.UnixNano(), tags, map[string]interface{}{"v": 1}))
+
+	res := p.Flush(time.Unix(1120, 0))
+	expected := []*formatters.EventMsg{gapEvent(time.Unix(1010, 0), time.Unix(1120, 0), 12)}
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatalf("expected a final gap %v, got %v", expected, res)
+	}
+	if len(p.series) != 0 {
+		t.Errorf("expected the series to expire, got %v", p.series)
+	}
+}
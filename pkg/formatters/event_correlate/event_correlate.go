@@ -0,0 +1,769 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_correlate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itchyny/gojq"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	processorType = "event-correlate"
+	loggingPrefix = "[" + processorType + "] "
+
+	relationSameTag     = "same-tag"
+	relationAdjacency   = "adjacency"
+	relationParentChild = "parent-child"
+
+	stateOpen   = "open"
+	stateUpdate = "update"
+	stateClear  = "clear"
+
+	reasonRecovered = "recovered"
+	reasonExpired   = "expired"
+
+	memberActive  = "active"
+	memberCleared = "cleared"
+
+	defaultTargetTag        = "source"
+	defaultIncidentName     = "incident"
+	defaultWindow           = 30 * time.Second
+	defaultExpiration       = time.Hour
+	defaultInterfaceField   = "interface_name"
+	defaultNeighborField    = "system-name"
+	defaultNeighborIntField = "port-id"
+
+	// minimum period between two checks of the learned links expiration.
+	linksExpirationPeriod = time.Second
+)
+
+// correlate groups related events from one or more targets
+// into incidents, emitted as a single event per incident state change.
+type correlate struct {
+	Events       map[string]*eventConfig `mapstructure:"events,omitempty" json:"events,omitempty"`
+	Rules        []*ruleConfig           `mapstructure:"rules,omitempty" json:"rules,omitempty"`
+	TargetTag    string                  `mapstructure:"target-tag,omitempty" json:"target-tag,omitempty"`
+	Window       time.Duration           `mapstructure:"window,omitempty" json:"window,omitempty"`
+	Delay        time.Duration           `mapstructure:"delay,omitempty" json:"delay,omitempty"`
+	ClearDelay   time.Duration           `mapstructure:"clear-delay,omitempty" json:"clear-delay,omitempty"`
+	Expiration   time.Duration           `mapstructure:"expiration,omitempty" json:"expiration,omitempty"`
+	IncidentName string                  `mapstructure:"incident-name,omitempty" json:"incident-name,omitempty"`
+	DropMembers  bool                    `mapstructure:"drop-members,omitempty" json:"drop-members,omitempty"`
+	Debug        bool                    `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	// event names sorted for a deterministic matching order.
+	eventNames []string
+	levels     map[string]int
+
+	m         sync.Mutex
+	members   map[string]*member
+	incidents []*incident
+	// learned links indexed by local target and interface.
+	links        map[string]map[string]*link
+	linksChecked time.Time
+
+	now    func() time.Time
+	logger *log.Logger
+}
+
+// eventConfig defines an incident member event:
+// the events matching condition raise a member,
+// the events matching clear-condition clear it.
+type eventConfig struct {
+	Condition      string   `mapstructure:"condition,omitempty" json:"condition,omitempty"`
+	ClearCondition string   `mapstructure:"clear-condition,omitempty" json:"clear-condition,omitempty"`
+	KeyTags        []string `mapstructure:"key-tags,omitempty" json:"key-tags,omitempty"`
+
+	code      *gojq.Code
+	clearCode *gojq.Code
+}
+
+// ruleConfig defines when two members belong to the same incident.
+type ruleConfig struct {
+	Relation string   `mapstructure:"relation,omitempty" json:"relation,omitempty"`
+	Events   []string `mapstructure:"events,omitempty" json:"events,omitempty"`
+	// same-tag
+	Tags []string `mapstructure:"tags,omitempty" json:"tags,omitempty"`
+	// adjacency
+	InterfaceTag     string      `mapstructure:"interface-tag,omitempty" json:"interface-tag,omitempty"`
+	PeerTag          string      `mapstructure:"peer-tag,omitempty" json:"peer-tag,omitempty"`
+	PeerInterfaceTag string      `mapstructure:"peer-interface-tag,omitempty" json:"peer-interface-tag,omitempty"`
+	LLDP             *lldpConfig `mapstructure:"lldp,omitempty" json:"lldp,omitempty"`
+	// parent-child
+	Parent     string `mapstructure:"parent,omitempty" json:"parent,omitempty"`
+	Child      string `mapstructure:"child,omitempty" json:"child,omitempty"`
+	SameTarget *bool  `mapstructure:"same-target,omitempty" json:"same-target,omitempty"`
+}
+
+// lldpConfig selects the events the links between targets are learned from.
+type lldpConfig struct {
+	Condition         string `mapstructure:"condition,omitempty" json:"condition,omitempty"`
+	Interface         string `mapstructure:"interface,omitempty" json:"interface,omitempty"`
+	Neighbor          string `mapstructure:"neighbor,omitempty" json:"neighbor,omitempty"`
+	NeighborInterface string `mapstructure:"neighbor-interface,omitempty" json:"neighbor-interface,omitempty"`
+
+	code *gojq.Code
+}
+
+// member is an event raised by a target object, e.g a link down on an interface.
+type member struct {
+	key    string
+	target string
+	event  string
+	keys   map[string]string
+	tags   map[string]string
+
+	ts       int64
+	cleared  bool
+	incident *incident
+}
+
+type incident struct {
+	id        string
+	created   time.Time
+	updated   time.Time
+	clearedAt time.Time
+	members   []*member
+	emitted   bool
+	changed   bool
+}
+
+type endpoint struct {
+	target string
+	iface  string
+}
+
+type link struct {
+	peer endpoint
+	seen time.Time
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &correlate{
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (p *correlate) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if len(p.Events) == 0 {
+		return fmt.Errorf("%s: missing events definitions", processorType)
+	}
+	p.eventNames = make([]string, 0, len(p.Events))
+	for n, ec := range p.Events {
+		if ec == nil {
+			return fmt.Errorf("%s: event %q: missing definition", processorType, n)
+		}
+		ec.code, err = compileCondition(ec.Condition)
+		if err != nil {
+			return fmt.Errorf("%s: event %q: condition: %v", processorType, n, err)
+		}
+		if ec.code == nil {
+			return fmt.Errorf("%s: event %q: missing condition", processorType, n)
+		}
+		ec.clearCode, err = compileCondition(ec.ClearCondition)
+		if err != nil {
+			return fmt.Errorf("%s: event %q: clear-condition: %v", processorType, n, err)
+		}
+		p.eventNames = append(p.eventNames, n)
+	}
+	sort.Strings(p.eventNames)
+	for i, r := range p.Rules {
+		if err := p.initRule(r); err != nil {
+			return fmt.Errorf("%s: rule %d: %v", processorType, i, err)
+		}
+	}
+	p.levels, err = p.eventLevels()
+	if err != nil {
+		return fmt.Errorf("%s: %v", processorType, err)
+	}
+	if p.TargetTag == "" {
+		p.TargetTag = defaultTargetTag
+	}
+	if p.IncidentName == "" {
+		p.IncidentName = defaultIncidentName
+	}
+	if p.Window <= 0 {
+		p.Window = defaultWindow
+	}
+	if p.Expiration <= 0 {
+		p.Expiration = defaultExpiration
+	}
+	p.members = make(map[string]*member)
+	p.links = make(map[string]map[string]*link)
+	if p.now == nil {
+		p.now = time.Now
+	}
+
+	if p.logger.Writer() != io.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *correlate) initRule(r *ruleConfig) error {
+	if r == nil {
+		return fmt.Errorf("missing definition")
+	}
+	for _, n := range r.Events {
+		if _, ok := p.Events[n]; !ok {
+			return fmt.Errorf("unknown event %q", n)
+		}
+	}
+	switch r.Relation {
+	case relationSameTag:
+		if len(r.Tags) == 0 {
+			return fmt.Errorf("relation %q requires tags", r.Relation)
+		}
+	case relationAdjacency:
+		if r.InterfaceTag == "" {
+			r.InterfaceTag = defaultInterfaceField
+		}
+		if r.LLDP == nil && r.PeerTag == "" {
+			return fmt.Errorf("relation %q requires lldp or peer-tag", r.Relation)
+		}
+		if r.LLDP != nil {
+			var err error
+			r.LLDP.code, err = compileCondition(r.LLDP.Condition)
+			if err != nil {
+				return fmt.Errorf("lldp condition: %v", err)
+			}
+			if r.LLDP.Interface == "" {
+				r.LLDP.Interface = defaultInterfaceField
+			}
+			if r.LLDP.Neighbor == "" {
+				r.LLDP.Neighbor = defaultNeighborField
+			}
+			if r.LLDP.NeighborInterface == "" {
+				r.LLDP.NeighborInterface = defaultNeighborIntField
+			}
+		}
+	case relationParentChild:
+		if _, ok := p.Events[r.Parent]; !ok {
+			return fmt.Errorf("unknown parent event %q", r.Parent)
+		}
+		if _, ok := p.Events[r.Child]; !ok {
+			return fmt.Errorf("unknown child event %q", r.Child)
+		}
+		if r.Parent == r.Child {
+			return fmt.Errorf("event %q cannot be its own parent", r.Parent)
+		}
+		if r.SameTarget == nil {
+			sameTarget := true
+			r.SameTarget = &sameTarget
+		}
+	default:
+		return fmt.Errorf("unknown relation %q, expected %q, %q or %q",
+			r.Relation, relationSameTag, relationAdjacency, relationParentChild)
+	}
+	return nil
+}
+
+// eventLevels returns the depth of each event in the parent-child hierarchy,
+// the root cause of an incident is the member with the lowest level.
+func (p *correlate) eventLevels() (map[string]int, error) {
+	parents := make(map[string][]string)
+	for _, r := range p.Rules {
+		if r.Relation == relationParentChild {
+			parents[r.Child] = append(parents[r.Child], r.Parent)
+		}
+	}
+	levels := make(map[string]int, len(p.Events))
+	visiting := make(map[string]bool)
+	var level func(n string) (int, error)
+	level = func(n string) (int, error) {
+		if l, ok := levels[n]; ok {
+			return l, nil
+		}
+		if visiting[n] {
+			return 0, fmt.Errorf("parent-child cycle involving event %q", n)
+		}
+		visiting[n] = true
+		l := 0
+		for _, pn := range parents[n] {
+			pl, err := level(pn)
+			if err != nil {
+				return 0, err
+			}
+			if pl+1 > l {
+				l = pl + 1
+			}
+		}
+		levels[n] = l
+		return l, nil
+	}
+	for _, n := range p.eventNames {
+		if _, err := level(n); err != nil {
+			return nil, err
+		}
+	}
+	return levels, nil
+}
+
+func (p *correlate) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	now := p.now()
+	result := make([]*formatters.EventMsg, 0, len(es))
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		p.learnLinks(e, now)
+		name, clear := p.matchEvent(e)
+		if name == "" {
+			result = append(result, e)
+			continue
+		}
+		if clear {
+			p.clearMember(e, name, now)
+		} else {
+			p.raiseMember(e, name, now)
+		}
+		if !p.DropMembers {
+			result = append(result, e)
+		}
+	}
+	return append(result, p.flush(now)...)
+}
+
+// Flush returns the incident events due at now, it allows the delays
+// and the expiration to apply when no events are received.
+func (p *correlate) Flush(now time.Time) []*formatters.EventMsg {
+	p.m.Lock()
+	defer p.m.Unlock()
+	return p.flush(now)
+}
+
+func (p *correlate) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (p *correlate) WithTargets(tcs map[string]*types.TargetConfig) {}
+
+func (p *correlate) WithActions(act map[string]map[string]interface{}) {}
+
+func (p *correlate) WithProcessors(procs map[string]map[string]any) {}
+
+// matchEvent returns the name of the member event e matches,
+// and whether it matched its clear condition.
+func (p *correlate) matchEvent(e *formatters.EventMsg) (string, bool) {
+	for _, n := range p.eventNames {
+		ec := p.Events[n]
+		ok, err := formatters.CheckCondition(ec.code, e)
+		if err != nil {
+			p.logger.Printf("event %q: failed to evaluate condition: %v", n, err)
+			continue
+		}
+		if ok {
+			return n, false
+		}
+		if ec.clearCode == nil {
+			continue
+		}
+		ok, err = formatters.CheckCondition(ec.clearCode, e)
+		if err != nil {
+			p.logger.Printf("event %q: failed to evaluate clear-condition: %v", n, err)
+			continue
+		}
+		if ok {
+			return n, true
+		}
+	}
+	return "", false
+}
+
+func (p *correlate) memberKey(e *formatters.EventMsg, name string) (string, map[string]string) {
+	target := normalizeTarget(e.Tags[p.TargetTag])
+	keys := make(map[string]string, len(p.Events[name].KeyTags))
+	sb := new(strings.Builder)
+	sb.WriteString(target)
+	sb.WriteString("\x00")
+	sb.WriteString(name)
+	for _, k := range p.Events[name].KeyTags {
+		keys[k] = e.Tags[k]
+		sb.WriteString("\x00")
+		sb.WriteString(e.Tags[k])
+	}
+	return sb.String(), keys
+}
+
+func (p *correlate) raiseMember(e *formatters.EventMsg, name string, now time.Time) {
+	key, keys := p.memberKey(e, name)
+	if m, ok := p.members[key]; ok {
+		m.incident.updated = now
+		if m.cleared {
+			p.logger.Printf("incident %s: member %q raised again", m.incident.id, key)
+			m.cleared = false
+			m.incident.clearedAt = time.Time{}
+			m.incident.changed = true
+		}
+		return
+	}
+	tags := make(map[string]string, len(e.Tags))
+	for k, v := range e.Tags {
+		tags[k] = v
+	}
+	m := &member{
+		key:    key,
+		target: normalizeTarget(e.Tags[p.TargetTag]),
+		event:  name,
+		keys:   keys,
+		tags:   tags,
+		ts:     e.Timestamp,
+	}
+	p.members[key] = m
+	for _, inc := range p.incidents {
+		if now.Sub(inc.updated) > p.Window || !p.relatesTo(m, inc) {
+			continue
+		}
+		p.logger.Printf("incident %s: adding member %q", inc.id, key)
+		m.incident = inc
+		inc.members = append(inc.members, m)
+		inc.updated = now
+		inc.clearedAt = time.Time{}
+		inc.changed = true
+		return
+	}
+	inc := &incident{
+		id:      uuid.New().String(),
+		created: now,
+		updated: now,
+		members: []*member{m},
+	}
+	p.logger.Printf("incident %s: new incident for member %q", inc.id, key)
+	m.incident = inc
+	p.incidents = append(p.incidents, inc)
+}
+
+func (p *correlate) clearMember(e *formatters.EventMsg, name string, now time.Time) {
+	key, _ := p.memberKey(e, name)
+	m, ok := p.members[key]
+	if !ok || m.cleared {
+		return
+	}
+	p.logger.Printf("incident %s: member %q cleared", m.incident.id, key)
+	m.cleared = true
+	m.incident.updated = now
+	m.incident.changed = true
+	for _, om := range m.incident.members {
+		if !om.cleared {
+			return
+		}
+	}
+	m.incident.clearedAt = now
+}
+
+// flush returns the incident events due: the new incidents older than the delay,
+// the updated ones and the cleared or expired ones, which are deleted.
+func (p *correlate) flush(now time.Time) []*formatters.EventMsg {
+	p.expireLinks(now)
+	var res []*formatters.EventMsg
+	incidents := p.incidents[:0]
+	for _, inc := range p.incidents {
+		var reason string
+		switch {
+		case !inc.clearedAt.IsZero() && now.Sub(inc.clearedAt) >= p.ClearDelay:
+			reason = reasonRecovered
+		case now.Sub(inc.updated) > p.Expiration:
+			reason = reasonExpired
+		}
+		if reason != "" {
+			p.logger.Printf("incident %s: cleared: %s", inc.id, reason)
+			if inc.emitted {
+				res = append(res, p.incidentEvent(inc, stateClear, reason, now))
+			}
+			for _, m := range inc.members {
+				delete(p.members, m.key)
+			}
+			continue
+		}
+		incidents = append(incidents, inc)
+		switch {
+		case !inc.emitted && now.Sub(inc.created) >= p.Delay:
+			res = append(res, p.incidentEvent(inc, stateOpen, "", now))
+			inc.emitted = true
+			inc.changed = false
+		case inc.emitted && inc.changed:
+			res = append(res, p.incidentEvent(inc, stateUpdate, "", now))
+			inc.changed = false
+		}
+	}
+	for i := len(incidents); i < len(p.incidents); i++ {
+		p.incidents[i] = nil
+	}
+	p.incidents = incidents
+	return res
+}
+
+func (p *correlate) incidentEvent(inc *incident, state, reason string, now time.Time) *formatters.EventMsg {
+	root := p.rootCause(inc)
+	tags := map[string]string{
+		"incident_id":       inc.id,
+		"incident_state":    state,
+		"root_cause_target": root.target,
+		"root_cause_event":  root.event,
+	}
+	for k, v := range root.keys {
+		tags["root_cause_"+k] = v
+	}
+	if reason != "" {
+		tags["incident_clear_reason"] = reason
+	}
+	members := make([]interface{}, 0, len(inc.members))
+	targets := make(map[string]struct{})
+	active := 0
+	for _, m := range inc.members {
+		ms := memberActive
+		if m.cleared {
+			ms = memberCleared
+		} else {
+			active++
+		}
+		targets[m.target] = struct{}{}
+		keys := make(map[string]interface{}, len(m.keys))
+		for k, v := range m.keys {
+			keys[k] = v
+		}
+		members = append(members, map[string]interface{}{
+			"target":    m.target,
+			"event":     m.event,
+			"keys":      keys,
+			"state":     ms,
+			"timestamp": m.ts,
+		})
+	}
+	return &formatters.EventMsg{
+		Name:      p.IncidentName,
+		Timestamp: now.UnixNano(),
+		Tags:      tags,
+		Values: map[string]interface{}{
+			"members":        members,
+			"member_count":   len(inc.members),
+			"active_members": active,
+			"target_count":   len(targets),
+			"start":          inc.created.UnixNano(),
+			"duration":       now.Sub(inc.created).Nanoseconds(),
+		},
+	}
+}
+
+// rootCause returns the root cause candidate of an incident: the member
+// highest in the parent-child hierarchy, the earliest first.
+func (p *correlate) rootCause(inc *incident) *member {
+	root := inc.members[0]
+	for _, m := range inc.members[1:] {
+		lm, lr := p.levels[m.event], p.levels[root.event]
+		switch {
+		case lm < lr:
+			root = m
+		case lm == lr && m.ts < root.ts:
+			root = m
+		}
+	}
+	return root
+}
+
+func (p *correlate) relatesTo(m *member, inc *incident) bool {
+	for _, om := range inc.members {
+		for _, r := range p.Rules {
+			if p.related(r, m, om) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *correlate) related(r *ruleConfig, a, b *member) bool {
+	if len(r.Events) > 0 && (!strInList(a.event, r.Events) || !strInList(b.event, r.Events)) {
+		return false
+	}
+	switch r.Relation {
+	case relationSameTag:
+		for _, t := range r.Tags {
+			if a.tags[t] == "" || a.tags[t] != b.tags[t] {
+				return false
+			}
+		}
+		return true
+	case relationAdjacency:
+		if a.target == b.target {
+			return false
+		}
+		return p.peers(r, a, b) || p.peers(r, b, a) || p.linked(r, a, b)
+	case relationParentChild:
+		if !(a.event == r.Parent && b.event == r.Child) && !(a.event == r.Child && b.event == r.Parent) {
+			return false
+		}
+		return !*r.SameTarget || a.target == b.target
+	}
+	return false
+}
+
+// peers reports whether the peer tags of a point to b.
+func (p *correlate) peers(r *ruleConfig, a, b *member) bool {
+	if r.PeerTag == "" || normalizeTarget(a.tags[r.PeerTag]) != b.target || b.target == "" {
+		return false
+	}
+	pi, bi := a.tags[r.PeerInterfaceTag], b.tags[r.InterfaceTag]
+	return r.PeerInterfaceTag == "" || pi == "" || bi == "" || pi == bi
+}
+
+// linked reports whether a link learned from the LLDP events connects a and b.
+func (p *correlate) linked(r *ruleConfig, a, b *member) bool {
+	if r.LLDP == nil {
+		return false
+	}
+	ai, bi := a.tags[r.InterfaceTag], b.tags[r.InterfaceTag]
+	match := func(l *link) bool {
+		return l.peer.target == b.target && (bi == "" || l.peer.iface == bi)
+	}
+	if ai != "" {
+		l, ok := p.links[a.target][ai]
+		return ok && match(l)
+	}
+	for _, l := range p.links[a.target] {
+		if match(l) {
+			return true
+		}
+	}
+	return false
+}
+
+// learnLinks records the links described by e in both directions.
+func (p *correlate) learnLinks(e *formatters.EventMsg, now time.Time) {
+	for _, r := range p.Rules {
+		if r.LLDP == nil {
+			continue
+		}
+		ok, err := formatters.CheckCondition(r.LLDP.code, e)
+		if err != nil || !ok {
+			continue
+		}
+		neighbor := normalizeTarget(field(e, r.LLDP.Neighbor))
+		if neighbor == "" {
+			continue
+		}
+		local := endpoint{target: normalizeTarget(e.Tags[p.TargetTag]), iface: field(e, r.LLDP.Interface)}
+		remote := endpoint{target: neighbor, iface: field(e, r.LLDP.NeighborInterface)}
+		p.setLink(local, remote, now)
+		p.setLink(remote, local, now)
+	}
+}
+
+func (p *correlate) setLink(local, remote endpoint, now time.Time) {
+	if p.links[local.target] == nil {
+		p.links[local.target] = make(map[string]*link)
+	}
+	p.links[local.target][local.iface] = &link{peer: remote, seen: now}
+}
+
+// expireLinks deletes the links not seen during the expiration,
+// at most once per linksExpirationPeriod.
+func (p *correlate) expireLinks(now time.Time) {
+	if now.Sub(p.linksChecked) < linksExpirationPeriod {
+		return
+	}
+	p.linksChecked = now
+	for target, ifaces := range p.links {
+		for iface, l := range ifaces {
+			if now.Sub(l.seen) > p.Expiration {
+				delete(ifaces, iface)
+			}
+		}
+		if len(ifaces) == 0 {
+			delete(p.links, target)
+		}
+	}
+}
+
+// field returns the value of a tag or of a value of e,
+// the values are matched by their full name or their last path element.
+func field(e *formatters.EventMsg, name string) string {
+	if v, ok := e.Tags[name]; ok {
+		return v
+	}
+	if v, ok := e.Values[name]; ok {
+		return fmt.Sprint(v)
+	}
+	for k, v := range e.Values {
+		if strings.HasSuffix(k, "/"+name) {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+// normalizeTarget strips the port number and the domain name
+// from a target, so that LLDP system names match target names.
+func normalizeTarget(t string) string {
+	t = utils.GetHost(t)
+	if net.ParseIP(t) != nil {
+		return t
+	}
+	if i := strings.Index(t, "."); i > 0 {
+		return t[:i]
+	}
+	return t
+}
+
+func compileCondition(c string) (*gojq.Code, error) {
+	c = strings.TrimSpace(c)
+	if c == "" {
+		return nil, nil
+	}
+	q, err := gojq.Parse(c)
+	if err != nil {
+		return nil, err
+	}
+	return gojq.Compile(q)
+}
+
+func strInList(s string, l []string) bool {
+	for _, ls := range l {
+		if ls == s {
+			return true
+		}
+	}
+	return false
+}
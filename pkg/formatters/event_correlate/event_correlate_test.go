@@ -0,0 +1,358 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_correlate
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time { return c.t }
+
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+var testConfig = map[string]interface{}{
+	"events": map[string]interface{}{
+		"link-down": map[string]interface{}{
+			"condition":       `.name == "interface" and .values["oper-status"] == "DOWN"`,
+			"clear-condition": `.name == "interface" and .values["oper-status"] == "UP"`,
+			"key-tags":        []string{"interface_name"},
+		},
+		"bgp-down": map[string]interface{}{
+			"condition":       `.name == "bgp" and .values["session-state"] != "ESTABLISHED"`,
+			"clear-condition": `.name == "bgp" and .values["session-state"] == "ESTABLISHED"`,
+			"key-tags":        []string{"neighbor"},
+		},
+	},
+	"rules": []interface{}{
+		map[string]interface{}{
+			"relation": "adjacency",
+			"lldp": map[string]interface{}{
+				"condition": `.name == "lldp"`,
+			},
+		},
+		map[string]interface{}{
+			"relation": "parent-child",
+			"parent":   "link-down",
+			"child":    "bgp-down",
+		},
+	},
+}
+
+func newTestProcessor(t *testing.T, cfg map[string]interface{}) (*correlate, *clock) {
+	t.Helper()
+	c := &clock{t: time.Unix(1000, 0)}
+	p := &correlate{now: c.now, logger: log.New(io.Discard, "", 0)}
+	err := p.Init(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, c
+}
+
+func withConfig(kvs ...interface{}) map[string]interface{} {
+	cfg := make(map[string]interface{}, len(testConfig)+len(kvs)/2)
+	for k, v := range testConfig {
+		cfg[k] = v
+	}
+	for i := 0; i+1 < len(kvs); i += 2 {
+		cfg[kvs[i].(string)] = kvs[i+1]
+	}
+	return cfg
+}
+
+func lldp(source, iface, neighbor, neighborIface string) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name: "lldp",
+		Tags: map[string]string{"source": source, "interface_name": iface},
+		Values: map[string]interface{}{
+			"/lldp/interfaces/interface/neighbors/neighbor/state/system-name": neighbor,
+			"/lldp/interfaces/interface/neighbors/neighbor/state/port-id":     neighborIface,
+		},
+	}
+}
+
+func intf(ts int64, source, iface, status string) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      "interface",
+		Timestamp: ts,
+		Tags:      map[string]string{"source": source, "interface_name": iface},
+		Values:    map[string]interface{}{"oper-status": status},
+	}
+}
+
+func bgp(ts int64, source, neighbor, state string) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      "bgp",
+		Timestamp: ts,
+		Tags:      map[string]string{"source": source, "neighbor": neighbor},
+		Values:    map[string]interface{}{"session-state": state},
+	}
+}
+
+func incidents(es []*formatters.EventMsg) []*formatters.EventMsg {
+	res := make([]*formatters.EventMsg, 0)
+	for _, e := range es {
+		if e.Name == defaultIncidentName {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+func TestFibreCut(t *testing.T) {
+	p, c := newTestProcessor(t, testConfig)
+	p.Apply(lldp("r1:57400", "ethernet-1/1", "r2.example.com", "ethernet-1/3"))
+
+	res := p.Apply(
+		intf(20, "r2:57400", "ethernet-1/3", "DOWN"),
+		intf(10, "r1:57400", "ethernet-1/1", "DOWN"),
+		bgp(30, "r1:57400", "10.0.0.2", "IDLE"),
+		bgp(30, "r2:57400", "10.0.0.1", "ACTIVE"),
+	)
+	if len(res) != 5 {
+		t.Fatalf("expected the 4 member events and an incident, got %d events", len(res))
+	}
+	incs := incidents(res)
+	if len(incs) != 1 {
+		t.Fatalf("expected 1 incident, got %d", len(incs))
+	}
+	inc := incs[0]
+	if inc.Tags["incident_state"] != stateOpen {
+		t.Errorf("expected incident state %q, got %q", stateOpen, inc.Tags["incident_state"])
+	}
+	if inc.Tags["root_cause_target"] != "r1" || inc.Tags["root_cause_event"] != "link-down" ||
+		inc.Tags["root_cause_interface_name"] != "ethernet-1/1" {
+		t.Errorf("unexpected root cause: %v", inc.Tags)
+	}
+	if inc.Values["member_count"] != 4 || inc.Values["target_count"] != 2 {
+		t.Errorf("unexpected incident values: %v", inc.Values)
+	}
+	id := inc.Tags["incident_id"]
+
+	// repeated samples of the active members do not update the incident.
+	c.advance(time.Second)
+	if res := incidents(p.Apply(intf(40, "r1:57400", "ethernet-1/1", "DOWN"))); len(res) != 0 {
+		t.Errorf("expected no incident event, got %v", res)
+	}
+
+	c.advance(time.Second)
+	res = incidents(p.Apply(intf(50, "r1:57400", "ethernet-1/1", "UP")))
+	if len(res) != 1 || res[0].Tags["incident_state"] != stateUpdate || res[0].Values["active_members"] != 3 {
+		t.Fatalf("expected an incident update with 3 active members, got %v", res)
+	}
+
+	c.advance(time.Second)
+	res = incidents(p.Apply(
+		intf(60, "r2:57400", "ethernet-1/3", "UP"),
+		bgp(60, "r1:57400", "10.0.0.2", "ESTABLISHED"),
+		bgp(60, "r2:57400", "10.0.0.1", "ESTABLISHED"),
+	))
+	if len(res) != 1 {
+		t.Fatalf("expected 1 incident event, got %d", len(res))
+	}
+	if res[0].Tags["incident_id"] != id || res[0].Tags["incident_state"] != stateClear ||
+		res[0].Tags["incident_clear_reason"] != reasonRecovered {
+		t.Errorf("expected incident %s to be cleared, got %v", id, res[0].Tags)
+	}
+	if len(p.incidents) != 0 || len(p.members) != 0 {
+		t.Errorf("expected the incident state to be deleted")
+	}
+}
+
+func TestUnrelatedEvents(t *testing.T) {
+	p, _ := newTestProcessor(t, testConfig)
+	res := incidents(p.Apply(
+		intf(10, "r1", "ethernet-1/1", "DOWN"),
+		intf(10, "r3", "ethernet-1/1", "DOWN"),
+	))
+	if len(res) != 2 || res[0].Tags["incident_id"] == res[1].Tags["incident_id"] {
+		t.Errorf("expected 2 incidents, got %v", res)
+	}
+}
+
+func TestSameTagAndWindow(t *testing.T) {
+	p, c := newTestProcessor(t, withConfig(
+		"rules", []interface{}{
+			map[string]interface{}{"relation": "same-tag", "tags": []string{"site"}},
+		},
+		"window", "10s",
+	))
+	e1 := intf(10, "r1", "ethernet-1/1", "DOWN")
+	e1.Tags["site"] = "par1"
+	p.Apply(e1)
+
+	c.advance(5 * time.Second)
+	e2 := intf(20, "r2", "ethernet-1/1", "DOWN")
+	e2.Tags["site"] = "par1"
+	res := incidents(p.Apply(e2))
+	if len(res) != 1 || res[0].Tags["incident_state"] != stateUpdate || res[0].Values["member_count"] != 2 {
+		t.Fatalf("expected the second event to join the incident, got %v", res)
+	}
+
+	c.advance(20 * time.Second)
+	e3 := intf(30, "r3", "ethernet-1/1", "DOWN")
+	e3.Tags["site"] = "par1"
+	res = incidents(p.Apply(e3))
+	if len(res) != 1 || res[0].Tags["incident_state"] != stateOpen {
+		t.Fatalf("expected an event outside the window to open a new incident, got %v", res)
+	}
+}
+
+func TestPeerTag(t *testing.T) {
+	p, _ := newTestProcessor(t, withConfig(
+		"rules", []interface{}{
+			map[string]interface{}{"relation": "adjacency", "peer-tag": "peer", "peer-interface-tag": "peer_interface"},
+		},
+	))
+	e1 := intf(10, "r1", "ethernet-1/1", "DOWN")
+	e1.Tags["peer"] = "r2"
+	e1.Tags["peer_interface"] = "ethernet-1/3"
+	res := incidents(p.Apply(
+		e1,
+		intf(20, "r2", "ethernet-1/4", "DOWN"),
+		intf(20, "r2", "ethernet-1/3", "DOWN"),
+	))
+	if len(res) != 2 {
+		t.Fatalf("expected 2 incidents, got %d", len(res))
+	}
+	if res[0].Values["member_count"] != 2 || res[1].Values["member_count"] != 1 {
+		t.Errorf("expected the peer interface to join the first incident, got %v and %v", res[0].Values, res[1].Values)
+	}
+}
+
+func TestDelayAndDropMembers(t *testing.T) {
+	p, c := newTestProcessor(t, withConfig("delay", "5s", "drop-members", true))
+	res := p.Apply(intf(10, "r1", "ethernet-1/1", "DOWN"), bgp(10, "r2", "10.0.0.1", "IDLE"))
+	if len(res) != 0 {
+		t.Fatalf("expected the member events to be dropped and the incidents delayed, got %v", res)
+	}
+	c.advance(2 * time.Second)
+	p.Apply(bgp(20, "r1", "10.0.0.2", "IDLE"))
+	c.advance(5 * time.Second)
+	res = p.Apply(&formatters.EventMsg{Name: "other"})
+	if len(res) != 3 || res[0].Name != "other" {
+		t.Fatalf("expected the other event and 2 incidents, got %v", res)
+	}
+	if res[1].Values["member_count"] != 2 || res[1].Tags["root_cause_event"] != "link-down" {
+		t.Errorf("unexpected first incident: %v %v", res[1].Tags, res[1].Values)
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	p, c := newTestProcessor(t, withConfig("expiration", "1m"))
+	p.Apply(intf(10, "r1", "ethernet-1/1", "DOWN"))
+	c.advance(2 * time.Minute)
+	res := incidents(p.Apply())
+	if len(res) != 1 || res[0].Tags["incident_clear_reason"] != reasonExpired {
+		t.Fatalf("expected the incident to expire, got %v", res)
+	}
+}
+
+func TestClearDelay(t *testing.T) {
+	p, c := newTestProcessor(t, withConfig("clear-delay", "10s"))
+	p.Apply(intf(10, "r1", "ethernet-1/1", "DOWN"))
+	res := incidents(p.Apply(intf(20, "r1", "ethernet-1/1", "UP")))
+	if len(res) != 1 || res[0].Tags["incident_state"] != stateUpdate {
+		t.Fatalf("expected an incident update, got %v", res)
+	}
+	c.advance(5 * time.Second)
+	// the member flaps back within the clear delay.
+	res = incidents(p.Apply(intf(30, "r1", "ethernet-1/1", "DOWN")))
+	if len(res) != 1 || res[0].Tags["incident_state"] != stateUpdate || res[0].Values["active_members"] != 1 {
+		t.Fatalf("expected an incident update, got %v", res)
+	}
+	p.Apply(intf(40, "r1", "ethernet-1/1", "UP"))
+	c.advance(11 * time.Second)
+	// no new events, the incident is cleared by a flush.
+	res = incidents(p.Flush(c.now()))
+	if len(res) != 1 || res[0].Tags["incident_state"] != stateClear {
+		t.Fatalf("expected the incident to be cleared, got %v", res)
+	}
+}
+
+func TestOutputFlush(t *testing.T) {
+	formatters.FlushPeriod = 10 * time.Millisecond
+	defer func() { formatters.FlushPeriod = time.Second }()
+	p, c := newTestProcessor(t, withConfig("clear-delay", "10s"))
+	evps := formatters.ForOutput("out1", []formatters.EventProcessor{p})
+	evps[0].Apply(intf(10, "r1", "ethernet-1/1", "DOWN"))
+	evps[0].Apply(intf(20, "r1", "ethernet-1/1", "UP"))
+	c.advance(11 * time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	written := make(chan []*formatters.EventMsg, 1)
+	formatters.StartFlush(ctx, evps, nil, func(evs []*formatters.EventMsg) { written <- evs })
+	select {
+	case evs := <-written:
+		res := incidents(evs)
+		if len(res) != 1 || res[0].Tags["incident_state"] != stateClear {
+			t.Fatalf("expected the incident to be cleared, got %v", evs)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the output chain to flush the incident")
+	}
+}
+
+func TestLinksExpiration(t *testing.T) {
+	p, c := newTestProcessor(t, withConfig("expiration", "1m"))
+	p.Apply(lldp("r1", "ethernet-1/1", "r2", "ethernet-1/3"))
+	if l := p.links["r2"]["ethernet-1/3"]; l == nil || l.peer != (endpoint{target: "r1", iface: "ethernet-1/1"}) {
+		t.Fatalf("expected the link to be learned in both directions, got %v", p.links)
+	}
+	c.advance(2 * time.Minute)
+	p.Flush(c.now())
+	if len(p.links) != 0 {
+		t.Fatalf("expected the links to expire, got %v", p.links)
+	}
+	p.Apply(intf(10, "r1", "ethernet-1/1", "DOWN"), intf(10, "r2", "ethernet-1/3", "DOWN"))
+	if len(p.incidents) != 2 {
+		t.Errorf("expected 2 unrelated incidents, got %d", len(p.incidents))
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"no_events": {},
+		"missing_condition": {
+			"events": map[string]interface{}{"e1": map[string]interface{}{}},
+		},
+		"unknown_relation": withConfig("rules", []interface{}{
+			map[string]interface{}{"relation": "same-site"},
+		}),
+		"unknown_event": withConfig("rules", []interface{}{
+			map[string]interface{}{"relation": "same-tag", "tags": []string{"site"}, "events": []string{"unknown"}},
+		}),
+		"adjacency_without_source": withConfig("rules", []interface{}{
+			map[string]interface{}{"relation": "adjacency"},
+		}),
+		"parent_child_cycle": withConfig("rules", []interface{}{
+			map[string]interface{}{"relation": "parent-child", "parent": "link-down", "child": "bgp-down"},
+			map[string]interface{}{"relation": "parent-child", "parent": "bgp-down", "child": "link-down"},
+		}),
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := &correlate{logger: log.New(io.Discard, "", 0)}
+			if err := p.Init(cfg); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,438 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_dedup
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	processorType = "event-dedup"
+	loggingPrefix = "[" + processorType + "] "
+
+	policyKeepFirst    = "keep-first"
+	policyKeepLatest   = "keep-latest"
+	policyPreferSource = "prefer-source"
+
+	defaultWindow     = time.Minute
+	defaultMaxEntries = 100000
+	defaultSourceTag  = "source"
+
+	// maximum number of forwarded timestamps kept per key
+	maxSamples = 32
+)
+
+var (
+	dedupEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gnmic",
+		Subsystem: "event_dedup",
+		Name:      "events_total",
+		Help:      "Number of events processed by the deduplication processor",
+	}, []string{"processor"})
+	dedupDuplicates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gnmic",
+		Subsystem: "event_dedup",
+		Name:      "duplicates_total",
+		Help:      "Number of duplicate events dropped by the deduplication processor",
+	}, []string{"processor"})
+	dedupEntries = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gnmic",
+		Subsystem: "event_dedup",
+		Name:      "entries",
+		Help:      "Number of deduplication keys kept in memory",
+	}, []string{"processor"})
+)
+
+// dedup drops the events identified as duplicates of
+// a previously seen event within a time window.
+type dedup struct {
+	Key                *keyConfig    `mapstructure:"key,omitempty" json:"key,omitempty"`
+	TimestampTolerance time.Duration `mapstructure:"timestamp-tolerance,omitempty" json:"timestamp-tolerance,omitempty"`
+	Window             time.Duration `mapstructure:"window,omitempty" json:"window,omitempty"`
+	Policy             string        `mapstructure:"policy,omitempty" json:"policy,omitempty"`
+	SourceTag          string        `mapstructure:"source-tag,omitempty" json:"source-tag,omitempty"`
+	Sources            []string      `mapstructure:"sources,omitempty" json:"sources,omitempty"`
+	MaxEntries         int           `mapstructure:"max-entries,omitempty" json:"max-entries,omitempty"`
+	Debug              bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	name            string
+	tagNames        []*regexp.Regexp
+	excludeTagNames []*regexp.Regexp
+	valueNames      []*regexp.Regexp
+	sourceRank      map[string]int
+
+	m sync.Mutex
+	// entries by key
+	entries map[string]*list.Element
+	// entries ordered by last update, oldest first
+	lru *list.List
+
+	events     prometheus.Counter
+	duplicates prometheus.Counter
+	size       prometheus.Gauge
+
+	logger *log.Logger
+}
+
+// keyConfig defines the parts of an event used to identify duplicates.
+type keyConfig struct {
+	// include the event name in the key, defaults to true
+	Name *bool `mapstructure:"name,omitempty" json:"name,omitempty"`
+	// regexes of the tag names included in the key, defaults to all tags
+	TagNames []string `mapstructure:"tag-names,omitempty" json:"tag-names,omitempty"`
+	// regexes of the tag names excluded from the key
+	ExcludeTagNames []string `mapstructure:"exclude-tag-names,omitempty" json:"exclude-tag-names,omitempty"`
+	// regexes of the value names included in the key, defaults to all values
+	ValueNames []string `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+}
+
+type entry struct {
+	key string
+	// forwarded events within the window, in forward order
+	samples []*sample
+	// last update time, used for the TTL eviction
+	seen time.Time
+}
+
+// sample is a forwarded occurrence of the data of a key.
+type sample struct {
+	// timestamp of the forwarded event
+	ts int64
+	// rank of the source of the forwarded event
+	rank int
+	// forward time, used to prune the samples older than the window
+	seen time.Time
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &dedup{
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+	formatters.RegisterMetrics(dedupEvents, dedupDuplicates, dedupEntries)
+}
+
+func (p *dedup) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.name == "" {
+		p.name = processorType
+	}
+	p.setDefaults()
+	switch p.Policy {
+	case policyKeepFirst, policyKeepLatest:
+	case policyPreferSource:
+		if len(p.Sources) == 0 {
+			return fmt.Errorf("policy %q requires a list of sources", p.Policy)
+		}
+	default:
+		return fmt.Errorf("unknown policy %q", p.Policy)
+	}
+	if p.TimestampTolerance < 0 {
+		return fmt.Errorf("invalid timestamp-tolerance %v", p.TimestampTolerance)
+	}
+	p.tagNames, err = compileRegexes(p.Key.TagNames)
+	if err != nil {
+		return err
+	}
+	p.excludeTagNames, err = compileRegexes(p.Key.ExcludeTagNames)
+	if err != nil {
+		return err
+	}
+	p.valueNames, err = compileRegexes(p.Key.ValueNames)
+	if err != nil {
+		return err
+	}
+	p.sourceRank = make(map[string]int, len(p.Sources))
+	for i, s := range p.Sources {
+		p.sourceRank[s] = i
+	}
+	p.entries = make(map[string]*list.Element)
+	p.lru = list.New()
+	p.events = dedupEvents.WithLabelValues(p.name)
+	p.duplicates = dedupDuplicates.WithLabelValues(p.name)
+	p.size = dedupEntries.WithLabelValues(p.name)
+
+	if p.logger.Writer() != io.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *dedup) setDefaults() {
+	if p.Key == nil {
+		p.Key = new(keyConfig)
+	}
+	if p.Key.Name == nil {
+		name := true
+		p.Key.Name = &name
+	}
+	if p.Window <= 0 {
+		p.Window = defaultWindow
+	}
+	if p.Policy == "" {
+		p.Policy = policyKeepFirst
+	}
+	if p.SourceTag == "" {
+		p.SourceTag = defaultSourceTag
+	}
+	if p.MaxEntries <= 0 {
+		p.MaxEntries = defaultMaxEntries
+	}
+}
+
+func (p *dedup) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	now := time.Now()
+	p.evictExpired(now)
+	res := make([]*formatters.EventMsg, 0, len(es))
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		p.events.Inc()
+		key, ok := p.key(e)
+		if !ok || p.forward(key, e, now) {
+			res = append(res, e)
+			continue
+		}
+		p.duplicates.Inc()
+		p.logger.Printf("dropped duplicate event: %v", e)
+	}
+	p.size.Set(float64(p.lru.Len()))
+	return res
+}
+
+func (p *dedup) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (p *dedup) WithTargets(tcs map[string]*types.TargetConfig) {}
+
+func (p *dedup) WithActions(act map[string]map[string]interface{}) {}
+
+func (p *dedup) WithProcessors(procs map[string]map[string]any) {}
+
+func (p *dedup) WithName(name string) {
+	p.name = name
+}
+
+// forward reports whether the event e should be forwarded
+// and updates the entry of its key accordingly.
+// The event is a duplicate if its timestamp is within the tolerance
+// of any event of the same key forwarded within the window,
+// so that a source lagging behind another one is still deduplicated.
+func (p *dedup) forward(key string, e *formatters.EventMsg, now time.Time) bool {
+	rank := p.rank(e)
+	el, ok := p.entries[key]
+	if !ok {
+		p.add(&entry{key: key, samples: []*sample{{ts: e.Timestamp, rank: rank, seen: now}}, seen: now})
+		return true
+	}
+	en := el.Value.(*entry)
+	en.prune(now.Add(-p.Window))
+	sm := en.nearest(e.Timestamp)
+	if sm == nil || abs(e.Timestamp-sm.ts) > p.TimestampTolerance.Nanoseconds() {
+		// not a duplicate, a new occurrence of the same data
+		en.push(&sample{ts: e.Timestamp, rank: rank, seen: now})
+		p.touch(el, now)
+		return true
+	}
+	switch p.Policy {
+	case policyKeepLatest:
+		if e.Timestamp <= sm.ts {
+			return false
+		}
+	case policyPreferSource:
+		if rank >= sm.rank {
+			return false
+		}
+	default: // keep-first
+		return false
+	}
+	sm.ts = e.Timestamp
+	sm.rank = rank
+	sm.seen = now
+	p.touch(el, now)
+	return true
+}
+
+// prune removes the samples forwarded before t.
+func (en *entry) prune(t time.Time) {
+	samples := en.samples[:0]
+	for _, sm := range en.samples {
+		if !sm.seen.Before(t) {
+			samples = append(samples, sm)
+		}
+	}
+	en.samples = samples
+}
+
+// nearest returns the sample with the timestamp closest to ts.
+func (en *entry) nearest(ts int64) *sample {
+	var res *sample
+	for _, sm := range en.samples {
+		if res == nil || abs(ts-sm.ts) < abs(ts-res.ts) {
+			res = sm
+		}
+	}
+	return res
+}
+
+// push appends the sample sm, dropping the oldest one
+// when the entry holds maxSamples samples.
+func (en *entry) push(sm *sample) {
+	if len(en.samples) >= maxSamples {
+		en.samples = en.samples[1:]
+	}
+	en.samples = append(en.samples, sm)
+}
+
+func (p *dedup) add(en *entry) {
+	for p.lru.Len() >= p.MaxEntries {
+		p.remove(p.lru.Front())
+	}
+	p.entries[en.key] = p.lru.PushBack(en)
+}
+
+func (p *dedup) touch(el *list.Element, now time.Time) {
+	el.Value.(*entry).seen = now
+	p.lru.MoveToBack(el)
+}
+
+func (p *dedup) remove(el *list.Element) {
+	p.lru.Remove(el)
+	delete(p.entries, el.Value.(*entry).key)
+}
+
+func (p *dedup) evictExpired(now time.Time) {
+	for el := p.lru.Front(); el != nil; el = p.lru.Front() {
+		if now.Sub(el.Value.(*entry).seen) <= p.Window {
+			return
+		}
+		p.remove(el)
+	}
+}
+
+// rank returns the preference rank of the event source,
+// the lower the better. Unknown sources rank last.
+func (p *dedup) rank(e *formatters.EventMsg) int {
+	if p.Policy != policyPreferSource {
+		return 0
+	}
+	if r, ok := p.sourceRank[e.Tags[p.SourceTag]]; ok {
+		return r
+	}
+	return len(p.Sources)
+}
+
+// key builds the deduplication key of an event.
+// It returns false if the event has none of the configured value names.
+func (p *dedup) key(e *formatters.EventMsg) (string, bool) {
+	tags := make([]string, 0, len(e.Tags))
+	for k, v := range e.Tags {
+		if !p.keyTag(k) {
+			continue
+		}
+		tags = append(tags, k+"="+v)
+	}
+	values := make([]string, 0, len(e.Values))
+	for k := range e.Values {
+		if len(p.valueNames) > 0 && !matchAny(k, p.valueNames) {
+			continue
+		}
+		values = append(values, k)
+	}
+	if len(p.valueNames) > 0 && len(values) == 0 {
+		return "", false
+	}
+	sort.Strings(tags)
+	sort.Strings(values)
+	sb := new(strings.Builder)
+	if *p.Key.Name {
+		sb.WriteString(e.Name)
+	}
+	sb.WriteByte(0)
+	sb.WriteString(strings.Join(tags, "\x00"))
+	sb.WriteByte(0)
+	sb.WriteString(strings.Join(values, "\x00"))
+	return sb.String(), true
+}
+
+func (p *dedup) keyTag(k string) bool {
+	// with the prefer-source policy, the duplicates differ by their source tag
+	if p.Policy == policyPreferSource && k == p.SourceTag {
+		return false
+	}
+	if matchAny(k, p.excludeTagNames) {
+		return false
+	}
+	return len(p.tagNames) == 0 || matchAny(k, p.tagNames)
+}
+
+func matchAny(s string, res []*regexp.Regexp) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func compileRegexes(exprs []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+func abs(i int64) int64 {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
@@ -0,0 +1,260 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_dedup
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+func ev(name string, ts int64, tags map[string]string, values map[string]interface{}) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      name,
+		Timestamp: ts,
+		Tags:      tags,
+		Values:    values,
+	}
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"keep_first": {
+		processorType: processorType,
+		processor:     map[string]interface{}{},
+		tests: []item{
+			{
+				input:  nil,
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					ev("sub1", 1, map[string]string{"source": "r1"}, map[string]interface{}{"v": 1}),
+					ev("sub1", 1, map[string]string{"source": "r1"}, map[string]interface{}{"v": 1}),
+					ev("sub1", 1, map[string]string{"source": "r2"}, map[string]interface{}{"v": 1}),
+				},
+				output: []*formatters.EventMsg{
+					ev("sub1", 1, map[string]string{"source": "r1"}, map[string]interface{}{"v": 1}),
+					ev("sub1", 1, map[string]string{"source": "r2"}, map[string]interface{}{"v": 1}),
+				},
+			},
+			{
+				// same key, different timestamp: not a duplicate
+				input: []*formatters.EventMsg{
+					ev("sub1", 2, map[string]string{"source": "r1"}, map[string]interface{}{"v": 2}),
+					ev("sub1", 2, map[string]string{"source": "r1"}, map[string]interface{}{"v": 2}),
+				},
+				output: []*formatters.EventMsg{
+					ev("sub1", 2, map[string]string{"source": "r1"}, map[string]interface{}{"v": 2}),
+				},
+			},
+		},
+	},
+	"overlapping_subscriptions": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"key": map[string]interface{}{
+				"name":              false,
+				"exclude-tag-names": []string{"^subscription-name$"},
+				"value-names":       []string{"counters"},
+			},
+			"timestamp-tolerance": "100ns",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					ev("sub1", 1000, map[string]string{"source": "r1", "subscription-name": "sub1"}, map[string]interface{}{"counters/in": 1}),
+					ev("sub2", 1050, map[string]string{"source": "r1", "subscription-name": "sub2"}, map[string]interface{}{"counters/in": 1}),
+					ev("sub2", 1200, map[string]string{"source": "r1", "subscription-name": "sub2"}, map[string]interface{}{"counters/in": 2}),
+					// no value matching the key value names
+					ev("sub2", 1200, map[string]string{"source": "r1", "subscription-name": "sub2"}, map[string]interface{}{"state": "up"}),
+					ev("sub2", 1200, map[string]string{"source": "r1", "subscription-name": "sub2"}, map[string]interface{}{"state": "up"}),
+				},
+				output: []*formatters.EventMsg{
+					ev("sub1", 1000, map[string]string{"source": "r1", "subscription-name": "sub1"}, map[string]interface{}{"counters/in": 1}),
+					ev("sub2", 1200, map[string]string{"source": "r1", "subscription-name": "sub2"}, map[string]interface{}{"counters/in": 2}),
+					ev("sub2", 1200, map[string]string{"source": "r1", "subscription-name": "sub2"}, map[string]interface{}{"state": "up"}),
+					ev("sub2", 1200, map[string]string{"source": "r1", "subscription-name": "sub2"}, map[string]interface{}{"state": "up"}),
+				},
+			},
+		},
+	},
+	"lagging_source": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"key": map[string]interface{}{
+				"tag-names": []string{"^interface$"},
+			},
+			"timestamp-tolerance": "10ns",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					ev("sub1", 1000, map[string]string{"interface": "e1", "collector": "c1"}, map[string]interface{}{"v": 1}),
+					ev("sub1", 2000, map[string]string{"interface": "e1", "collector": "c1"}, map[string]interface{}{"v": 1}),
+					// c2 lags behind c1, its events are duplicates of earlier c1 events.
+					ev("sub1", 1005, map[string]string{"interface": "e1", "collector": "c2"}, map[string]interface{}{"v": 1}),
+					ev("sub1", 2000, map[string]string{"interface": "e1", "collector": "c2"}, map[string]interface{}{"v": 1}),
+					ev("sub1", 3000, map[string]string{"interface": "e1", "collector": "c2"}, map[string]interface{}{"v": 1}),
+				},
+				output: []*formatters.EventMsg{
+					ev("sub1", 1000, map[string]string{"interface": "e1", "collector": "c1"}, map[string]interface{}{"v": 1}),
+					ev("sub1", 2000, map[string]string{"interface": "e1", "collector": "c1"}, map[string]interface{}{"v": 1}),
+					ev("sub1", 3000, map[string]string{"interface": "e1", "collector": "c2"}, map[string]interface{}{"v": 1}),
+				},
+			},
+		},
+	},
+	"keep_latest": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"key": map[string]interface{}{
+				"tag-names": []string{"^interface$"},
+			},
+			"policy":              "keep-latest",
+			"timestamp-tolerance": "1s",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					ev("sub1", 1000, map[string]string{"interface": "e1", "collector": "c1"}, map[string]interface{}{"v": 1}),
+					ev("sub1", 900, map[string]string{"interface": "e1", "collector": "c2"}, map[string]interface{}{"v": 1}),
+					ev("sub1", 1100, map[string]string{"interface": "e1", "collector": "c2"}, map[string]interface{}{"v": 1}),
+					ev("sub1", 1100, map[string]string{"interface": "e1", "collector": "c1"}, map[string]interface{}{"v": 1}),
+				},
+				output: []*formatters.EventMsg{
+					ev("sub1", 1000, map[string]string{"interface": "e1", "collector": "c1"}, map[string]interface{}{"v": 1}),
+					ev("sub1", 1100, map[string]string{"interface": "e1", "collector": "c2"}, map[string]interface{}{"v": 1}),
+				},
+			},
+		},
+	},
+	"prefer_source": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"policy":     "prefer-source",
+			"source-tag": "collector",
+			"sources":    []string{"primary", "secondary"},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					ev("sub1", 1, map[string]string{"source": "r1", "collector": "other"}, map[string]interface{}{"v": 1}),
+					ev("sub1", 1, map[string]string{"source": "r1", "collector": "secondary"}, map[string]interface{}{"v": 1}),
+					ev("sub1", 1, map[string]string{"source": "r1", "collector": "primary"}, map[string]interface{}{"v": 1}),
+					ev("sub1", 1, map[string]string{"source": "r1", "collector": "secondary"}, map[string]interface{}{"v": 1}),
+					ev("sub1", 1, map[string]string{"source": "r1", "collector": "primary"}, map[string]interface{}{"v": 1}),
+				},
+				output: []*formatters.EventMsg{
+					ev("sub1", 1, map[string]string{"source": "r1", "collector": "other"}, map[string]interface{}{"v": 1}),
+					ev("sub1", 1, map[string]string{"source": "r1", "collector": "secondary"}, map[string]interface{}{"v": 1}),
+					ev("sub1", 1, map[string]string{"source": "r1", "collector": "primary"}, map[string]interface{}{"v": 1}),
+				},
+			},
+		},
+	},
+}
+
+func TestEventDedup(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor)
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if !reflect.DeepEqual(outs, item.output) {
+						t.Errorf("failed at %s item %d, expected %+v, got: %+v", name, i, item.output, outs)
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventDedupEviction(t *testing.T) {
+	p := formatters.EventProcessors[processorType]().(*dedup)
+	err := p.Init(map[string]interface{}{
+		"window":      "50ms",
+		"max-entries": 2,
+	}, formatters.WithName("eviction-test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e1 := ev("sub1", 1, map[string]string{"source": "r1"}, map[string]interface{}{"v": 1})
+	e2 := ev("sub1", 1, map[string]string{"source": "r2"}, map[string]interface{}{"v": 1})
+	e3 := ev("sub1", 1, map[string]string{"source": "r3"}, map[string]interface{}{"v": 1})
+	// e1 is evicted when e3 is added
+	if outs := p.Apply(e1, e2, e3, e1); len(outs) != 4 {
+		t.Errorf("expected 4 events, got %d", len(outs))
+	}
+	if outs := p.Apply(e1, e3); len(outs) != 0 {
+		t.Errorf("expected duplicates to be dropped, got %d events", len(outs))
+	}
+	if p.lru.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", p.lru.Len())
+	}
+	// all entries expire
+	time.Sleep(100 * time.Millisecond)
+	if outs := p.Apply(e1); len(outs) != 1 {
+		t.Errorf("expected expired entry to be forwarded, got %d events", len(outs))
+	}
+	if p.lru.Len() != 1 {
+		t.Errorf("expected 1 entry, got %d", p.lru.Len())
+	}
+	if v := testutil.ToFloat64(dedupEvents.WithLabelValues("eviction-test")); v != 7 {
+		t.Errorf("expected 7 processed events, got %v", v)
+	}
+	if v := testutil.ToFloat64(dedupDuplicates.WithLabelValues("eviction-test")); v != 2 {
+		t.Errorf("expected 2 duplicates, got %v", v)
+	}
+}
+
+func TestEventDedupInitErrors(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"unknown_policy": {
+			"policy": "keep-last",
+		},
+		"prefer_source_without_sources": {
+			"policy": "prefer-source",
+		},
+		"bad_regex": {
+			"key": map[string]interface{}{"tag-names": []string{"("}},
+		},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := formatters.EventProcessors[processorType]()
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an init error")
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package formatters
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/api/path"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+)
+
+// EventMapping defines how event messages are converted back to gNMI notifications.
+//
+// Each event value becomes an update whose path is the value name, or the path
+// it is mapped to under Values. The path elements keys are set from the event tags:
+// a tag mapped under Keys sets the key of the path elements it names, any other tag
+// called <elem>_<key> sets the key <key> of the path elements called <elem>,
+// which is the reverse of the tags created from the gNMI path keys.
+// The notification target is the "target" tag if present, the host part of the "source" tag otherwise.
+type EventMapping struct {
+	// tag name to path key, formatted as elem[key].
+	Keys map[string]string `mapstructure:"keys,omitempty" json:"keys,omitempty" yaml:"keys,omitempty"`
+	// value name to gNMI path.
+	Values map[string]string `mapstructure:"values,omitempty" json:"values,omitempty" yaml:"values,omitempty"`
+
+	keys map[string]pathKey
+}
+
+type pathKey struct {
+	elem string
+	key  string
+}
+
+// Init validates the mapping, it must be called before EventToNotification.
+// A nil EventMapping is valid and applies the default mapping only.
+func (m *EventMapping) Init() error {
+	if m == nil {
+		return nil
+	}
+	m.keys = make(map[string]pathKey, len(m.Keys))
+	for tag, k := range m.Keys {
+		i := strings.Index(k, "[")
+		if i <= 0 || !strings.HasSuffix(k, "]") || i == len(k)-2 {
+			return fmt.Errorf("invalid key mapping %q for tag %q: expected format elem[key]", k, tag)
+		}
+		m.keys[tag] = pathKey{elem: k[:i], key: k[i+1 : len(k)-1]}
+	}
+	for vn, p := range m.Values {
+		if _, err := path.ParsePath(p); err != nil {
+			return fmt.Errorf("invalid path %q for value %q: %w", p, vn, err)
+		}
+	}
+	return nil
+}
+
+// EventToNotification converts an event message to a gNMI notification
+// using the mapping m. It returns nil if the event has no values and no deletes.
+func EventToNotification(ev *EventMsg, m *EventMapping) (*gnmi.Notification, error) {
+	if ev == nil || (len(ev.Values) == 0 && len(ev.Deletes) == 0) {
+		return nil, nil
+	}
+	n := &gnmi.Notification{
+		Timestamp: ev.Timestamp,
+		Prefix:    &gnmi.Path{Target: ev.Tags["target"]},
+	}
+	if n.Prefix.Target == "" {
+		n.Prefix.Target = utils.GetHost(ev.Tags["source"])
+	}
+	names := make([]string, 0, len(ev.Values))
+	for vn := range ev.Values {
+		names = append(names, vn)
+	}
+	sort.Strings(names)
+	for _, vn := range names {
+		p, err := m.path(vn, ev.Tags)
+		if err != nil {
+			return nil, err
+		}
+		tv, err := toTypedValue(ev.Values[vn])
+		if err != nil {
+			return nil, fmt.Errorf("value %q: %w", vn, err)
+		}
+		n.Update = append(n.Update, &gnmi.Update{Path: p, Val: tv})
+	}
+	for _, d := range ev.Deletes {
+		p, err := m.path(d, ev.Tags)
+		if err != nil {
+			return nil, err
+		}
+		n.Delete = append(n.Delete, p)
+	}
+	return n, nil
+}
+
+func (m *EventMapping) path(name string, tags map[string]string) (*gnmi.Path, error) {
+	p := name
+	if m != nil {
+		if mp, ok := m.Values[name]; ok {
+			p = mp
+		}
+	}
+	gp, err := path.ParsePath(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse path %q: %w", p, err)
+	}
+	for _, e := range gp.GetElem() {
+		local := e.GetName()
+		if i := strings.LastIndex(local, ":"); i >= 0 {
+			local = local[i+1:]
+		}
+		for tag, v := range tags {
+			var key string
+			if m != nil && m.keys != nil {
+				if pk, ok := m.keys[tag]; ok {
+					if pk.elem != local && pk.elem != e.GetName() {
+						continue
+					}
+					key = pk.key
+				}
+			}
+			if key == "" {
+				if !strings.HasPrefix(tag, local+"_") {
+					continue
+				}
+				key = tag[len(local)+1:]
+			}
+			if key == "" {
+				continue
+			}
+			if e.Key == nil {
+				e.Key = make(map[string]string)
+			}
+			if _, ok := e.Key[key]; !ok {
+				e.Key[key] = v
+			}
+		}
+	}
+	return gp, nil
+}
+
+func toTypedValue(v interface{}) (*gnmi.TypedValue, error) {
+	switch v := v.(type) {
+	case string:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: v}}, nil
+	case bool:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_BoolVal{BoolVal: v}}, nil
+	case int:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: int64(v)}}, nil
+	case int8:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: int64(v)}}, nil
+	case int16:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: int64(v)}}, nil
+	case int32:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: int64(v)}}, nil
+	case int64:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: v}}, nil
+	case uint:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: uint64(v)}}, nil
+	case uint8:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: uint64(v)}}, nil
+	case uint16:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: uint64(v)}}, nil
+	case uint32:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: uint64(v)}}, nil
+	case uint64:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: v}}, nil
+	case float32:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_DoubleVal{DoubleVal: float64(v)}}, nil
+	case float64:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_DoubleVal{DoubleVal: v}}, nil
+	case []byte:
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_BytesVal{BytesVal: v}}, nil
+	case []interface{}:
+		ll := &gnmi.ScalarArray{Element: make([]*gnmi.TypedValue, 0, len(v))}
+		for _, e := range v {
+			tv, err := toTypedValue(e)
+			if err != nil {
+				return nil, err
+			}
+			ll.Element = append(ll.Element, tv)
+		}
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_LeaflistVal{LeaflistVal: ll}}, nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return &gnmi.TypedValue{Value: &gnmi.TypedValue_JsonVal{JsonVal: b}}, nil
+	}
+}
@@ -0,0 +1,182 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package formatters
+
+import (
+	"testing"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"google.golang.org/protobuf/proto"
+)
+
+var eventToNotificationTestSet = map[string]struct {
+	mapping *EventMapping
+	input   *EventMsg
+	output  *gnmi.Notification
+}{
+	"nil": {},
+	"no_values": {
+		input: &EventMsg{Name: "sub1", Tags: map[string]string{"source": "r1"}},
+	},
+	"default_mapping": {
+		input: &EventMsg{
+			Name:      "sub1",
+			Timestamp: 42,
+			Tags: map[string]string{
+				"source":             "r1:57400",
+				"subscription-name":  "sub1",
+				"interface_name":     "ethernet-1/1",
+				"subinterface_index": "0",
+			},
+			Values: map[string]interface{}{
+				"/interface/subinterface/statistics/in-octets": uint64(100),
+				"/interface/oper-state":                        "up",
+			},
+		},
+		output: &gnmi.Notification{
+			Timestamp: 42,
+			Prefix:    &gnmi.Path{Target: "r1"},
+			Update: []*gnmi.Update{
+				{
+					Path: &gnmi.Path{Elem: []*gnmi.PathElem{
+						{Name: "interface", Key: map[string]string{"name": "ethernet-1/1"}},
+						{Name: "oper-state"},
+					}},
+					Val: &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: "up"}},
+				},
+				{
+					Path: &gnmi.Path{Elem: []*gnmi.PathElem{
+						{Name: "interface", Key: map[string]string{"name": "ethernet-1/1"}},
+						{Name: "subinterface", Key: map[string]string{"index": "0"}},
+						{Name: "statistics"},
+						{Name: "in-octets"},
+					}},
+					Val: &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: 100}},
+				},
+			},
+		},
+	},
+	"explicit_mapping": {
+		mapping: &EventMapping{
+			Keys:   map[string]string{"ifname": "interface[name]"},
+			Values: map[string]string{"in_mbps": "/interface/statistics/in-mbps"},
+		},
+		input: &EventMsg{
+			Timestamp: 42,
+			Tags:      map[string]string{"target": "t1", "source": "r1", "ifname": "mgmt0"},
+			Values:    map[string]interface{}{"in_mbps": 1.5},
+			Deletes:   []string{"/interface/description"},
+		},
+		output: &gnmi.Notification{
+			Timestamp: 42,
+			Prefix:    &gnmi.Path{Target: "t1"},
+			Update: []*gnmi.Update{
+				{
+					Path: &gnmi.Path{Elem: []*gnmi.PathElem{
+						{Name: "interface", Key: map[string]string{"name": "mgmt0"}},
+						{Name: "statistics"},
+						{Name: "in-mbps"},
+					}},
+					Val: &gnmi.TypedValue{Value: &gnmi.TypedValue_DoubleVal{DoubleVal: 1.5}},
+				},
+			},
+			Delete: []*gnmi.Path{
+				{Elem: []*gnmi.PathElem{
+					{Name: "interface", Key: map[string]string{"name": "mgmt0"}},
+					{Name: "description"},
+				}},
+			},
+		},
+	},
+	"module_prefix_and_leaflist": {
+		input: &EventMsg{
+			Tags: map[string]string{"source": "r1", "network-instance_name": "default"},
+			Values: map[string]interface{}{
+				"/srl_nokia-network-instance:network-instance/interfaces": []interface{}{"e1", "e2"},
+			},
+		},
+		output: &gnmi.Notification{
+			Prefix: &gnmi.Path{Target: "r1"},
+			Update: []*gnmi.Update{
+				{
+					Path: &gnmi.Path{Elem: []*gnmi.PathElem{
+						{Name: "srl_nokia-network-instance:network-instance", Key: map[string]string{"name": "default"}},
+						{Name: "interfaces"},
+					}},
+					Val: &gnmi.TypedValue{Value: &gnmi.TypedValue_LeaflistVal{LeaflistVal: &gnmi.ScalarArray{
+						Element: []*gnmi.TypedValue{
+							{Value: &gnmi.TypedValue_StringVal{StringVal: "e1"}},
+							{Value: &gnmi.TypedValue_StringVal{StringVal: "e2"}},
+						},
+					}}},
+				},
+			},
+		},
+	},
+}
+
+func TestEventToNotification(t *testing.T) {
+	for name, ts := range eventToNotificationTestSet {
+		t.Run(name, func(t *testing.T) {
+			if err := ts.mapping.Init(); err != nil {
+				t.Fatal(err)
+			}
+			n, err := EventToNotification(ts.input, ts.mapping)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !proto.Equal(n, ts.output) {
+				t.Errorf("expected %v, got %v", ts.output, n)
+			}
+		})
+	}
+}
+
+func TestEventMappingRoundTrip(t *testing.T) {
+	rsp := interfaceResponse("ethernet-1/1", 10)
+	rsp.GetUpdate().Prefix.Target = "r1"
+	evs, err := ResponseToEventMsgs("sub1", rsp, map[string]string{"source": "r1:57400"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ev := range evs {
+		n, err := EventToNotification(ev, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(n.GetUpdate()) != 1 {
+			t.Fatalf("unexpected notification: %v", n)
+		}
+		found := false
+		for _, upd := range rsp.GetUpdate().GetUpdate() {
+			p := &gnmi.Path{Elem: append(proto.Clone(rsp.GetUpdate().GetPrefix()).(*gnmi.Path).GetElem(), upd.GetPath().GetElem()...)}
+			if proto.Equal(p, n.GetUpdate()[0].GetPath()) && proto.Equal(upd.GetVal(), n.GetUpdate()[0].GetVal()) {
+				found = true
+			}
+		}
+		if !found || n.GetPrefix().GetTarget() != "r1" {
+			t.Errorf("notification %v does not match the response %v", n, rsp)
+		}
+	}
+}
+
+func TestEventMappingInit(t *testing.T) {
+	for name, m := range map[string]*EventMapping{
+		"missing_key":   {Keys: map[string]string{"t": "interface"}},
+		"empty_key":     {Keys: map[string]string{"t": "interface[]"}},
+		"missing_elem":  {Keys: map[string]string{"t": "[name]"}},
+		"invalid_value": {Values: map[string]string{"v": "/interface[name=e1"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := m.Init(); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
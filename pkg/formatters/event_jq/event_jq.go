@@ -9,6 +9,7 @@
 package event_jq
 
 import (
+	"context"
 	"errors"
 	"io"
 	"log"
@@ -89,6 +90,11 @@ func (p *jq) setDefaults() {
 }
 
 func (p *jq) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	return p.ApplyContext(context.Background(), es...)
+}
+
+// ApplyContext runs the condition and expression, stopping their evaluation when ctx is done.
+func (p *jq) ApplyContext(ctx context.Context, es ...*formatters.EventMsg) []*formatters.EventMsg {
 	nuMsgs := len(es)
 	inputs := make([]interface{}, 0, nuMsgs)
 	res := make([]*formatters.EventMsg, 0, nuMsgs)
@@ -97,7 +103,7 @@ func (p *jq) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
 			continue
 		}
 		input := e.ToMap()
-		ok, err := p.evaluateCondition(input)
+		ok, err := p.evaluateCondition(ctx, input)
 		if err != nil {
 			p.logger.Printf("failed to evaluate condition: %v", err)
 			continue
@@ -108,7 +114,7 @@ func (p *jq) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
 		}
 		res = append(res, e)
 	}
-	evs, err := p.applyExpression(inputs)
+	evs, err := p.applyExpression(ctx, inputs)
 	if err != nil {
 		p.logger.Printf("failed to apply jq expression: %v", err)
 		return nil
@@ -116,11 +122,11 @@ func (p *jq) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
 	return append(res, evs...)
 }
 
-func (p *jq) evaluateCondition(input map[string]interface{}) (bool, error) {
+func (p *jq) evaluateCondition(ctx context.Context, input map[string]interface{}) (bool, error) {
 	var res interface{}
 	var err error
 	if p.cond != nil {
-		iter := p.cond.Run(input)
+		iter := p.cond.RunWithContext(ctx, input)
 		var ok bool
 		res, ok = iter.Next()
 		if !ok {
@@ -140,11 +146,11 @@ func (p *jq) evaluateCondition(input map[string]interface{}) (bool, error) {
 	}
 }
 
-func (p *jq) applyExpression(input []interface{}) ([]*formatters.EventMsg, error) {
+func (p *jq) applyExpression(ctx context.Context, input []interface{}) ([]*formatters.EventMsg, error) {
 	var res []interface{}
 	var err error
 	var evs = make([]*formatters.EventMsg, 0)
-	iter := p.expr.Run(input)
+	iter := p.expr.RunWithContext(ctx, input)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,365 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	processorType = "event-redact"
+	loggingPrefix = "[" + processorType + "] "
+
+	actionMask     = "mask"
+	actionHash     = "hash"
+	actionTruncate = "truncate"
+	actionDrop     = "drop"
+
+	detectorIPv4  = "ipv4"
+	detectorIPv6  = "ipv6"
+	detectorMAC   = "mac"
+	detectorEmail = "email"
+
+	defaultMaskChar = "*"
+)
+
+var redactHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "event_redact",
+	Name:      "hits_total",
+	Help:      "Number of tags and values redacted per rule",
+}, []string{"rule", "action"})
+
+var detectors = map[string]*matcher{
+	detectorIPv4: {
+		re: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`),
+	},
+	// the regex selects IPv6 candidates, they are validated by net.ParseIP.
+	detectorIPv6: {
+		re: regexp.MustCompile(`(?i)[0-9a-f]{0,4}:[0-9a-f:]*:[0-9a-f.]*`),
+		valid: func(s string) bool {
+			return strings.Count(s, ":") >= 2 && net.ParseIP(s) != nil
+		},
+	},
+	detectorMAC: {
+		re: regexp.MustCompile(`(?i)\b(?:[0-9a-f]{2}(?:[:-][0-9a-f]{2}){5}|[0-9a-f]{4}\.[0-9a-f]{4}\.[0-9a-f]{4})\b`),
+	},
+	detectorEmail: {
+		re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	},
+}
+
+// redact hides sensitive data found in the events tags and values.
+type redact struct {
+	Rules []*rule `mapstructure:"rules,omitempty" json:"rules,omitempty"`
+	Debug bool    `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	logger *log.Logger
+}
+
+// rule selects the tags and values to redact and
+// the part of them that should be redacted.
+type rule struct {
+	Name string `mapstructure:"name,omitempty" json:"name,omitempty"`
+	// regexes of the tag names the rule applies to
+	TagNames []string `mapstructure:"tag-names,omitempty" json:"tag-names,omitempty"`
+	// regexes of the value names the rule applies to
+	ValueNames []string `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	// builtin detectors: ipv4, ipv6, mac and email
+	Detectors []string `mapstructure:"detectors,omitempty" json:"detectors,omitempty"`
+	// custom regexes
+	Patterns []string `mapstructure:"patterns,omitempty" json:"patterns,omitempty"`
+	// mask, hash, truncate or drop
+	Action string `mapstructure:"action,omitempty" json:"action,omitempty"`
+	// mask action: the character replacing each redacted character
+	MaskChar string `mapstructure:"mask-char,omitempty" json:"mask-char,omitempty"`
+	// mask action: a fixed replacement string, takes precedence over mask-char
+	Replacement string `mapstructure:"replacement,omitempty" json:"replacement,omitempty"`
+	// hash action: the HMAC key and the number of hex characters kept
+	HashKey     string `mapstructure:"hash-key,omitempty" json:"-"`
+	HashKeyFile string `mapstructure:"hash-key-file,omitempty" json:"hash-key-file,omitempty"`
+	HashLength  int    `mapstructure:"hash-length,omitempty" json:"hash-length,omitempty"`
+	// truncate action: the number of characters kept
+	Length int `mapstructure:"length,omitempty" json:"length,omitempty"`
+
+	tagNames   []*regexp.Regexp
+	valueNames []*regexp.Regexp
+	matchers   []*matcher
+	hashKey    []byte
+	hits       prometheus.Counter
+}
+
+type matcher struct {
+	re    *regexp.Regexp
+	valid func(string) bool
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &redact{
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+	formatters.RegisterMetrics(redactHits)
+}
+
+func (p *redact) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if len(p.Rules) == 0 {
+		return errors.New("missing rules")
+	}
+	for i, r := range p.Rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule-%d", i)
+		}
+		err = r.init()
+		if err != nil {
+			return fmt.Errorf("rule %q: %v", r.Name, err)
+		}
+	}
+	if p.logger.Writer() != io.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (r *rule) init() error {
+	var err error
+	r.tagNames, err = compileRegexes(r.TagNames)
+	if err != nil {
+		return err
+	}
+	r.valueNames, err = compileRegexes(r.ValueNames)
+	if err != nil {
+		return err
+	}
+	for _, d := range r.Detectors {
+		m, ok := detectors[strings.ToLower(d)]
+		if !ok {
+			return fmt.Errorf("unknown detector %q", d)
+		}
+		r.matchers = append(r.matchers, m)
+	}
+	pts, err := compileRegexes(r.Patterns)
+	if err != nil {
+		return err
+	}
+	for _, re := range pts {
+		r.matchers = append(r.matchers, &matcher{re: re})
+	}
+	switch r.Action {
+	case "":
+		r.Action = actionMask
+		fallthrough
+	case actionMask:
+		if r.MaskChar == "" {
+			r.MaskChar = defaultMaskChar
+		}
+	case actionHash:
+		switch {
+		case r.HashKey != "":
+			r.hashKey = []byte(r.HashKey)
+		case r.HashKeyFile != "":
+			r.hashKey, err = os.ReadFile(r.HashKeyFile)
+			if err != nil {
+				return err
+			}
+			r.hashKey = []byte(strings.TrimSpace(string(r.hashKey)))
+		}
+		if len(r.hashKey) == 0 {
+			return errors.New("hash action requires a hash-key or a hash-key-file")
+		}
+		if r.HashLength < 0 || r.HashLength > sha256.Size*2 {
+			return fmt.Errorf("hash-length must be between 0 and %d", sha256.Size*2)
+		}
+	case actionTruncate:
+		if r.Length < 0 {
+			return errors.New("length must be a positive integer")
+		}
+	case actionDrop:
+	default:
+		return fmt.Errorf("unknown action %q", r.Action)
+	}
+	r.hits = redactHits.WithLabelValues(r.Name, r.Action)
+	return nil
+}
+
+func (p *redact) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		for _, r := range p.Rules {
+			for k, v := range e.Tags {
+				if !r.appliesTo(k, r.tagNames) {
+					continue
+				}
+				nv, drop, ok := r.redact(v)
+				if !ok {
+					continue
+				}
+				p.logger.Printf("rule %q redacted tag %q", r.Name, k)
+				if drop {
+					delete(e.Tags, k)
+					continue
+				}
+				e.Tags[k] = nv
+			}
+			for k, v := range e.Values {
+				if !r.appliesTo(k, r.valueNames) {
+					continue
+				}
+				var s string
+				switch v := v.(type) {
+				case string:
+					s = v
+				default:
+					// only string values are scanned by detectors and patterns
+					if len(r.matchers) > 0 {
+						continue
+					}
+					s = fmt.Sprint(v)
+				}
+				nv, drop, ok := r.redact(s)
+				if !ok {
+					continue
+				}
+				p.logger.Printf("rule %q redacted value %q", r.Name, k)
+				if drop {
+					delete(e.Values, k)
+					continue
+				}
+				e.Values[k] = nv
+			}
+		}
+	}
+	return es
+}
+
+func (p *redact) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (p *redact) WithTargets(tcs map[string]*types.TargetConfig) {}
+
+func (p *redact) WithActions(act map[string]map[string]interface{}) {}
+
+func (p *redact) WithProcessors(procs map[string]map[string]any) {}
+
+// appliesTo returns true if the tag or value name k is selected by the rule.
+// A rule without tag-names nor value-names applies to all tags and values.
+func (r *rule) appliesTo(k string, res []*regexp.Regexp) bool {
+	if len(r.tagNames) == 0 && len(r.valueNames) == 0 {
+		return true
+	}
+	for _, re := range res {
+		if re.MatchString(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact applies the rule action to s.
+// If the rule has detectors or patterns, only the matching parts of s
+// are redacted, otherwise the whole string is.
+// It returns the redacted string, whether the field should be dropped
+// and whether the rule matched at all.
+func (r *rule) redact(s string) (string, bool, bool) {
+	if len(r.matchers) == 0 {
+		r.hits.Inc()
+		if r.Action == actionDrop {
+			return "", true, true
+		}
+		return r.apply(s), false, true
+	}
+	matched := false
+	for _, m := range r.matchers {
+		s = m.re.ReplaceAllStringFunc(s, func(sub string) string {
+			if m.valid != nil && !m.valid(sub) {
+				return sub
+			}
+			matched = true
+			return r.apply(sub)
+		})
+	}
+	if !matched {
+		return s, false, false
+	}
+	r.hits.Inc()
+	return s, r.Action == actionDrop, true
+}
+
+func (r *rule) apply(s string) string {
+	switch r.Action {
+	case actionMask:
+		if r.Replacement != "" {
+			return r.Replacement
+		}
+		return strings.Repeat(r.MaskChar, len([]rune(s)))
+	case actionHash:
+		mac := hmac.New(sha256.New, r.hashKey)
+		mac.Write([]byte(s))
+		h := hex.EncodeToString(mac.Sum(nil))
+		if r.HashLength > 0 {
+			return h[:r.HashLength]
+		}
+		return h
+	case actionTruncate:
+		rs := []rune(s)
+		if len(rs) > r.Length {
+			return string(rs[:r.Length])
+		}
+	}
+	return s
+}
+
+func compileRegexes(exprs []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
@@ -0,0 +1,284 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+func hmacHex(key, s string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(s))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+var testset = map[string]struct {
+	processorType string
+	processor     map[string]interface{}
+	tests         []item
+}{
+	"mask_whole_tag": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{
+					"tag-names": []string{"^username$"},
+				},
+			},
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: nil,
+			},
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"username": "admin", "source": "r1"},
+						Values: map[string]interface{}{"username": "admin"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"username": "*****", "source": "r1"},
+						Values: map[string]interface{}{"username": "admin"},
+					},
+				},
+			},
+		},
+	},
+	"mask_detectors": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{
+					"detectors":   []string{"ipv4", "ipv6", "mac", "email"},
+					"replacement": "<redacted>",
+				},
+			},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"peer":      "10.1.1.1",
+							"neighbor":  "2001:db8::1",
+							"interface": "ethernet-1/1",
+						},
+						Values: map[string]interface{}{
+							"description": "link to 192.168.1.254 owned by noc@example.com",
+							"mac":         "00:1A:2b:3c:4D:5e",
+							"time":        "10:20:30",
+							"counter":     42,
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags: map[string]string{
+							"peer":      "<redacted>",
+							"neighbor":  "<redacted>",
+							"interface": "ethernet-1/1",
+						},
+						Values: map[string]interface{}{
+							"description": "link to <redacted> owned by <redacted>",
+							"mac":         "<redacted>",
+							"time":        "10:20:30",
+							"counter":     42,
+						},
+					},
+				},
+			},
+		},
+	},
+	"hash_custom_pattern": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{
+					"value-names": []string{"description$"},
+					"patterns":    []string{`cust-\d+`},
+					"action":      "hash",
+					"hash-key":    "secret",
+					"hash-length": 12,
+				},
+			},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/interface/description": "uplink cust-1234",
+							"/interface/name":        "cust-1234",
+						},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{
+							"/interface/description": "uplink " + hmacHex("secret", "cust-1234")[:12],
+							"/interface/name":        "cust-1234",
+						},
+					},
+				},
+			},
+		},
+	},
+	"truncate": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{
+					"value-names": []string{"serial"},
+					"action":      "truncate",
+					"length":      3,
+				},
+			},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"serial": "ABC123456", "serial_id": 12345},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Values: map[string]interface{}{"serial": "ABC", "serial_id": "123"},
+					},
+				},
+			},
+		},
+	},
+	"drop": {
+		processorType: processorType,
+		processor: map[string]interface{}{
+			"rules": []interface{}{
+				map[string]interface{}{
+					"tag-names":   []string{".*"},
+					"value-names": []string{".*"},
+					"detectors":   []string{"email"},
+					"action":      "drop",
+				},
+			},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"contact": "noc@example.com", "source": "r1"},
+						Values: map[string]interface{}{"owner": "Owner <owner@example.com>", "value": 1},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Tags:   map[string]string{"source": "r1"},
+						Values: map[string]interface{}{"value": 1},
+					},
+				},
+			},
+		},
+	},
+}
+
+func TestEventRedact(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[ts.processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor)
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if len(outs) != len(item.output) {
+						t.Errorf("failed at %s item %d, expected %d events, got %d", name, i, len(item.output), len(outs))
+						return
+					}
+					for j := range outs {
+						if !reflect.DeepEqual(outs[j], item.output[j]) {
+							t.Errorf("failed at %s item %d, index %d, expected %+v, got: %+v", name, i, j, item.output[j], outs[j])
+						}
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", ts.processorType)
+		}
+	}
+}
+
+func TestEventRedactHits(t *testing.T) {
+	p := formatters.EventProcessors[processorType]()
+	err := p.Init(map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{
+				"name":      "hits-test",
+				"detectors": []string{"ipv4"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Apply(&formatters.EventMsg{
+		Tags:   map[string]string{"peer": "10.0.0.1", "source": "r1"},
+		Values: map[string]interface{}{"next-hop": "10.0.0.2 and 10.0.0.3"},
+	})
+	got := testutil.ToFloat64(redactHits.WithLabelValues("hits-test", actionMask))
+	if got != 2 {
+		t.Errorf("expected 2 hits, got %v", got)
+	}
+}
+
+func TestEventRedactInitErrors(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"no_rules": {},
+		"unknown_detector": {
+			"rules": []interface{}{map[string]interface{}{"detectors": []string{"ssn"}}},
+		},
+		"unknown_action": {
+			"rules": []interface{}{map[string]interface{}{"action": "encrypt"}},
+		},
+		"hash_without_key": {
+			"rules": []interface{}{map[string]interface{}{"action": "hash"}},
+		},
+		"bad_pattern": {
+			"rules": []interface{}{map[string]interface{}{"patterns": []string{"("}}},
+		},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := formatters.EventProcessors[processorType]()
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an init error")
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@
 package event_starlark
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -117,6 +118,11 @@ func (p *starlarkProc) validate() error {
 }
 
 func (p *starlarkProc) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	return p.ApplyContext(context.Background(), es...)
+}
+
+// ApplyContext runs the script, cancelling its execution when ctx is done.
+func (p *starlarkProc) ApplyContext(ctx context.Context, es ...*formatters.EventMsg) []*formatters.EventMsg {
 	p.m.Lock()
 	defer p.m.Unlock()
 	numMsgs := len(es)
@@ -142,6 +148,19 @@ func (p *starlarkProc) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg
 	if p.Debug {
 		p.logger.Printf("events input: %v", sevs)
 	}
+	if ctx.Done() != nil {
+		cancelled := make(chan struct{})
+		stop := context.AfterFunc(ctx, func() {
+			p.thread.Cancel(ctx.Err().Error())
+			close(cancelled)
+		})
+		defer func() {
+			if !stop() {
+				<-cancelled
+			}
+			p.thread.Uncancel()
+		}()
+	}
 	r, err := starlark.Call(p.thread, p.applyFn, sevs, nil)
 	if err != nil {
 		if p.Debug {
@@ -9,10 +9,12 @@
 package event_starlark
 
 import (
+	"context"
 	"log"
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/openconfig/gnmic/pkg/formatters"
 )
@@ -766,3 +768,30 @@ def apply(*events):
 		})
 	}
 }
+
+func Test_starlarkProc_ApplyContext(t *testing.T) {
+	p := &starlarkProc{}
+	err := p.Init(map[string]interface{}{
+		"source": `
+def apply(*events):
+  for i in range(events[0].values["n"]):
+    pass
+  return events
+`,
+	}, formatters.WithLogger(log.New(os.Stderr, "test", log.Default().Flags())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	now := time.Now()
+	p.ApplyContext(ctx, &formatters.EventMsg{Name: "ev1", Values: map[string]interface{}{"n": 1000000000}})
+	if d := time.Since(now); d > time.Second {
+		t.Errorf("script was not cancelled, ran for %s", d)
+	}
+	// the thread is usable again after a cancellation.
+	got := p.Apply(&formatters.EventMsg{Name: "ev1", Values: map[string]interface{}{"n": 1}})
+	if len(got) != 1 || got[0].Values["n"] != 1 {
+		t.Errorf("unexpected output after cancellation: %+v", got)
+	}
+}
@@ -0,0 +1,444 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_topk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+const (
+	processorType = "event-topk"
+	loggingPrefix = "[" + processorType + "] "
+
+	defaultK        = 10
+	defaultWindow   = time.Minute
+	defaultRankTag  = "rank"
+	otherRank       = "other"
+	capacityPerK    = 10
+	orderDesc       = "desc"
+	orderAsc        = "asc"
+	modeExact       = "exact"
+	modeApproximate = "approximate"
+)
+
+// topK keeps the top K series by value over tumbling or sliding windows
+// and emits only the ranked series at the end of each window.
+type topK struct {
+	ValueNames  []string      `mapstructure:"value-names,omitempty" json:"value-names,omitempty"`
+	K           int           `mapstructure:"k,omitempty" json:"k,omitempty"`
+	GroupBy     []string      `mapstructure:"group-by,omitempty" json:"group-by,omitempty"`
+	Window      time.Duration `mapstructure:"window,omitempty" json:"window,omitempty"`
+	Slide       time.Duration `mapstructure:"slide,omitempty" json:"slide,omitempty"`
+	Aggregation string        `mapstructure:"aggregation,omitempty" json:"aggregation,omitempty"`
+	Order       string        `mapstructure:"order,omitempty" json:"order,omitempty"`
+	Mode        string        `mapstructure:"mode,omitempty" json:"mode,omitempty"`
+	Capacity    int           `mapstructure:"capacity,omitempty" json:"capacity,omitempty"`
+	RankTag     string        `mapstructure:"rank-tag,omitempty" json:"rank-tag,omitempty"`
+	Other       bool          `mapstructure:"other,omitempty" json:"other,omitempty"`
+	Debug       bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+
+	valueNames []*regexp.Regexp
+	score      func(*series) float64
+
+	m sync.Mutex
+	// bucket duration, the slide for sliding windows,
+	// the window for tumbling windows.
+	bucketDur int64
+	buckets   []*bucket
+	// index of the current bucket
+	cur int
+	// start timestamp of the current bucket
+	curStart int64
+	started  bool
+
+	logger *log.Logger
+}
+
+func init() {
+	formatters.Register(processorType, func() formatters.EventProcessor {
+		return &topK{
+			logger: log.New(io.Discard, "", 0),
+		}
+	})
+}
+
+func (p *topK) Init(cfg interface{}, opts ...formatters.Option) error {
+	err := formatters.DecodeConfig(cfg, p)
+	if err != nil {
+		return err
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if len(p.ValueNames) == 0 {
+		return fmt.Errorf("%s: missing value-names", processorType)
+	}
+	p.valueNames = make([]*regexp.Regexp, 0, len(p.ValueNames))
+	for _, reg := range p.ValueNames {
+		re, err := regexp.Compile(reg)
+		if err != nil {
+			return err
+		}
+		p.valueNames = append(p.valueNames, re)
+	}
+	if p.K <= 0 {
+		p.K = defaultK
+	}
+	if p.Window <= 0 {
+		p.Window = defaultWindow
+	}
+	if p.Slide > p.Window {
+		return fmt.Errorf("%s: slide %s must be lower than the window %s", processorType, p.Slide, p.Window)
+	}
+	if p.Slide > 0 && p.Window%p.Slide != 0 {
+		return fmt.Errorf("%s: window %s must be a multiple of the slide %s", processorType, p.Window, p.Slide)
+	}
+	if p.Aggregation == "" {
+		p.Aggregation = "last"
+	}
+	p.score, err = scoreFn(p.Aggregation)
+	if err != nil {
+		return err
+	}
+	switch p.Order {
+	case "":
+		p.Order = orderDesc
+	case orderDesc, orderAsc:
+	default:
+		return fmt.Errorf("%s: unknown order %q", processorType, p.Order)
+	}
+	switch p.Mode {
+	case "":
+		p.Mode = modeExact
+	case modeExact, modeApproximate:
+	default:
+		return fmt.Errorf("%s: unknown mode %q", processorType, p.Mode)
+	}
+	if p.Mode == modeApproximate && p.Capacity < p.K {
+		p.Capacity = capacityPerK * p.K
+	}
+	if p.RankTag == "" {
+		p.RankTag = defaultRankTag
+	}
+	numBuckets := 1
+	p.bucketDur = int64(p.Window)
+	if p.Slide > 0 {
+		numBuckets = int(p.Window / p.Slide)
+		p.bucketDur = int64(p.Slide)
+	}
+	p.buckets = make([]*bucket, numBuckets)
+	for i := range p.buckets {
+		p.buckets[i] = newBucket()
+	}
+
+	if p.logger.Writer() != io.Discard {
+		b, err := json.Marshal(p)
+		if err != nil {
+			p.logger.Printf("initialized processor '%s': %+v", processorType, p)
+			return nil
+		}
+		p.logger.Printf("initialized processor '%s': %s", processorType, string(b))
+	}
+	return nil
+}
+
+func (p *topK) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	result := make([]*formatters.EventMsg, 0, len(es))
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		matched := false
+		for k, v := range e.Values {
+			if !p.matchValueName(k) {
+				continue
+			}
+			fv, err := formatters.ToFloat(v)
+			if err != nil {
+				p.logger.Printf("failed to convert value %q: %v", k, err)
+				continue
+			}
+			ts := e.Timestamp
+			if ts <= 0 {
+				ts = time.Now().UnixNano()
+			}
+			result = append(result, p.advance(ts)...)
+			p.add(e, k, fv, ts)
+			delete(e.Values, k)
+			matched = true
+		}
+		// the event is passed through if it has values
+		// other than the ranked ones.
+		if !matched || len(e.Values) > 0 || len(e.Deletes) > 0 {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Flush emits the windows that ended more than one bucket duration before now,
+// so that the last window is emitted when no more events are received.
+// The grace period accounts for the events timestamped by the targets clocks.
+func (p *topK) Flush(now time.Time) []*formatters.EventMsg {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if !p.started {
+		return nil
+	}
+	return p.advance(now.UnixNano() - p.bucketDur)
+}
+
+func (p *topK) WithLogger(l *log.Logger) {
+	if p.Debug && l != nil {
+		p.logger = log.New(l.Writer(), loggingPrefix, l.Flags())
+	} else if p.Debug {
+		p.logger = log.New(os.Stderr, loggingPrefix, utils.DefaultLoggingFlags)
+	}
+}
+
+func (p *topK) WithTargets(tcs map[string]*types.TargetConfig) {}
+
+func (p *topK) WithActions(act map[string]map[string]interface{}) {}
+
+func (p *topK) WithProcessors(procs map[string]map[string]any) {}
+
+func (p *topK) matchValueName(k string) bool {
+	for _, re := range p.valueNames {
+		if re.MatchString(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// advance moves the current bucket to the one containing ts,
+// it returns the events emitted for each window closed in the process.
+// Timestamps older than the current bucket are accounted in the current bucket.
+func (p *topK) advance(ts int64) []*formatters.EventMsg {
+	start := ts - ts%p.bucketDur
+	if !p.started {
+		p.curStart = start
+		p.started = true
+		return nil
+	}
+	var evs []*formatters.EventMsg
+	numBuckets := int64(len(p.buckets))
+	for p.curStart < start {
+		evs = append(evs, p.emit(p.curStart+p.bucketDur)...)
+		p.cur = (p.cur + 1) % len(p.buckets)
+		p.buckets[p.cur] = newBucket()
+		p.curStart += p.bucketDur
+		// all the buckets are empty, skip to the new bucket
+		if start-p.curStart >= numBuckets*p.bucketDur && p.empty() {
+			p.curStart = start
+		}
+	}
+	return evs
+}
+
+func (p *topK) empty() bool {
+	for _, b := range p.buckets {
+		if len(b.groups) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *topK) add(e *formatters.EventMsg, valueName string, v float64, ts int64) {
+	b := p.buckets[p.cur]
+	gk := p.groupKey(e.Tags)
+	tb, ok := b.groups[gk]
+	if !ok {
+		tb = newTable(p.Mode == modeApproximate, p.less, p.score)
+		b.groups[gk] = tb
+	}
+	sk := formatters.SeriesKey(e.Name, valueName, e.Tags)
+	s, ok := tb.series[sk]
+	if ok {
+		s.update(v, ts)
+		tb.fix(s)
+		return
+	}
+	s = &series{
+		name:      e.Name,
+		valueName: valueName,
+		tags:      copyTags(e.Tags),
+		min:       v,
+		max:       v,
+	}
+	if tb.h != nil && len(tb.series) >= p.Capacity {
+		switch p.Aggregation {
+		case "sum", "count", "avg":
+			// Space-Saving: the new series replaces the lowest ranked one
+			// and inherits its accumulated value as its maximum overestimation.
+			evicted := tb.evict()
+			s.sum = evicted.sum
+			s.count = evicted.count
+			s.err = p.score(evicted)
+		default:
+			// the new series is only admitted if it ranks higher
+			// than the lowest ranked one.
+			if !p.less(p.score(tb.lowest()), v) {
+				return
+			}
+			tb.evict()
+		}
+	}
+	s.update(v, ts)
+	tb.push(sk, s)
+}
+
+// emit builds the ranked events of the window ending at end.
+func (p *topK) emit(end int64) []*formatters.EventMsg {
+	groups := make(map[string]map[string]*series)
+	for _, b := range p.buckets {
+		for gk, tb := range b.groups {
+			g, ok := groups[gk]
+			if !ok {
+				g = make(map[string]*series, len(tb.series))
+				groups[gk] = g
+			}
+			for sk, s := range tb.series {
+				if ms, ok := g[sk]; ok {
+					ms.merge(s)
+					continue
+				}
+				ns := *s
+				g[sk] = &ns
+			}
+		}
+	}
+	gks := make([]string, 0, len(groups))
+	for gk := range groups {
+		gks = append(gks, gk)
+	}
+	sort.Strings(gks)
+
+	evs := make([]*formatters.EventMsg, 0)
+	for _, gk := range gks {
+		ss := make([]*series, 0, len(groups[gk]))
+		for _, s := range groups[gk] {
+			ss = append(ss, s)
+		}
+		sort.SliceStable(ss, func(i, j int) bool {
+			si, sj := p.score(ss[i]), p.score(ss[j])
+			if si == sj {
+				return formatters.SeriesKey(ss[i].name, ss[i].valueName, ss[i].tags) <
+					formatters.SeriesKey(ss[j].name, ss[j].valueName, ss[j].tags)
+			}
+			return p.less(sj, si)
+		})
+		for i, s := range ss {
+			if i >= p.K {
+				break
+			}
+			tags := copyTags(s.tags)
+			tags[p.RankTag] = strconv.Itoa(i + 1)
+			evs = append(evs, &formatters.EventMsg{
+				Name:      s.name,
+				Timestamp: end,
+				Tags:      tags,
+				Values:    map[string]interface{}{s.valueName: p.score(s)},
+			})
+		}
+		if !p.Other || len(ss) <= p.K {
+			continue
+		}
+		var other float64
+		for _, s := range ss[p.K:] {
+			other += p.score(s)
+		}
+		tags := make(map[string]string, len(p.GroupBy)+1)
+		for _, t := range p.GroupBy {
+			if v, ok := ss[0].tags[t]; ok {
+				tags[t] = v
+			}
+		}
+		tags[p.RankTag] = otherRank
+		evs = append(evs, &formatters.EventMsg{
+			Name:      ss[0].name,
+			Timestamp: end,
+			Tags:      tags,
+			Values:    map[string]interface{}{ss[0].valueName: other},
+		})
+	}
+	if p.Debug {
+		p.logger.Printf("window ending at %d: emitting %d events", end, len(evs))
+	}
+	return evs
+}
+
+// less reports whether score a ranks lower than score b.
+func (p *topK) less(a, b float64) bool {
+	if p.Order == orderAsc {
+		return a > b
+	}
+	return a < b
+}
+
+func (p *topK) groupKey(tags map[string]string) string {
+	if len(p.GroupBy) == 0 {
+		return ""
+	}
+	sb := new(strings.Builder)
+	for _, t := range p.GroupBy {
+		sb.WriteString(tags[t])
+		sb.WriteString("\x00")
+	}
+	return sb.String()
+}
+
+func scoreFn(agg string) (func(*series) float64, error) {
+	switch agg {
+	case "last":
+		return func(s *series) float64 { return s.last }, nil
+	case "min":
+		return func(s *series) float64 { return s.min }, nil
+	case "max":
+		return func(s *series) float64 { return s.max }, nil
+	case "sum":
+		return func(s *series) float64 { return s.sum }, nil
+	case "count":
+		return func(s *series) float64 { return float64(s.count) }, nil
+	case "avg":
+		return func(s *series) float64 {
+			if s.count == 0 {
+				return 0
+			}
+			return s.sum / float64(s.count)
+		}, nil
+	}
+	return nil, fmt.Errorf("%s: unknown aggregation %q", processorType, agg)
+}
+
+func copyTags(tags map[string]string) map[string]string {
+	ntags := make(map[string]string, len(tags)+1)
+	for k, v := range tags {
+		ntags[k] = v
+	}
+	return ntags
+}
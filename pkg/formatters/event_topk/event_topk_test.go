@@ -0,0 +1,290 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_topk
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+type item struct {
+	input  []*formatters.EventMsg
+	output []*formatters.EventMsg
+}
+
+const sec = int64(time.Second)
+
+func ifEvent(ts int64, ifName string, v interface{}) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: ts,
+		Tags:      map[string]string{"source": "r1", "interface_name": ifName},
+		Values:    map[string]interface{}{"in-octets": v},
+	}
+}
+
+func rankEvent(ts int64, ifName, rank string, v float64) *formatters.EventMsg {
+	return &formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: ts,
+		Tags:      map[string]string{"source": "r1", "interface_name": ifName, "rank": rank},
+		Values:    map[string]interface{}{"in-octets": v},
+	}
+}
+
+var testset = map[string]struct {
+	processor map[string]interface{}
+	tests     []item
+}{
+	"tumbling_exact": {
+		processor: map[string]interface{}{
+			"value-names": []string{"in-octets"},
+			"k":           2,
+			"window":      "10s",
+		},
+		tests: []item{
+			{
+				input:  nil,
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					ifEvent(1*sec, "e1", 10),
+					ifEvent(1*sec, "e2", "30"),
+					ifEvent(1*sec, "e3", uint64(20)),
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					ifEvent(2*sec, "e1", 40),
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					ifEvent(11*sec, "e1", 1),
+				},
+				output: []*formatters.EventMsg{
+					rankEvent(10*sec, "e1", "1", 40),
+					rankEvent(10*sec, "e2", "2", 30),
+				},
+			},
+		},
+	},
+	"tumbling_other_asc_max": {
+		processor: map[string]interface{}{
+			"value-names": []string{"in-octets"},
+			"k":           1,
+			"window":      "10s",
+			"aggregation": "max",
+			"order":       "asc",
+			"other":       true,
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					ifEvent(1*sec, "e1", 10),
+					ifEvent(2*sec, "e1", 50),
+					ifEvent(1*sec, "e2", 30),
+					ifEvent(1*sec, "e3", 20),
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					ifEvent(12*sec, "e1", 1),
+				},
+				output: []*formatters.EventMsg{
+					rankEvent(10*sec, "e3", "1", 20),
+					{
+						Name:      "sub1",
+						Timestamp: 10 * sec,
+						Tags:      map[string]string{"rank": "other"},
+						Values:    map[string]interface{}{"in-octets": float64(80)},
+					},
+				},
+			},
+		},
+	},
+	"sliding_sum": {
+		processor: map[string]interface{}{
+			"value-names": []string{"in-octets"},
+			"k":           1,
+			"window":      "20s",
+			"slide":       "10s",
+			"aggregation": "sum",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					ifEvent(1*sec, "e1", 10),
+					ifEvent(1*sec, "e2", 5),
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					ifEvent(11*sec, "e2", 10),
+				},
+				output: []*formatters.EventMsg{
+					rankEvent(10*sec, "e1", "1", 10),
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					ifEvent(21*sec, "e1", 1),
+				},
+				output: []*formatters.EventMsg{
+					rankEvent(20*sec, "e2", "1", 15),
+				},
+			},
+		},
+	},
+	"group_by_pass_through": {
+		processor: map[string]interface{}{
+			"value-names": []string{"in-octets"},
+			"k":           1,
+			"window":      "10s",
+			"group-by":    []string{"source"},
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					ifEvent(1*sec, "e1", 10),
+					ifEvent(1*sec, "e2", 20),
+					{
+						Name:      "sub1",
+						Timestamp: 1 * sec,
+						Tags:      map[string]string{"source": "r1"},
+						Values:    map[string]interface{}{"oper-state": "up"},
+					},
+				},
+				output: []*formatters.EventMsg{
+					{
+						Name:      "sub1",
+						Timestamp: 1 * sec,
+						Tags:      map[string]string{"source": "r1"},
+						Values:    map[string]interface{}{"oper-state": "up"},
+					},
+				},
+			},
+			{
+				input: []*formatters.EventMsg{
+					ifEvent(10*sec, "e1", 1),
+				},
+				output: []*formatters.EventMsg{
+					rankEvent(10*sec, "e2", "1", 20),
+				},
+			},
+		},
+	},
+	"approximate": {
+		processor: map[string]interface{}{
+			"value-names": []string{"in-octets"},
+			"k":           2,
+			"capacity":    2,
+			"window":      "10s",
+			"mode":        "approximate",
+			"aggregation": "max",
+		},
+		tests: []item{
+			{
+				input: []*formatters.EventMsg{
+					ifEvent(1*sec, "e1", 10),
+					ifEvent(1*sec, "e2", 30),
+					ifEvent(1*sec, "e3", 20),
+					ifEvent(1*sec, "e4", 5),
+				},
+				output: []*formatters.EventMsg{},
+			},
+			{
+				input: []*formatters.EventMsg{
+					ifEvent(10*sec, "e1", 1),
+				},
+				output: []*formatters.EventMsg{
+					rankEvent(10*sec, "e2", "1", 30),
+					rankEvent(10*sec, "e3", "2", 20),
+				},
+			},
+		},
+	},
+}
+
+func TestEventTopK(t *testing.T) {
+	for name, ts := range testset {
+		if pi, ok := formatters.EventProcessors[processorType]; ok {
+			t.Log("found processor")
+			p := pi()
+			err := p.Init(ts.processor)
+			if err != nil {
+				t.Errorf("failed to initialize processors: %v", err)
+				return
+			}
+			t.Logf("processor: %+v", p)
+			for i, item := range ts.tests {
+				t.Run(name, func(t *testing.T) {
+					t.Logf("running test item %d", i)
+					outs := p.Apply(item.input...)
+					if !reflect.DeepEqual(outs, item.output) {
+						t.Errorf("failed at %s item %d, expected: %+v", name, i, item.output)
+						t.Errorf("failed at %s item %d,      got: %+v", name, i, outs)
+					}
+				})
+			}
+		} else {
+			t.Errorf("event processor %s not found", processorType)
+		}
+	}
+}
+
+func TestEventTopKFlush(t *testing.T) {
+	p := formatters.EventProcessors[processorType]().(*topK)
+	err := p.Init(map[string]interface{}{
+		"value-names": []string{"in-octets"},
+		"k":           1,
+		"window":      "10s",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outs := p.Flush(time.Unix(100, 0)); len(outs) != 0 {
+		t.Fatalf("expected no events before the first window, got %v", outs)
+	}
+	p.Apply(ifEvent(1*sec, "e1", 10), ifEvent(1*sec, "e2", 30))
+	// the window ended, the grace period did not.
+	if outs := p.Flush(time.Unix(15, 0)); len(outs) != 0 {
+		t.Fatalf("expected no events within the grace period, got %v", outs)
+	}
+	outs := p.Flush(time.Unix(20, 0))
+	want := []*formatters.EventMsg{rankEvent(10*sec, "e2", "1", 30)}
+	if !reflect.DeepEqual(outs, want) {
+		t.Errorf("expected the last window to be flushed, got %v", outs)
+	}
+}
+
+func TestEventTopKInit(t *testing.T) {
+	for name, cfg := range map[string]map[string]interface{}{
+		"missing_value_names": {},
+		"bad_slide":           {"value-names": []string{".*"}, "window": "10s", "slide": "3s"},
+		"bad_aggregation":     {"value-names": []string{".*"}, "aggregation": "median"},
+		"bad_order":           {"value-names": []string{".*"}, "order": "up"},
+		"bad_mode":            {"value-names": []string{".*"}, "mode": "fast"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := formatters.EventProcessors[processorType]()
+			if err := p.Init(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package event_topk
+
+import (
+	"container/heap"
+)
+
+// series is the aggregated state of a single series within a bucket.
+type series struct {
+	name      string
+	valueName string
+	tags      map[string]string
+
+	sum    float64
+	count  int64
+	min    float64
+	max    float64
+	last   float64
+	lastTs int64
+	// maximum overestimation of sum, count and avg in approximate mode.
+	err float64
+
+	key   string
+	index int
+}
+
+func (s *series) update(v float64, ts int64) {
+	s.sum += v
+	s.count++
+	if v < s.min {
+		s.min = v
+	}
+	if v > s.max {
+		s.max = v
+	}
+	if ts >= s.lastTs {
+		s.last = v
+		s.lastTs = ts
+	}
+}
+
+func (s *series) merge(o *series) {
+	s.sum += o.sum
+	s.count += o.count
+	s.err += o.err
+	if o.min < s.min {
+		s.min = o.min
+	}
+	if o.max > s.max {
+		s.max = o.max
+	}
+	if o.lastTs >= s.lastTs {
+		s.last = o.last
+		s.lastTs = o.lastTs
+	}
+}
+
+type bucket struct {
+	groups map[string]*table
+}
+
+func newBucket() *bucket {
+	return &bucket{groups: make(map[string]*table)}
+}
+
+// table holds the series of a group within a bucket.
+// In approximate mode, the series are also kept in a heap
+// whose root is the lowest ranked series, which is evicted
+// when the table reaches its capacity.
+type table struct {
+	series map[string]*series
+	h      *seriesHeap
+}
+
+func newTable(bounded bool, less func(a, b float64) bool, score func(*series) float64) *table {
+	t := &table{series: make(map[string]*series)}
+	if bounded {
+		t.h = &seriesHeap{less: less, score: score}
+	}
+	return t
+}
+
+func (t *table) push(key string, s *series) {
+	s.key = key
+	t.series[key] = s
+	if t.h != nil {
+		heap.Push(t.h, s)
+	}
+}
+
+func (t *table) fix(s *series) {
+	if t.h != nil {
+		heap.Fix(t.h, s.index)
+	}
+}
+
+func (t *table) lowest() *series {
+	return t.h.items[0]
+}
+
+func (t *table) evict() *series {
+	s := heap.Pop(t.h).(*series)
+	delete(t.series, s.key)
+	return s
+}
+
+// seriesHeap ranks series by their current score.
+type seriesHeap struct {
+	items []*series
+	less  func(a, b float64) bool
+	score func(*series) float64
+}
+
+func (h *seriesHeap) Len() int { return len(h.items) }
+
+func (h *seriesHeap) Less(i, j int) bool {
+	return h.less(h.score(h.items[i]), h.score(h.items[j]))
+}
+
+func (h *seriesHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+
+func (h *seriesHeap) Push(x any) {
+	s := x.(*series)
+	s.index = len(h.items)
+	h.items = append(h.items, s)
+}
+
+func (h *seriesHeap) Pop() any {
+	n := len(h.items)
+	s := h.items[n-1]
+	h.items[n-1] = nil
+	h.items = h.items[:n-1]
+	s.index = -1
+	return s
+}
@@ -0,0 +1,100 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package formatters
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// FlushPeriod is the period at which the event processors holding events are flushed.
+var FlushPeriod = time.Second
+
+// FlushingEventProcessor is implemented by the event processors holding events
+// that become due without new input, e.g an incident cleared after a delay.
+type FlushingEventProcessor interface {
+	// Flush returns the events due at now.
+	Flush(now time.Time) []*EventMsg
+}
+
+func flusher(ep EventProcessor) (FlushingEventProcessor, bool) {
+	for {
+		if f, ok := ep.(FlushingEventProcessor); ok {
+			return f, true
+		}
+		w, ok := ep.(wrapper)
+		if !ok {
+			return nil, false
+		}
+		ep = w.unwrap()
+	}
+}
+
+// HasFlushingProcessors reports whether one of evps holds events until they are flushed.
+func HasFlushingProcessors(evps []EventProcessor) bool {
+	for _, ep := range evps {
+		if _, ok := flusher(ep); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// FlushEventProcessors flushes the processors of the chain evps,
+// the events flushed by a processor go through the processors following it.
+func FlushEventProcessors(evps []EventProcessor, now time.Time, logger *log.Logger) []*EventMsg {
+	var res []*EventMsg
+	for _, ep := range evps {
+		if len(res) > 0 {
+			res = ep.Apply(res...)
+		}
+		// a processor is not flushed while a timed out run is in flight.
+		if f, ok := flusher(ep); ok && !abandonedRun(ep) {
+			res = append(res, safeFlush(f, now, logger)...)
+		}
+	}
+	return res
+}
+
+func safeFlush(f FlushingEventProcessor, now time.Time, logger *log.Logger) (res []*EventMsg) {
+	defer func() {
+		if r := recover(); r != nil {
+			res = nil
+			if logger != nil {
+				logger.Printf("event processor flush panic: %v", r)
+			}
+		}
+	}()
+	return f.Flush(now)
+}
+
+// StartFlush flushes evps every FlushPeriod until ctx is done
+// and passes the flushed events to write.
+// It does not start if none of the processors holds events.
+func StartFlush(ctx context.Context, evps []EventProcessor, logger *log.Logger, write func([]*EventMsg)) {
+	if !HasFlushingProcessors(evps) {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(FlushPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				evs := FlushEventProcessors(evps, now, logger)
+				if len(evs) > 0 {
+					write(evs)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,75 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package formatters
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// holdingProcessor holds the events until they are flushed.
+type holdingProcessor struct {
+	testProcessor
+	m    sync.Mutex
+	held []*EventMsg
+}
+
+func (p *holdingProcessor) Apply(es ...*EventMsg) []*EventMsg {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.held = append(p.held, es...)
+	return nil
+}
+
+func (p *holdingProcessor) Flush(now time.Time) []*EventMsg {
+	p.m.Lock()
+	defer p.m.Unlock()
+	res := p.held
+	p.held = nil
+	return res
+}
+
+func TestFlushEventProcessors(t *testing.T) {
+	hp := &holdingProcessor{}
+	evps := []EventProcessor{
+		&tracedProcessor{EventProcessor: newGuardedProcessor(hp, "hold", "hold", nil)},
+		&testProcessor{apply: func(es ...*EventMsg) []*EventMsg {
+			for _, e := range es {
+				e.Tags = map[string]string{"next": "true"}
+			}
+			return es
+		}},
+	}
+	if !HasFlushingProcessors(evps) || HasFlushingProcessors(evps[1:]) {
+		t.Fatal("unexpected flushing processors detection")
+	}
+	evps[0].Apply(&EventMsg{Name: "e1"})
+	res := FlushEventProcessors(evps, time.Now(), nil)
+	if len(res) != 1 || res[0].Name != "e1" || res[0].Tags["next"] != "true" {
+		t.Fatalf("expected the held event to go through the next processor, got %v", res)
+	}
+
+	FlushPeriod = 10 * time.Millisecond
+	defer func() { FlushPeriod = time.Second }()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	written := make(chan []*EventMsg, 1)
+	StartFlush(ctx, evps, nil, func(evs []*EventMsg) { written <- evs })
+	evps[0].Apply(&EventMsg{Name: "e2"})
+	select {
+	case evs := <-written:
+		if len(evs) != 1 || evs[0].Name != "e2" {
+			t.Errorf("unexpected flushed events %v", evs)
+		}
+	case <-time.After(time.Second):
+		t.Error("timeout waiting for the flushed events")
+	}
+}
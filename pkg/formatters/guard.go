@@ -0,0 +1,559 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package formatters
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	OnOpenBypass  = "bypass"
+	OnOpenDisable = "disable"
+
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitDisabled = "disabled"
+
+	defaultGuardResetAfter     = time.Minute
+	defaultGuardQuarantineSize = 100
+)
+
+// failClosedTypes are the processor types never bypassed:
+// the events are dropped when their circuit is open
+// and they are quarantined without their content.
+var failClosedTypes = map[string]struct{}{
+	"event-redact": {},
+}
+
+var (
+	guardPanics = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gnmic",
+		Subsystem: "processor",
+		Name:      "panics_total",
+		Help:      "Number of panics recovered from an event processor",
+	}, []string{"processor", "type"})
+	guardTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gnmic",
+		Subsystem: "processor",
+		Name:      "timeouts_total",
+		Help:      "Number of event processor runs that exceeded their time budget",
+	}, []string{"processor", "type"})
+	guardQuarantined = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gnmic",
+		Subsystem: "processor",
+		Name:      "quarantined_events_total",
+		Help:      "Number of events quarantined after an event processor failure",
+	}, []string{"processor", "type"})
+	guardBypassed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gnmic",
+		Subsystem: "processor",
+		Name:      "bypassed_events_total",
+		Help:      "Number of events that skipped an event processor with an open circuit or an abandoned run in flight",
+	}, []string{"processor", "type"})
+	guardDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gnmic",
+		Subsystem: "processor",
+		Name:      "dropped_events_total",
+		Help:      "Number of events dropped by a fail-closed event processor with an open circuit or an abandoned run in flight",
+	}, []string{"processor", "type"})
+	guardCircuitOpen = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "gnmic",
+		Subsystem: "processor",
+		Name:      "circuit_open",
+		Help:      "1 if the event processor circuit breaker is open or disabled, 0 otherwise",
+	}, []string{"processor", "type"})
+)
+
+func init() {
+	RegisterMetrics(guardPanics, guardTimeouts, guardQuarantined, guardBypassed, guardDropped, guardCircuitOpen)
+}
+
+// GuardPolicy defines the time budget and the circuit breaker policy of an event processor.
+type GuardPolicy struct {
+	// maximum duration of a processor run, 0 means no budget.
+	// Only the processors implementing ContextEventProcessor are cancelled
+	// when it is exceeded, the others keep running in the background
+	// and their result is discarded. The processor is skipped
+	// until the abandoned run returns.
+	Timeout time.Duration `mapstructure:"timeout,omitempty" json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// number of consecutive failures (panics or timeouts) opening the circuit,
+	// 0 disables the circuit breaker.
+	MaxFailures int `mapstructure:"max-failures,omitempty" json:"max-failures,omitempty" yaml:"max-failures,omitempty"`
+	// what happens when the circuit opens: `bypass` or `disable`.
+	OnOpen string `mapstructure:"on-open,omitempty" json:"on-open,omitempty" yaml:"on-open,omitempty"`
+	// duration after which a bypassed processor is tried again.
+	ResetAfter time.Duration `mapstructure:"reset-after,omitempty" json:"reset-after,omitempty" yaml:"reset-after,omitempty"`
+}
+
+// GuardConfig is the event processors guard configuration.
+type GuardConfig struct {
+	GuardPolicy `mapstructure:",squash" json:",inline" yaml:",inline"`
+	// maximum number of quarantined events kept in memory.
+	QuarantineSize int `mapstructure:"quarantine-size,omitempty" json:"quarantine-size,omitempty" yaml:"quarantine-size,omitempty"`
+	// per processor name policies, overriding the global one.
+	Processors map[string]*GuardPolicy `mapstructure:"processors,omitempty" json:"processors,omitempty" yaml:"processors,omitempty"`
+}
+
+// ContextEventProcessor is implemented by the event processors
+// that can abort a run when its time budget is exceeded.
+type ContextEventProcessor interface {
+	ApplyContext(ctx context.Context, es ...*EventMsg) []*EventMsg
+}
+
+// QuarantinedEvent is an event that made a processor fail.
+// Event is the event as left by the failed run,
+// it is nil for the fail-closed processors.
+type QuarantinedEvent struct {
+	Time      time.Time `json:"time"`
+	Processor string    `json:"processor"`
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason"`
+	Event     *EventMsg `json:"event,omitempty"`
+}
+
+// CircuitState is the circuit breaker state of a processor.
+type CircuitState struct {
+	Processor           string    `json:"processor"`
+	Type                string    `json:"type"`
+	State               string    `json:"state"`
+	ConsecutiveFailures int       `json:"consecutive-failures"`
+	Panics              uint64    `json:"panics"`
+	Timeouts            uint64    `json:"timeouts"`
+	LastFailure         string    `json:"last-failure,omitempty"`
+	LastFailureTime     time.Time `json:"last-failure-time,omitzero"`
+	OpenedAt            time.Time `json:"opened-at,omitzero"`
+}
+
+// Guard recovers the event processors panics, enforces their time budget
+// and keeps a circuit breaker per processor name.
+type Guard struct {
+	cfg *GuardConfig
+
+	m          *sync.Mutex
+	circuits   map[string]*CircuitState
+	quarantine []*QuarantinedEvent
+}
+
+var (
+	guard        atomic.Pointer[Guard]
+	defaultGuard = NewGuard(nil)
+)
+
+// SetGuard sets the Guard wrapping the processors runs.
+// A nil Guard restores the default one, which only recovers panics.
+func SetGuard(g *Guard) {
+	guard.Store(g)
+}
+
+// GetGuard returns the current Guard.
+func GetGuard() *Guard {
+	if g := guard.Load(); g != nil {
+		return g
+	}
+	return defaultGuard
+}
+
+func NewGuard(cfg *GuardConfig) *Guard {
+	if cfg == nil {
+		cfg = new(GuardConfig)
+	}
+	if cfg.QuarantineSize <= 0 {
+		cfg.QuarantineSize = defaultGuardQuarantineSize
+	}
+	setPolicyDefaults(&cfg.GuardPolicy)
+	for n, p := range cfg.Processors {
+		if p == nil {
+			p = new(GuardPolicy)
+			cfg.Processors[n] = p
+		}
+		if p.Timeout == 0 {
+			p.Timeout = cfg.Timeout
+		}
+		if p.MaxFailures == 0 {
+			p.MaxFailures = cfg.MaxFailures
+		}
+		if p.OnOpen == "" {
+			p.OnOpen = cfg.OnOpen
+		}
+		if p.ResetAfter == 0 {
+			p.ResetAfter = cfg.ResetAfter
+		}
+		setPolicyDefaults(p)
+	}
+	return &Guard{
+		cfg:        cfg,
+		m:          new(sync.Mutex),
+		circuits:   make(map[string]*CircuitState),
+		quarantine: make([]*QuarantinedEvent, 0),
+	}
+}
+
+func setPolicyDefaults(p *GuardPolicy) {
+	if p.OnOpen == "" {
+		p.OnOpen = OnOpenBypass
+	}
+	if p.ResetAfter <= 0 {
+		p.ResetAfter = defaultGuardResetAfter
+	}
+}
+
+// Validate checks the guard policies.
+func (c *GuardConfig) Validate() error {
+	if err := c.GuardPolicy.validate(); err != nil {
+		return err
+	}
+	for n, p := range c.Processors {
+		if p == nil {
+			continue
+		}
+		if err := p.validate(); err != nil {
+			return fmt.Errorf("processor %q: %w", n, err)
+		}
+	}
+	return nil
+}
+
+func (p *GuardPolicy) validate() error {
+	switch p.OnOpen {
+	case "", OnOpenBypass, OnOpenDisable:
+	default:
+		return fmt.Errorf("unknown on-open policy %q, expected %q or %q", p.OnOpen, OnOpenBypass, OnOpenDisable)
+	}
+	if p.Timeout < 0 {
+		return fmt.Errorf("negative timeout %s", p.Timeout)
+	}
+	if p.MaxFailures < 0 {
+		return fmt.Errorf("negative max-failures %d", p.MaxFailures)
+	}
+	return nil
+}
+
+func (g *Guard) Config() *GuardConfig {
+	return g.cfg
+}
+
+func (g *Guard) policy(name string) *GuardPolicy {
+	if p, ok := g.cfg.Processors[name]; ok {
+		return p
+	}
+	return &g.cfg.GuardPolicy
+}
+
+// Circuits returns the circuit breakers states, one per processor name.
+func (g *Guard) Circuits() []*CircuitState {
+	g.m.Lock()
+	defer g.m.Unlock()
+	res := make([]*CircuitState, 0, len(g.circuits))
+	for _, c := range g.circuits {
+		nc := *c
+		res = append(res, &nc)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Processor < res[j].Processor
+	})
+	return res
+}
+
+// Quarantined returns the quarantined events, oldest first.
+// An empty processor name matches all processors.
+func (g *Guard) Quarantined(processor string) []*QuarantinedEvent {
+	g.m.Lock()
+	defer g.m.Unlock()
+	res := make([]*QuarantinedEvent, 0, len(g.quarantine))
+	for _, q := range g.quarantine {
+		if processor != "" && q.Processor != processor {
+			continue
+		}
+		res = append(res, q)
+	}
+	return res
+}
+
+// Reset closes the circuits and clears the quarantined events.
+// An empty processor name resets all processors.
+func (g *Guard) Reset(processor string) {
+	g.m.Lock()
+	defer g.m.Unlock()
+	for n, c := range g.circuits {
+		if processor != "" && n != processor {
+			continue
+		}
+		guardCircuitOpen.WithLabelValues(c.Processor, c.Type).Set(0)
+		delete(g.circuits, n)
+	}
+	q := g.quarantine[:0]
+	for _, qe := range g.quarantine {
+		if processor != "" && qe.Processor != processor {
+			q = append(q, qe)
+		}
+	}
+	g.quarantine = q
+}
+
+func (g *Guard) circuit(p *guardedProcessor) *CircuitState {
+	c, ok := g.circuits[p.name]
+	if !ok {
+		c = &CircuitState{Processor: p.name, Type: p.typ, State: CircuitClosed}
+		g.circuits[p.name] = c
+	}
+	return c
+}
+
+// allow reports whether the processor p can run.
+// A bypassed processor is tried again once its reset-after duration elapsed.
+func (g *Guard) allow(p *guardedProcessor, pol *GuardPolicy) bool {
+	g.m.Lock()
+	defer g.m.Unlock()
+	c, ok := g.circuits[p.name]
+	if !ok {
+		return true
+	}
+	switch c.State {
+	case CircuitOpen:
+		return time.Since(c.OpenedAt) >= pol.ResetAfter
+	case CircuitDisabled:
+		return false
+	}
+	return true
+}
+
+func (g *Guard) success(p *guardedProcessor) {
+	g.m.Lock()
+	defer g.m.Unlock()
+	c, ok := g.circuits[p.name]
+	if !ok || (c.ConsecutiveFailures == 0 && c.State == CircuitClosed) {
+		return
+	}
+	if c.State == CircuitOpen {
+		p.logger.Printf("processor %q of type=%s recovered, closing its circuit", p.name, p.typ)
+		guardCircuitOpen.WithLabelValues(p.name, p.typ).Set(0)
+	}
+	c.State = CircuitClosed
+	c.ConsecutiveFailures = 0
+}
+
+func (g *Guard) failure(p *guardedProcessor, pol *GuardPolicy, f *failure) {
+	g.m.Lock()
+	defer g.m.Unlock()
+	c := g.circuit(p)
+	if f.timeout {
+		c.Timeouts++
+	} else {
+		c.Panics++
+	}
+	c.LastFailure = f.reason
+	c.LastFailureTime = time.Now()
+	c.ConsecutiveFailures++
+	if pol.MaxFailures <= 0 {
+		return
+	}
+	// a failed retry of an open circuit reopens it for another reset-after period.
+	if c.State == CircuitOpen || c.ConsecutiveFailures >= pol.MaxFailures {
+		if c.State == CircuitClosed {
+			p.logger.Printf("processor %q of type=%s failed %d consecutive times, opening its circuit: on-open=%s",
+				p.name, p.typ, c.ConsecutiveFailures, pol.OnOpen)
+		}
+		c.State = CircuitOpen
+		if pol.OnOpen == OnOpenDisable {
+			c.State = CircuitDisabled
+		}
+		c.OpenedAt = time.Now()
+		guardCircuitOpen.WithLabelValues(p.name, p.typ).Set(1)
+	}
+}
+
+func (g *Guard) addQuarantined(p *guardedProcessor, reason string, es []*EventMsg) {
+	guardQuarantined.WithLabelValues(p.name, p.typ).Add(float64(len(es)))
+	now := time.Now()
+	g.m.Lock()
+	defer g.m.Unlock()
+	for _, e := range es {
+		if e == nil {
+			continue
+		}
+		p.logger.Printf("processor %q of type=%s: quarantined event %s: %s", p.name, p.typ, eventID(e), reason)
+		if len(g.quarantine) >= g.cfg.QuarantineSize {
+			g.quarantine = g.quarantine[1:]
+		}
+		qe := &QuarantinedEvent{
+			Time:      now,
+			Processor: p.name,
+			Type:      p.typ,
+			Reason:    reason,
+		}
+		if !p.failClosed() {
+			qe.Event = e
+		}
+		g.quarantine = append(g.quarantine, qe)
+	}
+}
+
+// guardedProcessor wraps an EventProcessor and runs it under the current Guard.
+type guardedProcessor struct {
+	EventProcessor
+	name   string
+	typ    string
+	logger *log.Logger
+	// set while a timed out run is still in flight,
+	// the processor is not run concurrently with it.
+	abandoned atomic.Bool
+}
+
+func newGuardedProcessor(ep EventProcessor, name, typ string, logger *log.Logger) *guardedProcessor {
+	if logger == nil {
+		logger = log.New(io.Discard, "", 0)
+	}
+	return &guardedProcessor{EventProcessor: ep, name: name, typ: typ, logger: logger}
+}
+
+func (p *guardedProcessor) unwrap() EventProcessor { return p.EventProcessor }
+
+// abandonedRun reports whether ep is guarded and has a timed out run in flight.
+func abandonedRun(ep EventProcessor) bool {
+	for {
+		if p, ok := ep.(*guardedProcessor); ok {
+			return p.abandoned.Load()
+		}
+		w, ok := ep.(wrapper)
+		if !ok {
+			return false
+		}
+		ep = w.unwrap()
+	}
+}
+
+func (p *guardedProcessor) failClosed() bool {
+	_, ok := failClosedTypes[p.typ]
+	return ok
+}
+
+// failure describes a failed processor run.
+type failure struct {
+	timeout bool
+	reason  string
+	// closed when the timed out run returns.
+	done chan struct{}
+}
+
+// Apply runs the processor under the current Guard.
+// The events of a failed run are quarantined: on a panic, the whole batch
+// is quarantined as left by the processor. After a timeout, they are
+// quarantined once the abandoned run returns, since it still holds them.
+// Until then, the processor is skipped as if its circuit was open.
+func (p *guardedProcessor) Apply(es ...*EventMsg) []*EventMsg {
+	if len(es) == 0 {
+		if p.abandoned.Load() {
+			return nil
+		}
+		return p.EventProcessor.Apply(es...)
+	}
+	g := GetGuard()
+	pol := g.policy(p.name)
+	if p.abandoned.Load() || !g.allow(p, pol) {
+		return p.skip(es)
+	}
+	res, f := p.run(pol, es)
+	if f == nil {
+		g.success(p)
+		return res
+	}
+	p.record(g, pol, f, len(es))
+	if f.timeout {
+		p.abandoned.Store(true)
+		go func() {
+			<-f.done
+			g.addQuarantined(p, f.reason, es)
+			p.abandoned.Store(false)
+		}()
+		return nil
+	}
+	g.addQuarantined(p, f.reason, es)
+	return nil
+}
+
+// skip passes es unchanged, or drops them if the processor fails closed.
+func (p *guardedProcessor) skip(es []*EventMsg) []*EventMsg {
+	if p.failClosed() {
+		guardDropped.WithLabelValues(p.name, p.typ).Add(float64(len(es)))
+		return nil
+	}
+	guardBypassed.WithLabelValues(p.name, p.typ).Add(float64(len(es)))
+	return es
+}
+
+func (p *guardedProcessor) record(g *Guard, pol *GuardPolicy, f *failure, n int) {
+	if f.timeout {
+		guardTimeouts.WithLabelValues(p.name, p.typ).Inc()
+	} else {
+		guardPanics.WithLabelValues(p.name, p.typ).Inc()
+	}
+	p.logger.Printf("processor %q of type=%s failed on %d event(s): %s", p.name, p.typ, n, f.reason)
+	g.failure(p, pol, f)
+}
+
+// run applies the processor to es, recovering from a panic
+// and abandoning the run if it exceeds the policy timeout.
+func (p *guardedProcessor) run(pol *GuardPolicy, es []*EventMsg) ([]*EventMsg, *failure) {
+	if pol.Timeout <= 0 {
+		return p.safeApply(context.Background(), es)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pol.Timeout)
+	defer cancel()
+	type result struct {
+		res []*EventMsg
+		f   *failure
+	}
+	done := make(chan result, 1)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		res, f := p.safeApply(ctx, es)
+		done <- result{res: res, f: f}
+	}()
+	select {
+	case r := <-done:
+		if r.f == nil && ctx.Err() != nil {
+			// a context aware processor aborted its run.
+			return nil, &failure{timeout: true, reason: fmt.Sprintf("time budget of %s exceeded", pol.Timeout), done: finished}
+		}
+		return r.res, r.f
+	case <-ctx.Done():
+		return nil, &failure{timeout: true, reason: fmt.Sprintf("time budget of %s exceeded", pol.Timeout), done: finished}
+	}
+}
+
+func (p *guardedProcessor) safeApply(ctx context.Context, es []*EventMsg) (res []*EventMsg, f *failure) {
+	defer func() {
+		if r := recover(); r != nil {
+			res = nil
+			f = &failure{reason: fmt.Sprintf("panic: %v", r)}
+			p.logger.Printf("processor %q of type=%s panic stack: %s", p.name, p.typ, debug.Stack())
+		}
+	}()
+	if cp, ok := p.EventProcessor.(ContextEventProcessor); ok {
+		return cp.ApplyContext(ctx, es...), nil
+	}
+	return p.EventProcessor.Apply(es...), nil
+}
+
+// eventID identifies an event in the logs without its values,
+// which may hold sensitive data.
+func eventID(e *EventMsg) string {
+	return fmt.Sprintf("name=%s source=%s timestamp=%d", e.Name, e.Tags["source"], e.Timestamp)
+}
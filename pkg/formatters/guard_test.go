@@ -0,0 +1,272 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package formatters
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func panicOnBad(es ...*EventMsg) []*EventMsg {
+	for _, e := range es {
+		if e.Tags["bad"] == "true" {
+			panic("bad event")
+		}
+		e.Tags["seen"] = "true"
+	}
+	return es
+}
+
+func newGuardTestEvents(bad ...bool) []*EventMsg {
+	es := make([]*EventMsg, 0, len(bad))
+	for _, b := range bad {
+		e := &EventMsg{Name: "sub1", Tags: map[string]string{"source": "router1"}}
+		if b {
+			e.Tags["bad"] = "true"
+		}
+		es = append(es, e)
+	}
+	return es
+}
+
+func newGuardTestProcessor(fn func(es ...*EventMsg) []*EventMsg) *guardedProcessor {
+	return newGuardedProcessor(&testProcessor{apply: fn}, "p1", "test", nil)
+}
+
+func TestGuardPanicQuarantine(t *testing.T) {
+	g := NewGuard(nil)
+	SetGuard(g)
+	defer SetGuard(nil)
+
+	p := newGuardTestProcessor(panicOnBad)
+	res := p.Apply(newGuardTestEvents(false, true, false)...)
+	if len(res) != 0 {
+		t.Fatalf("expected the batch to be dropped, got %d events", len(res))
+	}
+	q := g.Quarantined("")
+	if len(q) != 3 {
+		t.Fatalf("expected 3 quarantined events, got %d", len(q))
+	}
+	// the events are quarantined as left by the failed run.
+	if q[0].Processor != "p1" || q[0].Event.Tags["seen"] != "true" || q[1].Event.Tags["bad"] != "true" {
+		t.Errorf("unexpected quarantined events: %+v, %+v", q[0].Event, q[1].Event)
+	}
+	cs := g.Circuits()
+	if len(cs) != 1 || cs[0].Panics != 1 || cs[0].State != CircuitClosed {
+		t.Errorf("unexpected circuits: %+v", cs)
+	}
+	// a successful run resets the consecutive failures.
+	p.Apply(newGuardTestEvents(false)...)
+	if cs := g.Circuits(); cs[0].ConsecutiveFailures != 0 {
+		t.Errorf("expected no consecutive failures, got %d", cs[0].ConsecutiveFailures)
+	}
+}
+
+func TestGuardTimeout(t *testing.T) {
+	g := NewGuard(&GuardConfig{GuardPolicy: GuardPolicy{Timeout: 10 * time.Millisecond}})
+	SetGuard(g)
+	defer SetGuard(nil)
+
+	p := newGuardTestProcessor(func(es ...*EventMsg) []*EventMsg {
+		time.Sleep(100 * time.Millisecond)
+		return es
+	})
+	res := p.Apply(newGuardTestEvents(false, false)...)
+	if len(res) != 0 {
+		t.Errorf("expected the timed out events to be dropped, got %d", len(res))
+	}
+	// the events are quarantined once the abandoned run returns.
+	if q := g.Quarantined("p1"); len(q) != 0 {
+		t.Errorf("expected no quarantined events while the run is ongoing, got %d", len(q))
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(g.Quarantined("p1")) != 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if q := g.Quarantined("p1"); len(q) != 2 {
+		t.Errorf("expected 2 quarantined events, got %d", len(q))
+	}
+	if cs := g.Circuits(); len(cs) != 1 || cs[0].Timeouts != 1 {
+		t.Errorf("unexpected circuits: %+v", cs)
+	}
+}
+
+func TestGuardTimeoutSerialized(t *testing.T) {
+	SetGuard(NewGuard(&GuardConfig{GuardPolicy: GuardPolicy{Timeout: 10 * time.Millisecond}}))
+	defer SetGuard(nil)
+
+	release := make(chan struct{})
+	// state not safe for concurrent runs.
+	seen := make(map[string]int)
+	calls := 0
+	p := newGuardTestProcessor(func(es ...*EventMsg) []*EventMsg {
+		calls++
+		for _, e := range es {
+			seen[e.Tags["source"]]++
+		}
+		if calls == 1 {
+			<-release
+		}
+		return es
+	})
+	if res := p.Apply(newGuardTestEvents(false)...); len(res) != 0 {
+		t.Errorf("expected the timed out events to be dropped, got %d", len(res))
+	}
+	// the processor is bypassed while the abandoned run is in flight.
+	if res := p.Apply(newGuardTestEvents(false, false)...); len(res) != 2 {
+		t.Errorf("expected the events to bypass the processor, got %d", len(res))
+	}
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for p.abandoned.Load() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if res := p.Apply(newGuardTestEvents(false)...); len(res) != 1 {
+		t.Errorf("expected the processor to run once the abandoned run returned, got %d", len(res))
+	}
+	if calls != 2 || seen["router1"] != 2 {
+		t.Errorf("expected 2 runs, got %d: %v", calls, seen)
+	}
+}
+
+type ctxTestProcessor struct {
+	testProcessor
+	cancelled chan struct{}
+}
+
+func (p *ctxTestProcessor) ApplyContext(ctx context.Context, es ...*EventMsg) []*EventMsg {
+	<-ctx.Done()
+	close(p.cancelled)
+	return es
+}
+
+func TestGuardTimeoutCancelsContextProcessor(t *testing.T) {
+	SetGuard(NewGuard(&GuardConfig{GuardPolicy: GuardPolicy{Timeout: 10 * time.Millisecond}}))
+	defer SetGuard(nil)
+
+	cp := &ctxTestProcessor{cancelled: make(chan struct{})}
+	p := newGuardedProcessor(cp, "p1", "test", nil)
+	if res := p.Apply(newGuardTestEvents(false)...); len(res) != 0 {
+		t.Errorf("expected the timed out events to be dropped, got %d", len(res))
+	}
+	select {
+	case <-cp.cancelled:
+	case <-time.After(time.Second):
+		t.Error("processor context was not cancelled")
+	}
+}
+
+func TestGuardCircuitBreaker(t *testing.T) {
+	tests := map[string]struct {
+		onOpen string
+		state  string
+		// whether the processor is tried again after reset-after.
+		retried bool
+	}{
+		"bypass":  {onOpen: OnOpenBypass, state: CircuitOpen, retried: true},
+		"disable": {onOpen: OnOpenDisable, state: CircuitDisabled},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := NewGuard(&GuardConfig{
+				Processors: map[string]*GuardPolicy{
+					"p1": {MaxFailures: 2, OnOpen: tt.onOpen, ResetAfter: 50 * time.Millisecond},
+				},
+			})
+			SetGuard(g)
+			defer SetGuard(nil)
+
+			p := newGuardTestProcessor(panicOnBad)
+			for i := 0; i < 2; i++ {
+				p.Apply(newGuardTestEvents(true)...)
+			}
+			cs := g.Circuits()
+			if len(cs) != 1 || cs[0].State != tt.state {
+				t.Fatalf("expected circuit state %q, got %+v", tt.state, cs)
+			}
+			// the open circuit lets the events through unchanged.
+			res := p.Apply(newGuardTestEvents(true)...)
+			if len(res) != 1 || res[0].Tags["bad"] != "true" {
+				t.Fatalf("expected the event to bypass the processor, got %+v", res)
+			}
+			if q := g.Quarantined(""); len(q) != 2 {
+				t.Errorf("expected 2 quarantined events, got %d", len(q))
+			}
+			time.Sleep(60 * time.Millisecond)
+			res = p.Apply(newGuardTestEvents(false)...)
+			if len(res) != 1 {
+				t.Fatalf("expected 1 event, got %d", len(res))
+			}
+			if (res[0].Tags["seen"] == "true") != tt.retried {
+				t.Errorf("expected processor retried=%v, got event %+v", tt.retried, res[0])
+			}
+			wantState := tt.state
+			if tt.retried {
+				wantState = CircuitClosed
+			}
+			if cs := g.Circuits(); cs[0].State != wantState {
+				t.Errorf("expected circuit state %q, got %q", wantState, cs[0].State)
+			}
+			g.Reset("p1")
+			if cs := g.Circuits(); len(cs) != 0 {
+				t.Errorf("expected no circuits after reset, got %+v", cs)
+			}
+			if q := g.Quarantined(""); len(q) != 0 {
+				t.Errorf("expected no quarantined events after reset, got %d", len(q))
+			}
+		})
+	}
+}
+
+func TestGuardFailClosed(t *testing.T) {
+	g := NewGuard(&GuardConfig{GuardPolicy: GuardPolicy{MaxFailures: 1}})
+	SetGuard(g)
+	defer SetGuard(nil)
+
+	p := newGuardedProcessor(&testProcessor{apply: panicOnBad}, "redact", "event-redact", nil)
+	if res := p.Apply(newGuardTestEvents(true)...); len(res) != 0 {
+		t.Fatalf("expected the event to be dropped, got %+v", res)
+	}
+	// the quarantined events of a fail-closed processor have no content.
+	if q := g.Quarantined("redact"); len(q) != 1 || q[0].Event != nil {
+		t.Errorf("unexpected quarantined events: %+v", q)
+	}
+	// the open circuit drops the events rather than bypassing the processor.
+	if res := p.Apply(newGuardTestEvents(false)...); len(res) != 0 {
+		t.Errorf("expected the event to be dropped, got %+v", res)
+	}
+}
+
+func TestGuardQuarantineSize(t *testing.T) {
+	g := NewGuard(&GuardConfig{QuarantineSize: 2})
+	SetGuard(g)
+	defer SetGuard(nil)
+
+	p := newGuardTestProcessor(panicOnBad)
+	p.Apply(newGuardTestEvents(true, true, true)...)
+	if q := g.Quarantined(""); len(q) != 2 {
+		t.Errorf("expected 2 quarantined events, got %d", len(q))
+	}
+}
+
+func TestGuardConfigValidate(t *testing.T) {
+	tests := map[string]*GuardConfig{
+		"unknown_on_open":  {GuardPolicy: GuardPolicy{OnOpen: "drop"}},
+		"negative_timeout": {GuardPolicy: GuardPolicy{Timeout: -time.Second}},
+		"processor_policy": {Processors: map[string]*GuardPolicy{"p1": {MaxFailures: -1}}},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := cfg.Validate(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
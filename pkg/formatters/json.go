@@ -14,9 +14,11 @@ import (
 	"time"
 
 	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/openconfig/gnmi/proto/gnmi_ext"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/openconfig/gnmic/pkg/api/path"
+	"github.com/openconfig/gnmic/pkg/extensions"
 )
 
 // FormatJSON formats a proto.Message and returns a []byte and an error
@@ -75,7 +77,7 @@ func (o *MarshalOptions) formatSubscribeRequest(m *gnmi.SubscribeRequest) ([]byt
 		msg.Poll = new(poll)
 	}
 	if len(m.GetExtension()) > 0 {
-		msg.Extensions = m.GetExtension()
+		msg.Extensions = formatExtensions(m.GetExtension())
 	}
 	if o.Multiline {
 		return json.MarshalIndent(msg, "", o.Indent)
@@ -87,7 +89,7 @@ func (o *MarshalOptions) formatSubscribeResponse(m *gnmi.SubscribeResponse, meta
 	switch mr := m.GetResponse().(type) {
 	default:
 		if len(m.GetExtension()) > 0 {
-			msg := notificationRspMsg{Extensions: m.GetExtension()}
+			msg := notificationRspMsg{Extensions: formatExtensions(m.GetExtension())}
 			if o.Multiline {
 				return json.MarshalIndent(msg, "", o.Indent)
 			}
@@ -96,7 +98,7 @@ func (o *MarshalOptions) formatSubscribeResponse(m *gnmi.SubscribeResponse, meta
 	case *gnmi.SubscribeResponse_SyncResponse:
 		msg := &syncResponseMsg{
 			SyncResponse: mr.SyncResponse,
-			Extensions:   m.GetExtension(),
+			Extensions:   formatExtensions(m.GetExtension()),
 		}
 		if o.Multiline {
 			return json.MarshalIndent(msg, "", o.Indent)
@@ -152,7 +154,7 @@ func (o *MarshalOptions) formatSubscribeResponse(m *gnmi.SubscribeResponse, meta
 			msg.Deletes = append(msg.Deletes, path.GnmiPathToXPath(del, false))
 		}
 		if len(m.GetExtension()) > 0 {
-			msg.Extensions = m.GetExtension()
+			msg.Extensions = formatExtensions(m.GetExtension())
 		}
 		if o.Multiline {
 			return json.MarshalIndent(msg, "", o.Indent)
@@ -164,7 +166,7 @@ func (o *MarshalOptions) formatSubscribeResponse(m *gnmi.SubscribeResponse, meta
 
 func (o *MarshalOptions) formatCapabilitiesRequest(m *gnmi.CapabilityRequest) ([]byte, error) {
 	capReq := capRequest{
-		Extensions: m.Extension,
+		Extensions: formatExtensions(m.Extension),
 	}
 	if o.Multiline {
 		return json.MarshalIndent(capReq, "", o.Indent)
@@ -174,7 +176,7 @@ func (o *MarshalOptions) formatCapabilitiesRequest(m *gnmi.CapabilityRequest) ([
 
 func (o *MarshalOptions) formatCapabilitiesResponse(m *gnmi.CapabilityResponse) ([]byte, error) {
 	capRspMsg := capResponse{
-		Extensions: m.Extension,
+		Extensions: formatExtensions(m.Extension),
 	}
 	capRspMsg.Version = m.GetGNMIVersion()
 	for _, sm := range m.SupportedModels {
@@ -201,7 +203,7 @@ func (o *MarshalOptions) formatGetRequest(m *gnmi.GetRequest) ([]byte, error) {
 		Paths:      make([]string, 0, len(m.Path)),
 		Encoding:   m.GetEncoding().String(),
 		DataType:   m.GetType().String(),
-		Extensions: m.Extension,
+		Extensions: formatExtensions(m.Extension),
 	}
 	for _, p := range m.Path {
 		msg.Paths = append(msg.Paths, path.GnmiPathToXPath(p, false))
@@ -223,7 +225,7 @@ func (o *MarshalOptions) formatGetRequest(m *gnmi.GetRequest) ([]byte, error) {
 func (o *MarshalOptions) formatGetResponse(m *gnmi.GetResponse, meta map[string]string) ([]byte, error) {
 	getRsp := getRspMsg{
 		Notifications: make([]notificationRspMsg, 0, len(m.GetNotification())),
-		Extensions:    m.GetExtension(),
+		Extensions:    formatExtensions(m.GetExtension()),
 	}
 
 	for _, notif := range m.GetNotification() {
@@ -302,7 +304,7 @@ func (o *MarshalOptions) formatSetRequest(m *gnmi.SetRequest) ([]byte, error) {
 		Delete:     make([]string, 0, len(m.GetDelete())),
 		Replace:    make([]updateMsg, 0, len(m.GetReplace())),
 		Update:     make([]updateMsg, 0, len(m.GetUpdate())),
-		Extensions: m.GetExtension(),
+		Extensions: formatExtensions(m.GetExtension()),
 	}
 
 	for _, del := range m.GetDelete() {
@@ -335,7 +337,7 @@ func (o *MarshalOptions) formatSetResponse(m *gnmi.SetResponse, meta map[string]
 		Target:     m.GetPrefix().GetTarget(),
 		Timestamp:  m.GetTimestamp(),
 		Time:       time.Unix(0, m.Timestamp),
-		Extensions: m.GetExtension(),
+		Extensions: formatExtensions(m.GetExtension()),
 	}
 	if meta == nil {
 		meta = make(map[string]string)
@@ -356,3 +358,9 @@ func (o *MarshalOptions) formatSetResponse(m *gnmi.SetResponse, meta map[string]
 	}
 	return json.Marshal(msg)
 }
+
+// formatExtensions decodes the registered extensions declared in the configuration,
+// the other extensions are formatted as is.
+func formatExtensions(exts []*gnmi_ext.Extension) []any {
+	return extensions.GetRegistry().Format(exts)
+}
@@ -0,0 +1,38 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package formatters
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var processorsMetrics = struct {
+	m          sync.Mutex
+	collectors []prometheus.Collector
+}{}
+
+// RegisterMetrics registers the prometheus collectors of a processor type.
+// It is meant to be called from the processor package init function,
+// the collectors are exposed by the API server metrics endpoint.
+func RegisterMetrics(cs ...prometheus.Collector) {
+	processorsMetrics.m.Lock()
+	defer processorsMetrics.m.Unlock()
+	processorsMetrics.collectors = append(processorsMetrics.collectors, cs...)
+}
+
+// Metrics returns the prometheus collectors registered by the processors.
+func Metrics() []prometheus.Collector {
+	processorsMetrics.m.Lock()
+	defer processorsMetrics.m.Unlock()
+	cs := make([]prometheus.Collector, len(processorsMetrics.collectors))
+	copy(cs, processorsMetrics.collectors)
+	return cs
+}
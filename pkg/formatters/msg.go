@@ -13,12 +13,11 @@ import (
 	"time"
 
 	"github.com/openconfig/gnmi/proto/gnmi"
-	"github.com/openconfig/gnmi/proto/gnmi_ext"
 )
 
 type syncResponseMsg struct {
-	SyncResponse bool                  `json:"sync-response,omitempty"`
-	Extensions   []*gnmi_ext.Extension `json:"extensions,omitempty"`
+	SyncResponse bool  `json:"sync-response,omitempty"`
+	Extensions   []any `json:"extensions,omitempty"`
 }
 
 type notificationRspMsg struct {
@@ -36,20 +35,20 @@ type notificationRspMsg struct {
 	Target           string                 `json:"target,omitempty"`
 	Updates          []update               `json:"updates,omitempty"`
 	Deletes          []string               `json:"deletes,omitempty"`
-	Extensions       []*gnmi_ext.Extension  `json:"extensions,omitempty"`
+	Extensions       []any                  `json:"extensions,omitempty"`
 }
 type update struct {
 	Path   string
 	Values map[string]interface{} `json:"values,omitempty"`
 }
 type capRequest struct {
-	Extensions []*gnmi_ext.Extension `json:"extensions,omitempty"`
+	Extensions []any `json:"extensions,omitempty"`
 }
 type capResponse struct {
-	Version         string                `json:"version,omitempty"`
-	SupportedModels []model               `json:"supported-models,omitempty"`
-	Encodings       []string              `json:"encodings,omitempty"`
-	Extensions      []*gnmi_ext.Extension `json:"extensions,omitempty"`
+	Version         string   `json:"version,omitempty"`
+	SupportedModels []model  `json:"supported-models,omitempty"`
+	Encodings       []string `json:"encodings,omitempty"`
+	Extensions      []any    `json:"extensions,omitempty"`
 }
 type model struct {
 	Name         string `json:"name,omitempty"`
@@ -58,27 +57,27 @@ type model struct {
 }
 
 type getRqMsg struct {
-	Prefix     string                `json:"prefix,omitempty"`
-	Target     string                `json:"target,omitempty"`
-	Paths      []string              `json:"paths,omitempty"`
-	Encoding   string                `json:"encoding,omitempty"`
-	DataType   string                `json:"data-type,omitempty"`
-	Models     []model               `json:"models,omitempty"`
-	Extensions []*gnmi_ext.Extension `json:"extensions,omitempty"`
+	Prefix     string   `json:"prefix,omitempty"`
+	Target     string   `json:"target,omitempty"`
+	Paths      []string `json:"paths,omitempty"`
+	Encoding   string   `json:"encoding,omitempty"`
+	DataType   string   `json:"data-type,omitempty"`
+	Models     []model  `json:"models,omitempty"`
+	Extensions []any    `json:"extensions,omitempty"`
 }
 
 type getRspMsg struct {
-	Notifications []notificationRspMsg  `json:"notifications,omitempty"`
-	Extensions    []*gnmi_ext.Extension `json:"extensions,omitempty"`
+	Notifications []notificationRspMsg `json:"notifications,omitempty"`
+	Extensions    []any                `json:"extensions,omitempty"`
 }
 type setRspMsg struct {
-	Source     string                `json:"source,omitempty"`
-	Timestamp  int64                 `json:"timestamp,omitempty"`
-	Time       time.Time             `json:"time,omitempty"`
-	Prefix     string                `json:"prefix,omitempty"`
-	Target     string                `json:"target,omitempty"`
-	Results    []updateResultMsg     `json:"results,omitempty"`
-	Extensions []*gnmi_ext.Extension `json:"extensions,omitempty"`
+	Source     string            `json:"source,omitempty"`
+	Timestamp  int64             `json:"timestamp,omitempty"`
+	Time       time.Time         `json:"time,omitempty"`
+	Prefix     string            `json:"prefix,omitempty"`
+	Target     string            `json:"target,omitempty"`
+	Results    []updateResultMsg `json:"results,omitempty"`
+	Extensions []any             `json:"extensions,omitempty"`
 }
 
 type updateResultMsg struct {
@@ -88,12 +87,12 @@ type updateResultMsg struct {
 }
 
 type setReqMsg struct {
-	Prefix     string                `json:"prefix,omitempty"`
-	Target     string                `json:"target,omitempty"`
-	Delete     []string              `json:"delete,omitempty"`
-	Replace    []updateMsg           `json:"replace,omitempty"`
-	Update     []updateMsg           `json:"update,omitempty"`
-	Extensions []*gnmi_ext.Extension `json:"extensions,omitempty"`
+	Prefix     string      `json:"prefix,omitempty"`
+	Target     string      `json:"target,omitempty"`
+	Delete     []string    `json:"delete,omitempty"`
+	Replace    []updateMsg `json:"replace,omitempty"`
+	Update     []updateMsg `json:"update,omitempty"`
+	Extensions []any       `json:"extensions,omitempty"`
 }
 
 type updateMsg struct {
@@ -102,10 +101,10 @@ type updateMsg struct {
 }
 
 type subscribeReq struct {
-	Subscribe  subscribe             `json:"subscribe,omitempty"`
-	Poll       *poll                 `json:"poll,omitempty"`
-	Aliases    map[string]string     `json:"aliases,omitempty"`
-	Extensions []*gnmi_ext.Extension `json:"extensions,omitempty"`
+	Subscribe  subscribe         `json:"subscribe,omitempty"`
+	Poll       *poll             `json:"poll,omitempty"`
+	Aliases    map[string]string `json:"aliases,omitempty"`
+	Extensions []any             `json:"extensions,omitempty"`
 }
 
 type poll struct{}
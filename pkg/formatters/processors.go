@@ -45,6 +45,11 @@ var EventProcessorTypes = []string{
 	"event-combine",
 	"event-ieeefloat32",
 	"event-time-epoch",
+	"event-topk",
+	"event-redact",
+	"event-dedup",
+	"event-align",
+	"event-correlate",
 }
 
 type Initializer func() EventProcessor
@@ -101,6 +106,82 @@ func WithProcessors(procs map[string]map[string]interface{}) Option {
 	}
 }
 
+// NamedEventProcessor is implemented by the event processors
+// that use their configured name, e.g to label their metrics.
+type NamedEventProcessor interface {
+	WithName(name string)
+}
+
+func WithName(name string) Option {
+	return func(p EventProcessor) {
+		if np, ok := p.(NamedEventProcessor); ok {
+			np.WithName(name)
+		}
+	}
+}
+
+// wrapper is implemented by the processors wrapping another one.
+type wrapper interface {
+	unwrap() EventProcessor
+}
+
+// Unwrap returns the processor configured by the user, without the tracing
+// and guard wrappers added by MakeEventProcessors.
+// It is meant for type assertions on the processors of a chain.
+func Unwrap(ep EventProcessor) EventProcessor {
+	for {
+		w, ok := ep.(wrapper)
+		if !ok {
+			return ep
+		}
+		ep = w.unwrap()
+	}
+}
+
+// outputChain is the processors chain of an output without processors,
+// it passes the events through and carries the output name.
+type outputChain struct {
+	output string
+}
+
+func (p *outputChain) Init(interface{}, ...Option) error                { return nil }
+func (p *outputChain) Apply(es ...*EventMsg) []*EventMsg                { return es }
+func (p *outputChain) WithTargets(map[string]*types.TargetConfig)       {}
+func (p *outputChain) WithLogger(*log.Logger)                           {}
+func (p *outputChain) WithActions(map[string]map[string]interface{})    {}
+func (p *outputChain) WithProcessors(map[string]map[string]interface{}) {}
+
+// ForOutput names the output the processors chain evps belongs to,
+// so that the traces and the catalog entries of the events going through it
+// record the output name. It returns the chain the output uses,
+// a chain without processors is replaced by one carrying the output name.
+func ForOutput(output string, evps []EventProcessor) []EventProcessor {
+	if len(evps) == 0 {
+		return []EventProcessor{&outputChain{output: output}}
+	}
+	for _, ep := range evps {
+		if tp, ok := ep.(*tracedProcessor); ok {
+			tp.output = output
+		}
+	}
+	return evps
+}
+
+// ChainOutput returns the name of the output the processors chain eps
+// belongs to, empty if the chain was not named using ForOutput.
+func ChainOutput(eps []EventProcessor) string {
+	if len(eps) == 0 {
+		return ""
+	}
+	switch ep := eps[0].(type) {
+	case *outputChain:
+		return ep.output
+	case *tracedProcessor:
+		return ep.output
+	}
+	return ""
+}
+
 func CheckCondition(code *gojq.Code, e *EventMsg) (bool, error) {
 	if code == nil {
 		return true, nil
@@ -136,6 +217,14 @@ func CheckCondition(code *gojq.Code, e *EventMsg) (bool, error) {
 	}
 }
 
+// sideEffectTypes are the types of the event processors acting outside
+// of the processors chain (running actions, writing the events),
+// they return the events unchanged.
+var sideEffectTypes = map[string]struct{}{
+	"event-trigger": {},
+	"event-write":   {},
+}
+
 func MakeEventProcessors(
 	logger *log.Logger,
 	processorNames []string,
@@ -143,7 +232,30 @@ func MakeEventProcessors(
 	tcs map[string]*types.TargetConfig,
 	acts map[string]map[string]interface{},
 ) ([]EventProcessor, error) {
-	evps := make([]EventProcessor, len(processorNames))
+	return makeEventProcessors(logger, processorNames, ps, tcs, acts, false)
+}
+
+// MakeDryRunEventProcessors is MakeEventProcessors for a dry run of the processors chain:
+// the processors with side effects are left out and the processors are not traced nor guarded.
+func MakeDryRunEventProcessors(
+	logger *log.Logger,
+	processorNames []string,
+	ps map[string]map[string]interface{},
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{},
+) ([]EventProcessor, error) {
+	return makeEventProcessors(logger, processorNames, ps, tcs, acts, true)
+}
+
+func makeEventProcessors(
+	logger *log.Logger,
+	processorNames []string,
+	ps map[string]map[string]interface{},
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{},
+	dryRun bool,
+) ([]EventProcessor, error) {
+	evps := make([]EventProcessor, 0, len(processorNames))
 	for i, epName := range processorNames {
 		if epCfg, ok := ps[epName]; ok {
 			epType := ""
@@ -152,17 +264,31 @@ func MakeEventProcessors(
 				break
 			}
 			if in, ok := EventProcessors[epType]; ok {
+				if _, ok := sideEffectTypes[epType]; ok && dryRun {
+					continue
+				}
 				ep := in()
 				err := ep.Init(epCfg[epType],
 					WithLogger(logger),
 					WithTargets(tcs),
 					WithActions(acts),
 					WithProcessors(ps),
+					WithName(epName),
 				)
 				if err != nil {
 					return nil, fmt.Errorf("failed initializing event processor '%s' of type='%s': %w", epName, epType, err)
 				}
-				evps[i] = ep
+				if dryRun {
+					evps = append(evps, ep)
+					continue
+				}
+				evps = append(evps, &tracedProcessor{
+					EventProcessor: newGuardedProcessor(ep, epName, epType, logger),
+					name:           epName,
+					typ:            epType,
+					index:          i,
+					last:           i == len(processorNames)-1,
+				})
 				logger.Printf("added event processor '%s' of type=%s to output", epName, epType)
 				continue
 			}
@@ -0,0 +1,82 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package formatters
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SeriesKey returns a key identifying the series of the value valueName
+// of the events with the given name and tags.
+func SeriesKey(name, valueName string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	sb := new(strings.Builder)
+	sb.WriteString(name)
+	sb.WriteString("\x00")
+	sb.WriteString(valueName)
+	for _, k := range keys {
+		sb.WriteString("\x00")
+		sb.WriteString(k)
+		sb.WriteString("=")
+		sb.WriteString(tags[k])
+	}
+	return sb.String()
+}
+
+// ToFloat converts an event value to a float64.
+// 4 and 8 bytes slices are decoded as IEEE 754 floats.
+func ToFloat(i interface{}) (float64, error) {
+	switch i := i.(type) {
+	case []uint8:
+		if len(i) == 4 {
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(i))), nil
+		}
+		if len(i) == 8 {
+			return math.Float64frombits(binary.BigEndian.Uint64(i)), nil
+		}
+		return 0, fmt.Errorf("cannot convert %v to float64", i)
+	case string:
+		return strconv.ParseFloat(i, 64)
+	case int:
+		return float64(i), nil
+	case int8:
+		return float64(i), nil
+	case int16:
+		return float64(i), nil
+	case int32:
+		return float64(i), nil
+	case int64:
+		return float64(i), nil
+	case uint:
+		return float64(i), nil
+	case uint8:
+		return float64(i), nil
+	case uint16:
+		return float64(i), nil
+	case uint32:
+		return float64(i), nil
+	case uint64:
+		return float64(i), nil
+	case float32:
+		return float64(i), nil
+	case float64:
+		return i, nil
+	default:
+		return 0, fmt.Errorf("cannot convert %v to float64, type %T", i, i)
+	}
+}
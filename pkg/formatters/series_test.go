@@ -0,0 +1,34 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package formatters
+
+import "testing"
+
+func TestSeriesKey(t *testing.T) {
+	k1 := SeriesKey("sub1", "in-octets", map[string]string{"source": "r1", "interface_name": "e1"})
+	k2 := SeriesKey("sub1", "in-octets", map[string]string{"interface_name": "e1", "source": "r1"})
+	if k1 != k2 {
+		t.Errorf("expected the key to not depend on the tags order: %q != %q", k1, k2)
+	}
+	if k1 == SeriesKey("sub1", "out-octets", map[string]string{"source": "r1", "interface_name": "e1"}) {
+		t.Errorf("expected different keys for different values")
+	}
+}
+
+func TestToFloat(t *testing.T) {
+	for _, v := range []interface{}{"1.5", float32(1.5), 1.5, []uint8{0x3f, 0xc0, 0x00, 0x00}} {
+		f, err := ToFloat(v)
+		if err != nil || f != 1.5 {
+			t.Errorf("%v: unexpected result %f, %v", v, f, err)
+		}
+	}
+	if _, err := ToFloat(true); err == nil {
+		t.Errorf("expected an error")
+	}
+}
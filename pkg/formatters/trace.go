@@ -0,0 +1,435 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package formatters
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/itchyny/gojq"
+)
+
+const defaultTraceBufferSize = 100
+
+// TraceConfig selects the events traced through the processors chains.
+// An event is traced if it matches the targets, subscriptions and condition
+// filters, and if its message is selected by the sample rate.
+type TraceConfig struct {
+	Targets       []string `mapstructure:"targets,omitempty" json:"targets,omitempty" yaml:"targets,omitempty"`
+	Subscriptions []string `mapstructure:"subscriptions,omitempty" json:"subscriptions,omitempty" yaml:"subscriptions,omitempty"`
+	Condition     string   `mapstructure:"condition,omitempty" json:"condition,omitempty" yaml:"condition,omitempty"`
+	SampleRate    float64  `mapstructure:"sample-rate,omitempty" json:"sample-rate,omitempty" yaml:"sample-rate,omitempty"`
+	BufferSize    int      `mapstructure:"buffer-size,omitempty" json:"buffer-size,omitempty" yaml:"buffer-size,omitempty"`
+}
+
+// Trace is the lineage of a group of events (the events resulting from a single message)
+// through a processors chain.
+type Trace struct {
+	ID           string       `json:"id,omitempty"`
+	Time         time.Time    `json:"time,omitempty"`
+	Target       string       `json:"target,omitempty"`
+	Subscription string       `json:"subscription,omitempty"`
+	Output       string       `json:"output,omitempty"`
+	Input        []*EventMsg  `json:"input,omitempty"`
+	Steps        []*TraceStep `json:"steps,omitempty"`
+	Result       []*EventMsg  `json:"result,omitempty"`
+	Dropped      bool         `json:"dropped,omitempty"`
+}
+
+// TraceStep is the state of the traced events before and after a single processor.
+type TraceStep struct {
+	Index     int         `json:"index"`
+	Processor string      `json:"processor,omitempty"`
+	Type      string      `json:"type,omitempty"`
+	Before    []*EventMsg `json:"before,omitempty"`
+	After     []*EventMsg `json:"after,omitempty"`
+	Dropped   bool        `json:"dropped,omitempty"`
+	FanOut    bool        `json:"fan-out,omitempty"`
+	Duration  int64       `json:"duration-ns,omitempty"`
+}
+
+type Tracer struct {
+	cfg  *TraceConfig
+	code *gojq.Code
+
+	m      *sync.RWMutex
+	traces map[string]*Trace
+	order  []string
+	// in flight traced events, mapped to their trace ID.
+	events map[*EventMsg]string
+	// number of in flight traced events, checked before taking
+	// the lock so that untraced events do not contend on it.
+	inFlight atomic.Int64
+}
+
+var tracer atomic.Pointer[Tracer]
+
+// SetTracer sets the Tracer used by the processors chains.
+// A nil Tracer disables tracing.
+func SetTracer(t *Tracer) {
+	tracer.Store(t)
+}
+
+// GetTracer returns the current Tracer, nil if tracing is disabled.
+func GetTracer() *Tracer {
+	return tracer.Load()
+}
+
+func NewTracer(cfg *TraceConfig) (*Tracer, error) {
+	if cfg == nil {
+		cfg = new(TraceConfig)
+	}
+	if cfg.SampleRate <= 0 || cfg.SampleRate > 1 {
+		cfg.SampleRate = 1
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = defaultTraceBufferSize
+	}
+	t := &Tracer{
+		cfg:    cfg,
+		m:      new(sync.RWMutex),
+		traces: make(map[string]*Trace),
+		order:  make([]string, 0, cfg.BufferSize),
+		events: make(map[*EventMsg]string),
+	}
+	cfg.Condition = strings.TrimSpace(cfg.Condition)
+	if cfg.Condition != "" {
+		q, err := gojq.Parse(cfg.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse trace condition: %w", err)
+		}
+		t.code, err = gojq.Compile(q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile trace condition: %w", err)
+		}
+	}
+	return t, nil
+}
+
+func (t *Tracer) Config() *TraceConfig {
+	return t.cfg
+}
+
+// Traces returns the recorded traces, oldest first.
+// Traces can be filtered by target, subscription and output name,
+// an empty filter value matches all traces.
+func (t *Tracer) Traces(target, subscription, output string) []*Trace {
+	t.m.RLock()
+	defer t.m.RUnlock()
+	res := make([]*Trace, 0, len(t.order))
+	for _, id := range t.order {
+		tr := t.traces[id]
+		if target != "" && tr.Target != target {
+			continue
+		}
+		if subscription != "" && tr.Subscription != subscription {
+			continue
+		}
+		if output != "" && tr.Output != output {
+			continue
+		}
+		ntr := *tr
+		res = append(res, &ntr)
+	}
+	return res
+}
+
+func (t *Tracer) Get(id string) (*Trace, bool) {
+	t.m.RLock()
+	defer t.m.RUnlock()
+	tr, ok := t.traces[id]
+	if !ok {
+		return nil, false
+	}
+	ntr := *tr
+	return &ntr, true
+}
+
+// Reset drops the recorded traces, the in flight events are no longer traced.
+func (t *Tracer) Reset() {
+	t.m.Lock()
+	defer t.m.Unlock()
+	t.traces = make(map[string]*Trace)
+	t.order = make([]string, 0, t.cfg.BufferSize)
+	t.events = make(map[*EventMsg]string)
+	t.inFlight.Store(0)
+}
+
+// Start marks the events matching the tracer filters with a new trace ID.
+// The events are expected to result from the same message,
+// output is the name of the output whose chain the events are going through.
+func (t *Tracer) Start(output string, evs []*EventMsg) {
+	// the filters are evaluated without the lock,
+	// it is only taken if some events are traced.
+	var selected []*EventMsg
+	for _, ev := range evs {
+		if ev == nil || !t.sampled(ev) || !t.match(ev) {
+			continue
+		}
+		selected = append(selected, ev)
+	}
+	if len(selected) == 0 {
+		return
+	}
+	t.m.Lock()
+	defer t.m.Unlock()
+	var tr *Trace
+	for _, ev := range selected {
+		if _, ok := t.events[ev]; ok {
+			continue
+		}
+		if tr == nil {
+			tr = &Trace{
+				ID:           uuid.New().String(),
+				Time:         time.Now(),
+				Target:       ev.Tags["source"],
+				Subscription: ev.Tags["subscription-name"],
+				Output:       output,
+			}
+		}
+		t.events[ev] = tr.ID
+		t.inFlight.Add(1)
+		tr.Input = append(tr.Input, copyEvent(ev))
+	}
+	if tr == nil {
+		return
+	}
+	if len(t.order) >= t.cfg.BufferSize {
+		t.evict(t.order[0])
+		t.order = t.order[1:]
+	}
+	t.traces[tr.ID] = tr
+	t.order = append(t.order, tr.ID)
+}
+
+// Finish records the events at the end of a processors chain.
+// The events are no longer considered in flight.
+func (t *Tracer) Finish(evs []*EventMsg) {
+	if t.inFlight.Load() == 0 {
+		return
+	}
+	t.m.Lock()
+	defer t.m.Unlock()
+	results := make(map[string][]*EventMsg)
+	for _, ev := range evs {
+		id, ok := t.events[ev]
+		if !ok {
+			continue
+		}
+		t.forget(ev)
+		results[id] = append(results[id], copyEvent(ev))
+	}
+	for id, res := range results {
+		if tr, ok := t.traces[id]; ok {
+			tr.Result = res
+		}
+	}
+}
+
+// evict drops the trace id and its in flight events, t.m must be held.
+func (t *Tracer) evict(id string) {
+	delete(t.traces, id)
+	for ev, evID := range t.events {
+		if evID == id {
+			t.forget(ev)
+		}
+	}
+}
+
+// forget removes ev from the in flight events, t.m must be held.
+func (t *Tracer) forget(ev *EventMsg) {
+	if _, ok := t.events[ev]; ok {
+		delete(t.events, ev)
+		t.inFlight.Add(-1)
+	}
+}
+
+// sampled reports whether the message ev results from is selected by the sample rate.
+// The decision is derived from the event source, subscription and timestamp,
+// so it is the same for all the events of a message and for all the chains it goes through.
+func (t *Tracer) sampled(ev *EventMsg) bool {
+	if t.cfg.SampleRate >= 1 {
+		return true
+	}
+	h := fnv.New64a()
+	h.Write([]byte(ev.Tags["source"]))
+	h.Write([]byte{0})
+	h.Write([]byte(ev.Tags["subscription-name"]))
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(ev.Timestamp)))
+	return float64(h.Sum64()%1e6)/1e6 < t.cfg.SampleRate
+}
+
+func (t *Tracer) match(ev *EventMsg) bool {
+	if len(t.cfg.Targets) > 0 && !strInList(ev.Tags["source"], t.cfg.Targets) {
+		return false
+	}
+	if len(t.cfg.Subscriptions) > 0 && !strInList(ev.Tags["subscription-name"], t.cfg.Subscriptions) {
+		return false
+	}
+	ok, err := CheckCondition(t.code, ev)
+	if err != nil {
+		return false
+	}
+	return ok
+}
+
+// step records a processor step for the traced events in es.
+// res is the processor output: input events missing from it are no longer in flight,
+// new events inherit the trace ID of the input events.
+func (t *Tracer) step(p *tracedProcessor, es, res []*EventMsg, before map[string][]*EventMsg, d time.Duration) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	var inherited string
+	for id := range before {
+		inherited = id
+		break
+	}
+	in := make(map[*EventMsg]struct{}, len(es))
+	for _, e := range es {
+		in[e] = struct{}{}
+	}
+	out := make(map[*EventMsg]struct{}, len(res))
+	after := make(map[string][]*EventMsg)
+	for _, e := range res {
+		if e == nil {
+			continue
+		}
+		out[e] = struct{}{}
+		id, ok := t.events[e]
+		if !ok {
+			if _, ok := in[e]; ok || inherited == "" {
+				// untraced input event
+				continue
+			}
+			// new event created by the processor
+			id = inherited
+			t.events[e] = id
+			t.inFlight.Add(1)
+		}
+		after[id] = append(after[id], copyEvent(e))
+	}
+	for _, e := range es {
+		if _, ok := out[e]; !ok {
+			t.forget(e)
+		}
+	}
+	for id, bevs := range before {
+		tr, ok := t.traces[id]
+		if !ok {
+			continue
+		}
+		aevs := after[id]
+		st := &TraceStep{
+			Index:     p.index,
+			Processor: p.name,
+			Type:      p.typ,
+			Before:    bevs,
+			After:     aevs,
+			Dropped:   len(aevs) == 0,
+			FanOut:    len(aevs) > len(bevs),
+			Duration:  d.Nanoseconds(),
+		}
+		tr.Steps = append(tr.Steps, st)
+		if st.Dropped {
+			tr.Dropped = true
+			tr.Result = nil
+		}
+	}
+}
+
+// tracedProcessor wraps an EventProcessor and records the state
+// of the traced events before and after its Apply method.
+type tracedProcessor struct {
+	EventProcessor
+	name  string
+	typ   string
+	index int
+	last  bool
+	// name of the output the chain belongs to, set by ForOutput.
+	output string
+}
+
+func (p *tracedProcessor) Apply(es ...*EventMsg) []*EventMsg {
+	t := GetTracer()
+	if t == nil {
+		return p.EventProcessor.Apply(es...)
+	}
+	if p.index == 0 {
+		// events not coming from a traced message (inputs, processor command)
+		// start their trace at the first processor of the chain.
+		t.Start(p.output, es)
+	}
+	if t.inFlight.Load() == 0 {
+		return p.EventProcessor.Apply(es...)
+	}
+	before := make(map[string][]*EventMsg)
+	t.m.RLock()
+	for _, e := range es {
+		if id, ok := t.events[e]; ok {
+			before[id] = append(before[id], copyEvent(e))
+		}
+	}
+	t.m.RUnlock()
+	if len(before) == 0 {
+		// none of the events is traced, there is no step to record.
+		res := p.EventProcessor.Apply(es...)
+		if p.last {
+			t.Finish(res)
+		}
+		return res
+	}
+	now := time.Now()
+	res := p.EventProcessor.Apply(es...)
+	t.step(p, es, res, before, time.Since(now))
+	if p.last {
+		t.Finish(res)
+	}
+	return res
+}
+
+func (p *tracedProcessor) unwrap() EventProcessor { return p.EventProcessor }
+
+func copyEvent(e *EventMsg) *EventMsg {
+	ne := &EventMsg{
+		Name:      e.Name,
+		Timestamp: e.Timestamp,
+	}
+	if e.Tags != nil {
+		ne.Tags = make(map[string]string, len(e.Tags))
+		for k, v := range e.Tags {
+			ne.Tags[k] = v
+		}
+	}
+	if e.Values != nil {
+		ne.Values = make(map[string]interface{}, len(e.Values))
+		for k, v := range e.Values {
+			ne.Values[k] = v
+		}
+	}
+	if e.Deletes != nil {
+		ne.Deletes = make([]string, len(e.Deletes))
+		copy(ne.Deletes, e.Deletes)
+	}
+	return ne
+}
+
+func strInList(s string, ls []string) bool {
+	for _, ss := range ls {
+		if ss == s {
+			return true
+		}
+	}
+	return false
+}
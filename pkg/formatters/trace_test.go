@@ -0,0 +1,291 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package formatters
+
+import (
+	"log"
+	"testing"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+)
+
+type testProcessor struct {
+	apply func(es ...*EventMsg) []*EventMsg
+}
+
+func (p *testProcessor) Init(interface{}, ...Option) error             { return nil }
+func (p *testProcessor) Apply(es ...*EventMsg) []*EventMsg             { return p.apply(es...) }
+func (p *testProcessor) WithTargets(map[string]*types.TargetConfig)    {}
+func (p *testProcessor) WithLogger(*log.Logger)                        {}
+func (p *testProcessor) WithActions(map[string]map[string]interface{}) {}
+func (p *testProcessor) WithProcessors(map[string]map[string]any)      {}
+
+func dropAll(es ...*EventMsg) []*EventMsg { return nil }
+
+func passThrough(es ...*EventMsg) []*EventMsg { return es }
+
+func duplicate(es ...*EventMsg) []*EventMsg {
+	res := make([]*EventMsg, 0, 2*len(es))
+	for _, e := range es {
+		res = append(res, e, copyEvent(e))
+	}
+	return res
+}
+
+func newTestChain(fns ...func(es ...*EventMsg) []*EventMsg) []EventProcessor {
+	eps := make([]EventProcessor, 0, len(fns))
+	for i, fn := range fns {
+		eps = append(eps, &tracedProcessor{
+			EventProcessor: &testProcessor{apply: fn},
+			name:           "p",
+			typ:            "test",
+			index:          i,
+			last:           i == len(fns)-1,
+		})
+	}
+	return eps
+}
+
+var traceTestSet = map[string]struct {
+	cfg      *TraceConfig
+	chain    []func(es ...*EventMsg) []*EventMsg
+	input    []*EventMsg
+	traces   int
+	steps    int
+	dropped  bool
+	fanOut   bool
+	nResults int
+}{
+	"pass_through": {
+		cfg:   &TraceConfig{},
+		chain: []func(es ...*EventMsg) []*EventMsg{passThrough, passThrough},
+		input: []*EventMsg{
+			{Name: "sub1", Tags: map[string]string{"source": "router1"}, Values: map[string]interface{}{"v": 1}},
+		},
+		traces:   1,
+		steps:    2,
+		nResults: 1,
+	},
+	"dropped": {
+		cfg:   &TraceConfig{},
+		chain: []func(es ...*EventMsg) []*EventMsg{passThrough, dropAll},
+		input: []*EventMsg{
+			{Name: "sub1", Tags: map[string]string{"source": "router1"}, Values: map[string]interface{}{"v": 1}},
+		},
+		traces:  1,
+		steps:   2,
+		dropped: true,
+	},
+	"fan_out": {
+		cfg:   &TraceConfig{},
+		chain: []func(es ...*EventMsg) []*EventMsg{duplicate, passThrough},
+		input: []*EventMsg{
+			{Name: "sub1", Tags: map[string]string{"source": "router1"}, Values: map[string]interface{}{"v": 1}},
+		},
+		traces:   1,
+		steps:    2,
+		fanOut:   true,
+		nResults: 2,
+	},
+	"target_filter": {
+		cfg:   &TraceConfig{Targets: []string{"router2"}},
+		chain: []func(es ...*EventMsg) []*EventMsg{passThrough},
+		input: []*EventMsg{
+			{Name: "sub1", Tags: map[string]string{"source": "router1"}, Values: map[string]interface{}{"v": 1}},
+		},
+		traces: 0,
+	},
+	"condition": {
+		cfg:   &TraceConfig{Condition: `.values.v > 1`},
+		chain: []func(es ...*EventMsg) []*EventMsg{passThrough},
+		input: []*EventMsg{
+			{Name: "sub1", Tags: map[string]string{"source": "router1"}, Values: map[string]interface{}{"v": 1}},
+			{Name: "sub1", Tags: map[string]string{"source": "router1"}, Values: map[string]interface{}{"v": 2}},
+		},
+		traces:   1,
+		steps:    1,
+		nResults: 1,
+	},
+}
+
+func TestTracer(t *testing.T) {
+	for name, ts := range traceTestSet {
+		t.Run(name, func(t *testing.T) {
+			tr, err := NewTracer(ts.cfg)
+			if err != nil {
+				t.Fatalf("failed to create tracer: %v", err)
+			}
+			SetTracer(tr)
+			defer SetTracer(nil)
+
+			evs := ts.input
+			for _, ep := range newTestChain(ts.chain...) {
+				evs = ep.Apply(evs...)
+			}
+			traces := tr.Traces("", "", "")
+			if len(traces) != ts.traces {
+				t.Fatalf("expected %d traces, got %d", ts.traces, len(traces))
+			}
+			if ts.traces == 0 {
+				return
+			}
+			trace := traces[0]
+			if len(trace.Steps) != ts.steps {
+				t.Errorf("expected %d steps, got %d", ts.steps, len(trace.Steps))
+			}
+			if trace.Dropped != ts.dropped {
+				t.Errorf("expected dropped=%v, got %v", ts.dropped, trace.Dropped)
+			}
+			if trace.Steps[0].FanOut != ts.fanOut {
+				t.Errorf("expected fan-out=%v, got %v", ts.fanOut, trace.Steps[0].FanOut)
+			}
+			if len(trace.Result) != ts.nResults {
+				t.Errorf("expected %d result events, got %d", ts.nResults, len(trace.Result))
+			}
+			if len(tr.events) != 0 {
+				t.Errorf("expected no in flight events, got %d", len(tr.events))
+			}
+		})
+	}
+}
+
+func TestTracerBufferSize(t *testing.T) {
+	tr, err := NewTracer(&TraceConfig{BufferSize: 2})
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		tr.Start("out1", []*EventMsg{{Name: "sub1", Tags: map[string]string{"source": "router1"}}})
+	}
+	if n := len(tr.Traces("", "", "out1")); n != 2 {
+		t.Errorf("expected 2 traces, got %d", n)
+	}
+	if n := len(tr.Traces("", "", "out2")); n != 0 {
+		t.Errorf("expected 0 traces, got %d", n)
+	}
+	// the events of the evicted traces are no longer in flight.
+	if len(tr.events) != 2 || tr.inFlight.Load() != 2 {
+		t.Errorf("expected 2 in flight events, got %d (%d)", len(tr.events), tr.inFlight.Load())
+	}
+}
+
+func TestTracerReset(t *testing.T) {
+	tr, err := NewTracer(nil)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	tr.Start("out1", []*EventMsg{{Name: "sub1", Tags: map[string]string{"source": "router1"}}})
+	tr.Reset()
+	if len(tr.Traces("", "", "")) != 0 || len(tr.events) != 0 || tr.inFlight.Load() != 0 {
+		t.Errorf("expected no traces nor in flight events, got %d (%d)", len(tr.events), tr.inFlight.Load())
+	}
+}
+
+func TestTracerResponseToEventMsgs(t *testing.T) {
+	tr, err := NewTracer(nil)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	SetTracer(tr)
+	defer SetTracer(nil)
+	rsp := &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: &gnmi.Notification{
+		Timestamp: 1,
+		Update: []*gnmi.Update{{
+			Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "a"}}},
+			Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 1}},
+		}},
+	}}}
+	_, err = ResponseToEventMsgs("sub1", rsp, map[string]string{"source": "router1"}, ForOutput("out1", newTestChain(passThrough))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trs := tr.Traces("", "", "")
+	if len(trs) != 1 {
+		t.Fatalf("expected a single trace, got %d", len(trs))
+	}
+	if len(trs[0].Steps) != 1 || len(trs[0].Result) != 1 {
+		t.Errorf("unexpected trace: %+v", trs[0])
+	}
+	if len(tr.events) != 0 || tr.inFlight.Load() != 0 {
+		t.Errorf("expected no in flight events, got %d", len(tr.events))
+	}
+}
+
+func TestTracerUntracedEvents(t *testing.T) {
+	tr, err := NewTracer(&TraceConfig{Targets: []string{"router2"}})
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	SetTracer(tr)
+	defer SetTracer(nil)
+	evs := []*EventMsg{{Name: "sub1", Tags: map[string]string{"source": "router1"}}}
+	for _, ep := range ForOutput("out1", newTestChain(passThrough, duplicate)) {
+		evs = ep.Apply(evs...)
+	}
+	if len(evs) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(evs))
+	}
+	if len(tr.events) != 0 || tr.inFlight.Load() != 0 {
+		t.Errorf("expected no in flight events, got %d", len(tr.events))
+	}
+}
+
+func TestTracerSampleRate(t *testing.T) {
+	tr, err := NewTracer(&TraceConfig{SampleRate: 0.5, BufferSize: 1000})
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	for i := 0; i < 200; i++ {
+		ev := &EventMsg{Name: "sub1", Timestamp: int64(i), Tags: map[string]string{"source": "router1"}}
+		if tr.sampled(ev) != tr.sampled(copyEvent(ev)) {
+			t.Fatalf("expected the same sampling decision for the events of a message")
+		}
+		tr.Start("out1", []*EventMsg{ev})
+	}
+	if n := len(tr.Traces("", "", "")); n < 50 || n > 150 {
+		t.Errorf("expected about 100 traces, got %d", n)
+	}
+}
+
+func TestForOutput(t *testing.T) {
+	tr, err := NewTracer(nil)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	SetTracer(tr)
+	defer SetTracer(nil)
+	evps := ForOutput("out1", newTestChain(passThrough))
+	if ChainOutput(evps) != "out1" {
+		t.Errorf("unexpected chain output %q", ChainOutput(evps))
+	}
+	evps[0].Apply(&EventMsg{Name: "sub1", Tags: map[string]string{"source": "router1"}})
+	if n := len(tr.Traces("", "", "out1")); n != 1 {
+		t.Errorf("expected 1 trace for out1, got %d", n)
+	}
+	empty := ForOutput("out2", nil)
+	if len(empty) != 1 || ChainOutput(empty) != "out2" {
+		t.Errorf("unexpected empty chain: %v", empty)
+	}
+	if ChainOutput(newTestChain(passThrough)) != "" {
+		t.Error("expected no output for an unnamed chain")
+	}
+}
+
+func TestUnwrap(t *testing.T) {
+	p := &testProcessor{apply: passThrough}
+	ep := &tracedProcessor{EventProcessor: newGuardedProcessor(p, "p1", "test", nil)}
+	if _, ok := Unwrap(ep).(*testProcessor); !ok {
+		t.Errorf("unexpected unwrapped processor %T", Unwrap(ep))
+	}
+	if Unwrap(p) != p {
+		t.Error("expected an unwrapped processor to be returned as is")
+	}
+}
@@ -0,0 +1,712 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gtemplate
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"math"
+	"math/big"
+	"net"
+	"net/netip"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/openconfig/gnmic/pkg/api/utils"
+)
+
+// library is the gNMIc template functions library.
+// The functions taking the value to transform as their last argument
+// can be used in a pipeline, e.g: {{ .source | host | upper }}.
+var library = template.FuncMap{
+	// strings
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"trimPrefix": trimPrefix,
+	"trimSuffix": trimSuffix,
+	"replace":    replace,
+	"substr":     substr,
+	"truncate":   truncate,
+	"host":       utils.GetHost,
+	// regular expressions
+	"regexMatch":   regexMatch,
+	"regexFind":    regexFind,
+	"regexFindAll": regexFindAll,
+	"regexReplace": regexReplace,
+	"regexSplit":   regexSplit,
+	"regexGroups":  regexGroups,
+	// lists
+	"list":    list,
+	"first":   first,
+	"last":    last,
+	"rest":    rest,
+	"inList":  inList,
+	"compact": compact,
+	// dicts
+	"hasKey": hasKey,
+	"get":    get,
+	"pick":   pick,
+	"omit":   omit,
+	// math
+	"min":   minOf,
+	"max":   maxOf,
+	"abs":   abs,
+	"round": round,
+	"floor": floor,
+	"ceil":  ceil,
+	// time
+	"now":        time.Now,
+	"toTime":     toTime,
+	"formatTime": formatTime,
+	"parseTime":  parseTime,
+	"duration":   duration,
+	// IP addresses and prefixes
+	"cidrHost":    cidrHost,
+	"cidrNetwork": cidrNetwork,
+	"cidrNetmask": cidrNetmask,
+	"cidrSubnet":  cidrSubnet,
+	"nextIP":      nextIP,
+	"prevIP":      prevIP,
+	"ipInCIDR":    ipInCIDR,
+	"ipVersion":   ipVersion,
+	// encoding
+	"fromJSON": fromJSON,
+	"fromYAML": fromYAML,
+	// hashing
+	"md5":    md5sum,
+	"sha1":   sha1sum,
+	"sha256": sha256sum,
+	"sha512": sha512sum,
+	"fnv32a": fnv32a,
+	// defaults
+	"empty":    empty,
+	"coalesce": coalesce,
+}
+
+// strings
+
+func trimPrefix(prefix, s string) string {
+	return strings.TrimPrefix(s, prefix)
+}
+
+func trimSuffix(suffix, s string) string {
+	return strings.TrimSuffix(s, suffix)
+}
+
+func replace(old, new, s string) string {
+	return strings.ReplaceAll(s, old, new)
+}
+
+// substr returns the characters of s between start and end,
+// a negative end means the end of the string.
+func substr(start, end int, s string) string {
+	rs := []rune(s)
+	if end < 0 || end > len(rs) {
+		end = len(rs)
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start >= end {
+		return ""
+	}
+	return string(rs[start:end])
+}
+
+func truncate(n int, s string) string {
+	return substr(0, n, s)
+}
+
+// regular expressions
+
+func regexMatch(re, s string) (bool, error) {
+	r, err := regexp.Compile(re)
+	if err != nil {
+		return false, err
+	}
+	return r.MatchString(s), nil
+}
+
+func regexFind(re, s string) (string, error) {
+	r, err := regexp.Compile(re)
+	if err != nil {
+		return "", err
+	}
+	return r.FindString(s), nil
+}
+
+func regexFindAll(re, s string) ([]string, error) {
+	r, err := regexp.Compile(re)
+	if err != nil {
+		return nil, err
+	}
+	return r.FindAllString(s, -1), nil
+}
+
+func regexReplace(re, repl, s string) (string, error) {
+	r, err := regexp.Compile(re)
+	if err != nil {
+		return "", err
+	}
+	return r.ReplaceAllString(s, repl), nil
+}
+
+func regexSplit(re, s string) ([]string, error) {
+	r, err := regexp.Compile(re)
+	if err != nil {
+		return nil, err
+	}
+	return r.Split(s, -1), nil
+}
+
+// regexGroups returns the named groups of the first match of re in s.
+func regexGroups(re, s string) (map[string]string, error) {
+	r, err := regexp.Compile(re)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]string)
+	m := r.FindStringSubmatch(s)
+	if m == nil {
+		return res, nil
+	}
+	for i, n := range r.SubexpNames() {
+		if i == 0 || n == "" {
+			continue
+		}
+		res[n] = m[i]
+	}
+	return res, nil
+}
+
+// lists
+
+func list(vs ...any) []any {
+	return vs
+}
+
+func toList(v any) ([]any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		res := make([]any, rv.Len())
+		for i := range res {
+			res[i] = rv.Index(i).Interface()
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("expected a list, got %T", v)
+}
+
+func first(v any) (any, error) {
+	l, err := toList(v)
+	if err != nil || len(l) == 0 {
+		return nil, err
+	}
+	return l[0], nil
+}
+
+func last(v any) (any, error) {
+	l, err := toList(v)
+	if err != nil || len(l) == 0 {
+		return nil, err
+	}
+	return l[len(l)-1], nil
+}
+
+func rest(v any) ([]any, error) {
+	l, err := toList(v)
+	if err != nil || len(l) == 0 {
+		return nil, err
+	}
+	return l[1:], nil
+}
+
+// inList reports whether v is an element of l,
+// values are compared using their string representation.
+func inList(v, l any) (bool, error) {
+	ls, err := toList(l)
+	if err != nil {
+		return false, err
+	}
+	sv := fmt.Sprint(v)
+	for _, e := range ls {
+		if fmt.Sprint(e) == sv {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// compact removes the empty values from a list.
+func compact(v any) ([]any, error) {
+	l, err := toList(v)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]any, 0, len(l))
+	for _, e := range l {
+		if !empty(e) {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+// dicts
+
+func toDict(v any) (map[string]any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if m, ok := v.(map[string]any); ok {
+		return m, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("expected a dict, got %T", v)
+	}
+	res := make(map[string]any, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		res[iter.Key().String()] = iter.Value().Interface()
+	}
+	return res, nil
+}
+
+func hasKey(key string, d any) (bool, error) {
+	m, err := toDict(d)
+	if err != nil {
+		return false, err
+	}
+	_, ok := m[key]
+	return ok, nil
+}
+
+// get returns the value of key in d, nil if the key is missing.
+func get(key string, d any) (any, error) {
+	m, err := toDict(d)
+	if err != nil {
+		return nil, err
+	}
+	return m[key], nil
+}
+
+// pick returns a dict with the given keys of d only.
+func pick(d any, keys ...string) (map[string]any, error) {
+	m, err := toDict(d)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]any, len(keys))
+	for _, k := range keys {
+		if v, ok := m[k]; ok {
+			res[k] = v
+		}
+	}
+	return res, nil
+}
+
+// omit returns a dict without the given keys of d.
+func omit(d any, keys ...string) (map[string]any, error) {
+	m, err := toDict(d)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]any, len(m))
+	for k, v := range m {
+		res[k] = v
+	}
+	for _, k := range keys {
+		delete(res, k)
+	}
+	return res, nil
+}
+
+// math
+
+func toFloat(v any) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int8:
+		return float64(v), nil
+	case int16:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case uint8:
+		return float64(v), nil
+	case uint16:
+		return float64(v), nil
+	case uint32:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+	return 0, fmt.Errorf("expected a number, got %T", v)
+}
+
+func minOf(v any, vs ...any) (float64, error) {
+	res, err := toFloat(v)
+	if err != nil {
+		return 0, err
+	}
+	for _, v := range vs {
+		f, err := toFloat(v)
+		if err != nil {
+			return 0, err
+		}
+		res = math.Min(res, f)
+	}
+	return res, nil
+}
+
+func maxOf(v any, vs ...any) (float64, error) {
+	res, err := toFloat(v)
+	if err != nil {
+		return 0, err
+	}
+	for _, v := range vs {
+		f, err := toFloat(v)
+		if err != nil {
+			return 0, err
+		}
+		res = math.Max(res, f)
+	}
+	return res, nil
+}
+
+func abs(v any) (float64, error) {
+	f, err := toFloat(v)
+	return math.Abs(f), err
+}
+
+// round rounds v to the given number of decimal places.
+func round(precision int, v any) (float64, error) {
+	f, err := toFloat(v)
+	if err != nil {
+		return 0, err
+	}
+	p := math.Pow10(precision)
+	return math.Round(f*p) / p, nil
+}
+
+func floor(v any) (float64, error) {
+	f, err := toFloat(v)
+	return math.Floor(f), err
+}
+
+func ceil(v any) (float64, error) {
+	f, err := toFloat(v)
+	return math.Ceil(f), err
+}
+
+// time
+
+var timeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RFC822":      time.RFC822,
+	"RFC1123":     time.RFC1123,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"Stamp":       time.Stamp,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+}
+
+func timeLayout(layout string) string {
+	if l, ok := timeLayouts[layout]; ok {
+		return l
+	}
+	return layout
+}
+
+// toTime converts v to a time.Time. v is either a time, an RFC3339 string
+// or a number of nanoseconds since the Unix epoch, such as an event timestamp.
+func toTime(v any) (time.Time, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		if v == nil {
+			return time.Time{}, errors.New("nil time")
+		}
+		return *v, nil
+	case string:
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(0, n).UTC(), nil
+		}
+		return time.Parse(time.RFC3339Nano, v)
+	}
+	f, err := toFloat(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a time, got %T", v)
+	}
+	return time.Unix(0, int64(f)).UTC(), nil
+}
+
+// formatTime formats v using layout, a Go time layout
+// or the name of one of the time package layouts, e.g RFC3339.
+func formatTime(layout string, v any) (string, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(timeLayout(layout)), nil
+}
+
+func parseTime(layout, s string) (time.Time, error) {
+	return time.Parse(timeLayout(layout), s)
+}
+
+// duration converts a duration string or a number of nanoseconds to a time.Duration.
+func duration(v any) (time.Duration, error) {
+	switch v := v.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		return time.ParseDuration(v)
+	}
+	f, err := toFloat(v)
+	return time.Duration(f), err
+}
+
+// IP addresses and prefixes
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return p.Masked(), nil
+}
+
+func addrAdd(a netip.Addr, n *big.Int) (netip.Addr, error) {
+	b := new(big.Int).SetBytes(a.AsSlice())
+	b.Add(b, n)
+	if b.Sign() < 0 || b.BitLen() > a.BitLen() {
+		return netip.Addr{}, fmt.Errorf("address out of range")
+	}
+	bs := make([]byte, a.BitLen()/8)
+	res, _ := netip.AddrFromSlice(b.FillBytes(bs))
+	return res, nil
+}
+
+// cidrHost returns the address number num of the prefix,
+// a negative num counts from the end of the prefix.
+func cidrHost(num int, prefix string) (string, error) {
+	p, err := parsePrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+	size := new(big.Int).Lsh(big.NewInt(1), uint(p.Addr().BitLen()-p.Bits()))
+	n := big.NewInt(int64(num))
+	if num < 0 {
+		n.Add(n, size)
+	}
+	if n.Sign() < 0 || n.Cmp(size) >= 0 {
+		return "", fmt.Errorf("prefix %s has no host number %d", prefix, num)
+	}
+	a, err := addrAdd(p.Addr(), n)
+	if err != nil {
+		return "", err
+	}
+	return a.String(), nil
+}
+
+func cidrNetwork(prefix string) (string, error) {
+	p, err := parsePrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+	return p.Addr().String(), nil
+}
+
+func cidrNetmask(prefix string) (string, error) {
+	p, err := parsePrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+	return net.IP(net.CIDRMask(p.Bits(), p.Addr().BitLen())).String(), nil
+}
+
+// cidrSubnet returns the subnet number num of the prefix
+// extended by newbits, e.g: cidrSubnet 8 2 "10.0.0.0/16" is 10.0.2.0/24.
+func cidrSubnet(newbits, num int, prefix string) (string, error) {
+	p, err := parsePrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+	bits := p.Bits() + newbits
+	if newbits < 0 || bits > p.Addr().BitLen() {
+		return "", fmt.Errorf("cannot extend prefix %s by %d bits", prefix, newbits)
+	}
+	if num < 0 || big.NewInt(int64(num)).BitLen() > newbits {
+		return "", fmt.Errorf("prefix %s has no subnet number %d with %d new bits", prefix, num, newbits)
+	}
+	n := new(big.Int).Lsh(big.NewInt(int64(num)), uint(p.Addr().BitLen()-bits))
+	a, err := addrAdd(p.Addr(), n)
+	if err != nil {
+		return "", err
+	}
+	return netip.PrefixFrom(a, bits).String(), nil
+}
+
+func nextIP(ip string) (string, error) {
+	a, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", err
+	}
+	n := a.Next()
+	if !n.IsValid() {
+		return "", fmt.Errorf("no address after %s", ip)
+	}
+	return n.String(), nil
+}
+
+func prevIP(ip string) (string, error) {
+	a, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", err
+	}
+	n := a.Prev()
+	if !n.IsValid() {
+		return "", fmt.Errorf("no address before %s", ip)
+	}
+	return n.String(), nil
+}
+
+func ipInCIDR(prefix, ip string) (bool, error) {
+	p, err := parsePrefix(prefix)
+	if err != nil {
+		return false, err
+	}
+	a, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false, err
+	}
+	return p.Contains(a), nil
+}
+
+func ipVersion(ip string) (int, error) {
+	a, err := netip.ParseAddr(ip)
+	if err != nil {
+		return 0, err
+	}
+	if a.Unmap().Is4() {
+		return 4, nil
+	}
+	return 6, nil
+}
+
+// encoding
+
+func fromJSON(s string) (any, error) {
+	var v any
+	err := json.Unmarshal([]byte(s), &v)
+	return v, err
+}
+
+func fromYAML(s string) (any, error) {
+	var v any
+	err := yaml.Unmarshal([]byte(s), &v)
+	if err != nil {
+		return nil, err
+	}
+	return utils.Convert(v), nil
+}
+
+// hashing
+
+func hexHash(h hash.Hash, s string) string {
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func md5sum(s string) string {
+	return hexHash(md5.New(), s)
+}
+
+func sha1sum(s string) string {
+	return hexHash(sha1.New(), s)
+}
+
+func sha256sum(s string) string {
+	return hexHash(sha256.New(), s)
+}
+
+func sha512sum(s string) string {
+	return hexHash(sha512.New(), s)
+}
+
+func fnv32a(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+// defaults
+
+// empty reports whether v is nil, a zero value or an empty string, list or dict.
+func empty(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return rv.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return rv.IsNil()
+	}
+	return rv.IsZero()
+}
+
+// coalesce returns the first non empty value.
+func coalesce(vs ...any) any {
+	for _, v := range vs {
+		if !empty(v) {
+			return v
+		}
+	}
+	return nil
+}
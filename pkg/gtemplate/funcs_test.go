@@ -0,0 +1,198 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gtemplate
+
+import (
+	"bytes"
+	"testing"
+)
+
+type funcTestCase struct {
+	tpl     string
+	data    any
+	want    string
+	wantErr bool
+}
+
+func runFuncTests(t *testing.T, tests map[string]funcTestCase) {
+	t.Helper()
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			tpl, err := CreateTemplate(name, tt.tpl)
+			if err != nil {
+				t.Fatalf("failed to parse template: %v", err)
+			}
+			b := new(bytes.Buffer)
+			err = tpl.Execute(b, tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %q", b.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to execute template: %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, b.String())
+			}
+		})
+	}
+}
+
+func TestStringFuncs(t *testing.T) {
+	runFuncTests(t, map[string]funcTestCase{
+		"lower":          {tpl: `{{ "Ethernet-1/1" | lower }}`, want: "ethernet-1/1"},
+		"upper":          {tpl: `{{ "up" | upper }}`, want: "UP"},
+		"trimPrefix":     {tpl: `{{ "ethernet-1/1" | trimPrefix "ethernet-" }}`, want: "1/1"},
+		"trimSuffix":     {tpl: `{{ "router1.example.com" | trimSuffix ".example.com" }}`, want: "router1"},
+		"replace":        {tpl: `{{ "ethernet-1/1" | replace "/" "_" }}`, want: "ethernet-1_1"},
+		"substr":         {tpl: `{{ "router1" | substr 0 6 }}`, want: "router"},
+		"substr_to_end":  {tpl: `{{ "router1" | substr 6 -1 }}`, want: "1"},
+		"substr_empty":   {tpl: `{{ "router1" | substr 5 2 }}`, want: ""},
+		"truncate":       {tpl: `{{ "router1" | truncate 3 }}`, want: "rou"},
+		"truncate_short": {tpl: `{{ "r1" | truncate 3 }}`, want: "r1"},
+		"host":           {tpl: `{{ "router1:57400" | host }}`, want: "router1"},
+		"host_no_port":   {tpl: `{{ "router1" | host }}`, want: "router1"},
+	})
+}
+
+func TestRegexFuncs(t *testing.T) {
+	runFuncTests(t, map[string]funcTestCase{
+		"regexMatch":     {tpl: `{{ "ethernet-1/1" | regexMatch "^ethernet-" }}`, want: "true"},
+		"regexMatch_no":  {tpl: `{{ "mgmt0" | regexMatch "^ethernet-" }}`, want: "false"},
+		"regexFind":      {tpl: `{{ "ethernet-1/12" | regexFind "[0-9]+$" }}`, want: "12"},
+		"regexFindAll":   {tpl: `{{ "ethernet-1/12" | regexFindAll "[0-9]+" }}`, want: "[1 12]"},
+		"regexReplace":   {tpl: `{{ "ethernet-1/12" | regexReplace "ethernet-([0-9]+)/([0-9]+)" "e$1-$2" }}`, want: "e1-12"},
+		"regexSplit":     {tpl: `{{ "a, b,c" | regexSplit ",\\s*" }}`, want: "[a b c]"},
+		"regexGroups":    {tpl: `{{ $g := "ethernet-1/12" | regexGroups "(?P<slot>[0-9]+)/(?P<port>[0-9]+)" }}{{ $g.slot }}-{{ $g.port }}`, want: "1-12"},
+		"regexGroups_no": {tpl: `{{ "mgmt0" | regexGroups "(?P<port>[0-9]+)/" | len }}`, want: "0"},
+		"invalid_regex":  {tpl: `{{ "a" | regexMatch "(" }}`, wantErr: true},
+	})
+}
+
+func TestListFuncs(t *testing.T) {
+	runFuncTests(t, map[string]funcTestCase{
+		"list":          {tpl: `{{ list 1 "a" true }}`, want: "[1 a true]"},
+		"first":         {tpl: `{{ list "a" "b" "c" | first }}`, want: "a"},
+		"first_empty":   {tpl: `{{ list | first }}`, want: "<no value>"},
+		"last":          {tpl: `{{ list "a" "b" "c" | last }}`, want: "c"},
+		"last_strings":  {tpl: `{{ .l | last }}`, data: map[string]any{"l": []string{"x", "y"}}, want: "y"},
+		"rest":          {tpl: `{{ list "a" "b" "c" | rest }}`, want: "[b c]"},
+		"inList":        {tpl: `{{ list "r1" "r2" | inList "r2" }}`, want: "true"},
+		"inList_number": {tpl: `{{ .l | inList 2 }}`, data: map[string]any{"l": []any{1.0, 2.0}}, want: "true"},
+		"inList_no":     {tpl: `{{ list "r1" "r2" | inList "r3" }}`, want: "false"},
+		"compact":       {tpl: `{{ list "a" "" 0 nil "b" | compact }}`, want: "[a b]"},
+		"not_a_list":    {tpl: `{{ "a" | first }}`, wantErr: true},
+	})
+}
+
+func TestDictFuncs(t *testing.T) {
+	tags := map[string]any{
+		"tags": map[string]string{"source": "r1", "interface_name": "e1", "subscription-name": "sub1"},
+	}
+	runFuncTests(t, map[string]funcTestCase{
+		"hasKey":      {tpl: `{{ .tags | hasKey "source" }}`, data: tags, want: "true"},
+		"hasKey_no":   {tpl: `{{ .tags | hasKey "target" }}`, data: tags, want: "false"},
+		"get":         {tpl: `{{ .tags | get "subscription-name" }}`, data: tags, want: "sub1"},
+		"get_missing": {tpl: `{{ .tags | get "target" }}`, data: tags, want: "<no value>"},
+		"pick":        {tpl: `{{ pick .tags "source" "unknown" }}`, data: tags, want: "map[source:r1]"},
+		"omit":        {tpl: `{{ omit .tags "source" "subscription-name" }}`, data: tags, want: "map[interface_name:e1]"},
+		"not_a_dict":  {tpl: `{{ list 1 | get "a" }}`, wantErr: true},
+	})
+}
+
+func TestMathFuncs(t *testing.T) {
+	runFuncTests(t, map[string]funcTestCase{
+		"min":            {tpl: `{{ min 3 1.5 "2" }}`, want: "1.5"},
+		"max":            {tpl: `{{ max 3 1.5 "2" }}`, want: "3"},
+		"abs":            {tpl: `{{ -4 | abs }}`, want: "4"},
+		"round":          {tpl: `{{ 3.14159 | round 2 }}`, want: "3.14"},
+		"round_integer":  {tpl: `{{ 2.5 | round 0 }}`, want: "3"},
+		"floor":          {tpl: `{{ 2.7 | floor }}`, want: "2"},
+		"ceil":           {tpl: `{{ 2.1 | ceil }}`, want: "3"},
+		"not_a_number":   {tpl: `{{ "a" | abs }}`, wantErr: true},
+		"uint64_value":   {tpl: `{{ .v | max 1 }}`, data: map[string]any{"v": uint64(42)}, want: "42"},
+		"min_not_number": {tpl: `{{ min 1 true }}`, wantErr: true},
+	})
+}
+
+func TestTimeFuncs(t *testing.T) {
+	runFuncTests(t, map[string]funcTestCase{
+		"formatTime_ns":      {tpl: `{{ .timestamp | formatTime "RFC3339" }}`, data: map[string]any{"timestamp": int64(1707574865000000000)}, want: "2024-02-10T14:21:05Z"},
+		"formatTime_float":   {tpl: `{{ .timestamp | formatTime "DateOnly" }}`, data: map[string]any{"timestamp": float64(1707574865000000000)}, want: "2024-02-10"},
+		"formatTime_layout":  {tpl: `{{ "2024-02-10T14:21:05Z" | formatTime "15:04" }}`, want: "14:21"},
+		"parseTime":          {tpl: `{{ (parseTime "DateTime" "2024-02-10 14:21:05").Unix }}`, want: "1707574865"},
+		"toTime":             {tpl: `{{ ("1707574865000000000" | toTime).Year }}`, want: "2024"},
+		"duration":           {tpl: `{{ "1m30s" | duration }}`, want: "1m30s"},
+		"duration_ns":        {tpl: `{{ (1000000000 | duration).Seconds }}`, want: "1"},
+		"now":                {tpl: `{{ gt (now.Unix) 0 }}`, want: "true"},
+		"formatTime_invalid": {tpl: `{{ "yesterday" | formatTime "RFC3339" }}`, wantErr: true},
+		"parseTime_invalid":  {tpl: `{{ parseTime "DateOnly" "10/02/2024" }}`, wantErr: true},
+		"duration_invalid":   {tpl: `{{ "1 minute" | duration }}`, wantErr: true},
+	})
+}
+
+func TestIPFuncs(t *testing.T) {
+	runFuncTests(t, map[string]funcTestCase{
+		"cidrHost":          {tpl: `{{ "10.0.0.0/24" | cidrHost 5 }}`, want: "10.0.0.5"},
+		"cidrHost_negative": {tpl: `{{ "10.0.0.0/24" | cidrHost -2 }}`, want: "10.0.0.254"},
+		"cidrHost_v6":       {tpl: `{{ "2001:db8::/64" | cidrHost 1 }}`, want: "2001:db8::1"},
+		"cidrHost_range":    {tpl: `{{ "10.0.0.0/30" | cidrHost 4 }}`, wantErr: true},
+		"cidrNetwork":       {tpl: `{{ "10.0.0.17/24" | cidrNetwork }}`, want: "10.0.0.0"},
+		"cidrNetmask":       {tpl: `{{ "10.0.0.0/22" | cidrNetmask }}`, want: "255.255.252.0"},
+		"cidrSubnet":        {tpl: `{{ "10.0.0.0/16" | cidrSubnet 8 2 }}`, want: "10.0.2.0/24"},
+		"cidrSubnet_v6":     {tpl: `{{ "2001:db8::/32" | cidrSubnet 16 1 }}`, want: "2001:db8:1::/48"},
+		"cidrSubnet_num":    {tpl: `{{ "10.0.0.0/16" | cidrSubnet 2 4 }}`, wantErr: true},
+		"cidrSubnet_bits":   {tpl: `{{ "10.0.0.0/30" | cidrSubnet 4 0 }}`, wantErr: true},
+		"nextIP":            {tpl: `{{ "10.0.0.255" | nextIP }}`, want: "10.0.1.0"},
+		"nextIP_last":       {tpl: `{{ "255.255.255.255" | nextIP }}`, wantErr: true},
+		"prevIP":            {tpl: `{{ "2001:db8::1:0" | prevIP }}`, want: "2001:db8::ffff"},
+		"ipInCIDR":          {tpl: `{{ "192.168.1.10" | ipInCIDR "192.168.0.0/16" }}`, want: "true"},
+		"ipInCIDR_no":       {tpl: `{{ "10.0.0.1" | ipInCIDR "192.168.0.0/16" }}`, want: "false"},
+		"ipVersion":         {tpl: `{{ "10.0.0.1" | ipVersion }} {{ "::ffff:10.0.0.1" | ipVersion }} {{ "2001:db8::1" | ipVersion }}`, want: "4 4 6"},
+		"invalid_prefix":    {tpl: `{{ "10.0.0.0" | cidrNetwork }}`, wantErr: true},
+		"invalid_address":   {tpl: `{{ "router1" | nextIP }}`, wantErr: true},
+	})
+}
+
+func TestEncodingFuncs(t *testing.T) {
+	runFuncTests(t, map[string]funcTestCase{
+		"fromJSON":         {tpl: `{{ ("{\"a\": {\"b\": 1}}" | fromJSON).a.b }}`, want: "1"},
+		"fromJSON_list":    {tpl: `{{ "[1, 2]" | fromJSON | len }}`, want: "2"},
+		"fromJSON_invalid": {tpl: `{{ "{" | fromJSON }}`, wantErr: true},
+		"fromYAML":         {tpl: `{{ ("a:\n  b: x" | fromYAML).a.b }}`, want: "x"},
+		"fromYAML_invalid": {tpl: `{{ "a: [" | fromYAML }}`, wantErr: true},
+		"toJSON_roundtrip": {tpl: `{{ "{\"a\":\"x\"}" | fromJSON | toJSON }}`, want: `{"a":"x"}`},
+	})
+}
+
+func TestHashFuncs(t *testing.T) {
+	runFuncTests(t, map[string]funcTestCase{
+		"md5":    {tpl: `{{ "gnmic" | md5 }}`, want: "f3297e6bf1f99d51ade0303557049cda"},
+		"sha1":   {tpl: `{{ "abc" | sha1 }}`, want: "a9993e364706816aba3e25717850c26c9cd0d89d"},
+		"sha256": {tpl: `{{ "abc" | sha256 }}`, want: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		"sha512": {tpl: `{{ "abc" | sha512 | truncate 16 }}`, want: "ddaf35a193617aba"},
+		"fnv32a": {tpl: `{{ "a" | fnv32a }}`, want: "3826002220"},
+	})
+}
+
+func TestDefaultFuncs(t *testing.T) {
+	data := map[string]any{"tags": map[string]string{"source": "r1"}, "zero": 0, "list": []string{}}
+	runFuncTests(t, map[string]funcTestCase{
+		"empty_string":   {tpl: `{{ empty "" }}`, want: "true"},
+		"empty_zero":     {tpl: `{{ empty .zero }}`, data: data, want: "true"},
+		"empty_list":     {tpl: `{{ empty .list }}`, data: data, want: "true"},
+		"empty_missing":  {tpl: `{{ empty .missing }}`, data: data, want: "true"},
+		"not_empty":      {tpl: `{{ empty .tags }}`, data: data, want: "false"},
+		"coalesce":       {tpl: `{{ coalesce .tags.target .tags.source "unknown" }}`, data: data, want: "r1"},
+		"coalesce_last":  {tpl: `{{ coalesce .missing "" "unknown" }}`, data: data, want: "unknown"},
+		"coalesce_empty": {tpl: `{{ coalesce .missing "" }}`, data: data, want: "<no value>"},
+	})
+}
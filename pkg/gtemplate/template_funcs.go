@@ -14,6 +14,8 @@ import (
 
 	"github.com/hairyhenderson/gomplate/v3"
 	"github.com/hairyhenderson/gomplate/v3/data"
+
+	"github.com/openconfig/gnmic/pkg/facts"
 )
 
 type templateEngine interface {
@@ -27,5 +29,14 @@ func NewTemplateEngine() templateEngine {
 type gmplt struct{}
 
 func (*gmplt) CreateFuncs() template.FuncMap {
-	return gomplate.CreateFuncs(context.TODO(), new(data.Data))
+	funcs := gomplate.CreateFuncs(context.TODO(), new(data.Data))
+	for n, f := range library {
+		funcs[n] = f
+	}
+	// targets facts
+	funcs["facts"] = facts.Lookup
+	funcs["fact"] = func(target, name string) string {
+		return facts.Lookup(target)[name]
+	}
+	return funcs
 }
@@ -0,0 +1,779 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bmp_input
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BMP message types, RFC 7854 section 4.1.
+const (
+	msgTypeRouteMonitoring  uint8 = 0
+	msgTypeStatisticsReport uint8 = 1
+	msgTypePeerDown         uint8 = 2
+	msgTypePeerUp           uint8 = 3
+	msgTypeInitiation       uint8 = 4
+	msgTypeTermination      uint8 = 5
+	msgTypeRouteMirroring   uint8 = 6
+)
+
+// per-peer header peer types, RFC 7854 section 4.2 and RFC 9069.
+const (
+	peerTypeGlobal uint8 = 0
+	peerTypeRD     uint8 = 1
+	peerTypeLocal  uint8 = 2
+	peerTypeLocRIB uint8 = 3
+)
+
+// per-peer header flags, RFC 7854 section 4.2 and RFC 8671.
+const (
+	peerFlagIPv6       uint8 = 0x80
+	peerFlagPostPolicy uint8 = 0x40
+	peerFlag2ByteAS    uint8 = 0x20
+	peerFlagAdjRIBOut  uint8 = 0x10
+)
+
+const (
+	bmpVersion       = 3
+	commonHeaderLen  = 6
+	perPeerHeaderLen = 42
+	bgpHeaderLen     = 19
+
+	bgpMsgTypeOpen   = 1
+	bgpMsgTypeUpdate = 2
+
+	afiIPv4     = 1
+	afiIPv6     = 2
+	safiUnicast = 1
+)
+
+// BGP path attribute types.
+const (
+	attrOrigin          = 1
+	attrASPath          = 2
+	attrNextHop         = 3
+	attrMED             = 4
+	attrLocalPref       = 5
+	attrAtomicAggregate = 6
+	attrAggregator      = 7
+	attrCommunities     = 8
+	attrOriginatorID    = 9
+	attrClusterList     = 10
+	attrMPReachNLRI     = 14
+	attrMPUnreachNLRI   = 15
+	attrLargeCommunity  = 32
+)
+
+var errShortMessage = errors.New("message too short")
+
+var messageTypeNames = map[uint8]string{
+	msgTypeRouteMonitoring:  "route-monitoring",
+	msgTypeStatisticsReport: "statistics-report",
+	msgTypePeerDown:         "peer-down",
+	msgTypePeerUp:           "peer-up",
+	msgTypeInitiation:       "initiation",
+	msgTypeTermination:      "termination",
+	msgTypeRouteMirroring:   "route-mirroring",
+}
+
+var peerTypeNames = map[uint8]string{
+	peerTypeGlobal: "global",
+	peerTypeRD:     "rd",
+	peerTypeLocal:  "local",
+	peerTypeLocRIB: "loc-rib",
+}
+
+// statistics types, RFC 7854 section 4.8 and RFC 8671 section 5.
+var statNames = map[uint16]string{
+	0:  "rejected-prefixes",
+	1:  "duplicate-prefix-advertisements",
+	2:  "duplicate-withdraws",
+	3:  "cluster-list-loops",
+	4:  "as-path-loops",
+	5:  "originator-id-loops",
+	6:  "as-confed-loops",
+	7:  "adj-rib-in-routes",
+	8:  "loc-rib-routes",
+	9:  "adj-rib-in-routes",
+	10: "loc-rib-routes",
+	11: "updates-treated-as-withdraw",
+	12: "prefixes-treated-as-withdraw",
+	13: "duplicate-update-messages",
+	14: "adj-rib-out-pre-policy-routes",
+	15: "adj-rib-out-post-policy-routes",
+	16: "adj-rib-out-pre-policy-routes",
+	17: "adj-rib-out-post-policy-routes",
+}
+
+// per AFI/SAFI statistics types.
+var afiSafiStats = map[uint16]struct{}{9: {}, 10: {}, 16: {}, 17: {}}
+
+var peerDownReasons = map[uint8]string{
+	1: "local-notification",
+	2: "local-no-notification",
+	3: "remote-notification",
+	4: "remote-no-notification",
+	5: "peer-deconfigured",
+	6: "local-system-closed",
+}
+
+var terminationReasons = map[uint16]string{
+	0: "administratively-closed",
+	1: "unspecified",
+	2: "out-of-resources",
+	3: "redundant-connection",
+	4: "permanently-administratively-closed",
+}
+
+// message is a BMP message, the per-peer header is
+// decoded for the message types that carry one.
+type message struct {
+	Type uint8
+	Peer *peerHeader
+	// message body, after the per-peer header.
+	Body []byte
+}
+
+type peerHeader struct {
+	Type          uint8
+	Flags         uint8
+	Distinguisher [8]byte
+	Address       net.IP
+	AS            uint32
+	BGPID         net.IP
+	Timestamp     time.Time
+}
+
+// readMessage reads a BMP message from r.
+// Messages longer than maxSize are rejected.
+func readMessage(r io.Reader, maxSize int) (*message, error) {
+	hdr := make([]byte, commonHeaderLen)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, err
+	}
+	if hdr[0] != bmpVersion {
+		return nil, fmt.Errorf("unsupported BMP version %d", hdr[0])
+	}
+	length := int(binary.BigEndian.Uint32(hdr[1:5]))
+	if length < commonHeaderLen {
+		return nil, fmt.Errorf("invalid BMP message length %d", length)
+	}
+	if length > maxSize {
+		return nil, fmt.Errorf("BMP message length %d exceeds the maximum size %d", length, maxSize)
+	}
+	b := make([]byte, length-commonHeaderLen)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return decodeMessage(hdr[5], b)
+}
+
+func decodeMessage(typ uint8, b []byte) (*message, error) {
+	m := &message{Type: typ, Body: b}
+	switch typ {
+	case msgTypeRouteMonitoring, msgTypeStatisticsReport, msgTypePeerDown, msgTypePeerUp, msgTypeRouteMirroring:
+		if len(b) < perPeerHeaderLen {
+			return nil, fmt.Errorf("%s: per-peer header: %w", messageTypeNames[typ], errShortMessage)
+		}
+		m.Peer = decodePeerHeader(b[:perPeerHeaderLen])
+		m.Body = b[perPeerHeaderLen:]
+	case msgTypeInitiation, msgTypeTermination:
+	default:
+		return nil, fmt.Errorf("unknown BMP message type %d", typ)
+	}
+	return m, nil
+}
+
+func decodePeerHeader(b []byte) *peerHeader {
+	h := &peerHeader{
+		Type:  b[0],
+		Flags: b[1],
+		AS:    binary.BigEndian.Uint32(b[26:30]),
+		BGPID: net.IP(append([]byte(nil), b[30:34]...)),
+	}
+	copy(h.Distinguisher[:], b[2:10])
+	if h.ipv6() {
+		h.Address = net.IP(append([]byte(nil), b[10:26]...))
+	} else {
+		h.Address = net.IP(append([]byte(nil), b[22:26]...))
+	}
+	sec := binary.BigEndian.Uint32(b[34:38])
+	usec := binary.BigEndian.Uint32(b[38:42])
+	if sec != 0 || usec != 0 {
+		h.Timestamp = time.Unix(int64(sec), int64(usec)*int64(time.Microsecond))
+	}
+	return h
+}
+
+// ipv6 reports whether the peer address is an IPv6 address.
+// The flag has a different meaning for Loc-RIB peers.
+func (h *peerHeader) ipv6() bool {
+	return h.Type != peerTypeLocRIB && h.Flags&peerFlagIPv6 != 0
+}
+
+// twoByteAS reports whether the AS_PATH uses the legacy 2-byte AS format.
+func (h *peerHeader) twoByteAS() bool {
+	return h.Type != peerTypeLocRIB && h.Flags&peerFlag2ByteAS != 0
+}
+
+// rib returns the RIB the routes of a route monitoring message belong to.
+func (h *peerHeader) rib() string {
+	if h.Type == peerTypeLocRIB {
+		return "loc-rib"
+	}
+	sb := new(strings.Builder)
+	sb.WriteString("adj-rib-")
+	if h.Flags&peerFlagAdjRIBOut != 0 {
+		sb.WriteString("out-")
+	} else {
+		sb.WriteString("in-")
+	}
+	if h.Flags&peerFlagPostPolicy != 0 {
+		sb.WriteString("post")
+	} else {
+		sb.WriteString("pre")
+	}
+	return sb.String()
+}
+
+// distinguisher returns the peer distinguisher formatted as a route distinguisher,
+// or an empty string if it is not set.
+func (h *peerHeader) distinguisher() string {
+	d := h.Distinguisher
+	if d == [8]byte{} {
+		return ""
+	}
+	switch binary.BigEndian.Uint16(d[:2]) {
+	case 0:
+		return fmt.Sprintf("%d:%d", binary.BigEndian.Uint16(d[2:4]), binary.BigEndian.Uint32(d[4:8]))
+	case 1:
+		return fmt.Sprintf("%s:%d", net.IP(d[2:6]), binary.BigEndian.Uint16(d[6:8]))
+	case 2:
+		return fmt.Sprintf("%d:%d", binary.BigEndian.Uint32(d[2:6]), binary.BigEndian.Uint16(d[6:8]))
+	}
+	return fmt.Sprintf("%x", d[:])
+}
+
+// tlv is an information TLV of the initiation, termination and peer up messages.
+type tlv struct {
+	Type  uint16
+	Value []byte
+}
+
+func decodeTLVs(b []byte) ([]tlv, error) {
+	var tlvs []tlv
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return nil, fmt.Errorf("TLV header: %w", errShortMessage)
+		}
+		l := int(binary.BigEndian.Uint16(b[2:4]))
+		if len(b) < 4+l {
+			return nil, fmt.Errorf("TLV value: %w", errShortMessage)
+		}
+		tlvs = append(tlvs, tlv{Type: binary.BigEndian.Uint16(b[:2]), Value: b[4 : 4+l]})
+		b = b[4+l:]
+	}
+	return tlvs, nil
+}
+
+// statistic is a counter or gauge of a statistics report.
+type statistic struct {
+	Name    string
+	AfiSafi string
+	Value   uint64
+}
+
+func decodeStatistics(b []byte) ([]statistic, error) {
+	if len(b) < 4 {
+		return nil, fmt.Errorf("stats count: %w", errShortMessage)
+	}
+	count := int(binary.BigEndian.Uint32(b[:4]))
+	tlvs, err := decodeTLVs(b[4:])
+	if err != nil {
+		return nil, err
+	}
+	if len(tlvs) != count {
+		return nil, fmt.Errorf("expected %d statistics, got %d", count, len(tlvs))
+	}
+	stats := make([]statistic, 0, count)
+	for _, t := range tlvs {
+		s := statistic{Name: statNames[t.Type]}
+		if s.Name == "" {
+			s.Name = "stat-" + strconv.Itoa(int(t.Type))
+		}
+		v := t.Value
+		if _, ok := afiSafiStats[t.Type]; ok {
+			if len(v) != 11 {
+				return nil, fmt.Errorf("invalid statistic %d length %d", t.Type, len(v))
+			}
+			s.AfiSafi = afiSafiName(binary.BigEndian.Uint16(v[:2]), v[2])
+			v = v[3:]
+		}
+		switch len(v) {
+		case 4:
+			s.Value = uint64(binary.BigEndian.Uint32(v))
+		case 8:
+			s.Value = binary.BigEndian.Uint64(v)
+		default:
+			return nil, fmt.Errorf("invalid statistic %d length %d", t.Type, len(t.Value))
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// peerUp is the body of a peer up notification.
+type peerUp struct {
+	LocalAddress net.IP
+	LocalPort    uint16
+	RemotePort   uint16
+	SentOpen     *bgpOpen
+	ReceivedOpen *bgpOpen
+	Information  []string
+}
+
+type bgpOpen struct {
+	AS       uint32
+	HoldTime uint16
+	BGPID    net.IP
+}
+
+func decodePeerUp(h *peerHeader, b []byte) (*peerUp, error) {
+	if len(b) < 20 {
+		return nil, fmt.Errorf("peer up: %w", errShortMessage)
+	}
+	p := &peerUp{
+		LocalPort:  binary.BigEndian.Uint16(b[16:18]),
+		RemotePort: binary.BigEndian.Uint16(b[18:20]),
+	}
+	if h.ipv6() {
+		p.LocalAddress = net.IP(append([]byte(nil), b[:16]...))
+	} else {
+		p.LocalAddress = net.IP(append([]byte(nil), b[12:16]...))
+	}
+	b = b[20:]
+	var err error
+	for _, o := range []**bgpOpen{&p.SentOpen, &p.ReceivedOpen} {
+		var msg []byte
+		msg, b, err = splitBGPMessage(b, bgpMsgTypeOpen)
+		if err != nil {
+			return nil, fmt.Errorf("peer up: %w", err)
+		}
+		*o, err = decodeOpen(msg)
+		if err != nil {
+			return nil, fmt.Errorf("peer up: %w", err)
+		}
+	}
+	tlvs, err := decodeTLVs(b)
+	if err != nil {
+		return nil, fmt.Errorf("peer up: %w", err)
+	}
+	for _, t := range tlvs {
+		if t.Type == 0 {
+			p.Information = append(p.Information, string(t.Value))
+		}
+	}
+	return p, nil
+}
+
+// splitBGPMessage returns the body of the BGP message at the start of b
+// and the bytes following it.
+func splitBGPMessage(b []byte, typ uint8) ([]byte, []byte, error) {
+	if len(b) < bgpHeaderLen {
+		return nil, nil, fmt.Errorf("BGP header: %w", errShortMessage)
+	}
+	l := int(binary.BigEndian.Uint16(b[16:18]))
+	if l < bgpHeaderLen || len(b) < l {
+		return nil, nil, fmt.Errorf("invalid BGP message length %d", l)
+	}
+	if b[18] != typ {
+		return nil, nil, fmt.Errorf("unexpected BGP message type %d, expected %d", b[18], typ)
+	}
+	return b[bgpHeaderLen:l], b[l:], nil
+}
+
+func decodeOpen(b []byte) (*bgpOpen, error) {
+	if len(b) < 10 {
+		return nil, fmt.Errorf("BGP OPEN: %w", errShortMessage)
+	}
+	o := &bgpOpen{
+		AS:       uint32(binary.BigEndian.Uint16(b[1:3])),
+		HoldTime: binary.BigEndian.Uint16(b[3:5]),
+		BGPID:    net.IP(append([]byte(nil), b[5:9]...)),
+	}
+	params := b[10:]
+	if len(params) < int(b[9]) {
+		return nil, fmt.Errorf("BGP OPEN optional parameters: %w", errShortMessage)
+	}
+	params = params[:b[9]]
+	// look for the 4-octet AS number capability, RFC 6793.
+	for len(params) >= 2 {
+		pt, pl := params[0], int(params[1])
+		if len(params) < 2+pl {
+			break
+		}
+		if pt == 2 {
+			caps := params[2 : 2+pl]
+			for len(caps) >= 2 {
+				code, cl := caps[0], int(caps[1])
+				if len(caps) < 2+cl {
+					break
+				}
+				if code == 65 && cl == 4 {
+					o.AS = binary.BigEndian.Uint32(caps[2:6])
+				}
+				caps = caps[2+cl:]
+			}
+		}
+		params = params[2+pl:]
+	}
+	return o, nil
+}
+
+// peerDown is the body of a peer down notification.
+type peerDown struct {
+	Reason              string
+	NotificationCode    uint8
+	NotificationSubcode uint8
+	FSMEvent            uint16
+}
+
+func decodePeerDown(b []byte) (*peerDown, error) {
+	if len(b) < 1 {
+		return nil, fmt.Errorf("peer down: %w", errShortMessage)
+	}
+	p := &peerDown{Reason: peerDownReasons[b[0]]}
+	if p.Reason == "" {
+		p.Reason = "reason-" + strconv.Itoa(int(b[0]))
+	}
+	switch b[0] {
+	case 1, 3:
+		msg, _, err := splitBGPMessage(b[1:], 3)
+		if err != nil {
+			return nil, fmt.Errorf("peer down: %w", err)
+		}
+		if len(msg) < 2 {
+			return nil, fmt.Errorf("peer down notification: %w", errShortMessage)
+		}
+		p.NotificationCode, p.NotificationSubcode = msg[0], msg[1]
+	case 2:
+		if len(b) < 3 {
+			return nil, fmt.Errorf("peer down FSM event: %w", errShortMessage)
+		}
+		p.FSMEvent = binary.BigEndian.Uint16(b[1:3])
+	}
+	return p, nil
+}
+
+// route is a prefix announced or withdrawn by a BGP UPDATE.
+type route struct {
+	AfiSafi  string
+	Prefix   string
+	NextHop  string
+	Withdraw bool
+}
+
+// pathAttributes are the common path attributes of a BGP UPDATE.
+type pathAttributes struct {
+	Origin           string
+	ASPath           []string
+	ASPathLength     int
+	NextHop          string
+	MED              *uint32
+	LocalPref        *uint32
+	AtomicAggregate  bool
+	Aggregator       string
+	Communities      []string
+	LargeCommunities []string
+	OriginatorID     string
+	ClusterList      []string
+}
+
+// decodeUpdate decodes the BGP UPDATE message b. Only the IPv4 and IPv6 unicast routes
+// are returned, the routes of other address families are ignored.
+func decodeUpdate(b []byte, twoByteAS bool) ([]route, *pathAttributes, error) {
+	body, _, err := splitBGPMessage(b, bgpMsgTypeUpdate)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(body) < 2 {
+		return nil, nil, fmt.Errorf("BGP UPDATE withdrawn routes length: %w", errShortMessage)
+	}
+	wl := int(binary.BigEndian.Uint16(body[:2]))
+	if len(body) < 2+wl+2 {
+		return nil, nil, fmt.Errorf("BGP UPDATE withdrawn routes: %w", errShortMessage)
+	}
+	withdrawn, err := decodePrefixes(body[2:2+wl], afiIPv4)
+	if err != nil {
+		return nil, nil, err
+	}
+	body = body[2+wl:]
+	al := int(binary.BigEndian.Uint16(body[:2]))
+	if len(body) < 2+al {
+		return nil, nil, fmt.Errorf("BGP UPDATE path attributes: %w", errShortMessage)
+	}
+	nlri, err := decodePrefixes(body[2+al:], afiIPv4)
+	if err != nil {
+		return nil, nil, err
+	}
+	attrs := new(pathAttributes)
+	var routes []route
+	for _, p := range withdrawn {
+		routes = append(routes, route{AfiSafi: afiSafiName(afiIPv4, safiUnicast), Prefix: p, Withdraw: true})
+	}
+	ab := body[2 : 2+al]
+	for len(ab) > 0 {
+		if len(ab) < 3 {
+			return nil, nil, fmt.Errorf("path attribute header: %w", errShortMessage)
+		}
+		flags, typ := ab[0], ab[1]
+		hl, l := 3, int(ab[2])
+		if flags&0x10 != 0 { // extended length
+			if len(ab) < 4 {
+				return nil, nil, fmt.Errorf("path attribute header: %w", errShortMessage)
+			}
+			hl, l = 4, int(binary.BigEndian.Uint16(ab[2:4]))
+		}
+		if len(ab) < hl+l {
+			return nil, nil, fmt.Errorf("path attribute %d: %w", typ, errShortMessage)
+		}
+		v := ab[hl : hl+l]
+		ab = ab[hl+l:]
+		switch typ {
+		case attrMPReachNLRI:
+			rs, err := decodeMPReach(v)
+			if err != nil {
+				return nil, nil, err
+			}
+			routes = append(routes, rs...)
+		case attrMPUnreachNLRI:
+			rs, err := decodeMPUnreach(v)
+			if err != nil {
+				return nil, nil, err
+			}
+			routes = append(routes, rs...)
+		default:
+			if err := attrs.decode(typ, v, twoByteAS); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	for _, p := range nlri {
+		routes = append(routes, route{AfiSafi: afiSafiName(afiIPv4, safiUnicast), Prefix: p, NextHop: attrs.NextHop})
+	}
+	return routes, attrs, nil
+}
+
+func (a *pathAttributes) decode(typ uint8, v []byte, twoByteAS bool) error {
+	switch typ {
+	case attrOrigin:
+		if len(v) != 1 {
+			return fmt.Errorf("invalid ORIGIN length %d", len(v))
+		}
+		switch v[0] {
+		case 0:
+			a.Origin = "igp"
+		case 1:
+			a.Origin = "egp"
+		default:
+			a.Origin = "incomplete"
+		}
+	case attrASPath:
+		asLen := 4
+		if twoByteAS {
+			asLen = 2
+		}
+		for len(v) > 0 {
+			if len(v) < 2 {
+				return fmt.Errorf("AS_PATH segment: %w", errShortMessage)
+			}
+			st, n := v[0], int(v[1])
+			if len(v) < 2+n*asLen {
+				return fmt.Errorf("AS_PATH segment: %w", errShortMessage)
+			}
+			asns := make([]string, 0, n)
+			for i := 0; i < n; i++ {
+				b := v[2+i*asLen : 2+(i+1)*asLen]
+				if asLen == 2 {
+					asns = append(asns, strconv.Itoa(int(binary.BigEndian.Uint16(b))))
+				} else {
+					asns = append(asns, strconv.FormatUint(uint64(binary.BigEndian.Uint32(b)), 10))
+				}
+			}
+			// AS_SET and AS_CONFED_SET count as a single AS in the path length.
+			switch st {
+			case 1, 4:
+				a.ASPath = append(a.ASPath, "{"+strings.Join(asns, ",")+"}")
+				a.ASPathLength++
+			default:
+				a.ASPath = append(a.ASPath, asns...)
+				a.ASPathLength += n
+			}
+			v = v[2+n*asLen:]
+		}
+	case attrNextHop:
+		if len(v) != 4 {
+			return fmt.Errorf("invalid NEXT_HOP length %d", len(v))
+		}
+		a.NextHop = net.IP(v).String()
+	case attrMED:
+		if len(v) != 4 {
+			return fmt.Errorf("invalid MULTI_EXIT_DISC length %d", len(v))
+		}
+		med := binary.BigEndian.Uint32(v)
+		a.MED = &med
+	case attrLocalPref:
+		if len(v) != 4 {
+			return fmt.Errorf("invalid LOCAL_PREF length %d", len(v))
+		}
+		lp := binary.BigEndian.Uint32(v)
+		a.LocalPref = &lp
+	case attrAtomicAggregate:
+		a.AtomicAggregate = true
+	case attrAggregator:
+		switch len(v) {
+		case 6:
+			a.Aggregator = fmt.Sprintf("%d:%s", binary.BigEndian.Uint16(v[:2]), net.IP(v[2:6]))
+		case 8:
+			a.Aggregator = fmt.Sprintf("%d:%s", binary.BigEndian.Uint32(v[:4]), net.IP(v[4:8]))
+		default:
+			return fmt.Errorf("invalid AGGREGATOR length %d", len(v))
+		}
+	case attrCommunities:
+		if len(v)%4 != 0 {
+			return fmt.Errorf("invalid COMMUNITIES length %d", len(v))
+		}
+		for i := 0; i < len(v); i += 4 {
+			a.Communities = append(a.Communities, fmt.Sprintf("%d:%d", binary.BigEndian.Uint16(v[i:i+2]), binary.BigEndian.Uint16(v[i+2:i+4])))
+		}
+	case attrOriginatorID:
+		if len(v) != 4 {
+			return fmt.Errorf("invalid ORIGINATOR_ID length %d", len(v))
+		}
+		a.OriginatorID = net.IP(v).String()
+	case attrClusterList:
+		if len(v)%4 != 0 {
+			return fmt.Errorf("invalid CLUSTER_LIST length %d", len(v))
+		}
+		for i := 0; i < len(v); i += 4 {
+			a.ClusterList = append(a.ClusterList, net.IP(v[i:i+4]).String())
+		}
+	case attrLargeCommunity:
+		if len(v)%12 != 0 {
+			return fmt.Errorf("invalid LARGE_COMMUNITY length %d", len(v))
+		}
+		for i := 0; i < len(v); i += 12 {
+			a.LargeCommunities = append(a.LargeCommunities, fmt.Sprintf("%d:%d:%d",
+				binary.BigEndian.Uint32(v[i:i+4]), binary.BigEndian.Uint32(v[i+4:i+8]), binary.BigEndian.Uint32(v[i+8:i+12])))
+		}
+	}
+	return nil
+}
+
+func decodeMPReach(v []byte) ([]route, error) {
+	if len(v) < 5 {
+		return nil, fmt.Errorf("MP_REACH_NLRI: %w", errShortMessage)
+	}
+	afi, safi, nhl := binary.BigEndian.Uint16(v[:2]), v[2], int(v[3])
+	if len(v) < 4+nhl+1 {
+		return nil, fmt.Errorf("MP_REACH_NLRI next hop: %w", errShortMessage)
+	}
+	if safi != safiUnicast || (afi != afiIPv4 && afi != afiIPv6) {
+		return nil, nil
+	}
+	var nh string
+	nhb := v[4 : 4+nhl]
+	switch {
+	case afi == afiIPv6 && (nhl == 16 || nhl == 32): // global and optional link local addresses
+		nh = net.IP(nhb[:16]).String()
+	case nhl == 4 || nhl == 16:
+		nh = net.IP(nhb).String()
+	}
+	prefixes, err := decodePrefixes(v[4+nhl+1:], afi)
+	if err != nil {
+		return nil, err
+	}
+	routes := make([]route, 0, len(prefixes))
+	for _, p := range prefixes {
+		routes = append(routes, route{AfiSafi: afiSafiName(afi, safi), Prefix: p, NextHop: nh})
+	}
+	return routes, nil
+}
+
+func decodeMPUnreach(v []byte) ([]route, error) {
+	if len(v) < 3 {
+		return nil, fmt.Errorf("MP_UNREACH_NLRI: %w", errShortMessage)
+	}
+	afi, safi := binary.BigEndian.Uint16(v[:2]), v[2]
+	if safi != safiUnicast || (afi != afiIPv4 && afi != afiIPv6) {
+		return nil, nil
+	}
+	prefixes, err := decodePrefixes(v[3:], afi)
+	if err != nil {
+		return nil, err
+	}
+	routes := make([]route, 0, len(prefixes))
+	for _, p := range prefixes {
+		routes = append(routes, route{AfiSafi: afiSafiName(afi, safi), Prefix: p, Withdraw: true})
+	}
+	return routes, nil
+}
+
+// decodePrefixes decodes a list of NLRI prefixes of the address family afi.
+func decodePrefixes(b []byte, afi uint16) ([]string, error) {
+	size := 4
+	if afi == afiIPv6 {
+		size = 16
+	}
+	var prefixes []string
+	for len(b) > 0 {
+		bits := int(b[0])
+		n := (bits + 7) / 8
+		if bits > size*8 {
+			return nil, fmt.Errorf("invalid prefix length %d", bits)
+		}
+		if len(b) < 1+n {
+			return nil, fmt.Errorf("prefix: %w", errShortMessage)
+		}
+		ip := make(net.IP, size)
+		copy(ip, b[1:1+n])
+		prefixes = append(prefixes, fmt.Sprintf("%s/%d", ip, bits))
+		b = b[1+n:]
+	}
+	return prefixes, nil
+}
+
+func afiSafiName(afi uint16, safi uint8) string {
+	var sb strings.Builder
+	switch afi {
+	case afiIPv4:
+		sb.WriteString("ipv4")
+	case afiIPv6:
+		sb.WriteString("ipv6")
+	default:
+		sb.WriteString("afi-" + strconv.Itoa(int(afi)))
+	}
+	sb.WriteString("-")
+	switch safi {
+	case safiUnicast:
+		sb.WriteString("unicast")
+	case 2:
+		sb.WriteString("multicast")
+	default:
+		sb.WriteString("safi-" + strconv.Itoa(int(safi)))
+	}
+	return sb.String()
+}
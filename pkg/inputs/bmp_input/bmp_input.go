@@ -0,0 +1,462 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bmp_input
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/inputs"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	loggingPrefix         = "[bmp_input] "
+	defaultAddress        = ":11019"
+	defaultMaxConnections = 100
+	defaultMaxMessageSize = 1024 * 1024
+)
+
+func init() {
+	inputs.Register("bmp", func() inputs.Input {
+		return &bmpInput{
+			Cfg:    &Config{},
+			logger: log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+			wg:     new(sync.WaitGroup),
+		}
+	})
+}
+
+// bmpInput accepts BMP sessions from BGP speakers and
+// exports the received messages as events.
+type bmpInput struct {
+	Cfg *Config
+	// events name
+	name   string
+	ctx    context.Context
+	cfn    context.CancelFunc
+	logger *log.Logger
+
+	wg       *sync.WaitGroup
+	listener net.Listener
+	sem      chan struct{}
+	outputs  []outputs.Output
+	evps     []formatters.EventProcessor
+}
+
+// Config //
+type Config struct {
+	Name string `mapstructure:"name,omitempty"`
+	// TCP address to listen on for BMP sessions.
+	Address string `mapstructure:"address,omitempty"`
+	// maximum number of concurrent BMP sessions.
+	MaxConnections int `mapstructure:"max-connections,omitempty"`
+	// maximum BMP message size, longer messages close the session.
+	MaxMessageSize  int      `mapstructure:"max-message-size,omitempty"`
+	Debug           bool     `mapstructure:"debug,omitempty"`
+	Outputs         []string `mapstructure:"outputs,omitempty"`
+	EventProcessors []string `mapstructure:"event-processors,omitempty"`
+}
+
+// Start //
+func (b *bmpInput) Start(ctx context.Context, name string, cfg map[string]interface{}, opts ...inputs.Option) error {
+	err := outputs.DecodeConfig(cfg, b.Cfg)
+	if err != nil {
+		return err
+	}
+	if b.Cfg.Name == "" {
+		b.Cfg.Name = name
+	}
+	b.name = b.Cfg.Name
+	for _, opt := range opts {
+		if err := opt(b); err != nil {
+			return err
+		}
+	}
+	b.setDefaults()
+	b.listener, err = net.Listen("tcp", b.Cfg.Address)
+	if err != nil {
+		return err
+	}
+	b.sem = make(chan struct{}, b.Cfg.MaxConnections)
+	b.ctx, b.cfn = context.WithCancel(ctx)
+	b.logger.Printf("input starting with config: %+v", b.Cfg)
+	inputs.StartFlush(b.ctx, b.evps, b.outputs, b.logger)
+	b.wg.Add(1)
+	go b.serve()
+	return nil
+}
+
+func (b *bmpInput) serve() {
+	defer b.wg.Done()
+	go func() {
+		<-b.ctx.Done()
+		b.listener.Close()
+	}()
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			if b.ctx.Err() != nil {
+				return
+			}
+			b.logger.Printf("failed to accept connection: %v", err)
+			continue
+		}
+		select {
+		case b.sem <- struct{}{}:
+		default:
+			b.logger.Printf("rejecting BMP session from %s: max-connections (%d) reached", conn.RemoteAddr(), b.Cfg.MaxConnections)
+			conn.Close()
+			continue
+		}
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			defer func() { <-b.sem }()
+			b.handleSession(conn)
+		}()
+	}
+}
+
+func (b *bmpInput) handleSession(conn net.Conn) {
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(b.ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	s := &session{name: b.name, router: utils.GetHost(conn.RemoteAddr().String())}
+	b.logger.Printf("BMP session from %s started", s.router)
+	r := bufio.NewReader(conn)
+	for {
+		m, err := readMessage(r, b.Cfg.MaxMessageSize)
+		if err != nil {
+			if errors.Is(err, io.EOF) || ctx.Err() != nil {
+				b.logger.Printf("BMP session from %s closed", s.router)
+			} else {
+				b.logger.Printf("BMP session from %s failed: %v", s.router, err)
+			}
+			return
+		}
+		evs, err := s.events(m, time.Now())
+		if err != nil {
+			// the message boundaries are still known,
+			// a message that fails to decode does not close the session.
+			b.logger.Printf("router %s: failed to decode %s message: %v", s.router, messageTypeNames[m.Type], err)
+			continue
+		}
+		if b.Cfg.Debug {
+			b.logger.Printf("router %s: received %s message, %d event(s)", s.router, messageTypeNames[m.Type], len(evs))
+		}
+		for _, p := range b.evps {
+			evs = p.Apply(evs...)
+		}
+		for _, o := range b.outputs {
+			for _, ev := range evs {
+				o.WriteEvent(ctx, ev)
+			}
+		}
+		if m.Type == msgTypeTermination {
+			b.logger.Printf("BMP session from %s terminated", s.router)
+			return
+		}
+	}
+}
+
+// Close //
+func (b *bmpInput) Close() error {
+	if b.cfn != nil {
+		b.cfn()
+	}
+	b.wg.Wait()
+	return nil
+}
+
+// SetLogger //
+func (b *bmpInput) SetLogger(logger *log.Logger) {
+	if logger != nil && b.logger != nil {
+		b.logger.SetOutput(logger.Writer())
+		b.logger.SetFlags(logger.Flags())
+	}
+}
+
+// SetOutputs //
+func (b *bmpInput) SetOutputs(outs map[string]outputs.Output) {
+	if len(b.Cfg.Outputs) == 0 {
+		members := outputs.GroupMembers(outs)
+		for name, o := range outs {
+			if _, ok := members[name]; ok {
+				continue
+			}
+			b.outputs = append(b.outputs, o)
+		}
+		return
+	}
+	for _, name := range b.Cfg.Outputs {
+		if o, ok := outs[name]; ok {
+			b.outputs = append(b.outputs, o)
+		}
+	}
+}
+
+func (b *bmpInput) SetName(name string) {
+	sb := strings.Builder{}
+	if name != "" {
+		sb.WriteString(name)
+		sb.WriteString("-")
+	}
+	sb.WriteString(b.Cfg.Name)
+	sb.WriteString("-bmp")
+	b.Cfg.Name = sb.String()
+}
+
+func (b *bmpInput) SetEventProcessors(ps map[string]map[string]interface{}, logger *log.Logger, tcs map[string]*types.TargetConfig, acts map[string]map[string]interface{}) error {
+	var err error
+	b.evps, err = formatters.MakeEventProcessors(
+		logger,
+		b.Cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	return err
+}
+
+// helper functions
+
+func (b *bmpInput) setDefaults() {
+	if b.Cfg.Address == "" {
+		b.Cfg.Address = defaultAddress
+	}
+	if b.Cfg.MaxConnections <= 0 {
+		b.Cfg.MaxConnections = defaultMaxConnections
+	}
+	if b.Cfg.MaxMessageSize <= 0 {
+		b.Cfg.MaxMessageSize = defaultMaxMessageSize
+	}
+}
+
+// session is the state of a BMP session with a router.
+type session struct {
+	// events name
+	name string
+	// router address
+	router string
+	// sysName from the initiation message
+	routerName string
+}
+
+// events converts the BMP message m to event messages.
+// now is the timestamp of the messages without a per-peer header timestamp.
+func (s *session) events(m *message, now time.Time) ([]*formatters.EventMsg, error) {
+	switch m.Type {
+	case msgTypeInitiation:
+		tlvs, err := decodeTLVs(m.Body)
+		if err != nil {
+			return nil, err
+		}
+		ev := s.event(m, now)
+		for _, t := range tlvs {
+			switch t.Type {
+			case 0:
+				ev.Values["information"] = string(t.Value)
+			case 1:
+				ev.Values["sys_descr"] = string(t.Value)
+			case 2:
+				s.routerName = string(t.Value)
+				ev.Values["sys_name"] = s.routerName
+				ev.Tags["router_name"] = s.routerName
+			}
+		}
+		return []*formatters.EventMsg{ev}, nil
+	case msgTypeTermination:
+		tlvs, err := decodeTLVs(m.Body)
+		if err != nil {
+			return nil, err
+		}
+		ev := s.event(m, now)
+		for _, t := range tlvs {
+			switch t.Type {
+			case 0:
+				ev.Values["information"] = string(t.Value)
+			case 1:
+				if len(t.Value) != 2 {
+					return nil, fmt.Errorf("invalid termination reason length %d", len(t.Value))
+				}
+				code := binary.BigEndian.Uint16(t.Value)
+				reason, ok := terminationReasons[code]
+				if !ok {
+					reason = "reason-" + strconv.Itoa(int(code))
+				}
+				ev.Values["reason"] = reason
+			}
+		}
+		return []*formatters.EventMsg{ev}, nil
+	case msgTypePeerUp:
+		p, err := decodePeerUp(m.Peer, m.Body)
+		if err != nil {
+			return nil, err
+		}
+		ev := s.event(m, now)
+		ev.Values["state"] = "up"
+		ev.Values["local_address"] = p.LocalAddress.String()
+		ev.Values["local_port"] = p.LocalPort
+		ev.Values["remote_port"] = p.RemotePort
+		ev.Values["sent_as"] = p.SentOpen.AS
+		ev.Values["sent_hold_time"] = p.SentOpen.HoldTime
+		ev.Values["sent_bgp_id"] = p.SentOpen.BGPID.String()
+		ev.Values["received_as"] = p.ReceivedOpen.AS
+		ev.Values["received_hold_time"] = p.ReceivedOpen.HoldTime
+		ev.Values["received_bgp_id"] = p.ReceivedOpen.BGPID.String()
+		if len(p.Information) > 0 {
+			ev.Values["information"] = strings.Join(p.Information, "\n")
+		}
+		return []*formatters.EventMsg{ev}, nil
+	case msgTypePeerDown:
+		p, err := decodePeerDown(m.Body)
+		if err != nil {
+			return nil, err
+		}
+		ev := s.event(m, now)
+		ev.Values["state"] = "down"
+		ev.Values["reason"] = p.Reason
+		if p.NotificationCode != 0 {
+			ev.Values["notification_code"] = p.NotificationCode
+			ev.Values["notification_subcode"] = p.NotificationSubcode
+		}
+		if p.FSMEvent != 0 {
+			ev.Values["fsm_event"] = p.FSMEvent
+		}
+		return []*formatters.EventMsg{ev}, nil
+	case msgTypeStatisticsReport:
+		stats, err := decodeStatistics(m.Body)
+		if err != nil {
+			return nil, err
+		}
+		ev := s.event(m, now)
+		for _, st := range stats {
+			name := st.Name
+			if st.AfiSafi != "" {
+				name += "/" + st.AfiSafi
+			}
+			ev.Values[name] = st.Value
+		}
+		return []*formatters.EventMsg{ev}, nil
+	case msgTypeRouteMonitoring:
+		routes, attrs, err := decodeUpdate(m.Body, m.Peer.twoByteAS())
+		if err != nil {
+			return nil, err
+		}
+		evs := make([]*formatters.EventMsg, 0, len(routes))
+		for _, r := range routes {
+			ev := s.event(m, now)
+			ev.Tags["rib"] = m.Peer.rib()
+			ev.Tags["afi_safi"] = r.AfiSafi
+			ev.Tags["prefix"] = r.Prefix
+			if r.Withdraw {
+				ev.Values["action"] = "withdraw"
+				evs = append(evs, ev)
+				continue
+			}
+			ev.Values["action"] = "announce"
+			attrs.addValues(ev.Values)
+			if r.NextHop != "" {
+				ev.Values["next_hop"] = r.NextHop
+			}
+			evs = append(evs, ev)
+		}
+		return evs, nil
+	}
+	// route mirroring messages are ignored.
+	return nil, nil
+}
+
+// event returns an event with the message type, router and peer tags set.
+func (s *session) event(m *message, now time.Time) *formatters.EventMsg {
+	ev := &formatters.EventMsg{
+		Name:      s.name,
+		Timestamp: now.UnixNano(),
+		Tags: map[string]string{
+			"source":       s.router,
+			"message_type": messageTypeNames[m.Type],
+		},
+		Values: make(map[string]interface{}),
+	}
+	if s.routerName != "" {
+		ev.Tags["router_name"] = s.routerName
+	}
+	h := m.Peer
+	if h == nil {
+		return ev
+	}
+	if !h.Timestamp.IsZero() {
+		ev.Timestamp = h.Timestamp.UnixNano()
+	}
+	ev.Tags["peer_type"] = peerTypeNames[h.Type]
+	if ev.Tags["peer_type"] == "" {
+		ev.Tags["peer_type"] = strconv.Itoa(int(h.Type))
+	}
+	if d := h.distinguisher(); d != "" {
+		ev.Tags["peer_distinguisher"] = d
+	}
+	if h.Type != peerTypeLocRIB {
+		ev.Tags["peer_address"] = h.Address.String()
+		ev.Tags["peer_as"] = strconv.FormatUint(uint64(h.AS), 10)
+	}
+	ev.Tags["peer_bgp_id"] = h.BGPID.String()
+	return ev
+}
+
+func (a *pathAttributes) addValues(vs map[string]interface{}) {
+	if a.Origin != "" {
+		vs["origin"] = a.Origin
+	}
+	if len(a.ASPath) > 0 {
+		vs["as_path"] = strings.Join(a.ASPath, " ")
+	}
+	vs["as_path_length"] = a.ASPathLength
+	if a.MED != nil {
+		vs["med"] = *a.MED
+	}
+	if a.LocalPref != nil {
+		vs["local_pref"] = *a.LocalPref
+	}
+	if a.AtomicAggregate {
+		vs["atomic_aggregate"] = true
+	}
+	if a.Aggregator != "" {
+		vs["aggregator"] = a.Aggregator
+	}
+	if len(a.Communities) > 0 {
+		vs["communities"] = strings.Join(a.Communities, " ")
+	}
+	if len(a.LargeCommunities) > 0 {
+		vs["large_communities"] = strings.Join(a.LargeCommunities, " ")
+	}
+	if a.OriginatorID != "" {
+		vs["originator_id"] = a.OriginatorID
+	}
+	if len(a.ClusterList) > 0 {
+		vs["cluster_list"] = strings.Join(a.ClusterList, " ")
+	}
+}
@@ -0,0 +1,436 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bmp_input
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"log"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+// BMP and BGP messages builders
+
+func u16(v uint16) []byte { return binary.BigEndian.AppendUint16(nil, v) }
+func u32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+func concat(bs ...[]byte) []byte { return bytes.Join(bs, nil) }
+
+func bmpMsg(typ uint8, body ...[]byte) []byte {
+	b := concat(body...)
+	return concat([]byte{bmpVersion}, u32(uint32(commonHeaderLen+len(b))), []byte{typ}, b)
+}
+
+func peerHdr(typ, flags uint8, addr string, as uint32, bgpID string, ts uint32) []byte {
+	a := make([]byte, 16)
+	if ip := net.ParseIP(addr); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			copy(a[12:], ip4)
+		} else {
+			copy(a, ip)
+		}
+	}
+	return concat([]byte{typ, flags}, make([]byte, 8), a, u32(as), net.ParseIP(bgpID).To4(), u32(ts), u32(0))
+}
+
+func bgpMsg(typ uint8, body []byte) []byte {
+	marker := bytes.Repeat([]byte{0xff}, 16)
+	return concat(marker, u16(uint16(bgpHeaderLen+len(body))), []byte{typ}, body)
+}
+
+func updateMsg(withdrawn, attrs, nlri []byte) []byte {
+	return bgpMsg(bgpMsgTypeUpdate, concat(u16(uint16(len(withdrawn))), withdrawn, u16(uint16(len(attrs))), attrs, nlri))
+}
+
+func attr(typ uint8, v []byte) []byte {
+	if len(v) > 255 {
+		return concat([]byte{0x50, typ}, u16(uint16(len(v))), v)
+	}
+	return concat([]byte{0x40, typ, uint8(len(v))}, v)
+}
+
+func prefix(bits int, ip string) []byte {
+	b := net.ParseIP(ip)
+	if b4 := b.To4(); b4 != nil {
+		b = b4
+	}
+	return concat([]byte{uint8(bits)}, b[:(bits+7)/8])
+}
+
+func tlvBytes(typ uint16, v []byte) []byte {
+	return concat(u16(typ), u16(uint16(len(v))), v)
+}
+
+func openMsg(as uint16, holdTime uint16, bgpID string, as4 uint32) []byte {
+	caps := []byte{65, 4}
+	caps = append(caps, u32(as4)...)
+	params := concat([]byte{2, uint8(len(caps))}, caps)
+	return bgpMsg(bgpMsgTypeOpen, concat([]byte{4}, u16(as), u16(holdTime), net.ParseIP(bgpID).To4(), []byte{uint8(len(params))}, params))
+}
+
+func testUpdate() []byte {
+	return updateMsg(
+		prefix(24, "10.0.9.0"),
+		concat(
+			attr(attrOrigin, []byte{0}),
+			attr(attrASPath, concat([]byte{2, 2}, u32(65001), u32(4200000000), []byte{1, 2}, u32(65010), u32(65011))),
+			attr(attrNextHop, net.ParseIP("192.0.2.1").To4()),
+			attr(attrMED, u32(10)),
+			attr(attrLocalPref, u32(200)),
+			attr(attrCommunities, concat(u16(65001), u16(100))),
+			attr(attrLargeCommunity, concat(u32(65001), u32(1), u32(2))),
+			attr(attrMPReachNLRI, concat(u16(afiIPv6), []byte{safiUnicast, 32}, net.ParseIP("2001:db8::1"), net.ParseIP("fe80::1"), []byte{0}, prefix(48, "2001:db8:1::"))),
+			attr(attrMPUnreachNLRI, concat(u16(afiIPv6), []byte{safiUnicast}, prefix(64, "2001:db8:2::"))),
+		),
+		concat(prefix(24, "10.0.1.0"), prefix(32, "10.0.2.1")),
+	)
+}
+
+func TestDecodeUpdate(t *testing.T) {
+	routes, attrs, err := decodeUpdate(testUpdate(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedRoutes := []route{
+		{AfiSafi: "ipv4-unicast", Prefix: "10.0.9.0/24", Withdraw: true},
+		{AfiSafi: "ipv6-unicast", Prefix: "2001:db8:1::/48", NextHop: "2001:db8::1"},
+		{AfiSafi: "ipv6-unicast", Prefix: "2001:db8:2::/64", Withdraw: true},
+		{AfiSafi: "ipv4-unicast", Prefix: "10.0.1.0/24", NextHop: "192.0.2.1"},
+		{AfiSafi: "ipv4-unicast", Prefix: "10.0.2.1/32", NextHop: "192.0.2.1"},
+	}
+	if !reflect.DeepEqual(routes, expectedRoutes) {
+		t.Errorf("unexpected routes:\n%+v\nexpected:\n%+v", routes, expectedRoutes)
+	}
+	med, lp := uint32(10), uint32(200)
+	expectedAttrs := &pathAttributes{
+		Origin:           "igp",
+		ASPath:           []string{"65001", "4200000000", "{65010,65011}"},
+		ASPathLength:     3,
+		NextHop:          "192.0.2.1",
+		MED:              &med,
+		LocalPref:        &lp,
+		Communities:      []string{"65001:100"},
+		LargeCommunities: []string{"65001:1:2"},
+	}
+	if !reflect.DeepEqual(attrs, expectedAttrs) {
+		t.Errorf("unexpected attributes:\n%+v\nexpected:\n%+v", attrs, expectedAttrs)
+	}
+
+	// legacy 2-byte AS_PATH
+	_, attrs, err = decodeUpdate(updateMsg(nil, attr(attrASPath, concat([]byte{2, 2}, u16(65001), u16(65002))), prefix(8, "10.0.0.0")), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(attrs.ASPath, []string{"65001", "65002"}) {
+		t.Errorf("unexpected 2-byte AS path: %v", attrs.ASPath)
+	}
+
+	// truncated messages
+	b := testUpdate()
+	for _, l := range []int{bgpHeaderLen + 1, len(b) - 1} {
+		tb := append([]byte(nil), b[:l]...)
+		binary.BigEndian.PutUint16(tb[16:18], uint16(l))
+		if _, _, err := decodeUpdate(tb, false); err == nil {
+			t.Errorf("expected an error decoding a %d bytes update", l)
+		}
+	}
+}
+
+func TestSessionEvents(t *testing.T) {
+	now := time.Unix(100, 0)
+	peerTags := map[string]string{
+		"source":       "10.1.1.1",
+		"router_name":  "r1",
+		"peer_type":    "global",
+		"peer_address": "192.0.2.2",
+		"peer_as":      "65002",
+		"peer_bgp_id":  "2.2.2.2",
+	}
+	withTags := func(m map[string]string, kvs ...string) map[string]string {
+		r := make(map[string]string, len(m)+len(kvs)/2)
+		for k, v := range m {
+			r[k] = v
+		}
+		for i := 0; i < len(kvs); i += 2 {
+			r[kvs[i]] = kvs[i+1]
+		}
+		return r
+	}
+	tests := []struct {
+		name   string
+		msg    []byte
+		events []*formatters.EventMsg
+	}{
+		{
+			name: "initiation",
+			msg:  bmpMsg(msgTypeInitiation, tlvBytes(1, []byte("router OS")), tlvBytes(2, []byte("r1"))),
+			events: []*formatters.EventMsg{{
+				Name:      "bmp1",
+				Timestamp: now.UnixNano(),
+				Tags:      map[string]string{"source": "10.1.1.1", "router_name": "r1", "message_type": "initiation"},
+				Values:    map[string]interface{}{"sys_descr": "router OS", "sys_name": "r1"},
+			}},
+		},
+		{
+			name: "peer_up",
+			msg: bmpMsg(msgTypePeerUp,
+				peerHdr(peerTypeGlobal, 0, "192.0.2.2", 65002, "2.2.2.2", 200),
+				make([]byte, 12), net.ParseIP("192.0.2.1").To4(), u16(179), u16(50000),
+				openMsg(23456, 90, "1.1.1.1", 4200000001),
+				openMsg(65002, 30, "2.2.2.2", 65002),
+				tlvBytes(0, []byte("peer up"))),
+			events: []*formatters.EventMsg{{
+				Name:      "bmp1",
+				Timestamp: time.Unix(200, 0).UnixNano(),
+				Tags:      withTags(peerTags, "message_type", "peer-up"),
+				Values: map[string]interface{}{
+					"state":              "up",
+					"local_address":      "192.0.2.1",
+					"local_port":         uint16(179),
+					"remote_port":        uint16(50000),
+					"sent_as":            uint32(4200000001),
+					"sent_hold_time":     uint16(90),
+					"sent_bgp_id":        "1.1.1.1",
+					"received_as":        uint32(65002),
+					"received_hold_time": uint16(30),
+					"received_bgp_id":    "2.2.2.2",
+					"information":        "peer up",
+				},
+			}},
+		},
+		{
+			name: "peer_down",
+			msg: bmpMsg(msgTypePeerDown,
+				peerHdr(peerTypeGlobal, 0, "192.0.2.2", 65002, "2.2.2.2", 0),
+				[]byte{3}, bgpMsg(3, []byte{6, 2})),
+			events: []*formatters.EventMsg{{
+				Name:      "bmp1",
+				Timestamp: now.UnixNano(),
+				Tags:      withTags(peerTags, "message_type", "peer-down"),
+				Values: map[string]interface{}{
+					"state":                "down",
+					"reason":               "remote-notification",
+					"notification_code":    uint8(6),
+					"notification_subcode": uint8(2),
+				},
+			}},
+		},
+		{
+			name: "statistics_report",
+			msg: bmpMsg(msgTypeStatisticsReport,
+				peerHdr(peerTypeGlobal, 0, "192.0.2.2", 65002, "2.2.2.2", 0),
+				u32(3),
+				tlvBytes(0, u32(5)),
+				tlvBytes(7, binary.BigEndian.AppendUint64(nil, 1000)),
+				tlvBytes(16, concat(u16(afiIPv6), []byte{safiUnicast}, binary.BigEndian.AppendUint64(nil, 20)))),
+			events: []*formatters.EventMsg{{
+				Name:      "bmp1",
+				Timestamp: now.UnixNano(),
+				Tags:      withTags(peerTags, "message_type", "statistics-report"),
+				Values: map[string]interface{}{
+					"rejected-prefixes":                          uint64(5),
+					"adj-rib-in-routes":                          uint64(1000),
+					"adj-rib-out-pre-policy-routes/ipv6-unicast": uint64(20),
+				},
+			}},
+		},
+		{
+			name: "route_monitoring_adj_rib_out_post",
+			msg: bmpMsg(msgTypeRouteMonitoring,
+				peerHdr(peerTypeGlobal, peerFlagPostPolicy|peerFlagAdjRIBOut, "192.0.2.2", 65002, "2.2.2.2", 0),
+				updateMsg(prefix(24, "10.0.9.0"), concat(attr(attrOrigin, []byte{2}), attr(attrNextHop, net.ParseIP("192.0.2.1").To4())), prefix(24, "10.0.1.0"))),
+			events: []*formatters.EventMsg{
+				{
+					Name:      "bmp1",
+					Timestamp: now.UnixNano(),
+					Tags:      withTags(peerTags, "message_type", "route-monitoring", "rib", "adj-rib-out-post", "afi_safi", "ipv4-unicast", "prefix", "10.0.9.0/24"),
+					Values:    map[string]interface{}{"action": "withdraw"},
+				},
+				{
+					Name:      "bmp1",
+					Timestamp: now.UnixNano(),
+					Tags:      withTags(peerTags, "message_type", "route-monitoring", "rib", "adj-rib-out-post", "afi_safi", "ipv4-unicast", "prefix", "10.0.1.0/24"),
+					Values:    map[string]interface{}{"action": "announce", "origin": "incomplete", "as_path_length": 0, "next_hop": "192.0.2.1"},
+				},
+			},
+		},
+		{
+			name: "route_monitoring_loc_rib",
+			msg: bmpMsg(msgTypeRouteMonitoring,
+				peerHdr(peerTypeLocRIB, 0, "", 0, "1.1.1.1", 0),
+				updateMsg(nil, concat(attr(attrASPath, concat([]byte{2, 1}, u32(65002))), attr(attrNextHop, net.ParseIP("192.0.2.2").To4())), prefix(16, "172.16.0.0"))),
+			events: []*formatters.EventMsg{{
+				Name:      "bmp1",
+				Timestamp: now.UnixNano(),
+				Tags: map[string]string{
+					"source":       "10.1.1.1",
+					"router_name":  "r1",
+					"message_type": "route-monitoring",
+					"peer_type":    "loc-rib",
+					"peer_bgp_id":  "1.1.1.1",
+					"rib":          "loc-rib",
+					"afi_safi":     "ipv4-unicast",
+					"prefix":       "172.16.0.0/16",
+				},
+				Values: map[string]interface{}{"action": "announce", "as_path": "65002", "as_path_length": 1, "next_hop": "192.0.2.2"},
+			}},
+		},
+		{
+			name: "termination",
+			msg:  bmpMsg(msgTypeTermination, tlvBytes(1, u16(0))),
+			events: []*formatters.EventMsg{{
+				Name:      "bmp1",
+				Timestamp: now.UnixNano(),
+				Tags:      map[string]string{"source": "10.1.1.1", "router_name": "r1", "message_type": "termination"},
+				Values:    map[string]interface{}{"reason": "administratively-closed"},
+			}},
+		},
+	}
+	// the tests share the session, the router name is learned from the initiation message.
+	s := &session{name: "bmp1", router: "10.1.1.1"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := readMessage(bytes.NewReader(tt.msg), defaultMaxMessageSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			evs, err := s.events(m, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(evs, tt.events) {
+				for _, ev := range evs {
+					t.Logf("got: %+v", ev)
+				}
+				t.Errorf("unexpected events")
+			}
+		})
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	tests := map[string][]byte{
+		"version":  {2, 0, 0, 0, 6, msgTypeInitiation},
+		"length":   {3, 0, 0, 0, 5, msgTypeInitiation},
+		"max_size": {3, 0, 0, 0x10, 0, msgTypeInitiation},
+		"type":     bmpMsg(42),
+		"peer_hdr": bmpMsg(msgTypePeerUp, make([]byte, perPeerHeaderLen-1)),
+		"short":    bmpMsg(msgTypeInitiation, make([]byte, 10))[:10],
+	}
+	for name, b := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := readMessage(bytes.NewReader(b), 1024); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+type testOutput struct {
+	m      sync.Mutex
+	events []*formatters.EventMsg
+}
+
+func (t *testOutput) Init(context.Context, string, map[string]interface{}, ...outputs.Option) error {
+	return nil
+}
+func (t *testOutput) Write(context.Context, proto.Message, outputs.Meta) {}
+func (t *testOutput) WriteEvent(_ context.Context, ev *formatters.EventMsg) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	t.events = append(t.events, ev)
+}
+func (t *testOutput) Close() error                                    { return nil }
+func (t *testOutput) RegisterMetrics(*prometheus.Registry)            {}
+func (t *testOutput) String() string                                  { return "" }
+func (t *testOutput) SetLogger(*log.Logger)                           {}
+func (t *testOutput) SetName(string)                                  {}
+func (t *testOutput) SetClusterName(string)                           {}
+func (t *testOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+func (t *testOutput) SetEventProcessors(map[string]map[string]interface{}, *log.Logger, map[string]*types.TargetConfig, map[string]map[string]interface{}) error {
+	return nil
+}
+
+func (t *testOutput) messageTypes() []string {
+	t.m.Lock()
+	defer t.m.Unlock()
+	types := make([]string, 0, len(t.events))
+	for _, ev := range t.events {
+		types = append(types, ev.Tags["message_type"])
+	}
+	return types
+}
+
+// TestBMPInput replays a recorded BMP stream over loopback.
+func TestBMPInput(t *testing.T) {
+	out := new(testOutput)
+	b := &bmpInput{
+		Cfg:    &Config{},
+		logger: log.New(bytes.NewBuffer(nil), loggingPrefix, 0),
+		wg:     new(sync.WaitGroup),
+	}
+	err := b.Start(context.Background(), "bmp1", map[string]interface{}{"address": "127.0.0.1:0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	b.SetOutputs(map[string]outputs.Output{"out1": out})
+
+	stream := concat(
+		bmpMsg(msgTypeInitiation, tlvBytes(2, []byte("r1"))),
+		bmpMsg(msgTypePeerUp,
+			peerHdr(peerTypeGlobal, 0, "192.0.2.2", 65002, "2.2.2.2", 0),
+			make([]byte, 12), net.ParseIP("192.0.2.1").To4(), u16(179), u16(50000),
+			openMsg(65001, 90, "1.1.1.1", 65001),
+			openMsg(65002, 90, "2.2.2.2", 65002)),
+		// a message that fails to decode is skipped
+		bmpMsg(msgTypeRouteMonitoring, peerHdr(peerTypeGlobal, 0, "192.0.2.2", 65002, "2.2.2.2", 0), []byte{1, 2, 3}),
+		bmpMsg(msgTypeRouteMonitoring, peerHdr(peerTypeGlobal, 0, "192.0.2.2", 65002, "2.2.2.2", 0), testUpdate()),
+		bmpMsg(msgTypeTermination, tlvBytes(1, u16(0))),
+	)
+	conn, err := net.Dial("tcp", b.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// write the stream in small chunks to exercise the message framing
+	for i := 0; i < len(stream); i += 7 {
+		end := min(i+7, len(stream))
+		if _, err := conn.Write(stream[i:end]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := []string{"initiation", "peer-up",
+		"route-monitoring", "route-monitoring", "route-monitoring", "route-monitoring", "route-monitoring",
+		"termination"}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) && len(out.messageTypes()) < len(expected) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := out.messageTypes(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected message types %v, got %v", expected, got)
+	}
+	// the session is closed by the input after the termination message
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err == nil {
+		t.Errorf("expected the session to be closed")
+	}
+	conn.Close()
+}
@@ -0,0 +1,89 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inputs
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+// rejectedLogInterval is the minimum interval between two logs
+// of the messages rejected by an input.
+const rejectedLogInterval = 10 * time.Second
+
+var rejectedMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "input",
+	Name:      "rejected_messages_total",
+	Help:      "Number of messages rejected by an input because their envelope failed verification or decryption",
+}, []string{"input", "type"})
+
+// Metrics returns the prometheus collectors of the inputs.
+func Metrics() []prometheus.Collector {
+	return []prometheus.Collector{rejectedMsgs}
+}
+
+type printfLogger interface {
+	Printf(format string, v ...any)
+}
+
+// Opener opens the envelopes of the messages received by an input.
+// The rejected messages are counted and logged, at most once every 10s.
+type Opener struct {
+	opener *outputs.Opener
+	name   string
+	typ    string
+	logger printfLogger
+
+	m          sync.Mutex
+	lastLog    time.Time
+	suppressed int
+}
+
+// NewOpener returns an Opener for the envelope config of input name of type typ,
+// or nil if the config does not enable signing nor encryption.
+func NewOpener(cfg *outputs.EnvelopeConfig, name, typ string, logger printfLogger) (*Opener, error) {
+	o, err := outputs.NewOpener(cfg)
+	if err != nil || o == nil {
+		return nil, err
+	}
+	return &Opener{opener: o, name: name, typ: typ, logger: logger}, nil
+}
+
+// Open verifies and decrypts the envelope b.
+func (o *Opener) Open(b []byte) ([]byte, error) {
+	p, err := o.opener.Open(b)
+	if err != nil {
+		o.rejected(err)
+		return nil, err
+	}
+	return p, nil
+}
+
+func (o *Opener) rejected(err error) {
+	rejectedMsgs.WithLabelValues(o.name, o.typ).Inc()
+	o.m.Lock()
+	defer o.m.Unlock()
+	now := time.Now()
+	if now.Sub(o.lastLog) < rejectedLogInterval {
+		o.suppressed++
+		return
+	}
+	if o.suppressed > 0 {
+		o.logger.Printf("rejected message: %v (%d other rejected message(s) not logged)", err, o.suppressed)
+	} else {
+		o.logger.Printf("rejected message: %v", err)
+	}
+	o.lastLog = now
+	o.suppressed = 0
+}
@@ -0,0 +1,56 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package inputs
+
+import (
+	"bytes"
+	"encoding/base64"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+func TestOpenerRejected(t *testing.T) {
+	cfg := &outputs.EnvelopeConfig{Sign: &outputs.EnvelopeKeysConfig{
+		Algorithm: outputs.SignAlgorithmHMACSHA256,
+		Keys:      []*outputs.EnvelopeKey{{ID: "k1", Key: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))}},
+	}}
+	buf := new(bytes.Buffer)
+	o, err := NewOpener(cfg, "in1", "kafka", log.New(buf, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := outputs.NewSealer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.Seal([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.Open(b); err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := o.Open([]byte(`{}`)); err == nil {
+			t.Fatal("expected the message to be rejected")
+		}
+	}
+	if n := testutil.ToFloat64(rejectedMsgs.WithLabelValues("in1", "kafka")); n != 3 {
+		t.Errorf("expected 3 rejected messages, got %v", n)
+	}
+	// the rejected messages are logged at most once per interval.
+	if n := strings.Count(buf.String(), "rejected message"); n != 1 {
+		t.Errorf("expected 1 log line, got %d: %s", n, buf.String())
+	}
+}
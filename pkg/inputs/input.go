@@ -13,6 +13,7 @@ import (
 	"log"
 
 	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/formatters"
 	"github.com/openconfig/gnmic/pkg/outputs"
 )
 
@@ -32,6 +33,7 @@ var InputTypes = []string{
 	"stan",
 	"kafka",
 	"jetstream",
+	"bmp",
 }
 
 var Inputs = map[string]Initializer{}
@@ -68,3 +70,15 @@ func WithEventProcessors(eps map[string]map[string]interface{}, log *log.Logger,
 		return i.SetEventProcessors(eps, log, tcs, acts)
 	}
 }
+
+// StartFlush writes the events flushed by the event processors evps
+// to the outputs outs until ctx is done.
+func StartFlush(ctx context.Context, evps []formatters.EventProcessor, outs []outputs.Output, logger *log.Logger) {
+	formatters.StartFlush(ctx, evps, logger, func(evs []*formatters.EventMsg) {
+		for _, o := range outs {
+			for _, ev := range evs {
+				o.WriteEvent(ctx, ev)
+			}
+		}
+	})
+}
@@ -86,6 +86,7 @@ type JetstreamInput struct {
 	wg      *sync.WaitGroup
 	outputs []outputs.Output
 	evps    []formatters.EventProcessor
+	opener  *inputs.Opener
 }
 
 type subjectFormat string
@@ -98,23 +99,24 @@ const (
 
 // Config //
 type Config struct {
-	Name            string           `mapstructure:"name,omitempty"`
-	Address         string           `mapstructure:"address,omitempty"`
-	Stream          string           `mapstructure:"stream,omitempty"`
-	Subjects        []string         `mapstructure:"subjects,omitempty"`
-	SubjectFormat   subjectFormat    `mapstructure:"subject-format,omitempty" json:"subject-format,omitempty"`
-	DeliverPolicy   deliverPolicy    `mapstructure:"deliver-policy,omitempty"`
-	Username        string           `mapstructure:"username,omitempty"`
-	Password        string           `mapstructure:"password,omitempty"`
-	ConnectTimeWait time.Duration    `mapstructure:"connect-time-wait,omitempty"`
-	TLS             *types.TLSConfig `mapstructure:"tls,omitempty" json:"tls,omitempty"`
-	Format          string           `mapstructure:"format,omitempty"`
-	Debug           bool             `mapstructure:"debug,omitempty"`
-	NumWorkers      int              `mapstructure:"num-workers,omitempty"`
-	BufferSize      int              `mapstructure:"buffer-size,omitempty"`
-	FetchBatchSize  int              `mapstructure:"fetch-batch-size,omitempty"`
-	Outputs         []string         `mapstructure:"outputs,omitempty"`
-	EventProcessors []string         `mapstructure:"event-processors,omitempty"`
+	Name            string                  `mapstructure:"name,omitempty"`
+	Address         string                  `mapstructure:"address,omitempty"`
+	Stream          string                  `mapstructure:"stream,omitempty"`
+	Subjects        []string                `mapstructure:"subjects,omitempty"`
+	SubjectFormat   subjectFormat           `mapstructure:"subject-format,omitempty" json:"subject-format,omitempty"`
+	DeliverPolicy   deliverPolicy           `mapstructure:"deliver-policy,omitempty"`
+	Username        string                  `mapstructure:"username,omitempty"`
+	Password        string                  `mapstructure:"password,omitempty"`
+	ConnectTimeWait time.Duration           `mapstructure:"connect-time-wait,omitempty"`
+	TLS             *types.TLSConfig        `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	Format          string                  `mapstructure:"format,omitempty"`
+	Debug           bool                    `mapstructure:"debug,omitempty"`
+	NumWorkers      int                     `mapstructure:"num-workers,omitempty"`
+	BufferSize      int                     `mapstructure:"buffer-size,omitempty"`
+	FetchBatchSize  int                     `mapstructure:"fetch-batch-size,omitempty"`
+	Outputs         []string                `mapstructure:"outputs,omitempty"`
+	EventProcessors []string                `mapstructure:"event-processors,omitempty"`
+	Envelope        *outputs.EnvelopeConfig `mapstructure:"envelope,omitempty" json:"envelope,omitempty"`
 }
 
 // Init //
@@ -136,8 +138,13 @@ func (n *JetstreamInput) Start(ctx context.Context, name string, cfg map[string]
 	if err != nil {
 		return err
 	}
+	n.opener, err = inputs.NewOpener(n.Cfg.Envelope, n.Cfg.Name, "jetstream", n.logger)
+	if err != nil {
+		return err
+	}
 	n.ctx, n.cfn = context.WithCancel(ctx)
 	n.logger.Printf("input starting with config: %+v", n.Cfg)
+	inputs.StartFlush(n.ctx, n.evps, n.outputs, n.logger)
 	n.wg.Add(n.Cfg.NumWorkers)
 	for i := 0; i < n.Cfg.NumWorkers; i++ {
 		go n.worker(ctx, i)
@@ -216,11 +223,19 @@ func (n *JetstreamInput) msgHandler(msg jetstream.Msg) {
 	if n.Cfg.Debug {
 		n.logger.Printf("received msg, subject=%s, len=%d, data=%s", msg.Subject(), len(msg.Data()), msg.Data())
 	}
+	data := msg.Data()
+	if n.opener != nil {
+		var err error
+		data, err = n.opener.Open(data)
+		if err != nil {
+			return
+		}
+	}
 
 	switch n.Cfg.Format {
 	case "event":
 		evMsgs := make([]*formatters.EventMsg, 1)
-		err := json.Unmarshal(msg.Data(), &evMsgs)
+		err := json.Unmarshal(data, &evMsgs)
 		if err != nil {
 			if n.Cfg.Debug {
 				n.logger.Printf("failed to unmarshal event msg: %v", err)
@@ -241,7 +256,7 @@ func (n *JetstreamInput) msgHandler(msg jetstream.Msg) {
 		}()
 	case "proto":
 		var protoMsg = &gnmi.SubscribeResponse{}
-		err := proto.Unmarshal(msg.Data(), protoMsg)
+		err := proto.Unmarshal(data, protoMsg)
 		if err != nil {
 			if n.Cfg.Debug {
 				n.logger.Printf("failed to unmarshal proto msg: %v", err)
@@ -296,7 +311,11 @@ func (n *JetstreamInput) SetLogger(logger *log.Logger) {
 // SetOutputs //
 func (n *JetstreamInput) SetOutputs(outs map[string]outputs.Output) {
 	if len(n.Cfg.Outputs) == 0 {
-		for _, o := range outs {
+		members := outputs.GroupMembers(outs)
+		for name, o := range outs {
+			if _, ok := members[name]; ok {
+				continue
+			}
 			n.outputs = append(n.outputs, o)
 		}
 		return
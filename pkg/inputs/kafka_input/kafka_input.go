@@ -66,25 +66,27 @@ type KafkaInput struct {
 	wg      *sync.WaitGroup
 	outputs []outputs.Output
 	evps    []formatters.EventProcessor
+	opener  *inputs.Opener
 }
 
 // Config //
 type Config struct {
-	Name              string           `mapstructure:"name,omitempty"`
-	Address           string           `mapstructure:"address,omitempty"`
-	Topics            string           `mapstructure:"topics,omitempty"`
-	SASL              *types.SASL      `mapstructure:"sasl,omitempty"`
-	TLS               *types.TLSConfig `mapstructure:"tls,omitempty"`
-	GroupID           string           `mapstructure:"group-id,omitempty"`
-	SessionTimeout    time.Duration    `mapstructure:"session-timeout,omitempty"`
-	HeartbeatInterval time.Duration    `mapstructure:"heartbeat-interval,omitempty"`
-	RecoveryWaitTime  time.Duration    `mapstructure:"recovery-wait-time,omitempty"`
-	Version           string           `mapstructure:"version,omitempty"`
-	Format            string           `mapstructure:"format,omitempty"`
-	Debug             bool             `mapstructure:"debug,omitempty"`
-	NumWorkers        int              `mapstructure:"num-workers,omitempty"`
-	Outputs           []string         `mapstructure:"outputs,omitempty"`
-	EventProcessors   []string         `mapstructure:"event-processors,omitempty"`
+	Name              string                  `mapstructure:"name,omitempty"`
+	Address           string                  `mapstructure:"address,omitempty"`
+	Topics            string                  `mapstructure:"topics,omitempty"`
+	SASL              *types.SASL             `mapstructure:"sasl,omitempty"`
+	TLS               *types.TLSConfig        `mapstructure:"tls,omitempty"`
+	GroupID           string                  `mapstructure:"group-id,omitempty"`
+	SessionTimeout    time.Duration           `mapstructure:"session-timeout,omitempty"`
+	HeartbeatInterval time.Duration           `mapstructure:"heartbeat-interval,omitempty"`
+	RecoveryWaitTime  time.Duration           `mapstructure:"recovery-wait-time,omitempty"`
+	Version           string                  `mapstructure:"version,omitempty"`
+	Format            string                  `mapstructure:"format,omitempty"`
+	Debug             bool                    `mapstructure:"debug,omitempty"`
+	NumWorkers        int                     `mapstructure:"num-workers,omitempty"`
+	Outputs           []string                `mapstructure:"outputs,omitempty"`
+	EventProcessors   []string                `mapstructure:"event-processors,omitempty"`
+	Envelope          *outputs.EnvelopeConfig `mapstructure:"envelope,omitempty" json:"envelope,omitempty"`
 
 	kafkaVersion sarama.KafkaVersion
 }
@@ -106,10 +108,16 @@ func (k *KafkaInput) Start(ctx context.Context, name string, cfg map[string]inte
 	if err != nil {
 		return err
 	}
+	k.opener, err = inputs.NewOpener(k.Cfg.Envelope, k.Cfg.Name, "kafka", k.logger)
+	if err != nil {
+		return err
+	}
 	config, err := k.createConfig()
 	if err != nil {
 		return err
 	}
+	logger, _ := k.logger.(*log.Logger)
+	inputs.StartFlush(ctx, k.evps, k.outputs, logger)
 	k.wg.Add(k.Cfg.NumWorkers)
 	for i := 0; i < k.Cfg.NumWorkers; i++ {
 		cfg := *config
@@ -166,6 +174,12 @@ START:
 			if k.Cfg.Debug {
 				k.logger.Printf("%s client=%s received msg, topic=%s, partition=%d, key=%q, length=%d, value=%s", workerLogPrefix, config.ClientID, m.Topic, m.Partition, string(m.Key), len(m.Value), string(m.Value))
 			}
+			if k.opener != nil {
+				m.Value, err = k.opener.Open(m.Value)
+				if err != nil {
+					continue
+				}
+			}
 			switch k.Cfg.Format {
 			case "event":
 				m.Value = bytes.TrimSpace(m.Value)
@@ -236,7 +250,11 @@ func (k *KafkaInput) SetLogger(logger *log.Logger) {
 
 func (k *KafkaInput) SetOutputs(outs map[string]outputs.Output) {
 	if len(k.Cfg.Outputs) == 0 {
-		for _, o := range outs {
+		members := outputs.GroupMembers(outs)
+		for name, o := range outs {
+			if _, ok := members[name]; ok {
+				continue
+			}
 			k.outputs = append(k.outputs, o)
 		}
 		return
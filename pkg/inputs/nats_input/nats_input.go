@@ -61,24 +61,26 @@ type NatsInput struct {
 	wg      *sync.WaitGroup
 	outputs []outputs.Output
 	evps    []formatters.EventProcessor
+	opener  *inputs.Opener
 }
 
 // Config //
 type Config struct {
-	Name            string           `mapstructure:"name,omitempty"`
-	Address         string           `mapstructure:"address,omitempty"`
-	Subject         string           `mapstructure:"subject,omitempty"`
-	Queue           string           `mapstructure:"queue,omitempty"`
-	Username        string           `mapstructure:"username,omitempty"`
-	Password        string           `mapstructure:"password,omitempty"`
-	ConnectTimeWait time.Duration    `mapstructure:"connect-time-wait,omitempty"`
-	TLS             *types.TLSConfig `mapstructure:"tls,omitempty" json:"tls,omitempty"`
-	Format          string           `mapstructure:"format,omitempty"`
-	Debug           bool             `mapstructure:"debug,omitempty"`
-	NumWorkers      int              `mapstructure:"num-workers,omitempty"`
-	BufferSize      int              `mapstructure:"buffer-size,omitempty"`
-	Outputs         []string         `mapstructure:"outputs,omitempty"`
-	EventProcessors []string         `mapstructure:"event-processors,omitempty"`
+	Name            string                  `mapstructure:"name,omitempty"`
+	Address         string                  `mapstructure:"address,omitempty"`
+	Subject         string                  `mapstructure:"subject,omitempty"`
+	Queue           string                  `mapstructure:"queue,omitempty"`
+	Username        string                  `mapstructure:"username,omitempty"`
+	Password        string                  `mapstructure:"password,omitempty"`
+	ConnectTimeWait time.Duration           `mapstructure:"connect-time-wait,omitempty"`
+	TLS             *types.TLSConfig        `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	Format          string                  `mapstructure:"format,omitempty"`
+	Debug           bool                    `mapstructure:"debug,omitempty"`
+	NumWorkers      int                     `mapstructure:"num-workers,omitempty"`
+	BufferSize      int                     `mapstructure:"buffer-size,omitempty"`
+	Outputs         []string                `mapstructure:"outputs,omitempty"`
+	EventProcessors []string                `mapstructure:"event-processors,omitempty"`
+	Envelope        *outputs.EnvelopeConfig `mapstructure:"envelope,omitempty" json:"envelope,omitempty"`
 }
 
 // Init //
@@ -99,8 +101,13 @@ func (n *NatsInput) Start(ctx context.Context, name string, cfg map[string]inter
 	if err != nil {
 		return err
 	}
+	n.opener, err = inputs.NewOpener(n.Cfg.Envelope, n.Cfg.Name, "nats", n.logger)
+	if err != nil {
+		return err
+	}
 	n.ctx, n.cfn = context.WithCancel(ctx)
 	n.logger.Printf("input starting with config: %+v", n.Cfg)
+	inputs.StartFlush(n.ctx, n.evps, n.outputs, n.logger)
 	n.wg.Add(n.Cfg.NumWorkers)
 	for i := 0; i < n.Cfg.NumWorkers; i++ {
 		go n.worker(ctx, i)
@@ -152,6 +159,12 @@ START:
 			if n.Cfg.Debug {
 				n.logger.Printf("received msg, subject=%s, queue=%s, len=%d, data=%s", m.Subject, m.Sub.Queue, len(m.Data), string(m.Data))
 			}
+			if n.opener != nil {
+				m.Data, err = n.opener.Open(m.Data)
+				if err != nil {
+					continue
+				}
+			}
 
 			switch n.Cfg.Format {
 			case "event":
@@ -219,7 +232,11 @@ func (n *NatsInput) SetLogger(logger *log.Logger) {
 // SetOutputs //
 func (n *NatsInput) SetOutputs(outs map[string]outputs.Output) {
 	if len(n.Cfg.Outputs) == 0 {
-		for _, o := range outs {
+		members := outputs.GroupMembers(outs)
+		for name, o := range outs {
+			if _, ok := members[name]; ok {
+				continue
+			}
 			n.outputs = append(n.outputs, o)
 		}
 		return
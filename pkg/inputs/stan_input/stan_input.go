@@ -102,6 +102,7 @@ func (s *StanInput) Start(ctx context.Context, name string, cfg map[string]inter
 		return err
 	}
 	s.ctx, s.cfn = context.WithCancel(ctx)
+	inputs.StartFlush(s.ctx, s.evps, s.outputs, s.logger)
 	s.wg.Add(s.Cfg.NumWorkers)
 	for i := 0; i < s.Cfg.NumWorkers; i++ {
 		go s.worker(ctx, i)
@@ -156,7 +157,11 @@ func (s *StanInput) SetLogger(logger *log.Logger) {
 
 func (s *StanInput) SetOutputs(outs map[string]outputs.Output) {
 	if len(s.Cfg.Outputs) == 0 {
-		for _, o := range outs {
+		members := outputs.GroupMembers(outs)
+		for name, o := range outs {
+			if _, ok := members[name]; ok {
+				continue
+			}
 			s.outputs = append(s.outputs, o)
 		}
 		return
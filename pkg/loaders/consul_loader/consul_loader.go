@@ -32,6 +32,7 @@ import (
 	"github.com/openconfig/gnmic/pkg/api/types"
 	"github.com/openconfig/gnmic/pkg/api/utils"
 	gfile "github.com/openconfig/gnmic/pkg/file"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
 	"github.com/openconfig/gnmic/pkg/loaders"
 )
 
@@ -411,7 +412,7 @@ SRV:
 		tc.Name = se.Service.ID
 
 		if configName, ok := sd.Config["name"].(string); ok {
-			nameTemplate, err := template.New("targetName").Option("missingkey=zero").Parse(configName)
+			nameTemplate, err := gtemplate.CreateTemplate("targetName", configName)
 			if err != nil {
 				c.logger.Println("Could not parse nameTemplate")
 			}
@@ -428,12 +429,12 @@ SRV:
 
 		// Create Event tags from Consul via templates
 		if configEventTags, ok := sd.Config["event-tags"].(map[string]interface{}); ok {
-			// Allow to use join function in tags
+			// join keeps its strings.Join signature in tags templates
 			templateFunctions := template.FuncMap{"join": strings.Join}
 
 			sd.targetTagsTemplate = make(map[string]*template.Template)
 			for tagName, tagTemplateString := range configEventTags {
-				tagTemplate, err := template.New(tagName).Funcs(templateFunctions).Option("missingkey=zero").Parse(fmt.Sprintf("%v", tagTemplateString))
+				tagTemplate, err := template.New(tagName).Funcs(gtemplate.NewTemplateEngine().CreateFuncs()).Funcs(templateFunctions).Option("missingkey=zero").Parse(fmt.Sprintf("%v", tagTemplateString))
 				if err != nil {
 					c.logger.Println("Could not parse tagTemplate:", tagName)
 					continue
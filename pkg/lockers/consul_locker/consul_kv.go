@@ -0,0 +1,40 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package consul_locker
+
+import (
+	"context"
+
+	"github.com/hashicorp/consul/api"
+)
+
+func (c *ConsulLocker) Put(ctx context.Context, key string, val []byte) error {
+	wrOpts := new(api.WriteOptions)
+	_, err := c.client.KV().Put(&api.KVPair{Key: key, Value: val}, wrOpts.WithContext(ctx))
+	return err
+}
+
+func (c *ConsulLocker) GetPrefix(ctx context.Context, prefix string) (map[string][]byte, error) {
+	qOpts := &api.QueryOptions{}
+	kvs, _, err := c.client.KV().List(prefix, qOpts.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	rs := make(map[string][]byte, len(kvs))
+	for _, kv := range kvs {
+		rs[kv.Key] = kv.Value
+	}
+	return rs, nil
+}
+
+func (c *ConsulLocker) Delete(ctx context.Context, key string) error {
+	wrOpts := new(api.WriteOptions)
+	_, err := c.client.KV().Delete(key, wrOpts.WithContext(ctx))
+	return err
+}
@@ -0,0 +1,88 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package k8s_locker
+
+import (
+	"context"
+	"strings"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// kvLabelName marks the leases holding a value rather than a lock.
+	kvLabelName = "gnmic-kv"
+	// kvValueName is the annotation holding the value of a key.
+	kvValueName = "value"
+)
+
+// Put stores val as an annotation of a lease without holder,
+// so that it does not require any permission other than the ones of the locks.
+func (k *k8sLocker) Put(ctx context.Context, key string, val []byte) error {
+	nkey := strings.ReplaceAll(key, "/", "-")
+	leases := k.clientset.CoordinationV1().Leases(k.Cfg.Namespace)
+	l, err := leases.Get(ctx, nkey, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		_, err = leases.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nkey,
+				Namespace: k.Cfg.Namespace,
+				Labels: map[string]string{
+					"app":       "gnmic",
+					kvLabelName: "true",
+				},
+				Annotations: map[string]string{
+					origKeyName: key,
+					kvValueName: string(val),
+				},
+			},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if l.Annotations == nil {
+		l.Annotations = make(map[string]string)
+	}
+	l.Annotations[origKeyName] = key
+	l.Annotations[kvValueName] = string(val)
+	_, err = leases.Update(ctx, l, metav1.UpdateOptions{})
+	return err
+}
+
+func (k *k8sLocker) GetPrefix(ctx context.Context, prefix string) (map[string][]byte, error) {
+	ll, err := k.clientset.CoordinationV1().Leases(k.Cfg.Namespace).List(ctx,
+		metav1.ListOptions{
+			LabelSelector: "app=gnmic," + kvLabelName + "=true",
+		})
+	if err != nil {
+		return nil, err
+	}
+	rs := make(map[string][]byte)
+	for _, l := range ll.Items {
+		key, ok := l.Annotations[origKeyName]
+		if !ok || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		rs[key] = []byte(l.Annotations[kvValueName])
+	}
+	return rs, nil
+}
+
+func (k *k8sLocker) Delete(ctx context.Context, key string) error {
+	nkey := strings.ReplaceAll(key, "/", "-")
+	err := k.clientset.CoordinationV1().Leases(k.Cfg.Namespace).Delete(ctx, nkey, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
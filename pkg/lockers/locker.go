@@ -55,6 +55,15 @@ type Locker interface {
 	// List returns all locks that start with prefix string,
 	// indexed by the lock name. Could be target locks or leader lock. It must return a map of matching keys to instance name.
 	List(ctx context.Context, prefix string) (map[string]string, error)
+
+	// This is the key value logic, for the cluster state that must outlive the instances.
+
+	// Put stores val under key. Unlike a lock, it is not released when the instance stops.
+	Put(ctx context.Context, key string, val []byte) error
+	// GetPrefix returns the values stored with Put under the keys starting with prefix, indexed by key.
+	GetPrefix(ctx context.Context, prefix string) (map[string][]byte, error)
+	// Delete removes the value stored under key, it does not fail if the key does not exist.
+	Delete(ctx context.Context, key string) error
 }
 
 type Initializer func() Locker
@@ -0,0 +1,61 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package redis_locker
+
+import (
+	"context"
+	"fmt"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func (k *redisLocker) Put(ctx context.Context, key string, val []byte) error {
+	err := k.client.Set(ctx, key, val, 0).Err()
+	if err != nil {
+		return fmt.Errorf("failed to set key %q: %w", key, err)
+	}
+	return nil
+}
+
+func (k *redisLocker) GetPrefix(ctx context.Context, prefix string) (map[string][]byte, error) {
+	var cursor uint64
+	var err error
+	var cmds map[string]*goredis.StringCmd
+	data := make(map[string][]byte)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		cursor, cmds, err = k.getBatchOfKeys(ctx, fmt.Sprintf("%s*", prefix), 100, cursor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch from redis: %w", err)
+		}
+		for key, cmd := range cmds {
+			b, err := cmd.Bytes()
+			if err != nil {
+				// key removed from redis
+				continue
+			}
+			data[key] = b
+		}
+		if cursor == 0 {
+			return data, nil
+		}
+	}
+}
+
+func (k *redisLocker) Delete(ctx context.Context, key string) error {
+	err := k.client.Del(ctx, key).Err()
+	if err != nil {
+		return fmt.Errorf("failed to delete key %q: %w", key, err)
+	}
+	return nil
+}
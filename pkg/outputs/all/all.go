@@ -12,6 +12,7 @@ import (
 	_ "github.com/openconfig/gnmic/pkg/outputs/asciigraph_output"
 	_ "github.com/openconfig/gnmic/pkg/outputs/file"
 	_ "github.com/openconfig/gnmic/pkg/outputs/gnmi_output"
+	_ "github.com/openconfig/gnmic/pkg/outputs/group_output"
 	_ "github.com/openconfig/gnmic/pkg/outputs/influxdb_output"
 	_ "github.com/openconfig/gnmic/pkg/outputs/kafka_output"
 	_ "github.com/openconfig/gnmic/pkg/outputs/nats_outputs/jetstream"
@@ -169,6 +169,7 @@ func (a *asciigraphOutput) Init(ctx context.Context, name string, cfg map[string
 			return err
 		}
 	}
+	a.evps = formatters.ForOutput(name, a.evps)
 
 	if a.cfg.TargetTemplate == "" {
 		a.targetTpl = outputs.DefaultTargetTemplate
@@ -186,6 +187,12 @@ func (a *asciigraphOutput) Init(ctx context.Context, name string, cfg map[string
 	}
 	//
 	go a.graph(ctx)
+	formatters.StartFlush(ctx, a.evps, a.logger, func(evs []*formatters.EventMsg) {
+		formatters.ObserveEvents(name, evs)
+		for _, ev := range evs {
+			a.WriteEvent(ctx, ev)
+		}
+	})
 	a.logger.Printf("initialized asciigraph output: %s", a.String())
 	return nil
 }
@@ -0,0 +1,513 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package outputs
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	envelopeVersion = 1
+
+	SignAlgorithmHMACSHA256 = "hmac-sha256"
+	SignAlgorithmEd25519    = "ed25519"
+
+	EncryptAlgorithmAESGCM = "aes-gcm"
+	EncryptAlgorithmX25519 = "x25519"
+
+	x25519HKDFInfo = "gnmic-envelope-x25519"
+)
+
+var ErrNotEnvelope = errors.New("message is not an envelope")
+
+// EnvelopeConfig enables wrapping the marshaled messages of an output
+// in a signed and/or encrypted envelope.
+// The same configuration is used by inputs to verify and decrypt them.
+type EnvelopeConfig struct {
+	Sign    *EnvelopeKeysConfig `mapstructure:"sign,omitempty" json:"sign,omitempty"`
+	Encrypt *EnvelopeKeysConfig `mapstructure:"encrypt,omitempty" json:"encrypt,omitempty"`
+}
+
+type EnvelopeKeysConfig struct {
+	Algorithm string `mapstructure:"algorithm,omitempty" json:"algorithm,omitempty"`
+	// KeyID is the ID of the key used to sign or encrypt messages.
+	// It is ignored by inputs, which accept any of the configured keys.
+	KeyID string         `mapstructure:"key-id,omitempty" json:"key-id,omitempty"`
+	Keys  []*EnvelopeKey `mapstructure:"keys,omitempty" json:"keys,omitempty"`
+}
+
+type EnvelopeKey struct {
+	ID string `mapstructure:"id,omitempty" json:"id,omitempty"`
+	// base64 encoded key
+	Key string `mapstructure:"key,omitempty" json:"-"`
+	// file containing the base64 encoded key
+	KeyFile string `mapstructure:"key-file,omitempty" json:"key-file,omitempty"`
+}
+
+// Envelope is the wire format of a sealed message.
+type Envelope struct {
+	Version    int                 `json:"gnmic-envelope"`
+	Encryption *EnvelopeEncryption `json:"encryption,omitempty"`
+	Signature  *EnvelopeSignature  `json:"signature,omitempty"`
+	Payload    []byte              `json:"payload"`
+}
+
+type EnvelopeEncryption struct {
+	Algorithm    string `json:"algorithm"`
+	KeyID        string `json:"key-id"`
+	Nonce        []byte `json:"nonce"`
+	EphemeralKey []byte `json:"ephemeral-key,omitempty"`
+}
+
+type EnvelopeSignature struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key-id"`
+	Value     []byte `json:"value"`
+}
+
+func (k *EnvelopeKey) bytes() ([]byte, error) {
+	s := k.Key
+	if k.KeyFile != "" {
+		b, err := os.ReadFile(k.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		s = string(b)
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("key %q: missing key material", k.ID)
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %v", k.ID, err)
+	}
+	return b, nil
+}
+
+func (c *EnvelopeKeysConfig) keys() (map[string][]byte, error) {
+	if len(c.Keys) == 0 {
+		return nil, errors.New("no keys configured")
+	}
+	keys := make(map[string][]byte, len(c.Keys))
+	for _, k := range c.Keys {
+		if k.ID == "" {
+			return nil, errors.New("missing key id")
+		}
+		if _, ok := keys[k.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", k.ID)
+		}
+		b, err := k.bytes()
+		if err != nil {
+			return nil, err
+		}
+		keys[k.ID] = b
+	}
+	return keys, nil
+}
+
+// activeKey returns the ID and value of the key used for writing.
+// It defaults to the last configured key.
+func (c *EnvelopeKeysConfig) activeKey() (string, []byte, error) {
+	keys, err := c.keys()
+	if err != nil {
+		return "", nil, err
+	}
+	kid := c.KeyID
+	if kid == "" {
+		kid = c.Keys[len(c.Keys)-1].ID
+	}
+	k, ok := keys[kid]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown key-id %q", kid)
+	}
+	return kid, k, nil
+}
+
+// Sealer wraps output messages in signed and/or encrypted envelopes.
+type Sealer struct {
+	signAlg string
+	signKID string
+	hmacKey []byte
+	edKey   ed25519.PrivateKey
+
+	encAlg  string
+	encKID  string
+	aead    cipher.AEAD
+	peerKey *ecdh.PublicKey
+}
+
+// NewSealer returns a Sealer for the given config,
+// or nil if the config does not enable signing nor encryption.
+func NewSealer(cfg *EnvelopeConfig) (*Sealer, error) {
+	if cfg == nil || (cfg.Sign == nil && cfg.Encrypt == nil) {
+		return nil, nil
+	}
+	s := new(Sealer)
+	if cfg.Sign != nil {
+		kid, k, err := cfg.Sign.activeKey()
+		if err != nil {
+			return nil, fmt.Errorf("envelope sign: %v", err)
+		}
+		s.signKID = kid
+		s.signAlg = cfg.Sign.Algorithm
+		switch s.signAlg {
+		case SignAlgorithmHMACSHA256:
+			s.hmacKey = k
+		case SignAlgorithmEd25519:
+			s.edKey, err = ed25519PrivateKey(k)
+			if err != nil {
+				return nil, fmt.Errorf("envelope sign: key %q: %v", kid, err)
+			}
+		default:
+			return nil, fmt.Errorf("envelope sign: unsupported algorithm %q", s.signAlg)
+		}
+	}
+	if cfg.Encrypt != nil {
+		kid, k, err := cfg.Encrypt.activeKey()
+		if err != nil {
+			return nil, fmt.Errorf("envelope encrypt: %v", err)
+		}
+		s.encKID = kid
+		s.encAlg = cfg.Encrypt.Algorithm
+		switch s.encAlg {
+		case EncryptAlgorithmAESGCM:
+			s.aead, err = newAESGCM(k)
+		case EncryptAlgorithmX25519:
+			// the output holds the recipient public key
+			s.peerKey, err = ecdh.X25519().NewPublicKey(k)
+		default:
+			return nil, fmt.Errorf("envelope encrypt: unsupported algorithm %q", s.encAlg)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("envelope encrypt: key %q: %v", kid, err)
+		}
+	}
+	return s, nil
+}
+
+// Seal wraps b in an envelope, encrypting it first then signing the result.
+func (s *Sealer) Seal(b []byte) ([]byte, error) {
+	env := &Envelope{
+		Version: envelopeVersion,
+		Payload: b,
+	}
+	var err error
+	switch s.encAlg {
+	case EncryptAlgorithmAESGCM:
+		env.Encryption = &EnvelopeEncryption{
+			Algorithm: s.encAlg,
+			KeyID:     s.encKID,
+			Nonce:     make([]byte, s.aead.NonceSize()),
+		}
+		if _, err = rand.Read(env.Encryption.Nonce); err != nil {
+			return nil, err
+		}
+		env.Payload = s.aead.Seal(nil, env.Encryption.Nonce, b, []byte(s.encKID))
+	case EncryptAlgorithmX25519:
+		env.Encryption, env.Payload, err = s.sealX25519(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.signAlg != "" {
+		env.Signature = &EnvelopeSignature{
+			Algorithm: s.signAlg,
+			KeyID:     s.signKID,
+		}
+		data := signedData(env)
+		switch s.signAlg {
+		case SignAlgorithmHMACSHA256:
+			env.Signature.Value = hmacSum(s.hmacKey, data)
+		case SignAlgorithmEd25519:
+			env.Signature.Value = ed25519.Sign(s.edKey, data)
+		}
+	}
+	return json.Marshal(env)
+}
+
+// sealX25519 encrypts b for the recipient public key using an ephemeral
+// X25519 key exchange and ChaCha20-Poly1305, in the style of age.
+func (s *Sealer) sealX25519(b []byte) (*EnvelopeEncryption, []byte, error) {
+	eph, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	shared, err := eph.ECDH(s.peerKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	ephPub := eph.PublicKey().Bytes()
+	aead, err := x25519AEAD(shared, ephPub, s.peerKey.Bytes())
+	if err != nil {
+		return nil, nil, err
+	}
+	enc := &EnvelopeEncryption{
+		Algorithm:    s.encAlg,
+		KeyID:        s.encKID,
+		Nonce:        make([]byte, aead.NonceSize()),
+		EphemeralKey: ephPub,
+	}
+	if _, err = rand.Read(enc.Nonce); err != nil {
+		return nil, nil, err
+	}
+	return enc, aead.Seal(nil, enc.Nonce, b, []byte(s.encKID)), nil
+}
+
+// Opener verifies and decrypts envelopes produced by a Sealer.
+// Multiple keys can be configured to allow key rotation.
+type Opener struct {
+	signAlg   string
+	hmacKeys  map[string][]byte
+	edKeys    map[string]ed25519.PublicKey
+	encAlg    string
+	aeads     map[string]cipher.AEAD
+	x25519Key map[string]*ecdh.PrivateKey
+}
+
+// NewOpener returns an Opener for the given config,
+// or nil if the config does not enable signing nor encryption.
+func NewOpener(cfg *EnvelopeConfig) (*Opener, error) {
+	if cfg == nil || (cfg.Sign == nil && cfg.Encrypt == nil) {
+		return nil, nil
+	}
+	o := new(Opener)
+	if cfg.Sign != nil {
+		keys, err := cfg.Sign.keys()
+		if err != nil {
+			return nil, fmt.Errorf("envelope sign: %v", err)
+		}
+		o.signAlg = cfg.Sign.Algorithm
+		switch o.signAlg {
+		case SignAlgorithmHMACSHA256:
+			o.hmacKeys = keys
+		case SignAlgorithmEd25519:
+			o.edKeys = make(map[string]ed25519.PublicKey, len(keys))
+			for kid, k := range keys {
+				o.edKeys[kid], err = ed25519PublicKey(k)
+				if err != nil {
+					return nil, fmt.Errorf("envelope sign: key %q: %v", kid, err)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("envelope sign: unsupported algorithm %q", o.signAlg)
+		}
+	}
+	if cfg.Encrypt != nil {
+		keys, err := cfg.Encrypt.keys()
+		if err != nil {
+			return nil, fmt.Errorf("envelope encrypt: %v", err)
+		}
+		o.encAlg = cfg.Encrypt.Algorithm
+		switch o.encAlg {
+		case EncryptAlgorithmAESGCM:
+			o.aeads = make(map[string]cipher.AEAD, len(keys))
+			for kid, k := range keys {
+				o.aeads[kid], err = newAESGCM(k)
+				if err != nil {
+					return nil, fmt.Errorf("envelope encrypt: key %q: %v", kid, err)
+				}
+			}
+		case EncryptAlgorithmX25519:
+			// the input holds the recipient private key
+			o.x25519Key = make(map[string]*ecdh.PrivateKey, len(keys))
+			for kid, k := range keys {
+				o.x25519Key[kid], err = ecdh.X25519().NewPrivateKey(k)
+				if err != nil {
+					return nil, fmt.Errorf("envelope encrypt: key %q: %v", kid, err)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("envelope encrypt: unsupported algorithm %q", o.encAlg)
+		}
+	}
+	return o, nil
+}
+
+// Open verifies the signature of the envelope b, decrypts it
+// and returns the original payload.
+// Envelopes that are not signed or not encrypted while the Opener
+// expects them to be are rejected.
+func (o *Opener) Open(b []byte) ([]byte, error) {
+	env := new(Envelope)
+	err := json.Unmarshal(b, env)
+	if err != nil || env.Version == 0 {
+		return nil, ErrNotEnvelope
+	}
+	if env.Version != envelopeVersion {
+		return nil, fmt.Errorf("unsupported envelope version %d", env.Version)
+	}
+	if o.signAlg != "" {
+		if err = o.verify(env); err != nil {
+			return nil, err
+		}
+	}
+	if o.encAlg == "" {
+		if env.Encryption != nil {
+			return nil, errors.New("unexpected encrypted envelope")
+		}
+		return env.Payload, nil
+	}
+	return o.decrypt(env)
+}
+
+func (o *Opener) verify(env *Envelope) error {
+	if env.Signature == nil {
+		return errors.New("envelope is not signed")
+	}
+	if env.Signature.Algorithm != o.signAlg {
+		return fmt.Errorf("unexpected signature algorithm %q", env.Signature.Algorithm)
+	}
+	data := signedData(env)
+	switch o.signAlg {
+	case SignAlgorithmHMACSHA256:
+		k, ok := o.hmacKeys[env.Signature.KeyID]
+		if !ok {
+			return fmt.Errorf("unknown signature key-id %q", env.Signature.KeyID)
+		}
+		if !hmac.Equal(hmacSum(k, data), env.Signature.Value) {
+			return errors.New("invalid signature")
+		}
+	case SignAlgorithmEd25519:
+		k, ok := o.edKeys[env.Signature.KeyID]
+		if !ok {
+			return fmt.Errorf("unknown signature key-id %q", env.Signature.KeyID)
+		}
+		if !ed25519.Verify(k, data, env.Signature.Value) {
+			return errors.New("invalid signature")
+		}
+	}
+	return nil
+}
+
+func (o *Opener) decrypt(env *Envelope) ([]byte, error) {
+	enc := env.Encryption
+	if enc == nil {
+		return nil, errors.New("envelope is not encrypted")
+	}
+	if enc.Algorithm != o.encAlg {
+		return nil, fmt.Errorf("unexpected encryption algorithm %q", enc.Algorithm)
+	}
+	var aead cipher.AEAD
+	switch o.encAlg {
+	case EncryptAlgorithmAESGCM:
+		var ok bool
+		aead, ok = o.aeads[enc.KeyID]
+		if !ok {
+			return nil, fmt.Errorf("unknown encryption key-id %q", enc.KeyID)
+		}
+	case EncryptAlgorithmX25519:
+		k, ok := o.x25519Key[enc.KeyID]
+		if !ok {
+			return nil, fmt.Errorf("unknown encryption key-id %q", enc.KeyID)
+		}
+		ephPub, err := ecdh.X25519().NewPublicKey(enc.EphemeralKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ephemeral key: %v", err)
+		}
+		shared, err := k.ECDH(ephPub)
+		if err != nil {
+			return nil, err
+		}
+		aead, err = x25519AEAD(shared, enc.EphemeralKey, k.PublicKey().Bytes())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(enc.Nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid nonce size")
+	}
+	return aead.Open(nil, enc.Nonce, env.Payload, []byte(enc.KeyID))
+}
+
+// signedData returns the envelope bytes covered by the signature:
+// the version, the encryption parameters and the payload.
+func signedData(env *Envelope) []byte {
+	buf := new(bytes.Buffer)
+	buf.WriteString("gnmic-envelope")
+	binary.Write(buf, binary.BigEndian, uint32(env.Version))
+	writeField := func(b []byte) {
+		binary.Write(buf, binary.BigEndian, uint32(len(b)))
+		buf.Write(b)
+	}
+	if env.Encryption != nil {
+		writeField([]byte(env.Encryption.Algorithm))
+		writeField([]byte(env.Encryption.KeyID))
+		writeField(env.Encryption.Nonce)
+		writeField(env.Encryption.EphemeralKey)
+	} else {
+		writeField(nil)
+	}
+	writeField(env.Payload)
+	return buf.Bytes()
+}
+
+func hmacSum(key, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func newAESGCM(k []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func x25519AEAD(shared, ephPub, peerPub []byte) (cipher.AEAD, error) {
+	salt := make([]byte, 0, len(ephPub)+len(peerPub))
+	salt = append(salt, ephPub...)
+	salt = append(salt, peerPub...)
+	k, err := hkdf.Key(sha256.New, shared, salt, x25519HKDFInfo, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.New(k)
+}
+
+// ed25519PrivateKey accepts either a 32 bytes seed or a 64 bytes private key.
+func ed25519PrivateKey(k []byte) (ed25519.PrivateKey, error) {
+	switch len(k) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(k), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(k), nil
+	}
+	return nil, fmt.Errorf("invalid ed25519 private key size %d", len(k))
+}
+
+// ed25519PublicKey accepts either a 32 bytes public key or a 64 bytes private key.
+func ed25519PublicKey(k []byte) (ed25519.PublicKey, error) {
+	switch len(k) {
+	case ed25519.PublicKeySize:
+		return ed25519.PublicKey(k), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(k).Public().(ed25519.PublicKey), nil
+	}
+	return nil, fmt.Errorf("invalid ed25519 public key size %d", len(k))
+}
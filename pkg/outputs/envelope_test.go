@@ -0,0 +1,296 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package outputs
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"text/template"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+func b64(b []byte) string {
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func randKey(t *testing.T, n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b64(b)
+}
+
+func TestEnvelopeSealOpen(t *testing.T) {
+	edPub, edPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xPriv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmacKey := randKey(t, 32)
+	aesKey1 := randKey(t, 32)
+	aesKey2 := randKey(t, 16)
+
+	tests := map[string]struct {
+		out *EnvelopeConfig
+		in  *EnvelopeConfig
+	}{
+		"hmac": {
+			out: &EnvelopeConfig{Sign: &EnvelopeKeysConfig{
+				Algorithm: SignAlgorithmHMACSHA256,
+				Keys:      []*EnvelopeKey{{ID: "k1", Key: hmacKey}},
+			}},
+			in: &EnvelopeConfig{Sign: &EnvelopeKeysConfig{
+				Algorithm: SignAlgorithmHMACSHA256,
+				Keys:      []*EnvelopeKey{{ID: "k1", Key: hmacKey}},
+			}},
+		},
+		"ed25519": {
+			out: &EnvelopeConfig{Sign: &EnvelopeKeysConfig{
+				Algorithm: SignAlgorithmEd25519,
+				Keys:      []*EnvelopeKey{{ID: "k1", Key: b64(edPriv.Seed())}},
+			}},
+			in: &EnvelopeConfig{Sign: &EnvelopeKeysConfig{
+				Algorithm: SignAlgorithmEd25519,
+				Keys:      []*EnvelopeKey{{ID: "k1", Key: b64(edPub)}},
+			}},
+		},
+		"aes_gcm_rotation": {
+			out: &EnvelopeConfig{Encrypt: &EnvelopeKeysConfig{
+				Algorithm: EncryptAlgorithmAESGCM,
+				KeyID:     "k2",
+				Keys: []*EnvelopeKey{
+					{ID: "k1", Key: aesKey1},
+					{ID: "k2", Key: aesKey2},
+				},
+			}},
+			in: &EnvelopeConfig{Encrypt: &EnvelopeKeysConfig{
+				Algorithm: EncryptAlgorithmAESGCM,
+				Keys: []*EnvelopeKey{
+					{ID: "k1", Key: aesKey1},
+					{ID: "k2", Key: aesKey2},
+				},
+			}},
+		},
+		"x25519_ed25519": {
+			out: &EnvelopeConfig{
+				Sign: &EnvelopeKeysConfig{
+					Algorithm: SignAlgorithmEd25519,
+					Keys:      []*EnvelopeKey{{ID: "s1", Key: b64(edPriv)}},
+				},
+				Encrypt: &EnvelopeKeysConfig{
+					Algorithm: EncryptAlgorithmX25519,
+					Keys:      []*EnvelopeKey{{ID: "e1", Key: b64(xPriv.PublicKey().Bytes())}},
+				},
+			},
+			in: &EnvelopeConfig{
+				Sign: &EnvelopeKeysConfig{
+					Algorithm: SignAlgorithmEd25519,
+					Keys:      []*EnvelopeKey{{ID: "s1", Key: b64(edPub)}},
+				},
+				Encrypt: &EnvelopeKeysConfig{
+					Algorithm: EncryptAlgorithmX25519,
+					Keys:      []*EnvelopeKey{{ID: "e1", Key: b64(xPriv.Bytes())}},
+				},
+			},
+		},
+	}
+	payload := []byte(`{"name":"sub1","timestamp":1,"values":{"counter":1}}`)
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := NewSealer(tt.out)
+			if err != nil {
+				t.Fatalf("failed to create sealer: %v", err)
+			}
+			o, err := NewOpener(tt.in)
+			if err != nil {
+				t.Fatalf("failed to create opener: %v", err)
+			}
+			b, err := s.Seal(payload)
+			if err != nil {
+				t.Fatalf("failed to seal: %v", err)
+			}
+			if tt.out.Encrypt != nil && bytes.Contains(b, payload) {
+				t.Errorf("sealed message contains the clear payload")
+			}
+			got, err := o.Open(b)
+			if err != nil {
+				t.Fatalf("failed to open: %v", err)
+			}
+			if !bytes.Equal(got, payload) {
+				t.Errorf("expected %s, got %s", payload, got)
+			}
+			// tamper with the payload
+			env := new(Envelope)
+			if err = json.Unmarshal(b, env); err != nil {
+				t.Fatal(err)
+			}
+			env.Payload[0] ^= 0xff
+			tb, _ := json.Marshal(env)
+			if _, err = o.Open(tb); err == nil {
+				t.Errorf("expected tampered envelope to be rejected")
+			}
+		})
+	}
+}
+
+func TestEnvelopeOpenRejects(t *testing.T) {
+	k1 := randKey(t, 32)
+	k2 := randKey(t, 32)
+	signer := func(kid, key string) *Sealer {
+		s, err := NewSealer(&EnvelopeConfig{Sign: &EnvelopeKeysConfig{
+			Algorithm: SignAlgorithmHMACSHA256,
+			Keys:      []*EnvelopeKey{{ID: kid, Key: key}},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	o, err := NewOpener(&EnvelopeConfig{Sign: &EnvelopeKeysConfig{
+		Algorithm: SignAlgorithmHMACSHA256,
+		Keys:      []*EnvelopeKey{{ID: "k1", Key: k1}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned, _ := json.Marshal(&Envelope{Version: envelopeVersion, Payload: []byte("x")})
+	wrongKey, _ := signer("k1", k2).Seal([]byte("x"))
+	unknownKey, _ := signer("k2", k2).Seal([]byte("x"))
+
+	for name, b := range map[string][]byte{
+		"not_envelope": []byte(`{"name":"sub1"}`),
+		"unsigned":     unsigned,
+		"wrong_key":    wrongKey,
+		"unknown_key":  unknownKey,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := o.Open(b); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestEnvelopeConfigErrors(t *testing.T) {
+	for name, cfg := range map[string]*EnvelopeConfig{
+		"no_keys": {Sign: &EnvelopeKeysConfig{Algorithm: SignAlgorithmHMACSHA256}},
+		"bad_alg": {Sign: &EnvelopeKeysConfig{Algorithm: "md5", Keys: []*EnvelopeKey{{ID: "k1", Key: "a2V5"}}}},
+		"bad_key_id": {Encrypt: &EnvelopeKeysConfig{
+			Algorithm: EncryptAlgorithmAESGCM,
+			KeyID:     "k2",
+			Keys:      []*EnvelopeKey{{ID: "k1", Key: randKey(t, 32)}},
+		}},
+		"bad_aes_key": {Encrypt: &EnvelopeKeysConfig{
+			Algorithm: EncryptAlgorithmAESGCM,
+			Keys:      []*EnvelopeKey{{ID: "k1", Key: randKey(t, 10)}},
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewSealer(cfg); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestMarshalSeal(t *testing.T) {
+	key := randKey(t, 32)
+	cfg := &EnvelopeConfig{Sign: &EnvelopeKeysConfig{
+		Algorithm: SignAlgorithmHMACSHA256,
+		Keys:      []*EnvelopeKey{{ID: "k1", Key: key}},
+	}}
+	s, err := NewSealer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := NewOpener(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mo := &MarshalOptions{
+		MarshalOptions: formatters.MarshalOptions{Format: "event"},
+		MsgTemplate:    template.Must(template.New("msg").Parse(`{{ len . }}`)),
+		Sealer:         s,
+	}
+	rsp := &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: &gnmi.Notification{
+		Timestamp: 1,
+		Update: []*gnmi.Update{
+			{Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "a"}}}, Val: &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 1}}},
+			{Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "b"}}}, Val: &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 2}}},
+		},
+	}}}
+	for _, split := range []bool{false, true} {
+		bb, err := Marshal(rsp, map[string]string{"subscription-name": "sub1"}, mo, split)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bb) == 0 {
+			t.Fatalf("split=%v: no messages", split)
+		}
+		for _, b := range bb {
+			// the message template is executed before sealing.
+			got, err := o.Open(b)
+			if err != nil {
+				t.Fatalf("split=%v: failed to open %s: %v", split, b, err)
+			}
+			if _, err := strconv.Atoi(string(got)); err != nil {
+				t.Errorf("split=%v: unexpected payload %s", split, got)
+			}
+		}
+	}
+}
+
+func TestMarshalMessagesTemplateError(t *testing.T) {
+	mo := &MarshalOptions{
+		MarshalOptions: formatters.MarshalOptions{Format: "event"},
+		// fails on the event with a value "b" only.
+		MsgTemplate: template.Must(template.New("msg").Parse(`{{ with index .values "/b" }}{{ len . }}{{ else }}ok{{ end }}`)),
+	}
+	rsp := &gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: &gnmi.Notification{
+		Timestamp: 1,
+		Update: []*gnmi.Update{
+			{Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "a"}}}, Val: &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 1}}},
+			{Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "b"}}}, Val: &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 2}}},
+		},
+	}}}
+	ms, err := MarshalMessages(rsp, map[string]string{"subscription-name": "sub1"}, mo, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(ms))
+	}
+	var ok, failed int
+	for _, m := range ms {
+		switch {
+		case m.Err == nil && string(m.Data) == "ok":
+			ok++
+		case m.Err != nil && m.Reason == ReasonTemplateError:
+			failed++
+		default:
+			t.Errorf("unexpected message: data=%s, err=%v, reason=%q", m.Data, m.Err, m.Reason)
+		}
+	}
+	if ok != 1 || failed != 1 {
+		t.Errorf("expected 1 ok and 1 failed message, got %d and %d", ok, failed)
+	}
+}
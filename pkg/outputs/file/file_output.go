@@ -57,36 +57,36 @@ type File struct {
 	cfg    *Config
 	file   file
 	logger *log.Logger
-	mo     *formatters.MarshalOptions
+	mo     *outputs.MarshalOptions
 	sem    *semaphore.Weighted
 	evps   []formatters.EventProcessor
 
 	targetTpl *template.Template
-	msgTpl    *template.Template
 
 	reg *prometheus.Registry
 }
 
 // Config //
 type Config struct {
-	Name               string          `mapstructure:"name,omitempty"`
-	FileName           string          `mapstructure:"filename,omitempty"`
-	FileType           string          `mapstructure:"file-type,omitempty"`
-	Format             string          `mapstructure:"format,omitempty"`
-	Multiline          bool            `mapstructure:"multiline,omitempty"`
-	Indent             string          `mapstructure:"indent,omitempty"`
-	Separator          string          `mapstructure:"separator,omitempty"`
-	SplitEvents        bool            `mapstructure:"split-events,omitempty"`
-	OverrideTimestamps bool            `mapstructure:"override-timestamps,omitempty"`
-	AddTarget          string          `mapstructure:"add-target,omitempty"`
-	TargetTemplate     string          `mapstructure:"target-template,omitempty"`
-	EventProcessors    []string        `mapstructure:"event-processors,omitempty"`
-	MsgTemplate        string          `mapstructure:"msg-template,omitempty"`
-	ConcurrencyLimit   int             `mapstructure:"concurrency-limit,omitempty"`
-	EnableMetrics      bool            `mapstructure:"enable-metrics,omitempty"`
-	Debug              bool            `mapstructure:"debug,omitempty"`
-	CalculateLatency   bool            `mapstructure:"calculate-latency,omitempty"`
-	Rotation           *rotationConfig `mapstructure:"rotation,omitempty"`
+	Name               string                  `mapstructure:"name,omitempty"`
+	FileName           string                  `mapstructure:"filename,omitempty"`
+	FileType           string                  `mapstructure:"file-type,omitempty"`
+	Format             string                  `mapstructure:"format,omitempty"`
+	Multiline          bool                    `mapstructure:"multiline,omitempty"`
+	Indent             string                  `mapstructure:"indent,omitempty"`
+	Separator          string                  `mapstructure:"separator,omitempty"`
+	SplitEvents        bool                    `mapstructure:"split-events,omitempty"`
+	OverrideTimestamps bool                    `mapstructure:"override-timestamps,omitempty"`
+	AddTarget          string                  `mapstructure:"add-target,omitempty"`
+	TargetTemplate     string                  `mapstructure:"target-template,omitempty"`
+	EventProcessors    []string                `mapstructure:"event-processors,omitempty"`
+	MsgTemplate        string                  `mapstructure:"msg-template,omitempty"`
+	ConcurrencyLimit   int                     `mapstructure:"concurrency-limit,omitempty"`
+	EnableMetrics      bool                    `mapstructure:"enable-metrics,omitempty"`
+	Debug              bool                    `mapstructure:"debug,omitempty"`
+	CalculateLatency   bool                    `mapstructure:"calculate-latency,omitempty"`
+	Rotation           *rotationConfig         `mapstructure:"rotation,omitempty"`
+	Envelope           *outputs.EnvelopeConfig `mapstructure:"envelope,omitempty" json:"envelope,omitempty"`
 }
 
 type file interface {
@@ -144,6 +144,7 @@ func (f *File) Init(ctx context.Context, name string, cfg map[string]interface{}
 			return err
 		}
 	}
+	f.evps = formatters.ForOutput(name, f.evps)
 
 	err = f.registerMetrics()
 	if err != nil {
@@ -199,12 +200,18 @@ func (f *File) Init(ctx context.Context, name string, cfg map[string]interface{}
 
 	f.sem = semaphore.NewWeighted(int64(f.cfg.ConcurrencyLimit))
 
-	f.mo = &formatters.MarshalOptions{
-		Multiline:        f.cfg.Multiline,
-		Indent:           f.cfg.Indent,
-		Format:           f.cfg.Format,
-		OverrideTS:       f.cfg.OverrideTimestamps,
-		CalculateLatency: f.cfg.CalculateLatency,
+	f.mo = &outputs.MarshalOptions{
+		MarshalOptions: formatters.MarshalOptions{
+			Multiline:        f.cfg.Multiline,
+			Indent:           f.cfg.Indent,
+			Format:           f.cfg.Format,
+			OverrideTS:       f.cfg.OverrideTimestamps,
+			CalculateLatency: f.cfg.CalculateLatency,
+		},
+	}
+	f.mo.Sealer, err = outputs.NewSealer(f.cfg.Envelope)
+	if err != nil {
+		return err
 	}
 	if f.cfg.TargetTemplate == "" {
 		f.targetTpl = outputs.DefaultTargetTemplate
@@ -217,14 +224,15 @@ func (f *File) Init(ctx context.Context, name string, cfg map[string]interface{}
 	}
 
 	if f.cfg.MsgTemplate != "" {
-		f.msgTpl, err = gtemplate.CreateTemplate(fmt.Sprintf("%s-msg-template", name), f.cfg.MsgTemplate)
+		f.mo.MsgTemplate, err = gtemplate.CreateTemplate(fmt.Sprintf("%s-msg-template", name), f.cfg.MsgTemplate)
 		if err != nil {
 			return err
 		}
-		f.msgTpl = f.msgTpl.Funcs(outputs.TemplateFuncs)
+		f.mo.MsgTemplate = f.mo.MsgTemplate.Funcs(outputs.TemplateFuncs)
 	}
 
 	f.logger.Printf("initialized file output: %s", f.String())
+	formatters.StartFlush(ctx, f.evps, f.logger, f.writeEvents)
 	go func() {
 		<-ctx.Done()
 		f.Close()
@@ -252,7 +260,7 @@ func (f *File) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta)
 	if err != nil {
 		f.logger.Printf("failed to add target to the response: %v", err)
 	}
-	bb, err := outputs.Marshal(rsp, meta, f.mo, f.cfg.SplitEvents, f.evps...)
+	ms, err := outputs.MarshalMessages(rsp, meta, f.mo, f.cfg.SplitEvents, f.evps...)
 	if err != nil {
 		if f.cfg.Debug {
 			f.logger.Printf("failed marshaling proto msg: %v", err)
@@ -260,22 +268,18 @@ func (f *File) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta)
 		numberOfFailWriteMsgs.WithLabelValues(f.cfg.Name, f.file.Name(), "marshal_error").Inc()
 		return
 	}
-	if len(bb) == 0 {
+	if len(ms) == 0 {
 		return
 	}
-	for _, b := range bb {
-		if f.msgTpl != nil {
-			b, err = outputs.ExecTemplate(b, f.msgTpl)
-			if err != nil {
-				if f.cfg.Debug {
-					log.Printf("failed to execute template: %v", err)
-				}
-				numberOfFailWriteMsgs.WithLabelValues(f.cfg.Name, f.file.Name(), "template_error").Inc()
-				continue
+	for _, m := range ms {
+		if m.Err != nil {
+			if f.cfg.Debug {
+				f.logger.Printf("%v", m.Err)
 			}
+			numberOfFailWriteMsgs.WithLabelValues(f.cfg.Name, f.file.Name(), m.Reason).Inc()
+			continue
 		}
-
-		n, err := f.file.Write(append(b, []byte(f.cfg.Separator)...))
+		n, err := f.file.Write(append(m.Data, []byte(f.cfg.Separator)...))
 		if err != nil {
 			if f.cfg.Debug {
 				f.logger.Printf("failed to write to file '%s': %v", f.file.Name(), err)
@@ -298,6 +302,12 @@ func (f *File) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
 	for _, proc := range f.evps {
 		evs = proc.Apply(evs...)
 	}
+	f.writeEvents(evs)
+}
+
+// writeEvents writes the processed events evs.
+func (f *File) writeEvents(evs []*formatters.EventMsg) {
+	formatters.ObserveEvents(f.cfg.Name, evs)
 	toWrite := []byte{}
 	if f.cfg.SplitEvents {
 		for _, pev := range evs {
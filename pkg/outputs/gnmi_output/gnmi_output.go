@@ -55,6 +55,7 @@ type gNMIOutput struct {
 	cfg       *config
 	logger    *log.Logger
 	targetTpl *template.Template
+	evps      []formatters.EventProcessor
 	//
 	srv     *server
 	grpcSrv *grpc.Server
@@ -71,6 +72,9 @@ type config struct {
 	TLS              *types.TLSConfig `mapstructure:"tls,omitempty"`
 	EnableMetrics    bool             `mapstructure:"enable-metrics,omitempty"`
 	Debug            bool             `mapstructure:"debug,omitempty"`
+	// event processors applied before caching
+	EventProcessors []string                 `mapstructure:"event-processors,omitempty"`
+	EventMapping    *formatters.EventMapping `mapstructure:"event-mapping,omitempty"`
 }
 
 func (g *gNMIOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
@@ -86,11 +90,18 @@ func (g *gNMIOutput) Init(ctx context.Context, name string, cfg map[string]inter
 			return err
 		}
 	}
+	if len(g.evps) > 0 {
+		g.evps = formatters.ForOutput(name, g.evps)
+	}
 
 	err = g.setDefaults()
 	if err != nil {
 		return err
 	}
+	err = g.cfg.EventMapping.Init()
+	if err != nil {
+		return err
+	}
 	g.logger.SetPrefix(fmt.Sprintf(loggingPrefix, name))
 	if g.targetTpl == nil {
 		g.targetTpl, err = gtemplate.CreateTemplate(fmt.Sprintf("%s-target-template", name), g.cfg.TargetTemplate)
@@ -102,6 +113,12 @@ func (g *gNMIOutput) Init(ctx context.Context, name string, cfg map[string]inter
 	if err != nil {
 		return err
 	}
+	formatters.StartFlush(ctx, g.evps, g.logger, func(evs []*formatters.EventMsg) {
+		formatters.ObserveEvents(name, evs)
+		for _, m := range outputs.FlushedMsgs(evs) {
+			g.updateEvents(m.GetMeta()["source"], m.GetEvents())
+		}
+	})
 	g.logger.Printf("started gnmi output: %v", g)
 	return nil
 }
@@ -128,6 +145,10 @@ func (g *gNMIOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.
 			if g.cfg.Debug {
 				g.logger.Printf("updating target %q local cache", target)
 			}
+			if len(g.evps) > 0 {
+				g.updateProcessed(target, rsp, meta)
+				return
+			}
 			err = g.c.GnmiUpdate(rsp.Update)
 			if err != nil {
 				g.logger.Printf("failed to update gNMI cache: %v", err)
@@ -138,6 +159,47 @@ func (g *gNMIOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.
 	}
 }
 
+// updateProcessed applies the event processors to the response
+// and writes the resulting events to the cache as gNMI notifications.
+func (g *gNMIOutput) updateProcessed(target string, rsp *gnmi.SubscribeResponse_Update, meta outputs.Meta) {
+	evs, err := formatters.ResponseToEventMsgs(meta["subscription-name"], &gnmi.SubscribeResponse{Response: rsp}, meta, g.evps...)
+	if err != nil {
+		g.logger.Printf("failed to convert response to events: %v", err)
+		return
+	}
+	g.updateEvents(target, evs)
+}
+
+// updateEvents writes the events evs to the cache as gNMI notifications,
+// using target for the notifications that do not carry one.
+func (g *gNMIOutput) updateEvents(target string, evs []*formatters.EventMsg) {
+	for _, ev := range evs {
+		n, err := formatters.EventToNotification(ev, g.cfg.EventMapping)
+		if err != nil {
+			g.logger.Printf("failed to convert event to notification: %v", err)
+			continue
+		}
+		if n == nil {
+			continue
+		}
+		if n.GetPrefix().GetTarget() == "" {
+			if target == "" {
+				g.logger.Printf("event %q missing target", ev.Name)
+				continue
+			}
+			n.Prefix.Target = target
+		}
+		if !g.c.HasTarget(n.GetPrefix().GetTarget()) {
+			g.c.Add(n.GetPrefix().GetTarget())
+			g.logger.Printf("target %q added to the local cache", n.GetPrefix().GetTarget())
+		}
+		err = g.c.GnmiUpdate(n)
+		if err != nil {
+			g.logger.Printf("failed to update gNMI cache: %v", err)
+		}
+	}
+}
+
 func (g *gNMIOutput) WriteEvent(context.Context, *formatters.EventMsg) {}
 
 func (g *gNMIOutput) Close() error {
@@ -176,8 +238,19 @@ func (g *gNMIOutput) SetLogger(logger *log.Logger) {
 	}
 }
 
-func (g *gNMIOutput) SetEventProcessors(map[string]map[string]interface{}, *log.Logger, map[string]*types.TargetConfig, map[string]map[string]interface{}) error {
-	return nil
+func (g *gNMIOutput) SetEventProcessors(ps map[string]map[string]interface{},
+	logger *log.Logger,
+	tcs map[string]*types.TargetConfig,
+	acts map[string]map[string]interface{}) error {
+	var err error
+	g.evps, err = formatters.MakeEventProcessors(
+		logger,
+		g.cfg.EventProcessors,
+		ps,
+		tcs,
+		acts,
+	)
+	return err
 }
 
 func (g *gNMIOutput) SetName(string) {}
@@ -0,0 +1,108 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package outputs
+
+import (
+	"sync"
+)
+
+// HealthReporter is implemented by the outputs able to report their health.
+// It is used by the output groups to eject the failing members.
+type HealthReporter interface {
+	// Health returns the reason the output is unhealthy, nil if it is healthy.
+	Health() error
+}
+
+// Group is implemented by the outputs writing to other outputs.
+type Group interface {
+	// Members returns the names of the group member outputs.
+	Members() []string
+	// SetOutputLookup sets the function used to get a member output by name.
+	SetOutputLookup(func(name string) (Output, bool))
+}
+
+// WithOutputLookup sets the function used by the output groups
+// to get their member outputs.
+// It is ignored by the outputs that do not implement Group.
+func WithOutputLookup(fn func(name string) (Output, bool)) Option {
+	return func(o Output) error {
+		if g, ok := o.(Group); ok {
+			g.SetOutputLookup(fn)
+		}
+		return nil
+	}
+}
+
+// GroupMembers returns the names of the outputs that are members
+// of one of the output groups in outs.
+// Those outputs receive their messages through their group.
+func GroupMembers(outs map[string]Output) map[string]struct{} {
+	members := make(map[string]struct{})
+	for _, o := range outs {
+		if g, ok := o.(Group); ok {
+			for _, m := range g.Members() {
+				members[m] = struct{}{}
+			}
+		}
+	}
+	return members
+}
+
+// HealthState tracks the write errors of an output.
+// Outputs embedding it implement HealthReporter.
+// By default, a single failed write marks the output unhealthy and a single
+// successful one marks it healthy again, SetHealthThresholds debounces it.
+type HealthState struct {
+	m         sync.RWMutex
+	err       error
+	failures  int
+	successes int
+	// number of consecutive failed writes marking the output unhealthy.
+	failureThreshold int
+	// number of consecutive successful writes marking the output healthy.
+	successThreshold int
+}
+
+// SetHealthThresholds sets the number of consecutive failed writes needed
+// to mark the output unhealthy and the number of consecutive successful
+// writes needed to mark it healthy again.
+// Values lower than 1 are set to 1.
+func (h *HealthState) SetHealthThresholds(failures, successes int) {
+	h.m.Lock()
+	defer h.m.Unlock()
+	h.failureThreshold = max(failures, 1)
+	h.successThreshold = max(successes, 1)
+}
+
+// SetHealth records the result of a write attempt,
+// a nil error counts as a successful write.
+func (h *HealthState) SetHealth(err error) {
+	h.m.Lock()
+	defer h.m.Unlock()
+	if err != nil {
+		h.successes = 0
+		h.failures++
+		if h.failures >= max(h.failureThreshold, 1) {
+			h.err = err
+		}
+		return
+	}
+	h.failures = 0
+	h.successes++
+	if h.successes >= max(h.successThreshold, 1) {
+		h.err = nil
+	}
+}
+
+// Health returns the error that marked the output unhealthy.
+func (h *HealthState) Health() error {
+	h.m.RLock()
+	defer h.m.RUnlock()
+	return h.err
+}
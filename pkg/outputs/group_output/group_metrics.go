@@ -0,0 +1,77 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package group_output
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var registerMetricsOnce sync.Once
+
+var groupMemberUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "gnmic",
+	Subsystem: "output_group",
+	Name:      "member_up",
+	Help:      "Whether an output group member is in service (1) or ejected (0)",
+}, []string{"group", "member"})
+
+var groupEjections = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "output_group",
+	Name:      "ejections_total",
+	Help:      "Number of times an output group member was ejected",
+}, []string{"group", "member"})
+
+var groupMsgs = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "output_group",
+	Name:      "msgs_total",
+	Help:      "Number of msgs written by an output group to a member",
+}, []string{"group", "member"})
+
+var groupProbes = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "gnmic",
+	Subsystem: "output_group",
+	Name:      "probes_total",
+	Help:      "Number of probe msgs written by an output group to an ejected member",
+}, []string{"group", "member"})
+
+func (g *groupOutput) initMetrics() {
+	for _, m := range g.cfg.Members {
+		groupMemberUp.WithLabelValues(g.name, m).Set(1)
+		groupEjections.WithLabelValues(g.name, m).Add(0)
+		groupMsgs.WithLabelValues(g.name, m).Add(0)
+		groupProbes.WithLabelValues(g.name, m).Add(0)
+	}
+}
+
+func (g *groupOutput) registerMetrics() error {
+	if g.reg == nil {
+		return nil
+	}
+	var err error
+	registerMetricsOnce.Do(func() {
+		if err = g.reg.Register(groupMemberUp); err != nil {
+			return
+		}
+		if err = g.reg.Register(groupEjections); err != nil {
+			return
+		}
+		if err = g.reg.Register(groupMsgs); err != nil {
+			return
+		}
+		if err = g.reg.Register(groupProbes); err != nil {
+			return
+		}
+	})
+	g.initMetrics()
+	return err
+}
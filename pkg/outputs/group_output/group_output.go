@@ -0,0 +1,352 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package group_output
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/api/utils"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	loggingPrefix = "[group_output:%s] "
+
+	policyFailover     = "failover"
+	policyRoundRobin   = "round-robin"
+	policyHashByTarget = "hash-by-target"
+	policyBroadcast    = "broadcast"
+
+	defaultEjectionTime  = 30 * time.Second
+	defaultProbeInterval = 10 * time.Second
+	defaultHashKey       = "source"
+)
+
+func init() {
+	outputs.Register("group", func() outputs.Output {
+		return &groupOutput{
+			cfg:    &config{},
+			logger: log.New(io.Discard, loggingPrefix, utils.DefaultLoggingFlags),
+			now:    time.Now,
+		}
+	})
+}
+
+// groupOutput writes the messages it receives to one or more
+// of its member outputs, depending on the group policy.
+type groupOutput struct {
+	name   string
+	cfg    *config
+	logger *log.Logger
+	lookup func(name string) (outputs.Output, bool)
+
+	m       sync.Mutex
+	members []*member
+	next    uint64
+
+	reg *prometheus.Registry
+	now func() time.Time
+}
+
+type config struct {
+	Policy        string        `mapstructure:"policy,omitempty" json:"policy,omitempty"`
+	Members       []string      `mapstructure:"members,omitempty" json:"members,omitempty"`
+	EjectionTime  time.Duration `mapstructure:"ejection-time,omitempty" json:"ejection-time,omitempty"`
+	ProbeInterval time.Duration `mapstructure:"probe-interval,omitempty" json:"probe-interval,omitempty"`
+	HashKey       string        `mapstructure:"hash-key,omitempty" json:"hash-key,omitempty"`
+	EnableMetrics bool          `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+	Debug         bool          `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+}
+
+// member is the state of a group member output.
+// A member is ejected when it reports an error, it is reinstated
+// once it reports healthy after the ejection time.
+// An ejected member receives a copy of a message every probe interval
+// so that its health gets re-evaluated.
+type member struct {
+	name         string
+	ejectedUntil time.Time
+	lastProbe    time.Time
+}
+
+// selected is a member output chosen to write a message.
+type selected struct {
+	name string
+	out  outputs.Output
+}
+
+func (g *groupOutput) Init(ctx context.Context, name string, cfg map[string]interface{}, opts ...outputs.Option) error {
+	err := outputs.DecodeConfig(cfg, g.cfg)
+	if err != nil {
+		return err
+	}
+	g.name = name
+	g.logger.SetPrefix(fmt.Sprintf(loggingPrefix, name))
+	for _, opt := range opts {
+		if err := opt(g); err != nil {
+			return err
+		}
+	}
+	err = g.setDefaults()
+	if err != nil {
+		return err
+	}
+	g.members = make([]*member, 0, len(g.cfg.Members))
+	for _, m := range g.cfg.Members {
+		g.members = append(g.members, &member{name: m})
+	}
+	err = g.registerMetrics()
+	if err != nil {
+		return err
+	}
+	g.logger.Printf("initialized output group: %s", g.String())
+	return nil
+}
+
+func (g *groupOutput) setDefaults() error {
+	switch g.cfg.Policy {
+	case "":
+		g.cfg.Policy = policyFailover
+	case policyFailover, policyRoundRobin, policyHashByTarget, policyBroadcast:
+	default:
+		return fmt.Errorf("unknown output group policy %q", g.cfg.Policy)
+	}
+	if len(g.cfg.Members) == 0 {
+		return errors.New("missing output group members")
+	}
+	seen := make(map[string]struct{}, len(g.cfg.Members))
+	for _, m := range g.cfg.Members {
+		if m == g.name {
+			return fmt.Errorf("output group %q cannot be its own member", g.name)
+		}
+		if _, ok := seen[m]; ok {
+			return fmt.Errorf("duplicate output group member %q", m)
+		}
+		seen[m] = struct{}{}
+	}
+	if g.cfg.EjectionTime <= 0 {
+		g.cfg.EjectionTime = defaultEjectionTime
+	}
+	if g.cfg.ProbeInterval <= 0 {
+		g.cfg.ProbeInterval = defaultProbeInterval
+	}
+	if g.cfg.HashKey == "" {
+		g.cfg.HashKey = defaultHashKey
+	}
+	return nil
+}
+
+func (g *groupOutput) Write(ctx context.Context, rsp proto.Message, meta outputs.Meta) {
+	if rsp == nil {
+		return
+	}
+	for _, s := range g.pick(meta[g.cfg.HashKey]) {
+		s.out.Write(ctx, rsp, meta)
+	}
+}
+
+func (g *groupOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {
+	if ev == nil {
+		return
+	}
+	for _, s := range g.pick(ev.Tags[g.cfg.HashKey]) {
+		s.out.WriteEvent(ctx, ev)
+	}
+}
+
+// pick updates the members state and returns the members
+// a message with the given hash key is written to.
+func (g *groupOutput) pick(key string) []*selected {
+	if g.lookup == nil {
+		return nil
+	}
+	now := g.now()
+	g.m.Lock()
+	defer g.m.Unlock()
+
+	available := make([]*selected, 0, len(g.members))
+	active := make([]*selected, 0, len(g.members))
+	var probes []*selected
+	for _, m := range g.members {
+		o, ok := g.lookup(m.name)
+		if !ok {
+			continue
+		}
+		s := &selected{name: m.name, out: o}
+		available = append(available, s)
+		err := health(o)
+		if m.ejectedUntil.IsZero() {
+			if err == nil {
+				active = append(active, s)
+				continue
+			}
+			g.eject(m, err, now)
+			continue
+		}
+		if now.Before(m.ejectedUntil) {
+			continue
+		}
+		if err == nil {
+			g.reinstate(m)
+			active = append(active, s)
+			continue
+		}
+		if now.Sub(m.lastProbe) >= g.cfg.ProbeInterval {
+			m.lastProbe = now
+			probes = append(probes, s)
+		}
+	}
+	if len(active) == 0 {
+		// all members are failing, keep writing to them
+		// rather than dropping the messages.
+		active = available
+		probes = nil
+	}
+	if len(active) == 0 {
+		return nil
+	}
+	var sel []*selected
+	switch g.cfg.Policy {
+	case policyFailover:
+		sel = active[:1]
+	case policyRoundRobin:
+		n := atomic.AddUint64(&g.next, 1)
+		sel = []*selected{active[(n-1)%uint64(len(active))]}
+	case policyHashByTarget:
+		sel = []*selected{rendezvous(active, key)}
+	case policyBroadcast:
+		sel = active
+	}
+	if g.cfg.EnableMetrics {
+		for _, s := range sel {
+			groupMsgs.WithLabelValues(g.name, s.name).Inc()
+		}
+		for _, s := range probes {
+			groupProbes.WithLabelValues(g.name, s.name).Inc()
+		}
+	}
+	res := make([]*selected, 0, len(sel)+len(probes))
+	res = append(res, sel...)
+	return append(res, probes...)
+}
+
+func (g *groupOutput) eject(m *member, err error, now time.Time) {
+	g.logger.Printf("ejecting member %q for %s: %v", m.name, g.cfg.EjectionTime, err)
+	m.ejectedUntil = now.Add(g.cfg.EjectionTime)
+	m.lastProbe = time.Time{}
+	if g.cfg.EnableMetrics {
+		groupEjections.WithLabelValues(g.name, m.name).Inc()
+		groupMemberUp.WithLabelValues(g.name, m.name).Set(0)
+	}
+}
+
+func (g *groupOutput) reinstate(m *member) {
+	g.logger.Printf("reinstating member %q", m.name)
+	m.ejectedUntil = time.Time{}
+	if g.cfg.EnableMetrics {
+		groupMemberUp.WithLabelValues(g.name, m.name).Set(1)
+	}
+}
+
+// rendezvous returns the member with the highest hash of the key
+// and the member name, so that only the keys mapped to an ejected
+// member move to another one.
+func rendezvous(ss []*selected, key string) *selected {
+	var best *selected
+	var top uint64
+	for _, s := range ss {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(s.name))
+		if v := h.Sum64(); best == nil || v > top {
+			best, top = s, v
+		}
+	}
+	return best
+}
+
+func health(o outputs.Output) error {
+	if hr, ok := o.(outputs.HealthReporter); ok {
+		return hr.Health()
+	}
+	return nil
+}
+
+// Health reports the group unhealthy when none of its members is healthy.
+func (g *groupOutput) Health() error {
+	if g.lookup == nil {
+		return nil
+	}
+	for _, m := range g.cfg.Members {
+		o, ok := g.lookup(m)
+		if ok && health(o) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("output group %q: no healthy member", g.name)
+}
+
+func (g *groupOutput) Members() []string {
+	return g.cfg.Members
+}
+
+func (g *groupOutput) SetOutputLookup(fn func(name string) (outputs.Output, bool)) {
+	g.lookup = fn
+}
+
+func (g *groupOutput) Close() error { return nil }
+
+func (g *groupOutput) RegisterMetrics(reg *prometheus.Registry) {
+	if !g.cfg.EnableMetrics {
+		return
+	}
+	if reg == nil {
+		g.logger.Printf("ERR: output metrics enabled but main registry is not initialized, enable main metrics under `api-server`")
+		return
+	}
+	g.reg = reg
+}
+
+func (g *groupOutput) String() string {
+	b, err := json.Marshal(g.cfg)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (g *groupOutput) SetLogger(logger *log.Logger) {
+	if logger != nil && g.logger != nil {
+		g.logger.SetOutput(logger.Writer())
+		g.logger.SetFlags(logger.Flags())
+	}
+}
+
+func (g *groupOutput) SetEventProcessors(map[string]map[string]interface{}, *log.Logger, map[string]*types.TargetConfig, map[string]map[string]interface{}) error {
+	return nil
+}
+
+func (g *groupOutput) SetName(name string)                             {}
+func (g *groupOutput) SetClusterName(name string)                      {}
+func (g *groupOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
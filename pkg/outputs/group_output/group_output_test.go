@@ -0,0 +1,265 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package group_output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+// testOutput counts the messages it receives per source.
+type testOutput struct {
+	outputs.HealthState
+	writes map[string]int
+}
+
+func newTestOutput() *testOutput {
+	return &testOutput{writes: make(map[string]int)}
+}
+
+func (t *testOutput) Init(context.Context, string, map[string]interface{}, ...outputs.Option) error {
+	return nil
+}
+func (t *testOutput) Write(_ context.Context, _ proto.Message, m outputs.Meta) {
+	t.writes[m["source"]]++
+}
+func (t *testOutput) WriteEvent(_ context.Context, ev *formatters.EventMsg) {
+	t.writes[ev.Tags["source"]]++
+}
+func (t *testOutput) Close() error                                    { return nil }
+func (t *testOutput) RegisterMetrics(*prometheus.Registry)            {}
+func (t *testOutput) String() string                                  { return "" }
+func (t *testOutput) SetLogger(*log.Logger)                           {}
+func (t *testOutput) SetName(string)                                  {}
+func (t *testOutput) SetClusterName(string)                           {}
+func (t *testOutput) SetTargetsConfig(map[string]*types.TargetConfig) {}
+func (t *testOutput) SetEventProcessors(map[string]map[string]interface{}, *log.Logger, map[string]*types.TargetConfig, map[string]map[string]interface{}) error {
+	return nil
+}
+
+func (t *testOutput) total() int {
+	n := 0
+	for _, c := range t.writes {
+		n += c
+	}
+	return n
+}
+
+type testClock struct{ t time.Time }
+
+func (c *testClock) now() time.Time { return c.t }
+
+func newTestGroup(t *testing.T, cfg map[string]interface{}, members ...string) (*groupOutput, map[string]*testOutput, *testClock) {
+	t.Helper()
+	outs := make(map[string]*testOutput)
+	for _, m := range members {
+		outs[m] = newTestOutput()
+	}
+	clock := &testClock{t: time.Unix(0, 0)}
+	g := outputs.Outputs["group"]().(*groupOutput)
+	g.now = clock.now
+	cfg["members"] = members
+	err := g.Init(context.TODO(), "group1", cfg,
+		outputs.WithOutputLookup(func(name string) (outputs.Output, bool) {
+			o, ok := outs[name]
+			return o, ok
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g, outs, clock
+}
+
+func write(g *groupOutput, n int, sources ...string) {
+	rsp := &gnmi.SubscribeResponse{}
+	for i := 0; i < n; i++ {
+		for _, s := range sources {
+			g.Write(context.TODO(), rsp, outputs.Meta{"source": s})
+		}
+	}
+}
+
+func TestGroupFailover(t *testing.T) {
+	g, outs, clock := newTestGroup(t, map[string]interface{}{
+		"policy":         "failover",
+		"ejection-time":  "30s",
+		"probe-interval": "10s",
+	}, "primary", "secondary")
+
+	write(g, 2, "r1")
+	if outs["primary"].total() != 2 || outs["secondary"].total() != 0 {
+		t.Fatalf("expected writes to the primary only, got %v, %v", outs["primary"].writes, outs["secondary"].writes)
+	}
+	// primary fails: it is ejected
+	outs["primary"].SetHealth(errors.New("broker down"))
+	write(g, 2, "r1")
+	if outs["primary"].total() != 2 || outs["secondary"].total() != 2 {
+		t.Fatalf("expected writes to the secondary, got %v, %v", outs["primary"].writes, outs["secondary"].writes)
+	}
+	// ejection time elapsed: the primary is probed once per probe interval
+	clock.t = clock.t.Add(31 * time.Second)
+	write(g, 2, "r1")
+	if outs["primary"].total() != 3 || outs["secondary"].total() != 4 {
+		t.Fatalf("expected a single probe to the primary, got %v, %v", outs["primary"].writes, outs["secondary"].writes)
+	}
+	// primary recovers: it is reinstated
+	outs["primary"].SetHealth(nil)
+	write(g, 2, "r1")
+	if outs["primary"].total() != 5 || outs["secondary"].total() != 4 {
+		t.Fatalf("expected the primary to be reinstated, got %v, %v", outs["primary"].writes, outs["secondary"].writes)
+	}
+}
+
+func TestGroupAllMembersFailing(t *testing.T) {
+	g, outs, _ := newTestGroup(t, map[string]interface{}{}, "o1", "o2")
+	outs["o1"].SetHealth(errors.New("down"))
+	outs["o2"].SetHealth(errors.New("down"))
+	write(g, 1, "r1")
+	if outs["o1"].total() != 1 {
+		t.Fatalf("expected writes to the first member, got %v", outs["o1"].writes)
+	}
+	if g.Health() == nil {
+		t.Errorf("expected the group to be unhealthy")
+	}
+}
+
+func TestGroupRoundRobin(t *testing.T) {
+	g, outs, _ := newTestGroup(t, map[string]interface{}{
+		"policy": "round-robin",
+	}, "o1", "o2", "o3")
+	write(g, 3, "r1")
+	for n, o := range outs {
+		if o.total() != 1 {
+			t.Errorf("expected 1 write to %s, got %d", n, o.total())
+		}
+	}
+}
+
+func TestGroupBroadcast(t *testing.T) {
+	g, outs, _ := newTestGroup(t, map[string]interface{}{
+		"policy": "broadcast",
+	}, "o1", "o2")
+	write(g, 2, "r1")
+	outs["o2"].SetHealth(errors.New("down"))
+	write(g, 1, "r1")
+	if outs["o1"].total() != 3 || outs["o2"].total() != 2 {
+		t.Errorf("unexpected writes: %v, %v", outs["o1"].writes, outs["o2"].writes)
+	}
+}
+
+func TestGroupHashByTarget(t *testing.T) {
+	g, outs, _ := newTestGroup(t, map[string]interface{}{
+		"policy": "hash-by-target",
+	}, "o1", "o2", "o3")
+	sources := make([]string, 0, 30)
+	for i := 0; i < 30; i++ {
+		sources = append(sources, fmt.Sprintf("r%d", i))
+	}
+	write(g, 2, sources...)
+	owner := make(map[string]string)
+	for n, o := range outs {
+		if len(o.writes) == 0 {
+			t.Errorf("expected %s to receive messages", n)
+		}
+		for s, c := range o.writes {
+			if c != 2 {
+				t.Errorf("expected all the messages of %s to be written to %s", s, n)
+			}
+			owner[s] = n
+		}
+	}
+	// ejecting a member only moves its own sources
+	outs["o1"].SetHealth(errors.New("down"))
+	before := make(map[string]map[string]int)
+	for n, o := range outs {
+		before[n] = make(map[string]int)
+		for s, c := range o.writes {
+			before[n][s] = c
+		}
+	}
+	write(g, 1, sources...)
+	for _, s := range sources {
+		if owner[s] == "o1" {
+			continue
+		}
+		if outs[owner[s]].writes[s] != before[owner[s]][s]+1 {
+			t.Errorf("source %s moved from %s", s, owner[s])
+		}
+	}
+	if !reflect.DeepEqual(outs["o1"].writes, before["o1"]) {
+		t.Errorf("expected no writes to the ejected member")
+	}
+}
+
+func TestGroupInitErrors(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"unknown_policy": {
+			"policy":  "random",
+			"members": []string{"o1"},
+		},
+		"no_members": {},
+		"self_member": {
+			"members": []string{"group1"},
+		},
+		"duplicate_member": {
+			"members": []string{"o1", "o1"},
+		},
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := outputs.Outputs["group"]()
+			if err := g.Init(context.TODO(), "group1", cfg); err == nil {
+				t.Errorf("expected an init error")
+			}
+		})
+	}
+}
+
+func TestHealthThresholds(t *testing.T) {
+	var h outputs.HealthState
+	h.SetHealthThresholds(3, 2)
+	down := errors.New("down")
+	for i := 0; i < 2; i++ {
+		h.SetHealth(down)
+	}
+	h.SetHealth(nil)
+	for i := 0; i < 2; i++ {
+		h.SetHealth(down)
+	}
+	if h.Health() != nil {
+		t.Fatalf("unhealthy before 3 consecutive failures")
+	}
+	h.SetHealth(down)
+	if h.Health() == nil {
+		t.Fatalf("healthy after 3 consecutive failures")
+	}
+	h.SetHealth(nil)
+	h.SetHealth(down)
+	h.SetHealth(nil)
+	if h.Health() == nil {
+		t.Fatalf("healthy before 2 consecutive successes")
+	}
+	h.SetHealth(nil)
+	if h.Health() != nil {
+		t.Fatalf("unhealthy after 2 consecutive successes: %v", h.Health())
+	}
+}
@@ -59,7 +59,10 @@ func init() {
 }
 
 type influxDBOutput struct {
+	outputs.HealthState
+
 	Cfg       *Config
+	name      string
 	client    influxdb2.Client
 	logger    *log.Logger
 	cancelFn  context.CancelFunc
@@ -138,6 +141,7 @@ func (i *influxDBOutput) Init(ctx context.Context, name string, cfg map[string]i
 	if err != nil {
 		return err
 	}
+	i.name = name
 	i.logger.SetPrefix(fmt.Sprintf(loggingPrefix, name))
 
 	for _, opt := range opts {
@@ -145,6 +149,7 @@ func (i *influxDBOutput) Init(ctx context.Context, name string, cfg map[string]i
 			return err
 		}
 	}
+	i.evps = formatters.ForOutput(name, i.evps)
 	i.setDefaults()
 
 	if i.Cfg.CacheConfig != nil {
@@ -187,6 +192,16 @@ CRCLIENT:
 	for k := 0; k < numWorkers; k++ {
 		go i.worker(ctx, k)
 	}
+	formatters.StartFlush(ctx, i.evps, i.logger, func(evs []*formatters.EventMsg) {
+		formatters.ObserveEvents(i.name, evs)
+		for _, ev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case i.eventChan <- ev:
+			}
+		}
+	})
 	go func() {
 		<-ctx.Done()
 		i.Close()
@@ -261,6 +276,7 @@ func (i *influxDBOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg
 		for _, proc := range i.evps {
 			evs = proc.Apply(evs...)
 		}
+		formatters.ObserveEvents(i.name, evs)
 		for _, pev := range evs {
 			i.eventChan <- pev
 		}
@@ -292,6 +308,7 @@ func (i *influxDBOutput) healthCheck(ctx context.Context) {
 
 func (i *influxDBOutput) health(ctx context.Context) error {
 	res, err := i.client.Health(ctx)
+	i.SetHealth(err)
 	if err != nil {
 		i.logger.Printf("failed health check: %v", err)
 		if i.wasUP {
@@ -390,6 +407,7 @@ START:
 			goto START
 		case err := <-writer.Errors():
 			i.logger.Printf("worker-%d write error: %v", idx, err)
+			i.SetHealth(err)
 		}
 	}
 }
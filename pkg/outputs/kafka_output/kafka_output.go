@@ -45,6 +45,9 @@ const (
 	loggingPrefix           = "[kafka_output:%s] "
 	defaultCompressionCodec = sarama.CompressionNone
 
+	defaultHealthFailureThreshold = 3
+	defaultHealthSuccessThreshold = 3
+
 	requiredAcksNoResponse   = "no-response"
 	requiredAcksWaitForLocal = "wait-for-local"
 	requiredAcksWaitForAll   = "wait-for-all"
@@ -62,48 +65,51 @@ func init() {
 
 // kafkaOutput //
 type kafkaOutput struct {
+	outputs.HealthState
+
 	cfg      *config
 	logger   sarama.StdLogger
-	mo       *formatters.MarshalOptions
+	mo       *outputs.MarshalOptions
 	cancelFn context.CancelFunc
 	msgChan  chan *outputs.ProtoMsg
 	wg       *sync.WaitGroup
 	evps     []formatters.EventProcessor
 
 	targetTpl *template.Template
-	msgTpl    *template.Template
 
 	reg *prometheus.Registry
 }
 
 // config //
 type config struct {
-	Address            string           `mapstructure:"address,omitempty"`
-	Topic              string           `mapstructure:"topic,omitempty"`
-	TopicPrefix        string           `mapstructure:"topic-prefix,omitempty"`
-	Name               string           `mapstructure:"name,omitempty"`
-	SASL               *types.SASL      `mapstructure:"sasl,omitempty"`
-	TLS                *types.TLSConfig `mapstructure:"tls,omitempty"`
-	MaxRetry           int              `mapstructure:"max-retry,omitempty"`
-	Timeout            time.Duration    `mapstructure:"timeout,omitempty"`
-	RecoveryWaitTime   time.Duration    `mapstructure:"recovery-wait-time,omitempty"`
-	FlushFrequency     time.Duration    `mapstructure:"flush-frequency,omitempty"`
-	SyncProducer       bool             `mapstructure:"sync-producer,omitempty"`
-	RequiredAcks       string           `mapstructure:"required-acks,omitempty"`
-	Format             string           `mapstructure:"format,omitempty"`
-	InsertKey          bool             `mapstructure:"insert-key,omitempty"`
-	AddTarget          string           `mapstructure:"add-target,omitempty"`
-	TargetTemplate     string           `mapstructure:"target-template,omitempty"`
-	MsgTemplate        string           `mapstructure:"msg-template,omitempty"`
-	SplitEvents        bool             `mapstructure:"split-events,omitempty"`
-	NumWorkers         int              `mapstructure:"num-workers,omitempty"`
-	CompressionCodec   string           `mapstructure:"compression-codec,omitempty"`
-	KafkaVersion       string           `mapstructure:"kafka-version,omitempty"`
-	Debug              bool             `mapstructure:"debug,omitempty"`
-	BufferSize         int              `mapstructure:"buffer-size,omitempty"`
-	OverrideTimestamps bool             `mapstructure:"override-timestamps,omitempty"`
-	EnableMetrics      bool             `mapstructure:"enable-metrics,omitempty"`
-	EventProcessors    []string         `mapstructure:"event-processors,omitempty"`
+	Address            string                  `mapstructure:"address,omitempty"`
+	Topic              string                  `mapstructure:"topic,omitempty"`
+	TopicPrefix        string                  `mapstructure:"topic-prefix,omitempty"`
+	Name               string                  `mapstructure:"name,omitempty"`
+	SASL               *types.SASL             `mapstructure:"sasl,omitempty"`
+	TLS                *types.TLSConfig        `mapstructure:"tls,omitempty"`
+	MaxRetry           int                     `mapstructure:"max-retry,omitempty"`
+	Timeout            time.Duration           `mapstructure:"timeout,omitempty"`
+	RecoveryWaitTime   time.Duration           `mapstructure:"recovery-wait-time,omitempty"`
+	FlushFrequency     time.Duration           `mapstructure:"flush-frequency,omitempty"`
+	SyncProducer       bool                    `mapstructure:"sync-producer,omitempty"`
+	RequiredAcks       string                  `mapstructure:"required-acks,omitempty"`
+	Format             string                  `mapstructure:"format,omitempty"`
+	InsertKey          bool                    `mapstructure:"insert-key,omitempty"`
+	AddTarget          string                  `mapstructure:"add-target,omitempty"`
+	TargetTemplate     string                  `mapstructure:"target-template,omitempty"`
+	MsgTemplate        string                  `mapstructure:"msg-template,omitempty"`
+	SplitEvents        bool                    `mapstructure:"split-events,omitempty"`
+	NumWorkers         int                     `mapstructure:"num-workers,omitempty"`
+	CompressionCodec   string                  `mapstructure:"compression-codec,omitempty"`
+	KafkaVersion       string                  `mapstructure:"kafka-version,omitempty"`
+	Debug              bool                    `mapstructure:"debug,omitempty"`
+	BufferSize         int                     `mapstructure:"buffer-size,omitempty"`
+	OverrideTimestamps bool                    `mapstructure:"override-timestamps,omitempty"`
+	EnableMetrics      bool                    `mapstructure:"enable-metrics,omitempty"`
+	EventProcessors    []string                `mapstructure:"event-processors,omitempty"`
+	Health             *healthConfig           `mapstructure:"health,omitempty"`
+	Envelope           *outputs.EnvelopeConfig `mapstructure:"envelope,omitempty" json:"envelope,omitempty"`
 }
 
 func (k *kafkaOutput) String() string {
@@ -153,18 +159,22 @@ func (k *kafkaOutput) Init(ctx context.Context, name string, cfg map[string]inte
 			return err
 		}
 	}
+	k.evps = formatters.ForOutput(name, k.evps)
 	err = k.setDefaults()
 	if err != nil {
 		return err
 	}
+	k.SetHealthThresholds(k.cfg.Health.FailureThreshold, k.cfg.Health.SuccessThreshold)
 	err = k.registerMetrics()
 	if err != nil {
 		return err
 	}
 	k.msgChan = make(chan *outputs.ProtoMsg, uint(k.cfg.BufferSize))
-	k.mo = &formatters.MarshalOptions{
-		Format:     k.cfg.Format,
-		OverrideTS: k.cfg.OverrideTimestamps,
+	k.mo = &outputs.MarshalOptions{
+		MarshalOptions: formatters.MarshalOptions{
+			Format:     k.cfg.Format,
+			OverrideTS: k.cfg.OverrideTimestamps,
+		},
 	}
 
 	if k.cfg.TargetTemplate == "" {
@@ -178,11 +188,16 @@ func (k *kafkaOutput) Init(ctx context.Context, name string, cfg map[string]inte
 	}
 
 	if k.cfg.MsgTemplate != "" {
-		k.msgTpl, err = gtemplate.CreateTemplate("msg-template", k.cfg.MsgTemplate)
+		k.mo.MsgTemplate, err = gtemplate.CreateTemplate("msg-template", k.cfg.MsgTemplate)
 		if err != nil {
 			return err
 		}
-		k.msgTpl = k.msgTpl.Funcs(outputs.TemplateFuncs)
+		k.mo.MsgTemplate = k.mo.MsgTemplate.Funcs(outputs.TemplateFuncs)
+	}
+
+	k.mo.Sealer, err = outputs.NewSealer(k.cfg.Envelope)
+	if err != nil {
+		return err
 	}
 
 	config, err := k.createConfig()
@@ -196,6 +211,17 @@ func (k *kafkaOutput) Init(ctx context.Context, name string, cfg map[string]inte
 		cfg.ClientID = fmt.Sprintf("%s-%d", config.ClientID, i)
 		go k.worker(ctx, i, &cfg)
 	}
+	logger, _ := k.logger.(*log.Logger)
+	formatters.StartFlush(ctx, k.evps, logger, func(evs []*formatters.EventMsg) {
+		formatters.ObserveEvents(k.cfg.Name, evs)
+		for _, m := range outputs.FlushedMsgs(evs) {
+			select {
+			case <-ctx.Done():
+				return
+			case k.msgChan <- m:
+			}
+		}
+	})
 	go func() {
 		<-ctx.Done()
 		k.Close()
@@ -203,6 +229,13 @@ func (k *kafkaOutput) Init(ctx context.Context, name string, cfg map[string]inte
 	return nil
 }
 
+// healthConfig sets the number of consecutive failed and successful
+// messages marking the output unhealthy and healthy again.
+type healthConfig struct {
+	FailureThreshold int `mapstructure:"failure-threshold,omitempty"`
+	SuccessThreshold int `mapstructure:"success-threshold,omitempty"`
+}
+
 func (k *kafkaOutput) setDefaults() error {
 	if k.cfg.Format == "" {
 		k.cfg.Format = defaultFormat
@@ -231,6 +264,15 @@ func (k *kafkaOutput) setDefaults() error {
 	if k.cfg.Name == "" {
 		k.cfg.Name = "gnmic-" + uuid.New().String()
 	}
+	if k.cfg.Health == nil {
+		k.cfg.Health = new(healthConfig)
+	}
+	if k.cfg.Health.FailureThreshold <= 0 {
+		k.cfg.Health.FailureThreshold = defaultHealthFailureThreshold
+	}
+	if k.cfg.Health.SuccessThreshold <= 0 {
+		k.cfg.Health.SuccessThreshold = defaultHealthSuccessThreshold
+	}
 	if k.cfg.SASL == nil {
 		return nil
 	}
@@ -301,6 +343,19 @@ func (k *kafkaOutput) RegisterMetrics(reg *prometheus.Registry) {
 	k.reg = reg
 }
 
+// marshal marshals the message m, or the events it carries
+// if they were flushed by the output processors.
+func (k *kafkaOutput) marshal(m *outputs.ProtoMsg) ([]*outputs.Message, error) {
+	if evs := m.GetEvents(); evs != nil {
+		return outputs.MarshalEvents(evs, k.mo, k.cfg.SplitEvents)
+	}
+	pmsg, err := outputs.AddSubscriptionTarget(m.GetMsg(), m.GetMeta(), k.cfg.AddTarget, k.targetTpl)
+	if err != nil {
+		k.logger.Printf("failed to add target to the response: %v", err)
+	}
+	return outputs.MarshalMessages(pmsg, m.GetMeta(), k.mo, k.cfg.SplitEvents, k.evps...)
+}
+
 func (k *kafkaOutput) worker(ctx context.Context, idx int, config *sarama.Config) {
 	if k.cfg.SyncProducer {
 		k.syncProducerWorker(ctx, idx, config)
@@ -319,6 +374,7 @@ CRPROD:
 	producer, err = sarama.NewAsyncProducer(strings.Split(k.cfg.Address, ","), config)
 	if err != nil {
 		k.logger.Printf("%s failed to create kafka producer: %v", workerLogPrefix, err)
+		k.SetHealth(err)
 		time.Sleep(k.cfg.RecoveryWaitTime)
 		goto CRPROD
 	}
@@ -334,6 +390,7 @@ CRPROD:
 				if !ok {
 					return
 				}
+				k.SetHealth(nil)
 				if k.cfg.EnableMetrics {
 					start, ok := msg.Metadata.(time.Time)
 					if ok {
@@ -346,6 +403,7 @@ CRPROD:
 				if !ok {
 					return
 				}
+				k.SetHealth(err.Err)
 				if k.cfg.Debug {
 					k.logger.Printf("%s failed to send a kafka msg to topic '%s': %v", workerLogPrefix, err.Msg.Topic, err.Err)
 				}
@@ -362,12 +420,7 @@ CRPROD:
 			k.logger.Printf("%s shutting down", workerLogPrefix)
 			return
 		case m := <-k.msgChan:
-			pmsg := m.GetMsg()
-			pmsg, err = outputs.AddSubscriptionTarget(pmsg, m.GetMeta(), k.cfg.AddTarget, k.targetTpl)
-			if err != nil {
-				k.logger.Printf("failed to add target to the response: %v", err)
-			}
-			bb, err := outputs.Marshal(pmsg, m.GetMeta(), k.mo, k.cfg.SplitEvents, k.evps...)
+			ms, err := k.marshal(m)
 			if err != nil {
 				if k.cfg.Debug {
 					k.logger.Printf("%s failed marshaling proto msg: %v", workerLogPrefix, err)
@@ -377,21 +430,18 @@ CRPROD:
 				}
 				continue
 			}
-			if len(bb) == 0 {
+			if len(ms) == 0 {
 				continue
 			}
-			for _, b := range bb {
-				if k.msgTpl != nil {
-					b, err = outputs.ExecTemplate(b, k.msgTpl)
-					if err != nil {
-						if k.cfg.Debug {
-							log.Printf("failed to execute template: %v", err)
-						}
-						kafkaNumberOfFailSendMsgs.WithLabelValues(k.cfg.Name, config.ClientID, "template_error").Inc()
-						continue
+			for _, om := range ms {
+				if om.Err != nil {
+					if k.cfg.Debug {
+						k.logger.Printf("%s %v", workerLogPrefix, om.Err)
 					}
+					kafkaNumberOfFailSendMsgs.WithLabelValues(k.cfg.Name, config.ClientID, om.Reason).Inc()
+					continue
 				}
-
+				b := om.Data
 				topic := k.selectTopic(m.GetMeta())
 				msg := &sarama.ProducerMessage{
 					Topic: topic,
@@ -421,6 +471,7 @@ CRPROD:
 	producer, err = sarama.NewSyncProducer(strings.Split(k.cfg.Address, ","), config)
 	if err != nil {
 		k.logger.Printf("%s failed to create kafka producer: %v", workerLogPrefix, err)
+		k.SetHealth(err)
 		time.Sleep(k.cfg.RecoveryWaitTime)
 		goto CRPROD
 	}
@@ -432,12 +483,7 @@ CRPROD:
 			k.logger.Printf("%s shutting down", workerLogPrefix)
 			return
 		case m := <-k.msgChan:
-			pmsg := m.GetMsg()
-			pmsg, err = outputs.AddSubscriptionTarget(pmsg, m.GetMeta(), k.cfg.AddTarget, k.targetTpl)
-			if err != nil {
-				k.logger.Printf("failed to add target to the response: %v", err)
-			}
-			bb, err := outputs.Marshal(pmsg, m.GetMeta(), k.mo, k.cfg.SplitEvents, k.evps...)
+			ms, err := k.marshal(m)
 			if err != nil {
 				if k.cfg.Debug {
 					k.logger.Printf("%s failed marshaling proto msg: %v", workerLogPrefix, err)
@@ -447,21 +493,18 @@ CRPROD:
 				}
 				continue
 			}
-			if len(bb) == 0 {
+			if len(ms) == 0 {
 				continue
 			}
-			for _, b := range bb {
-				if k.msgTpl != nil {
-					b, err = outputs.ExecTemplate(b, k.msgTpl)
-					if err != nil {
-						if k.cfg.Debug {
-							log.Printf("failed to execute template: %v", err)
-						}
-						kafkaNumberOfFailSendMsgs.WithLabelValues(k.cfg.Name, config.ClientID, "template_error").Inc()
-						continue
+			for _, om := range ms {
+				if om.Err != nil {
+					if k.cfg.Debug {
+						k.logger.Printf("%s %v", workerLogPrefix, om.Err)
 					}
+					kafkaNumberOfFailSendMsgs.WithLabelValues(k.cfg.Name, config.ClientID, om.Reason).Inc()
+					continue
 				}
-
+				b := om.Data
 				topic := k.selectTopic(m.GetMeta())
 				msg := &sarama.ProducerMessage{
 					Topic: topic,
@@ -475,6 +518,7 @@ CRPROD:
 					start = time.Now()
 				}
 				_, _, err = producer.SendMessage(msg)
+				k.SetHealth(err)
 				if err != nil {
 					if k.cfg.Debug {
 						k.logger.Printf("%s failed to send a kafka msg to topic '%s': %v", workerLogPrefix, topic, err)
@@ -34,6 +34,7 @@ import (
 	"github.com/openconfig/gnmic/pkg/formatters"
 	"github.com/openconfig/gnmic/pkg/gtemplate"
 	"github.com/openconfig/gnmic/pkg/outputs"
+	"github.com/openconfig/gnmic/pkg/outputs/nats_outputs"
 )
 
 const (
@@ -68,38 +69,55 @@ const (
 )
 
 type config struct {
-	Name               string              `mapstructure:"name,omitempty" json:"name,omitempty"`
-	Address            string              `mapstructure:"address,omitempty" json:"address,omitempty"`
-	Stream             string              `mapstructure:"stream,omitempty" json:"stream,omitempty"`
-	Subject            string              `mapstructure:"subject,omitempty" json:"subject,omitempty"`
-	SubjectFormat      subjectFormat       `mapstructure:"subject-format,omitempty" json:"subject-format,omitempty"`
-	CreateStream       *createStreamConfig `mapstructure:"create-stream,omitempty" json:"create-stream,omitempty"`
-	Username           string              `mapstructure:"username,omitempty" json:"username,omitempty"`
-	Password           string              `mapstructure:"password,omitempty" json:"password,omitempty"`
-	ConnectTimeWait    time.Duration       `mapstructure:"connect-time-wait,omitempty" json:"connect-time-wait,omitempty"`
-	TLS                *types.TLSConfig    `mapstructure:"tls,omitempty" json:"tls,omitempty"`
-	Format             string              `mapstructure:"format,omitempty" json:"format,omitempty"`
-	SplitEvents        bool                `mapstructure:"split-events,omitempty"`
-	AddTarget          string              `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
-	TargetTemplate     string              `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
-	MsgTemplate        string              `mapstructure:"msg-template,omitempty" json:"msg-template,omitempty"`
-	OverrideTimestamps bool                `mapstructure:"override-timestamps,omitempty" json:"override-timestamps,omitempty"`
-	NumWorkers         int                 `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
-	WriteTimeout       time.Duration       `mapstructure:"write-timeout,omitempty" json:"write-timeout,omitempty"`
-	Debug              bool                `mapstructure:"debug,omitempty" json:"debug,omitempty"`
-	BufferSize         uint                `mapstructure:"buffer-size,omitempty"`
-	EnableMetrics      bool                `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
-	EventProcessors    []string            `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	Name               string                  `mapstructure:"name,omitempty" json:"name,omitempty"`
+	Address            string                  `mapstructure:"address,omitempty" json:"address,omitempty"`
+	Stream             string                  `mapstructure:"stream,omitempty" json:"stream,omitempty"`
+	Subject            string                  `mapstructure:"subject,omitempty" json:"subject,omitempty"`
+	SubjectFormat      subjectFormat           `mapstructure:"subject-format,omitempty" json:"subject-format,omitempty"`
+	SubjectTemplate    string                  `mapstructure:"subject-template,omitempty" json:"subject-template,omitempty"`
+	Headers            map[string]string       `mapstructure:"headers,omitempty" json:"headers,omitempty"`
+	MetaHeaders        bool                    `mapstructure:"meta-headers,omitempty" json:"meta-headers,omitempty"`
+	Deduplication      *deduplicationConfig    `mapstructure:"deduplication,omitempty" json:"deduplication,omitempty"`
+	CreateStream       *createStreamConfig     `mapstructure:"create-stream,omitempty" json:"create-stream,omitempty"`
+	Username           string                  `mapstructure:"username,omitempty" json:"username,omitempty"`
+	Password           string                  `mapstructure:"password,omitempty" json:"password,omitempty"`
+	ConnectTimeWait    time.Duration           `mapstructure:"connect-time-wait,omitempty" json:"connect-time-wait,omitempty"`
+	TLS                *types.TLSConfig        `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	Format             string                  `mapstructure:"format,omitempty" json:"format,omitempty"`
+	SplitEvents        bool                    `mapstructure:"split-events,omitempty"`
+	AddTarget          string                  `mapstructure:"add-target,omitempty" json:"add-target,omitempty"`
+	TargetTemplate     string                  `mapstructure:"target-template,omitempty" json:"target-template,omitempty"`
+	MsgTemplate        string                  `mapstructure:"msg-template,omitempty" json:"msg-template,omitempty"`
+	OverrideTimestamps bool                    `mapstructure:"override-timestamps,omitempty" json:"override-timestamps,omitempty"`
+	NumWorkers         int                     `mapstructure:"num-workers,omitempty" json:"num-workers,omitempty"`
+	WriteTimeout       time.Duration           `mapstructure:"write-timeout,omitempty" json:"write-timeout,omitempty"`
+	Debug              bool                    `mapstructure:"debug,omitempty" json:"debug,omitempty"`
+	BufferSize         uint                    `mapstructure:"buffer-size,omitempty"`
+	EnableMetrics      bool                    `mapstructure:"enable-metrics,omitempty" json:"enable-metrics,omitempty"`
+	EventProcessors    []string                `mapstructure:"event-processors,omitempty" json:"event-processors,omitempty"`
+	Envelope           *outputs.EnvelopeConfig `mapstructure:"envelope,omitempty" json:"envelope,omitempty"`
 }
 
 type createStreamConfig struct {
-	Description string        `mapstructure:"description,omitempty" json:"description,omitempty"`
-	Subjects    []string      `mapstructure:"subjects,omitempty" json:"subjects,omitempty"`
-	Storage     string        `mapstructure:"storage,omitempty" json:"storage,omitempty"`
-	MaxMsgs     int64         `mapstructure:"max-msgs,omitempty" json:"max-msgs,omitempty"`
-	MaxBytes    int64         `mapstructure:"max-bytes,omitempty" json:"max-bytes,omitempty"`
-	MaxAge      time.Duration `mapstructure:"max-age,omitempty" json:"max-age,omitempty"`
-	MaxMsgSize  int32         `mapstructure:"max-msg-size,omitempty" json:"max-msg-size,omitempty"`
+	Description     string        `mapstructure:"description,omitempty" json:"description,omitempty"`
+	Subjects        []string      `mapstructure:"subjects,omitempty" json:"subjects,omitempty"`
+	Storage         string        `mapstructure:"storage,omitempty" json:"storage,omitempty"`
+	MaxMsgs         int64         `mapstructure:"max-msgs,omitempty" json:"max-msgs,omitempty"`
+	MaxBytes        int64         `mapstructure:"max-bytes,omitempty" json:"max-bytes,omitempty"`
+	MaxAge          time.Duration `mapstructure:"max-age,omitempty" json:"max-age,omitempty"`
+	MaxMsgSize      int32         `mapstructure:"max-msg-size,omitempty" json:"max-msg-size,omitempty"`
+	Retention       string        `mapstructure:"retention,omitempty" json:"retention,omitempty"`
+	Discard         string        `mapstructure:"discard,omitempty" json:"discard,omitempty"`
+	Replicas        int           `mapstructure:"replicas,omitempty" json:"replicas,omitempty"`
+	DuplicateWindow time.Duration `mapstructure:"duplicate-window,omitempty" json:"duplicate-window,omitempty"`
+	// update the stream configuration if it already exists
+	UpdateExisting bool `mapstructure:"update-existing,omitempty" json:"update-existing,omitempty"`
+}
+
+type deduplicationConfig struct {
+	// template of the Nats-Msg-Id header,
+	// defaults to a hash of the subject and the payload.
+	MsgIDTemplate string `mapstructure:"msg-id-template,omitempty" json:"msg-id-template,omitempty"`
 }
 
 // jetstreamOutput //
@@ -110,11 +128,11 @@ type jetstreamOutput struct {
 	msgChan  chan *outputs.ProtoMsg
 	wg       *sync.WaitGroup
 	logger   *log.Logger
-	mo       *formatters.MarshalOptions
+	mo       *outputs.MarshalOptions
 	evps     []formatters.EventProcessor
 
 	targetTpl *template.Template
-	msgTpl    *template.Template
+	tpls      *nats_outputs.Templates
 
 	reg *prometheus.Registry
 }
@@ -134,6 +152,7 @@ func (n *jetstreamOutput) Init(ctx context.Context, name string, cfg map[string]
 			return err
 		}
 	}
+	n.evps = formatters.ForOutput(name, n.evps)
 	err = n.setDefaults()
 	if err != nil {
 		return err
@@ -143,9 +162,11 @@ func (n *jetstreamOutput) Init(ctx context.Context, name string, cfg map[string]
 		return err
 	}
 	n.msgChan = make(chan *outputs.ProtoMsg, n.Cfg.BufferSize)
-	n.mo = &formatters.MarshalOptions{
-		Format:     n.Cfg.Format,
-		OverrideTS: n.Cfg.OverrideTimestamps,
+	n.mo = &outputs.MarshalOptions{
+		MarshalOptions: formatters.MarshalOptions{
+			Format:     n.Cfg.Format,
+			OverrideTS: n.Cfg.OverrideTimestamps,
+		},
 	}
 	if n.Cfg.TargetTemplate == "" {
 		n.targetTpl = outputs.DefaultTargetTemplate
@@ -158,11 +179,25 @@ func (n *jetstreamOutput) Init(ctx context.Context, name string, cfg map[string]
 	}
 
 	if n.Cfg.MsgTemplate != "" {
-		n.msgTpl, err = gtemplate.CreateTemplate("msg-template", n.Cfg.MsgTemplate)
+		n.mo.MsgTemplate, err = gtemplate.CreateTemplate("msg-template", n.Cfg.MsgTemplate)
 		if err != nil {
 			return err
 		}
-		n.msgTpl = n.msgTpl.Funcs(outputs.TemplateFuncs)
+		n.mo.MsgTemplate = n.mo.MsgTemplate.Funcs(outputs.TemplateFuncs)
+	}
+
+	var msgIDTpl string
+	if n.Cfg.Deduplication != nil {
+		msgIDTpl = n.Cfg.Deduplication.MsgIDTemplate
+	}
+	n.tpls, err = nats_outputs.NewTemplates(n.Cfg.Name, n.Cfg.SubjectTemplate, n.Cfg.Headers, msgIDTpl)
+	if err != nil {
+		return err
+	}
+
+	n.mo.Sealer, err = outputs.NewSealer(n.Cfg.Envelope)
+	if err != nil {
+		return err
 	}
 
 	n.ctx, n.cancelFn = context.WithCancel(ctx)
@@ -173,6 +208,16 @@ func (n *jetstreamOutput) Init(ctx context.Context, name string, cfg map[string]
 		cfg.Name = fmt.Sprintf("%s-%d", cfg.Name, i)
 		go n.worker(ctx, i, &cfg)
 	}
+	formatters.StartFlush(ctx, n.evps, n.logger, func(evs []*formatters.EventMsg) {
+		formatters.ObserveEvents(n.Cfg.Name, evs)
+		for _, m := range outputs.FlushedMsgs(evs) {
+			select {
+			case <-ctx.Done():
+				return
+			case n.msgChan <- m:
+			}
+		}
+	})
 
 	go func() {
 		<-ctx.Done()
@@ -228,6 +273,18 @@ func (n *jetstreamOutput) setDefaults() error {
 		if n.Cfg.CreateStream.Storage == "" {
 			n.Cfg.CreateStream.Storage = "memory"
 		}
+		if _, err := retentionPolicy(n.Cfg.CreateStream.Retention); err != nil {
+			return err
+		}
+		if _, err := discardPolicy(n.Cfg.CreateStream.Discard); err != nil {
+			return err
+		}
+		if n.Cfg.CreateStream.Replicas == 0 {
+			n.Cfg.CreateStream.Replicas = 1
+		}
+		if n.Cfg.CreateStream.Replicas < 1 || n.Cfg.CreateStream.Replicas > 5 {
+			return fmt.Errorf("invalid stream replicas %d: must be between 1 and 5", n.Cfg.CreateStream.Replicas)
+		}
 		return nil
 	}
 	return nil
@@ -374,26 +431,38 @@ CRCONN:
 			n.logger.Printf("%s shutting down", workerLogPrefix)
 			return
 		case m := <-n.msgChan:
-			pmsg := m.GetMsg()
-			pmsg, err = outputs.AddSubscriptionTarget(pmsg, m.GetMeta(), n.Cfg.AddTarget, n.targetTpl)
-			if err != nil {
-				n.logger.Printf("failed to add target to the response: %v", err)
-			}
+			evs := m.GetEvents()
 			var rs []proto.Message
-			switch n.Cfg.SubjectFormat {
-			case subjectFormat_Static, subjectFormat_TargetSub, subjectFormat_SubTarget:
-				rs = []proto.Message{pmsg}
-			case subjectFormat_SubTargetPath, subjectFormat_SubTargetPathWithKeys:
-				switch rsp := pmsg.(type) {
-				case *gnmi.SubscribeResponse:
-					switch rsp := rsp.Response.(type) {
-					case *gnmi.SubscribeResponse_Update:
-						rs = splitSubscribeResponse(rsp)
+			if evs != nil {
+				// the events flushed by the output processors are published
+				// without a response to derive a path subject from.
+				rs = []proto.Message{nil}
+			} else {
+				pmsg := m.GetMsg()
+				pmsg, err = outputs.AddSubscriptionTarget(pmsg, m.GetMeta(), n.Cfg.AddTarget, n.targetTpl)
+				if err != nil {
+					n.logger.Printf("failed to add target to the response: %v", err)
+				}
+				switch n.Cfg.SubjectFormat {
+				case subjectFormat_Static, subjectFormat_TargetSub, subjectFormat_SubTarget:
+					rs = []proto.Message{pmsg}
+				case subjectFormat_SubTargetPath, subjectFormat_SubTargetPathWithKeys:
+					switch rsp := pmsg.(type) {
+					case *gnmi.SubscribeResponse:
+						switch rsp := rsp.Response.(type) {
+						case *gnmi.SubscribeResponse_Update:
+							rs = splitSubscribeResponse(rsp)
+						}
 					}
 				}
 			}
 			for _, r := range rs {
-				bb, err := outputs.Marshal(r, m.GetMeta(), n.mo, n.Cfg.SplitEvents, n.evps...)
+				var ms []*nats_outputs.Message
+				if evs != nil {
+					ms, err = nats_outputs.MarshalEvents(evs, m.GetMeta(), n.mo, n.Cfg.SplitEvents)
+				} else {
+					ms, err = nats_outputs.Marshal(r, m.GetMeta(), n.mo, n.Cfg.SplitEvents, n.evps...)
+				}
 				if err != nil {
 					if n.Cfg.Debug {
 						n.logger.Printf("%s failed marshaling proto msg: %v", workerLogPrefix, err)
@@ -403,22 +472,16 @@ CRCONN:
 					}
 					continue
 				}
-				if len(bb) == 0 {
-					continue
-				}
-				for _, b := range bb {
-					if n.msgTpl != nil {
-						b, err = outputs.ExecTemplate(b, n.msgTpl)
-						if err != nil {
-							if n.Cfg.Debug {
-								log.Printf("failed to execute template: %v", err)
-							}
-							jetStreamNumberOfFailSendMsgs.WithLabelValues(cfg.Name, "template_error").Inc()
-							continue
+				for _, msg := range ms {
+					if msg.Err != nil {
+						if n.Cfg.Debug {
+							n.logger.Printf("%s %v", workerLogPrefix, msg.Err)
 						}
+						jetStreamNumberOfFailSendMsgs.WithLabelValues(cfg.Name, msg.Reason).Inc()
+						continue
 					}
-
-					subject, err = n.subjectName(r, m.GetMeta())
+					b := msg.Data
+					subject, err = n.subjectName(r, msg)
 					if err != nil {
 						if n.Cfg.Debug {
 							n.logger.Printf("%s failed to get subject name: %v", workerLogPrefix, err)
@@ -428,11 +491,35 @@ CRCONN:
 						}
 						continue
 					}
+					headers, err := n.tpls.Headers(msg, n.Cfg.Format, n.Cfg.MetaHeaders)
+					if err != nil {
+						if n.Cfg.Debug {
+							n.logger.Printf("%s failed to build message headers: %v", workerLogPrefix, err)
+						}
+						if n.Cfg.EnableMetrics {
+							jetStreamNumberOfFailSendMsgs.WithLabelValues(cfg.Name, "headers_error").Inc()
+						}
+						continue
+					}
+					var popts []nats.PubOpt
+					if n.Cfg.Deduplication != nil {
+						id, err := n.tpls.MsgID(subject, msg)
+						if err != nil {
+							if n.Cfg.Debug {
+								n.logger.Printf("%s failed to build message ID: %v", workerLogPrefix, err)
+							}
+							if n.Cfg.EnableMetrics {
+								jetStreamNumberOfFailSendMsgs.WithLabelValues(cfg.Name, "msg_id_error").Inc()
+							}
+							continue
+						}
+						popts = append(popts, nats.MsgId(id))
+					}
 					var start time.Time
 					if n.Cfg.EnableMetrics {
 						start = time.Now()
 					}
-					_, err = js.Publish(subject, b)
+					_, err = js.PublishMsg(&nats.Msg{Subject: subject, Data: b, Header: headers}, popts...)
 					if err != nil {
 						if n.Cfg.Debug {
 							n.logger.Printf("%s failed to write to subject '%s': %v", workerLogPrefix, subject, err)
@@ -521,7 +608,11 @@ func (n *jetstreamOutput) createNATSConn(c *config) (*nats.Conn, error) {
 	return nc, nil
 }
 
-func (n *jetstreamOutput) subjectName(m proto.Message, meta outputs.Meta) (string, error) {
+func (n *jetstreamOutput) subjectName(m proto.Message, msg *nats_outputs.Message) (string, error) {
+	if n.tpls.HasSubject() {
+		return n.tpls.Subject(msg)
+	}
+	meta := msg.Meta
 	sb := new(strings.Builder)
 	sb.WriteString(n.Cfg.Stream)
 	sb.WriteString(".")
@@ -555,11 +646,11 @@ func (n *jetstreamOutput) subjectName(m proto.Message, meta outputs.Meta) (strin
 		if err != nil {
 			return "", err
 		}
-		sb.WriteString(".")
 		switch rsp := m.(type) {
 		case *gnmi.SubscribeResponse:
 			switch rsp := rsp.Response.(type) {
 			case *gnmi.SubscribeResponse_Update:
+				sb.WriteString(".")
 				var prefixSubject string
 				if rsp.Update.GetPrefix() != nil {
 					prefixSubject = gNMIPathToSubject(rsp.Update.GetPrefix(), false)
@@ -586,11 +677,11 @@ func (n *jetstreamOutput) subjectName(m proto.Message, meta outputs.Meta) (strin
 		if err != nil {
 			return "", err
 		}
-		sb.WriteString(".")
 		switch rsp := m.(type) {
 		case *gnmi.SubscribeResponse:
 			switch rsp := rsp.Response.(type) {
 			case *gnmi.SubscribeResponse_Update:
+				sb.WriteString(".")
 				var prefixSubject string
 				if rsp.Update.GetPrefix() != nil {
 					prefixSubject = gNMIPathToSubject(rsp.Update.GetPrefix(), true)
@@ -696,6 +787,28 @@ func storageType(s string) nats.StorageType {
 	return nats.MemoryStorage
 }
 
+func retentionPolicy(s string) (nats.RetentionPolicy, error) {
+	switch strings.ToLower(s) {
+	case "", "limits":
+		return nats.LimitsPolicy, nil
+	case "interest":
+		return nats.InterestPolicy, nil
+	case "workqueue":
+		return nats.WorkQueuePolicy, nil
+	}
+	return 0, fmt.Errorf("unknown stream retention policy %q", s)
+}
+
+func discardPolicy(s string) (nats.DiscardPolicy, error) {
+	switch strings.ToLower(s) {
+	case "", "old":
+		return nats.DiscardOld, nil
+	case "new":
+		return nats.DiscardNew, nil
+	}
+	return 0, fmt.Errorf("unknown stream discard policy %q", s)
+}
+
 // var storageTypes = map[string]nats.StorageType{
 // 	"file":   nats.FileStorage,
 // 	"memory": nats.MemoryStorage,
@@ -711,11 +824,9 @@ func (n *jetstreamOutput) createStream(js nats.JetStreamContext) error {
 			return err
 		}
 	}
-	// stream exists
-	if stream != nil {
-		return nil
-	}
-	// create stream
+	// policies are validated in setDefaults
+	retention, _ := retentionPolicy(n.Cfg.CreateStream.Retention)
+	discard, _ := discardPolicy(n.Cfg.CreateStream.Discard)
 	streamConfig := &nats.StreamConfig{
 		Name:        n.Cfg.Stream,
 		Description: n.Cfg.CreateStream.Description,
@@ -725,7 +836,20 @@ func (n *jetstreamOutput) createStream(js nats.JetStreamContext) error {
 		MaxBytes:    n.Cfg.CreateStream.MaxBytes,
 		MaxAge:      n.Cfg.CreateStream.MaxAge,
 		MaxMsgSize:  n.Cfg.CreateStream.MaxMsgSize,
+		Retention:   retention,
+		Discard:     discard,
+		Replicas:    n.Cfg.CreateStream.Replicas,
+		Duplicates:  n.Cfg.CreateStream.DuplicateWindow,
+	}
+	// stream exists
+	if stream != nil {
+		if !n.Cfg.CreateStream.UpdateExisting {
+			return nil
+		}
+		_, err = js.UpdateStream(streamConfig)
+		return err
 	}
+	// create stream
 	_, err = js.AddStream(streamConfig)
 	return err
 }
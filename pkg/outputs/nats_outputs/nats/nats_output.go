@@ -30,6 +30,7 @@ import (
 	"github.com/openconfig/gnmic/pkg/formatters"
 	"github.com/openconfig/gnmic/pkg/gtemplate"
 	"github.com/openconfig/gnmic/pkg/outputs"
+	"github.com/openconfig/gnmic/pkg/outputs/nats_outputs"
 )
 
 const (
@@ -61,37 +62,41 @@ type NatsOutput struct {
 	msgChan  chan *outputs.ProtoMsg
 	wg       *sync.WaitGroup
 	logger   *log.Logger
-	mo       *formatters.MarshalOptions
+	mo       *outputs.MarshalOptions
 	evps     []formatters.EventProcessor
 
 	targetTpl *template.Template
-	msgTpl    *template.Template
+	tpls      *nats_outputs.Templates
 
 	reg *prometheus.Registry
 }
 
 // Config //
 type Config struct {
-	Name               string           `mapstructure:"name,omitempty"`
-	Address            string           `mapstructure:"address,omitempty"`
-	SubjectPrefix      string           `mapstructure:"subject-prefix,omitempty"`
-	Subject            string           `mapstructure:"subject,omitempty"`
-	Username           string           `mapstructure:"username,omitempty"`
-	Password           string           `mapstructure:"password,omitempty"`
-	ConnectTimeWait    time.Duration    `mapstructure:"connect-time-wait,omitempty"`
-	TLS                *types.TLSConfig `mapstructure:"tls,omitempty" json:"tls,omitempty"`
-	Format             string           `mapstructure:"format,omitempty"`
-	SplitEvents        bool             `mapstructure:"split-events,omitempty"`
-	AddTarget          string           `mapstructure:"add-target,omitempty"`
-	TargetTemplate     string           `mapstructure:"target-template,omitempty"`
-	MsgTemplate        string           `mapstructure:"msg-template,omitempty"`
-	OverrideTimestamps bool             `mapstructure:"override-timestamps,omitempty"`
-	NumWorkers         int              `mapstructure:"num-workers,omitempty"`
-	WriteTimeout       time.Duration    `mapstructure:"write-timeout,omitempty"`
-	Debug              bool             `mapstructure:"debug,omitempty"`
-	BufferSize         uint             `mapstructure:"buffer-size,omitempty"`
-	EnableMetrics      bool             `mapstructure:"enable-metrics,omitempty"`
-	EventProcessors    []string         `mapstructure:"event-processors,omitempty"`
+	Name               string                  `mapstructure:"name,omitempty"`
+	Address            string                  `mapstructure:"address,omitempty"`
+	SubjectPrefix      string                  `mapstructure:"subject-prefix,omitempty"`
+	Subject            string                  `mapstructure:"subject,omitempty"`
+	SubjectTemplate    string                  `mapstructure:"subject-template,omitempty"`
+	Headers            map[string]string       `mapstructure:"headers,omitempty"`
+	MetaHeaders        bool                    `mapstructure:"meta-headers,omitempty"`
+	Username           string                  `mapstructure:"username,omitempty"`
+	Password           string                  `mapstructure:"password,omitempty"`
+	ConnectTimeWait    time.Duration           `mapstructure:"connect-time-wait,omitempty"`
+	TLS                *types.TLSConfig        `mapstructure:"tls,omitempty" json:"tls,omitempty"`
+	Format             string                  `mapstructure:"format,omitempty"`
+	SplitEvents        bool                    `mapstructure:"split-events,omitempty"`
+	AddTarget          string                  `mapstructure:"add-target,omitempty"`
+	TargetTemplate     string                  `mapstructure:"target-template,omitempty"`
+	MsgTemplate        string                  `mapstructure:"msg-template,omitempty"`
+	OverrideTimestamps bool                    `mapstructure:"override-timestamps,omitempty"`
+	NumWorkers         int                     `mapstructure:"num-workers,omitempty"`
+	WriteTimeout       time.Duration           `mapstructure:"write-timeout,omitempty"`
+	Debug              bool                    `mapstructure:"debug,omitempty"`
+	BufferSize         uint                    `mapstructure:"buffer-size,omitempty"`
+	EnableMetrics      bool                    `mapstructure:"enable-metrics,omitempty"`
+	EventProcessors    []string                `mapstructure:"event-processors,omitempty"`
+	Envelope           *outputs.EnvelopeConfig `mapstructure:"envelope,omitempty" json:"envelope,omitempty"`
 }
 
 func (n *NatsOutput) String() string {
@@ -143,6 +148,7 @@ func (n *NatsOutput) Init(ctx context.Context, name string, cfg map[string]inter
 			return err
 		}
 	}
+	n.evps = formatters.ForOutput(name, n.evps)
 	err = n.setDefaults()
 	if err != nil {
 		return err
@@ -152,9 +158,11 @@ func (n *NatsOutput) Init(ctx context.Context, name string, cfg map[string]inter
 		return err
 	}
 	n.msgChan = make(chan *outputs.ProtoMsg, n.Cfg.BufferSize)
-	n.mo = &formatters.MarshalOptions{
-		Format:     n.Cfg.Format,
-		OverrideTS: n.Cfg.OverrideTimestamps,
+	n.mo = &outputs.MarshalOptions{
+		MarshalOptions: formatters.MarshalOptions{
+			Format:     n.Cfg.Format,
+			OverrideTS: n.Cfg.OverrideTimestamps,
+		},
 	}
 	if n.Cfg.TargetTemplate == "" {
 		n.targetTpl = outputs.DefaultTargetTemplate
@@ -167,11 +175,21 @@ func (n *NatsOutput) Init(ctx context.Context, name string, cfg map[string]inter
 	}
 
 	if n.Cfg.MsgTemplate != "" {
-		n.msgTpl, err = gtemplate.CreateTemplate("msg-template", n.Cfg.MsgTemplate)
+		n.mo.MsgTemplate, err = gtemplate.CreateTemplate("msg-template", n.Cfg.MsgTemplate)
 		if err != nil {
 			return err
 		}
-		n.msgTpl = n.msgTpl.Funcs(outputs.TemplateFuncs)
+		n.mo.MsgTemplate = n.mo.MsgTemplate.Funcs(outputs.TemplateFuncs)
+	}
+
+	n.tpls, err = nats_outputs.NewTemplates(n.Cfg.Name, n.Cfg.SubjectTemplate, n.Cfg.Headers, "")
+	if err != nil {
+		return err
+	}
+
+	n.mo.Sealer, err = outputs.NewSealer(n.Cfg.Envelope)
+	if err != nil {
+		return err
 	}
 
 	n.ctx, n.cancelFn = context.WithCancel(ctx)
@@ -181,6 +199,16 @@ func (n *NatsOutput) Init(ctx context.Context, name string, cfg map[string]inter
 		cfg.Name = fmt.Sprintf("%s-%d", cfg.Name, i)
 		go n.worker(ctx, i, &cfg)
 	}
+	formatters.StartFlush(ctx, n.evps, n.logger, func(evs []*formatters.EventMsg) {
+		formatters.ObserveEvents(n.Cfg.Name, evs)
+		for _, m := range outputs.FlushedMsgs(evs) {
+			select {
+			case <-ctx.Done():
+				return
+			case n.msgChan <- m:
+			}
+		}
+	})
 
 	go func() {
 		<-ctx.Done()
@@ -354,12 +382,7 @@ CRCONN:
 			natsConn.Close()
 			return
 		case m := <-n.msgChan:
-			pmsg := m.GetMsg()
-			pmsg, err = outputs.AddSubscriptionTarget(pmsg, m.GetMeta(), n.Cfg.AddTarget, n.targetTpl)
-			if err != nil {
-				n.logger.Printf("failed to add target to the response: %v", err)
-			}
-			bb, err := outputs.Marshal(pmsg, m.GetMeta(), n.mo, n.Cfg.SplitEvents, n.evps...)
+			ms, err := n.marshal(m)
 			if err != nil {
 				if n.Cfg.Debug {
 					n.logger.Printf("%s failed marshaling proto msg: %v", workerLogPrefix, err)
@@ -369,27 +392,40 @@ CRCONN:
 				}
 				continue
 			}
-			if len(bb) == 0 {
-				continue
-			}
-			for _, b := range bb {
-				if n.msgTpl != nil {
-					b, err = outputs.ExecTemplate(b, n.msgTpl)
-					if err != nil {
-						if n.Cfg.Debug {
-							log.Printf("failed to execute template: %v", err)
-						}
-						NatsNumberOfFailSendMsgs.WithLabelValues(cfg.Name, "template_error").Inc()
-						continue
+			for _, msg := range ms {
+				if msg.Err != nil {
+					if n.Cfg.Debug {
+						n.logger.Printf("%s %v", workerLogPrefix, msg.Err)
 					}
+					NatsNumberOfFailSendMsgs.WithLabelValues(cfg.Name, msg.Reason).Inc()
+					continue
+				}
+				b := msg.Data
+				subject, err := n.subjectName(cfg, msg)
+				if err != nil {
+					if n.Cfg.Debug {
+						n.logger.Printf("%s failed to get subject name: %v", workerLogPrefix, err)
+					}
+					if n.Cfg.EnableMetrics {
+						NatsNumberOfFailSendMsgs.WithLabelValues(cfg.Name, "subject_name_error").Inc()
+					}
+					continue
+				}
+				headers, err := n.tpls.Headers(msg, n.Cfg.Format, n.Cfg.MetaHeaders)
+				if err != nil {
+					if n.Cfg.Debug {
+						n.logger.Printf("%s failed to build message headers: %v", workerLogPrefix, err)
+					}
+					if n.Cfg.EnableMetrics {
+						NatsNumberOfFailSendMsgs.WithLabelValues(cfg.Name, "headers_error").Inc()
+					}
+					continue
 				}
-
-				subject := n.subjectName(cfg, m.GetMeta())
 				var start time.Time
 				if n.Cfg.EnableMetrics {
 					start = time.Now()
 				}
-				err = natsConn.Publish(subject, b)
+				err = natsConn.PublishMsg(&nats.Msg{Subject: subject, Data: b, Header: headers})
 				if err != nil {
 					if n.Cfg.Debug {
 						n.logger.Printf("%s failed to write to nats subject '%s': %v", workerLogPrefix, subject, err)
@@ -419,7 +455,24 @@ CRCONN:
 	}
 }
 
-func (n *NatsOutput) subjectName(c *Config, meta outputs.Meta) string {
+// marshal marshals the message m, or the events it carries
+// if they were flushed by the output processors.
+func (n *NatsOutput) marshal(m *outputs.ProtoMsg) ([]*nats_outputs.Message, error) {
+	if evs := m.GetEvents(); evs != nil {
+		return nats_outputs.MarshalEvents(evs, m.GetMeta(), n.mo, n.Cfg.SplitEvents)
+	}
+	pmsg, err := outputs.AddSubscriptionTarget(m.GetMsg(), m.GetMeta(), n.Cfg.AddTarget, n.targetTpl)
+	if err != nil {
+		n.logger.Printf("failed to add target to the response: %v", err)
+	}
+	return nats_outputs.Marshal(pmsg, m.GetMeta(), n.mo, n.Cfg.SplitEvents, n.evps...)
+}
+
+func (n *NatsOutput) subjectName(c *Config, msg *nats_outputs.Message) (string, error) {
+	if n.tpls.HasSubject() {
+		return n.tpls.Subject(msg)
+	}
+	meta := msg.Meta
 	if c.SubjectPrefix != "" {
 		ssb := strings.Builder{}
 		ssb.WriteString(n.Cfg.SubjectPrefix)
@@ -433,9 +486,9 @@ func (n *NatsOutput) subjectName(c *Config, meta outputs.Meta) string {
 			ssb.WriteString(".")
 			ssb.WriteString(subname)
 		}
-		return strings.ReplaceAll(ssb.String(), " ", "_")
+		return strings.ReplaceAll(ssb.String(), " ", "_"), nil
 	}
-	return strings.ReplaceAll(n.Cfg.Subject, " ", "_")
+	return strings.ReplaceAll(n.Cfg.Subject, " ", "_"), nil
 }
 
 func (n *NatsOutput) SetName(name string) {
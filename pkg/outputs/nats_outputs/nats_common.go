@@ -0,0 +1,212 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package nats_outputs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/nats-io/nats.go"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/gtemplate"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+const (
+	HeaderSource       = "Gnmic-Source"
+	HeaderSubscription = "Gnmic-Subscription-Name"
+	HeaderFormat       = "Gnmic-Format"
+)
+
+// trace context meta keys copied as is to the message headers.
+var traceContextKeys = []string{"traceparent", "tracestate"}
+
+// Message is a marshaled message along with the meta and event
+// its subject, headers and message ID are derived from.
+type Message struct {
+	Data []byte
+	// Unsealed is Data before it is wrapped in an envelope.
+	Unsealed []byte
+	// Meta of the gNMI message.
+	Meta outputs.Meta
+	// Event is set if the message is a single event,
+	// i.e the format is event and split-events is enabled.
+	Event *formatters.EventMsg
+	// Err and Reason are the message template or envelope
+	// failure of the message, see outputs.Message.
+	Err    error
+	Reason string
+}
+
+// TemplateData is the input of the subject, headers and message ID templates.
+type TemplateData struct {
+	Meta  outputs.Meta
+	Event *formatters.EventMsg
+}
+
+// Marshal is outputs.MarshalMessages attaching the gNMI message meta
+// to each message.
+func Marshal(pmsg proto.Message, meta outputs.Meta, mo *outputs.MarshalOptions, splitEvents bool, evps ...formatters.EventProcessor) ([]*Message, error) {
+	oms, err := outputs.MarshalMessages(pmsg, meta, mo, splitEvents, evps...)
+	if err != nil {
+		return nil, err
+	}
+	return withMeta(oms, meta), nil
+}
+
+// MarshalEvents is outputs.MarshalEvents attaching meta to each message.
+func MarshalEvents(evs []*formatters.EventMsg, meta outputs.Meta, mo *outputs.MarshalOptions, splitEvents bool) ([]*Message, error) {
+	oms, err := outputs.MarshalEvents(evs, mo, splitEvents)
+	if err != nil {
+		return nil, err
+	}
+	return withMeta(oms, meta), nil
+}
+
+func withMeta(oms []*outputs.Message, meta outputs.Meta) []*Message {
+	ms := make([]*Message, 0, len(oms))
+	for _, m := range oms {
+		ms = append(ms, &Message{
+			Data:     m.Data,
+			Unsealed: m.Unsealed,
+			Meta:     meta,
+			Event:    m.Event,
+			Err:      m.Err,
+			Reason:   m.Reason,
+		})
+	}
+	return ms
+}
+
+// Templates holds the parsed subject, headers and message ID templates of a NATS output.
+type Templates struct {
+	subject     *template.Template
+	headers     map[string]*template.Template
+	headerNames []string
+	msgID       *template.Template
+}
+
+// NewTemplates parses the subject, headers and message ID templates.
+// Empty templates are ignored.
+func NewTemplates(name, subject string, headers map[string]string, msgID string) (*Templates, error) {
+	t := &Templates{headers: make(map[string]*template.Template, len(headers))}
+	var err error
+	if subject != "" {
+		t.subject, err = newTemplate(name+"-subject-template", subject)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse subject template: %w", err)
+		}
+	}
+	for h, s := range headers {
+		t.headers[h], err = newTemplate(name+"-header-"+h, s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse header %q template: %w", h, err)
+		}
+		t.headerNames = append(t.headerNames, h)
+	}
+	sort.Strings(t.headerNames)
+	if msgID != "" {
+		t.msgID, err = newTemplate(name+"-msg-id-template", msgID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse msg-id template: %w", err)
+		}
+	}
+	return t, nil
+}
+
+func newTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).
+		Option("missingkey=zero").
+		Funcs(gtemplate.NewTemplateEngine().CreateFuncs()).
+		Funcs(outputs.TemplateFuncs).
+		Parse(text)
+}
+
+// HasSubject reports whether a subject template is configured.
+func (t *Templates) HasSubject() bool {
+	return t != nil && t.subject != nil
+}
+
+// Subject executes the subject template.
+// Spaces are not allowed in NATS subjects, they are replaced with underscores.
+func (t *Templates) Subject(m *Message) (string, error) {
+	sb := new(strings.Builder)
+	err := t.subject.Execute(sb, &TemplateData{Meta: m.Meta, Event: m.Event})
+	if err != nil {
+		return "", err
+	}
+	s := strings.Join(strings.Fields(sb.String()), "_")
+	if s == "" {
+		return "", fmt.Errorf("empty subject")
+	}
+	return s, nil
+}
+
+// Headers returns the message headers: the meta headers if metaHeaders is true,
+// followed by the templated headers. It returns nil if there are no headers.
+func (t *Templates) Headers(m *Message, format string, metaHeaders bool) (nats.Header, error) {
+	var h nats.Header
+	if metaHeaders {
+		h = make(nats.Header)
+		if s, ok := m.Meta["source"]; ok {
+			h.Set(HeaderSource, s)
+		}
+		if s, ok := m.Meta["subscription-name"]; ok {
+			h.Set(HeaderSubscription, s)
+		}
+		h.Set(HeaderFormat, format)
+		for _, k := range traceContextKeys {
+			if v, ok := m.Meta[k]; ok {
+				h.Set(k, v)
+			}
+		}
+	}
+	if t == nil || len(t.headerNames) == 0 {
+		return h, nil
+	}
+	if h == nil {
+		h = make(nats.Header, len(t.headerNames))
+	}
+	td := &TemplateData{Meta: m.Meta, Event: m.Event}
+	for _, name := range t.headerNames {
+		sb := new(strings.Builder)
+		err := t.headers[name].Execute(sb, td)
+		if err != nil {
+			return nil, fmt.Errorf("header %q: %w", name, err)
+		}
+		h.Set(name, sb.String())
+	}
+	return h, nil
+}
+
+// MsgID returns the JetStream message ID used for deduplication.
+// It is the result of the msg-id template if configured, otherwise a hash
+// of the subject and unsealed payload so that a message published twice gets the same ID,
+// the envelope nonce making each sealed payload unique.
+func (t *Templates) MsgID(subject string, m *Message) (string, error) {
+	if t != nil && t.msgID != nil {
+		sb := new(strings.Builder)
+		err := t.msgID.Execute(sb, &TemplateData{Meta: m.Meta, Event: m.Event})
+		if err != nil {
+			return "", err
+		}
+		return sb.String(), nil
+	}
+	hs := sha256.New()
+	hs.Write([]byte(subject))
+	hs.Write([]byte{0})
+	hs.Write(m.Unsealed)
+	return hex.EncodeToString(hs.Sum(nil)[:16]), nil
+}
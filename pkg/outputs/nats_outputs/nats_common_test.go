@@ -0,0 +1,153 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package nats_outputs
+
+import (
+	"testing"
+
+	"github.com/openconfig/gnmi/proto/gnmi"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
+	"github.com/openconfig/gnmic/pkg/outputs"
+)
+
+func testResponse() *gnmi.SubscribeResponse {
+	return &gnmi.SubscribeResponse{
+		Response: &gnmi.SubscribeResponse_Update{
+			Update: &gnmi.Notification{
+				Timestamp: 42,
+				Prefix:    &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "interface", Key: map[string]string{"name": "ethernet-1/1"}}}},
+				Update: []*gnmi.Update{
+					{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "oper-state"}}},
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_StringVal{StringVal: "up"}},
+					},
+					{
+						Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "mtu"}}},
+						Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: 1500}},
+					},
+				},
+			},
+		},
+	}
+}
+
+var testMeta = outputs.Meta{
+	"source":            "router1:57400",
+	"subscription-name": "sub1",
+	"traceparent":       "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+}
+
+func TestMarshal(t *testing.T) {
+	tests := map[string]struct {
+		format      string
+		splitEvents bool
+		messages    int
+		events      bool
+	}{
+		"event":       {format: "event", messages: 1},
+		"event_split": {format: "event", splitEvents: true, messages: 2, events: true},
+		"json_split":  {format: "json", splitEvents: true, messages: 1},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ms, err := Marshal(testResponse(), testMeta, &outputs.MarshalOptions{MarshalOptions: formatters.MarshalOptions{Format: tt.format}}, tt.splitEvents)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ms) != tt.messages {
+				t.Fatalf("expected %d messages, got %d", tt.messages, len(ms))
+			}
+			for _, m := range ms {
+				if len(m.Data) == 0 {
+					t.Errorf("empty message")
+				}
+				if (m.Event != nil) != tt.events {
+					t.Errorf("unexpected message event: %v", m.Event)
+				}
+			}
+		})
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	tpls, err := NewTemplates("out1",
+		`telemetry.{{ index .Meta "source" | host }}.{{ with .Event }}{{ index .Tags "interface_name" | strings.ReplaceAll "/" "-" }}{{ else }}all{{ end }}`,
+		map[string]string{"Interface": `{{ with .Event }}{{ .Tags.interface_name }}{{ end }}`},
+		`{{ index .Meta "source" }}-{{ .Event.Timestamp }}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := &formatters.EventMsg{Timestamp: 42, Tags: map[string]string{"interface_name": "ethernet-1/1"}}
+	tests := map[string]struct {
+		msg     *Message
+		subject string
+	}{
+		"message": {msg: &Message{Meta: testMeta}, subject: "telemetry.router1.all"},
+		"event":   {msg: &Message{Meta: testMeta, Event: ev}, subject: "telemetry.router1.ethernet-1-1"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := tpls.Subject(tt.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s != tt.subject {
+				t.Errorf("expected subject %q, got %q", tt.subject, s)
+			}
+		})
+	}
+
+	h, err := tpls.Headers(&Message{Meta: testMeta, Event: ev}, "event", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		HeaderSource:       "router1:57400",
+		HeaderSubscription: "sub1",
+		HeaderFormat:       "event",
+		"traceparent":      testMeta["traceparent"],
+		"Interface":        "ethernet-1/1",
+	}
+	for k, v := range expected {
+		if h.Get(k) != v {
+			t.Errorf("expected header %s=%q, got %q", k, v, h.Get(k))
+		}
+	}
+	h, err = tpls.Headers(&Message{Meta: testMeta}, "event", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 1 || h.Get("Interface") != "" {
+		t.Errorf("unexpected headers: %v", h)
+	}
+
+	id, err := tpls.MsgID("s1", &Message{Meta: testMeta, Event: ev})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "router1:57400-42" {
+		t.Errorf("unexpected msg id %q", id)
+	}
+}
+
+func TestMsgIDHash(t *testing.T) {
+	var tpls *Templates
+	m := &Message{Data: []byte("sealed1"), Unsealed: []byte("payload")}
+	id1, _ := tpls.MsgID("s1", m)
+	// the same payload sealed twice gets the same ID.
+	id2, _ := tpls.MsgID("s1", &Message{Data: []byte("sealed2"), Unsealed: []byte("payload")})
+	id3, _ := tpls.MsgID("s2", m)
+	if id1 == "" || id1 != id2 {
+		t.Errorf("expected the same ID for the same subject and payload: %q, %q", id1, id2)
+	}
+	if id1 == id3 {
+		t.Errorf("expected different IDs for different subjects")
+	}
+}
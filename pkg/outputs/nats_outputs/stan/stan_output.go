@@ -63,7 +63,7 @@ type StanOutput struct {
 	logger   *log.Logger
 	msgChan  chan *outputs.ProtoMsg
 	wg       *sync.WaitGroup
-	mo       *formatters.MarshalOptions
+	mo       *outputs.MarshalOptions
 	evps     []formatters.EventProcessor
 
 	targetTpl *template.Template
@@ -71,25 +71,26 @@ type StanOutput struct {
 
 // Config //
 type Config struct {
-	Name               string        `mapstructure:"name,omitempty"`
-	Address            string        `mapstructure:"address,omitempty"`
-	SubjectPrefix      string        `mapstructure:"subject-prefix,omitempty"`
-	Subject            string        `mapstructure:"subject,omitempty"`
-	Username           string        `mapstructure:"username,omitempty"`
-	Password           string        `mapstructure:"password,omitempty"`
-	ClusterName        string        `mapstructure:"cluster-name,omitempty"`
-	PingInterval       int           `mapstructure:"ping-interval,omitempty"`
-	PingRetry          int           `mapstructure:"ping-retry,omitempty"`
-	Format             string        `mapstructure:"format,omitempty"`
-	AddTarget          string        `mapstructure:"add-target,omitempty"`
-	TargetTemplate     string        `mapstructure:"target-template,omitempty"`
-	OverrideTimestamps bool          `mapstructure:"override-timestamps,omitempty"`
-	RecoveryWaitTime   time.Duration `mapstructure:"recovery-wait-time,omitempty"`
-	NumWorkers         int           `mapstructure:"num-workers,omitempty"`
-	Debug              bool          `mapstructure:"debug,omitempty"`
-	WriteTimeout       time.Duration `mapstructure:"write-timeout,omitempty"`
-	EnableMetrics      bool          `mapstructure:"enable-metrics,omitempty"`
-	EventProcessors    []string      `mapstructure:"event-processors,omitempty"`
+	Name               string                  `mapstructure:"name,omitempty"`
+	Address            string                  `mapstructure:"address,omitempty"`
+	SubjectPrefix      string                  `mapstructure:"subject-prefix,omitempty"`
+	Subject            string                  `mapstructure:"subject,omitempty"`
+	Username           string                  `mapstructure:"username,omitempty"`
+	Password           string                  `mapstructure:"password,omitempty"`
+	ClusterName        string                  `mapstructure:"cluster-name,omitempty"`
+	PingInterval       int                     `mapstructure:"ping-interval,omitempty"`
+	PingRetry          int                     `mapstructure:"ping-retry,omitempty"`
+	Format             string                  `mapstructure:"format,omitempty"`
+	AddTarget          string                  `mapstructure:"add-target,omitempty"`
+	TargetTemplate     string                  `mapstructure:"target-template,omitempty"`
+	OverrideTimestamps bool                    `mapstructure:"override-timestamps,omitempty"`
+	RecoveryWaitTime   time.Duration           `mapstructure:"recovery-wait-time,omitempty"`
+	NumWorkers         int                     `mapstructure:"num-workers,omitempty"`
+	Debug              bool                    `mapstructure:"debug,omitempty"`
+	WriteTimeout       time.Duration           `mapstructure:"write-timeout,omitempty"`
+	EnableMetrics      bool                    `mapstructure:"enable-metrics,omitempty"`
+	EventProcessors    []string                `mapstructure:"event-processors,omitempty"`
+	Envelope           *outputs.EnvelopeConfig `mapstructure:"envelope,omitempty" json:"envelope,omitempty"`
 }
 
 func (s *StanOutput) String() string {
@@ -139,15 +140,22 @@ func (s *StanOutput) Init(ctx context.Context, name string, cfg map[string]inter
 			return err
 		}
 	}
+	s.evps = formatters.ForOutput(name, s.evps)
 	err = s.setDefaults()
 	if err != nil {
 		return err
 	}
 	s.msgChan = make(chan *outputs.ProtoMsg)
 
-	s.mo = &formatters.MarshalOptions{
-		Format:     s.Cfg.Format,
-		OverrideTS: s.Cfg.OverrideTimestamps,
+	s.mo = &outputs.MarshalOptions{
+		MarshalOptions: formatters.MarshalOptions{
+			Format:     s.Cfg.Format,
+			OverrideTS: s.Cfg.OverrideTimestamps,
+		},
+	}
+	s.mo.Sealer, err = outputs.NewSealer(s.Cfg.Envelope)
+	if err != nil {
+		return err
 	}
 
 	if s.Cfg.TargetTemplate == "" {
@@ -166,6 +174,16 @@ func (s *StanOutput) Init(ctx context.Context, name string, cfg map[string]inter
 		cfg.Name = fmt.Sprintf("%s-%d", cfg.Name, i)
 		go s.worker(ctx, i, &cfg)
 	}
+	formatters.StartFlush(ctx, s.evps, s.logger, func(evs []*formatters.EventMsg) {
+		formatters.ObserveEvents(s.Cfg.Name, evs)
+		for _, m := range outputs.FlushedMsgs(evs) {
+			select {
+			case <-ctx.Done():
+				return
+			case s.msgChan <- m:
+			}
+		}
+	})
 
 	s.logger.Printf("initialized stan producer: %s", s.String())
 	go func() {
@@ -175,6 +193,19 @@ func (s *StanOutput) Init(ctx context.Context, name string, cfg map[string]inter
 	return nil
 }
 
+// marshal marshals the message m, or the events it carries
+// if they were flushed by the output processors.
+func (s *StanOutput) marshal(m *outputs.ProtoMsg) ([]*outputs.Message, error) {
+	if evs := m.GetEvents(); evs != nil {
+		return outputs.MarshalEvents(evs, s.mo, false)
+	}
+	pmsg, err := outputs.AddSubscriptionTarget(m.GetMsg(), m.GetMeta(), s.Cfg.AddTarget, s.targetTpl)
+	if err != nil {
+		s.logger.Printf("failed to add target to the response: %v", err)
+	}
+	return outputs.MarshalMessages(pmsg, m.GetMeta(), s.mo, false, s.evps...)
+}
+
 func (s *StanOutput) setDefaults() error {
 	if s.Cfg.Format == "" {
 		s.Cfg.Format = defaultFormat
@@ -307,19 +338,13 @@ CRCONN:
 	s.logger.Printf("%s initialized stan producer: %s", workerLogPrefix, s.String())
 	defer stanConn.Close()
 	defer stanConn.NatsConn().Close()
-	var err error
 	for {
 		select {
 		case <-ctx.Done():
 			s.logger.Printf("%s shutting down", workerLogPrefix)
 			return
 		case m := <-s.msgChan:
-			pmsg := m.GetMsg()
-			pmsg, err = outputs.AddSubscriptionTarget(pmsg, m.GetMeta(), s.Cfg.AddTarget, s.targetTpl)
-			if err != nil {
-				s.logger.Printf("failed to add target to the response: %v", err)
-			}
-			b, err := s.mo.Marshal(pmsg, m.GetMeta(), s.evps...)
+			ms, err := s.marshal(m)
 			if err != nil {
 				if s.Cfg.Debug {
 					s.logger.Printf("%s failed marshaling proto msg: %v", workerLogPrefix, err)
@@ -329,9 +354,19 @@ CRCONN:
 				}
 				continue
 			}
-			if len(b) == 0 {
+			if len(ms) == 0 {
+				continue
+			}
+			if ms[0].Err != nil {
+				if s.Cfg.Debug {
+					s.logger.Printf("%s %v", workerLogPrefix, ms[0].Err)
+				}
+				if s.Cfg.EnableMetrics {
+					StanNumberOfFailSendMsgs.WithLabelValues(c.Name, ms[0].Reason).Inc()
+				}
 				continue
 			}
+			b := ms[0].Data
 			subject := s.subjectName(c, m.GetMeta())
 			start := time.Now()
 			err = stanConn.Publish(subject, b)
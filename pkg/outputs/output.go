@@ -62,6 +62,7 @@ var OutputTypes = map[string]struct{}{
 	"jetstream":        {},
 	"snmp":             {},
 	"asciigraph":       {},
+	"group":            {},
 }
 
 func Register(name string, initFn Initializer) {
@@ -157,26 +158,170 @@ const (
 {{- end -}}`
 )
 
-func Marshal(pmsg protoreflect.ProtoMessage, meta map[string]string, mo *formatters.MarshalOptions, splitEvents bool, evps ...formatters.EventProcessor) ([][]byte, error) {
+// MarshalOptions are the options used by an output to marshal its messages.
+// Once marshaled, each message goes through the message template and
+// is wrapped in an envelope, if configured.
+type MarshalOptions struct {
+	formatters.MarshalOptions
+	// MsgTemplate is executed on each marshaled message.
+	MsgTemplate *template.Template
+	// Sealer wraps each message in an envelope, after the message template.
+	Sealer *Sealer
+}
+
+// Seal executes the message template on the marshaled message b
+// and wraps the result in an envelope.
+func (mo *MarshalOptions) Seal(b []byte) ([]byte, error) {
+	b, err := mo.execTemplate(b)
+	if err != nil {
+		return nil, err
+	}
+	return mo.seal(b)
+}
+
+func (mo *MarshalOptions) execTemplate(b []byte) ([]byte, error) {
+	if mo.MsgTemplate == nil {
+		return b, nil
+	}
+	return ExecTemplate(b, mo.MsgTemplate)
+}
+
+func (mo *MarshalOptions) seal(b []byte) ([]byte, error) {
+	if mo.Sealer == nil {
+		return b, nil
+	}
+	b, err := mo.Sealer.Seal(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal message: %v", err)
+	}
+	return b, nil
+}
+
+// failure reasons of a marshaled Message.
+const (
+	ReasonTemplateError = "template_error"
+	ReasonEnvelopeError = "envelope_error"
+)
+
+// Message is a message marshaled by MarshalMessages.
+type Message struct {
+	// Data is the message written by the output.
+	Data []byte
+	// Unsealed is the message before it is wrapped in an envelope,
+	// it is Data if no envelope is configured.
+	Unsealed []byte
+	// Event is set if the message is a single event,
+	// i.e the format is event and the events are split.
+	Event *formatters.EventMsg
+	// Err is set if the message template or the envelope failed
+	// on this message, Reason is then one of ReasonTemplateError
+	// or ReasonEnvelopeError.
+	Err    error
+	Reason string
+}
+
+// Marshal marshals pmsg according to mo, the returned messages
+// went through the message template and the envelope.
+// It fails if any of the messages fails, use MarshalMessages
+// to handle each message failure separately.
+func Marshal(pmsg protoreflect.ProtoMessage, meta map[string]string, mo *MarshalOptions, splitEvents bool, evps ...formatters.EventProcessor) ([][]byte, error) {
+	ms, err := MarshalMessages(pmsg, meta, mo, splitEvents, evps...)
+	if err != nil || len(ms) == 0 {
+		return nil, err
+	}
+	bb := make([][]byte, 0, len(ms))
+	for _, m := range ms {
+		if m.Err != nil {
+			return nil, m.Err
+		}
+		bb = append(bb, m.Data)
+	}
+	return bb, nil
+}
+
+// MarshalMessages is Marshal returning the messages along with
+// their unsealed content and the event they result from.
+// The returned error is a marshaling error, a message template or
+// envelope failure is set on the message it applies to.
+func MarshalMessages(pmsg protoreflect.ProtoMessage, meta map[string]string, mo *MarshalOptions, splitEvents bool, evps ...formatters.EventProcessor) ([]*Message, error) {
+	var ms []*Message
+	var err error
 	switch mo.Format {
 	case "event":
 		if splitEvents {
-			return marshalSplit(pmsg, meta, mo, evps...)
+			ms, err = marshalSplit(pmsg, meta, mo, evps...)
+			break
 		}
 		fallthrough
 	default:
-		b, err := mo.Marshal(pmsg, meta, evps...)
+		if rsp, ok := pmsg.(*gnmi.SubscribeResponse); ok && mo.Format != "event" {
+			// the event format messages are recorded once converted.
+			formatters.ObserveResponse(formatters.ChainOutput(evps), meta["subscription-name"], rsp, meta)
+		}
+		var b []byte
+		b, err = mo.MarshalOptions.Marshal(pmsg, meta, evps...)
+		if len(b) > 0 {
+			ms = []*Message{{Unsealed: b}}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	mo.finalize(ms)
+	return ms, nil
+}
+
+// MarshalEvents marshals the events evs, already processed by the output
+// processors, in the event format whatever the configured format.
+// Like MarshalMessages, the messages go through the message template and the envelope.
+func MarshalEvents(evs []*formatters.EventMsg, mo *MarshalOptions, splitEvents bool) ([]*Message, error) {
+	if len(evs) == 0 {
+		return nil, nil
+	}
+	marshalFn := json.Marshal
+	if mo.Multiline {
+		marshalFn = func(v any) ([]byte, error) {
+			return json.MarshalIndent(v, "", mo.Indent)
+		}
+	}
+	var ms []*Message
+	if splitEvents {
+		ms = make([]*Message, 0, len(evs))
+		for _, ev := range evs {
+			b, err := marshalFn(ev)
+			if err != nil {
+				return nil, err
+			}
+			ms = append(ms, &Message{Unsealed: b, Event: ev})
+		}
+	} else {
+		b, err := marshalFn(evs)
 		if err != nil {
 			return nil, err
 		}
-		if len(b) == 0 {
-			return nil, nil
+		ms = []*Message{{Unsealed: b}}
+	}
+	mo.finalize(ms)
+	return ms, nil
+}
+
+// finalize executes the message template and the envelope on ms,
+// setting the failure of each message.
+func (mo *MarshalOptions) finalize(ms []*Message) {
+	for _, m := range ms {
+		m.Unsealed, m.Err = mo.execTemplate(m.Unsealed)
+		if m.Err != nil {
+			m.Reason = ReasonTemplateError
+			continue
+		}
+		m.Data, m.Err = mo.seal(m.Unsealed)
+		if m.Err != nil {
+			m.Reason = ReasonEnvelopeError
 		}
-		return [][]byte{b}, nil
 	}
 }
 
-func marshalSplit(pmsg protoreflect.ProtoMessage, meta map[string]string, mo *formatters.MarshalOptions, evps ...formatters.EventProcessor) ([][]byte, error) {
+func marshalSplit(pmsg protoreflect.ProtoMessage, meta map[string]string, mo *MarshalOptions, evps ...formatters.EventProcessor) ([]*Message, error) {
 	var subscriptionName string
 	var ok bool
 	if subscriptionName, ok = meta["subscription-name"]; !ok {
@@ -194,7 +339,7 @@ func marshalSplit(pmsg protoreflect.ProtoMessage, meta map[string]string, mo *fo
 			if numEvents == 0 {
 				return nil, nil
 			}
-			rs := make([][]byte, 0, numEvents)
+			ms := make([]*Message, 0, numEvents)
 			marshalFn := json.Marshal
 			if mo.Multiline {
 				marshalFn = func(v any) ([]byte, error) {
@@ -206,9 +351,9 @@ func marshalSplit(pmsg protoreflect.ProtoMessage, meta map[string]string, mo *fo
 				if err != nil {
 					return nil, err
 				}
-				rs = append(rs, b)
+				ms = append(ms, &Message{Unsealed: b, Event: ev})
 			}
-			return rs, nil
+			return ms, nil
 		default:
 			return nil, fmt.Errorf("unexpected message type: %T", msg)
 		}
@@ -163,6 +163,7 @@ func (p *prometheusOutput) Init(ctx context.Context, name string, cfg map[string
 			return err
 		}
 	}
+	p.evps = formatters.ForOutput(name, p.evps)
 	if p.cfg.TargetTemplate == "" {
 		p.targetTpl = outputs.DefaultTargetTemplate
 	} else if p.cfg.AddTarget != "" {
@@ -251,6 +252,16 @@ func (p *prometheusOutput) Init(ctx context.Context, name string, cfg map[string
 	if p.cfg.CacheConfig == nil {
 		go p.expireMetricsPeriodic(wctx)
 	}
+	formatters.StartFlush(wctx, p.evps, p.logger, func(evs []*formatters.EventMsg) {
+		formatters.ObserveEvents(p.cfg.Name, evs)
+		for _, ev := range evs {
+			select {
+			case <-wctx.Done():
+				return
+			case p.eventChan <- ev:
+			}
+		}
+	})
 
 	go func() {
 		defer p.wg.Done()
@@ -299,6 +310,7 @@ func (p *prometheusOutput) WriteEvent(ctx context.Context, ev *formatters.EventM
 		for _, proc := range p.evps {
 			evs = proc.Apply(evs...)
 		}
+		formatters.ObserveEvents(p.cfg.Name, evs)
 		for _, pev := range evs {
 			p.eventChan <- pev
 		}
@@ -151,6 +151,7 @@ func (p *promWriteOutput) Init(ctx context.Context, name string, cfg map[string]
 			return err
 		}
 	}
+	p.evps = formatters.ForOutput(name, p.evps)
 
 	err = p.registerMetrics()
 	if err != nil {
@@ -193,6 +194,16 @@ func (p *promWriteOutput) Init(ctx context.Context, name string, cfg map[string]
 		go p.writer(ctx)
 	}
 	go p.metadataWriter(ctx)
+	formatters.StartFlush(ctx, p.evps, p.logger, func(evs []*formatters.EventMsg) {
+		formatters.ObserveEvents(p.cfg.Name, evs)
+		for _, ev := range evs {
+			select {
+			case <-ctx.Done():
+				return
+			case p.eventChan <- ev:
+			}
+		}
+	})
 	p.logger.Printf("initialized prometheus write output %s: %s", p.cfg.Name, p.String())
 	return nil
 }
@@ -226,6 +237,7 @@ func (p *promWriteOutput) WriteEvent(ctx context.Context, ev *formatters.EventMs
 		for _, proc := range p.evps {
 			evs = proc.Apply(evs...)
 		}
+		formatters.ObserveEvents(p.cfg.Name, evs)
 		for _, pev := range evs {
 			p.eventChan <- pev
 		}
@@ -9,12 +9,17 @@
 package outputs
 
 import (
+	"sort"
+
 	"google.golang.org/protobuf/proto"
+
+	"github.com/openconfig/gnmic/pkg/formatters"
 )
 
 type ProtoMsg struct {
 	m    proto.Message
 	meta Meta
+	evs  []*formatters.EventMsg
 }
 
 func NewProtoMsg(m proto.Message, meta Meta) *ProtoMsg {
@@ -24,6 +29,51 @@ func NewProtoMsg(m proto.Message, meta Meta) *ProtoMsg {
 	}
 }
 
+// NewEventsMsg returns a ProtoMsg carrying events already
+// processed by the output processors, e.g flushed by them.
+func NewEventsMsg(evs []*formatters.EventMsg, meta Meta) *ProtoMsg {
+	return &ProtoMsg{
+		evs:  evs,
+		meta: meta,
+	}
+}
+
+// FlushedMsgs groups the events flushed by the output processors
+// by source and subscription name, so that each group is written
+// with the meta of the subscription it results from.
+func FlushedMsgs(evs []*formatters.EventMsg) []*ProtoMsg {
+	groups := make(map[[2]string][]*formatters.EventMsg)
+	for _, ev := range evs {
+		if ev == nil {
+			continue
+		}
+		k := [2]string{ev.Tags["source"], ev.Tags["subscription-name"]}
+		groups[k] = append(groups[k], ev)
+	}
+	keys := make([][2]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] == keys[j][0] {
+			return keys[i][1] < keys[j][1]
+		}
+		return keys[i][0] < keys[j][0]
+	})
+	ms := make([]*ProtoMsg, 0, len(keys))
+	for _, k := range keys {
+		meta := Meta{}
+		if k[0] != "" {
+			meta["source"] = k[0]
+		}
+		if k[1] != "" {
+			meta["subscription-name"] = k[1]
+		}
+		ms = append(ms, NewEventsMsg(groups[k], meta))
+	}
+	return ms
+}
+
 func (m *ProtoMsg) GetMsg() proto.Message {
 	if m == nil {
 		return nil
@@ -37,3 +87,11 @@ func (m *ProtoMsg) GetMeta() Meta {
 	}
 	return m.meta
 }
+
+// GetEvents returns the processed events carried by m, see NewEventsMsg.
+func (m *ProtoMsg) GetEvents() []*formatters.EventMsg {
+	if m == nil {
+		return nil
+	}
+	return m.evs
+}
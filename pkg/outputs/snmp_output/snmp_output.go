@@ -137,6 +137,7 @@ func (s *snmpOutput) Init(ctx context.Context, name string, cfg map[string]inter
 			return err
 		}
 	}
+	s.evps = formatters.ForOutput(name, s.evps)
 
 	s.setDefaults()
 	err = s.registerMetrics()
@@ -199,6 +200,10 @@ func (s *snmpOutput) Init(ctx context.Context, name string, cfg map[string]inter
 	}()
 	s.startTime = time.Now()
 	go s.start(ctx)
+	formatters.StartFlush(ctx, s.evps, s.logger, func(evs []*formatters.EventMsg) {
+		formatters.ObserveEvents(name, evs)
+		s.writeEvents(ctx, evs)
+	})
 	s.logger.Printf("initialized SNMP output: %s", s.String())
 	return nil
 }
@@ -230,12 +235,16 @@ func (s *snmpOutput) Write(ctx context.Context, m proto.Message, meta outputs.Me
 			s.logger.Printf("failed to convert message to event: %v", err)
 			return
 		}
-		for _, ev := range events {
-			select {
-			case <-ctx.Done():
-				return
-			case s.eventChan <- ev:
-			}
+		s.writeEvents(ctx, events)
+	}
+}
+
+func (s *snmpOutput) writeEvents(ctx context.Context, evs []*formatters.EventMsg) {
+	for _, ev := range evs {
+		select {
+		case <-ctx.Done():
+			return
+		case s.eventChan <- ev:
 		}
 	}
 }
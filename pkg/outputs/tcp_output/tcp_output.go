@@ -51,7 +51,7 @@ type tcpOutput struct {
 	buffer   chan []byte
 	limiter  *time.Ticker
 	logger   *log.Logger
-	mo       *formatters.MarshalOptions
+	mo       *outputs.MarshalOptions
 	evps     []formatters.EventProcessor
 
 	targetTpl *template.Template
@@ -59,20 +59,21 @@ type tcpOutput struct {
 }
 
 type config struct {
-	Address            string        `mapstructure:"address,omitempty"` // ip:port
-	Rate               time.Duration `mapstructure:"rate,omitempty"`
-	BufferSize         uint          `mapstructure:"buffer-size,omitempty"`
-	Format             string        `mapstructure:"format,omitempty"`
-	AddTarget          string        `mapstructure:"add-target,omitempty"`
-	TargetTemplate     string        `mapstructure:"target-template,omitempty"`
-	OverrideTimestamps bool          `mapstructure:"override-timestamps,omitempty"`
-	SplitEvents        bool          `mapstructure:"split-events,omitempty"`
-	Delimiter          string        `mapstructure:"delimiter,omitempty"`
-	KeepAlive          time.Duration `mapstructure:"keep-alive,omitempty"`
-	RetryInterval      time.Duration `mapstructure:"retry-interval,omitempty"`
-	NumWorkers         int           `mapstructure:"num-workers,omitempty"`
-	EnableMetrics      bool          `mapstructure:"enable-metrics,omitempty"`
-	EventProcessors    []string      `mapstructure:"event-processors,omitempty"`
+	Address            string                  `mapstructure:"address,omitempty"` // ip:port
+	Rate               time.Duration           `mapstructure:"rate,omitempty"`
+	BufferSize         uint                    `mapstructure:"buffer-size,omitempty"`
+	Format             string                  `mapstructure:"format,omitempty"`
+	AddTarget          string                  `mapstructure:"add-target,omitempty"`
+	TargetTemplate     string                  `mapstructure:"target-template,omitempty"`
+	OverrideTimestamps bool                    `mapstructure:"override-timestamps,omitempty"`
+	SplitEvents        bool                    `mapstructure:"split-events,omitempty"`
+	Delimiter          string                  `mapstructure:"delimiter,omitempty"`
+	KeepAlive          time.Duration           `mapstructure:"keep-alive,omitempty"`
+	RetryInterval      time.Duration           `mapstructure:"retry-interval,omitempty"`
+	NumWorkers         int                     `mapstructure:"num-workers,omitempty"`
+	EnableMetrics      bool                    `mapstructure:"enable-metrics,omitempty"`
+	EventProcessors    []string                `mapstructure:"event-processors,omitempty"`
+	Envelope           *outputs.EnvelopeConfig `mapstructure:"envelope,omitempty" json:"envelope,omitempty"`
 }
 
 func (t *tcpOutput) SetLogger(logger *log.Logger) {
@@ -112,6 +113,7 @@ func (t *tcpOutput) Init(ctx context.Context, name string, cfg map[string]interf
 			return err
 		}
 	}
+	t.evps = formatters.ForOutput(name, t.evps)
 	_, _, err = net.SplitHostPort(t.cfg.Address)
 	if err != nil {
 		return fmt.Errorf("wrong address format: %v", err)
@@ -129,9 +131,15 @@ func (t *tcpOutput) Init(ctx context.Context, name string, cfg map[string]interf
 	if len(t.cfg.Delimiter) > 0 {
 		t.delimiter = []byte(t.cfg.Delimiter)
 	}
-	t.mo = &formatters.MarshalOptions{
-		Format:     t.cfg.Format,
-		OverrideTS: t.cfg.OverrideTimestamps,
+	t.mo = &outputs.MarshalOptions{
+		MarshalOptions: formatters.MarshalOptions{
+			Format:     t.cfg.Format,
+			OverrideTS: t.cfg.OverrideTimestamps,
+		},
+	}
+	t.mo.Sealer, err = outputs.NewSealer(t.cfg.Envelope)
+	if err != nil {
+		return err
 	}
 
 	if t.cfg.TargetTemplate == "" {
@@ -152,6 +160,9 @@ func (t *tcpOutput) Init(ctx context.Context, name string, cfg map[string]interf
 	for i := 0; i < t.cfg.NumWorkers; i++ {
 		go t.start(ctx, i)
 	}
+	formatters.StartFlush(ctx, t.evps, t.logger, func(evs []*formatters.EventMsg) {
+		t.writeFlushed(ctx, evs)
+	})
 	return nil
 }
 
@@ -167,17 +178,41 @@ func (t *tcpOutput) Write(ctx context.Context, m proto.Message, meta outputs.Met
 		if err != nil {
 			t.logger.Printf("failed to add target to the response: %v", err)
 		}
-		bb, err := outputs.Marshal(rsp, meta, t.mo, t.cfg.SplitEvents, t.evps...)
+		ms, err := outputs.MarshalMessages(rsp, meta, t.mo, t.cfg.SplitEvents, t.evps...)
 		if err != nil {
 			t.logger.Printf("failed marshaling proto msg: %v", err)
 			return
 		}
-		for _, b := range bb {
-			t.buffer <- b
+		t.writeMessages(ctx, ms)
+	}
+}
+
+// writeMessages buffers the messages ms to be sent.
+func (t *tcpOutput) writeMessages(ctx context.Context, ms []*outputs.Message) {
+	for _, m := range ms {
+		if m.Err != nil {
+			t.logger.Printf("%v", m.Err)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case t.buffer <- m.Data:
 		}
 	}
 }
 
+// writeFlushed writes the events flushed by the output processors.
+func (t *tcpOutput) writeFlushed(ctx context.Context, evs []*formatters.EventMsg) {
+	formatters.ObserveEvents(formatters.ChainOutput(t.evps), evs)
+	ms, err := outputs.MarshalEvents(evs, t.mo, t.cfg.SplitEvents)
+	if err != nil {
+		t.logger.Printf("failed marshaling flushed events: %v", err)
+		return
+	}
+	t.writeMessages(ctx, ms)
+}
+
 func (t *tcpOutput) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {}
 
 func (t *tcpOutput) Close() error {
@@ -51,24 +51,25 @@ type UDPSock struct {
 	buffer   chan []byte
 	limiter  *time.Ticker
 	logger   *log.Logger
-	mo       *formatters.MarshalOptions
+	mo       *outputs.MarshalOptions
 	evps     []formatters.EventProcessor
 
 	targetTpl *template.Template
 }
 
 type Config struct {
-	Address            string        `mapstructure:"address,omitempty"` // ip:port
-	Rate               time.Duration `mapstructure:"rate,omitempty"`
-	BufferSize         uint          `mapstructure:"buffer-size,omitempty"`
-	Format             string        `mapstructure:"format,omitempty"`
-	AddTarget          string        `mapstructure:"add-target,omitempty"`
-	TargetTemplate     string        `mapstructure:"target-template,omitempty"`
-	OverrideTimestamps bool          `mapstructure:"override-timestamps,omitempty"`
-	SplitEvents        bool          `mapstructure:"split-events,omitempty"`
-	RetryInterval      time.Duration `mapstructure:"retry-interval,omitempty"`
-	EnableMetrics      bool          `mapstructure:"enable-metrics,omitempty"`
-	EventProcessors    []string      `mapstructure:"event-processors,omitempty"`
+	Address            string                  `mapstructure:"address,omitempty"` // ip:port
+	Rate               time.Duration           `mapstructure:"rate,omitempty"`
+	BufferSize         uint                    `mapstructure:"buffer-size,omitempty"`
+	Format             string                  `mapstructure:"format,omitempty"`
+	AddTarget          string                  `mapstructure:"add-target,omitempty"`
+	TargetTemplate     string                  `mapstructure:"target-template,omitempty"`
+	OverrideTimestamps bool                    `mapstructure:"override-timestamps,omitempty"`
+	SplitEvents        bool                    `mapstructure:"split-events,omitempty"`
+	RetryInterval      time.Duration           `mapstructure:"retry-interval,omitempty"`
+	EnableMetrics      bool                    `mapstructure:"enable-metrics,omitempty"`
+	EventProcessors    []string                `mapstructure:"event-processors,omitempty"`
+	Envelope           *outputs.EnvelopeConfig `mapstructure:"envelope,omitempty" json:"envelope,omitempty"`
 }
 
 func (u *UDPSock) SetLogger(logger *log.Logger) {
@@ -108,6 +109,7 @@ func (u *UDPSock) Init(ctx context.Context, name string, cfg map[string]interfac
 			return err
 		}
 	}
+	u.evps = formatters.ForOutput(name, u.evps)
 	_, _, err = net.SplitHostPort(u.Cfg.Address)
 	if err != nil {
 		return fmt.Errorf("wrong address format: %v", err)
@@ -120,14 +122,20 @@ func (u *UDPSock) Init(ctx context.Context, name string, cfg map[string]interfac
 	if u.Cfg.Rate > 0 {
 		u.limiter = time.NewTicker(u.Cfg.Rate)
 	}
+	ctx, u.cancelFn = context.WithCancel(ctx)
 	go func() {
 		<-ctx.Done()
 		u.Close()
 	}()
-	ctx, u.cancelFn = context.WithCancel(ctx)
-	u.mo = &formatters.MarshalOptions{
-		Format:     u.Cfg.Format,
-		OverrideTS: u.Cfg.OverrideTimestamps,
+	u.mo = &outputs.MarshalOptions{
+		MarshalOptions: formatters.MarshalOptions{
+			Format:     u.Cfg.Format,
+			OverrideTS: u.Cfg.OverrideTimestamps,
+		},
+	}
+	u.mo.Sealer, err = outputs.NewSealer(u.Cfg.Envelope)
+	if err != nil {
+		return err
 	}
 	if u.Cfg.TargetTemplate == "" {
 		u.targetTpl = outputs.DefaultTargetTemplate
@@ -139,6 +147,9 @@ func (u *UDPSock) Init(ctx context.Context, name string, cfg map[string]interfac
 		u.targetTpl = u.targetTpl.Funcs(outputs.TemplateFuncs)
 	}
 	go u.start(ctx)
+	formatters.StartFlush(ctx, u.evps, u.logger, func(evs []*formatters.EventMsg) {
+		u.writeFlushed(ctx, evs)
+	})
 	return nil
 }
 
@@ -155,17 +166,41 @@ func (u *UDPSock) Write(ctx context.Context, m proto.Message, meta outputs.Meta)
 		if err != nil {
 			u.logger.Printf("failed to add target to the response: %v", err)
 		}
-		bb, err := outputs.Marshal(rsp, meta, u.mo, u.Cfg.SplitEvents, u.evps...)
+		ms, err := outputs.MarshalMessages(rsp, meta, u.mo, u.Cfg.SplitEvents, u.evps...)
 		if err != nil {
 			u.logger.Printf("failed marshaling proto msg: %v", err)
 			return
 		}
-		for _, b := range bb {
-			u.buffer <- b
+		u.writeMessages(ctx, ms)
+	}
+}
+
+// writeMessages buffers the messages ms to be sent.
+func (u *UDPSock) writeMessages(ctx context.Context, ms []*outputs.Message) {
+	for _, m := range ms {
+		if m.Err != nil {
+			u.logger.Printf("%v", m.Err)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case u.buffer <- m.Data:
 		}
 	}
 }
 
+// writeFlushed writes the events flushed by the output processors.
+func (u *UDPSock) writeFlushed(ctx context.Context, evs []*formatters.EventMsg) {
+	formatters.ObserveEvents(formatters.ChainOutput(u.evps), evs)
+	ms, err := outputs.MarshalEvents(evs, u.mo, u.Cfg.SplitEvents)
+	if err != nil {
+		u.logger.Printf("failed marshaling flushed events: %v", err)
+		return
+	}
+	u.writeMessages(ctx, ms)
+}
+
 func (u *UDPSock) WriteEvent(ctx context.Context, ev *formatters.EventMsg) {}
 
 func (u *UDPSock) Close() error {
@@ -0,0 +1,90 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package udp_output
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/openconfig/gnmic/pkg/api/types"
+	"github.com/openconfig/gnmic/pkg/formatters"
+)
+
+// heldProcessor holds its events until it is flushed.
+type heldProcessor struct {
+	held chan []*formatters.EventMsg
+}
+
+func (p *heldProcessor) Init(interface{}, ...formatters.Option) error { return nil }
+func (p *heldProcessor) Apply(es ...*formatters.EventMsg) []*formatters.EventMsg {
+	p.held <- es
+	return nil
+}
+func (p *heldProcessor) WithTargets(map[string]*types.TargetConfig)    {}
+func (p *heldProcessor) WithLogger(*log.Logger)                        {}
+func (p *heldProcessor) WithActions(map[string]map[string]interface{}) {}
+func (p *heldProcessor) WithProcessors(map[string]map[string]any)      {}
+
+func (p *heldProcessor) Flush(time.Time) []*formatters.EventMsg {
+	select {
+	case es := <-p.held:
+		return es
+	default:
+		return nil
+	}
+}
+
+func TestUDPOutputFlush(t *testing.T) {
+	formatters.FlushPeriod = 10 * time.Millisecond
+	defer func() { formatters.FlushPeriod = time.Second }()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	hp := &heldProcessor{held: make(chan []*formatters.EventMsg, 1)}
+	hp.Apply(&formatters.EventMsg{
+		Name:      "sub1",
+		Timestamp: 42,
+		Tags:      map[string]string{"source": "r1", "subscription-name": "sub1"},
+		Values:    map[string]interface{}{"/a": 1},
+	})
+	u := &UDPSock{
+		Cfg:    &Config{},
+		logger: log.New(io.Discard, "", 0),
+		evps:   []formatters.EventProcessor{hp},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err = u.Init(ctx, "udp1", map[string]interface{}{"address": conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	b := make([]byte, 4096)
+	n, _, err := conn.ReadFrom(b)
+	if err != nil {
+		t.Fatalf("expected the flushed events to be sent: %v", err)
+	}
+	var evs []*formatters.EventMsg
+	if err := json.Unmarshal(b[:n], &evs); err != nil {
+		t.Fatalf("failed to decode %q: %v", b[:n], err)
+	}
+	if len(evs) != 1 || evs[0].Name != "sub1" || evs[0].Tags["source"] != "r1" {
+		t.Errorf("unexpected flushed events %s", b[:n])
+	}
+}
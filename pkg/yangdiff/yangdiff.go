@@ -0,0 +1,578 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package yangdiff compares two resolved YANG schema trees
+// and checks paths against the differences.
+package yangdiff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/openconfig/goyang/pkg/yang"
+
+	"github.com/openconfig/gnmic/pkg/api/path"
+)
+
+const (
+	KindContainer = "container"
+	KindList      = "list"
+	KindLeaf      = "leaf"
+	KindLeafList  = "leaf-list"
+)
+
+// Node is a schema node, identified by its path
+// without keys, module prefixes, choices and cases.
+type Node struct {
+	Path    string   `json:"path"`
+	Module  string   `json:"module,omitempty"`
+	Kind    string   `json:"kind"`
+	Type    string   `json:"type,omitempty"`
+	Units   string   `json:"units,omitempty"`
+	Enums   []string `json:"enums,omitempty"`
+	Default string   `json:"default,omitempty"`
+	State   bool     `json:"state,omitempty"`
+	Keys    string   `json:"keys,omitempty"`
+
+	name     string
+	children map[string]*Node
+}
+
+func (n *Node) isLeaf() bool {
+	return n.Kind == KindLeaf || n.Kind == KindLeafList
+}
+
+// Schema is a flattened schema tree.
+type Schema struct {
+	root  *Node
+	nodes map[string]*Node
+}
+
+// NewSchema builds a Schema from a root entry holding the modules entries.
+func NewSchema(root *yang.Entry) *Schema {
+	s := &Schema{
+		root: &Node{
+			Path:     "/",
+			Kind:     KindContainer,
+			children: make(map[string]*Node),
+		},
+		nodes: make(map[string]*Node),
+	}
+	for _, m := range sortedEntries(root.Dir) {
+		for _, e := range sortedEntries(m.Dir) {
+			s.add(s.root, e, false)
+		}
+	}
+	return s
+}
+
+func (s *Schema) add(parent *Node, e *yang.Entry, state bool) {
+	// choices and cases are not part of the data tree
+	if e.IsChoice() || e.IsCase() {
+		for _, c := range sortedEntries(e.Dir) {
+			s.add(parent, c, state)
+		}
+		return
+	}
+	state = state || e.Config == yang.TSFalse
+	p := parent.Path + "/" + e.Name
+	if parent == s.root {
+		p = "/" + e.Name
+	}
+	n, ok := parent.children[e.Name]
+	if !ok {
+		n = &Node{
+			Path:     p,
+			Module:   moduleName(e),
+			Kind:     kind(e),
+			State:    state,
+			name:     e.Name,
+			children: make(map[string]*Node),
+		}
+		if e.IsList() {
+			n.Keys = e.Key
+		}
+		if n.isLeaf() {
+			n.Type = typeString(e.Type)
+			n.Enums = enums(e.Type)
+			n.Units = units(e)
+			if e.IsLeafList() {
+				n.Default = strings.Join(e.DefaultValues(), ", ")
+			} else {
+				n.Default, _ = e.SingleDefaultValue()
+			}
+		}
+		parent.children[e.Name] = n
+		s.nodes[p] = n
+	}
+	for _, c := range sortedEntries(e.Dir) {
+		s.add(n, c, state)
+	}
+}
+
+// Node returns the node with the given path.
+func (s *Schema) Node(p string) (*Node, bool) {
+	n, ok := s.nodes[p]
+	return n, ok
+}
+
+// Len returns the number of nodes in the schema.
+func (s *Schema) Len() int {
+	return len(s.nodes)
+}
+
+// Match returns the schema nodes matching the path p.
+// Keys and origin are ignored, module prefixes are removed
+// and the `*` and `...` wildcards are expanded.
+func (s *Schema) Match(p string) ([]*Node, error) {
+	gp, err := path.ParsePath(p)
+	if err != nil {
+		return nil, err
+	}
+	current := []*Node{s.root}
+	for _, pe := range gp.GetElem() {
+		name := pe.GetName()
+		if i := strings.Index(name, ":"); i >= 0 {
+			name = name[i+1:]
+		}
+		next := make([]*Node, 0)
+		seen := make(map[*Node]struct{})
+		appendNode := func(n *Node) {
+			if _, ok := seen[n]; ok {
+				return
+			}
+			seen[n] = struct{}{}
+			next = append(next, n)
+		}
+		for _, n := range current {
+			switch name {
+			case "...":
+				for _, d := range descendants(n, true) {
+					appendNode(d)
+				}
+			case "*":
+				for _, c := range sortedNodes(n.children) {
+					appendNode(c)
+				}
+			default:
+				if c, ok := n.children[name]; ok {
+					appendNode(c)
+				}
+			}
+		}
+		current = next
+		if len(current) == 0 {
+			break
+		}
+	}
+	return current, nil
+}
+
+// Change is a change of a node attribute.
+// For enum changes, Old holds the removed values and New the added ones.
+type Change struct {
+	Path  string `json:"path"`
+	Field string `json:"field"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+func (c *Change) String() string {
+	if c.Field == "enum" {
+		sb := new(strings.Builder)
+		sb.WriteString("enum values")
+		if c.Old != "" {
+			sb.WriteString(" removed: ")
+			sb.WriteString(c.Old)
+		}
+		if c.New != "" {
+			if c.Old != "" {
+				sb.WriteString(",")
+			}
+			sb.WriteString(" added: ")
+			sb.WriteString(c.New)
+		}
+		return sb.String()
+	}
+	return fmt.Sprintf("%s changed from %q to %q", c.Field, c.Old, c.New)
+}
+
+// breaking reports whether the change can break
+// the consumers of the node's data.
+func (c *Change) breaking() bool {
+	switch c.Field {
+	case "default":
+		return false
+	case "enum":
+		return c.Old != ""
+	}
+	return true
+}
+
+// Move is a leaf found under a different path.
+type Move struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Result is the difference between two schemas.
+// Added, removed and moved nodes are leaves and leaf-lists.
+type Result struct {
+	Added   []*Node   `json:"added,omitempty"`
+	Removed []*Node   `json:"removed,omitempty"`
+	Moved   []*Move   `json:"moved,omitempty"`
+	Changed []*Change `json:"changed,omitempty"`
+}
+
+// Diff compares the old and new schemas.
+// A removed leaf is reported as moved if a single added leaf has the same name
+// and type and shares the longest path suffix with it.
+func Diff(old, cur *Schema) *Result {
+	r := &Result{
+		Added:   make([]*Node, 0),
+		Removed: make([]*Node, 0),
+		Moved:   make([]*Move, 0),
+		Changed: make([]*Change, 0),
+	}
+	added := make([]*Node, 0)
+	removed := make([]*Node, 0)
+	for _, p := range sortedPaths(old.nodes) {
+		on := old.nodes[p]
+		nn, ok := cur.nodes[p]
+		if !ok {
+			if on.isLeaf() {
+				removed = append(removed, on)
+			}
+			continue
+		}
+		r.Changed = append(r.Changed, compare(p, on, nn)...)
+	}
+	for _, p := range sortedPaths(cur.nodes) {
+		nn := cur.nodes[p]
+		if _, ok := old.nodes[p]; !ok && nn.isLeaf() {
+			added = append(added, nn)
+		}
+	}
+	// moved leaves
+	byName := make(map[string][]*Node)
+	for _, n := range added {
+		byName[n.name] = append(byName[n.name], n)
+	}
+	moved := make(map[*Node]struct{})
+	for _, on := range removed {
+		to := moveCandidate(on, byName[on.name], moved)
+		if to == nil {
+			r.Removed = append(r.Removed, on)
+			continue
+		}
+		moved[to] = struct{}{}
+		r.Moved = append(r.Moved, &Move{From: on.Path, To: to.Path})
+		r.Changed = append(r.Changed, compare(to.Path, on, to)...)
+	}
+	for _, n := range added {
+		if _, ok := moved[n]; !ok {
+			r.Added = append(r.Added, n)
+		}
+	}
+	sort.SliceStable(r.Changed, func(i, j int) bool {
+		return r.Changed[i].Path < r.Changed[j].Path
+	})
+	return r
+}
+
+func moveCandidate(on *Node, candidates []*Node, moved map[*Node]struct{}) *Node {
+	var best *Node
+	bestScore := 0
+	tie := false
+	for _, c := range candidates {
+		if _, ok := moved[c]; ok || c.Type != on.Type || c.Kind != on.Kind {
+			continue
+		}
+		score := commonSuffix(on.Path, c.Path)
+		switch {
+		case score > bestScore:
+			best, bestScore, tie = c, score, false
+		case score == bestScore:
+			tie = true
+		}
+	}
+	if tie {
+		return nil
+	}
+	return best
+}
+
+func compare(p string, on, nn *Node) []*Change {
+	changes := make([]*Change, 0)
+	add := func(field, o, n string) {
+		if o != n {
+			changes = append(changes, &Change{Path: p, Field: field, Old: o, New: n})
+		}
+	}
+	add("kind", on.Kind, nn.Kind)
+	add("config", configString(on.State), configString(nn.State))
+	add("keys", on.Keys, nn.Keys)
+	add("type", on.Type, nn.Type)
+	add("units", on.Units, nn.Units)
+	add("default", on.Default, nn.Default)
+	if rm, ad := diffStrings(on.Enums, nn.Enums); len(rm) > 0 || len(ad) > 0 {
+		changes = append(changes, &Change{
+			Path:  p,
+			Field: "enum",
+			Old:   strings.Join(rm, ", "),
+			New:   strings.Join(ad, ", "),
+		})
+	}
+	return changes
+}
+
+// Issue is a path referenced by a configuration
+// that is affected by the schema changes.
+type Issue struct {
+	Source string `json:"source,omitempty"`
+	Path   string `json:"path"`
+	// the affected schema node, if different from the path.
+	Node   string `json:"node,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Check returns the issues affecting path p, a path referenced in a configuration.
+// Paths unknown to the old schema are ignored.
+// The leaves under p are checked as well.
+func Check(old, cur *Schema, r *Result, p string) ([]*Issue, error) {
+	oldNodes, err := old.Match(p)
+	if err != nil {
+		return nil, err
+	}
+	issues := make([]*Issue, 0)
+	if len(oldNodes) == 0 || (len(oldNodes) == 1 && oldNodes[0] == old.root) {
+		return issues, nil
+	}
+	moves := make(map[string]string, len(r.Moved))
+	for _, m := range r.Moved {
+		moves[m.From] = m.To
+	}
+	report := func(n, reason string) {
+		is := &Issue{Path: p, Reason: reason}
+		if n != p {
+			is.Node = n
+		}
+		issues = append(issues, is)
+	}
+	for _, on := range oldNodes {
+		if _, ok := cur.nodes[on.Path]; !ok {
+			if to, ok := moves[on.Path]; ok {
+				report(on.Path, "moved to "+to)
+				continue
+			}
+			report(on.Path, "removed")
+			continue
+		}
+		// the node still exists, check the leaves under it
+		for _, d := range descendants(on, true) {
+			if !d.isLeaf() || d == on {
+				continue
+			}
+			if _, ok := cur.nodes[d.Path]; ok {
+				continue
+			}
+			if to, ok := moves[d.Path]; ok {
+				report(d.Path, "moved to "+to)
+				continue
+			}
+			report(d.Path, "removed")
+		}
+		for _, c := range r.Changed {
+			if !c.breaking() {
+				continue
+			}
+			if c.Path == on.Path || strings.HasPrefix(c.Path, on.Path+"/") {
+				report(c.Path, c.String())
+			}
+		}
+	}
+	return issues, nil
+}
+
+func descendants(n *Node, self bool) []*Node {
+	res := make([]*Node, 0)
+	if self {
+		res = append(res, n)
+	}
+	for _, c := range sortedNodes(n.children) {
+		res = append(res, descendants(c, true)...)
+	}
+	return res
+}
+
+func kind(e *yang.Entry) string {
+	switch {
+	case e.IsList():
+		return KindList
+	case e.IsLeafList():
+		return KindLeafList
+	case e.IsLeaf():
+		return KindLeaf
+	}
+	return KindContainer
+}
+
+func moduleName(e *yang.Entry) string {
+	if e.Node == nil {
+		return ""
+	}
+	m := yang.RootNode(e.Node)
+	if m == nil {
+		return ""
+	}
+	return m.Name
+}
+
+func units(e *yang.Entry) string {
+	if e.Units != "" {
+		return e.Units
+	}
+	switch n := e.Node.(type) {
+	case *yang.Leaf:
+		if n.Units != nil {
+			return n.Units.Name
+		}
+	case *yang.LeafList:
+		if n.Units != nil {
+			return n.Units.Name
+		}
+	}
+	if e.Type != nil {
+		return e.Type.Units
+	}
+	return ""
+}
+
+func typeString(t *yang.YangType) string {
+	if t == nil {
+		return ""
+	}
+	if t.Kind == yang.Yunion {
+		ts := make([]string, 0, len(t.Type))
+		for _, mt := range t.Type {
+			ts = append(ts, typeString(mt))
+		}
+		return fmt.Sprintf("%s{%s}", t.Name, strings.Join(ts, "|"))
+	}
+	if t.Name == t.Kind.String() {
+		return t.Name
+	}
+	return fmt.Sprintf("%s(%s)", t.Name, t.Kind)
+}
+
+func enums(t *yang.YangType) []string {
+	if t == nil {
+		return nil
+	}
+	var vs []string
+	switch t.Kind {
+	case yang.Yenum:
+		if t.Enum != nil {
+			vs = append(vs, t.Enum.Names()...)
+		}
+	case yang.Yidentityref:
+		if t.IdentityBase != nil {
+			for _, v := range t.IdentityBase.Values {
+				vs = append(vs, v.Name)
+			}
+		}
+	case yang.Yunion:
+		for _, mt := range t.Type {
+			vs = append(vs, enums(mt)...)
+		}
+	}
+	sort.Strings(vs)
+	return vs
+}
+
+func configString(state bool) string {
+	if state {
+		return "state"
+	}
+	return "config"
+}
+
+// diffStrings returns the items of the sorted slice a not found in b,
+// and the items of b not found in a.
+func diffStrings(a, b []string) ([]string, []string) {
+	ma := make(map[string]struct{}, len(a))
+	for _, s := range a {
+		ma[s] = struct{}{}
+	}
+	mb := make(map[string]struct{}, len(b))
+	for _, s := range b {
+		mb[s] = struct{}{}
+	}
+	var removed, added []string
+	for _, s := range a {
+		if _, ok := mb[s]; !ok {
+			removed = append(removed, s)
+		}
+	}
+	for _, s := range b {
+		if _, ok := ma[s]; !ok {
+			added = append(added, s)
+		}
+	}
+	return removed, added
+}
+
+// commonSuffix returns the number of trailing path elements p1 and p2 have in common.
+func commonSuffix(p1, p2 string) int {
+	e1 := strings.Split(strings.Trim(p1, "/"), "/")
+	e2 := strings.Split(strings.Trim(p2, "/"), "/")
+	n := 0
+	for i, j := len(e1)-1, len(e2)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if e1[i] != e2[j] {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+func sortedEntries(m map[string]*yang.Entry) []*yang.Entry {
+	names := make([]string, 0, len(m))
+	for n := range m {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	es := make([]*yang.Entry, 0, len(names))
+	for _, n := range names {
+		es = append(es, m[n])
+	}
+	return es
+}
+
+func sortedNodes(m map[string]*Node) []*Node {
+	names := make([]string, 0, len(m))
+	for n := range m {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	ns := make([]*Node, 0, len(names))
+	for _, n := range names {
+		ns = append(ns, m[n])
+	}
+	return ns
+}
+
+func sortedPaths(m map[string]*Node) []string {
+	ps := make([]string, 0, len(m))
+	for p := range m {
+		ps = append(ps, p)
+	}
+	sort.Strings(ps)
+	return ps
+}
@@ -0,0 +1,193 @@
+// © 2022 Nokia.
+//
+// This code is a Contribution to the gNMIc project (“Work”) made under the Google Software Grant and Corporate Contributor License Agreement (“CLA”) and governed by the Apache License 2.0.
+// No other rights or licenses in or to any of Nokia’s intellectual property are granted for any other purpose.
+// This code is provided on an “as is” basis without any warranties of any kind.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package yangdiff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+const oldModule = `
+module test {
+  namespace "urn:test";
+  prefix t;
+
+  container system {
+    leaf hostname { type string; }
+    leaf mtu { type uint16; default 1500; }
+    container state {
+      config false;
+      leaf uptime { type uint32; units seconds; }
+      leaf oper-status {
+        type enumeration {
+          enum up;
+          enum down;
+          enum testing;
+        }
+      }
+      leaf serial { type string; }
+    }
+    list user {
+      key name;
+      leaf name { type string; }
+      leaf role { type string; }
+    }
+  }
+}
+`
+
+const newModule = `
+module test {
+  namespace "urn:test";
+  prefix t;
+
+  container system {
+    leaf mtu { type uint16; default 9000; }
+    container name {
+      leaf hostname { type string; }
+    }
+    container state {
+      config false;
+      leaf uptime { type uint64; units milliseconds; }
+      leaf oper-status {
+        type enumeration {
+          enum up;
+          enum down;
+          enum unknown;
+        }
+      }
+      leaf boot-time { type uint64; }
+    }
+    list user {
+      key "name";
+      leaf name { type string; }
+      choice auth {
+        case local {
+          leaf role { type string; }
+        }
+      }
+    }
+  }
+}
+`
+
+func schemaFromSource(t *testing.T, src string) *Schema {
+	t.Helper()
+	ms := yang.NewModules()
+	if err := ms.Parse(src, "test.yang"); err != nil {
+		t.Fatal(err)
+	}
+	if errs := ms.Process(); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	root := &yang.Entry{Name: "root", Dir: make(map[string]*yang.Entry)}
+	for _, m := range ms.Modules {
+		root.Dir[m.Name] = yang.ToEntry(m)
+	}
+	return NewSchema(root)
+}
+
+func TestDiff(t *testing.T) {
+	old := schemaFromSource(t, oldModule)
+	cur := schemaFromSource(t, newModule)
+	r := Diff(old, cur)
+
+	paths := func(ns []*Node) []string {
+		ps := make([]string, 0, len(ns))
+		for _, n := range ns {
+			ps = append(ps, n.Path)
+		}
+		return ps
+	}
+	if got, want := paths(r.Added), []string{"/system/state/boot-time"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("added: expected %v, got %v", want, got)
+	}
+	if got, want := paths(r.Removed), []string{"/system/state/serial"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("removed: expected %v, got %v", want, got)
+	}
+	if want := []*Move{{From: "/system/hostname", To: "/system/name/hostname"}}; !reflect.DeepEqual(r.Moved, want) {
+		t.Errorf("moved: expected %v, got %v", want, r.Moved)
+	}
+	want := []*Change{
+		{Path: "/system/mtu", Field: "default", Old: "1500", New: "9000"},
+		{Path: "/system/state/oper-status", Field: "enum", Old: "testing", New: "unknown"},
+		{Path: "/system/state/uptime", Field: "type", Old: "uint32", New: "uint64"},
+		{Path: "/system/state/uptime", Field: "units", Old: "seconds", New: "milliseconds"},
+	}
+	if !reflect.DeepEqual(r.Changed, want) {
+		for _, c := range r.Changed {
+			t.Logf("changed: %+v", c)
+		}
+		t.Errorf("changed: unexpected result")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	s := schemaFromSource(t, newModule)
+	tests := map[string][]string{
+		"/system/name/hostname":         {"/system/name/hostname"},
+		"t:system/t:mtu":                {"/system/mtu"},
+		"/system/user[name=admin]/role": {"/system/user/role"},
+		"/system/*/hostname":            {"/system/name/hostname"},
+		"/system/.../uptime":            {"/system/state/uptime"},
+		"/system/unknown":               {},
+	}
+	for p, want := range tests {
+		t.Run(p, func(t *testing.T) {
+			ns, err := s.Match(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(ns))
+			for _, n := range ns {
+				got = append(got, n.Path)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("expected %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	old := schemaFromSource(t, oldModule)
+	cur := schemaFromSource(t, newModule)
+	r := Diff(old, cur)
+	tests := map[string][]*Issue{
+		"/system/hostname": {
+			{Path: "/system/hostname", Reason: "moved to /system/name/hostname"},
+		},
+		"/system/state": {
+			{Path: "/system/state", Node: "/system/state/serial", Reason: "removed"},
+			{Path: "/system/state", Node: "/system/state/oper-status", Reason: "enum values removed: testing, added: unknown"},
+			{Path: "/system/state", Node: "/system/state/uptime", Reason: `type changed from "uint32" to "uint64"`},
+			{Path: "/system/state", Node: "/system/state/uptime", Reason: `units changed from "seconds" to "milliseconds"`},
+		},
+		// default changes are not breaking
+		"/system/mtu": {},
+		// unknown to the old model set
+		"/interfaces/interface": {},
+	}
+	for p, want := range tests {
+		t.Run(p, func(t *testing.T) {
+			got, err := Check(old, cur, r, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				for _, is := range got {
+					t.Logf("issue: %+v", is)
+				}
+				t.Errorf("unexpected issues")
+			}
+		})
+	}
+}